@@ -232,18 +232,48 @@ func sigmoid(a, b, x float64) float64 {
 
 // adjustLUT applies the given lookup table to the colors of the image.
 func adjustLUT(img image.Image, lut []uint8) *image.NRGBA {
+	return ApplyChannelLUTs(img, lut, lut, lut, nil)
+}
+
+// ApplyChannelLUTs maps the red, green, blue and alpha channels of every pixel of the
+// image through the lookup tables, each of 256 values indexed by the channel value, and
+// returns the adjusted image. A nil table leaves its channel unchanged, as does a table
+// of less than 256 values for the channel values beyond its end. The colors are not
+// premultiplied by the alpha. It is the primitive of the tone adjustments like
+// AdjustGamma and AdjustContrast, for custom tone curves computed once per channel
+// value instead of once per pixel.
+//
+// Example:
+//
+//	// Solarize: invert the colors brighter than the middle gray.
+//	lut := make([]uint8, 256)
+//	for i := range lut {
+//		lut[i] = uint8(i)
+//		if i >= 128 {
+//			lut[i] = uint8(255 - i)
+//		}
+//	}
+//	dstImage := imaging.ApplyChannelLUTs(srcImage, lut, lut, lut, nil)
+func ApplyChannelLUTs(img image.Image, rLUT, gLUT, bLUT, aLUT []uint8) *image.NRGBA {
+	lr, lg, lb := channelLUT(rLUT), channelLUT(gLUT), channelLUT(bLUT)
+	var la []uint8
+	if aLUT != nil {
+		la = channelLUT(aLUT)
+	}
 	src := newScanner(img)
 	dst := image.NewNRGBA(image.Rect(0, 0, src.w, src.h))
-	lut = lut[0:256]
 	parallel(0, src.h, func(ys <-chan int) {
 		for y := range ys {
 			i := y * dst.Stride
 			src.scan(0, y, src.w, y+1, dst.Pix[i:i+src.w*4])
 			for x := 0; x < src.w; x++ {
-				d := dst.Pix[i : i+3 : i+3]
-				d[0] = lut[d[0]]
-				d[1] = lut[d[1]]
-				d[2] = lut[d[2]]
+				d := dst.Pix[i : i+4 : i+4]
+				d[0] = lr[d[0]]
+				d[1] = lg[d[1]]
+				d[2] = lb[d[2]]
+				if la != nil {
+					d[3] = la[d[3]]
+				}
 				i += 4
 			}
 		}
@@ -251,6 +281,30 @@ func adjustLUT(img image.Image, lut []uint8) *image.NRGBA {
 	return dst
 }
 
+// identityLUT is the lookup table leaving the channel values unchanged.
+var identityLUT = func() []uint8 { //nolint
+	lut := make([]uint8, 256)
+	for i := range lut {
+		lut[i] = uint8(i)
+	}
+	return lut
+}()
+
+// channelLUT returns the first 256 values of the lookup table, completed with
+// the identity if it is shorter.
+func channelLUT(lut []uint8) []uint8 {
+	if lut == nil {
+		return identityLUT
+	}
+	if len(lut) < 256 {
+		full := make([]uint8, 256)
+		copy(full, identityLUT)
+		copy(full, lut)
+		return full
+	}
+	return lut[0:256]
+}
+
 // AdjustFunc applies the fn function to each pixel of the img image and returns the adjusted image.
 //
 // Example:
@@ -290,3 +344,150 @@ func AdjustFunc(img image.Image, fn func(c color.NRGBA) color.NRGBA) *image.NRGB
 	})
 	return dst
 }
+
+// MapPixels applies the function to every pixel of the image, given its coordinates and
+// color, and returns the image of the results. The coordinates start at 0, 0 at the top
+// left corner of the image whatever its bounds, like those of the returned image. The
+// rows are processed in parallel, so fn must be safe for concurrent use.
+//
+// Example:
+//
+//	// Darken the image towards the bottom.
+//	h := srcImage.Bounds().Dy()
+//	dstImage := imaging.MapPixels(srcImage, func(x, y int, c color.NRGBA) color.NRGBA {
+//		f := 1 - 0.5*float64(y)/float64(h)
+//		return color.NRGBA{uint8(float64(c.R) * f), uint8(float64(c.G) * f), uint8(float64(c.B) * f), c.A}
+//	})
+func MapPixels(img image.Image, fn func(x, y int, c color.NRGBA) color.NRGBA) *image.NRGBA {
+	src := newScanner(img)
+	dst := image.NewNRGBA(image.Rect(0, 0, src.w, src.h))
+	parallel(0, src.h, func(ys <-chan int) {
+		for y := range ys {
+			i := y * dst.Stride
+			src.scan(0, y, src.w, y+1, dst.Pix[i:i+src.w*4])
+			for x := 0; x < src.w; x++ {
+				d := dst.Pix[i : i+4 : i+4]
+				c := fn(x, y, color.NRGBA{d[0], d[1], d[2], d[3]})
+				d[0] = c.R
+				d[1] = c.G
+				d[2] = c.B
+				d[3] = c.A
+				i += 4
+			}
+		}
+	})
+	return dst
+}
+
+// EachPixel calls the function with the coordinates and the color of every pixel of the
+// image, e.g. to collect statistics. The coordinates start at 0, 0 at the top left
+// corner of the image whatever its bounds. The rows are processed in parallel, so fn
+// must be safe for concurrent use, and the pixels are visited in no particular order.
+//
+// Example:
+//
+//	var opaque atomic.Int64
+//	imaging.EachPixel(img, func(x, y int, c color.NRGBA) {
+//		if c.A == 0xff {
+//			opaque.Add(1)
+//		}
+//	})
+func EachPixel(img image.Image, fn func(x, y int, c color.NRGBA)) {
+	src := newScanner(img)
+	parallel(0, src.h, func(ys <-chan int) {
+		scanLine := make([]uint8, src.w*4)
+		for y := range ys {
+			src.scan(0, y, src.w, y+1, scanLine)
+			for x := 0; x < src.w; x++ {
+				s := scanLine[x*4 : x*4+4 : x*4+4]
+				fn(x, y, color.NRGBA{s[0], s[1], s[2], s[3]})
+			}
+		}
+	})
+}
+
+// AutoWhiteBalance removes a color cast from the image using the gray world assumption:
+// the red, green and blue channels are scaled so that their averages become equal.
+// Transparent pixels are ignored.
+//
+// Example:
+//
+//	dstImage = imaging.AutoWhiteBalance(srcImage)
+func AutoWhiteBalance(img image.Image) *image.NRGBA {
+	var sum [3]float64
+	var total float64
+	src := newScanner(img)
+	scanLine := make([]uint8, src.w*4)
+	for y := 0; y < src.h; y++ {
+		src.scan(0, y, src.w, y+1, scanLine)
+		for i := 0; i < len(scanLine); i += 4 {
+			a := float64(scanLine[i+3])
+			sum[0] += float64(scanLine[i]) * a
+			sum[1] += float64(scanLine[i+1]) * a
+			sum[2] += float64(scanLine[i+2]) * a
+			total += a
+		}
+	}
+	if total == 0 || sum[0] == 0 || sum[1] == 0 || sum[2] == 0 {
+		return Clone(img)
+	}
+
+	gray := (sum[0] + sum[1] + sum[2]) / 3
+	var luts [3][]uint8
+	for c := range luts {
+		scale := gray / sum[c]
+		luts[c] = make([]uint8, 256)
+		for i := 0; i < 256; i++ {
+			luts[c][i] = clamp(float64(i) * scale)
+		}
+	}
+
+	return ApplyChannelLUTs(img, luts[0], luts[1], luts[2], nil)
+}
+
+// AutoExposure corrects under- and overexposed images. It stretches the luminance
+// histogram so that the darkest and brightest 0.5% of the pixels become black and
+// white, then applies a gamma correction that moves the median luminance towards
+// the middle gray. The gamma correction is limited to the range [0.5, 2].
+//
+// Example:
+//
+//	dstImage = imaging.AutoExposure(srcImage)
+func AutoExposure(img image.Image) *image.NRGBA {
+	const clip = 0.005
+
+	histogram := Histogram(img)
+	lo, hi, median := -1, -1, -1
+	var cum float64
+	for i, p := range histogram {
+		cum += p
+		if lo < 0 && cum > clip {
+			lo = i
+		}
+		if median < 0 && cum >= 0.5 {
+			median = i
+		}
+		if hi < 0 && cum >= 1-clip {
+			hi = i
+		}
+	}
+	if lo < 0 || hi < 0 || hi <= lo {
+		return Clone(img)
+	}
+
+	stretch := func(v float64) float64 {
+		return math.Min(math.Max((v-float64(lo))/float64(hi-lo), 0), 1)
+	}
+	e := 1.0
+	if m := stretch(float64(median)); m > 0 && m < 1 {
+		gamma := math.Min(math.Max(math.Log(m)/math.Log(0.5), 0.5), 2)
+		e = 1 / gamma
+	}
+
+	lut := make([]uint8, 256)
+	for i := 0; i < 256; i++ {
+		lut[i] = clamp(math.Pow(stretch(float64(i)), e) * 255.0)
+	}
+
+	return adjustLUT(img, lut)
+}
@@ -4,6 +4,7 @@ import (
 	"image"
 	"image/color"
 	"path/filepath"
+	"sync"
 	"testing"
 )
 
@@ -1094,6 +1095,85 @@ func TestAdjustFunc(t *testing.T) {
 	}
 }
 
+func TestMapPixels(t *testing.T) {
+	t.Parallel()
+
+	src := &image.NRGBA{
+		Rect:   image.Rect(-1, -1, 2, 1),
+		Stride: 3 * 4,
+		Pix: []uint8{
+			0x10, 0x20, 0x30, 0xff, 0x40, 0x50, 0x60, 0xff, 0x70, 0x80, 0x90, 0xff,
+			0xa0, 0xb0, 0xc0, 0xff, 0xd0, 0xe0, 0xf0, 0xff, 0x00, 0x00, 0x00, 0x00,
+		},
+	}
+	got := MapPixels(src, func(x, y int, c color.NRGBA) color.NRGBA {
+		return color.NRGBA{uint8(x), uint8(y), c.B, 0xff - c.A}
+	})
+	want := &image.NRGBA{
+		Rect:   image.Rect(0, 0, 3, 2),
+		Stride: 3 * 4,
+		Pix: []uint8{
+			0x00, 0x00, 0x30, 0x00, 0x01, 0x00, 0x60, 0x00, 0x02, 0x00, 0x90, 0x00,
+			0x00, 0x01, 0xc0, 0x00, 0x01, 0x01, 0xf0, 0x00, 0x02, 0x01, 0x00, 0xff,
+		},
+	}
+	if !compareNRGBA(got, want, 0) {
+		t.Fatalf("got result %#v want %#v", got, want)
+	}
+
+	var mu sync.Mutex
+	seen := map[image.Point]color.NRGBA{}
+	EachPixel(src, func(x, y int, c color.NRGBA) {
+		mu.Lock()
+		defer mu.Unlock()
+		seen[image.Pt(x, y)] = c
+	})
+	if len(seen) != 6 {
+		t.Fatalf("got %d pixels want 6", len(seen))
+	}
+	for p, c := range seen {
+		if want := src.NRGBAAt(p.X-1, p.Y-1); c != want {
+			t.Fatalf("got color %v at %v want %v", c, p, want)
+		}
+	}
+	EachPixel(&image.NRGBA{}, func(x, y int, c color.NRGBA) {
+		t.Fatalf("unexpected pixel %d, %d", x, y)
+	})
+}
+
+func TestApplyChannelLUTs(t *testing.T) {
+	t.Parallel()
+
+	invert := make([]uint8, 256)
+	half := make([]uint8, 256)
+	for i := range invert {
+		invert[i] = uint8(255 - i)
+		half[i] = uint8(i / 2)
+	}
+	src := &image.NRGBA{
+		Rect:   image.Rect(-1, -1, 1, 0),
+		Stride: 2 * 4,
+		Pix:    []uint8{0x10, 0x20, 0x30, 0xff, 0xf0, 0xe0, 0xd0, 0x80},
+	}
+	testCases := []struct {
+		name       string
+		r, g, b, a []uint8
+		want       []uint8
+	}{
+		{"all channels", invert, half, invert, half, []uint8{0xef, 0x10, 0xcf, 0x7f, 0x0f, 0x70, 0x2f, 0x40}},
+		{"nil alpha", invert, nil, nil, nil, []uint8{0xef, 0x20, 0x30, 0xff, 0x0f, 0xe0, 0xd0, 0x80}},
+		{"identity", nil, nil, nil, nil, src.Pix},
+		{"short tables", half[:0x20], nil, nil, half[:0], []uint8{0x08, 0x20, 0x30, 0xff, 0xf0, 0xe0, 0xd0, 0x80}},
+	}
+	for _, tc := range testCases {
+		got := ApplyChannelLUTs(src, tc.r, tc.g, tc.b, tc.a)
+		want := &image.NRGBA{Rect: image.Rect(0, 0, 2, 1), Stride: 2 * 4, Pix: tc.want}
+		if !compareNRGBA(got, want, 0) {
+			t.Fatalf("%s: got result %#v want %#v", tc.name, got, want)
+		}
+	}
+}
+
 func BenchmarkAdjustFunc(b *testing.B) {
 	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
@@ -1102,3 +1182,84 @@ func BenchmarkAdjustFunc(b *testing.B) {
 		})
 	}
 }
+
+func TestAutoWhiteBalance(t *testing.T) {
+	t.Parallel()
+
+	t.Run("remove color cast", func(t *testing.T) {
+		src := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+		copy(src.Pix, []uint8{0xc0, 0x80, 0x40, 0xff, 0x60, 0x40, 0x20, 0xff})
+		got := AutoWhiteBalance(src)
+		want := &image.NRGBA{
+			Rect:   image.Rect(0, 0, 2, 1),
+			Stride: 2 * 4,
+			Pix:    []uint8{0x80, 0x80, 0x80, 0xff, 0x40, 0x40, 0x40, 0xff},
+		}
+		if !compareNRGBA(got, want, 0) {
+			t.Fatalf("got result %#v want %#v", got, want)
+		}
+	})
+
+	t.Run("ignore transparent pixels", func(t *testing.T) {
+		src := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+		copy(src.Pix, []uint8{0x80, 0x80, 0x80, 0xff, 0xff, 0x00, 0x00, 0x00})
+		got := AutoWhiteBalance(src)
+		if !compareNRGBA(got, src, 0) {
+			t.Fatalf("got result %#v want %#v", got, src)
+		}
+	})
+
+	t.Run("black image", func(t *testing.T) {
+		src := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+		got := AutoWhiteBalance(src)
+		if !compareNRGBA(got, src, 0) {
+			t.Fatalf("got result %#v want %#v", got, src)
+		}
+	})
+}
+
+func BenchmarkAutoWhiteBalance(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		AutoWhiteBalance(testdataBranchesJPG)
+	}
+}
+
+func TestAutoExposure(t *testing.T) {
+	t.Parallel()
+
+	t.Run("stretch dark image", func(t *testing.T) {
+		src := image.NewNRGBA(image.Rect(0, 0, 64, 1))
+		for x := 0; x < 64; x++ {
+			v := uint8(0x20 + x)
+			src.SetNRGBA(x, 0, color.NRGBA{v, v, v, 0xff})
+		}
+		got := AutoExposure(src)
+		if got.Pix[0] != 0x00 || got.Pix[63*4] != 0xff {
+			t.Fatalf("got range %#x-%#x want 0x0-0xff", got.Pix[0], got.Pix[63*4])
+		}
+		if m := got.Pix[32*4]; m < 0x70 || m > 0x90 {
+			t.Fatalf("got median %#x want near 0x80", m)
+		}
+		for x := 1; x < 64; x++ {
+			if got.Pix[x*4] < got.Pix[(x-1)*4] {
+				t.Fatalf("the result is not monotonic at %d", x)
+			}
+		}
+	})
+
+	t.Run("solid image", func(t *testing.T) {
+		src := New(4, 4, color.NRGBA{0x40, 0x40, 0x40, 0xff})
+		got := AutoExposure(src)
+		if !compareNRGBA(got, src, 0) {
+			t.Fatalf("got result %#v want %#v", got, src)
+		}
+	})
+}
+
+func BenchmarkAutoExposure(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		AutoExposure(testdataBranchesJPG)
+	}
+}
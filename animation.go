@@ -0,0 +1,291 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+	"math"
+	"time"
+)
+
+// Disposal specifies how the area of an animation frame is treated before the next frame is drawn.
+type Disposal int
+
+// Frame disposal methods.
+const (
+	// DisposalUnspecified means the decoder is free to choose the disposal method.
+	DisposalUnspecified Disposal = 0
+	// DisposalNone leaves the frame in place, the next frame is drawn over it.
+	DisposalNone Disposal = gif.DisposalNone
+	// DisposalBackground clears the frame area to the background before the next frame is drawn.
+	DisposalBackground Disposal = gif.DisposalBackground
+	// DisposalPrevious restores the frame area to the state before the frame was drawn.
+	DisposalPrevious Disposal = gif.DisposalPrevious
+)
+
+// Frame is a single frame of an animation.
+type Frame struct {
+	// Image is the frame image. Its bounds specify the position of the frame on the canvas.
+	Image image.Image
+	// Delay is the time the frame is displayed. GIF stores delays in hundredths of a second.
+	Delay time.Duration
+	// Disposal specifies how the frame area is treated before the next frame is drawn.
+	Disposal Disposal
+	// Transparent is the color that is encoded as transparent. If nil, the color set with
+	// GIFTransparentColor is used. Pixels with alpha below 50% are always transparent
+	// unless the frame image is *image.Paletted.
+	Transparent color.Color
+}
+
+// Animation is a sequence of frames drawn on a canvas.
+type Animation struct {
+	// Frames is the list of frames.
+	Frames []Frame
+	// Width and Height are the size of the canvas. If both are zero, the bounds
+	// of the first frame are used as the canvas and all frames are positioned
+	// relative to it.
+	Width, Height int
+	// LoopCount controls the number of times the animation is played.
+	// 0 means forever, -1 means once, n means n+1 times.
+	LoopCount int
+}
+
+// canvas returns the bounds of the canvas of the animation in the coordinates of the frames.
+func (a *Animation) canvas() image.Rectangle {
+	if a.Width == 0 && a.Height == 0 && len(a.Frames) > 0 {
+		return a.Frames[0].Image.Bounds()
+	}
+	return image.Rect(0, 0, a.Width, a.Height)
+}
+
+// CropAnimation cuts out the rectangular region of the canvas of the animation
+// (in the coordinates of the canvas, starting at 0, 0) and returns the cropped
+// animation, which has the size of the region. The frames stay partial: every frame
+// is clipped to the region, keeping its palette, and its disposal applies to the
+// clipped area, so the cropped animation plays as the region of the original one.
+// The frames outside the region become transparent 1x1 frames, keeping the timing.
+// An empty region gives an animation without frames.
+//
+// Example:
+//
+//	sticker := imaging.CropAnimation(anim, image.Rect(40, 0, 552, 512))
+func CropAnimation(anim *Animation, rect image.Rectangle) *Animation {
+	canvas := anim.canvas()
+	r := rect.Add(canvas.Min).Intersect(canvas)
+	if r.Empty() {
+		return &Animation{LoopCount: anim.LoopCount}
+	}
+	dst := &Animation{
+		Frames:    make([]Frame, len(anim.Frames)),
+		Width:     r.Dx(),
+		Height:    r.Dy(),
+		LoopCount: anim.LoopCount,
+	}
+	for i, f := range anim.Frames {
+		dst.Frames[i] = f
+		inter := f.Image.Bounds().Intersect(r)
+		if inter.Empty() {
+			dst.Frames[i].Image = image.NewPaletted(image.Rect(0, 0, 1, 1), color.Palette{color.NRGBA{}})
+			dst.Frames[i].Disposal = DisposalNone
+			continue
+		}
+		pos := inter.Min.Sub(r.Min)
+		if pm, ok := f.Image.(*image.Paletted); ok {
+			sub := pm.SubImage(inter).(*image.Paletted)
+			c := image.NewPaletted(inter.Sub(r.Min), append(color.Palette(nil), pm.Palette...))
+			for y := 0; y < inter.Dy(); y++ {
+				copy(c.Pix[y*c.Stride:y*c.Stride+inter.Dx()], sub.Pix[y*sub.Stride:])
+			}
+			dst.Frames[i].Image = c
+			continue
+		}
+		c := Crop(f.Image, inter)
+		c.Rect = c.Rect.Add(pos)
+		dst.Frames[i].Image = c
+	}
+	return dst
+}
+
+// ResizeAnimation resizes the canvas of the animation to the specified width and height
+// using the specified resampling filter and returns the resized animation. If one of
+// width or height is 0, the aspect ratio is preserved. An empty size gives an
+// animation without frames.
+//
+// The frames are not flattened: every frame is resized on its own and positioned
+// on the resized canvas, its bounds rounded outwards, so the partial frames and
+// their disposal keep working. The resized *image.Paletted frames are mapped back to
+// their own palettes, with the pixels of the alpha below 50% transparent, so the local
+// color tables of the frames are kept. NearestNeighbor keeps the pixel art and
+// stickers sharp, and resizes them exactly for scale factors that are integers.
+//
+// Example:
+//
+//	sticker := imaging.ResizeAnimation(anim, 512, 512, imaging.Lanczos)
+func ResizeAnimation(anim *Animation, width, height int, filter ResampleFilter) *Animation {
+	canvas := anim.canvas()
+	dstW, dstH := resizeDimensions(canvas.Dx(), canvas.Dy(), width, height)
+	if dstW == 0 {
+		return &Animation{LoopCount: anim.LoopCount}
+	}
+	dst := &Animation{
+		Frames:    make([]Frame, len(anim.Frames)),
+		Width:     dstW,
+		Height:    dstH,
+		LoopCount: anim.LoopCount,
+	}
+	sx := float64(dstW) / float64(canvas.Dx())
+	sy := float64(dstH) / float64(canvas.Dy())
+	for i, f := range anim.Frames {
+		dst.Frames[i] = f
+		b := f.Image.Bounds().Sub(canvas.Min)
+		r := image.Rect(
+			int(math.Floor(float64(b.Min.X)*sx)), int(math.Floor(float64(b.Min.Y)*sy)),
+			int(math.Ceil(float64(b.Max.X)*sx)), int(math.Ceil(float64(b.Max.Y)*sy)),
+		)
+		if r.Empty() {
+			dst.Frames[i].Image = image.NewPaletted(image.Rect(0, 0, 1, 1), color.Palette{color.NRGBA{}})
+			dst.Frames[i].Disposal = DisposalNone
+			continue
+		}
+		resized := Resize(f.Image, r.Dx(), r.Dy(), filter)
+		resized.Rect = resized.Rect.Add(r.Min)
+		if pm, ok := f.Image.(*image.Paletted); ok {
+			dst.Frames[i].Image = paletteFrame(resized, pm.Palette)
+			continue
+		}
+		dst.Frames[i].Image = resized
+	}
+	return dst
+}
+
+// paletteFrame maps the colors of the image to the nearest colors of the palette.
+// The pixels of the alpha below 50% are mapped to the transparent color of the
+// palette, if it has one.
+func paletteFrame(img *image.NRGBA, p color.Palette) *image.Paletted {
+	p = append(color.Palette(nil), p...)
+	transparent := -1
+	opaque := make(color.Palette, 0, len(p))
+	indexes := make([]uint8, 0, len(p))
+	for i, c := range p {
+		if _, _, _, a := c.RGBA(); a == 0 {
+			if transparent < 0 {
+				transparent = i
+			}
+			continue
+		}
+		opaque = append(opaque, c)
+		indexes = append(indexes, uint8(i))
+	}
+	if len(opaque) == 0 {
+		opaque, indexes = p, make([]uint8, len(p))
+		for i := range indexes {
+			indexes[i] = uint8(i)
+		}
+	}
+
+	dst := image.NewPaletted(img.Rect, p)
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	parallel(0, h, func(ys <-chan int) {
+		for y := range ys {
+			for x := 0; x < w; x++ {
+				s := img.Pix[y*img.Stride+x*4 : y*img.Stride+x*4+4]
+				d := &dst.Pix[y*dst.Stride+x]
+				if s[3] < 0x80 && transparent >= 0 {
+					*d = uint8(transparent)
+					continue
+				}
+				*d = indexes[opaque.Index(color.NRGBA{s[0], s[1], s[2], 0xff})]
+			}
+		}
+	})
+	return dst
+}
+
+// OpenAnimation loads an animation from a GIF file.
+func OpenAnimation(filename string) (anim *Animation, err error) {
+	file, err := openFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			if err == nil {
+				err = closeErr
+			} else {
+				err = fmt.Errorf("original error: %s, defer close error: %w", err.Error(), closeErr)
+			}
+		}
+	}()
+	return DecodeAnimation(file)
+}
+
+// DecodeAnimation reads an animation in GIF format from io.Reader.
+// The frame images are of *image.Paletted type.
+func DecodeAnimation(r io.Reader) (*Animation, error) {
+	g, err := gif.DecodeAll(r)
+	if err != nil {
+		return nil, err
+	}
+	anim := &Animation{
+		Frames:    make([]Frame, len(g.Image)),
+		Width:     g.Config.Width,
+		Height:    g.Config.Height,
+		LoopCount: g.LoopCount,
+	}
+	for i, img := range g.Image {
+		anim.Frames[i].Image = img
+		if i < len(g.Delay) {
+			anim.Frames[i].Delay = time.Duration(g.Delay[i]) * 10 * time.Millisecond
+		}
+		if i < len(g.Disposal) {
+			anim.Frames[i].Disposal = Disposal(g.Disposal[i])
+		}
+	}
+	return anim, nil
+}
+
+// EncodeAnimation writes the animation to w in GIF format. The GIF options
+// (GIFNumColors, GIFQuantizer, GIFDrawer, GIFInterlaced and GIFTransparentColor)
+// are applied to every frame.
+func EncodeAnimation(w io.Writer, anim *Animation, opts ...EncodeOption) error {
+	cfg, err := newEncodeConfig(GIF, opts)
+	if err != nil {
+		return err
+	}
+	return encodeGIF(w, anim, &cfg)
+}
+
+// SaveAnimation saves the animation to a GIF file.
+//
+// Example:
+//
+//	anim := &imaging.Animation{Frames: []imaging.Frame{
+//		{Image: frame1, Delay: 100 * time.Millisecond, Disposal: imaging.DisposalBackground},
+//		{Image: frame2, Delay: 100 * time.Millisecond, Disposal: imaging.DisposalBackground},
+//	}}
+//	err := imaging.SaveAnimation(anim, "out.gif", imaging.GIFInterlaced(true))
+func SaveAnimation(anim *Animation, filename string, opts ...EncodeOption) (err error) {
+	f, err := FormatFromFilename(filename)
+	if err != nil {
+		return err
+	}
+	if f != GIF {
+		return fmt.Errorf("%w: animations can not be saved as %s", ErrUnsupportedFormat, f)
+	}
+	if err = ValidateEncodeOptions(GIF, opts...); err != nil {
+		return err
+	}
+	file, err := createFile(filename)
+	if err != nil {
+		return err
+	}
+
+	err = EncodeAnimation(file, anim, opts...)
+	errClose := file.Close()
+	if err == nil {
+		err = errClose
+	}
+	return err
+}
@@ -0,0 +1,241 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEncodeDecodeAnimation(t *testing.T) {
+	t.Parallel()
+
+	frame1 := image.NewPaletted(image.Rect(0, 0, 8, 6), palette.WebSafe)
+	frame2 := image.NewPaletted(image.Rect(2, 1, 6, 5), palette.WebSafe)
+	for i := range frame1.Pix {
+		frame1.Pix[i] = uint8(i)
+	}
+	for i := range frame2.Pix {
+		frame2.Pix[i] = uint8(100 + i)
+	}
+	anim := &Animation{
+		Frames: []Frame{
+			{Image: frame1, Delay: 120 * time.Millisecond, Disposal: DisposalNone},
+			{Image: frame2, Delay: 50 * time.Millisecond, Disposal: DisposalPrevious},
+		},
+		Width:     8,
+		Height:    6,
+		LoopCount: 3,
+	}
+
+	for _, interlaced := range []bool{false, true} {
+		buf := &bytes.Buffer{}
+		if err := EncodeAnimation(buf, anim, GIFInterlaced(interlaced)); err != nil {
+			t.Fatalf("interlaced=%v: failed to encode: %v", interlaced, err)
+		}
+		got, err := DecodeAnimation(buf)
+		if err != nil {
+			t.Fatalf("interlaced=%v: failed to decode: %v", interlaced, err)
+		}
+		if got.Width != 8 || got.Height != 6 || got.LoopCount != 3 || len(got.Frames) != 2 {
+			t.Fatalf("interlaced=%v: got animation %dx%d, loop %d, %d frames",
+				interlaced, got.Width, got.Height, got.LoopCount, len(got.Frames))
+		}
+		for i, f := range got.Frames {
+			want := anim.Frames[i]
+			if f.Delay != want.Delay || f.Disposal != want.Disposal {
+				t.Fatalf("interlaced=%v: frame %d: got delay %v disposal %d", interlaced, i, f.Delay, f.Disposal)
+			}
+			if f.Image.Bounds() != want.Image.Bounds() {
+				t.Fatalf("interlaced=%v: frame %d: got bounds %v want %v", interlaced, i, f.Image.Bounds(), want.Image.Bounds())
+			}
+			if !compareNRGBA(Clone(f.Image), Clone(want.Image), 0) {
+				t.Fatalf("interlaced=%v: frame %d differs", interlaced, i)
+			}
+		}
+	}
+}
+
+func TestEncodeAnimationCanvas(t *testing.T) {
+	t.Parallel()
+
+	frame := image.NewNRGBA(image.Rect(10, 10, 14, 13))
+	for i := range frame.Pix {
+		frame.Pix[i] = 0xff
+	}
+	buf := &bytes.Buffer{}
+	if err := EncodeAnimation(buf, &Animation{Frames: []Frame{{Image: frame}}}); err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	got, err := DecodeAnimation(buf)
+	if err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	if got.Width != 4 || got.Height != 3 || got.Frames[0].Image.Bounds() != image.Rect(0, 0, 4, 3) {
+		t.Fatalf("got canvas %dx%d and frame bounds %v", got.Width, got.Height, got.Frames[0].Image.Bounds())
+	}
+
+	if err := EncodeAnimation(&bytes.Buffer{}, &Animation{}); !errors.Is(err, ErrNoFrames) {
+		t.Fatalf("got error %v want %v", err, ErrNoFrames)
+	}
+}
+
+func TestOpenSaveAnimation(t *testing.T) {
+	dir, err := os.MkdirTemp("", "imaging")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir) //nolint
+
+	anim := &Animation{Frames: []Frame{
+		{Image: New(5, 5, color.NRGBA{0x00, 0x00, 0x00, 0xff}), Delay: time.Second},
+		{Image: New(5, 5, color.NRGBA{0xff, 0xff, 0xff, 0xff}), Delay: time.Second},
+	}}
+	filename := filepath.Join(dir, "anim.gif")
+	if err := SaveAnimation(anim, filename); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+	got, err := OpenAnimation(filename)
+	if err != nil {
+		t.Fatalf("failed to open: %v", err)
+	}
+	if len(got.Frames) != 2 || got.Frames[1].Delay != time.Second {
+		t.Fatalf("got %d frames", len(got.Frames))
+	}
+
+	if err := SaveAnimation(anim, filepath.Join(dir, "anim.png")); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("got error %v want ErrUnsupportedFormat", err)
+	}
+	if _, err := OpenAnimation(filepath.Join(dir, "missing.gif")); err == nil {
+		t.Fatalf("expected error opening a missing file")
+	}
+}
+
+// renderAnimation returns the canvas after drawing every frame of the animation,
+// disposing the previous frames as GIF decoders do.
+func renderAnimation(anim *Animation) []*image.NRGBA {
+	canvas := image.NewNRGBA(anim.canvas())
+	out := make([]*image.NRGBA, len(anim.Frames))
+	for i, f := range anim.Frames {
+		b := f.Image.Bounds()
+		prev := Clone(canvas)
+		draw.Draw(canvas, b, f.Image, b.Min, draw.Over)
+		out[i] = Clone(canvas)
+		switch f.Disposal {
+		case DisposalBackground:
+			draw.Draw(canvas, b, image.Transparent, image.Point{}, draw.Src)
+		case DisposalPrevious:
+			draw.Draw(canvas, b, prev, b.Min.Sub(canvas.Rect.Min), draw.Src)
+		}
+	}
+	return out
+}
+
+// makePartialAnimation returns an animation of partial frames with local palettes
+// and all the disposal methods.
+func makePartialAnimation() *Animation {
+	warm := color.Palette{color.NRGBA{0xff, 0x00, 0x00, 0xff}, color.NRGBA{0xff, 0x80, 0x00, 0xff}, color.NRGBA{0xff, 0xff, 0x00, 0xff}}
+	cool := color.Palette{color.NRGBA{}, color.NRGBA{0x00, 0x00, 0xff, 0xff}, color.NRGBA{0x00, 0xff, 0xff, 0xff}}
+	frame1 := image.NewPaletted(image.Rect(0, 0, 8, 6), warm)
+	for i := range frame1.Pix {
+		frame1.Pix[i] = uint8(i * 7 % len(warm))
+	}
+	frame2 := image.NewPaletted(image.Rect(2, 1, 6, 5), cool)
+	for i := range frame2.Pix {
+		frame2.Pix[i] = uint8(i * 5 % len(cool))
+	}
+	frame3 := New(3, 3, color.NRGBA{0x00, 0x80, 0x00, 0xff})
+	frame3.Rect = frame3.Rect.Add(image.Pt(5, 3))
+	frame4 := image.NewPaletted(image.Rect(0, 0, 2, 2), warm)
+	return &Animation{
+		Frames: []Frame{
+			{Image: frame1, Delay: 100 * time.Millisecond, Disposal: DisposalNone},
+			{Image: frame2, Delay: 100 * time.Millisecond, Disposal: DisposalPrevious},
+			{Image: frame3, Delay: 100 * time.Millisecond, Disposal: DisposalBackground},
+			{Image: frame4, Delay: 200 * time.Millisecond, Disposal: DisposalNone},
+		},
+		Width:     8,
+		Height:    6,
+		LoopCount: 2,
+	}
+}
+
+func TestCropAnimation(t *testing.T) {
+	t.Parallel()
+
+	anim := makePartialAnimation()
+	rect := image.Rect(2, 1, 8, 6)
+	got := CropAnimation(anim, rect)
+	if got.Width != 6 || got.Height != 5 || got.LoopCount != 2 || len(got.Frames) != 4 {
+		t.Fatalf("got animation %dx%d, loop %d, %d frames", got.Width, got.Height, got.LoopCount, len(got.Frames))
+	}
+	want := renderAnimation(anim)
+	for i, frame := range renderAnimation(got) {
+		if !compareNRGBA(frame, Crop(want[i], rect), 0) {
+			t.Fatalf("frame %d: got %#v want %#v", i, frame, Crop(want[i], rect))
+		}
+		if got.Frames[i].Delay != anim.Frames[i].Delay {
+			t.Fatalf("frame %d: got delay %v", i, got.Frames[i].Delay)
+		}
+	}
+	if pm, ok := got.Frames[1].Image.(*image.Paletted); !ok || pm.Rect != image.Rect(0, 0, 4, 4) || len(pm.Palette) != 3 {
+		t.Fatalf("got frame %T with bounds %v", got.Frames[1].Image, got.Frames[1].Image.Bounds())
+	}
+	if got.Frames[2].Disposal != DisposalBackground || got.Frames[2].Image.Bounds() != image.Rect(3, 2, 6, 5) {
+		t.Fatalf("got frame bounds %v disposal %d", got.Frames[2].Image.Bounds(), got.Frames[2].Disposal)
+	}
+	if err := EncodeAnimation(&bytes.Buffer{}, got); err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+
+	if got := CropAnimation(anim, image.Rect(10, 10, 20, 20)); len(got.Frames) != 0 {
+		t.Fatalf("got %d frames want 0", len(got.Frames))
+	}
+}
+
+func TestResizeAnimation(t *testing.T) {
+	t.Parallel()
+
+	anim := makePartialAnimation()
+	got := ResizeAnimation(anim, 16, 0, NearestNeighbor)
+	if got.Width != 16 || got.Height != 12 || got.LoopCount != 2 || len(got.Frames) != 4 {
+		t.Fatalf("got animation %dx%d, loop %d, %d frames", got.Width, got.Height, got.LoopCount, len(got.Frames))
+	}
+	want := renderAnimation(anim)
+	for i, frame := range renderAnimation(got) {
+		if w := Resize(want[i], 16, 12, NearestNeighbor); !compareNRGBA(frame, w, 0) {
+			t.Fatalf("frame %d: got %#v want %#v", i, frame, w)
+		}
+		if got.Frames[i].Disposal != anim.Frames[i].Disposal {
+			t.Fatalf("frame %d: got disposal %d", i, got.Frames[i].Disposal)
+		}
+	}
+
+	got = ResizeAnimation(anim, 5, 4, Lanczos)
+	for i, f := range got.Frames {
+		src, ok := anim.Frames[i].Image.(*image.Paletted)
+		if !ok {
+			continue
+		}
+		pm, ok := f.Image.(*image.Paletted)
+		if !ok || len(pm.Palette) != len(src.Palette) || pm.Palette[1] != src.Palette[1] {
+			t.Fatalf("frame %d: got %T without the local palette", i, f.Image)
+		}
+	}
+	if b := got.Frames[1].Image.Bounds(); b != image.Rect(1, 0, 4, 4) {
+		t.Fatalf("got frame bounds %v want %v", b, image.Rect(1, 0, 4, 4))
+	}
+	if err := EncodeAnimation(&bytes.Buffer{}, got); err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+
+	if got := ResizeAnimation(anim, -1, 0, Linear); len(got.Frames) != 0 {
+		t.Fatalf("got %d frames want 0", len(got.Frames))
+	}
+}
@@ -0,0 +1,96 @@
+package imaging
+
+import (
+	"image"
+	"math"
+	"strings"
+)
+
+// DefaultASCIICharset is the charset of ToASCII from the darkest to the lightest pixels.
+const DefaultASCIICharset = "@%#*+=-:. "
+
+// ToASCII renders the image as text art of the given number of columns, e.g. to preview
+// images in a terminal. The characters of the charset are ordered from the darkest to
+// the lightest pixels; if it is empty, DefaultASCIICharset is used. Charsets of Unicode
+// characters such as "█▓▒░ " work as well. The rows are half as many as the columns of
+// an image of the same aspect ratio, since the characters are about twice as tall as
+// they are wide. The transparent pixels are light. Every row ends with a newline. For
+// the terminals with light text on a dark background, reverse the charset.
+//
+// Example:
+//
+//	fmt.Print(imaging.ToASCII(img, 80, ""))
+func ToASCII(img image.Image, cols int, charset string) string {
+	chars := []rune(charset)
+	if len(chars) == 0 {
+		chars = []rune(DefaultASCIICharset)
+	}
+	lum, w, h := textLuminance(img, cols, 0.5)
+	var sb strings.Builder
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := int(lum[y*w+x]/255*float64(len(chars)-1) + 0.5)
+			sb.WriteRune(chars[i])
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// ToBraille renders the image as text art of Braille patterns of the given number of
+// columns, like ToASCII, but with 2x4 dots per character, four times as many pixels
+// in the same space. The dots are drawn for the dark pixels, separated from the light
+// ones by Otsu's threshold. For the terminals with light text on a dark background,
+// Invert the image first.
+//
+// Example:
+//
+//	fmt.Print(imaging.ToBraille(img, 80))
+func ToBraille(img image.Image, cols int) string {
+	lum, w, h := textLuminance(img, cols*2, 1)
+	var histogram [256]float64
+	for _, l := range lum {
+		histogram[int(l+0.5)]++
+	}
+	threshold := float64(otsuThreshold(histogram))
+
+	// brailleDots are the bits of the dots of the 2x4 cell, by rows.
+	brailleDots := [4][2]rune{{0x01, 0x08}, {0x02, 0x10}, {0x04, 0x20}, {0x40, 0x80}}
+	var sb strings.Builder
+	for y := 0; y < h; y += 4 {
+		for x := 0; x < w; x += 2 {
+			r := rune(0x2800)
+			for dy := 0; dy < 4 && y+dy < h; dy++ {
+				for dx := 0; dx < 2 && x+dx < w; dx++ {
+					if lum[(y+dy)*w+x+dx] <= threshold {
+						r |= brailleDots[dy][dx]
+					}
+				}
+			}
+			sb.WriteRune(r)
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// textLuminance scales the image to the width and the height of the aspect ratio
+// times the pixel aspect (the height of the pixels relative to their width) and
+// returns the luminances (0-255) of the pixels over a white background.
+func textLuminance(img image.Image, width int, aspect float64) ([]float64, int, int) {
+	srcW, srcH := img.Bounds().Dx(), img.Bounds().Dy()
+	if width <= 0 || srcW <= 0 || srcH <= 0 {
+		return nil, 0, 0
+	}
+	height := int(math.Max(1, math.Round(float64(width)*float64(srcH)/float64(srcW)*aspect)))
+	small := ResizeArea(img, width, height)
+	lum := make([]float64, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			p := small.Pix[y*small.Stride+x*4:]
+			a := float64(p[3]) / 255
+			lum[y*width+x] = luminance(p)*a + 255*(1-a)
+		}
+	}
+	return lum, width, height
+}
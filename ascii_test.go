@@ -0,0 +1,63 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestToASCII(t *testing.T) {
+	t.Parallel()
+
+	halves := New(4, 2, color.White)
+	for y := 0; y < 2; y++ {
+		halves.SetNRGBA(0, y, color.NRGBA{0, 0, 0, 0xff})
+		halves.SetNRGBA(1, y, color.NRGBA{0, 0, 0, 0xff})
+	}
+	gray := New(6, 12, color.NRGBA{0x80, 0x80, 0x80, 0xff})
+	testCases := []struct {
+		name    string
+		src     image.Image
+		cols    int
+		charset string
+		want    string
+	}{
+		{"halves", halves, 4, "#.", "##..\n"},
+		{"default charset", halves, 2, "", "@ \n"},
+		{"unicode", gray, 3, "█▓▒░ ", "▒▒▒\n▒▒▒\n▒▒▒\n"},
+		{"transparent", image.NewNRGBA(image.Rect(0, 0, 2, 4)), 2, "#.", "..\n..\n"},
+		{"no columns", halves, 0, "", ""},
+		{"empty", &image.NRGBA{}, 10, "", ""},
+	}
+	for _, tc := range testCases {
+		if got := ToASCII(tc.src, tc.cols, tc.charset); got != tc.want {
+			t.Fatalf("%s: got %q want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestToBraille(t *testing.T) {
+	t.Parallel()
+
+	column := New(2, 4, color.White)
+	for y := 0; y < 4; y++ {
+		column.SetNRGBA(0, y, color.NRGBA{0, 0, 0, 0xff})
+	}
+	testCases := []struct {
+		name string
+		src  image.Image
+		cols int
+		want string
+	}{
+		{"left column", column, 1, "⡇\n"},
+		{"black", New(4, 8, color.Black), 2, "⣿⣿\n⣿⣿\n"},
+		{"white", New(4, 4, color.White), 2, "⠀⠀\n"},
+		{"partial cell", New(2, 2, color.Black), 1, "⠛\n"},
+		{"empty", &image.NRGBA{}, 2, ""},
+	}
+	for _, tc := range testCases {
+		if got := ToBraille(tc.src, tc.cols); got != tc.want {
+			t.Fatalf("%s: got %q want %q", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+)
+
+// autoColor is the type of the AutoColor placeholder.
+type autoColor struct{}
+
+// RGBA implements color.Color interface. AutoColor is transparent black
+// when it is used without being resolved by InferBackground.
+func (autoColor) RGBA() (r, g, b, a uint32) { return 0, 0, 0, 0 }
+
+// AutoColor is a placeholder color that can be passed to the functions that
+// accept a background color (e.g. Rotate). The actual color is inferred from
+// the source image borders using InferBackground.
+//
+// Example:
+//
+//	dstImage := imaging.Rotate(srcImage, 30, imaging.AutoColor)
+var AutoColor color.Color = autoColor{} //nolint
+
+type blurBackground struct{}
+
+// RGBA implements color.Color interface. BlurColor is transparent black
+// when it is used by other functions than FitPad.
+func (blurBackground) RGBA() (r, g, b, a uint32) { return 0, 0, 0, 0 }
+
+// BlurColor is a placeholder color that can be passed to FitPad to fill the
+// padding with a blurred copy of the image scaled to fill the canvas, as the
+// video players do for the portrait videos.
+//
+// Example:
+//
+//	dstImage := imaging.FitPad(srcImage, 1280, 720, imaging.Lanczos, imaging.BlurColor)
+var BlurColor color.Color = blurBackground{} //nolint
+
+// InferBackground samples the border pixels of the image and returns the color
+// that matches the background of the image, e.g. the off-white backdrop of a
+// product shot. The median of each channel is used, so objects that touch the
+// edges of the image do not affect the result much.
+func InferBackground(img image.Image) color.NRGBA {
+	src := newScanner(img)
+	if src.w == 0 || src.h == 0 {
+		return color.NRGBA{}
+	}
+
+	var hist [4][256]int
+	count := 0
+	add := func(pix []uint8) {
+		for i := 0; i+3 < len(pix); i += 4 {
+			hist[0][pix[i]]++
+			hist[1][pix[i+1]]++
+			hist[2][pix[i+2]]++
+			hist[3][pix[i+3]]++
+			count++
+		}
+	}
+
+	row := make([]uint8, src.w*4)
+	src.scan(0, 0, src.w, 1, row)
+	add(row)
+	if src.h > 1 {
+		src.scan(0, src.h-1, src.w, src.h, row)
+		add(row)
+	}
+	if src.h > 2 {
+		col := make([]uint8, (src.h-2)*4)
+		src.scan(0, 1, 1, src.h-1, col)
+		add(col)
+		if src.w > 1 {
+			src.scan(src.w-1, 1, src.w, src.h-1, col)
+			add(col)
+		}
+	}
+
+	var c [4]uint8
+	for ch := range hist {
+		n := 0
+		for v := 0; v < 256; v++ {
+			n += hist[ch][v]
+			if 2*n >= count {
+				c[ch] = uint8(v)
+				break
+			}
+		}
+	}
+	return color.NRGBA{R: c[0], G: c[1], B: c[2], A: c[3]}
+}
+
+// resolveColor returns the color to use as a background for the image,
+// replacing AutoColor with the color inferred from the image.
+func resolveColor(img image.Image, c color.Color) color.NRGBA {
+	if _, ok := c.(autoColor); ok {
+		return InferBackground(img)
+	}
+	return color.NRGBAModel.Convert(c).(color.NRGBA)
+}
@@ -0,0 +1,68 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestInferBackground(t *testing.T) {
+	t.Parallel()
+
+	offWhite := color.NRGBA{0xf4, 0xf2, 0xee, 0xff}
+	product := New(10, 10, offWhite)
+	for y := 2; y < 10; y++ {
+		for x := 3; x < 7; x++ {
+			product.SetNRGBA(x, y, color.NRGBA{0x20, 0x40, 0x80, 0xff})
+		}
+	}
+
+	testCases := []struct {
+		name string
+		img  image.Image
+		want color.NRGBA
+	}{
+		{
+			name: "object touching the bottom edge",
+			img:  product,
+			want: offWhite,
+		},
+		{
+			name: "single pixel",
+			img:  New(1, 1, color.NRGBA{1, 2, 3, 4}),
+			want: color.NRGBA{1, 2, 3, 4},
+		},
+		{
+			name: "gray image",
+			img:  image.NewGray(image.Rect(-2, -2, 3, 3)),
+			want: color.NRGBA{0, 0, 0, 0xff},
+		},
+		{
+			name: "empty image",
+			img:  &image.NRGBA{},
+			want: color.NRGBA{},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if got := InferBackground(tc.img); got != tc.want {
+				t.Fatalf("got %v want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRotateAutoColor(t *testing.T) {
+	t.Parallel()
+
+	bg := color.NRGBA{0x10, 0x80, 0x10, 0xff}
+	src := New(8, 8, bg)
+	got := Rotate(src, 45, AutoColor)
+	if c := got.NRGBAAt(0, 0); c != bg {
+		t.Fatalf("got corner color %v want %v", c, bg)
+	}
+}
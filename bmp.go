@@ -0,0 +1,215 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"io"
+	"math/bits"
+
+	"golang.org/x/image/bmp"
+)
+
+// BMP header sizes and compression types.
+const (
+	bmpFileHeaderLen   = 14
+	bmpInfoHeaderLen   = 40
+	bmpV4InfoHeaderLen = 108
+	bmpV5InfoHeaderLen = 124
+
+	bmpCompressionRGB            = 0
+	bmpCompressionBitfields      = 3
+	bmpCompressionAlphaBitfields = 6
+)
+
+// bmpColorSpaceSRGB is the LCS_sRGB color space type of the BITMAPV4HEADER.
+const bmpColorSpaceSRGB = 0x73524742
+
+// errInvalidBMP means the BMP structure is malformed.
+var errInvalidBMP = errors.New("imaging: invalid BMP structure")
+
+// decodeBMP decodes a BMP image. Unlike the bmp package decoder, it supports
+// 32-bit images with arbitrary bit masks (BI_BITFIELDS and BI_ALPHABITFIELDS)
+// and the alpha channel of 32-bit images with the BITMAPINFOHEADER.
+// Other BMP images are decoded by the bmp package.
+func decodeBMP(r io.Reader) (image.Image, error) {
+	hdr := make([]byte, bmpFileHeaderLen+4)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return nil, err
+	}
+	infoLen := binary.LittleEndian.Uint32(hdr[14:])
+	if infoLen < bmpInfoHeaderLen || infoLen > bmpV5InfoHeaderLen {
+		return bmp.Decode(io.MultiReader(bytes.NewReader(hdr), r))
+	}
+	hdr = append(hdr, make([]byte, infoLen-4)...)
+	if _, err := io.ReadFull(r, hdr[bmpFileHeaderLen+4:]); err != nil {
+		return nil, err
+	}
+
+	bpp := binary.LittleEndian.Uint16(hdr[28:])
+	compression := binary.LittleEndian.Uint32(hdr[30:])
+	if bpp != 32 || binary.LittleEndian.Uint16(hdr[26:]) != 1 ||
+		(compression != bmpCompressionRGB && compression != bmpCompressionBitfields &&
+			compression != bmpCompressionAlphaBitfields) {
+		return bmp.Decode(io.MultiReader(bytes.NewReader(hdr), r))
+	}
+
+	// The masks follow the BITMAPINFOHEADER, they are either a part of
+	// the larger headers or stored right after the 40-byte header.
+	masks := [4]uint32{0xff0000, 0xff00, 0xff, 0xff000000}
+	hasAlpha := infoLen > bmpInfoHeaderLen
+	inferAlpha := !hasAlpha
+	if compression != bmpCompressionRGB {
+		n := 3
+		if compression == bmpCompressionAlphaBitfields || infoLen >= bmpInfoHeaderLen+16 {
+			n = 4
+		}
+		if infoLen < bmpInfoHeaderLen+uint32(4*n) {
+			extra := make([]byte, bmpInfoHeaderLen+4*n-int(infoLen))
+			if _, err := io.ReadFull(r, extra); err != nil {
+				return nil, err
+			}
+			hdr = append(hdr, extra...)
+		}
+		masks[3] = 0
+		for i := 0; i < n; i++ {
+			masks[i] = binary.LittleEndian.Uint32(hdr[bmpFileHeaderLen+bmpInfoHeaderLen+4*i:])
+		}
+		hasAlpha, inferAlpha = masks[3] != 0, false
+	}
+
+	width := int(int32(binary.LittleEndian.Uint32(hdr[18:])))
+	height := int(int32(binary.LittleEndian.Uint32(hdr[22:])))
+	topDown := height < 0
+	if topDown {
+		height = -height
+	}
+	if width < 0 || height < 0 || int64(width)*int64(height) > 1<<28 {
+		return nil, errInvalidBMP
+	}
+
+	offset := int(binary.LittleEndian.Uint32(hdr[10:]))
+	if offset < len(hdr) {
+		return nil, errInvalidBMP
+	}
+	if _, err := io.CopyN(io.Discard, r, int64(offset-len(hdr))); err != nil {
+		return nil, err
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	row := make([]byte, 4*width)
+	anyAlpha := false
+	for y := 0; y < height; y++ {
+		if _, err := io.ReadFull(r, row); err != nil {
+			return nil, err
+		}
+		dy := height - 1 - y
+		if topDown {
+			dy = y
+		}
+		d := dst.Pix[dy*dst.Stride : dy*dst.Stride+4*width]
+		for i := 0; i < len(row); i += 4 {
+			p := binary.LittleEndian.Uint32(row[i:])
+			d[i+0] = bmpMaskValue(p, masks[0])
+			d[i+1] = bmpMaskValue(p, masks[1])
+			d[i+2] = bmpMaskValue(p, masks[2])
+			d[i+3] = 0xff
+			if hasAlpha || inferAlpha {
+				d[i+3] = bmpMaskValue(p, masks[3])
+				anyAlpha = anyAlpha || d[i+3] != 0
+			}
+		}
+	}
+
+	// Many encoders write 32-bit images with the BITMAPINFOHEADER
+	// and zero padding bytes, such images are opaque.
+	if inferAlpha && !anyAlpha {
+		for i := 3; i < len(dst.Pix); i += 4 {
+			dst.Pix[i] = 0xff
+		}
+	}
+	return dst, nil
+}
+
+// decodeBMPConfig decodes the dimensions of a BMP image. The color model is
+// color.NRGBAModel for 32-bit images, otherwise color.RGBAModel.
+func decodeBMPConfig(r io.Reader) (image.Config, error) {
+	hdr := make([]byte, bmpFileHeaderLen+16)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return image.Config{}, err
+	}
+	width := int(int32(binary.LittleEndian.Uint32(hdr[18:])))
+	height := int(int32(binary.LittleEndian.Uint32(hdr[22:])))
+	if height < 0 {
+		height = -height
+	}
+	if width < 0 || height < 0 {
+		return image.Config{}, errInvalidBMP
+	}
+	model := color.RGBAModel
+	if binary.LittleEndian.Uint16(hdr[28:]) == 32 {
+		model = color.NRGBAModel
+	}
+	return image.Config{ColorModel: model, Width: width, Height: height}, nil
+}
+
+// bmpMaskValue extracts the value of the bit mask from the pixel and scales it to 8 bits.
+func bmpMaskValue(p, mask uint32) uint8 {
+	if mask == 0 {
+		return 0
+	}
+	shift := bits.TrailingZeros32(mask)
+	n := bits.OnesCount32(mask)
+	v := (p & mask) >> shift
+	if n >= 8 {
+		return uint8(v >> (n - 8))
+	}
+	return uint8(v * 255 / (1<<n - 1))
+}
+
+// encodeBMP writes the image to w in BMP format. Images with transparent pixels
+// are written as 32-bit BMP with a BITMAPV4HEADER and an alpha bit mask,
+// other images are encoded by the bmp package.
+func encodeBMP(w io.Writer, img image.Image) error {
+	src := toNRGBA(img)
+	if src.Opaque() {
+		return bmp.Encode(w, img)
+	}
+
+	width, height := src.Rect.Dx(), src.Rect.Dy()
+	imageSize := 4 * width * height
+	hdr := make([]byte, bmpFileHeaderLen+bmpV4InfoHeaderLen)
+	le := binary.LittleEndian
+	hdr[0], hdr[1] = 'B', 'M'
+	le.PutUint32(hdr[2:], uint32(len(hdr)+imageSize))
+	le.PutUint32(hdr[10:], uint32(len(hdr)))
+	le.PutUint32(hdr[14:], bmpV4InfoHeaderLen)
+	le.PutUint32(hdr[18:], uint32(width))
+	le.PutUint32(hdr[22:], uint32(height))
+	le.PutUint16(hdr[26:], 1)
+	le.PutUint16(hdr[28:], 32)
+	le.PutUint32(hdr[30:], bmpCompressionBitfields)
+	le.PutUint32(hdr[34:], uint32(imageSize))
+	le.PutUint32(hdr[54:], 0xff0000)
+	le.PutUint32(hdr[58:], 0xff00)
+	le.PutUint32(hdr[62:], 0xff)
+	le.PutUint32(hdr[66:], 0xff000000)
+	le.PutUint32(hdr[70:], bmpColorSpaceSRGB)
+	if _, err := w.Write(hdr); err != nil {
+		return err
+	}
+
+	row := make([]byte, 4*width)
+	for y := height - 1; y >= 0; y-- {
+		s := src.Pix[y*src.Stride : y*src.Stride+4*width]
+		for i := 0; i < len(row); i += 4 {
+			row[i+0], row[i+1], row[i+2], row[i+3] = s[i+2], s[i+1], s[i+0], s[i+3]
+		}
+		if _, err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
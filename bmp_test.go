@@ -0,0 +1,171 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"testing"
+
+	"golang.org/x/image/bmp"
+)
+
+// makeBMP32 builds a bottom-up 32-bit BMP with the given info header length,
+// compression and bit masks (stored after the BITMAPINFOHEADER).
+func makeBMP32(infoLen, compression uint32, masks []uint32, pix []uint32, w, h int) []byte {
+	le := binary.LittleEndian
+	maskLen := 0
+	if infoLen == bmpInfoHeaderLen {
+		maskLen = 4 * len(masks)
+	}
+	offset := bmpFileHeaderLen + int(infoLen) + maskLen
+	data := make([]byte, offset+4*len(pix))
+	data[0], data[1] = 'B', 'M'
+	le.PutUint32(data[2:], uint32(len(data)))
+	le.PutUint32(data[10:], uint32(offset))
+	le.PutUint32(data[14:], infoLen)
+	le.PutUint32(data[18:], uint32(w))
+	le.PutUint32(data[22:], uint32(h))
+	le.PutUint16(data[26:], 1)
+	le.PutUint16(data[28:], 32)
+	le.PutUint32(data[30:], compression)
+	for i, m := range masks {
+		le.PutUint32(data[bmpFileHeaderLen+bmpInfoHeaderLen+4*i:], m)
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			le.PutUint32(data[offset+4*((h-1-y)*w+x):], pix[y*w+x])
+		}
+	}
+	return data
+}
+
+func TestDecodeBMP(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		data []byte
+		want []uint8
+	}{
+		{
+			name: "BITMAPINFOHEADER with alpha",
+			data: makeBMP32(bmpInfoHeaderLen, bmpCompressionRGB, nil, []uint32{0x80ff0000, 0x0000ff00}, 2, 1),
+			want: []uint8{0xff, 0x00, 0x00, 0x80, 0x00, 0xff, 0x00, 0x00},
+		},
+		{
+			name: "BITMAPINFOHEADER with zero padding",
+			data: makeBMP32(bmpInfoHeaderLen, bmpCompressionRGB, nil, []uint32{0x00ff0000, 0x000000ff}, 2, 1),
+			want: []uint8{0xff, 0x00, 0x00, 0xff, 0x00, 0x00, 0xff, 0xff},
+		},
+		{
+			name: "BI_ALPHABITFIELDS RGBA masks",
+			data: makeBMP32(bmpInfoHeaderLen, bmpCompressionAlphaBitfields,
+				[]uint32{0xff000000, 0x00ff0000, 0x0000ff00, 0x000000ff}, []uint32{0x11223344, 0xaabbcc00}, 1, 2),
+			want: []uint8{0x11, 0x22, 0x33, 0x44, 0xaa, 0xbb, 0xcc, 0x00},
+		},
+		{
+			name: "BI_BITFIELDS without alpha mask",
+			data: makeBMP32(bmpInfoHeaderLen, bmpCompressionBitfields,
+				[]uint32{0x3ff00000, 0x000ffc00, 0x000003ff}, []uint32{0x3ff003ff}, 1, 1),
+			want: []uint8{0xff, 0x00, 0xff, 0xff},
+		},
+		{
+			name: "BITMAPV4HEADER with custom masks",
+			data: func() []byte {
+				d := makeBMP32(bmpV4InfoHeaderLen, bmpCompressionBitfields, nil, []uint32{0x0f0f0f0f}, 1, 1)
+				for i, m := range []uint32{0x0000000f, 0x00000f00, 0x000f0000, 0x0f000000} {
+					binary.LittleEndian.PutUint32(d[bmpFileHeaderLen+bmpInfoHeaderLen+4*i:], m)
+				}
+				return d
+			}(),
+			want: []uint8{0xff, 0xff, 0xff, 0xff},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			img, err := Decode(bytes.NewReader(tc.data))
+			if err != nil {
+				t.Fatalf("failed to decode: %v", err)
+			}
+			got := toNRGBA(img)
+			if !compareBytes(got.Pix, tc.want, 0) {
+				t.Fatalf("got pixels %v want %v", got.Pix, tc.want)
+			}
+		})
+	}
+}
+
+func TestDecodeBMPErrors(t *testing.T) {
+	t.Parallel()
+
+	valid := makeBMP32(bmpInfoHeaderLen, bmpCompressionRGB, nil, []uint32{1, 2, 3, 4}, 2, 2)
+	badOffset := append([]byte(nil), valid...)
+	binary.LittleEndian.PutUint32(badOffset[10:], 4)
+
+	testCases := map[string][]byte{
+		"truncated header": valid[:20],
+		"truncated pixels": valid[:len(valid)-1],
+		"bad offset":       badOffset,
+	}
+	for name, data := range testCases {
+		if _, err := Decode(bytes.NewReader(data)); err == nil {
+			t.Fatalf("%s: expected error got nil", name)
+		}
+	}
+}
+
+func TestEncodeBMPAlpha(t *testing.T) {
+	t.Parallel()
+
+	src := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	copy(src.Pix, []uint8{
+		0xff, 0x00, 0x00, 0xff, 0x00, 0xff, 0x00, 0x80, 0x00, 0x00, 0xff, 0x00,
+		0x10, 0x20, 0x30, 0x40, 0x50, 0x60, 0x70, 0x80, 0x90, 0xa0, 0xb0, 0xc0,
+	})
+
+	images := map[string]image.Image{
+		"NRGBA":   src,
+		"NRGBA64": Clone64(src),
+	}
+	for name, img := range images {
+		want := Clone(img)
+		buf := &bytes.Buffer{}
+		if err := Encode(buf, img, BMP); err != nil {
+			t.Fatalf("%s: failed to encode: %v", name, err)
+		}
+		if got := binary.LittleEndian.Uint32(buf.Bytes()[14:]); got != bmpV4InfoHeaderLen {
+			t.Fatalf("%s: got info header length %d want %d", name, got, bmpV4InfoHeaderLen)
+		}
+
+		decoded, err := Decode(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("%s: failed to decode: %v", name, err)
+		}
+		if !compareNRGBA(toNRGBA(decoded), want, 0) {
+			t.Fatalf("%s: decoded image differs", name)
+		}
+
+		// The output must be readable by other decoders as well.
+		decoded, err = bmp.Decode(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("%s: bmp package failed to decode: %v", name, err)
+		}
+		if !compareNRGBA(Clone(decoded), want, 0) {
+			t.Fatalf("%s: image decoded by the bmp package differs", name)
+		}
+	}
+
+	opaque := New(4, 4, color.NRGBA{0x10, 0x20, 0x30, 0xff})
+	buf := &bytes.Buffer{}
+	if err := Encode(buf, opaque, BMP); err != nil {
+		t.Fatalf("failed to encode opaque image: %v", err)
+	}
+	if got := binary.LittleEndian.Uint16(buf.Bytes()[28:]); got != 24 {
+		t.Fatalf("got %d bits per pixel for opaque image want 24", got)
+	}
+}
@@ -0,0 +1,706 @@
+package imaging
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// ErrInvalidC2PASigner means the C2PA signer has no certificates or an unsupported key.
+var ErrInvalidC2PASigner = errors.New("imaging: invalid C2PA signer")
+
+// C2PA action names of the common edits, see the C2PA specification for the others.
+const (
+	C2PACreated          = "c2pa.created"
+	C2PAEdited           = "c2pa.edited"
+	C2PAResized          = "c2pa.resized"
+	C2PACropped          = "c2pa.cropped"
+	C2PAOrientation      = "c2pa.orientation"
+	C2PAColorAdjustments = "c2pa.color_adjustments"
+	C2PAFiltered         = "c2pa.filtered"
+	C2PAConverted        = "c2pa.converted"
+)
+
+// C2PA holds the C2PA (Content Credentials) manifest store of a JPEG or PNG image,
+// the provenance records of the image signed by the software that created or edited it.
+// The signatures and the hashes of the image data are not validated when it is read;
+// validate them with a C2PA validator before trusting the records.
+type C2PA struct {
+	// Manifests are the manifests of the store, the active manifest of the image last.
+	Manifests []C2PAManifest
+	// Store is the JUMBF encoded manifest store.
+	Store []byte
+}
+
+// Active returns the active manifest, describing the latest change of the image,
+// or nil if there is none.
+func (c *C2PA) Active() *C2PAManifest {
+	if c == nil || len(c.Manifests) == 0 {
+		return nil
+	}
+	return &c.Manifests[len(c.Manifests)-1]
+}
+
+// C2PAManifest is the claim of a C2PA manifest along with the recorded actions.
+type C2PAManifest struct {
+	// Label identifies the manifest, e.g. "urn:uuid:…". It is generated when empty.
+	Label string
+	// ClaimGenerator names the software that signed the claim, e.g. "imaging/1.0".
+	// The default is "imaging".
+	ClaimGenerator string
+	// Title is the title of the image, e.g. its file name.
+	Title string
+	// Format is the media type of the image, e.g. "image/jpeg". It is set on output.
+	Format string
+	// InstanceID identifies the image. It is generated when empty.
+	InstanceID string
+	// Actions are the actions of the c2pa.actions assertion, in order.
+	Actions []C2PAAction
+	// Assertions are the labels of all the assertions of the manifest, e.g.
+	// "c2pa.actions" and "c2pa.hash.data". It is ignored on output.
+	Assertions []string
+}
+
+// C2PAAction is an action applied to the image, recorded in a C2PA manifest.
+type C2PAAction struct {
+	// Action is the name of the action, e.g. C2PAResized.
+	Action string
+	// SoftwareAgent names the software that applied the action.
+	SoftwareAgent string
+	// When is the time of the action, zero if it is not recorded.
+	When time.Time
+	// Description describes the action, e.g. "fit 800x600".
+	Description string
+}
+
+// C2PASigner signs the C2PA manifests written by WithC2PA.
+type C2PASigner struct {
+	// Key is the private key of the signing certificate: an ECDSA key of the P-256,
+	// P-384 or P-521 curve, an Ed25519 key or an RSA key (signing with RSASSA-PSS).
+	// Any crypto.Signer works, e.g. a key kept in a hardware security module.
+	Key crypto.Signer
+	// Certificates holds the DER encoded certificate chain, the signing certificate first.
+	// Validators only trust the certificates issued for signing C2PA manifests.
+	Certificates [][]byte
+}
+
+// c2paOutput is the manifest and the signer of WithC2PA.
+type c2paOutput struct {
+	manifest *C2PAManifest
+	signer   *C2PASigner
+}
+
+// WithC2PA returns an EncodeOption that attaches a C2PA manifest signed by the signer
+// to JPEG and PNG images, recording the actions of the manifest, e.g. the operations
+// of a Pipeline returned by C2PAActions. The manifest is bound to the image by the
+// hash of the encoded file, so any change of the file invalidates it. The manifests
+// of the source image are not carried over, and other formats are written without
+// a manifest.
+//
+// Example:
+//
+//	signer := &imaging.C2PASigner{Key: key, Certificates: [][]byte{cert.Raw, ca.Raw}}
+//	manifest := &imaging.C2PAManifest{ClaimGenerator: "thumbnailer/2.1", Actions: p.C2PAActions()}
+//	err := imaging.Save(img, "out.jpg", imaging.WithC2PA(manifest, signer))
+func WithC2PA(manifest *C2PAManifest, signer *C2PASigner) EncodeOption {
+	return func(c *encodeConfig) {
+		c.c2pa = &c2paOutput{manifest: manifest, signer: signer}
+	}
+}
+
+// C2PAActions returns the pipeline operations as C2PA actions for WithC2PA, named by
+// the first word of the step names: "resize", "fit", "fill" and "thumbnail" are
+// resized, "crop" is cropped, "rotate", "flip", "transpose" and "transverse" change
+// the orientation, "blur" and "sharpen" are filtered, "adjust", "brightness", "contrast",
+// "gamma", "saturation", "hue", "grayscale" and "invert" are color adjustments, and
+// the others are edited. The step names are the descriptions of the actions.
+func (p *Pipeline) C2PAActions() []C2PAAction {
+	actions := make([]C2PAAction, 0, len(p.steps))
+	for _, s := range p.steps {
+		var word string
+		if fields := strings.Fields(s.name); len(fields) > 0 {
+			word = strings.ToLower(fields[0])
+		}
+		action := C2PAEdited
+		switch word {
+		case "resize", "fit", "fill", "thumbnail":
+			action = C2PAResized
+		case "crop":
+			action = C2PACropped
+		case "rotate", "flip", "fliph", "flipv", "transpose", "transverse":
+			action = C2PAOrientation
+		case "blur", "sharpen":
+			action = C2PAFiltered
+		case "adjust", "brightness", "contrast", "gamma", "saturation", "hue", "grayscale", "invert":
+			action = C2PAColorAdjustments
+		}
+		actions = append(actions, C2PAAction{Action: action, Description: s.name})
+	}
+	return actions
+}
+
+// JUMBF box types of the C2PA manifest store, the first 4 bytes of their UUIDs.
+const (
+	c2paStoreType      = "c2pa"
+	c2paManifestType   = "c2ma"
+	c2paAssertionsType = "c2as"
+	c2paClaimType      = "c2cl"
+	c2paSignatureType  = "c2cs"
+	c2paCBORType       = "cbor"
+)
+
+// jumbfUUIDSuffix is the common suffix of the UUIDs of the JUMBF box types.
+const jumbfUUIDSuffix = "\x00\x11\x00\x10\x80\x00\x00\xaa\x00\x38\x9b\x71"
+
+// c2paPNGChunk is the type of the PNG chunk holding the C2PA manifest store.
+const c2paPNGChunk = "caBX"
+
+// jpegXTHeader starts the JPEG XT boxes of the APP11 segments.
+const jpegXTHeader = "JP"
+
+// jumbfBox is a box of the JPEG universal metadata box format (JUMBF, ISO 19566-5).
+type jumbfBox struct {
+	// typ is the box type, e.g. "jumb" for the superboxes.
+	typ string
+	// payload is the box content after the header.
+	payload []byte
+	// kind is the first 4 bytes of the UUID of the superbox type.
+	kind string
+	// label is the label of the superbox.
+	label string
+	// boxes are the boxes of the superbox after the description box.
+	boxes []*jumbfBox
+}
+
+// child returns the superbox of the superbox with the label, or nil.
+func (b *jumbfBox) child(label string) *jumbfBox {
+	for _, c := range b.boxes {
+		if c.typ == "jumb" && c.label == label {
+			return c
+		}
+	}
+	return nil
+}
+
+// cbor decodes the CBOR content box of the superbox.
+func (b *jumbfBox) cbor() (any, error) {
+	for _, c := range b.boxes {
+		if c.typ == "cbor" {
+			return decodeCBOR(c.payload)
+		}
+	}
+	return nil, errInvalidCBOR
+}
+
+// parseJUMBF parses the boxes of the data.
+func parseJUMBF(data []byte, depth int) ([]*jumbfBox, error) {
+	if depth > 16 {
+		return nil, errors.New("imaging: JUMBF boxes nested too deep")
+	}
+	var boxes []*jumbfBox
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return nil, errors.New("imaging: truncated JUMBF box")
+		}
+		size, header := uint64(binary.BigEndian.Uint32(data)), 8
+		switch size {
+		case 0:
+			size = uint64(len(data))
+		case 1:
+			if len(data) < 16 {
+				return nil, errors.New("imaging: truncated JUMBF box")
+			}
+			size, header = binary.BigEndian.Uint64(data[8:]), 16
+		}
+		if size < uint64(header) || size > uint64(len(data)) {
+			return nil, errors.New("imaging: invalid JUMBF box size")
+		}
+		b := &jumbfBox{typ: string(data[4:8]), payload: data[header:size]}
+		if b.typ == "jumb" {
+			children, err := parseJUMBF(b.payload, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			if len(children) == 0 || children[0].typ != "jumd" || len(children[0].payload) < 17 {
+				return nil, errors.New("imaging: JUMBF superbox without description")
+			}
+			d := children[0].payload
+			b.kind = string(d[:4])
+			if d[16]&0x02 != 0 {
+				label := d[17:]
+				if i := bytes.IndexByte(label, 0); i >= 0 {
+					label = label[:i]
+				}
+				b.label = string(label)
+			}
+			b.boxes = children[1:]
+		}
+		boxes = append(boxes, b)
+		data = data[size:]
+	}
+	return boxes, nil
+}
+
+// readC2PA returns the C2PA manifest store of the JPEG or PNG image, or nil if there
+// is none or it is broken.
+func readC2PA(data []byte) *C2PA {
+	var store []byte
+	switch {
+	case isJPEG(data):
+		store = readJPEGC2PA(data)
+	case isPNG(data):
+		store = readPNGChunk(data, c2paPNGChunk)
+	}
+	if store == nil {
+		return nil
+	}
+	c, err := parseC2PA(store)
+	if err != nil {
+		return nil
+	}
+	return c
+}
+
+// readJPEGC2PA joins the JPEG XT boxes of the APP11 segments of the JPEG image and
+// returns the C2PA manifest store box, or nil. Every segment holds the box instance
+// and the sequence numbers, and repeats the box header after the first one.
+func readJPEGC2PA(data []byte) []byte {
+	boxes := map[uint16][]byte{}
+	next := map[uint16]uint32{}
+	walkJPEGSegments(data, func(marker byte, payload []byte) bool {
+		if marker != markerAPP11 || len(payload) < 16 || string(payload[:2]) != jpegXTHeader {
+			return true
+		}
+		en, z := binary.BigEndian.Uint16(payload[2:]), binary.BigEndian.Uint32(payload[4:])
+		switch {
+		case z == 1:
+			boxes[en] = append([]byte(nil), payload[8:]...)
+			next[en] = 2
+		case next[en] == z:
+			boxes[en] = append(boxes[en], payload[16:]...)
+			next[en]++
+		}
+		return true
+	})
+	for _, box := range boxes {
+		if len(box) >= 8 && string(box[4:8]) == "jumb" && int(binary.BigEndian.Uint32(box)) == len(box) {
+			if b, err := parseJUMBF(box, 0); err == nil && b[0].kind == c2paStoreType {
+				return box
+			}
+		}
+	}
+	return nil
+}
+
+// readPNGChunk returns the data of the first chunk of the type of the PNG image, or nil.
+func readPNGChunk(data []byte, typ string) []byte {
+	pos := len(pngHeader)
+	for pos+12 <= len(data) {
+		size := int(binary.BigEndian.Uint32(data[pos:]))
+		if size < 0 || pos+12+size > len(data) {
+			return nil
+		}
+		if string(data[pos+4:pos+8]) == typ {
+			return data[pos+8 : pos+8+size]
+		}
+		pos += 12 + size
+	}
+	return nil
+}
+
+// parseC2PA parses the JUMBF manifest store.
+func parseC2PA(store []byte) (*C2PA, error) {
+	boxes, err := parseJUMBF(store, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(boxes) != 1 || boxes[0].kind != c2paStoreType {
+		return nil, errors.New("imaging: not a C2PA manifest store")
+	}
+	c := &C2PA{Store: store}
+	for _, b := range boxes[0].boxes {
+		if b.typ != "jumb" || b.kind != c2paManifestType {
+			continue
+		}
+		m, err := parseC2PAManifest(b)
+		if err != nil {
+			return nil, err
+		}
+		c.Manifests = append(c.Manifests, m)
+	}
+	return c, nil
+}
+
+// parseC2PAManifest parses the claim and the actions of the manifest box.
+func parseC2PAManifest(b *jumbfBox) (C2PAManifest, error) {
+	m := C2PAManifest{Label: b.label}
+	claimBox := b.child("c2pa.claim")
+	if claimBox == nil {
+		return m, fmt.Errorf("imaging: C2PA manifest %s without claim", b.label)
+	}
+	v, err := claimBox.cbor()
+	if err != nil {
+		return m, err
+	}
+	claim, _ := v.(map[any]any)
+	m.ClaimGenerator, _ = claim["claim_generator"].(string)
+	if infos, ok := claim["claim_generator_info"].([]any); ok && m.ClaimGenerator == "" && len(infos) > 0 {
+		if info, ok := infos[0].(map[any]any); ok {
+			m.ClaimGenerator, _ = info["name"].(string)
+		}
+	}
+	m.Title, _ = claim["dc:title"].(string)
+	m.Format, _ = claim["dc:format"].(string)
+	m.InstanceID, _ = claim["instanceID"].(string)
+
+	assertions := b.child("c2pa.assertions")
+	if assertions == nil {
+		return m, nil
+	}
+	for _, a := range assertions.boxes {
+		if a.typ != "jumb" {
+			continue
+		}
+		m.Assertions = append(m.Assertions, a.label)
+		if !strings.HasPrefix(a.label, "c2pa.actions") {
+			continue
+		}
+		v, err := a.cbor()
+		if err != nil {
+			return m, err
+		}
+		list, _ := v.(map[any]any)
+		actions, _ := list["actions"].([]any)
+		for _, item := range actions {
+			action, ok := item.(map[any]any)
+			if !ok {
+				continue
+			}
+			var act C2PAAction
+			act.Action, _ = action["action"].(string)
+			act.SoftwareAgent, _ = action["softwareAgent"].(string)
+			if agent, ok := action["softwareAgent"].(map[any]any); ok {
+				act.SoftwareAgent, _ = agent["name"].(string)
+			}
+			if when, ok := action["when"].(string); ok {
+				act.When, _ = time.Parse(time.RFC3339, when)
+			}
+			if params, ok := action["parameters"].(map[any]any); ok {
+				act.Description, _ = params["description"].(string)
+			}
+			m.Actions = append(m.Actions, act)
+		}
+	}
+	return m, nil
+}
+
+// embedC2PA writes the JPEG or PNG image data to w with the signed manifest store
+// inserted: after the application segments of the JPEG image, or before the image
+// data chunks of the PNG image. The data hash of the manifest excludes the inserted
+// bytes, whose size depends on the manifest, so the manifest is rebuilt until the
+// size is stable. If deterministic is true, the identifiers are derived from the
+// hash of the data and the times of the actions are removed.
+func embedC2PA(w io.Writer, data []byte, format Format, out *c2paOutput, deterministic bool) error {
+	m := *out.manifest
+	hash := sha256.Sum256(data)
+	newID := func(string) string { return newUUID() }
+	if deterministic {
+		newID = func(name string) string { return hashUUID(hash[:], name) }
+		m.Actions = append([]C2PAAction(nil), m.Actions...)
+		for i := range m.Actions {
+			m.Actions[i].When = time.Time{}
+		}
+	}
+	var pos int
+	var wrap func([]byte) []byte
+	switch format {
+	case JPEG:
+		m.Format, pos, wrap = "image/jpeg", jpegAppSegmentsEnd(data), jpegC2PASegments
+	case PNG:
+		m.Format, pos, wrap = "image/png", pngChunkPos(data, "IDAT"), pngC2PAChunk
+	}
+	if pos < 0 {
+		return errInvalidPNG
+	}
+	if m.Label == "" {
+		m.Label = "urn:uuid:" + newID("label")
+	}
+	if m.InstanceID == "" {
+		m.InstanceID = "xmp:iid:" + newID("instance")
+	}
+	if m.ClaimGenerator == "" {
+		m.ClaimGenerator = "imaging"
+	}
+
+	var inserted []byte
+	for n := 0; n < 8; n++ {
+		store, err := buildC2PAStore(&m, out.signer, hash[:], pos, len(inserted))
+		if err != nil {
+			return err
+		}
+		size := len(inserted)
+		inserted = wrap(store)
+		if len(inserted) == size {
+			break
+		}
+	}
+	for _, part := range [][]byte{data[:pos], inserted, data[pos:]} {
+		if _, err := w.Write(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jpegAppSegmentsEnd returns the position after the SOI marker and the application
+// segments following it.
+func jpegAppSegmentsEnd(data []byte) int {
+	pos := 2
+	for pos+4 <= len(data) && data[pos] == 0xff && data[pos+1] >= 0xe0 && data[pos+1] <= 0xef {
+		pos += 2 + int(binary.BigEndian.Uint16(data[pos+2:]))
+	}
+	return pos
+}
+
+// pngChunkPos returns the position of the first chunk of the type, or -1.
+func pngChunkPos(data []byte, typ string) int {
+	pos := len(pngHeader)
+	for pos+12 <= len(data) {
+		if string(data[pos+4:pos+8]) == typ {
+			return pos
+		}
+		pos += 12 + int(binary.BigEndian.Uint32(data[pos:]))
+	}
+	return -1
+}
+
+// jpegC2PASegments splits the manifest store box into the APP11 segments of JPEG XT
+// boxes: "JP", the box instance number 1, the sequence number from 1 and the box
+// data, with the box header repeated in the segments after the first one.
+func jpegC2PASegments(box []byte) []byte {
+	const overhead = 2 + 2 + 2 + 2 + 4
+	var out []byte
+	header, body := box[:8], box[8:]
+	for seq := uint32(1); seq == 1 || len(body) > 0; seq++ {
+		n := 0xffff + 2 - overhead - len(header)
+		if n > len(body) {
+			n = len(body)
+		}
+		segment := []byte{0xff, markerAPP11, 0, 0}
+		segment = append(segment, jpegXTHeader...)
+		segment = binary.BigEndian.AppendUint16(segment, 1)
+		segment = binary.BigEndian.AppendUint32(segment, seq)
+		segment = append(segment, header...)
+		segment = append(segment, body[:n]...)
+		binary.BigEndian.PutUint16(segment[2:], uint16(len(segment)-2))
+		out = append(out, segment...)
+		body = body[n:]
+	}
+	return out
+}
+
+// pngC2PAChunk returns the caBX chunk holding the manifest store.
+func pngC2PAChunk(store []byte) []byte {
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(store)))
+	chunk = append(chunk, c2paPNGChunk...)
+	chunk = append(chunk, store...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+// buildC2PAStore returns the manifest store of the manifest signed by the signer,
+// binding it to the image data with the hash excluding length bytes from start.
+func buildC2PAStore(m *C2PAManifest, signer *C2PASigner, hash []byte, start, length int) ([]byte, error) {
+	var assertions [][]byte
+	var refs []any
+	add := func(label string, content any) {
+		box := jumbfSuperbox(c2paCBORType, label, jumbfContentBox("cbor", encodeCBOR(content)))
+		sum := sha256.Sum256(box[8:])
+		refs = append(refs, cborPairs{
+			{"url", "self#jumbf=c2pa.assertions/" + label},
+			{"hash", sum[:]},
+		})
+		assertions = append(assertions, box)
+	}
+	if len(m.Actions) > 0 {
+		actions := make([]any, len(m.Actions))
+		for i, a := range m.Actions {
+			action := cborPairs{{"action", a.Action}}
+			if a.SoftwareAgent != "" {
+				action = append(action, cborPair{"softwareAgent", a.SoftwareAgent})
+			}
+			if !a.When.IsZero() {
+				action = append(action, cborPair{"when", a.When.UTC().Format(time.RFC3339)})
+			}
+			if a.Description != "" {
+				action = append(action, cborPair{"parameters", cborPairs{{"description", a.Description}}})
+			}
+			actions[i] = action
+		}
+		add("c2pa.actions", cborPairs{{"actions", actions}})
+	}
+	add("c2pa.hash.data", cborPairs{
+		{"exclusions", []any{cborPairs{{"start", start}, {"length", length}}}},
+		{"name", "jumbf manifest"},
+		{"alg", "sha256"},
+		{"hash", hash},
+		{"pad", []byte{}},
+	})
+
+	claim := cborPairs{
+		{"claim_generator", m.ClaimGenerator},
+		{"signature", "self#jumbf=c2pa.signature"},
+		{"assertions", refs},
+		{"dc:format", m.Format},
+		{"instanceID", m.InstanceID},
+		{"alg", "sha256"},
+	}
+	if m.Title != "" {
+		claim = append(claim, cborPair{"dc:title", m.Title})
+	}
+	claimData := encodeCBOR(claim)
+	signature, err := signCOSE(signer, claimData)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := jumbfSuperbox(c2paManifestType, m.Label,
+		jumbfSuperbox(c2paAssertionsType, "c2pa.assertions", assertions...),
+		jumbfSuperbox(c2paClaimType, "c2pa.claim", jumbfContentBox("cbor", claimData)),
+		jumbfSuperbox(c2paSignatureType, "c2pa.signature", jumbfContentBox("cbor", signature)),
+	)
+	return jumbfSuperbox(c2paStoreType, "c2pa", manifest), nil
+}
+
+// jumbfSuperbox returns the superbox of the type and the label holding the boxes.
+func jumbfSuperbox(kind, label string, boxes ...[]byte) []byte {
+	desc := append([]byte(kind+jumbfUUIDSuffix), 0x03)
+	desc = append(desc, label...)
+	desc = append(desc, 0)
+	content := jumbfContentBox("jumd", desc)
+	for _, b := range boxes {
+		content = append(content, b...)
+	}
+	return jumbfContentBox("jumb", content)
+}
+
+// jumbfContentBox returns the box of the type holding the payload.
+func jumbfContentBox(typ string, payload []byte) []byte {
+	box := binary.BigEndian.AppendUint32(nil, uint32(8+len(payload)))
+	box = append(box, typ...)
+	return append(box, payload...)
+}
+
+// COSE header labels and algorithms used by the C2PA signatures.
+const (
+	coseAlg     = 1
+	coseX5Chain = 33
+	coseSign1   = 18
+	coseES256   = -7
+	coseES384   = -35
+	coseES512   = -36
+	coseEdDSA   = -8
+	cosePS256   = -37
+)
+
+// signCOSE returns the COSE_Sign1 signature of the claim with a detached payload,
+// carrying the certificate chain in the protected header.
+func signCOSE(signer *C2PASigner, claim []byte) ([]byte, error) {
+	if signer == nil || signer.Key == nil || len(signer.Certificates) == 0 {
+		return nil, fmt.Errorf("%w: no key or certificates", ErrInvalidC2PASigner)
+	}
+	var alg int
+	var hash crypto.Hash
+	var opts crypto.SignerOpts
+	var curveSize int
+	switch pub := signer.Key.Public().(type) {
+	case *ecdsa.PublicKey:
+		switch pub.Curve {
+		case elliptic.P256():
+			alg, hash = coseES256, crypto.SHA256
+		case elliptic.P384():
+			alg, hash = coseES384, crypto.SHA384
+		case elliptic.P521():
+			alg, hash = coseES512, crypto.SHA512
+		default:
+			return nil, fmt.Errorf("%w: unsupported curve %s", ErrInvalidC2PASigner, pub.Curve.Params().Name)
+		}
+		opts, curveSize = hash, (pub.Curve.Params().BitSize+7)/8
+	case ed25519.PublicKey:
+		alg, opts = coseEdDSA, crypto.Hash(0)
+	case *rsa.PublicKey:
+		alg, hash = cosePS256, crypto.SHA256
+		opts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: hash}
+	default:
+		return nil, fmt.Errorf("%w: unsupported key %T", ErrInvalidC2PASigner, pub)
+	}
+
+	var chain any = signer.Certificates[0]
+	if len(signer.Certificates) > 1 {
+		certs := make([]any, len(signer.Certificates))
+		for i, c := range signer.Certificates {
+			certs[i] = c
+		}
+		chain = certs
+	}
+	protected := encodeCBOR(cborPairs{{coseAlg, alg}, {coseX5Chain, chain}})
+	toSign := encodeCBOR([]any{"Signature1", protected, []byte{}, claim})
+	if hash != 0 {
+		h := hash.New()
+		h.Write(toSign)
+		toSign = h.Sum(nil)
+	}
+	sig, err := signer.Key.Sign(rand.Reader, toSign, opts)
+	if err != nil {
+		return nil, err
+	}
+	if curveSize > 0 {
+		// COSE takes the ECDSA signatures as the fixed size r and s, not ASN.1.
+		var rs struct{ R, S *big.Int }
+		if _, err := asn1.Unmarshal(sig, &rs); err != nil {
+			return nil, err
+		}
+		sig = make([]byte, 2*curveSize)
+		rs.R.FillBytes(sig[:curveSize])
+		rs.S.FillBytes(sig[curveSize:])
+	}
+	return encodeCBOR(cborTag{number: coseSign1, content: []any{protected, cborPairs{}, nil, sig}}), nil
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() string {
+	var u [16]byte
+	if _, err := io.ReadFull(rand.Reader, u[:]); err != nil {
+		panic(err)
+	}
+	return formatUUID(u, 4)
+}
+
+// hashUUID returns the UUID (version 8) made of the SHA-256 hash of the data hash
+// and the name.
+func hashUUID(hash []byte, name string) string {
+	sum := sha256.Sum256(append(append([]byte(nil), hash...), name...))
+	var u [16]byte
+	copy(u[:], sum[:])
+	return formatUUID(u, 8)
+}
+
+// formatUUID returns the string form of the UUID with the version and variant set.
+func formatUUID(u [16]byte, version byte) string {
+	u[6] = u[6]&0x0f | version<<4
+	u[8] = u[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:])
+}
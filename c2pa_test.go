@@ -0,0 +1,244 @@
+package imaging
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"image"
+	"image/color"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newC2PASigner returns a signer with a self-signed certificate of the key.
+func newC2PASigner(t *testing.T, key crypto.Signer) *C2PASigner {
+	t.Helper()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "imaging test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	return &C2PASigner{Key: key, Certificates: [][]byte{cert}}
+}
+
+// verifyC2PA checks the hard binding of the manifest store to the file data, the
+// hashes of the assertions and the signature of the claim.
+func verifyC2PA(t *testing.T, data []byte, store []byte) {
+	t.Helper()
+	boxes, err := parseJUMBF(store, 0)
+	if err != nil {
+		t.Fatalf("failed to parse the store: %v", err)
+	}
+	manifest := boxes[0].boxes[0]
+	assertions := manifest.child("c2pa.assertions")
+
+	v, err := assertions.child("c2pa.hash.data").cbor()
+	if err != nil {
+		t.Fatalf("failed to decode the data hash: %v", err)
+	}
+	hash := v.(map[any]any)
+	exclusion := hash["exclusions"].([]any)[0].(map[any]any)
+	start, length := int(exclusion["start"].(int64)), int(exclusion["length"].(int64))
+	if !(data[start] == 0xff && data[start+1] == markerAPP11) && string(data[start+4:start+8]) != c2paPNGChunk {
+		t.Fatalf("the exclusion %d+%d does not start with the store", start, length)
+	}
+	sum := sha256.Sum256(append(append([]byte(nil), data[:start]...), data[start+length:]...))
+	if !bytes.Equal(sum[:], hash["hash"].([]byte)) {
+		t.Fatalf("got data hash %x want %x", hash["hash"], sum)
+	}
+
+	claimBox := manifest.child("c2pa.claim")
+	v, err = claimBox.cbor()
+	if err != nil {
+		t.Fatalf("failed to decode the claim: %v", err)
+	}
+	for _, ref := range v.(map[any]any)["assertions"].([]any) {
+		ref := ref.(map[any]any)
+		label := strings.TrimPrefix(ref["url"].(string), "self#jumbf=c2pa.assertions/")
+		box := jumbfSuperbox(c2paCBORType, label, jumbfContentBox("cbor", assertions.child(label).boxes[0].payload))
+		if sum := sha256.Sum256(box[8:]); !bytes.Equal(sum[:], ref["hash"].([]byte)) {
+			t.Fatalf("got hash %x of %s want %x", ref["hash"], label, sum)
+		}
+	}
+
+	v, err = manifest.child("c2pa.signature").cbor()
+	if err != nil {
+		t.Fatalf("failed to decode the signature: %v", err)
+	}
+	sign1 := v.(cborTag).content.([]any)
+	protected := sign1[0].([]byte)
+	v, err = decodeCBOR(protected)
+	if err != nil {
+		t.Fatalf("failed to decode the protected header: %v", err)
+	}
+	cert, err := x509.ParseCertificate(v.(map[any]any)[int64(coseX5Chain)].([]byte))
+	if err != nil {
+		t.Fatalf("failed to parse the certificate: %v", err)
+	}
+	toSign := encodeCBOR([]any{"Signature1", protected, []byte{}, claimBox.boxes[0].payload})
+	sig := sign1[3].([]byte)
+	switch pub := cert.PublicKey.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(toSign)
+		r, s := new(big.Int).SetBytes(sig[:len(sig)/2]), new(big.Int).SetBytes(sig[len(sig)/2:])
+		if !ecdsa.Verify(pub, digest[:], r, s) {
+			t.Fatalf("invalid ECDSA signature")
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(pub, toSign, sig) {
+			t.Fatalf("invalid Ed25519 signature")
+		}
+	default:
+		t.Fatalf("unexpected key %T", pub)
+	}
+}
+
+func TestC2PA(t *testing.T) {
+	t.Parallel()
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	when := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	identity := func(img image.Image) image.Image { return img }
+	p := NewPipeline().Then("fit 8x8", identity).Then("grayscale", identity)
+	actions := append(p.C2PAActions(), C2PAAction{Action: C2PAEdited, SoftwareAgent: "editor", When: when})
+
+	testCases := []struct {
+		name   string
+		format Format
+		key    crypto.Signer
+		opts   []EncodeOption
+		title  string
+	}{
+		{"JPEG", JPEG, ecKey, nil, "photo.jpg"},
+		{"JPEG with EXIF", JPEG, edKey, []EncodeOption{WithAuthorship("Jane Doe", "")}, ""},
+		{"JPEG large", JPEG, ecKey, nil, strings.Repeat("x", 150000)},
+		{"PNG", PNG, edKey, nil, "photo.png"},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			manifest := &C2PAManifest{ClaimGenerator: "test/1.0", Title: tc.title, Actions: actions}
+			opts := append(tc.opts, WithC2PA(manifest, newC2PASigner(t, tc.key)))
+			buf := &bytes.Buffer{}
+			if err := Encode(buf, New(16, 16, color.White), tc.format, opts...); err != nil {
+				t.Fatalf("failed to encode: %v", err)
+			}
+			data := buf.Bytes()
+			if _, err := Decode(bytes.NewReader(data)); err != nil {
+				t.Fatalf("failed to decode: %v", err)
+			}
+			meta, err := DecodeMetadata(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("failed to decode metadata: %v", err)
+			}
+			m := meta.C2PA.Active()
+			if m == nil {
+				t.Fatalf("no C2PA manifest")
+			}
+			if m.ClaimGenerator != "test/1.0" || m.Title != tc.title || m.Format != "image/"+strings.ToLower(tc.format.String()) ||
+				!strings.HasPrefix(m.Label, "urn:uuid:") || !strings.HasPrefix(m.InstanceID, "xmp:iid:") {
+				t.Fatalf("got manifest %+v", m)
+			}
+			if !reflect.DeepEqual(m.Actions, actions) {
+				t.Fatalf("got actions %+v want %+v", m.Actions, actions)
+			}
+			if want := []string{"c2pa.actions", "c2pa.hash.data"}; !reflect.DeepEqual(m.Assertions, want) {
+				t.Fatalf("got assertions %v want %v", m.Assertions, want)
+			}
+			if tc.opts != nil && meta.EXIF.Artist() != "Jane Doe" {
+				t.Fatalf("got EXIF %+v", meta.EXIF)
+			}
+			verifyC2PA(t, data, meta.C2PA.Store)
+		})
+	}
+
+	signer := newC2PASigner(t, ecKey)
+	buf := &bytes.Buffer{}
+	if err := Encode(buf, New(4, 4, color.White), GIF, WithC2PA(&C2PAManifest{}, signer)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = Encode(buf, New(4, 4, color.White), JPEG, WithC2PA(&C2PAManifest{}, &C2PASigner{Key: ecKey}))
+	if !errors.Is(err, ErrInvalidC2PASigner) {
+		t.Fatalf("got error %v want %v", err, ErrInvalidC2PASigner)
+	}
+	meta, err := DecodeMetadata(bytes.NewReader(makeEXIFJPEG(t, makeTestEXIF(OrientationNormal))))
+	if err != nil || meta.C2PA != nil {
+		t.Fatalf("got C2PA %+v, error %v want none", meta.C2PA, err)
+	}
+}
+
+func TestC2PAActions(t *testing.T) {
+	t.Parallel()
+
+	identity := func(img image.Image) image.Image { return img }
+	p := NewPipeline().
+		Then("resize 800x600>", identity).
+		Then("Crop 10,10,100,100", identity).
+		Then("rotate 90", identity).
+		Then("sharpen 0.5", identity).
+		Then("gamma 2.2", identity).
+		Then("watermark logo.png", identity).
+		Then("", identity)
+	want := []C2PAAction{
+		{Action: C2PAResized, Description: "resize 800x600>"},
+		{Action: C2PACropped, Description: "Crop 10,10,100,100"},
+		{Action: C2PAOrientation, Description: "rotate 90"},
+		{Action: C2PAFiltered, Description: "sharpen 0.5"},
+		{Action: C2PAColorAdjustments, Description: "gamma 2.2"},
+		{Action: C2PAEdited, Description: "watermark logo.png"},
+		{Action: C2PAEdited},
+	}
+	if got := p.C2PAActions(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got actions %+v want %+v", got, want)
+	}
+}
+
+func TestParseJUMBF(t *testing.T) {
+	t.Parallel()
+
+	box := jumbfSuperbox(c2paStoreType, "c2pa", jumbfContentBox("json", []byte("{}")))
+	testCases := []struct {
+		name  string
+		data  []byte
+		valid bool
+	}{
+		{"superbox", box, true},
+		{"empty", nil, true},
+		{"truncated header", box[:6], false},
+		{"truncated box", box[:len(box)-1], false},
+		{"no description", jumbfContentBox("jumb", jumbfContentBox("json", nil)), false},
+		{"short description", jumbfContentBox("jumb", jumbfContentBox("jumd", []byte("c2pa"))), false},
+	}
+	for _, tc := range testCases {
+		if _, err := parseJUMBF(tc.data, 0); (err == nil) != tc.valid {
+			t.Fatalf("%s: got error %v", tc.name, err)
+		}
+	}
+	boxes, _ := parseJUMBF(box, 0)
+	if b := boxes[0]; b.kind != c2paStoreType || b.label != "c2pa" || len(b.boxes) != 1 || b.boxes[0].typ != "json" {
+		t.Fatalf("got box %+v", b)
+	}
+}
@@ -0,0 +1,280 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// errInvalidCBOR means the CBOR data is broken or uses unsupported features.
+var errInvalidCBOR = errors.New("imaging: invalid CBOR data")
+
+// CBOR major types.
+const (
+	cborUint   = 0
+	cborNegint = 1
+	cborBytes  = 2
+	cborText   = 3
+	cborArray  = 4
+	cborMap    = 5
+	cborTagged = 6
+	cborSimple = 7
+)
+
+// cborPairs is a CBOR map written in the order of its pairs. Decoded maps are
+// map[any]any with int64 or string keys.
+type cborPairs []cborPair
+
+// cborPair is a key and a value of a CBOR map.
+type cborPair struct {
+	key, value any
+}
+
+// cborTag is a tagged CBOR data item.
+type cborTag struct {
+	number  uint64
+	content any
+}
+
+// encodeCBOR returns the CBOR encoding of the value: nil, bool, int, int64, uint64,
+// string, []byte, []any, cborPairs or cborTag. It panics for other types.
+func encodeCBOR(v any) []byte {
+	buf := &bytes.Buffer{}
+	writeCBOR(buf, v)
+	return buf.Bytes()
+}
+
+// writeCBOR writes the CBOR encoding of the value to the buffer.
+func writeCBOR(buf *bytes.Buffer, v any) {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteByte(cborSimple<<5 | 22)
+	case bool:
+		if v {
+			buf.WriteByte(cborSimple<<5 | 21)
+		} else {
+			buf.WriteByte(cborSimple<<5 | 20)
+		}
+	case int:
+		writeCBOR(buf, int64(v))
+	case int64:
+		if v < 0 {
+			writeCBORHead(buf, cborNegint, uint64(-(v + 1)))
+		} else {
+			writeCBORHead(buf, cborUint, uint64(v))
+		}
+	case uint64:
+		writeCBORHead(buf, cborUint, v)
+	case string:
+		writeCBORHead(buf, cborText, uint64(len(v)))
+		buf.WriteString(v)
+	case []byte:
+		writeCBORHead(buf, cborBytes, uint64(len(v)))
+		buf.Write(v)
+	case []any:
+		writeCBORHead(buf, cborArray, uint64(len(v)))
+		for _, item := range v {
+			writeCBOR(buf, item)
+		}
+	case cborPairs:
+		writeCBORHead(buf, cborMap, uint64(len(v)))
+		for _, p := range v {
+			writeCBOR(buf, p.key)
+			writeCBOR(buf, p.value)
+		}
+	case cborTag:
+		writeCBORHead(buf, cborTagged, v.number)
+		writeCBOR(buf, v.content)
+	default:
+		panic("imaging: unsupported CBOR value")
+	}
+}
+
+// writeCBORHead writes the initial byte of the major type with the shortest
+// encoding of the argument.
+func writeCBORHead(buf *bytes.Buffer, major byte, n uint64) {
+	switch {
+	case n < 24:
+		buf.WriteByte(major<<5 | byte(n))
+	case n <= math.MaxUint8:
+		buf.Write([]byte{major<<5 | 24, byte(n)})
+	case n <= math.MaxUint16:
+		buf.WriteByte(major<<5 | 25)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	case n <= math.MaxUint32:
+		buf.WriteByte(major<<5 | 26)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	default:
+		buf.WriteByte(major<<5 | 27)
+		buf.Write(binary.BigEndian.AppendUint64(nil, n))
+	}
+}
+
+// decodeCBOR decodes the single CBOR data item of the data. The integers are
+// decoded as int64, the floats as float64, the byte strings as []byte, the arrays
+// as []any, the maps as map[any]any and the tags as cborTag. Indefinite lengths
+// are not supported.
+func decodeCBOR(data []byte) (any, error) {
+	d := &cborDecoder{data: data}
+	v, err := d.item(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(data) {
+		return nil, errInvalidCBOR
+	}
+	return v, nil
+}
+
+// cborDecoder reads the CBOR data items from the data.
+type cborDecoder struct {
+	data []byte
+	pos  int
+}
+
+// cborMaxDepth limits the nesting of the decoded arrays, maps and tags.
+const cborMaxDepth = 64
+
+// item reads the next data item nested at the depth.
+func (d *cborDecoder) item(depth int) (any, error) {
+	if depth > cborMaxDepth || d.pos >= len(d.data) {
+		return nil, errInvalidCBOR
+	}
+	b := d.data[d.pos]
+	d.pos++
+	major, info := b>>5, b&0x1f
+	if major == cborSimple {
+		return d.simple(info)
+	}
+	n, err := d.argument(info)
+	if err != nil {
+		return nil, err
+	}
+	switch major {
+	case cborUint:
+		if n > math.MaxInt64 {
+			return nil, errInvalidCBOR
+		}
+		return int64(n), nil
+	case cborNegint:
+		if n > math.MaxInt64 {
+			return nil, errInvalidCBOR
+		}
+		return -1 - int64(n), nil
+	case cborBytes, cborText:
+		if n > uint64(len(d.data)-d.pos) {
+			return nil, errInvalidCBOR
+		}
+		s := d.data[d.pos : d.pos+int(n)]
+		d.pos += int(n)
+		if major == cborText {
+			return string(s), nil
+		}
+		return s, nil
+	case cborArray:
+		// Every item takes at least a byte.
+		if n > uint64(len(d.data)-d.pos) {
+			return nil, errInvalidCBOR
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = d.item(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	case cborMap:
+		if n > uint64(len(d.data)-d.pos)/2 {
+			return nil, errInvalidCBOR
+		}
+		m := make(map[any]any, n)
+		for i := uint64(0); i < n; i++ {
+			k, err := d.item(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			switch k.(type) {
+			case int64, string:
+			default:
+				return nil, errInvalidCBOR
+			}
+			if m[k], err = d.item(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	default:
+		content, err := d.item(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return cborTag{number: n, content: content}, nil
+	}
+}
+
+// argument reads the argument of the initial byte with the additional information.
+func (d *cborDecoder) argument(info byte) (uint64, error) {
+	if info < 24 {
+		return uint64(info), nil
+	}
+	if info > 27 {
+		return 0, errInvalidCBOR
+	}
+	size := 1 << (info - 24)
+	if d.pos+size > len(d.data) {
+		return 0, errInvalidCBOR
+	}
+	var n uint64
+	for _, b := range d.data[d.pos : d.pos+size] {
+		n = n<<8 | uint64(b)
+	}
+	d.pos += size
+	return n, nil
+}
+
+// simple reads the simple value or the float with the additional information.
+func (d *cborDecoder) simple(info byte) (any, error) {
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23:
+		return nil, nil
+	case 25, 26, 27:
+		n, err := d.argument(info)
+		if err != nil {
+			return nil, err
+		}
+		switch info {
+		case 25:
+			return halfToFloat(uint16(n)), nil
+		case 26:
+			return float64(math.Float32frombits(uint32(n))), nil
+		}
+		return math.Float64frombits(n), nil
+	}
+	return nil, errInvalidCBOR
+}
+
+// halfToFloat converts the IEEE 754 half precision float.
+func halfToFloat(h uint16) float64 {
+	exp, frac := int(h>>10&0x1f), float64(h&0x3ff)
+	var v float64
+	switch exp {
+	case 0:
+		v = math.Ldexp(frac, -24)
+	case 31:
+		v = math.Inf(1)
+		if frac != 0 {
+			v = math.NaN()
+		}
+	default:
+		v = math.Ldexp(frac+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		v = -v
+	}
+	return v
+}
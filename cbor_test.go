@@ -0,0 +1,75 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestCBOR(t *testing.T) {
+	t.Parallel()
+
+	// The examples of RFC 8949, appendix A.
+	testCases := []struct {
+		value   any
+		encoded string
+		decoded any
+	}{
+		{0, "00", int64(0)},
+		{23, "17", int64(23)},
+		{24, "1818", int64(24)},
+		{1000, "1903e8", int64(1000)},
+		{1000000, "1a000f4240", int64(1000000)},
+		{uint64(1000000000000), "1b000000e8d4a51000", int64(1000000000000)},
+		{-1, "20", int64(-1)},
+		{-1000, "3903e7", int64(-1000)},
+		{false, "f4", false},
+		{true, "f5", true},
+		{nil, "f6", nil},
+		{[]byte{1, 2, 3, 4}, "4401020304", []byte{1, 2, 3, 4}},
+		{"IETF", "6449455446", "IETF"},
+		{"ü", "62c3bc", "ü"},
+		{[]any{1, []any{2, 3}}, "8201820203", []any{int64(1), []any{int64(2), int64(3)}}},
+		{cborPairs{{"a", 1}, {2, "b"}}, "a2616101026162", map[any]any{"a": int64(1), int64(2): "b"}},
+		{cborTag{number: 18, content: []any{}}, "d280", cborTag{number: 18, content: []any{}}},
+	}
+	for _, tc := range testCases {
+		if got := hex.EncodeToString(encodeCBOR(tc.value)); got != tc.encoded {
+			t.Fatalf("got encoding %s of %#v want %s", got, tc.value, tc.encoded)
+		}
+		data, _ := hex.DecodeString(tc.encoded)
+		got, err := decodeCBOR(data)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.encoded, err)
+		}
+		if !reflect.DeepEqual(got, tc.decoded) {
+			t.Fatalf("got %#v decoding %s want %#v", got, tc.encoded, tc.decoded)
+		}
+	}
+
+	for encoded, want := range map[string]float64{
+		"f93c00":             1,
+		"f9c400":             -4,
+		"f90001":             5.960464477539063e-8,
+		"fa47c35000":         100000,
+		"fb3ff199999999999a": 1.1,
+		"f97c00":             math.Inf(1),
+	} {
+		data, _ := hex.DecodeString(encoded)
+		if got, err := decodeCBOR(data); err != nil || got != want {
+			t.Fatalf("got %v, error %v decoding %s want %v", got, err, encoded, want)
+		}
+	}
+
+	for _, encoded := range []string{"", "18", "62c3", "8201", "a1f401", "9f01ff", "0000", "1c", "c6", "9b0000000100000000"} {
+		data, _ := hex.DecodeString(encoded)
+		if _, err := decodeCBOR(data); err == nil {
+			t.Fatalf("expected error decoding %s", encoded)
+		}
+	}
+	if _, err := decodeCBOR(bytes.Repeat([]byte{0x81}, cborMaxDepth+2)); err == nil {
+		t.Fatalf("expected error decoding deeply nested arrays")
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/go-spectest/imaging"
+	"github.com/spf13/cobra"
+)
+
+func newASCIICmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "ascii",
+		Short: "Print the image as ASCII or Braille art",
+		Long: `Print the image as ASCII or Braille art to the standard output.
+
+The characters of --charset are ordered from the darkest to the lightest pixels.
+With --braille every character shows 2x4 dots, for four times as many pixels.
+Use --invert for the terminals with light text on a dark background.`,
+		Example: `   gina ascii --cols 100 input.jpg
+   gina ascii --braille --invert input.png`,
+		RunE: ascii,
+	}
+
+	cmd.Flags().IntP("cols", "c", 80, "width of the art in characters")
+	cmd.Flags().String("charset", imaging.DefaultASCIICharset, "characters from the darkest to the lightest pixels")
+	cmd.Flags().BoolP("braille", "b", false, "draw with Braille patterns instead of the charset")
+	cmd.Flags().BoolP("invert", "i", false, "invert the image, for light text on a dark background")
+
+	return &cmd
+}
+
+// asciiArtist have options for printing image as text art.
+type asciiArtist struct {
+	cols    int
+	charset string
+	braille bool
+	invert  bool
+	input   string
+}
+
+// newASCIIArtist returns a new asciiArtist. It returns an error if the required options are not set.
+func newASCIIArtist(cmd *cobra.Command, args []string) (*asciiArtist, error) {
+	cols, err := cmd.Flags().GetInt("cols")
+	if err != nil {
+		return nil, err
+	}
+	if cols <= 0 {
+		return nil, errors.New("--cols must be positive")
+	}
+
+	charset, err := cmd.Flags().GetString("charset")
+	if err != nil {
+		return nil, err
+	}
+
+	braille, err := cmd.Flags().GetBool("braille")
+	if err != nil {
+		return nil, err
+	}
+
+	invert, err := cmd.Flags().GetBool("invert")
+	if err != nil {
+		return nil, err
+	}
+
+	if len(args) == 0 {
+		return nil, errors.New("no argument: input image file path is required")
+	}
+
+	return &asciiArtist{
+		cols:    cols,
+		charset: charset,
+		braille: braille,
+		invert:  invert,
+		input:   args[0],
+	}, nil
+}
+
+func ascii(cmd *cobra.Command, args []string) error {
+	artist, err := newASCIIArtist(cmd, args)
+	if err != nil {
+		return err
+	}
+	return artist.print()
+}
+
+func (a *asciiArtist) print() error {
+	src, err := imaging.Open(a.input)
+	if err != nil {
+		return err
+	}
+
+	if a.invert {
+		src = imaging.Invert(src)
+	}
+	if a.braille {
+		fmt.Fprint(os.Stdout, imaging.ToBraille(src, a.cols))
+		return nil
+	}
+	fmt.Fprint(os.Stdout, imaging.ToASCII(src, a.cols, a.charset))
+	return nil
+}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-spectest/imaging"
+	"github.com/spf13/cobra"
+)
+
+// Exit codes of gina.
+const (
+	// exitOK means the command succeeded, for batch commands every file was processed.
+	exitOK = 0
+	// exitFatal means the command failed before processing the files, e.g. because of
+	// invalid flags, a broken config file or an unwritable output directory.
+	exitFatal = 1
+	// exitPartialFailure means a batch command processed the files, but some (or all)
+	// of them failed.
+	exitPartialFailure = 2
+)
+
+// partialFailureError is returned by the batch commands when some of the files failed.
+type partialFailureError struct {
+	failed, total int
+}
+
+// Error returns the number of the failed files.
+func (e *partialFailureError) Error() string {
+	return fmt.Sprintf("%d of %d files failed", e.failed, e.total)
+}
+
+// exitCode returns the exit code of gina for the error returned by a command.
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	var partial *partialFailureError
+	if errors.As(err, &partial) {
+		return exitPartialFailure
+	}
+	return exitFatal
+}
+
+// Statuses of the files processed by the batch commands.
+const (
+	statusOK      = "ok"
+	statusSkipped = "skipped"
+	statusFailed  = "failed"
+	// statusCanceled means the file wasn't processed, as the batch stopped at a
+	// failed file with --on-error fail-fast.
+	statusCanceled = "canceled"
+)
+
+// Policies of the batch commands for the failed files.
+const (
+	// onErrorContinue reports the failed files and processes the others.
+	onErrorContinue = "continue"
+	// onErrorFailFast stops starting new files at the first failed file.
+	onErrorFailFast = "fail-fast"
+)
+
+// errUnchanged is returned by the batch processing functions for the inputs skipped
+// because their outputs are up to date.
+var errUnchanged = errors.New("the output is up to date")
+
+// fileResult is the result of processing a single file.
+type fileResult struct {
+	Input      string  `json:"input"`
+	Output     string  `json:"output,omitempty"`
+	Status     string  `json:"status"`
+	Error      string  `json:"error,omitempty"`
+	Retries    int     `json:"retries,omitempty"`
+	DurationMS float64 `json:"duration_ms"`
+}
+
+// batchResult is the JSON result summary of a batch command.
+type batchResult struct {
+	Command    string       `json:"command"`
+	Files      []fileResult `json:"files"`
+	Succeeded  int          `json:"succeeded"`
+	Skipped    int          `json:"skipped"`
+	Failed     int          `json:"failed"`
+	Canceled   int          `json:"canceled"`
+	Retries    int          `json:"retries"`
+	DurationMS float64      `json:"duration_ms"`
+	ExitCode   int          `json:"exit_code"`
+}
+
+// batcher runs a batch command over its input files.
+type batcher struct {
+	command string
+	// json prints the JSON result summary instead of the progress.
+	json bool
+	// plan lists the planned outputs instead of processing the files.
+	plan bool
+	// failFast stops the batch at the first failed file.
+	failFast bool
+	// retries is the number of times the files failed with temporary errors are
+	// retried, waiting retryDelay before the first retry and twice as long before
+	// every next one.
+	retries    int
+	retryDelay time.Duration
+	// workers is the number of files processed in parallel.
+	workers int
+	// limiter limits the rate of the processed files, nil means no limit.
+	limiter *rateLimiter
+	out     io.Writer
+	errs    io.Writer
+	// mu guards the progress output.
+	mu sync.Mutex
+}
+
+// addBatchFlags adds the flags of the batch commands.
+func addBatchFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("json", false, "print a JSON summary of the results (per-file status, output paths and timings) instead of the progress")
+	cmd.Flags().Bool("plan", false, "list the files that would be created, overwritten or skipped and the pixels to process, without processing them")
+	cmd.Flags().String("on-error", onErrorContinue, "what to do when a file fails (continue, fail-fast)")
+	cmd.Flags().Int("retries", 0, "number of retries of the files failed with temporary storage or network errors")
+	cmd.Flags().Duration("retry-delay", time.Second, "delay before the first retry, doubled for every next one")
+	registerFlagCompletion(cmd, "on-error", completeValues(func() []string { return []string{onErrorContinue, onErrorFailFast} }))
+}
+
+// newBatcher returns a new batcher for the command.
+func newBatcher(cmd *cobra.Command) (*batcher, error) {
+	j, err := cmd.Flags().GetBool("json")
+	if err != nil {
+		return nil, err
+	}
+	p, err := cmd.Flags().GetBool("plan")
+	if err != nil {
+		return nil, err
+	}
+	onError, err := cmd.Flags().GetString("on-error")
+	if err != nil {
+		return nil, err
+	}
+	retries, err := cmd.Flags().GetInt("retries")
+	if err != nil {
+		return nil, err
+	}
+	delay, err := cmd.Flags().GetDuration("retry-delay")
+	if err != nil {
+		return nil, err
+	}
+	if onError != onErrorContinue && onError != onErrorFailFast {
+		return nil, fmt.Errorf("invalid --on-error %q: must be %s or %s", onError, onErrorContinue, onErrorFailFast)
+	}
+	if retries < 0 || delay < 0 {
+		return nil, errors.New("--retries and --retry-delay must not be negative")
+	}
+	return &batcher{
+		command:    cmd.Name(),
+		json:       j,
+		plan:       p,
+		failFast:   onError == onErrorFailFast,
+		retries:    retries,
+		retryDelay: delay,
+		workers:    settings.workers,
+		limiter:    newRateLimiter(settings.rate),
+		out:        os.Stdout,
+		errs:       os.Stderr,
+	}, nil
+}
+
+// run calls process for every input, which returns the output path of the input (and
+// errUnchanged if the input is skipped). The inputs are processed by the workers in
+// parallel, at most at the rate of the limiter. The files failed with temporary errors
+// are retried. A failed file doesn't stop the batch unless failFast is set, in which
+// case the files not started yet are canceled: the error is reported, and once all the
+// files are done a partialFailureError is returned. The results are in the input order.
+func (b *batcher) run(inputs []string, process func(input string) (string, error)) error {
+	result := batchResult{Command: b.command, Files: make([]fileResult, len(inputs))}
+	start := time.Now()
+
+	workers := b.workers
+	if workers < 1 {
+		workers = 1
+	}
+	indexes := make(chan int)
+	var failed atomic.Bool
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if b.failFast && failed.Load() {
+					result.Files[i] = fileResult{Input: inputs[i], Status: statusCanceled}
+					continue
+				}
+				result.Files[i] = b.processFile(inputs[i], process)
+				if result.Files[i].Status == statusFailed {
+					failed.Store(true)
+				}
+			}
+		}()
+	}
+	for i := range inputs {
+		if b.failFast && failed.Load() {
+			result.Files[i] = fileResult{Input: inputs[i], Status: statusCanceled}
+			continue
+		}
+		b.limiter.wait()
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	for _, r := range result.Files {
+		switch r.Status {
+		case statusOK:
+			result.Succeeded++
+		case statusSkipped:
+			result.Skipped++
+		case statusCanceled:
+			result.Canceled++
+		default:
+			result.Failed++
+		}
+		result.Retries += r.Retries
+	}
+	result.DurationMS = milliseconds(time.Since(start))
+
+	var err error
+	if result.Failed > 0 {
+		err = &partialFailureError{failed: result.Failed, total: len(inputs)}
+	}
+	result.ExitCode = exitCode(err)
+	if b.json {
+		enc := json.NewEncoder(b.out)
+		enc.SetIndent("", "  ")
+		if encErr := enc.Encode(result); encErr != nil {
+			return encErr
+		}
+	} else if result.Canceled > 0 {
+		fmt.Fprintf(b.errs, "%d files canceled after the failure\n", result.Canceled)
+	}
+	return err
+}
+
+// processFile processes a single input and reports its progress.
+func (b *batcher) processFile(input string, process func(input string) (string, error)) fileResult {
+	start := time.Now()
+	output, err := process(input)
+	retries := 0
+	for delay := b.retryDelay; err != nil && retries < b.retries && imaging.IsTemporary(err); delay *= 2 {
+		if !b.json {
+			b.mu.Lock()
+			fmt.Fprintf(b.errs, "%s: %v, retrying in %s\n", input, err, delay)
+			b.mu.Unlock()
+		}
+		time.Sleep(delay)
+		retries++
+		output, err = process(input)
+	}
+	r := fileResult{Input: input, Output: output, Status: statusOK, Retries: retries, DurationMS: milliseconds(time.Since(start))}
+	if errors.Is(err, errUnchanged) {
+		r.Status, err = statusSkipped, nil
+	} else if err != nil {
+		r.Status, r.Error = statusFailed, err.Error()
+	}
+	if b.json {
+		return r
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err != nil {
+		fmt.Fprintf(b.errs, "%s: %v\n", input, err)
+	} else if r.Status == statusSkipped {
+		fmt.Fprintf(b.out, "skip unchanged image: %s\n", output)
+	} else if output != "" {
+		fmt.Fprintf(b.out, "save image: %s\n", output)
+	}
+	return r
+}
+
+// Actions planned for the outputs of the batch commands.
+const (
+	actionCreate    = "create"
+	actionOverwrite = "overwrite"
+	actionSkip      = "skip"
+)
+
+// plannedFile is the planned processing of a single file.
+type plannedFile struct {
+	Input  string `json:"input"`
+	Output string `json:"output,omitempty"`
+	Action string `json:"action,omitempty"`
+	// Pixels is the number of the source pixels to process, 0 for skipped files.
+	Pixels int64  `json:"pixels"`
+	Error  string `json:"error,omitempty"`
+}
+
+// batchPlan is the JSON plan of a batch command.
+type batchPlan struct {
+	Command   string        `json:"command"`
+	Files     []plannedFile `json:"files"`
+	Create    int           `json:"create"`
+	Overwrite int           `json:"overwrite"`
+	Skip      int           `json:"skip"`
+	Failed    int           `json:"failed"`
+	Pixels    int64         `json:"pixels"`
+	ExitCode  int           `json:"exit_code"`
+}
+
+// outputAction returns actionOverwrite if the local output file exists or actionCreate
+// otherwise. The outputs in object storages are assumed to be created.
+func outputAction(output string) string {
+	if isURL(output) {
+		return actionCreate
+	}
+	if _, err := os.Stat(output); err == nil {
+		return actionOverwrite
+	}
+	return actionCreate
+}
+
+// runPlan calls plan for every input, which returns the output path of the input and
+// the action planned for it, and prints the plan along with the number of the pixels
+// to process instead of processing the files. The inputs that can't be planned are
+// reported, and a partialFailureError is returned as by run.
+func (b *batcher) runPlan(inputs []string, plan func(input string) (output, action string, err error)) error {
+	result := batchPlan{Command: b.command, Files: make([]plannedFile, len(inputs))}
+	for i, input := range inputs {
+		f := plannedFile{Input: input}
+		output, action, err := plan(input)
+		if err == nil && action != actionSkip {
+			var info imaging.ImageInfo
+			if info, err = imaging.Inspect(input); err == nil {
+				f.Pixels = int64(info.Width) * int64(info.Height)
+			}
+		}
+		if err != nil {
+			f.Error = err.Error()
+			result.Failed++
+			if !b.json {
+				fmt.Fprintf(b.errs, "%s: %v\n", input, err)
+			}
+		} else {
+			f.Output, f.Action = output, action
+			switch action {
+			case actionCreate:
+				result.Create++
+			case actionOverwrite:
+				result.Overwrite++
+			case actionSkip:
+				result.Skip++
+			}
+			result.Pixels += f.Pixels
+			if !b.json {
+				fmt.Fprintf(b.out, "%-9s %s\n", action, output)
+			}
+		}
+		result.Files[i] = f
+	}
+
+	var err error
+	if result.Failed > 0 {
+		err = &partialFailureError{failed: result.Failed, total: len(inputs)}
+	}
+	result.ExitCode = exitCode(err)
+	if b.json {
+		enc := json.NewEncoder(b.out)
+		enc.SetIndent("", "  ")
+		if encErr := enc.Encode(result); encErr != nil {
+			return encErr
+		}
+	} else {
+		fmt.Fprintf(b.out, "%d to create, %d to overwrite, %d to skip, %.1f megapixels to process\n",
+			result.Create, result.Overwrite, result.Skip, float64(result.Pixels)/1e6)
+	}
+	return err
+}
+
+// rateLimiter spaces out the events to limit their rate.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// newRateLimiter returns a limiter of the rate per second, or nil if the rate is 0.
+func newRateLimiter(rate float64) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / rate)}
+}
+
+// wait blocks until the next event is allowed. A nil limiter never blocks.
+func (l *rateLimiter) wait() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	d := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+	time.Sleep(d)
+}
+
+// milliseconds returns the duration in milliseconds rounded to microseconds.
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-spectest/imaging"
+	"github.com/spf13/cobra"
+)
+
+func newBenchCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "bench",
+		Short: "Measure the speed of image operations on your images",
+		Long: `Measure the throughput and the memory allocations of the core operations on
+the given images and hardware, to help with capacity planning and filter selection.
+
+The operations are:
+  decode       decode the image from its file format
+  resize       resize to half of the width and height (Lanczos)
+  resize-fast  resize to half of the width and height (Linear)
+  blur         Gaussian blur with sigma 2
+  sharpen      sharpen with sigma 1
+  grayscale    convert to grayscale
+  rotate90     rotate by 90 degrees
+  encode-jpeg  encode in JPEG format (quality 95)
+  encode-png   encode in PNG format
+
+Each operation is benchmarked for every image and size. The sizes scale the input
+images before benchmarking, e.g. 0.5x halves the width and height.`,
+		Example: `   gina bench --input testdata/ --ops resize,blur,encode-jpeg --sizes 1x,0.5x`,
+		RunE:    bench,
+	}
+
+	cmd.Flags().StringP("input", "i", ".", "input image file or directory of images")
+	cmd.Flags().StringSlice("ops", benchOpNames(), "operations to benchmark")
+	cmd.Flags().StringSlice("sizes", []string{"1x"}, "sizes of the input images relative to the original size")
+	cmd.Flags().Duration("duration", time.Second, "minimum run time of each benchmark")
+	registerFlagCompletion(&cmd, "ops", completeValues(benchOpNames))
+
+	return &cmd
+}
+
+// benchOp is an operation to benchmark. The image and its encoded form at the
+// benchmarked size are passed to the operation.
+type benchOp func(img image.Image, data []byte, format imaging.Format) error
+
+// benchOps are the operations that can be benchmarked.
+var benchOps = map[string]benchOp{ //nolint
+	"decode": func(_ image.Image, data []byte, _ imaging.Format) error {
+		_, err := imaging.Decode(bytes.NewReader(data))
+		return err
+	},
+	"resize": func(img image.Image, _ []byte, _ imaging.Format) error {
+		b := img.Bounds()
+		imaging.Resize(img, (b.Dx()+1)/2, (b.Dy()+1)/2, imaging.Lanczos)
+		return nil
+	},
+	"resize-fast": func(img image.Image, _ []byte, _ imaging.Format) error {
+		b := img.Bounds()
+		imaging.Resize(img, (b.Dx()+1)/2, (b.Dy()+1)/2, imaging.Linear)
+		return nil
+	},
+	"blur": func(img image.Image, _ []byte, _ imaging.Format) error {
+		imaging.Blur(img, 2)
+		return nil
+	},
+	"sharpen": func(img image.Image, _ []byte, _ imaging.Format) error {
+		imaging.Sharpen(img, 1)
+		return nil
+	},
+	"grayscale": func(img image.Image, _ []byte, _ imaging.Format) error {
+		imaging.Grayscale(img)
+		return nil
+	},
+	"rotate90": func(img image.Image, _ []byte, _ imaging.Format) error {
+		imaging.Rotate90(img)
+		return nil
+	},
+	"encode-jpeg": func(img image.Image, _ []byte, _ imaging.Format) error {
+		return imaging.Encode(io.Discard, img, imaging.JPEG, imaging.JPEGQuality(95))
+	},
+	"encode-png": func(img image.Image, _ []byte, _ imaging.Format) error {
+		return imaging.Encode(io.Discard, img, imaging.PNG)
+	},
+}
+
+// benchOpNames returns the names of the benchmark operations in the order of the help text.
+func benchOpNames() []string {
+	return []string{"decode", "resize", "resize-fast", "blur", "sharpen", "grayscale", "rotate90", "encode-jpeg", "encode-png"}
+}
+
+// bencher have options for benchmark operations.
+type bencher struct {
+	inputs   []string
+	ops      []string
+	sizes    []float64
+	duration time.Duration
+}
+
+// benchResult is the result of a benchmark.
+type benchResult struct {
+	// n is the number of times the operation ran.
+	n int
+	// elapsed is the total run time.
+	elapsed time.Duration
+	// allocs and bytes are the total number and size of the memory allocations.
+	allocs, bytes uint64
+}
+
+// newBencher returns a new bencher. It returns an error if the options are invalid.
+func newBencher(cmd *cobra.Command, _ []string) (*bencher, error) {
+	i, err := cmd.Flags().GetString("input")
+	if err != nil {
+		return nil, err
+	}
+
+	ops, err := cmd.Flags().GetStringSlice("ops")
+	if err != nil {
+		return nil, err
+	}
+
+	sizes, err := cmd.Flags().GetStringSlice("sizes")
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := cmd.Flags().GetDuration("duration")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, op := range ops {
+		if _, ok := benchOps[op]; !ok {
+			return nil, fmt.Errorf("--ops: unknown operation %q (supported: %s)", op, strings.Join(benchOpNames(), ", "))
+		}
+	}
+	if len(ops) == 0 {
+		return nil, errors.New("--ops: at least one operation is required")
+	}
+	scales := make([]float64, 0, len(sizes))
+	for _, s := range sizes {
+		scale, err := strconv.ParseFloat(strings.TrimSuffix(s, "x"), 64)
+		if err != nil || !strings.HasSuffix(s, "x") || scale <= 0 {
+			return nil, fmt.Errorf("--sizes: invalid size %q, expected a scale like 0.5x", s)
+		}
+		scales = append(scales, scale)
+	}
+	if d <= 0 {
+		return nil, errors.New("--duration must be positive")
+	}
+
+	inputs, err := benchInputs(i)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bencher{
+		inputs:   inputs,
+		ops:      ops,
+		sizes:    scales,
+		duration: d,
+	}, nil
+}
+
+// benchInputs returns the image file or the supported image files in the directory.
+func benchInputs(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var inputs []string
+	for _, e := range entries {
+		if _, err := imaging.FormatFromFilename(e.Name()); err == nil && !e.IsDir() {
+			inputs = append(inputs, filepath.Join(path, e.Name()))
+		}
+	}
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("%s: no supported image files", path)
+	}
+	sort.Strings(inputs)
+	return inputs, nil
+}
+
+func bench(cmd *cobra.Command, args []string) error {
+	bencher, err := newBencher(cmd, args)
+	if err != nil {
+		return err
+	}
+	return bencher.bench(os.Stdout)
+}
+
+func (b *bencher) bench(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "IMAGE\tSIZE\tOP\tTIME/OP\tMPIXEL/S\tALLOCS/OP\tMB/OP\t")
+	for _, input := range b.inputs {
+		data, err := os.ReadFile(filepath.Clean(input))
+		if err != nil {
+			return err
+		}
+		format, err := imaging.FormatFromFilename(input)
+		if err != nil {
+			return err
+		}
+		src, err := imaging.Decode(bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("%s: %w", input, err)
+		}
+
+		for _, scale := range b.sizes {
+			img, encoded := src, data
+			if scale != 1 {
+				sb := src.Bounds()
+				width, height := int(float64(sb.Dx())*scale+0.5), int(float64(sb.Dy())*scale+0.5)
+				if width < 1 || height < 1 {
+					return fmt.Errorf("%s: the image is too small for the size %gx", input, scale)
+				}
+				img = imaging.Resize(src, width, height, imaging.Lanczos)
+				buf := &bytes.Buffer{}
+				if err := imaging.Encode(buf, img, format); err != nil {
+					return fmt.Errorf("%s: %w", input, err)
+				}
+				encoded = buf.Bytes()
+			}
+
+			ib := img.Bounds()
+			pixels := float64(ib.Dx() * ib.Dy())
+			for _, name := range b.ops {
+				op := benchOps[name]
+				r, err := measure(b.duration, func() error { return op(img, encoded, format) })
+				if err != nil {
+					return fmt.Errorf("%s: %s: %w", input, name, err)
+				}
+				perOp := r.elapsed / time.Duration(r.n)
+				fmt.Fprintf(tw, "%s\t%dx%d\t%s\t%s\t%.1f\t%d\t%.1f\t\n",
+					filepath.Base(input), ib.Dx(), ib.Dy(), name, perOp.Round(time.Microsecond),
+					pixels*float64(r.n)/r.elapsed.Seconds()/1e6, r.allocs/uint64(r.n), float64(r.bytes)/float64(r.n)/(1<<20))
+			}
+		}
+	}
+	return tw.Flush()
+}
+
+// measure runs fn repeatedly for at least the duration and returns the run time
+// and the memory allocations.
+func measure(d time.Duration, fn func() error) (benchResult, error) {
+	// Warm up, so that the one-time initialization is not measured.
+	if err := fn(); err != nil {
+		return benchResult{}, err
+	}
+	runtime.GC()
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	r := benchResult{}
+	for r.elapsed < d {
+		if err := fn(); err != nil {
+			return benchResult{}, err
+		}
+		r.n++
+		r.elapsed = time.Since(start)
+	}
+	runtime.ReadMemStats(&after)
+	r.allocs = after.Mallocs - before.Mallocs
+	r.bytes = after.TotalAlloc - before.TotalAlloc
+	return r, nil
+}
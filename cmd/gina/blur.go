@@ -72,5 +72,5 @@ func (b *blurer) blur() error {
 
 	dst := imaging.Blur(src, b.sigma)
 	fmt.Fprintf(os.Stdout, "save image: %s\n", b.output)
-	return imaging.Save(dst, b.output)
+	return imaging.Save(dst, b.output, settings.encodeOptions()...)
 }
@@ -0,0 +1,477 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/go-spectest/imaging"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+func newCardCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "card",
+		Short: "Generate a social (Open Graph) card image",
+		Long: `Generate a social (Open Graph) card image from a template.
+
+The template is a YAML file describing the card. All keys are optional:
+
+  width: 1200                 # card size in pixels
+  height: 630
+  padding: 80
+  background: "#0f172a"       # background color
+  background_image: cover.jpg # cropped to fill the card
+  overlay: "#00000080"        # color drawn over the background image
+  logo: logo.png              # drawn in the top left corner
+  logo_height: 96
+  title:
+    text: "{{.title}}"
+    size: 72
+    color: "#ffffff"
+    bold: true
+  subtitle:
+    text: "{{.subtitle}}"
+    size: 36
+    color: "#cbd5e1"
+  footer:
+    text: "{{.site}}"
+    size: 28
+    color: "#94a3b8"
+
+The texts are Go text/template templates. The variables are set with --title,
+--subtitle and --var name=value. Image paths are relative to the template file.
+Without --template the built-in template shown above (without images) is used.`,
+		Example: `   gina card --template card.yaml --title "Release v2.0" --out og.png
+   gina card --title "Release v2.0" --var site=example.com -o og.png`,
+		RunE: card,
+	}
+
+	cmd.Flags().StringP("template", "t", "", "card template file (YAML)")
+	cmd.Flags().String("title", "", "value of the title variable")
+	cmd.Flags().String("subtitle", "", "value of the subtitle variable")
+	cmd.Flags().StringArray("var", nil, "template variable in the name=value form (can be repeated)")
+	cmd.Flags().StringP("output", "o", "card.png", "output filename (supported format: jpg, png, gif, tiff, bmp)")
+	cmd.Flags().SetNormalizeFunc(func(_ *pflag.FlagSet, name string) pflag.NormalizedName {
+		// Accept --out as the Open Graph tools do.
+		if name == "out" {
+			name = "output"
+		}
+		return pflag.NormalizedName(name)
+	})
+
+	return &cmd
+}
+
+// cardText is a text block of the card.
+type cardText struct {
+	text  string
+	size  float64
+	color color.NRGBA
+	bold  bool
+}
+
+// cardTemplate describes the layout of the card.
+type cardTemplate struct {
+	width, height   int
+	padding         int
+	background      color.NRGBA
+	backgroundImage string
+	overlay         color.NRGBA
+	logo            string
+	logoHeight      int
+	title           cardText
+	subtitle        cardText
+	footer          cardText
+}
+
+// defaultCardTemplate is used without the --template parameter.
+var defaultCardTemplate = cardTemplate{ //nolint
+	width:      1200,
+	height:     630,
+	padding:    80,
+	background: color.NRGBA{0x0f, 0x17, 0x2a, 0xff},
+	logoHeight: 96,
+	title:      cardText{text: "{{.title}}", size: 72, color: color.NRGBA{0xff, 0xff, 0xff, 0xff}, bold: true},
+	subtitle:   cardText{text: "{{.subtitle}}", size: 36, color: color.NRGBA{0xcb, 0xd5, 0xe1, 0xff}},
+	footer:     cardText{text: `{{with .site}}{{.}}{{end}}`, size: 28, color: color.NRGBA{0x94, 0xa3, 0xb8, 0xff}},
+}
+
+// carder have options for generate card.
+type carder struct {
+	template cardTemplate
+	dir      string
+	vars     map[string]string
+	output   string
+}
+
+// newCarder returns a new carder. It returns an error if the template or the variables are invalid.
+func newCarder(cmd *cobra.Command, _ []string) (*carder, error) {
+	t, err := cmd.Flags().GetString("template")
+	if err != nil {
+		return nil, err
+	}
+
+	title, err := cmd.Flags().GetString("title")
+	if err != nil {
+		return nil, err
+	}
+
+	subtitle, err := cmd.Flags().GetString("subtitle")
+	if err != nil {
+		return nil, err
+	}
+
+	vars, err := cmd.Flags().GetStringArray("var")
+	if err != nil {
+		return nil, err
+	}
+
+	o, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return nil, err
+	}
+
+	c := &carder{
+		template: defaultCardTemplate,
+		dir:      ".",
+		vars:     map[string]string{"title": title, "subtitle": subtitle},
+		output:   o,
+	}
+	for _, v := range vars {
+		name, value, ok := strings.Cut(v, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("--var %s: expected name=value", v)
+		}
+		c.vars[name] = value
+	}
+	if t != "" {
+		data, err := os.ReadFile(filepath.Clean(t))
+		if err != nil {
+			return nil, err
+		}
+		if c.template, err = parseCardTemplate(data); err != nil {
+			return nil, fmt.Errorf("%s: %w", t, err)
+		}
+		c.dir = filepath.Dir(t)
+	}
+	return c, nil
+}
+
+func card(cmd *cobra.Command, args []string) error {
+	carder, err := newCarder(cmd, args)
+	if err != nil {
+		return err
+	}
+	return carder.card()
+}
+
+func (c *carder) card() error {
+	t := c.template
+	dst := imaging.New(t.width, t.height, t.background)
+	if t.backgroundImage != "" {
+		bg, err := imaging.Open(filepath.Join(c.dir, t.backgroundImage), imaging.AutoOrientation(true))
+		if err != nil {
+			return err
+		}
+		dst = imaging.Overlay(dst, imaging.Fill(bg, t.width, t.height, imaging.Center, settings.filter), image.Point{}, 1)
+	}
+	if t.overlay.A != 0 {
+		draw.Draw(dst, dst.Rect, image.NewUniform(t.overlay), image.Point{}, draw.Over)
+	}
+
+	top := t.padding
+	if t.logo != "" {
+		logo, err := imaging.Open(filepath.Join(c.dir, t.logo), imaging.AutoOrientation(true))
+		if err != nil {
+			return err
+		}
+		logo = imaging.Fit(logo, t.width-2*t.padding, t.logoHeight, settings.filter)
+		dst = imaging.Overlay(dst, logo, image.Pt(t.padding, t.padding), 1)
+		top += logo.Bounds().Dy() + t.padding/2
+	}
+
+	maxWidth := t.width - 2*t.padding
+	title, err := c.layout(t.title, maxWidth)
+	if err != nil {
+		return err
+	}
+	subtitle, err := c.layout(t.subtitle, maxWidth)
+	if err != nil {
+		return err
+	}
+	footer, err := c.layout(t.footer, maxWidth)
+	if err != nil {
+		return err
+	}
+
+	// The footer sits at the bottom, the title and the subtitle are centered
+	// vertically in the remaining space.
+	bottom := t.height - t.padding
+	if len(footer.lines) > 0 {
+		footer.draw(dst, t.padding, bottom-footer.height())
+		bottom -= footer.height() + t.padding/2
+	}
+	gap := 0
+	if len(title.lines) > 0 && len(subtitle.lines) > 0 {
+		gap = int(t.subtitle.size / 2)
+	}
+	y := top + (bottom-top-title.height()-gap-subtitle.height())/2
+	if y < top {
+		y = top
+	}
+	title.draw(dst, t.padding, y)
+	subtitle.draw(dst, t.padding, y+title.height()+gap)
+
+	fmt.Fprintf(os.Stdout, "save image: %s\n", c.output)
+	return imaging.Save(dst, c.output, settings.encodeOptions()...)
+}
+
+// textBlock is a text wrapped into lines.
+type textBlock struct {
+	face  font.Face
+	color color.NRGBA
+	lines []string
+}
+
+// layout executes the text template and wraps the result to the maximum width.
+func (c *carder) layout(t cardText, maxWidth int) (*textBlock, error) {
+	tmpl, err := template.New("card").Option("missingkey=error").Parse(t.text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid text template: %w", err)
+	}
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, c.vars); err != nil {
+		return nil, err
+	}
+
+	ttf := goregular.TTF
+	if t.bold {
+		ttf = gobold.TTF
+	}
+	f, err := opentype.Parse(ttf)
+	if err != nil {
+		return nil, err
+	}
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: t.size, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil, err
+	}
+
+	b := &textBlock{face: face, color: t.color}
+	for _, paragraph := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			next := strings.TrimSpace(line + " " + word)
+			if line != "" && font.MeasureString(face, next).Ceil() > maxWidth {
+				b.lines = append(b.lines, line)
+				next = word
+			}
+			line = next
+		}
+		if line != "" {
+			b.lines = append(b.lines, line)
+		}
+	}
+	return b, nil
+}
+
+// height returns the height of the text block in pixels.
+func (b *textBlock) height() int {
+	return len(b.lines) * b.face.Metrics().Height.Ceil()
+}
+
+// draw draws the text block with the top left corner at (x, y).
+func (b *textBlock) draw(dst draw.Image, x, y int) {
+	m := b.face.Metrics()
+	d := &font.Drawer{Dst: dst, Src: image.NewUniform(b.color), Face: b.face}
+	for i, line := range b.lines {
+		d.Dot = fixed.P(x, y+i*m.Height.Ceil()+m.Ascent.Ceil())
+		d.DrawString(line)
+	}
+}
+
+// parseCardTemplate parses the YAML card template. It supports the subset of YAML
+// used by the templates: mappings nested by indentation and scalar values.
+func parseCardTemplate(data []byte) (cardTemplate, error) {
+	values, err := parseSimpleYAML(data)
+	if err != nil {
+		return cardTemplate{}, err
+	}
+	t := defaultCardTemplate
+
+	ints := map[string]*int{
+		"width": &t.width, "height": &t.height, "padding": &t.padding, "logo_height": &t.logoHeight,
+	}
+	strs := map[string]*string{
+		"background_image": &t.backgroundImage, "logo": &t.logo,
+		"title.text": &t.title.text, "subtitle.text": &t.subtitle.text, "footer.text": &t.footer.text,
+	}
+	colors := map[string]*color.NRGBA{
+		"background": &t.background, "overlay": &t.overlay,
+		"title.color": &t.title.color, "subtitle.color": &t.subtitle.color, "footer.color": &t.footer.color,
+	}
+	floats := map[string]*float64{
+		"title.size": &t.title.size, "subtitle.size": &t.subtitle.size, "footer.size": &t.footer.size,
+	}
+	bools := map[string]*bool{
+		"title.bold": &t.title.bold, "subtitle.bold": &t.subtitle.bold, "footer.bold": &t.footer.bold,
+	}
+
+	for key, value := range values {
+		switch {
+		case ints[key] != nil:
+			if *ints[key], err = strconv.Atoi(value); err != nil || *ints[key] < 0 {
+				return cardTemplate{}, fmt.Errorf("%s: invalid number %q", key, value)
+			}
+		case strs[key] != nil:
+			*strs[key] = value
+		case colors[key] != nil:
+			if *colors[key], err = parseHexColor(value); err != nil {
+				return cardTemplate{}, fmt.Errorf("%s: %w", key, err)
+			}
+		case floats[key] != nil:
+			if *floats[key], err = strconv.ParseFloat(value, 64); err != nil || *floats[key] <= 0 {
+				return cardTemplate{}, fmt.Errorf("%s: invalid size %q", key, value)
+			}
+		case bools[key] != nil:
+			if *bools[key], err = strconv.ParseBool(value); err != nil {
+				return cardTemplate{}, fmt.Errorf("%s: invalid boolean %q", key, value)
+			}
+		default:
+			return cardTemplate{}, fmt.Errorf("unknown key %q", key)
+		}
+	}
+	if t.width == 0 || t.height == 0 {
+		return cardTemplate{}, errors.New("width and height must be positive")
+	}
+	return t, nil
+}
+
+// parseSimpleYAML parses YAML mappings with scalar values and returns the values
+// with the keys of the nested mappings joined by dots (e.g. "title.size").
+func parseSimpleYAML(data []byte) (map[string]string, error) {
+	values := map[string]string{}
+	type parent struct {
+		indent int
+		key    string
+	}
+	var parents []parent
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimRight(sc.Text(), " \t\r")
+		content := strings.TrimLeft(line, " ")
+		if content == "" || content[0] == '#' || content == "---" {
+			continue
+		}
+		if strings.HasPrefix(content, "\t") || strings.HasPrefix(content, "- ") {
+			return nil, fmt.Errorf("line %d: only mappings indented with spaces are supported", n)
+		}
+		indent := len(line) - len(content)
+		key, value, ok := strings.Cut(content, ":")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("line %d: expected key: value", n)
+		}
+
+		for len(parents) > 0 && parents[len(parents)-1].indent >= indent {
+			parents = parents[:len(parents)-1]
+		}
+		if len(parents) > 0 {
+			key = parents[len(parents)-1].key + "." + key
+		}
+
+		value, err := parseYAMLScalar(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		if value == "" {
+			// A nested mapping follows.
+			parents = append(parents, parent{indent: indent, key: key})
+			continue
+		}
+		values[key] = value
+	}
+	return values, sc.Err()
+}
+
+// parseYAMLScalar returns the value of a plain, single-quoted or double-quoted YAML scalar
+// without the trailing comment.
+func parseYAMLScalar(s string) (string, error) {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		end := 1
+		for ; end < len(s); end++ {
+			if s[end] == '\\' {
+				end++
+			} else if s[end] == '"' {
+				break
+			}
+		}
+		if end >= len(s) {
+			return "", errors.New("unterminated double-quoted string")
+		}
+		if rest := strings.TrimSpace(s[end+1:]); rest != "" && rest[0] != '#' {
+			return "", fmt.Errorf("unexpected %q after the string", rest)
+		}
+		return strconv.Unquote(s[:end+1])
+	case strings.HasPrefix(s, `'`):
+		var b strings.Builder
+		for i := 1; i < len(s); i++ {
+			if s[i] != '\'' {
+				b.WriteByte(s[i])
+				continue
+			}
+			if i+1 < len(s) && s[i+1] == '\'' {
+				b.WriteByte('\'')
+				i++
+				continue
+			}
+			if rest := strings.TrimSpace(s[i+1:]); rest != "" && rest[0] != '#' {
+				return "", fmt.Errorf("unexpected %q after the string", rest)
+			}
+			return b.String(), nil
+		}
+		return "", errors.New("unterminated single-quoted string")
+	}
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = s[:i]
+	}
+	if strings.HasPrefix(s, "#") {
+		return "", nil
+	}
+	return strings.TrimSpace(s), nil
+}
+
+// parseHexColor parses a hex color ("#rgb", "#rrggbb" or "#rrggbbaa").
+func parseHexColor(s string) (color.NRGBA, error) {
+	h := strings.TrimPrefix(s, "#")
+	if len(h) == 3 {
+		h = string([]byte{h[0], h[0], h[1], h[1], h[2], h[2]})
+	}
+	b, err := hex.DecodeString(h)
+	if err != nil || !strings.HasPrefix(s, "#") || (len(b) != 3 && len(b) != 4) {
+		return color.NRGBA{}, fmt.Errorf("invalid color %q", s)
+	}
+	c := color.NRGBA{R: b[0], G: b[1], B: b[2], A: 0xff}
+	if len(b) == 4 {
+		c.A = b[3]
+	}
+	return c, nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// completionFunc returns the shell completion suggestions of a flag value.
+type completionFunc func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective)
+
+// registerFlagCompletion registers the completion of the flag values. It panics if the
+// flag doesn't exist, which is a programming error.
+func registerFlagCompletion(cmd *cobra.Command, flag string, fn completionFunc) {
+	if err := cmd.RegisterFlagCompletionFunc(flag, fn); err != nil {
+		panic(err)
+	}
+}
+
+// completeValues returns a completion function that suggests the values returned by
+// names. The names are taken when the completion is requested, so the values registered
+// at run time (e.g. the image formats) are included. For the flags that take a
+// comma-separated list, the values already typed are kept.
+func completeValues(names func() []string) completionFunc {
+	return func(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		prefix := ""
+		if i := strings.LastIndex(toComplete, ","); i >= 0 {
+			prefix = toComplete[:i+1]
+		}
+		var values []string
+		for _, name := range names() {
+			if strings.HasPrefix(prefix+name, toComplete) {
+				values = append(values, prefix+name)
+			}
+		}
+		return values, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeProfiles suggests the built-in profiles and the profiles of the config file.
+func completeProfiles(cmd *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return completeValues(func() []string {
+		values := map[string]string{}
+		if _, data, err := readConfigFile(cmd); err == nil {
+			if parsed, err := parseSimpleYAML(data); err == nil {
+				values = parsed
+			}
+		}
+		profiles, err := splitProfiles(values)
+		if err != nil {
+			profiles, _ = splitProfiles(map[string]string{})
+		}
+		return profileNames(profiles)
+	})(cmd, nil, toComplete)
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-spectest/imaging"
+	"github.com/spf13/cobra"
+)
+
+// settings are the global settings of the running command. They are resolved from
+// the flags, the config file and the selected profile before the command runs.
+var settings = defaultSettings() //nolint
+
+// globalSettings are the settings shared by all the commands.
+type globalSettings struct {
+	// quality is the JPEG quality of the saved images.
+	quality int
+	// filter is the resample filter used to resize the images.
+	filter imaging.ResampleFilter
+	// workers is the number of files processed in parallel by the batch commands.
+	workers int
+	// rate is the maximum number of files started per second by the batch commands (0 means no limit).
+	rate float64
+}
+
+// defaultSettings returns the settings used without a config file.
+func defaultSettings() globalSettings {
+	return globalSettings{quality: 95, filter: imaging.Lanczos, workers: 1}
+}
+
+// encodeOptions returns the options used to save the images.
+func (s globalSettings) encodeOptions(opts ...imaging.EncodeOption) []imaging.EncodeOption {
+	return append([]imaging.EncodeOption{imaging.JPEGQuality(s.quality)}, opts...)
+}
+
+// builtinProfiles are the profiles available without a config file. The profiles
+// of the config file with the same names override their values.
+var builtinProfiles = map[string]map[string]string{ //nolint
+	"web": {
+		"quality": "80",
+		"filter":  "catmullrom",
+	},
+	"print": {
+		"quality": "100",
+		"filter":  "lanczos",
+	},
+}
+
+// addConfigFlags adds the flags that select the config file and the profile, and
+// the flags that override the global settings, to the root command.
+func addConfigFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().String("config", "", "config file (default: ~/.config/gina/config.yaml)")
+	cmd.PersistentFlags().String("profile", "", "profile of the config file to use (built-in: web, print)")
+	cmd.PersistentFlags().Int("quality", 0, "JPEG quality of the saved images, range [1, 100] (default 95)")
+	cmd.PersistentFlags().String("filter", "", "resample filter used to resize the images (default lanczos)")
+	cmd.PersistentFlags().Int("workers", 0, "number of files processed in parallel by the batch commands (default 1)")
+	cmd.PersistentFlags().String("rate", "", "maximum rate of files processed by the batch commands, e.g. 10/s or 100/m (default: no limit)")
+	registerFlagCompletion(cmd, "profile", completeProfiles)
+	registerFlagCompletion(cmd, "filter", completeValues(imaging.ResampleFilterNames))
+}
+
+// loadConfig reads the config file and the profile selected by the flags, and
+// applies them to the global settings and to the defaults of the command flags.
+// The flags set on the command line take precedence over the config file.
+func loadConfig(cmd *cobra.Command, _ []string) error {
+	profile, err := cmd.Flags().GetString("profile")
+	if err != nil {
+		return err
+	}
+	path, data, err := readConfigFile(cmd)
+	if err != nil {
+		return err
+	}
+
+	values, err := parseConfig(data, profile)
+	if err != nil {
+		return fmt.Errorf("config %s: %w", path, err)
+	}
+	flags := map[string]string{}
+	for _, name := range []string{"quality", "filter", "workers", "rate"} {
+		if f := cmd.Flags().Lookup(name); f != nil && f.Changed {
+			flags[name] = f.Value.String()
+			values[name] = flags[name]
+		}
+	}
+	if err := applyConfig(cmd, flags); err != nil {
+		return fmt.Errorf("--%w", err)
+	}
+	if err := applyConfig(cmd, values); err != nil {
+		return fmt.Errorf("config %s: %w", path, err)
+	}
+	// The CPUs are shared by the images processed in parallel.
+	procs := 0
+	if settings.workers > 1 {
+		procs = (runtime.GOMAXPROCS(0) + settings.workers - 1) / settings.workers
+	}
+	imaging.SetMaxProcs(procs)
+	return nil
+}
+
+// readConfigFile returns the path and the content of the config file given by the
+// --config flag or the default one. A missing default config file is not an error.
+func readConfigFile(cmd *cobra.Command) (string, []byte, error) {
+	path, err := cmd.Flags().GetString("config")
+	if err != nil {
+		return "", nil, err
+	}
+	explicit := path != ""
+	if !explicit {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			dir = ""
+		}
+		path = filepath.Join(dir, "gina", "config.yaml")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && (explicit || !errors.Is(err, fs.ErrNotExist)) {
+		return "", nil, fmt.Errorf("config: %w", err)
+	}
+	return path, data, nil
+}
+
+// parseConfig returns the values of the config file with the values of the profile
+// merged in.
+func parseConfig(data []byte, profile string) (map[string]string, error) {
+	values, err := parseSimpleYAML(data)
+	if err != nil {
+		return nil, err
+	}
+	profiles, err := splitProfiles(values)
+	if err != nil {
+		return nil, err
+	}
+	if profile == "" {
+		return values, nil
+	}
+	p, ok := profiles[profile]
+	if !ok {
+		return nil, fmt.Errorf("unknown profile %q (available: %s)", profile, strings.Join(profileNames(profiles), ", "))
+	}
+	for key, value := range p {
+		values[key] = value
+	}
+	return values, nil
+}
+
+// splitProfiles removes the values of the "profiles" mapping of the config file from
+// values and returns them by profile name, together with the built-in profiles.
+func splitProfiles(values map[string]string) (map[string]map[string]string, error) {
+	profiles := map[string]map[string]string{}
+	for name, p := range builtinProfiles {
+		profiles[name] = map[string]string{}
+		for key, value := range p {
+			profiles[name][key] = value
+		}
+	}
+	for key, value := range values {
+		rest, ok := strings.CutPrefix(key, "profiles.")
+		if !ok {
+			continue
+		}
+		delete(values, key)
+		name, key, ok := strings.Cut(rest, ".")
+		if !ok {
+			return nil, fmt.Errorf("profile %q must be a mapping", name)
+		}
+		if profiles[name] == nil {
+			profiles[name] = map[string]string{}
+		}
+		profiles[name][key] = value
+	}
+	return profiles, nil
+}
+
+// profileNames returns the sorted names of the profiles.
+func profileNames(profiles map[string]map[string]string) []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyConfig sets the global settings and the defaults of the flags of the running
+// command. The keys in the "command.flag" form set the default of the flag of the
+// command (e.g. "organize.template" sets the default output template of organize).
+func applyConfig(cmd *cobra.Command, values map[string]string) error {
+	s := defaultSettings()
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := values[key]
+		switch key {
+		case "quality":
+			q, err := strconv.Atoi(value)
+			if err != nil || q < 1 || q > 100 {
+				return fmt.Errorf("quality: must be in range [1, 100], got %q", value)
+			}
+			s.quality = q
+		case "filter":
+			f, err := imaging.ResampleFilterByName(value)
+			if err != nil {
+				return fmt.Errorf("filter: %w", err)
+			}
+			s.filter = f
+		case "workers":
+			w, err := strconv.Atoi(value)
+			if err != nil || w < 1 {
+				return fmt.Errorf("workers: must be a positive integer, got %q", value)
+			}
+			s.workers = w
+		case "rate":
+			r, err := parseRate(value)
+			if err != nil {
+				return fmt.Errorf("rate: %w", err)
+			}
+			s.rate = r
+		default:
+			name, flag, ok := strings.Cut(key, ".")
+			if !ok {
+				return fmt.Errorf("unknown key %q", key)
+			}
+			sub, _, err := cmd.Root().Find([]string{name})
+			if err != nil || sub == cmd.Root() || sub.Name() != name {
+				return fmt.Errorf("%s: unknown command %q", key, name)
+			}
+			f := sub.Flags().Lookup(flag)
+			if f == nil {
+				return fmt.Errorf("%s: unknown flag %q of the %s command", key, flag, name)
+			}
+			if sub != cmd || f.Changed {
+				continue
+			}
+			if err := f.Value.Set(value); err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+		}
+	}
+	settings = s
+	return nil
+}
+
+// parseRate parses a rate in the N/s, N/m or N/h form (or just N per second) and
+// returns it per second. The rate 0 means no limit.
+func parseRate(s string) (float64, error) {
+	n, unit, _ := strings.Cut(strings.TrimSpace(s), "/")
+	r, err := strconv.ParseFloat(n, 64)
+	if err != nil || r < 0 || math.IsInf(r, 0) {
+		return 0, fmt.Errorf("invalid rate %q, expected a rate like 10/s", s)
+	}
+	switch unit {
+	case "", "s":
+		return r, nil
+	case "m":
+		return r / 60, nil
+	case "h":
+		return r / 3600, nil
+	}
+	return 0, fmt.Errorf("invalid rate unit %q, expected s, m or h", unit)
+}
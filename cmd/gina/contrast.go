@@ -69,5 +69,5 @@ func (c *contraster) contrast() error {
 
 	dst := imaging.AdjustContrast(src, float64(c.percentage))
 	fmt.Fprintf(os.Stdout, "save image: %s\n", c.output)
-	return imaging.Save(dst, c.output)
+	return imaging.Save(dst, c.output, settings.encodeOptions()...)
 }
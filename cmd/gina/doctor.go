@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-spectest/imaging"
+	"github.com/spf13/cobra"
+)
+
+func newDoctorCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "doctor",
+		Short: "Find broken and problematic images",
+		Long: `Validate the images in the given directories (recursively) and files and report
+the problems with suggested fixes.
+
+The checks and their issue codes are:
+  unsupported-format     the file is not in a supported image format
+  extension-mismatch     the file extension doesn't match the image format
+  corrupt                the image can't be decoded
+  truncated              the JPEG image is incomplete
+  absurd-dimensions      the image is empty, larger than --max-dim or extremely narrow
+  missing-color-profile  the image has no embedded ICC color profile (info)
+
+The report is printed in JSON (the default) or text format. The command fails if
+any image has a problem of the error severity. Hidden files and directories are skipped.`,
+		Example: `   gina doctor photos/
+   gina doctor --format text --ignore missing-color-profile uploads/`,
+		RunE: doctor,
+	}
+
+	cmd.Flags().StringP("format", "f", "json", "report format (json or text)")
+	cmd.Flags().Int("max-dim", 30000, "maximum sensible width and height of the images")
+	cmd.Flags().StringSlice("ignore", nil, "issue codes to leave out of the report")
+
+	return &cmd
+}
+
+// Severities of the doctor issues.
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+	severityInfo    = "info"
+)
+
+// maxAspectRatio is the aspect ratio above which the dimensions are considered absurd.
+const maxAspectRatio = 100
+
+// doctorIssue is a problem found in an image file.
+type doctorIssue struct {
+	File     string `json:"file"`
+	Code     string `json:"code"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Fix      string `json:"fix"`
+}
+
+// doctorReport is the result of the validation.
+type doctorReport struct {
+	// Checked is the number of checked files.
+	Checked int `json:"checked"`
+	// Issues are the problems found.
+	Issues []doctorIssue `json:"issues"`
+}
+
+// doctorer have options for validate images.
+type doctorer struct {
+	format string
+	maxDim int
+	ignore map[string]bool
+	inputs []string
+}
+
+// newDoctorer returns a new doctorer. It returns an error if the options are invalid.
+func newDoctorer(cmd *cobra.Command, args []string) (*doctorer, error) {
+	f, err := cmd.Flags().GetString("format")
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := cmd.Flags().GetInt("max-dim")
+	if err != nil {
+		return nil, err
+	}
+
+	ignore, err := cmd.Flags().GetStringSlice("ignore")
+	if err != nil {
+		return nil, err
+	}
+
+	if len(args) == 0 {
+		return nil, errors.New("no argument: input directory or image file path is required")
+	}
+	if f != "json" && f != "text" {
+		return nil, fmt.Errorf("--format %s: expected json or text", f)
+	}
+	if m <= 0 {
+		return nil, errors.New("--max-dim must be positive")
+	}
+
+	d := &doctorer{
+		format: f,
+		maxDim: m,
+		ignore: map[string]bool{},
+		inputs: args,
+	}
+	for _, code := range ignore {
+		d.ignore[code] = true
+	}
+	return d, nil
+}
+
+func doctor(cmd *cobra.Command, args []string) error {
+	doctorer, err := newDoctorer(cmd, args)
+	if err != nil {
+		return err
+	}
+	return doctorer.doctor(os.Stdout)
+}
+
+func (d *doctorer) doctor(w io.Writer) error {
+	report := doctorReport{Issues: []doctorIssue{}}
+	for _, input := range d.inputs {
+		err := filepath.WalkDir(input, func(path string, e fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if path != input && strings.HasPrefix(e.Name(), ".") {
+				if e.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if e.IsDir() {
+				return nil
+			}
+			issues, err := d.check(path)
+			if err != nil {
+				return err
+			}
+			report.Checked++
+			for _, issue := range issues {
+				if !d.ignore[issue.Code] {
+					report.Issues = append(report.Issues, issue)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := report.write(w, d.format); err != nil {
+		return err
+	}
+	failed := map[string]bool{}
+	for _, issue := range report.Issues {
+		if issue.Severity == severityError {
+			failed[issue.File] = true
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d files have errors", len(failed), report.Checked)
+	}
+	return nil
+}
+
+// check validates the image file and returns the problems found.
+func (d *doctorer) check(path string) ([]doctorIssue, error) {
+	issue := func(code, severity, message, fix string) doctorIssue {
+		return doctorIssue{File: path, Code: code, Severity: severity, Message: message, Fix: fix}
+	}
+
+	extFormat, extErr := imaging.FormatFromFilename(path)
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	info, err := imaging.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		if extErr != nil || errors.Is(err, imaging.ErrUnsupportedFormat) {
+			return []doctorIssue{issue("unsupported-format", severityError, "the file is not in a supported image format",
+				"convert the image to JPEG or PNG, or remove the file")}, nil
+		}
+		return []doctorIssue{issue("corrupt", severityError, fmt.Sprintf("the image header can't be read: %v", err),
+			"restore the file from a backup or export it again from the original")}, nil
+	}
+
+	var issues []doctorIssue
+	if extErr != nil || extFormat != info.Format {
+		ext := extensions[info.Format]
+		issues = append(issues, issue("extension-mismatch", severityWarning,
+			fmt.Sprintf("the file is in %s format", info.Format),
+			fmt.Sprintf("rename the file to %s", strings.TrimSuffix(path, filepath.Ext(path))+ext)))
+	}
+
+	w, h := info.Width, info.Height
+	if w <= 0 || h <= 0 || w > d.maxDim || h > d.maxDim || w > maxAspectRatio*h || h > maxAspectRatio*w {
+		issues = append(issues, issue("absurd-dimensions", severityError,
+			fmt.Sprintf("the image is %dx%d pixels", w, h),
+			"check how the image was produced and resize or crop it with an image editor"))
+		// Decoding such images may exhaust the memory.
+		return issues, nil
+	}
+
+	if info.Format == imaging.JPEG && isTruncatedJPEG(data) {
+		return append(issues, issue("truncated", severityError, "the JPEG image is incomplete, the end of the image data is missing",
+			"download or copy the file again, or export it again from the original")), nil
+	}
+	if _, err := imaging.Decode(bytes.NewReader(data)); err != nil {
+		return append(issues, issue("corrupt", severityError, fmt.Sprintf("the image can't be decoded: %v", err),
+			"restore the file from a backup or export it again from the original")), nil
+	}
+
+	if info.Format == imaging.JPEG || info.Format == imaging.PNG || info.Format == imaging.TIFF {
+		meta, err := imaging.DecodeMetadata(bytes.NewReader(data))
+		if err == nil && len(meta.ICCProfile) == 0 {
+			issues = append(issues, issue("missing-color-profile", severityInfo,
+				"the image has no embedded color profile, the colors are interpreted as sRGB",
+				"embed the color profile when exporting if the image is not in sRGB"))
+		}
+	}
+	return issues, nil
+}
+
+// isTruncatedJPEG reports whether the JPEG image lacks the end of image marker after
+// the last start of scan marker. The markers can't appear in the compressed image data.
+func isTruncatedJPEG(data []byte) bool {
+	return bytes.LastIndex(data, []byte{0xff, 0xd9}) < bytes.LastIndex(data, []byte{0xff, 0xda})
+}
+
+// write writes the report in the format (json or text).
+func (r *doctorReport) write(w io.Writer, format string) error {
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	}
+	for _, issue := range r.Issues {
+		if _, err := fmt.Fprintf(w, "%s: %s: %s: %s\n  fix: %s\n", issue.File, issue.Severity, issue.Code, issue.Message, issue.Fix); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "checked %d files, found %d issues\n", r.Checked, len(r.Issues))
+	return err
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-spectest/imaging"
+	"github.com/spf13/cobra"
+)
+
+func newEnhanceCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "enhance",
+		Short: "Apply automatic photo corrections to images",
+		Long: `Apply automatic photo corrections to one or more images.
+
+The corrections are applied in the following order: denoise, white balance,
+exposure and clarity. Each output image is saved next to its input with the
+--suffix added to the name, or into the --output directory if it is given.
+The EXIF data is kept and the EXIF orientation is applied.
+
+With --manifest, the content hashes of the inputs and the corrections applied are
+recorded in the manifest file, and the inputs unchanged since the previous run are
+skipped, so a repeated run only processes the new and modified images.`,
+		Example: "   gina enhance --auto-wb --auto-exposure --clarity 0.2 --denoise *.jpg",
+		RunE:    enhance,
+	}
+
+	cmd.Flags().Bool("auto-wb", false, "remove the color cast (gray world white balance)")
+	cmd.Flags().Bool("auto-exposure", false, "stretch the levels and correct the brightness")
+	cmd.Flags().Float64("clarity", 0, "local contrast of the midtones, typically in range (-1, 1)")
+	cmd.Flags().Float64("denoise", 0, "strength of the noise reduction, typically from 10 to 30")
+	cmd.Flags().Lookup("denoise").NoOptDefVal = "20"
+	cmd.Flags().StringP("suffix", "s", "_enhanced", "suffix added to the output filenames")
+	cmd.Flags().StringP("output", "o", "", "output directory (default: the directory of each input image)")
+	cmd.Flags().String("manifest", "", "manifest file recording the processed images, to skip the unchanged inputs")
+	addBatchFlags(&cmd)
+
+	return &cmd
+}
+
+// enhancer have options for enhance images.
+type enhancer struct {
+	autoWB       bool
+	autoExposure bool
+	clarity      float64
+	denoise      float64
+	suffix       string
+	output       string
+	inputs       []string
+	batch        *batcher
+	// manifest records the processed images, nil means every input is processed.
+	manifest *imaging.Manifest
+}
+
+// newEnhancer returns a new enhancer. It returns an error if the required options are not set.
+func newEnhancer(cmd *cobra.Command, args []string) (*enhancer, error) {
+	wb, err := cmd.Flags().GetBool("auto-wb")
+	if err != nil {
+		return nil, err
+	}
+
+	exposure, err := cmd.Flags().GetBool("auto-exposure")
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := cmd.Flags().GetFloat64("clarity")
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := cmd.Flags().GetFloat64("denoise")
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := cmd.Flags().GetString("suffix")
+	if err != nil {
+		return nil, err
+	}
+
+	o, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := cmd.Flags().GetString("manifest")
+	if err != nil {
+		return nil, err
+	}
+
+	batch, err := newBatcher(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(args) == 0 {
+		return nil, errors.New("no argument: input image file path is required")
+	}
+	if d < 0 {
+		return nil, errors.New("--denoise must not be negative")
+	}
+	if s == "" && o == "" {
+		return nil, errors.New("--suffix or --output is required to keep the input images")
+	}
+
+	var manifest *imaging.Manifest
+	if m != "" {
+		if manifest, err = imaging.LoadManifest(m); err != nil {
+			return nil, err
+		}
+	}
+
+	return &enhancer{
+		autoWB:       wb,
+		autoExposure: exposure,
+		clarity:      c,
+		denoise:      d,
+		suffix:       s,
+		output:       o,
+		inputs:       args,
+		batch:        batch,
+		manifest:     manifest,
+	}, nil
+}
+
+func enhance(cmd *cobra.Command, args []string) error {
+	enhancer, err := newEnhancer(cmd, args)
+	if err != nil {
+		return err
+	}
+	return enhancer.enhance()
+}
+
+func (e *enhancer) enhance() error {
+	if e.output != "" && !isURL(e.output) && !e.batch.plan {
+		if err := os.MkdirAll(e.output, 0o755); err != nil {
+			return err
+		}
+	}
+	if e.batch.plan {
+		return e.batch.runPlan(e.inputs, e.planFile)
+	}
+	err := e.batch.run(e.inputs, e.enhanceFile)
+	if e.manifest != nil {
+		// The manifest is saved even if some files failed, to keep the others.
+		if saveErr := e.manifest.Save(); saveErr != nil && err == nil {
+			err = saveErr
+		}
+	}
+	return err
+}
+
+// enhanceFile applies the corrections to a single image and returns the output path.
+func (e *enhancer) enhanceFile(input string) (string, error) {
+	output := e.outputPath(input)
+	var entry imaging.ManifestEntry
+	if e.manifest != nil {
+		hash, err := imaging.HashFile(input)
+		if err != nil {
+			return "", err
+		}
+		entry = imaging.ManifestEntry{Source: input, SourceHash: hash, Spec: e.spec()}
+		if e.manifest.UpToDate(output, entry) {
+			return output, errUnchanged
+		}
+	}
+
+	src, meta, err := imaging.OpenWithMetadata(input, imaging.AutoOrientation(true))
+	if err != nil {
+		return "", err
+	}
+
+	dst := imaging.Clone(src)
+	if e.denoise > 0 {
+		dst = imaging.Denoise(dst, e.denoise)
+	}
+	if e.autoWB {
+		dst = imaging.AutoWhiteBalance(dst)
+	}
+	if e.autoExposure {
+		dst = imaging.AutoExposure(dst)
+	}
+	if e.clarity != 0 {
+		dst = imaging.Clarity(dst, e.clarity)
+	}
+
+	if err := imaging.SaveAtomic(dst, output, settings.encodeOptions(imaging.WithMetadata(meta))...); err != nil {
+		return output, err
+	}
+	if e.manifest != nil {
+		e.manifest.Record(output, entry)
+	}
+	return output, nil
+}
+
+// planFile returns the output path of the input and the action planned for it.
+func (e *enhancer) planFile(input string) (string, string, error) {
+	output := e.outputPath(input)
+	if e.manifest != nil {
+		hash, err := imaging.HashFile(input)
+		if err != nil {
+			return "", "", err
+		}
+		entry := imaging.ManifestEntry{Source: input, SourceHash: hash, Spec: e.spec()}
+		if e.manifest.UpToDate(output, entry) {
+			return output, actionSkip, nil
+		}
+	}
+	return output, outputAction(output), nil
+}
+
+// spec describes the corrections recorded in the manifest, so that the images are
+// processed again when they change.
+func (e *enhancer) spec() string {
+	return fmt.Sprintf("enhance denoise=%g auto-wb=%t auto-exposure=%t clarity=%g quality=%d",
+		e.denoise, e.autoWB, e.autoExposure, e.clarity, settings.quality)
+}
+
+// outputPath returns the output filename of the input image.
+func (e *enhancer) outputPath(input string) string {
+	ext := filepath.Ext(input)
+	name := strings.TrimSuffix(filepath.Base(input), ext) + e.suffix + ext
+	dir := e.output
+	if dir == "" {
+		dir = dirPath(input)
+	}
+	return joinPath(dir, name)
+}
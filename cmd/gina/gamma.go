@@ -69,5 +69,5 @@ func (g *gammer) adjustGammer() error {
 
 	dst := imaging.AdjustGamma(src, g.gamma)
 	fmt.Fprintf(os.Stdout, "save image: %s\n", g.output)
-	return imaging.Save(dst, g.output)
+	return imaging.Save(dst, g.output, settings.encodeOptions()...)
 }
@@ -6,11 +6,14 @@ require (
 	github.com/go-spectest/imaging v1.0.6
 	github.com/google/go-cmp v0.6.0
 	github.com/spf13/cobra v1.7.0
+	github.com/spf13/pflag v1.0.5
+	golang.org/x/image v0.13.0
 )
 
 require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/spf13/pflag v1.0.5 // indirect
-	golang.org/x/image v0.13.0 // indirect
 	golang.org/x/sync v0.4.0 // indirect
+	golang.org/x/text v0.13.0 // indirect
 )
+
+replace github.com/go-spectest/imaging => ../..
@@ -10,9 +10,10 @@ import (
 
 // main is entry point of gina command.
 func main() {
+	registerStorages()
 	if err := newRootCmd().Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }
 
@@ -23,9 +24,19 @@ func newRootCmd() *cobra.Command {
 		Long: `gina --Go Image 'N' Assistance-- is simple image processing CLI tool.
 
 The gina was created to help developers understand 'how to use the image
-processing methods provided by the go-spectest/imaging package'.`,
+processing methods provided by the go-spectest/imaging package'.
+
+Exit status:
+  0  success, batch commands (enhance, organize) processed every file
+  1  fatal error, e.g. invalid flags or config file, nothing was processed
+  2  partial failure, a batch command processed the files but some of them failed
+
+The batch commands process --workers files in parallel, at most at the --rate
+(e.g. 10/s or 600/m), when the outputs go to network file systems or rate-limited
+object stores.`,
 	}
-	cmd.CompletionOptions.DisableDefaultCmd = true
+	cmd.PersistentPreRunE = loadConfig
+	addConfigFlags(cmd)
 	cmd.SilenceUsage = true
 	cmd.SilenceErrors = true
 
@@ -36,5 +47,12 @@ processing methods provided by the go-spectest/imaging package'.`,
 	cmd.AddCommand(newBlurCmd())
 	cmd.AddCommand(newContrastCmd())
 	cmd.AddCommand(newGammaCmd())
+	cmd.AddCommand(newOrganizeCmd())
+	cmd.AddCommand(newEnhanceCmd())
+	cmd.AddCommand(newScanCmd())
+	cmd.AddCommand(newCardCmd())
+	cmd.AddCommand(newASCIICmd())
+	cmd.AddCommand(newBenchCmd())
+	cmd.AddCommand(newDoctorCmd())
 	return cmd
 }
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/go-spectest/imaging"
+	"github.com/spf13/cobra"
+)
+
+func newOrganizeCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "organize",
+		Short: "Rename and convert photos into a dated library",
+		Long: `Copy the images into the output directory under the names generated by the template.
+
+The template uses the Go text/template syntax with the following fields:
+  .Date  the date the photo was taken (EXIF) or the file modification time
+  .Name  the input filename without the extension
+  .Ext   the extension of the output format, including the dot
+
+The output format is determined from the extension of the generated name. Images are
+copied as is unless the format changes or the image exceeds --max-dim, in which case
+they are re-encoded with the EXIF orientation applied, keeping the EXIF data.
+Existing files are never overwritten: a numeric suffix is added to the name instead.`,
+		Example: `   gina organize --template '{{.Date.Format "2006/01"}}/{{.Name}}.jpg' --convert jpeg --max-dim 4000 -o library *.jpg`,
+		RunE:    organize,
+	}
+
+	cmd.Flags().StringP("template", "t", `{{.Date.Format "2006/01/02"}}/{{.Name}}{{.Ext}}`, "template of the output path")
+	cmd.Flags().StringP("convert", "c", "", "output format (supported format: jpg, png, gif, tiff, bmp)")
+	cmd.Flags().IntP("max-dim", "m", 0, "maximum width and height of output image (0 means no limit)")
+	cmd.Flags().StringP("output", "o", ".", "output directory")
+	cmd.Flags().BoolP("dry-run", "n", false, "print the output paths without writing any files")
+	addBatchFlags(&cmd)
+	registerFlagCompletion(&cmd, "convert", completeValues(imaging.FormatExtensions))
+
+	return &cmd
+}
+
+// organizer have options for organize images.
+type organizer struct {
+	template *template.Template
+	convert  *imaging.Format
+	maxDim   int
+	output   string
+	dryRun   bool
+	inputs   []string
+	batch    *batcher
+	// mu guards reserved, the output paths taken by the files processed in parallel.
+	mu       sync.Mutex
+	reserved map[string]bool
+}
+
+// organizeFields is the data passed to the organize template.
+type organizeFields struct {
+	// Date is the date the photo was taken.
+	Date time.Time
+	// Name is the input filename without the extension.
+	Name string
+	// Ext is the extension of the output format, including the dot.
+	Ext string
+}
+
+// newOrganizer returns a new organizer. It returns an error if the required options are not set.
+func newOrganizer(cmd *cobra.Command, args []string) (*organizer, error) {
+	t, err := cmd.Flags().GetString("template")
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := cmd.Flags().GetString("convert")
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := cmd.Flags().GetInt("max-dim")
+	if err != nil {
+		return nil, err
+	}
+
+	o, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		return nil, err
+	}
+
+	batch, err := newBatcher(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(args) == 0 {
+		return nil, errors.New("no argument: input image file path is required")
+	}
+	if m < 0 {
+		return nil, errors.New("--max-dim must not be negative")
+	}
+	if isURL(o) {
+		return nil, fmt.Errorf("--output %s: organize writes to local directories only", o)
+	}
+
+	tmpl, err := template.New("organize").Option("missingkey=error").Parse(t)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+
+	var convert *imaging.Format
+	if c != "" {
+		f, err := imaging.FormatFromExtension(c)
+		if err != nil {
+			return nil, fmt.Errorf("--convert %s: %w", c, err)
+		}
+		convert = &f
+	}
+
+	return &organizer{
+		template: tmpl,
+		convert:  convert,
+		maxDim:   m,
+		output:   o,
+		dryRun:   n,
+		inputs:   args,
+		batch:    batch,
+		reserved: map[string]bool{},
+	}, nil
+}
+
+func organize(cmd *cobra.Command, args []string) error {
+	organizer, err := newOrganizer(cmd, args)
+	if err != nil {
+		return err
+	}
+	return organizer.organize()
+}
+
+func (o *organizer) organize() error {
+	if o.batch.plan {
+		// The existing files are never overwritten, so every output is created.
+		o.dryRun = true
+		return o.batch.runPlan(o.inputs, func(input string) (string, string, error) {
+			output, err := o.organizeFile(input)
+			return output, actionCreate, err
+		})
+	}
+	return o.batch.run(o.inputs, o.organizeFile)
+}
+
+// organizeFile copies or converts a single image to its place in the library
+// and returns the output path.
+func (o *organizer) organizeFile(input string) (string, error) {
+	srcFormat, err := imaging.FormatFromFilename(input)
+	if err != nil {
+		return "", err
+	}
+	dstFormat := srcFormat
+	if o.convert != nil {
+		dstFormat = *o.convert
+	}
+
+	fields := organizeFields{
+		Name: strings.TrimSuffix(filepath.Base(input), filepath.Ext(input)),
+		Ext:  extensions[dstFormat],
+	}
+	if fields.Date, err = captureDate(input); err != nil {
+		return "", err
+	}
+
+	name := &bytes.Buffer{}
+	if err := o.template.Execute(name, fields); err != nil {
+		return "", err
+	}
+	output := filepath.Join(o.output, filepath.FromSlash(strings.TrimSpace(name.String())))
+	f, err := imaging.FormatFromFilename(output)
+	if err != nil {
+		return "", fmt.Errorf("output %s: %w", output, err)
+	}
+	if o.convert != nil && f != dstFormat {
+		return "", fmt.Errorf("output %s: the extension does not match --convert %s", output, dstFormat)
+	}
+	dstFormat = f
+
+	if output, err = o.reservePath(output); err != nil {
+		return "", err
+	}
+	if o.dryRun {
+		return output, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(output), 0o755); err != nil {
+		return output, err
+	}
+
+	if dstFormat == srcFormat && o.maxDim == 0 {
+		return output, copyFile(input, output)
+	}
+	src, meta, err := imaging.OpenWithMetadata(input, imaging.AutoOrientation(true))
+	if err != nil {
+		return output, err
+	}
+	b := src.Bounds()
+	if dstFormat == srcFormat && b.Dx() <= o.maxDim && b.Dy() <= o.maxDim {
+		return output, copyFile(input, output)
+	}
+	if o.maxDim > 0 {
+		src = imaging.Fit(src, o.maxDim, o.maxDim, settings.filter)
+	}
+	return output, imaging.SaveAtomic(src, output, settings.encodeOptions(imaging.WithMetadata(meta))...)
+}
+
+// extensions maps the image formats to the extensions used for .Ext.
+var extensions = map[imaging.Format]string{ //nolint
+	imaging.JPEG: ".jpg",
+	imaging.PNG:  ".png",
+	imaging.GIF:  ".gif",
+	imaging.TIFF: ".tif",
+	imaging.BMP:  ".bmp",
+}
+
+// captureDate returns the date the photo was taken from the EXIF data,
+// or the modification time of the file if the date is not recorded.
+func captureDate(filename string) (time.Time, error) {
+	meta, err := imaging.OpenMetadata(filename)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if meta.EXIF != nil {
+		if t, ok := meta.EXIF.DateTime(); ok {
+			return t, nil
+		}
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+// reservePath returns a unique output path for the path, which isn't used by an existing
+// file or by another file processed in parallel.
+func (o *organizer) reservePath(path string) (string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	path, err := uniquePath(path, o.reserved)
+	if err != nil {
+		return "", err
+	}
+	o.reserved[path] = true
+	return path, nil
+}
+
+// uniquePath returns the path, adding a numeric suffix to the name if the file already
+// exists or the path is reserved.
+func uniquePath(path string, reserved map[string]bool) (string, error) {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for i := 1; ; i++ {
+		_, err := os.Stat(path)
+		if errors.Is(err, os.ErrNotExist) && !reserved[path] {
+			return path, nil
+		}
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+		path = fmt.Sprintf("%s_%d%s", base, i, ext)
+	}
+}
+
+// copyFile copies the file without decoding it, keeping its metadata.
+func copyFile(src, dst string) (err error) {
+	in, err := os.Open(filepath.Clean(src))
+	if err != nil {
+		return err
+	}
+	defer in.Close() //nolint
+
+	out, err := os.OpenFile(filepath.Clean(dst), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()    //nolint
+		os.Remove(dst) //nolint
+		return err
+	}
+	return out.Close()
+}
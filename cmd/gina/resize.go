@@ -3,6 +3,7 @@ package main
 import (
 	"errors"
 	"fmt"
+	"image"
 	"os"
 
 	"github.com/go-spectest/imaging"
@@ -16,25 +17,36 @@ func newResizeCmd() *cobra.Command {
 		Long: `Resize the only one image. 
 
 If you specify either the height or width, the aspect ratio will be maintained during resizing.
+The --geometry parameter accepts the ImageMagick geometry syntax (e.g. "800x600^", "50%", "1024x1024>")
+and takes precedence over the --width and --height parameters.
+If you specify the --anchor parameter together with both the width and height, the image is
+resized to cover the size and cropped to it exactly, keeping the anchor point.
 The file extension specified in the --output parameter can be different from the input image's
 extension.`,
-		Example: "   gina resize -W 100 -o output.png input.jpg",
-		RunE:    resize,
+		Example: `   gina resize -W 100 -o output.png input.jpg
+   gina resize --geometry "800x600>" -o output.png input.jpg
+   gina resize -W 1200 -H 630 --anchor top -o output.png input.jpg`,
+		RunE: resize,
 	}
 
 	cmd.Flags().IntP("width", "W", 0, "width of output image")
 	cmd.Flags().IntP("height", "H", 0, "height of output image")
+	cmd.Flags().StringP("geometry", "g", "", "size of output image in the ImageMagick geometry syntax")
 	cmd.Flags().StringP("output", "o", "output.jpg", "output filename (supported format: jpg, png, gif, tiff, bmp)")
+	cmd.Flags().String("anchor", "", "crop the image to exactly the width and height, keeping the anchor point (e.g. center, top-left)")
+	registerFlagCompletion(&cmd, "anchor", completeValues(imaging.AnchorNames))
 
 	return &cmd
 }
 
 // resize have options for resize image.
 type resizer struct {
-	width  int
-	height int
-	input  string
-	output string
+	width    int
+	height   int
+	geometry *imaging.Geometry
+	anchor   *imaging.Anchor
+	input    string
+	output   string
 }
 
 // newResizer returns a new resizer. It returns an error if the required options are not set.
@@ -49,20 +61,53 @@ func newResizer(cmd *cobra.Command, args []string) (*resizer, error) {
 		return nil, err
 	}
 
+	g, err := cmd.Flags().GetString("geometry")
+	if err != nil {
+		return nil, err
+	}
+
 	o, err := cmd.Flags().GetString("output")
 	if err != nil {
 		return nil, err
 	}
 
+	a, err := cmd.Flags().GetString("anchor")
+	if err != nil {
+		return nil, err
+	}
+
 	if len(args) == 0 {
 		return nil, errors.New("no argument: input image file path is required")
 	}
 
+	var anchor *imaging.Anchor
+	if a != "" {
+		parsed, err := imaging.ParseAnchor(a)
+		if err != nil {
+			return nil, fmt.Errorf("--anchor: %w", err)
+		}
+		if w <= 0 || h <= 0 || g != "" {
+			return nil, errors.New("--anchor requires both --width and --height, and can't be used with --geometry")
+		}
+		anchor = &parsed
+	}
+
+	var geometry *imaging.Geometry
+	if g != "" {
+		parsed, err := imaging.ParseGeometry(g)
+		if err != nil {
+			return nil, err
+		}
+		geometry = &parsed
+	}
+
 	return &resizer{
-		width:  w,
-		height: h,
-		input:  args[0],
-		output: o,
+		width:    w,
+		height:   h,
+		geometry: geometry,
+		anchor:   anchor,
+		input:    args[0],
+		output:   o,
 	}, nil
 }
 
@@ -80,7 +125,14 @@ func (r *resizer) resize() error {
 		return err
 	}
 
-	dst := imaging.Resize(src, r.width, r.height, imaging.Lanczos)
+	var dst *image.NRGBA
+	if r.geometry != nil {
+		dst = imaging.ResizeGeometry(src, *r.geometry, settings.filter)
+	} else if r.anchor != nil {
+		dst = imaging.Fill(src, r.width, r.height, *r.anchor, settings.filter)
+	} else {
+		dst = imaging.Resize(src, r.width, r.height, settings.filter)
+	}
 	fmt.Fprintf(os.Stdout, "save image: %s\n", r.output)
-	return imaging.Save(dst, r.output)
+	return imaging.Save(dst, r.output, settings.encodeOptions()...)
 }
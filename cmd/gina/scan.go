@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+
+	"github.com/go-spectest/imaging"
+	"github.com/spf13/cobra"
+)
+
+func newScanCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "scan",
+		Short: "Turn a photo of a document into a clean scan",
+		Long: `Turn a photo of a document into a clean scan.
+
+The scan subcommand detects the document (a sheet of paper brighter than the
+background), corrects the perspective, straightens the text, trims the border
+and converts the result to black and white with an adaptive threshold.
+If no document is found, the whole photo is used.
+
+The file extension specified in the --output parameter can be different from the input image's extension.`,
+		Example: "   gina scan photo-of-receipt.jpg -o receipt.png",
+		RunE:    scan,
+	}
+
+	cmd.Flags().StringP("output", "o", "output.png", "output filename (supported format: jpg, png, gif, tiff, bmp)")
+	cmd.Flags().Float64("max-skew", 10, "maximum angle in degrees that is corrected by straightening the text")
+	cmd.Flags().Float64("offset", 10, "how much darker than the surroundings a pixel must be to become black")
+	cmd.Flags().Bool("color", false, "keep the colors instead of converting the scan to black and white")
+
+	return &cmd
+}
+
+// scanner have options for scan image.
+type scanner struct {
+	maxSkew float64
+	offset  float64
+	color   bool
+	input   string
+	output  string
+}
+
+// newScanner returns a new scanner. It returns an error if the required options are not set.
+func newScanner(cmd *cobra.Command, args []string) (*scanner, error) {
+	o, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := cmd.Flags().GetFloat64("max-skew")
+	if err != nil {
+		return nil, err
+	}
+
+	off, err := cmd.Flags().GetFloat64("offset")
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := cmd.Flags().GetBool("color")
+	if err != nil {
+		return nil, err
+	}
+
+	if len(args) == 0 {
+		return nil, errors.New("no argument: input image file path is required")
+	}
+
+	return &scanner{
+		maxSkew: m,
+		offset:  off,
+		color:   c,
+		input:   args[0],
+		output:  o,
+	}, nil
+}
+
+func scan(cmd *cobra.Command, args []string) error {
+	scanner, err := newScanner(cmd, args)
+	if err != nil {
+		return err
+	}
+	return scanner.scan()
+}
+
+func (s *scanner) scan() error {
+	src, err := imaging.Open(s.input, imaging.AutoOrientation(true))
+	if err != nil {
+		return err
+	}
+
+	doc := imaging.Clone(src)
+	if corners, ok := imaging.DetectDocument(src); ok {
+		doc = imaging.PerspectiveCorrect(src, corners, 0, 0)
+	} else {
+		fmt.Fprintln(os.Stderr, "no document found, using the whole image")
+	}
+	doc, _ = imaging.Deskew(doc, s.maxSkew, color.White)
+	if trimmed := imaging.Trim(doc, 16); !trimmed.Bounds().Empty() {
+		doc = trimmed
+	}
+
+	var dst image.Image = doc
+	if !s.color {
+		b := doc.Bounds()
+		radius := b.Dx()
+		if b.Dy() > radius {
+			radius = b.Dy()
+		}
+		dst = imaging.AdaptiveThreshold(doc, radius/50+1, s.offset)
+	}
+	fmt.Fprintf(os.Stdout, "save image: %s\n", s.output)
+	return imaging.Save(dst, s.output, settings.encodeOptions()...)
+}
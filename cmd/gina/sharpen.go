@@ -72,5 +72,5 @@ func (s *sharpener) sharpen() error {
 
 	dst := imaging.Sharpen(src, s.sigma)
 	fmt.Fprintf(os.Stdout, "save image: %s\n", s.output)
-	return imaging.Save(dst, s.output)
+	return imaging.Save(dst, s.output, settings.encodeOptions()...)
 }
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/go-spectest/imaging"
+	"github.com/go-spectest/imaging/storage/azblob"
+	"github.com/go-spectest/imaging/storage/gcs"
+	"github.com/go-spectest/imaging/storage/s3"
+)
+
+// registerStorages registers the object stores gina reads and writes, configured
+// from the environment.
+func registerStorages() {
+	imaging.RegisterStorage("s3", s3.New(s3.ConfigFromEnv()))
+	imaging.RegisterStorage("gs", gcs.New(gcs.ConfigFromEnv()))
+	imaging.RegisterStorage("azblob", azblob.New(azblob.ConfigFromEnv()))
+}
+
+// isURL reports whether the path is a URL of an object store, e.g. s3://bucket/key.
+func isURL(path string) bool {
+	return strings.Contains(path, "://")
+}
+
+// joinPath joins the directory and the filename, keeping the slashes of URLs.
+func joinPath(dir, name string) string {
+	if isURL(dir) {
+		return strings.TrimSuffix(dir, "/") + "/" + name
+	}
+	return filepath.Join(dir, name)
+}
+
+// dirPath returns the directory of the path, which may be a URL.
+func dirPath(path string) string {
+	if isURL(path) {
+		return path[:strings.LastIndex(path, "/")]
+	}
+	return filepath.Dir(path)
+}
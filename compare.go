@@ -0,0 +1,171 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+)
+
+// ErrSizeMismatch means the compared images have different sizes.
+var ErrSizeMismatch = errors.New("imaging: images have different sizes")
+
+// Difference is the result of comparing two images.
+type Difference struct {
+	// Pixels is the number of the pixels with a channel differing by more than the tolerance.
+	Pixels int
+	// MaxDelta is the largest difference of a channel value (0-255) of the pixels.
+	MaxDelta int
+	// Bounds are the bounds of the differing pixels, relative to the top-left corners
+	// of the images. They are empty if the images match.
+	Bounds image.Rectangle
+}
+
+// Compare compares the images of the same size pixel by pixel. The pixels with all
+// the channels, including the alpha, within the tolerance are considered equal. It
+// returns ErrSizeMismatch if the sizes of the images differ.
+//
+// Example:
+//
+//	diff, err := imaging.Compare(got, want, 2)
+//	if err != nil || diff.Pixels > 0 {
+//		t.Fatalf("the screenshot differs in %v", diff.Bounds)
+//	}
+func Compare(a, b image.Image, tolerance int) (Difference, error) {
+	if a.Bounds().Size() != b.Bounds().Size() {
+		return Difference{}, fmt.Errorf("%w: %v and %v", ErrSizeMismatch, a.Bounds().Size(), b.Bounds().Size())
+	}
+	src1, src2 := newScanner(a), newScanner(b)
+	var d Difference
+	line1, line2 := make([]uint8, src1.w*4), make([]uint8, src2.w*4)
+	for y := 0; y < src1.h; y++ {
+		src1.scan(0, y, src1.w, y+1, line1)
+		src2.scan(0, y, src2.w, y+1, line2)
+		for x := 0; x < src1.w; x++ {
+			differs := false
+			for i := x * 4; i < x*4+4; i++ {
+				delta := absInt(int(line1[i]) - int(line2[i]))
+				if delta > d.MaxDelta {
+					d.MaxDelta = delta
+				}
+				if delta > tolerance {
+					differs = true
+				}
+			}
+			if differs {
+				d.Pixels++
+				d.Bounds = d.Bounds.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return d, nil
+}
+
+// DisplayProfile is the color space assumed for the images without an embedded ICC
+// profile when comparing them with CompareColorManaged.
+type DisplayProfile struct {
+	profile *iccProfile
+}
+
+// displayP3ToXYZ holds the D50 adapted colorants of the Display P3 color space.
+var displayP3ToXYZ = [3][3]float64{ //nolint
+	{0.5151024, 0.2919648, 0.1571553},
+	{0.2411823, 0.6922359, 0.0665818},
+	{-0.0010504, 0.0418815, 0.7843851},
+}
+
+// srgbCurve is the tone reproduction curve of sRGB.
+var srgbCurve = iccCurve{params: [7]float64{2.4, 1 / 1.055, 0.055 / 1.055, 1 / 12.92, 0.04045}} //nolint
+
+// Display profiles.
+var (
+	// ProfileSRGB assumes that the untagged images are sRGB, as most browsers and
+	// viewers do. It is the default.
+	ProfileSRGB = DisplayProfile{} //nolint
+	// ProfileDisplayP3 assumes that the untagged images are Display P3, the color
+	// space of the displays of recent Macs and iPhones.
+	ProfileDisplayP3 = DisplayProfile{&iccProfile{matrix: displayP3ToXYZ, trc: [3]iccCurve{srgbCurve, srgbCurve, srgbCurve}}} //nolint
+)
+
+// ProfileGamma returns the display profile of the sRGB primaries with the pure gamma
+// curve, e.g. 1.8 for the screenshots of old Macs or 2.2 for those of displays
+// calibrated to a plain gamma.
+func ProfileGamma(gamma float64) DisplayProfile {
+	curve := iccCurve{params: [7]float64{gamma, 1}}
+	return DisplayProfile{&iccProfile{matrix: srgbToXYZ, trc: [3]iccCurve{curve, curve, curve}}}
+}
+
+// CompareOption sets an optional parameter of CompareFiles.
+type CompareOption func(*compareConfig)
+
+type compareConfig struct {
+	tolerance    int
+	colorManaged bool
+	untagged     DisplayProfile
+}
+
+// CompareTolerance sets the tolerance of the channel values, 0 by default.
+func CompareTolerance(tolerance int) CompareOption {
+	return func(c *compareConfig) {
+		c.tolerance = tolerance
+	}
+}
+
+// CompareColorManaged enables the color managed comparison: both images are converted
+// to sRGB before the comparison, using their embedded ICC profiles as ConvertToSRGB
+// does, and assuming the display profile for the images without one. It eliminates
+// the false differences between the same page captured on a Display P3 Mac and on an
+// sRGB Linux CI runner. By default the pixel values are compared as they are.
+func CompareColorManaged(untagged DisplayProfile) CompareOption {
+	return func(c *compareConfig) {
+		c.colorManaged = true
+		c.untagged = untagged
+	}
+}
+
+// CompareFiles opens and compares the image files, e.g. a screenshot and its golden
+// file, as Compare does.
+//
+// Example:
+//
+//	diff, err := imaging.CompareFiles("golden/home.png", "out/home.png",
+//		imaging.CompareTolerance(2), imaging.CompareColorManaged(imaging.ProfileSRGB))
+func CompareFiles(a, b string, opts ...CompareOption) (Difference, error) {
+	var cfg compareConfig
+	for _, option := range opts {
+		option(&cfg)
+	}
+	img1, err := openCompared(a, &cfg)
+	if err != nil {
+		return Difference{}, err
+	}
+	img2, err := openCompared(b, &cfg)
+	if err != nil {
+		return Difference{}, err
+	}
+	return Compare(img1, img2, cfg.tolerance)
+}
+
+// openCompared opens the image file, converted to sRGB for the color managed comparison.
+func openCompared(name string, cfg *compareConfig) (image.Image, error) {
+	data, err := readFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	img, err := Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if !cfg.colorManaged {
+		return img, nil
+	}
+	p := cfg.untagged.profile
+	if data := readICCProfile(data); data != nil {
+		// Other than matrix/TRC profiles are left unconverted, as ConvertToSRGB does.
+		p, _ = parseICCProfile(data)
+	}
+	if p != nil && !p.isSRGB() {
+		img = p.toSRGB(img)
+	}
+	return img, nil
+}
@@ -0,0 +1,114 @@
+package imaging
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	t.Parallel()
+
+	a := New(4, 3, color.NRGBA{0x10, 0x20, 0x30, 0xff})
+	b := Clone(a)
+	b.SetNRGBA(1, 1, color.NRGBA{0x12, 0x20, 0x30, 0xff})
+	b.SetNRGBA(3, 2, color.NRGBA{0x10, 0x20, 0x30, 0xf0})
+	testCases := []struct {
+		name      string
+		a, b      image.Image
+		tolerance int
+		want      Difference
+	}{
+		{"equal", a, a, 0, Difference{}},
+		{"different", a, b, 0, Difference{Pixels: 2, MaxDelta: 0x0f, Bounds: image.Rect(1, 1, 4, 3)}},
+		{"tolerance", a, b, 2, Difference{Pixels: 1, MaxDelta: 0x0f, Bounds: image.Rect(3, 2, 4, 3)}},
+		{
+			"offset bounds",
+			a, &image.NRGBA{Rect: image.Rect(5, 5, 9, 8), Stride: a.Stride, Pix: a.Pix}, 0,
+			Difference{},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := Compare(tc.a, tc.b, tc.tolerance)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("got %+v want %+v", got, tc.want)
+			}
+		})
+	}
+
+	if _, err := Compare(a, New(3, 4, color.Black), 0); !errors.Is(err, ErrSizeMismatch) {
+		t.Fatalf("got error %v want %v", err, ErrSizeMismatch)
+	}
+}
+
+func TestCompareFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	save := func(t *testing.T, name string, img image.Image, profile []byte) string {
+		t.Helper()
+		name = filepath.Join(dir, name)
+		if err := Save(img, name, WithMetadata(&Metadata{ICCProfile: profile})); err != nil {
+			t.Fatalf("failed to save: %v", err)
+		}
+		return name
+	}
+
+	// The same colors captured on a Display P3 display and converted to sRGB.
+	p3 := New(4, 4, color.NRGBA{0xe0, 0x40, 0x30, 0xff})
+	srgb := toNRGBA(ProfileDisplayP3.profile.toSRGB(p3))
+	p3Profile := makeICCProfile(displayP3ToXYZ, 2.4, 1/1.055, 0.055/1.055, 1/12.92, 0.04045)
+	tagged := save(t, "tagged.png", p3, p3Profile)
+	untagged := save(t, "untagged.png", p3, nil)
+	linux := save(t, "linux.png", srgb, nil)
+
+	testCases := []struct {
+		name   string
+		a, b   string
+		opts   []CompareOption
+		differ bool
+	}{
+		{"raw values", tagged, linux, nil, true},
+		{"color managed", tagged, linux, []CompareOption{CompareColorManaged(ProfileSRGB)}, false},
+		{"untagged as sRGB", untagged, linux, []CompareOption{CompareColorManaged(ProfileSRGB)}, true},
+		{"tagged and untagged", tagged, untagged, []CompareOption{CompareColorManaged(ProfileDisplayP3)}, false},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := CompareFiles(tc.a, tc.b, append(tc.opts, CompareTolerance(1))...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if differ := got.Pixels > 0; differ != tc.differ {
+				t.Fatalf("got %+v want differing %t", got, tc.differ)
+			}
+		})
+	}
+
+	if _, err := CompareFiles(tagged, filepath.Join(dir, "missing.png")); err == nil {
+		t.Fatalf("expected error comparing with a missing file")
+	}
+}
+
+func TestProfileGamma(t *testing.T) {
+	t.Parallel()
+
+	// The middle gray of the gamma 1.8 is lighter in sRGB.
+	got := toNRGBA(ProfileGamma(1.8).profile.toSRGB(New(1, 1, color.NRGBA{0x80, 0x80, 0x80, 0xff}))).NRGBAAt(0, 0)
+	if got.R <= 0x80 || got.R != got.G || got.G != got.B {
+		t.Fatalf("got %v want a gray lighter than 0x80", got)
+	}
+	if ProfileSRGB.profile != nil {
+		t.Fatalf("ProfileSRGB converts the colors")
+	}
+}
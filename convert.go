@@ -0,0 +1,210 @@
+package imaging
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsupportedConvertOption means the ImageMagick convert option is not supported by ParseConvertArgs.
+var ErrUnsupportedConvertOption = errors.New("imaging: unsupported convert option")
+
+// ConvertCommand is an ImageMagick convert command line translated by ParseConvertArgs.
+type ConvertCommand struct {
+	// Input is the source image filename.
+	Input string
+	// Output is the result image filename.
+	Output string
+	// Pipeline holds the operations and the encoding options of the command.
+	Pipeline *Pipeline
+}
+
+// Run processes the input file with the pipeline and saves the result to the output file.
+func (c *ConvertCommand) Run() error {
+	return c.Pipeline.Process(c.Input, c.Output)
+}
+
+// convertGravities maps ImageMagick gravity names to anchor points.
+var convertGravities = map[string]Anchor{ //nolint
+	"northwest": TopLeft,
+	"north":     Top,
+	"northeast": TopRight,
+	"west":      Left,
+	"center":    Center,
+	"east":      Right,
+	"southwest": BottomLeft,
+	"south":     Bottom,
+	"southeast": BottomRight,
+}
+
+// convertColors maps the supported ImageMagick color names to colors.
+var convertColors = map[string]color.NRGBA{ //nolint
+	"none":        {},
+	"transparent": {},
+	"white":       {0xff, 0xff, 0xff, 0xff},
+	"black":       {0x00, 0x00, 0x00, 0xff},
+	"gray":        {0x7e, 0x7e, 0x7e, 0xff},
+	"red":         {0xff, 0x00, 0x00, 0xff},
+	"green":       {0x00, 0x80, 0x00, 0xff},
+	"blue":        {0x00, 0x00, 0xff, 0xff},
+}
+
+// ParseConvertArgs translates the arguments of an ImageMagick convert command
+// line into a ConvertCommand. The leading "convert" or "magick" program name is
+// optional. The first non-option argument is the input and the last one is the output.
+//
+// The supported options are:
+//
+//	-resize geometry     resize using ResizeGeometry with the Lanczos filter
+//	-crop geometry       crop a single region using CropGeometry
+//	-rotate degrees      rotate clockwise, the uncovered area is filled with the background color
+//	-quality value       set the JPEG quality
+//	-strip               remove the metadata from the result
+//	-gravity type        set the anchor point of the subsequent -crop options
+//	-background color    set the background color of the subsequent -rotate options
+//	-auto-orient         transform the image according to its EXIF orientation
+//	+repage              ignored, cropped images always start at the origin
+//
+// Example:
+//
+//	cmd, err := imaging.ParseConvertArgs(strings.Fields("convert in.jpg -resize 800x600> -quality 85 -strip out.jpg"))
+//	if err != nil {
+//		return err
+//	}
+//	err = cmd.Run()
+func ParseConvertArgs(args []string) (*ConvertCommand, error) {
+	if len(args) > 0 && (args[0] == "convert" || args[0] == "magick") {
+		args = args[1:]
+	}
+
+	cmd := &ConvertCommand{Pipeline: NewPipeline()}
+	p := cmd.Pipeline
+	gravity := TopLeft
+	var background color.Color = color.White
+	var files []string
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "+") {
+			files = append(files, arg)
+			continue
+		}
+
+		// value returns the argument of the option.
+		value := func() (string, error) {
+			if i+1 >= len(args) {
+				return "", fmt.Errorf("%w: %s requires an argument", ErrUnsupportedConvertOption, arg)
+			}
+			i++
+			return args[i], nil
+		}
+
+		switch arg {
+		case "+repage":
+		case "-strip":
+			p.Strip(true)
+		case "-auto-orient":
+			p.WithDecodeOptions(AutoOrientation(true))
+		case "-resize":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			g, err := ParseGeometry(v)
+			if err != nil {
+				return nil, err
+			}
+			p.Then("resize "+v, func(img image.Image) image.Image {
+				return ResizeGeometry(img, g, Lanczos)
+			})
+		case "-crop":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			g, err := ParseGeometry(v)
+			if err != nil {
+				return nil, err
+			}
+			anchor := gravity
+			p.Then("crop "+v, func(img image.Image) image.Image {
+				return CropGeometry(img, g, anchor)
+			})
+		case "-rotate":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			angle, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid rotation angle %q", ErrUnsupportedConvertOption, v)
+			}
+			bg := background
+			p.Then("rotate "+v, func(img image.Image) image.Image {
+				return Rotate(img, -angle, bg)
+			})
+		case "-quality":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			quality, err := strconv.Atoi(v)
+			if err != nil || quality < 1 || quality > 100 {
+				return nil, fmt.Errorf("%w: invalid quality %q", ErrUnsupportedConvertOption, v)
+			}
+			p.WithEncodeOptions(JPEGQuality(quality))
+		case "-gravity":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			a, ok := convertGravities[strings.ToLower(v)]
+			if !ok {
+				return nil, fmt.Errorf("%w: unknown gravity %q", ErrUnsupportedConvertOption, v)
+			}
+			gravity = a
+		case "-background":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			c, err := parseConvertColor(v)
+			if err != nil {
+				return nil, err
+			}
+			background = c
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedConvertOption, arg)
+		}
+	}
+
+	if len(files) != 2 {
+		return nil, fmt.Errorf("%w: expected an input and an output file, got %d files", ErrUnsupportedConvertOption, len(files))
+	}
+	cmd.Input, cmd.Output = files[0], files[1]
+	return cmd, nil
+}
+
+// parseConvertColor parses a color name or a hex color ("#rgb", "#rrggbb" or "#rrggbbaa").
+func parseConvertColor(s string) (color.NRGBA, error) {
+	if c, ok := convertColors[strings.ToLower(s)]; ok {
+		return c, nil
+	}
+	h := strings.TrimPrefix(s, "#")
+	if len(h) == 3 {
+		h = string([]byte{h[0], h[0], h[1], h[1], h[2], h[2]})
+	}
+	b, err := hex.DecodeString(h)
+	if err != nil || !strings.HasPrefix(s, "#") || (len(b) != 3 && len(b) != 4) {
+		return color.NRGBA{}, fmt.Errorf("%w: unknown color %q", ErrUnsupportedConvertOption, s)
+	}
+	c := color.NRGBA{R: b[0], G: b[1], B: b[2], A: 0xff}
+	if len(b) == 4 {
+		c.A = b[3]
+	}
+	return c, nil
+}
@@ -0,0 +1,150 @@
+package imaging
+
+import (
+	"errors"
+	"image/color"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseConvertArgs(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		args   string
+		steps  []string
+		strip  bool
+		input  string
+		output string
+	}{
+		{
+			name:   "resize and quality",
+			args:   "convert in.jpg -resize 800x600> -quality 85 out.jpg",
+			steps:  []string{"resize 800x600>"},
+			input:  "in.jpg",
+			output: "out.jpg",
+		},
+		{
+			name:   "crop rotate strip",
+			args:   "magick in.png -gravity center -crop 10x10+0+0 +repage -background none -rotate 90 -strip out.png",
+			steps:  []string{"crop 10x10+0+0", "rotate 90"},
+			strip:  true,
+			input:  "in.png",
+			output: "out.png",
+		},
+		{
+			name:   "without program name",
+			args:   "a.tif -auto-orient b.png",
+			steps:  []string{},
+			input:  "a.tif",
+			output: "b.png",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			cmd, err := ParseConvertArgs(strings.Fields(tc.args))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := cmd.Pipeline.Steps(); !reflect.DeepEqual(got, tc.steps) {
+				t.Fatalf("got steps %q want %q", got, tc.steps)
+			}
+			if cmd.Pipeline.Stripped() != tc.strip {
+				t.Fatalf("got strip %v want %v", cmd.Pipeline.Stripped(), tc.strip)
+			}
+			if cmd.Input != tc.input || cmd.Output != tc.output {
+				t.Fatalf("got files %q %q want %q %q", cmd.Input, cmd.Output, tc.input, tc.output)
+			}
+		})
+	}
+}
+
+func TestParseConvertArgsErrors(t *testing.T) {
+	t.Parallel()
+
+	testCases := []string{
+		"convert in.jpg -sepia-tone 80% out.jpg",
+		"convert in.jpg -resize",
+		"convert in.jpg -rotate abc out.jpg",
+		"convert in.jpg -quality 0 out.jpg",
+		"convert in.jpg -gravity middle out.jpg",
+		"convert in.jpg -background #12 out.jpg",
+		"convert in.jpg",
+		"convert a.jpg b.jpg c.jpg",
+	}
+	for _, args := range testCases {
+		if _, err := ParseConvertArgs(strings.Fields(args)); !errors.Is(err, ErrUnsupportedConvertOption) {
+			t.Fatalf("%q: got error %v want %v", args, err, ErrUnsupportedConvertOption)
+		}
+	}
+	if _, err := ParseConvertArgs(strings.Fields("convert in.jpg -resize 10y10 out.jpg")); !errors.Is(err, ErrInvalidGeometry) {
+		t.Fatalf("got error %v want %v", err, ErrInvalidGeometry)
+	}
+}
+
+func TestParseConvertColor(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]color.NRGBA{
+		"White":     {0xff, 0xff, 0xff, 0xff},
+		"none":      {},
+		"#f00":      {0xff, 0x00, 0x00, 0xff},
+		"#102030":   {0x10, 0x20, 0x30, 0xff},
+		"#10203040": {0x10, 0x20, 0x30, 0x40},
+	}
+	for s, want := range testCases {
+		got, err := parseConvertColor(s)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", s, err)
+		}
+		if got != want {
+			t.Fatalf("%q: got %v want %v", s, got, want)
+		}
+	}
+	if _, err := parseConvertColor("102030"); err == nil {
+		t.Fatalf("expected error for a color without #")
+	}
+}
+
+func TestConvertCommandRun(t *testing.T) {
+	dir, err := os.MkdirTemp("", "imaging")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir) //nolint
+
+	src := New(20, 10, color.NRGBA{0x00, 0x00, 0xff, 0xff})
+	src.SetNRGBA(19, 0, color.NRGBA{0xff, 0x00, 0x00, 0xff})
+	in, out := filepath.Join(dir, "in.png"), filepath.Join(dir, "out.png")
+	if err := Save(src, in); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+
+	cmd, err := ParseConvertArgs([]string{"convert", in, "-gravity", "NorthEast", "-crop", "4x2", "-rotate", "90", out})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	img, err := Open(out)
+	if err != nil {
+		t.Fatalf("failed to open result: %v", err)
+	}
+	// The red top right pixel ends up in the bottom right corner after the clockwise rotation.
+	got := Clone(img)
+	if b := got.Bounds(); b.Dx() != 2 || b.Dy() != 4 {
+		t.Fatalf("got bounds %v want 2x4", b)
+	}
+	if c := got.NRGBAAt(1, 3); c != (color.NRGBA{0xff, 0x00, 0x00, 0xff}) {
+		t.Fatalf("got corner color %v", c)
+	}
+}
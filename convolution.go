@@ -14,6 +14,10 @@ type ConvolveOptions struct {
 
 	// Bias is added to each color channel value after convolution.
 	Bias int
+
+	// Edge defines the pixels outside of the image the kernel is applied to near
+	// the image edges. The zero value is EdgeClamp, replicating the edge pixels.
+	Edge EdgeMode
 }
 
 // Convolve3x3 convolves the image with the specified 3x3 convolution kernel.
@@ -70,27 +74,20 @@ func convolve(img image.Image, kernel []float64, options *ConvolveOptions) *imag
 		}
 	}
 
+	c := options.Edge.resolve(img).fill()
+	fill := [3]uint8{c.R, c.G, c.B}
 	parallel(0, h, func(ys <-chan int) {
 		for y := range ys {
 			for x := 0; x < w; x++ {
 				var r, g, b float64
 				for _, c := range coefs {
-					ix := x + c.x
-					if ix < 0 {
-						ix = 0
-					} else if ix >= w {
-						ix = w - 1
-					}
-
-					iy := y + c.y
-					if iy < 0 {
-						iy = 0
-					} else if iy >= h {
-						iy = h - 1
+					ix, okx := options.Edge.index(x+c.x, w)
+					iy, oky := options.Edge.index(y+c.y, h)
+					s := fill[:]
+					if okx && oky {
+						off := iy*src.Stride + ix*4
+						s = src.Pix[off : off+3 : off+3]
 					}
-
-					off := iy*src.Stride + ix*4
-					s := src.Pix[off : off+3 : off+3]
 					r += float64(s[0]) * c.k
 					g += float64(s[1]) * c.k
 					b += float64(s[2]) * c.k
@@ -2,6 +2,7 @@ package imaging
 
 import (
 	"image"
+	"image/color"
 	"testing"
 )
 
@@ -242,6 +243,51 @@ func TestConvolve5x5(t *testing.T) {
 	}
 }
 
+func TestConvolveEdge(t *testing.T) {
+	t.Parallel()
+
+	src := &image.NRGBA{
+		Rect:   image.Rect(0, 0, 3, 1),
+		Stride: 3 * 4,
+		Pix: []uint8{
+			0x10, 0x10, 0x10, 0xff, 0x20, 0x20, 0x20, 0x80, 0x30, 0x30, 0x30, 0xff,
+		},
+	}
+	// The kernel moves the image to the right by a pixel.
+	kernel := [9]float64{
+		0, 0, 0,
+		1, 0, 0,
+		0, 0, 0,
+	}
+	testCases := []struct {
+		name string
+		mode EdgeMode
+		want uint8
+	}{
+		{"clamp", EdgeClamp, 0x10},
+		{"reflect", EdgeReflect, 0x10},
+		{"wrap", EdgeWrap, 0x30},
+		{"color", EdgeColor(color.Gray{0x40}), 0x40},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got := Convolve3x3(src, kernel, &ConvolveOptions{Edge: tc.mode})
+			want := &image.NRGBA{
+				Rect:   image.Rect(0, 0, 3, 1),
+				Stride: 3 * 4,
+				Pix: []uint8{
+					tc.want, tc.want, tc.want, 0xff, 0x10, 0x10, 0x10, 0x80, 0x20, 0x20, 0x20, 0xff,
+				},
+			}
+			if !compareNRGBA(got, want, 0) {
+				t.Fatalf("got result %#v want %#v", got, want)
+			}
+		})
+	}
+}
+
 func TestNormalizeKernel(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,129 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"mime"
+	"net/url"
+	"strings"
+)
+
+// ErrInvalidDataURL means the string passed to DecodeDataURL is not a valid data URL.
+var ErrInvalidDataURL = errors.New("imaging: invalid data URL")
+
+// formatMIMETypes maps image formats to their MIME types.
+var formatMIMETypes = map[Format]string{
+	JPEG: "image/jpeg",
+	PNG:  "image/png",
+	GIF:  "image/gif",
+	TIFF: "image/tiff",
+	BMP:  "image/bmp",
+	PDF:  "application/pdf",
+}
+
+// mimeType returns the MIME type of the image format. The MIME type of
+// the formats registered with RegisterFormat is "image/" followed by the
+// lowercase name of the format.
+func mimeType(f Format) (string, error) {
+	if t, ok := formatMIMETypes[f]; ok {
+		return t, nil
+	}
+	if cf, ok := lookupCustomFormat(f); ok {
+		return "image/" + strings.ToLower(cf.name), nil
+	}
+	return "", ErrUnsupportedFormat
+}
+
+// EncodeDataURL encodes the image in the given format and returns it as
+// a base64 data URL (e.g. "data:image/png;base64,iVBORw0KGgo..."), which can
+// be inlined in HTML, CSS and JSON documents. It accepts the same options as Encode.
+//
+// Example:
+//
+//	src, err := imaging.EncodeDataURL(thumb, imaging.JPEG, imaging.JPEGQuality(80))
+//	html := `<img src="` + src + `">`
+func EncodeDataURL(img image.Image, format Format, opts ...EncodeOption) (string, error) {
+	t, err := mimeType(format)
+	if err != nil {
+		return "", err
+	}
+	buf := &bytes.Buffer{}
+	if err := Encode(buf, img, format, opts...); err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.Grow(len("data:;base64,") + len(t) + base64.StdEncoding.EncodedLen(buf.Len()))
+	b.WriteString("data:")
+	b.WriteString(t)
+	b.WriteString(";base64,")
+	b.WriteString(base64.StdEncoding.EncodeToString(buf.Bytes()))
+	return b.String(), nil
+}
+
+// DecodeDataURL decodes an image from a data URL, either base64 or percent-encoded.
+// It accepts the same options as Decode. Whitespace in the base64 data, such as
+// the line breaks in e-mail messages, is ignored. Data URLs with a media type
+// other than image/* or application/octet-stream are rejected with ErrUnsupportedFormat,
+// strings that are not valid data URLs with ErrInvalidDataURL.
+//
+// Example:
+//
+//	img, err := imaging.DecodeDataURL("data:image/png;base64,iVBORw0KGgo...")
+func DecodeDataURL(s string, opts ...DecodeOption) (image.Image, error) {
+	s = strings.TrimSpace(s)
+	if len(s) < len("data:") || !strings.EqualFold(s[:len("data:")], "data:") {
+		return nil, fmt.Errorf("%w: missing data: scheme", ErrInvalidDataURL)
+	}
+	header, payload, ok := strings.Cut(s[len("data:"):], ",")
+	if !ok {
+		return nil, fmt.Errorf("%w: missing comma", ErrInvalidDataURL)
+	}
+
+	isBase64 := false
+	if i := strings.LastIndex(header, ";"); i >= 0 && strings.EqualFold(strings.TrimSpace(header[i+1:]), "base64") {
+		isBase64 = true
+		header = header[:i]
+	}
+	if header != "" && !strings.HasPrefix(header, ";") {
+		mediaType, _, err := mime.ParseMediaType(header)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidDataURL, err)
+		}
+		if !strings.HasPrefix(mediaType, "image/") && mediaType != "application/octet-stream" {
+			return nil, fmt.Errorf("%w: media type %q", ErrUnsupportedFormat, mediaType)
+		}
+	}
+
+	var data []byte
+	if isBase64 {
+		payload = strings.Map(func(r rune) rune {
+			if r == ' ' || r == '\t' || r == '\r' || r == '\n' {
+				return -1
+			}
+			return r
+		}, payload)
+		if unescaped, err := url.PathUnescape(payload); err == nil {
+			payload = unescaped
+		}
+		enc := base64.StdEncoding
+		if len(payload)%4 != 0 {
+			enc = base64.RawStdEncoding
+		}
+		decoded, err := enc.DecodeString(payload)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidDataURL, err)
+		}
+		data = decoded
+	} else {
+		unescaped, err := url.PathUnescape(payload)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidDataURL, err)
+		}
+		data = []byte(unescaped)
+	}
+	return Decode(bytes.NewReader(data), opts...)
+}
@@ -0,0 +1,110 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"image"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestEncodeDataURL(t *testing.T) {
+	t.Parallel()
+
+	src := makeNoiseNRGBA(6, 4, 1)
+	testCases := []struct {
+		name   string
+		format Format
+		prefix string
+	}{
+		{"png", PNG, "data:image/png;base64,iVBORw0KGgo"},
+		{"jpeg", JPEG, "data:image/jpeg;base64,/9j/"},
+		{"gif", GIF, "data:image/gif;base64,R0lGOD"},
+		{"bmp", BMP, "data:image/bmp;base64,Qk"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			s, err := EncodeDataURL(src, tc.format)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !strings.HasPrefix(s, tc.prefix) {
+				t.Fatalf("got data URL %.40q want prefix %q", s, tc.prefix)
+			}
+			img, err := DecodeDataURL(s)
+			if err != nil {
+				t.Fatalf("failed to decode the data URL: %v", err)
+			}
+			if img.Bounds().Size() != src.Bounds().Size() {
+				t.Fatalf("got size %v want %v", img.Bounds().Size(), src.Bounds().Size())
+			}
+		})
+	}
+
+	if _, err := EncodeDataURL(src, Format(-1)); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("got error %v for an unknown format", err)
+	}
+}
+
+func TestDecodeDataURL(t *testing.T) {
+	t.Parallel()
+
+	src := makeNoiseNRGBA(5, 3, 2)
+	buf := &bytes.Buffer{}
+	if err := Encode(buf, src, PNG); err != nil {
+		t.Fatalf("failed to encode png: %v", err)
+	}
+	b64 := base64.StdEncoding.EncodeToString(buf.Bytes())
+	wrapped := ""
+	for i := 0; i < len(b64); i += 20 {
+		end := i + 20
+		if end > len(b64) {
+			end = len(b64)
+		}
+		wrapped += b64[i:end] + "\r\n"
+	}
+
+	testCases := []struct {
+		name string
+		s    string
+		want error
+	}{
+		{"base64", "data:image/png;base64," + b64, nil},
+		{"uppercase", "DATA:image/png;BASE64," + b64, nil},
+		{"unpadded", "data:image/png;base64," + strings.TrimRight(b64, "="), nil},
+		{"line breaks", "data:image/png;base64," + wrapped, nil},
+		{"parameters", "data:image/png;name=dot.png;base64," + b64, nil},
+		{"no media type", "data:;base64," + b64, nil},
+		{"percent-encoded", "data:image/png," + url.PathEscape(buf.String()), nil},
+		{"not a data URL", "https://example.com/image.png", ErrInvalidDataURL},
+		{"missing comma", "data:image/png;base64", ErrInvalidDataURL},
+		{"invalid base64", "data:image/png;base64,!!!!", ErrInvalidDataURL},
+		{"text", "data:text/plain;base64," + b64, ErrUnsupportedFormat},
+		{"not an image", "data:image/png;base64,aGVsbG8=", image.ErrFormat},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			img, err := DecodeDataURL(tc.s)
+			if tc.want != nil {
+				if !errors.Is(err, tc.want) {
+					t.Fatalf("got error %v want %v", err, tc.want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !compareNRGBA(Clone(img), src, 0) {
+				t.Fatalf("decoded image differs from source")
+			}
+		})
+	}
+}
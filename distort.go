@@ -0,0 +1,180 @@
+package imaging
+
+import (
+	"image"
+	"math"
+)
+
+// UndistortBrown corrects the lens distortion of the image described by the
+// Brown–Conrady model, e.g. the barrel distortion of the wide angle lenses of action
+// cameras. The coefficients k1 and k2 are of the radial distortion, negative for
+// barrel and positive for pincushion distortion, and p1 and p2 of the tangential
+// distortion of a lens not parallel to the sensor.
+//
+// The model maps the point (x, y) of the corrected image to the point
+//
+//	xd = x(1 + k1r² + k2r⁴) + 2p1xy + p2(r² + 2x²)
+//	yd = y(1 + k1r² + k2r⁴) + p1(r² + 2y²) + 2p2xy
+//
+// of the distorted image, where r² = x² + y². The coordinates are relative to the
+// image center, in the units of half of the image diagonal, so r = 1 at the corners.
+// The coefficients calibrated in other units, e.g. in those of the focal length as in
+// OpenCV, have to be scaled by the powers of the ratio of the units: k1 by the square,
+// k2 by the fourth power, and p1 and p2 by the ratio.
+//
+// The result is as large as the image. The pixels mapped outside of the image, e.g.
+// near the corners when correcting a pincushion distortion, are transparent.
+//
+// Example:
+//
+//	dstImage := imaging.UndistortBrown(srcImage, -0.28, 0.07, 0, 0)
+func UndistortBrown(img image.Image, k1, k2, p1, p2 float64) *image.NRGBA {
+	src := toNRGBA(img)
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	if w == 0 || h == 0 {
+		return dst
+	}
+	cx, cy := float64(w)/2, float64(h)/2
+	unit := math.Hypot(cx, cy)
+	parallel(0, h, func(ys <-chan int) {
+		for y := range ys {
+			for x := 0; x < w; x++ {
+				u, v := (float64(x)+0.5-cx)/unit, (float64(y)+0.5-cy)/unit
+				r2 := u*u + v*v
+				radial := 1 + k1*r2 + k2*r2*r2
+				ud := u*radial + 2*p1*u*v + p2*(r2+2*u*u)
+				vd := v*radial + p1*(r2+2*v*v) + 2*p2*u*v
+				i := dst.PixOffset(x, y)
+				distortPoint(dst.Pix[i:i+4:i+4], src, cx+ud*unit, cy+vd*unit)
+			}
+		}
+	})
+	return dst
+}
+
+// distortPoint sets the pixel to the interpolated source pixel at the point, or leaves
+// it transparent if the point is outside of the source image.
+func distortPoint(d []uint8, src *image.NRGBA, sx, sy float64) {
+	if !(sx >= 0 && sy >= 0 && sx <= float64(src.Rect.Dx()) && sy <= float64(src.Rect.Dy())) {
+		return
+	}
+	bilinearPoint(d, src, sx, sy, false)
+}
+
+// Swirl twists the image around the center point within the radius. The pixels are
+// rotated counter-clockwise, as by Rotate, by the angle in degrees at the center,
+// and less and less towards the circle, where they are not moved. Negative angles
+// twist the image clockwise.
+//
+// Example:
+//
+//	b := srcImage.Bounds()
+//	dstImage := imaging.Swirl(srcImage, image.Pt(b.Dx()/2, b.Dy()/2), 200, 180)
+func Swirl(img image.Image, center image.Point, radius, angle float64) *image.NRGBA {
+	theta := angle * math.Pi / 180
+	return distortRadial(img, center, radius, func(dx, dy, t float64) (float64, float64) {
+		// The rotation eases in quadratically from the circle to the center.
+		a := theta * t * t
+		sin, cos := math.Sincos(a)
+		return dx*cos - dy*sin, dx*sin + dy*cos
+	})
+}
+
+// Pinch pulls the image towards the center point within the radius, or pushes it
+// outwards, like a bulge, for negative strengths. The strength is from -1 to 1, 0
+// leaves the image unchanged, and the pixels on the circle are not moved.
+//
+// Example:
+//
+//	b := srcImage.Bounds()
+//	dstImage := imaging.Pinch(srcImage, image.Pt(b.Dx()/2, b.Dy()/2), 150, -0.6) // Bulge the face.
+func Pinch(img image.Image, center image.Point, radius, strength float64) *image.NRGBA {
+	strength = math.Min(math.Max(strength, -1), 1)
+	return distortRadial(img, center, radius, func(dx, dy, t float64) (float64, float64) {
+		// The source distance is r(d/r)^p with p from 1/2 to 2, so the center and
+		// the circle stay in place.
+		s := math.Pow(1-t, math.Pow(2, -strength)-1)
+		if math.IsInf(s, 0) || math.IsNaN(s) {
+			return 0, 0
+		}
+		return dx * s, dy * s
+	})
+}
+
+// distortRadial returns the image with the pixels within the radius of the center
+// point resampled at the source offsets returned by the mapping for their offsets
+// from the center and their closeness t to the center, 1 at the center and 0 on the circle.
+func distortRadial(img image.Image, center image.Point, radius float64, mapping func(dx, dy, t float64) (float64, float64)) *image.NRGBA {
+	src := Clone(img)
+	if !(radius > 0) {
+		return src
+	}
+	b := img.Bounds()
+	cx, cy := float64(center.X-b.Min.X), float64(center.Y-b.Min.Y)
+	dst := Clone(src)
+	x0, x1 := int(math.Max(0, math.Floor(cx-radius))), int(math.Min(float64(src.Rect.Dx()), math.Ceil(cx+radius)))
+	y0, y1 := int(math.Max(0, math.Floor(cy-radius))), int(math.Min(float64(src.Rect.Dy()), math.Ceil(cy+radius)))
+	parallel(y0, y1, func(ys <-chan int) {
+		for y := range ys {
+			for x := x0; x < x1; x++ {
+				dx, dy := float64(x)+0.5-cx, float64(y)+0.5-cy
+				d := math.Hypot(dx, dy)
+				if d >= radius {
+					continue
+				}
+				sx, sy := mapping(dx, dy, 1-d/radius)
+				i := dst.PixOffset(x, y)
+				bilinearPoint(dst.Pix[i:i+4:i+4], src, cx+sx, cy+sy, false)
+			}
+		}
+	})
+	return dst
+}
+
+// Displace moves the pixels of the image by the offsets read from the displacement
+// map, e.g. a rendered wave, flag or glass texture. The red channel of the map sets
+// the horizontal offset and the green channel the vertical offset: 128 leaves the
+// pixel in place, 255 takes the pixel scaleX (scaleY) pixels to the right (below)
+// and 0 the one as far to the left (above). The map is stretched to the image size.
+// The pixels taken from outside of the image are those of the nearest edge.
+//
+// Example:
+//
+//	// Ripple the image with a sine wave map.
+//	dstImage := imaging.Displace(srcImage, waveMap, 12, 12)
+func Displace(img image.Image, dispMap image.Image, scaleX, scaleY float64) *image.NRGBA {
+	src := toNRGBA(img)
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	if w == 0 || h == 0 {
+		return dst
+	}
+	m := toNRGBA(dispMap)
+	if m.Rect.Empty() {
+		return Clone(img)
+	}
+	if m.Rect.Dx() != w || m.Rect.Dy() != h {
+		m = Resize(m, w, h, Linear)
+	}
+	parallel(0, h, func(ys <-chan int) {
+		for y := range ys {
+			for x := 0; x < w; x++ {
+				j := y*m.Stride + x*4
+				dx := displacement(m.Pix[j]) * scaleX
+				dy := displacement(m.Pix[j+1]) * scaleY
+				i := dst.PixOffset(x, y)
+				bilinearPoint(dst.Pix[i:i+4:i+4], src, float64(x)+0.5+dx, float64(y)+0.5+dy, false)
+			}
+		}
+	})
+	return dst
+}
+
+// displacement returns the offset of the map value from -1 (0) to 1 (255), 0 for 128.
+func displacement(v uint8) float64 {
+	if v < 128 {
+		return (float64(v) - 128) / 128
+	}
+	return (float64(v) - 128) / 127
+}
@@ -0,0 +1,163 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestUndistortBrown(t *testing.T) {
+	t.Parallel()
+
+	// A grid of white lines on black, every 10 pixels.
+	src := New(81, 61, color.Black)
+	for y := 0; y < 61; y++ {
+		for x := 0; x < 81; x++ {
+			if x%10 == 0 || y%10 == 0 {
+				src.SetNRGBA(x, y, color.NRGBA{0xff, 0xff, 0xff, 0xff})
+			}
+		}
+	}
+
+	// Without distortion the image is unchanged.
+	if got := UndistortBrown(src, 0, 0, 0, 0); !compareNRGBA(got, src, 0) {
+		t.Fatalf("got result differing from the source without distortion")
+	}
+
+	// The correction of a barrel distortion moves the source pixels away from the
+	// center: the corrected pixel takes the source pixel closer to the center.
+	got := UndistortBrown(src, -0.2, 0, 0, 0)
+	if got.Bounds() != src.Bounds() {
+		t.Fatalf("got bounds %v want %v", got.Bounds(), src.Bounds())
+	}
+	if c := got.NRGBAAt(40, 30); c != (color.NRGBA{0xff, 0xff, 0xff, 0xff}) {
+		t.Fatalf("got color %v at the center want white", c)
+	}
+	// The white column 80 maps to the black source column about 75.
+	if c := got.NRGBAAt(80, 35); c.R != 0 {
+		t.Fatalf("got color %v at (80, 35) want black", c)
+	}
+
+	// A pincushion correction maps the corners outside of the image.
+	got = UndistortBrown(src, 0.3, 0, 0, 0)
+	if c := got.NRGBAAt(0, 0); c != (color.NRGBA{}) {
+		t.Fatalf("got color %v at the corner want transparent", c)
+	}
+
+	if got := UndistortBrown(&image.NRGBA{}, 0.1, 0, 0, 0); !got.Rect.Empty() {
+		t.Fatalf("got bounds %v want empty", got.Rect)
+	}
+}
+
+func TestSwirl(t *testing.T) {
+	t.Parallel()
+
+	// A red marker right of the center moves above it when twisted by 90°.
+	src := New(21, 21, color.White)
+	for y := 9; y <= 11; y++ {
+		for x := 14; x <= 16; x++ {
+			src.SetNRGBA(x, y, color.NRGBA{0xff, 0x00, 0x00, 0xff})
+		}
+	}
+	red := color.NRGBA{0xff, 0x00, 0x00, 0xff}
+	got := Swirl(src, image.Pt(10, 10), 1000, 90)
+	if c := got.NRGBAAt(10, 5); c != red {
+		t.Fatalf("got color %v above the center want red", c)
+	}
+	if c := got.NRGBAAt(15, 10); c == red {
+		t.Fatalf("got red right of the center, want the marker moved")
+	}
+	if got := Swirl(src, image.Pt(10, 10), 1000, -90); got.NRGBAAt(10, 15) != red {
+		t.Fatalf("got color %v below the center want red", got.NRGBAAt(10, 15))
+	}
+
+	// The pixels outside of the radius are not moved.
+	got = Swirl(src, image.Pt(0, 0), 5, 90)
+	if !compareNRGBA(got, src, 0) {
+		t.Fatalf("got the marker moved outside of the radius")
+	}
+	if got := Swirl(src, image.Pt(10, 10), 20, 0); !compareNRGBA(got, src, 0) {
+		t.Fatalf("got the image changed by a zero angle")
+	}
+}
+
+func TestPinch(t *testing.T) {
+	t.Parallel()
+
+	// A red square around the center.
+	src := New(41, 41, color.White)
+	for y := 16; y <= 24; y++ {
+		for x := 16; x <= 24; x++ {
+			src.SetNRGBA(x, y, color.NRGBA{0xff, 0x00, 0x00, 0xff})
+		}
+	}
+	red := color.NRGBA{0xff, 0x00, 0x00, 0xff}
+	testCases := []struct {
+		name     string
+		strength float64
+		// The pixel right of the square.
+		red bool
+	}{
+		{"none", 0, false},
+		{"pinch", 0.8, false},
+		{"bulge", -0.8, true},
+	}
+	for _, tc := range testCases {
+		got := Pinch(src, image.Pt(20, 20), 20, tc.strength)
+		if c := got.NRGBAAt(27, 20); (c == red) != tc.red {
+			t.Fatalf("%s: got color %v right of the square", tc.name, c)
+		}
+		if c := got.NRGBAAt(20, 20); c != red {
+			t.Fatalf("%s: got color %v at the center want red", tc.name, c)
+		}
+		if tc.strength > 0 {
+			// The pinch shrinks the square.
+			if c := got.NRGBAAt(24, 20); c == red {
+				t.Fatalf("%s: got red at the edge of the square", tc.name)
+			}
+		}
+	}
+	if got := Pinch(src, image.Pt(20, 20), 20, 0); !compareNRGBA(got, src, 0) {
+		t.Fatalf("got the image changed by a zero strength")
+	}
+}
+
+func TestDisplace(t *testing.T) {
+	t.Parallel()
+
+	src := &image.NRGBA{
+		Rect:   image.Rect(0, 0, 4, 1),
+		Stride: 4 * 4,
+		Pix: []uint8{
+			0x10, 0x10, 0x10, 0xff, 0x20, 0x20, 0x20, 0xff, 0x30, 0x30, 0x30, 0xff, 0x40, 0x40, 0x40, 0xff,
+		},
+	}
+	testCases := []struct {
+		name   string
+		dispX  uint8
+		scaleX float64
+		want   []uint8
+	}{
+		{"neutral", 128, 10, []uint8{0x10, 0x20, 0x30, 0x40}},
+		{"right", 255, 1, []uint8{0x20, 0x30, 0x40, 0x40}},
+		{"left", 0, 2, []uint8{0x10, 0x10, 0x10, 0x20}},
+		{"half", 255, 0.5, []uint8{0x18, 0x28, 0x38, 0x40}},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			// A single pixel map is stretched to the image.
+			dispMap := New(1, 1, color.NRGBA{tc.dispX, 128, 0, 0xff})
+			got := Displace(src, dispMap, tc.scaleX, 100)
+			for x, want := range tc.want {
+				if c := got.NRGBAAt(x, 0); c.R != want || c.A != 0xff {
+					t.Fatalf("got color %v at %d want gray 0x%02x", c, x, want)
+				}
+			}
+		})
+	}
+	if got := Displace(src, &image.NRGBA{}, 1, 1); !compareNRGBA(got, src, 0) {
+		t.Fatalf("got the image changed by an empty map")
+	}
+}
@@ -3,5 +3,10 @@ Package imaging provides basic image processing functions (resize, rotate, crop,
 
 All the image processing functions provided by the package accept any image type that implements image.Image interface
 as an input, and return a new image of *image.NRGBA type (32bit RGBA colors, non-premultiplied alpha).
+
+The results are deterministic: every pixel is computed by a single processing goroutine with the same sequence of
+operations, however the work is split between the goroutines (see SetMaxProcs), so the outputs are bit-identical
+across runs and worker counts and can be used as cache keys and golden files. The results may still differ in
+rounding between CPU architectures, e.g. where the compiler fuses multiply-adds.
 */
 package imaging
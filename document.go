@@ -0,0 +1,415 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// Quad is a quadrilateral given by its corners in the order top left, top right,
+// bottom right and bottom left.
+type Quad [4]image.Point
+
+// analysisSize is the maximum width and height of the downscaled copies of the
+// image used to detect documents and the skew angle.
+const analysisSize = 512
+
+// DetectDocument finds a document, e.g. a sheet of paper or a receipt, photographed
+// on a darker background and returns its corners in the image coordinates. The
+// document is the largest bright region of the image, so it must stand out from the
+// background and be rotated by less than about 30 degrees for the corners to be found.
+// It reports false if there is no such region.
+//
+// Example:
+//
+//	if corners, ok := imaging.DetectDocument(srcImage); ok {
+//		dstImage = imaging.PerspectiveCorrect(srcImage, corners, 0, 0)
+//	}
+func DetectDocument(img image.Image) (Quad, bool) {
+	b := img.Bounds()
+	if b.Dx() < 3 || b.Dy() < 3 {
+		return Quad{}, false
+	}
+	small := Blur(Fit(img, analysisSize, analysisSize, Box), 1)
+	w, h := small.Rect.Dx(), small.Rect.Dy()
+	threshold := otsuThreshold(Histogram(small))
+
+	bright := make([]bool, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			bright[y*w+x] = luminance(small.Pix[y*small.Stride+x*4:]) > float64(threshold)
+		}
+	}
+	region := largestRegion(bright, w, h)
+	if len(region) < w*h/10 || len(region) > w*h*95/100 {
+		// Too small to be the document or no background around it.
+		return Quad{}, false
+	}
+
+	// The corners are the extreme points along the diagonals.
+	q := [4]int{region[0], region[0], region[0], region[0]}
+	score := func(i, corner int) int {
+		x, y := i%w, i/w
+		switch corner {
+		case 0:
+			return -x - y
+		case 1:
+			return x - y
+		case 2:
+			return x + y
+		default:
+			return y - x
+		}
+	}
+	for _, i := range region {
+		for corner := range q {
+			if score(i, corner) > score(q[corner], corner) {
+				q[corner] = i
+			}
+		}
+	}
+
+	sx := float64(b.Dx()) / float64(w)
+	sy := float64(b.Dy()) / float64(h)
+	var corners Quad
+	for j, i := range q {
+		// Map the pixel to the corresponding corner of the document in the full size image.
+		fx, fy := float64(i%w), float64(i/w)
+		if j == 1 || j == 2 {
+			fx++
+		}
+		if j == 2 || j == 3 {
+			fy++
+		}
+		corners[j] = image.Pt(b.Min.X+int(math.Round(fx*sx)), b.Min.Y+int(math.Round(fy*sy)))
+	}
+	return corners, true
+}
+
+// largestRegion returns the indices of the pixels of the largest 4-connected region
+// of the set pixels.
+func largestRegion(set []bool, w, h int) []int {
+	seen := make([]bool, len(set))
+	var best, queue []int
+	for start := range set {
+		if !set[start] || seen[start] {
+			continue
+		}
+		seen[start] = true
+		queue = append(queue[:0], start)
+		for k := 0; k < len(queue); k++ {
+			i := queue[k]
+			x, y := i%w, i/w
+			for _, n := range [4][2]int{{x - 1, y}, {x + 1, y}, {x, y - 1}, {x, y + 1}} {
+				if n[0] < 0 || n[0] >= w || n[1] < 0 || n[1] >= h {
+					continue
+				}
+				j := n[1]*w + n[0]
+				if set[j] && !seen[j] {
+					seen[j] = true
+					queue = append(queue, j)
+				}
+			}
+		}
+		if len(queue) > len(best) {
+			best = append(best[:0], queue...)
+		}
+	}
+	return best
+}
+
+// otsuThreshold returns the luminance threshold that best separates the histogram
+// into dark and bright pixels (Otsu's method).
+func otsuThreshold(histogram [256]float64) int {
+	var total, sum float64
+	for i, p := range histogram {
+		total += p
+		sum += float64(i) * p
+	}
+	var wDark, sumDark, bestVar float64
+	threshold := 127
+	for i, p := range histogram {
+		wDark += p
+		sumDark += float64(i) * p
+		wBright := total - wDark
+		if wDark == 0 || wBright == 0 {
+			continue
+		}
+		diff := sumDark/wDark - (sum-sumDark)/wBright
+		if v := wDark * wBright * diff * diff; v > bestVar {
+			bestVar = v
+			threshold = i
+		}
+	}
+	return threshold
+}
+
+// luminance returns the luminance of the NRGBA pixel at the start of pix.
+func luminance(pix []uint8) float64 {
+	return 0.299*float64(pix[0]) + 0.587*float64(pix[1]) + 0.114*float64(pix[2])
+}
+
+// PerspectiveCorrect maps the quadrilateral region of the image to a rectangle of the
+// given size, removing the perspective distortion of a photographed document.
+// If width or height is 0, it's computed from the lengths of the quadrilateral sides.
+// The parts of the quadrilateral outside of the image become transparent.
+//
+// Example:
+//
+//	corners := imaging.Quad{{120, 80}, {910, 140}, {870, 1190}, {60, 1100}}
+//	dstImage := imaging.PerspectiveCorrect(srcImage, corners, 0, 0)
+func PerspectiveCorrect(img image.Image, q Quad, width, height int) *image.NRGBA {
+	src := toNRGBA(img)
+	b := img.Bounds()
+	var p [4][2]float64
+	for i, c := range q {
+		p[i] = [2]float64{float64(c.X - b.Min.X), float64(c.Y - b.Min.Y)}
+	}
+
+	dist := func(a, b [2]float64) float64 { return math.Hypot(a[0]-b[0], a[1]-b[1]) }
+	if width <= 0 {
+		width = int(math.Round(math.Max(dist(p[0], p[1]), dist(p[3], p[2]))))
+	}
+	if height <= 0 {
+		height = int(math.Round(math.Max(dist(p[0], p[3]), dist(p[1], p[2]))))
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	if width <= 0 || height <= 0 {
+		return dst
+	}
+
+	t := squareToQuad(p)
+	parallel(0, height, func(ys <-chan int) {
+		for y := range ys {
+			v := (float64(y) + 0.5) / float64(height)
+			for x := 0; x < width; x++ {
+				u := (float64(x) + 0.5) / float64(width)
+				xf, yf := t.apply(u, v)
+				// The interpolation works with pixel centers.
+				interpolatePoint(dst, x, y, src, xf-0.5, yf-0.5, color.NRGBA{})
+			}
+		}
+	})
+	return dst
+}
+
+// projective is a projective transformation:
+//
+//	x = (a*u + b*v + c) / (g*u + h*v + 1)
+//	y = (d*u + e*v + f) / (g*u + h*v + 1)
+type projective struct {
+	a, b, c, d, e, f, g, h float64
+}
+
+// apply returns the transformed point.
+func (t projective) apply(u, v float64) (float64, float64) {
+	z := t.g*u + t.h*v + 1
+	return (t.a*u + t.b*v + t.c) / z, (t.d*u + t.e*v + t.f) / z
+}
+
+// squareToQuad returns the transformation that maps the corners of the unit square
+// (0, 0), (1, 0), (1, 1) and (0, 1) to the corners of the quadrilateral.
+func squareToQuad(p [4][2]float64) projective {
+	x0, y0 := p[0][0], p[0][1]
+	x1, y1 := p[1][0], p[1][1]
+	x2, y2 := p[2][0], p[2][1]
+	x3, y3 := p[3][0], p[3][1]
+	dx3 := x0 - x1 + x2 - x3
+	dy3 := y0 - y1 + y2 - y3
+	if dx3 == 0 && dy3 == 0 {
+		// The quadrilateral is a parallelogram.
+		return projective{a: x1 - x0, b: x3 - x0, c: x0, d: y1 - y0, e: y3 - y0, f: y0}
+	}
+	dx1, dy1 := x1-x2, y1-y2
+	dx2, dy2 := x3-x2, y3-y2
+	det := dx1*dy2 - dx2*dy1
+	if det == 0 {
+		return projective{a: x1 - x0, b: x3 - x0, c: x0, d: y1 - y0, e: y3 - y0, f: y0}
+	}
+	g := (dx3*dy2 - dx2*dy3) / det
+	h := (dx1*dy3 - dx3*dy1) / det
+	return projective{
+		a: x1 - x0 + g*x1, b: x3 - x0 + h*x3, c: x0,
+		d: y1 - y0 + g*y1, e: y3 - y0 + h*y3, f: y0,
+		g: g, h: h,
+	}
+}
+
+// Deskew straightens a scanned or photographed text document. It finds the angle in
+// range [-maxAngle, maxAngle] degrees at which the dark pixels line up best in rows
+// and rotates the image by it. The bgColor parameter specifies the color of the
+// uncovered zone after the rotation, see Rotate. It returns the straightened image
+// and the angle the image was rotated by (counter-clockwise, 0 if no skew was found).
+//
+// Example:
+//
+//	dstImage, angle := imaging.Deskew(srcImage, 10, color.White)
+func Deskew(img image.Image, maxAngle float64, bgColor color.Color) (*image.NRGBA, float64) {
+	angle := skewAngle(img, math.Abs(maxAngle))
+	if angle == 0 {
+		return Clone(img), 0
+	}
+	return Rotate(img, angle, bgColor), angle
+}
+
+// skewAngle returns the rotation angle in degrees that aligns the dark pixels of
+// the image in horizontal rows.
+func skewAngle(img image.Image, maxAngle float64) float64 {
+	small := Fit(img, 2*analysisSize, 2*analysisSize, Box)
+	w, h := small.Rect.Dx(), small.Rect.Dy()
+	if w == 0 || h == 0 || maxAngle == 0 {
+		return 0
+	}
+	threshold := float64(otsuThreshold(Histogram(small)))
+
+	var points [][2]float64
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if luminance(small.Pix[y*small.Stride+x*4:]) < threshold {
+				points = append(points, [2]float64{float64(x), float64(y)})
+			}
+		}
+	}
+	if len(points) == 0 || len(points) > w*h/2 {
+		// Nothing to align or the ink isn't darker than the paper.
+		return 0
+	}
+
+	diag := int(math.Ceil(math.Hypot(float64(w), float64(h))))
+	bins := make([]float64, 2*diag+1)
+	// score measures how sharp the profile of the rows is after rotating the
+	// points by the angle: the sum of squares is the largest when the points
+	// are concentrated in few rows.
+	score := func(angle float64) float64 {
+		for i := range bins {
+			bins[i] = 0
+		}
+		sin, cos := math.Sincos(math.Pi * angle / 180)
+		for _, p := range points {
+			bins[int(math.Floor(p[0]*sin+p[1]*cos))+diag]++
+		}
+		var s float64
+		for _, v := range bins {
+			s += v * v
+		}
+		return s
+	}
+
+	best, bestScore := 0.0, score(0)
+	search := func(from, to, step float64) {
+		for a := from; a <= to+1e-9; a += step {
+			if s := score(a); s > bestScore {
+				best, bestScore = a, s
+			}
+		}
+	}
+	search(-maxAngle, maxAngle, 0.5)
+	search(math.Max(best-0.5, -maxAngle), math.Min(best+0.5, maxAngle), 0.05)
+	if math.Abs(best) < 0.05 {
+		return 0
+	}
+	// The rows are tilted by the best angle, rotate them back.
+	return -math.Round(best*100) / 100
+}
+
+// Trim removes the uniform border of the image. The color of the border is taken
+// from the top left pixel, and the pixels whose color channels differ from it by
+// at most tolerance (0-255) are considered part of the border. An image consisting
+// only of the border color gives an empty image.
+//
+// Example:
+//
+//	dstImage := imaging.Trim(srcImage, 10)
+func Trim(img image.Image, tolerance float64) *image.NRGBA {
+	src := toNRGBA(img)
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	if w == 0 || h == 0 {
+		return &image.NRGBA{}
+	}
+	bg := src.Pix[0:4:4]
+	isBorder := func(x, y int) bool {
+		s := src.Pix[y*src.Stride+x*4 : y*src.Stride+x*4+4]
+		for i := 0; i < 4; i++ {
+			if math.Abs(float64(s[i])-float64(bg[i])) > tolerance {
+				return false
+			}
+		}
+		return true
+	}
+
+	r := image.Rectangle{}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if !isBorder(x, y) {
+				r = r.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	if r.Empty() {
+		return &image.NRGBA{}
+	}
+	return Crop(img, r.Add(img.Bounds().Min))
+}
+
+// AdaptiveThreshold converts the image to black and white comparing each pixel with
+// the mean luminance of its neighbourhood within the radius, which copes with uneven
+// lighting much better than a global threshold. Pixels darker than the mean minus
+// the offset (0-255) become black, the others white. Typical values for photographed
+// documents are a radius of about 1/50 of the image size and an offset of 10.
+//
+// Example:
+//
+//	dstImage := imaging.AdaptiveThreshold(srcImage, 15, 10)
+func AdaptiveThreshold(img image.Image, radius int, offset float64) *image.Gray {
+	src := newScanner(img)
+	dst := image.NewGray(image.Rect(0, 0, src.w, src.h))
+	if src.w == 0 || src.h == 0 {
+		return dst
+	}
+	if radius < 1 {
+		radius = 1
+	}
+
+	// Summed-area table of the luminance with an extra zero row and column.
+	sw := src.w + 1
+	lum := make([]float64, src.w*src.h)
+	sat := make([]float64, sw*(src.h+1))
+	scanLine := make([]uint8, src.w*4)
+	for y := 0; y < src.h; y++ {
+		src.scan(0, y, src.w, y+1, scanLine)
+		var row float64
+		for x := 0; x < src.w; x++ {
+			l := luminance(scanLine[x*4:])
+			lum[y*src.w+x] = l
+			row += l
+			sat[(y+1)*sw+x+1] = sat[y*sw+x+1] + row
+		}
+	}
+
+	parallel(0, src.h, func(ys <-chan int) {
+		for y := range ys {
+			y0, y1 := y-radius, y+radius+1
+			if y0 < 0 {
+				y0 = 0
+			}
+			if y1 > src.h {
+				y1 = src.h
+			}
+			for x := 0; x < src.w; x++ {
+				x0, x1 := x-radius, x+radius+1
+				if x0 < 0 {
+					x0 = 0
+				}
+				if x1 > src.w {
+					x1 = src.w
+				}
+				sum := sat[y1*sw+x1] - sat[y0*sw+x1] - sat[y1*sw+x0] + sat[y0*sw+x0]
+				mean := sum / float64((x1-x0)*(y1-y0))
+				if lum[y*src.w+x] >= mean-offset {
+					dst.Pix[y*dst.Stride+x] = 0xff
+				}
+			}
+		}
+	})
+	return dst
+}
@@ -0,0 +1,206 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"testing"
+)
+
+// makeTextPage returns a white page with black horizontal lines imitating rows of text.
+func makeTextPage(w, h int) *image.NRGBA {
+	img := New(w, h, color.White)
+	for y := 20; y+4 < h-20; y += 14 {
+		draw.Draw(img, image.Rect(20, y, w-20, y+4), image.NewUniform(color.Black), image.Point{}, draw.Src)
+	}
+	return img
+}
+
+func TestDetectDocument(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		src  image.Rectangle
+		doc  image.Rectangle
+		ok   bool
+	}{
+		{"document", image.Rect(0, 0, 200, 150), image.Rect(40, 30, 160, 120), true},
+		{"offset bounds", image.Rect(-10, 5, 190, 155), image.Rect(20, 40, 150, 140), true},
+		{"large image", image.Rect(0, 0, 1200, 900), image.Rect(100, 200, 1000, 800), true},
+		{"no background", image.Rect(0, 0, 100, 100), image.Rect(0, 0, 100, 100), false},
+		{"too small", image.Rect(0, 0, 100, 100), image.Rect(10, 10, 20, 20), false},
+	}
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			src := image.NewNRGBA(tc.src)
+			draw.Draw(src, tc.src, image.NewUniform(color.NRGBA{0x30, 0x28, 0x20, 0xff}), image.Point{}, draw.Src)
+			draw.Draw(src, tc.doc, image.NewUniform(color.NRGBA{0xf0, 0xf0, 0xe8, 0xff}), image.Point{}, draw.Src)
+
+			got, ok := DetectDocument(src)
+			if ok != tc.ok {
+				t.Fatalf("got ok %v want %v", ok, tc.ok)
+			}
+			if !ok {
+				return
+			}
+			want := Quad{tc.doc.Min, image.Pt(tc.doc.Max.X, tc.doc.Min.Y), tc.doc.Max, image.Pt(tc.doc.Min.X, tc.doc.Max.Y)}
+			tolerance := 2 + tc.src.Dx()/analysisSize*2
+			for i := range want {
+				if absInt(got[i].X-want[i].X) > tolerance || absInt(got[i].Y-want[i].Y) > tolerance {
+					t.Fatalf("got corners %v want %v", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPerspectiveCorrect(t *testing.T) {
+	t.Parallel()
+
+	t.Run("identity", func(t *testing.T) {
+		src := makeNoiseNRGBA(16, 12, 1)
+		for i := 3; i < len(src.Pix); i += 4 {
+			src.Pix[i] = 0xff
+		}
+		got := PerspectiveCorrect(src, Quad{{0, 0}, {16, 0}, {16, 12}, {0, 12}}, 0, 0)
+		if !compareNRGBA(got, src, 1) {
+			t.Fatalf("got result %#v want %#v", got, src)
+		}
+	})
+
+	t.Run("trapezoid", func(t *testing.T) {
+		// A red trapezoid on a blue background.
+		q := Quad{{30, 10}, {70, 10}, {90, 90}, {10, 90}}
+		src := image.NewNRGBA(image.Rect(0, 0, 100, 100))
+		for y := 0; y < 100; y++ {
+			for x := 0; x < 100; x++ {
+				c := color.NRGBA{0, 0, 0xff, 0xff}
+				fy := float64(y) + 0.5
+				left := 30 - 20*(fy-10)/80
+				right := 70 + 20*(fy-10)/80
+				if fy > 10 && fy < 90 && float64(x)+0.5 > left && float64(x)+0.5 < right {
+					c = color.NRGBA{0xff, 0, 0, 0xff}
+				}
+				src.SetNRGBA(x, y, c)
+			}
+		}
+		got := PerspectiveCorrect(src, q, 40, 40)
+		if got.Bounds() != image.Rect(0, 0, 40, 40) {
+			t.Fatalf("got bounds %v want 40x40", got.Bounds())
+		}
+		for y := 1; y < 39; y++ {
+			for x := 1; x < 39; x++ {
+				if c := got.NRGBAAt(x, y); c.R < 0xc0 || c.B > 0x40 {
+					t.Fatalf("pixel (%d, %d): got %v want red", x, y, c)
+				}
+			}
+		}
+	})
+
+	t.Run("size from sides", func(t *testing.T) {
+		got := PerspectiveCorrect(makeNoiseNRGBA(100, 100, 1), Quad{{10, 10}, {70, 10}, {70, 50}, {10, 50}}, 0, 0)
+		if got.Bounds() != image.Rect(0, 0, 60, 40) {
+			t.Fatalf("got bounds %v want 60x40", got.Bounds())
+		}
+	})
+}
+
+func TestSquareToQuad(t *testing.T) {
+	t.Parallel()
+
+	for _, p := range [][4][2]float64{
+		{{0, 0}, {1, 0}, {1, 1}, {0, 1}},
+		{{30, 10}, {70, 10}, {90, 90}, {10, 90}},
+		{{12, 3}, {95, 20}, {80, 77}, {5, 60}},
+	} {
+		tr := squareToQuad(p)
+		for i, uv := range [4][2]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}} {
+			x, y := tr.apply(uv[0], uv[1])
+			if math.Abs(x-p[i][0]) > 1e-9 || math.Abs(y-p[i][1]) > 1e-9 {
+				t.Fatalf("%v: corner %d mapped to (%v, %v)", p, i, x, y)
+			}
+		}
+	}
+}
+
+func TestDeskew(t *testing.T) {
+	t.Parallel()
+
+	page := makeTextPage(300, 300)
+	for _, skew := range []float64{-7, -2.5, 4} {
+		src := Rotate(page, skew, color.White)
+		got, angle := Deskew(src, 10, color.White)
+		if math.Abs(angle+skew) > 0.3 {
+			t.Fatalf("skew %v: got angle %v want %v", skew, angle, -skew)
+		}
+		if got.Bounds().Dx() <= src.Bounds().Dx() {
+			t.Fatalf("skew %v: image not rotated", skew)
+		}
+	}
+
+	got, angle := Deskew(page, 10, color.White)
+	if angle != 0 || !compareNRGBA(got, page, 0) {
+		t.Fatalf("straight page rotated by %v", angle)
+	}
+}
+
+func TestTrim(t *testing.T) {
+	t.Parallel()
+
+	src := image.NewNRGBA(image.Rect(-5, -5, 15, 15))
+	draw.Draw(src, src.Rect, image.NewUniform(color.NRGBA{0xfe, 0xfe, 0xfe, 0xff}), image.Point{}, draw.Src)
+	src.SetNRGBA(0, 2, color.NRGBA{0xf8, 0xf8, 0xf8, 0xff})
+	src.SetNRGBA(1, 3, color.NRGBA{0, 0, 0, 0xff})
+	src.SetNRGBA(4, 6, color.NRGBA{0x10, 0, 0, 0xff})
+
+	got := Trim(src, 10)
+	want := Crop(src, image.Rect(1, 3, 5, 7))
+	if !compareNRGBA(got, want, 0) {
+		t.Fatalf("got bounds %v want %v", got.Bounds(), want.Bounds())
+	}
+	if got := Trim(src, 0); got.Bounds() != image.Rect(0, 0, 5, 5) {
+		t.Fatalf("got bounds %v want 5x5", got.Bounds())
+	}
+	if got := Trim(New(4, 4, color.White), 0); !got.Bounds().Empty() {
+		t.Fatalf("got bounds %v want empty", got.Bounds())
+	}
+}
+
+func TestAdaptiveThreshold(t *testing.T) {
+	t.Parallel()
+
+	// Dark dots on a background getting darker from left to right, the right part of the
+	// background being darker than the dots on the left.
+	src := image.NewNRGBA(image.Rect(0, 0, 64, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 64; x++ {
+			v := uint8(0xf0 - x*2)
+			if x%8 == 4 && y == 8 {
+				v -= 0x40
+			}
+			src.SetNRGBA(x, y, color.NRGBA{v, v, v, 0xff})
+		}
+	}
+	got := AdaptiveThreshold(src, 4, 10)
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 64; x++ {
+			want := uint8(0xff)
+			if x%8 == 4 && y == 8 {
+				want = 0
+			}
+			if v := got.GrayAt(x, y).Y; v != want {
+				t.Fatalf("pixel (%d, %d): got %#x want %#x", x, y, v, want)
+			}
+		}
+	}
+
+	if got := AdaptiveThreshold(&image.NRGBA{}, 4, 10); !got.Bounds().Empty() {
+		t.Fatalf("got bounds %v want empty", got.Bounds())
+	}
+}
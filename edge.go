@@ -0,0 +1,89 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+)
+
+// EdgeMode defines how the pixels outside of the image bounds are filled, e.g. when
+// the image is padded with Pad, or filtered near its edges with BlurEdge, SharpenEdge
+// and the convolutions.
+type EdgeMode struct {
+	kind edgeKind
+	// color is the fill color of EdgeColor as passed, AutoColor is resolved for each image.
+	color color.Color
+}
+
+type edgeKind int
+
+const (
+	edgeClamp edgeKind = iota
+	edgeReflect
+	edgeWrap
+	edgeConstant
+)
+
+// Edge modes.
+var (
+	// EdgeClamp replicates the edge pixels of the image. It is the zero EdgeMode.
+	EdgeClamp = EdgeMode{kind: edgeClamp} //nolint
+	// EdgeReflect mirrors the image at its edges, repeating the edge pixels: cba|abc|cba.
+	EdgeReflect = EdgeMode{kind: edgeReflect} //nolint
+	// EdgeWrap repeats the image as tiles: abc|abc|abc.
+	EdgeWrap = EdgeMode{kind: edgeWrap} //nolint
+)
+
+// EdgeColor returns the edge mode filling the pixels outside of the image with the color.
+// AutoColor fills them with the background color inferred from each image.
+func EdgeColor(c color.Color) EdgeMode {
+	return EdgeMode{kind: edgeConstant, color: c}
+}
+
+// fill returns the color filling the pixels outside of the image, transparent black
+// for the modes other than EdgeColor. AutoColor must be resolved first.
+func (m EdgeMode) fill() color.NRGBA {
+	if m.kind != edgeConstant {
+		return color.NRGBA{}
+	}
+	return color.NRGBAModel.Convert(m.color).(color.NRGBA)
+}
+
+// resolve returns the mode with AutoColor replaced by the color inferred from the image.
+func (m EdgeMode) resolve(img image.Image) EdgeMode {
+	if m.kind == edgeConstant {
+		m.color = resolveColor(img, m.color)
+	}
+	return m
+}
+
+// index maps the coordinate i to the range [0, n) of the image side. It reports false
+// for the coordinates outside of the image filled with the color of the mode.
+func (m EdgeMode) index(i, n int) (int, bool) {
+	if i >= 0 && i < n {
+		return i, true
+	}
+	switch m.kind {
+	case edgeReflect:
+		i %= 2 * n
+		if i < 0 {
+			i += 2 * n
+		}
+		if i >= n {
+			i = 2*n - 1 - i
+		}
+	case edgeWrap:
+		i %= n
+		if i < 0 {
+			i += n
+		}
+	case edgeConstant:
+		return 0, false
+	case edgeClamp:
+		if i < 0 {
+			i = 0
+		} else {
+			i = n - 1
+		}
+	}
+	return i, true
+}
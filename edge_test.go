@@ -0,0 +1,70 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestEdgeModeIndex(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		mode EdgeMode
+		want []int
+	}{
+		{"clamp", EdgeClamp, []int{0, 0, 0, 0, 1, 2, 2, 2, 2}},
+		{"reflect", EdgeReflect, []int{2, 1, 0, 0, 1, 2, 2, 1, 0}},
+		{"wrap", EdgeWrap, []int{0, 1, 2, 0, 1, 2, 0, 1, 2}},
+		{"color", EdgeColor(color.White), []int{-1, -1, -1, 0, 1, 2, -1, -1, -1}},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			for i, want := range tc.want {
+				got, ok := tc.mode.index(i-3, 3)
+				if !ok {
+					got = -1
+				}
+				if got != want {
+					t.Fatalf("index(%d, 3): got %d want %d", i-3, got, want)
+				}
+			}
+		})
+	}
+	if (EdgeMode{}) != EdgeClamp {
+		t.Fatalf("the zero EdgeMode is not EdgeClamp")
+	}
+}
+
+func TestEdgeColorAutoColor(t *testing.T) {
+	t.Parallel()
+
+	// The color inferred from a uniform image is its color, so the edges are not
+	// darkened by transparent black.
+	c := color.NRGBA{0x10, 0x80, 0x20, 0xff}
+	src := New(6, 4, c)
+	mode := EdgeColor(AutoColor)
+	box := [9]float64{1, 1, 1, 1, 1, 1, 1, 1, 1}
+
+	testCases := []struct {
+		name string
+		got  *image.NRGBA
+	}{
+		{"Pad", Pad(src, 10, 8, mode)},
+		{"BlurEdge", BlurEdge(src, 1, mode)},
+		{"SharpenEdge", SharpenEdge(src, 1, mode)},
+		{"Convolve3x3", Convolve3x3(src, box, &ConvolveOptions{Normalize: true, Edge: mode})},
+	}
+	for _, tc := range testCases {
+		want := New(tc.got.Rect.Dx(), tc.got.Rect.Dy(), c)
+		if !compareNRGBA(tc.got, want, 1) {
+			t.Fatalf("%s: got %v at the corner want %v", tc.name, tc.got.NRGBAAt(0, 0), c)
+		}
+	}
+	if got := Pad(&image.NRGBA{}, 2, 1, mode); !compareNRGBA(got, New(2, 1, color.Transparent), 0) {
+		t.Fatalf("Pad of an empty image: got %v", got.Pix)
+	}
+}
@@ -1,6 +1,7 @@
 package imaging
 
 import (
+	"fmt"
 	"image"
 	"math"
 )
@@ -12,29 +13,155 @@ func gaussianBlurKernel(x, sigma float64) float64 {
 
 // Blur produces a blurred version of the image using a Gaussian function.
 // Sigma parameter must be positive and indicates how much the image will be blurred.
+// Near the image edges, only the pixels inside of the image are averaged.
 //
 // Example:
 //
 //	dstImage := imaging.Blur(srcImage, 3.5)
 func Blur(img image.Image, sigma float64) *image.NRGBA {
+	return blur(img, sigma, nil)
+}
+
+// BlurEdge is like Blur, but the pixels outside of the image are averaged as well,
+// filled as the edge mode defines. EdgeWrap keeps the blurred tiling textures
+// seamless.
+//
+// Example:
+//
+//	dstImage := imaging.BlurEdge(textureImage, 3.5, imaging.EdgeWrap)
+func BlurEdge(img image.Image, sigma float64, mode EdgeMode) *image.NRGBA {
+	mode = mode.resolve(img)
+	return blur(img, sigma, &mode)
+}
+
+// blur blurs the image with the edge mode, nil for averaging only the pixels inside
+// of the image.
+func blur(img image.Image, sigma float64, edge *EdgeMode) *image.NRGBA {
 	if sigma <= 0 {
 		return Clone(img)
 	}
+	kernel := blurKernel(sigma)
+	return blurVertical(blurHorizontal(img, kernel, edge), kernel, edge)
+}
 
+// blurKernel returns the half of the Gaussian kernel of the sigma, from the center out.
+func blurKernel(sigma float64) []float64 {
 	radius := int(math.Ceil(sigma * 3.0))
 	kernel := make([]float64, radius+1)
 
 	for i := 0; i <= radius; i++ {
 		kernel[i] = gaussianBlurKernel(float64(i), sigma)
 	}
+	return kernel
+}
 
-	return blurVertical(blurHorizontal(img, kernel), kernel)
+// BlurInto is like Blur, but writes the blurred image into dst, which must have the
+// size of the image, instead of allocating it, e.g. to reuse the buffers of the video
+// frames. The intermediate buffer is reused between the calls as well. dst may be the
+// image itself, blurring it in place. It returns ErrDestinationSize if the sizes differ.
+//
+// Example:
+//
+//	err := imaging.BlurInto(frame, frame, 2)
+func BlurInto(dst *image.NRGBA, img image.Image, sigma float64) error {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	if dst.Rect.Dx() != w || dst.Rect.Dy() != h {
+		return fmt.Errorf("%w: %dx%d for the image of %dx%d", ErrDestinationSize, dst.Rect.Dx(), dst.Rect.Dy(), w, h)
+	}
+	if sigma <= 0 {
+		return CloneInto(dst, img)
+	}
+	kernel := blurKernel(sigma)
+	tmp := getScratch(w, h)
+	defer putScratch(tmp)
+	blurHorizontalInto(tmp, img, kernel, nil)
+	blurVerticalInto(dst, tmp, kernel, nil)
+	return nil
 }
 
-func blurHorizontal(img image.Image, kernel []float64) *image.NRGBA {
-	src := newScanner(img)
-	dst := image.NewNRGBA(image.Rect(0, 0, src.w, src.h))
+// Indexes of blurIndexes for the pixels outside of the image.
+const (
+	blurSkip  = -1 // not averaged
+	blurColor = -2 // the color of the edge mode
+)
+
+// blurIndexes returns the pixel offsets, from -radius to n+radius-1, of the image
+// side of n pixels along which the kernel of the radius is applied.
+func blurIndexes(n, radius int, edge *EdgeMode) []int {
+	if n == 0 {
+		return nil
+	}
+	idx := make([]int, n+2*radius)
+	for i := range idx {
+		j, ok := i-radius, true
+		if j < 0 || j >= n {
+			if edge == nil {
+				idx[i] = blurSkip
+				continue
+			}
+			j, ok = edge.index(j, n)
+		}
+		if ok {
+			idx[i] = j * 4
+		} else {
+			idx[i] = blurColor
+		}
+	}
+	return idx
+}
+
+// blurLine applies the kernel to the pixel at the position p of the scan line.
+func blurLine(scanLine []float64, idx []int, p int, kernel, fill []float64, d []uint8) {
 	radius := len(kernel) - 1
+	var r, g, b, a, wsum float64
+	for k := -radius; k <= radius; k++ {
+		i := idx[p+k+radius]
+		if i == blurSkip {
+			continue
+		}
+		weight := kernel[absInt(k)]
+		wsum += weight
+		s := fill
+		if i != blurColor {
+			s = scanLine[i : i+4 : i+4]
+		}
+		wa := s[3] * weight
+		r += s[0] * wa
+		g += s[1] * wa
+		b += s[2] * wa
+		a += wa
+	}
+	if a == 0 {
+		d[0], d[1], d[2], d[3] = 0, 0, 0, 0
+		return
+	}
+	aInv := 1 / a
+	d[0] = clamp(r * aInv)
+	d[1] = clamp(g * aInv)
+	d[2] = clamp(b * aInv)
+	d[3] = clamp(a / wsum)
+}
+
+// edgeFill returns the color of the resolved edge mode as float64 channels.
+func edgeFill(edge *EdgeMode) []float64 {
+	if edge == nil {
+		return nil
+	}
+	c := edge.fill()
+	return []float64{float64(c.R), float64(c.G), float64(c.B), float64(c.A)}
+}
+
+func blurHorizontal(img image.Image, kernel []float64, edge *EdgeMode) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	blurHorizontalInto(dst, img, kernel, edge)
+	return dst
+}
+
+// blurHorizontalInto is blurHorizontal writing into dst of the size of the image.
+func blurHorizontalInto(dst *image.NRGBA, img image.Image, kernel []float64, edge *EdgeMode) {
+	src := newScanner(img)
+	idx := blurIndexes(src.w, len(kernel)-1, edge)
+	fill := edgeFill(edge)
 
 	parallel(0, src.h, func(ys <-chan int) {
 		scanLine := make([]uint8, src.w*4)
@@ -45,46 +172,24 @@ func blurHorizontal(img image.Image, kernel []float64) *image.NRGBA {
 				scanLineF[i] = float64(v)
 			}
 			for x := 0; x < src.w; x++ {
-				min := x - radius
-				if min < 0 {
-					min = 0
-				}
-				max := x + radius
-				if max > src.w-1 {
-					max = src.w - 1
-				}
-				var r, g, b, a, wsum float64
-				for ix := min; ix <= max; ix++ {
-					i := ix * 4
-					weight := kernel[absInt(x-ix)]
-					wsum += weight
-					s := scanLineF[i : i+4 : i+4]
-					wa := s[3] * weight
-					r += s[0] * wa
-					g += s[1] * wa
-					b += s[2] * wa
-					a += wa
-				}
-				if a != 0 {
-					aInv := 1 / a
-					j := y*dst.Stride + x*4
-					d := dst.Pix[j : j+4 : j+4]
-					d[0] = clamp(r * aInv)
-					d[1] = clamp(g * aInv)
-					d[2] = clamp(b * aInv)
-					d[3] = clamp(a / wsum)
-				}
+				j := y*dst.Stride + x*4
+				blurLine(scanLineF, idx, x, kernel, fill, dst.Pix[j:j+4:j+4])
 			}
 		}
 	})
+}
 
+func blurVertical(img image.Image, kernel []float64, edge *EdgeMode) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	blurVerticalInto(dst, img, kernel, edge)
 	return dst
 }
 
-func blurVertical(img image.Image, kernel []float64) *image.NRGBA {
+// blurVerticalInto is blurVertical writing into dst of the size of the image.
+func blurVerticalInto(dst *image.NRGBA, img image.Image, kernel []float64, edge *EdgeMode) {
 	src := newScanner(img)
-	dst := image.NewNRGBA(image.Rect(0, 0, src.w, src.h))
-	radius := len(kernel) - 1
+	idx := blurIndexes(src.h, len(kernel)-1, edge)
+	fill := edgeFill(edge)
 
 	parallel(0, src.w, func(xs <-chan int) {
 		scanLine := make([]uint8, src.h*4)
@@ -95,40 +200,11 @@ func blurVertical(img image.Image, kernel []float64) *image.NRGBA {
 				scanLineF[i] = float64(v)
 			}
 			for y := 0; y < src.h; y++ {
-				min := y - radius
-				if min < 0 {
-					min = 0
-				}
-				max := y + radius
-				if max > src.h-1 {
-					max = src.h - 1
-				}
-				var r, g, b, a, wsum float64
-				for iy := min; iy <= max; iy++ {
-					i := iy * 4
-					weight := kernel[absInt(y-iy)]
-					wsum += weight
-					s := scanLineF[i : i+4 : i+4]
-					wa := s[3] * weight
-					r += s[0] * wa
-					g += s[1] * wa
-					b += s[2] * wa
-					a += wa
-				}
-				if a != 0 {
-					aInv := 1 / a
-					j := y*dst.Stride + x*4
-					d := dst.Pix[j : j+4 : j+4]
-					d[0] = clamp(r * aInv)
-					d[1] = clamp(g * aInv)
-					d[2] = clamp(b * aInv)
-					d[3] = clamp(a / wsum)
-				}
+				j := y*dst.Stride + x*4
+				blurLine(scanLineF, idx, y, kernel, fill, dst.Pix[j:j+4:j+4])
 			}
 		}
 	})
-
-	return dst
 }
 
 // Sharpen produces a sharpened version of the image.
@@ -138,13 +214,29 @@ func blurVertical(img image.Image, kernel []float64) *image.NRGBA {
 //
 //	dstImage := imaging.Sharpen(srcImage, 3.5)
 func Sharpen(img image.Image, sigma float64) *image.NRGBA {
+	return sharpen(img, sigma, nil)
+}
+
+// SharpenEdge is like Sharpen, but the image is blurred with the edge mode as in
+// BlurEdge.
+//
+// Example:
+//
+//	dstImage := imaging.SharpenEdge(textureImage, 3.5, imaging.EdgeReflect)
+func SharpenEdge(img image.Image, sigma float64, mode EdgeMode) *image.NRGBA {
+	mode = mode.resolve(img)
+	return sharpen(img, sigma, &mode)
+}
+
+// sharpen sharpens the image with the edge mode of blur.
+func sharpen(img image.Image, sigma float64, edge *EdgeMode) *image.NRGBA {
 	if sigma <= 0 {
 		return Clone(img)
 	}
 
 	src := newScanner(img)
 	dst := image.NewNRGBA(image.Rect(0, 0, src.w, src.h))
-	blurred := Blur(img, sigma)
+	blurred := blur(img, sigma, edge)
 
 	parallel(0, src.h, func(ys <-chan int) {
 		scanLine := make([]uint8, src.w*4)
@@ -166,3 +258,117 @@ func Sharpen(img image.Image, sigma float64) *image.NRGBA {
 
 	return dst
 }
+
+// Clarity increases (positive amount) or decreases (negative amount) the local contrast
+// of the midtones while leaving the shadows and highlights mostly unchanged. It makes
+// textures and details stand out without the halos of a strong sharpening.
+// The amount is typically in range (-1, 1). The amount = 0 gives the original image.
+//
+// Example:
+//
+//	dstImage := imaging.Clarity(srcImage, 0.3)
+func Clarity(img image.Image, amount float64) *image.NRGBA {
+	src := newScanner(img)
+	if amount == 0 || src.w == 0 || src.h == 0 {
+		return Clone(img)
+	}
+
+	// The local contrast is measured against a blur large enough to cover
+	// whole image features rather than single edges.
+	size := src.w
+	if src.h < size {
+		size = src.h
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, src.w, src.h))
+	blurred := Blur(img, math.Max(2, float64(size)/100))
+
+	parallel(0, src.h, func(ys <-chan int) {
+		for y := range ys {
+			i := y * dst.Stride
+			src.scan(0, y, src.w, y+1, dst.Pix[i:i+src.w*4])
+			for x := 0; x < src.w; x++ {
+				d := dst.Pix[i : i+3 : i+3]
+				b := blurred.Pix[i : i+3 : i+3]
+				l := (0.299*float64(d[0]) + 0.587*float64(d[1]) + 0.114*float64(d[2])) / 255
+				// The weight is 1 for the middle gray and falls to 0 for black and white.
+				f := amount * 4 * l * (1 - l)
+				d[0] = clamp(float64(d[0]) + f*(float64(d[0])-float64(b[0])))
+				d[1] = clamp(float64(d[1]) + f*(float64(d[1])-float64(b[1])))
+				d[2] = clamp(float64(d[2]) + f*(float64(d[2])-float64(b[2])))
+				i += 4
+			}
+		}
+	})
+
+	return dst
+}
+
+// Denoise reduces the noise of the image using a bilateral filter: each pixel is averaged
+// with its neighbours, weighting them down the more their colors differ. Sigma is the
+// color difference (in the 0-255 range) regarded as noise, so edges with a larger contrast
+// are preserved. Typical values are from 10 to 30.
+//
+// Example:
+//
+//	dstImage := imaging.Denoise(srcImage, 20)
+func Denoise(img image.Image, sigma float64) *image.NRGBA {
+	if sigma <= 0 {
+		return Clone(img)
+	}
+
+	const radius = 2
+	var spatial [radius + 1][radius + 1]float64
+	for dy := 0; dy <= radius; dy++ {
+		for dx := 0; dx <= radius; dx++ {
+			spatial[dy][dx] = math.Exp(-float64(dx*dx+dy*dy) / (2 * 1.5 * 1.5))
+		}
+	}
+	rangeFactor := -1 / (2 * sigma * sigma)
+
+	src := toNRGBA(img)
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+
+	parallel(0, h, func(ys <-chan int) {
+		for y := range ys {
+			for x := 0; x < w; x++ {
+				i := y*src.Stride + x*4
+				c := src.Pix[i : i+4 : i+4]
+				var r, g, b, a, wsum float64
+				for iy := y - radius; iy <= y+radius; iy++ {
+					if iy < 0 || iy >= h {
+						continue
+					}
+					for ix := x - radius; ix <= x+radius; ix++ {
+						if ix < 0 || ix >= w {
+							continue
+						}
+						j := iy*src.Stride + ix*4
+						s := src.Pix[j : j+4 : j+4]
+						dr := float64(s[0]) - float64(c[0])
+						dg := float64(s[1]) - float64(c[1])
+						db := float64(s[2]) - float64(c[2])
+						weight := spatial[absInt(iy-y)][absInt(ix-x)] * math.Exp((dr*dr+dg*dg+db*db)*rangeFactor/3)
+						wsum += weight
+						wa := float64(s[3]) * weight
+						r += float64(s[0]) * wa
+						g += float64(s[1]) * wa
+						b += float64(s[2]) * wa
+						a += wa
+					}
+				}
+				if a != 0 {
+					aInv := 1 / a
+					j := y*dst.Stride + x*4
+					d := dst.Pix[j : j+4 : j+4]
+					d[0] = clamp(r * aInv)
+					d[1] = clamp(g * aInv)
+					d[2] = clamp(b * aInv)
+					d[3] = clamp(a / wsum)
+				}
+			}
+		}
+	})
+
+	return dst
+}
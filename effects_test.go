@@ -1,7 +1,9 @@
 package imaging
 
 import (
+	"errors"
 	"image"
+	"image/color"
 	"testing"
 )
 
@@ -111,6 +113,61 @@ func TestBlurGolden(t *testing.T) {
 	}
 }
 
+func TestBlurEdge(t *testing.T) {
+	t.Parallel()
+
+	// Blurring the image padded with the edge mode and cropping the padding gives the
+	// image blurred with the edge mode.
+	src := Crop(testdataFlowersSmallPNG, image.Rect(0, 0, 16, 12))
+	const sigma, radius = 1.5, 5
+	inner := image.Rect(radius, radius, 16+radius, 12+radius)
+	testCases := []struct {
+		name string
+		mode EdgeMode
+	}{
+		{"clamp", EdgeClamp},
+		{"reflect", EdgeReflect},
+		{"wrap", EdgeWrap},
+		{"color", EdgeColor(color.NRGBA{0x20, 0x40, 0x80, 0x80})},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			padded := Pad(src, 16+2*radius, 12+2*radius, tc.mode)
+			if got, want := BlurEdge(src, sigma, tc.mode), Crop(Blur(padded, sigma), inner); !compareNRGBA(got, want, 0) {
+				t.Fatalf("BlurEdge: got result %#v want %#v", got, want)
+			}
+			if got, want := SharpenEdge(src, sigma, tc.mode), Crop(Sharpen(padded, sigma), inner); !compareNRGBA(got, want, 0) {
+				t.Fatalf("SharpenEdge: got result %#v want %#v", got, want)
+			}
+		})
+	}
+}
+
+func TestBlurInto(t *testing.T) {
+	t.Parallel()
+
+	src := makeNoiseNRGBA(20, 15, 1)
+	for _, sigma := range []float64{0, 0.5, 3} {
+		dst := New(20, 15, color.White)
+		if err := BlurInto(dst, src, sigma); err != nil {
+			t.Fatalf("sigma %v: unexpected error: %v", sigma, err)
+		}
+		want := Blur(src, sigma)
+		if !compareNRGBA(dst, want, 0) {
+			t.Fatalf("sigma %v: result differs from Blur", sigma)
+		}
+		inPlace := Clone(src)
+		if err := BlurInto(inPlace, inPlace, sigma); err != nil || !compareNRGBA(inPlace, want, 0) {
+			t.Fatalf("sigma %v: in place result differs from Blur, error %v", sigma, err)
+		}
+	}
+	if err := BlurInto(New(20, 14, color.White), src, 1); !errors.Is(err, ErrDestinationSize) {
+		t.Fatalf("got error %v want %v", err, ErrDestinationSize)
+	}
+}
+
 func BenchmarkBlur(b *testing.B) {
 	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
@@ -248,3 +305,86 @@ func BenchmarkSharpen(b *testing.B) {
 		Sharpen(testdataBranchesJPG, 3)
 	}
 }
+
+func TestClarity(t *testing.T) {
+	t.Parallel()
+
+	src := image.NewNRGBA(image.Rect(0, 0, 20, 1))
+	for x := 0; x < 20; x++ {
+		v := uint8(0x70)
+		if x >= 10 {
+			v = 0x90
+		}
+		src.SetNRGBA(x, 0, color.NRGBA{v, v, v, 0xff})
+	}
+
+	if got := Clarity(src, 0); !compareNRGBA(got, src, 0) {
+		t.Fatalf("got result %#v want %#v", got, src)
+	}
+
+	more := Clarity(src, 0.5)
+	if more.Pix[9*4] >= 0x70 || more.Pix[10*4] <= 0x90 {
+		t.Fatalf("local contrast not increased: %#x %#x", more.Pix[9*4], more.Pix[10*4])
+	}
+	less := Clarity(src, -0.5)
+	if less.Pix[9*4] <= 0x70 || less.Pix[10*4] >= 0x90 {
+		t.Fatalf("local contrast not decreased: %#x %#x", less.Pix[9*4], less.Pix[10*4])
+	}
+
+	white := New(20, 1, color.NRGBA{0xff, 0xff, 0xff, 0xff})
+	if got := Clarity(white, 1); !compareNRGBA(got, white, 0) {
+		t.Fatalf("highlights changed: %#v", got)
+	}
+}
+
+func BenchmarkClarity(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Clarity(testdataBranchesJPG, 0.3)
+	}
+}
+
+func TestDenoise(t *testing.T) {
+	t.Parallel()
+
+	src := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			v := 0x20
+			if x >= 8 {
+				v = 0xe0
+			}
+			// Add a checkerboard noise pattern.
+			if (x+y)%2 == 0 {
+				v += 6
+			} else {
+				v -= 6
+			}
+			src.SetNRGBA(x, y, color.NRGBA{uint8(v), uint8(v), uint8(v), 0xff})
+		}
+	}
+
+	if got := Denoise(src, 0); !compareNRGBA(got, src, 0) {
+		t.Fatalf("got result %#v want %#v", got, src)
+	}
+
+	got := Denoise(src, 20)
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			want := 0x20
+			if x >= 8 {
+				want = 0xe0
+			}
+			if v := int(got.NRGBAAt(x, y).R); absInt(v-want) > 3 {
+				t.Fatalf("pixel (%d, %d): got %#x want %#x", x, y, v, want)
+			}
+		}
+	}
+}
+
+func BenchmarkDenoise(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Denoise(testdataBranchesJPG, 20)
+	}
+}
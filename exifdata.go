@@ -0,0 +1,411 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+// EXIF tags used by the package.
+const (
+	tagDateTime            = 306
+	tagArtist              = 315
+	tagCopyright           = 33432
+	tagDateTimeOriginal    = 36867
+	tagDateTimeDigitized   = 36868
+	tagOffsetTime          = 36880
+	tagOffsetTimeOriginal  = 36881
+	tagOffsetTimeDigitized = 36882
+	tagPixelXDimension     = 40962
+	tagPixelYDimension     = 40963
+)
+
+// exifStructureTags are the IFD0 tags that describe the layout of the pixel data
+// or are handled separately (GeoTIFF, ICC profile). They belong to the encoded image rather
+// than to the EXIF metadata.
+var exifStructureTags = map[uint16]bool{ //nolint
+	254:                            true, // NewSubfileType
+	255:                            true, // SubfileType
+	tagImageWidth:                  true,
+	tagImageLength:                 true,
+	tagBitsPerSample:               true,
+	tagCompression:                 true,
+	262:                            true, // PhotometricInterpretation
+	tagStripOffsets:                true,
+	tagSamplesPerPixel:             true,
+	tagRowsPerStrip:                true,
+	tagStripByteCounts:             true,
+	284:                            true, // PlanarConfiguration
+	tagPageNumber:                  true,
+	tagPredictor:                   true,
+	320:                            true, // ColorMap
+	tagTileWidth:                   true,
+	tagTileLength:                  true,
+	tagTileOffsets:                 true,
+	tagTileByteCounts:              true,
+	338:                            true, // ExtraSamples
+	339:                            true, // SampleFormat
+	tagJPEGInterchangeFormat:       true,
+	tagJPEGInterchangeFormatLength: true,
+	530:                            true, // YCbCrSubSampling
+	tagModelPixelScale:             true,
+	tagModelTiepoint:               true,
+	tagModelTransformation:         true,
+	tagGeoKeyDirectory:             true,
+	tagGeoDoubleParams:             true,
+	tagGeoASCIIParams:              true,
+	tagGDALMetadata:                true,
+	tagGDALNoData:                  true,
+	tagICCProfile:                  true,
+}
+
+// errEXIFTooLarge means the EXIF data doesn't fit into a JPEG APP1 segment.
+var errEXIFTooLarge = errors.New("imaging: EXIF data too large for JPEG")
+
+// exifHeader is the header of the JPEG APP1 segment holding the EXIF data.
+const exifHeader = "Exif\x00\x00"
+
+// EXIF holds the EXIF metadata of a JPEG or TIFF image.
+type EXIF struct {
+	// dir is the main directory (IFD0) with the EXIF and GPS sub-directories.
+	dir *tiffDir
+}
+
+// newEXIF returns the EXIF metadata of the TIFF directory, leaving out the tags
+// that describe the pixel data. It returns nil if there is no metadata.
+func newEXIF(d *tiffDir) *EXIF {
+	dir := newTIFFDir(d.order)
+	for _, f := range d.fields {
+		if exifStructureTags[f.tag] {
+			continue
+		}
+		if _, ok := d.subs[f.tag]; !ok && isSubDirTag(f.tag) {
+			// The sub-directory couldn't be parsed.
+			continue
+		}
+		dir.fields = append(dir.fields, tiffField{tag: f.tag, typ: f.typ, count: f.count, data: append([]byte(nil), f.data...)})
+	}
+	for tag, sub := range d.subs {
+		dir.subs[tag] = cloneTIFFDir(sub)
+	}
+	if len(dir.fields) == 0 {
+		return nil
+	}
+	return &EXIF{dir: dir}
+}
+
+// NewEXIF returns empty EXIF metadata to be filled with the Set methods
+// and written with the WithEXIF option.
+//
+// Example:
+//
+//	exif := imaging.NewEXIF().SetArtist("Jane Doe").SetCopyright("(c) 2023 Jane Doe")
+//	err := imaging.Save(img, "out.jpg", imaging.WithEXIF(exif))
+func NewEXIF() *EXIF {
+	return &EXIF{dir: newTIFFDir(binary.BigEndian)}
+}
+
+// clone returns a deep copy of the EXIF metadata, or empty metadata if e is nil.
+func (e *EXIF) clone() *EXIF {
+	if e == nil {
+		return NewEXIF()
+	}
+	return &EXIF{dir: cloneTIFFDir(e.dir)}
+}
+
+// withoutModifyDate returns the EXIF metadata without the date of the last change
+// (DateTime), copied if it has the date.
+func (e *EXIF) withoutModifyDate() *EXIF {
+	if e == nil || e.dir.field(tagDateTime) == nil {
+		return e
+	}
+	c := e.clone()
+	c.dir.remove(tagDateTime)
+	return c
+}
+
+// empty reports whether there are no tags to write.
+func (e *EXIF) empty() bool {
+	return e == nil || (len(e.dir.fields) == 0 && len(e.dir.subs) == 0)
+}
+
+// exifIFD returns the EXIF sub-directory, creating it if needed.
+func (e *EXIF) exifIFD() *tiffDir {
+	sub := e.dir.subs[tagExifIFD]
+	if sub == nil {
+		sub = newTIFFDir(e.dir.order)
+		e.dir.subs[tagExifIFD] = sub
+	}
+	return sub
+}
+
+// Artist returns the name of the camera owner, photographer or image creator.
+func (e *EXIF) Artist() string {
+	return e.dir.ascii(tagArtist)
+}
+
+// Copyright returns the copyright notice.
+func (e *EXIF) Copyright() string {
+	return e.dir.ascii(tagCopyright)
+}
+
+// SetArtist returns a copy of the EXIF metadata with the artist set to s.
+// An empty string removes the tag. Like the other Set methods, it can be
+// called on a nil *EXIF, e.g. the EXIF field of Metadata of an image without EXIF data.
+func (e *EXIF) SetArtist(s string) *EXIF {
+	c := e.clone()
+	if s == "" {
+		c.dir.remove(tagArtist)
+	} else {
+		c.dir.setASCII(tagArtist, s)
+	}
+	return c
+}
+
+// SetCopyright returns a copy of the EXIF metadata with the copyright notice set to s.
+// An empty string removes the tag.
+func (e *EXIF) SetCopyright(s string) *EXIF {
+	c := e.clone()
+	if s == "" {
+		c.dir.remove(tagCopyright)
+	} else {
+		c.dir.setASCII(tagCopyright, s)
+	}
+	return c
+}
+
+// SetDateTime returns a copy of the EXIF metadata with the date and time the picture
+// was taken (DateTimeOriginal) and the date of the last change (DateTime) set to t.
+// The time zone offset of t is recorded as well. The zero time removes the dates.
+func (e *EXIF) SetDateTime(t time.Time) *EXIF {
+	c := e.clone()
+	if t.IsZero() {
+		c.dir.remove(tagDateTime)
+		if exif := c.dir.subs[tagExifIFD]; exif != nil {
+			for _, tag := range []uint16{tagDateTimeOriginal, tagDateTimeDigitized, tagOffsetTime, tagOffsetTimeOriginal, tagOffsetTimeDigitized} {
+				exif.remove(tag)
+			}
+		}
+		return c
+	}
+	s := t.Format("2006:01:02 15:04:05")
+	offset := t.Format("-07:00")
+	exif := c.exifIFD()
+	c.dir.setASCII(tagDateTime, s)
+	exif.setASCII(tagDateTimeOriginal, s)
+	exif.setASCII(tagOffsetTime, offset)
+	exif.setASCII(tagOffsetTimeOriginal, offset)
+	return c
+}
+
+// SetOrientation returns a copy of the EXIF metadata with the orientation tag set to o.
+// OrientationUnspecified removes the tag.
+func (e *EXIF) SetOrientation(o Orientation) *EXIF {
+	c := e.clone()
+	if o < OrientationNormal || o > OrientationRotate90 {
+		c.dir.remove(tagOrientation)
+	} else {
+		c.dir.setUints(tagOrientation, tiffShort, uint32(o))
+	}
+	return c
+}
+
+// StripGPS returns a copy of the EXIF metadata without the GPS data (the location
+// where the picture was taken).
+func (e *EXIF) StripGPS() *EXIF {
+	c := e.clone()
+	c.dir.remove(tagGPSIFD)
+	return c
+}
+
+// RemoveTag returns a copy of the EXIF metadata without the tag with the given ID.
+// The tag is removed from the main directory and from the EXIF sub-directory, so
+// both TIFF tags (e.g. 271 for Make) and EXIF tags (e.g. 37500 for MakerNote) can be removed.
+func (e *EXIF) RemoveTag(tag uint16) *EXIF {
+	c := e.clone()
+	c.dir.remove(tag)
+	if exif := c.dir.subs[tagExifIFD]; exif != nil {
+		exif.remove(tag)
+	}
+	return c
+}
+
+// isSubDirTag reports whether the tag points to a sub-directory.
+func isSubDirTag(tag uint16) bool {
+	for _, t := range tiffSubDirTags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// cloneTIFFDir returns a deep copy of the directory fields and sub-directories.
+func cloneTIFFDir(d *tiffDir) *tiffDir {
+	c := newTIFFDir(d.order)
+	for _, f := range d.fields {
+		c.fields = append(c.fields, tiffField{tag: f.tag, typ: f.typ, count: f.count, data: append([]byte(nil), f.data...)})
+	}
+	for tag, blobs := range d.blobs {
+		c.blobs[tag] = blobs
+	}
+	for tag, sub := range d.subs {
+		c.subs[tag] = cloneTIFFDir(sub)
+	}
+	return c
+}
+
+// forImage returns a copy of the EXIF directory with the pixel dimensions
+// updated to the size of the encoded image.
+func (e *EXIF) forImage(width, height int) *tiffDir {
+	dir := cloneTIFFDir(e.dir)
+	if sub := dir.subs[tagExifIFD]; sub != nil {
+		if sub.field(tagPixelXDimension) != nil {
+			sub.setUints(tagPixelXDimension, tiffLong, uint32(width))
+		}
+		if sub.field(tagPixelYDimension) != nil {
+			sub.setUints(tagPixelYDimension, tiffLong, uint32(height))
+		}
+	}
+	return dir
+}
+
+// writeTIFF adds the EXIF tags to the TIFF directory of the encoded image.
+// The tags already set by the encoder are kept.
+func (e *EXIF) writeTIFF(d *tiffDir) {
+	dir := e.forImage(int(d.uint(tagImageWidth)), int(d.uint(tagImageLength)))
+	for _, f := range dir.fields {
+		if d.field(f.tag) == nil {
+			d.set(f.tag, f.typ, f.count, f.convert(dir.order, d.order))
+		}
+	}
+	for tag, sub := range dir.subs {
+		d.subs[tag] = sub
+	}
+}
+
+// jpegSegment returns the JPEG APP1 segment holding the EXIF data for the image of the given size.
+func (e *EXIF) jpegSegment(width, height int) ([]byte, error) {
+	dir := e.forImage(width, height)
+	buf := &bytes.Buffer{}
+	buf.Write([]byte{0xff, 0xe1, 0, 0})
+	buf.WriteString(exifHeader)
+	if err := writeTIFF(buf, dir.order, []*tiffDir{dir}); err != nil {
+		return nil, err
+	}
+	segment := buf.Bytes()
+	if len(segment)-2 > 0xffff {
+		return nil, errEXIFTooLarge
+	}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(segment)-2))
+	return segment, nil
+}
+
+// DateTime returns the date and time the picture was taken. The original date
+// (DateTimeOriginal) is preferred over the digitization date and the date of the
+// last change (DateTime). EXIF dates are in local time of the camera, so unless the
+// time zone offset is recorded as well, the returned time is in UTC with the same
+// clock value. It reports false if there is no valid date.
+func (e *EXIF) DateTime() (time.Time, bool) {
+	exif := e.dir.subs[tagExifIFD]
+	if exif == nil {
+		exif = newTIFFDir(e.dir.order)
+	}
+	candidates := []struct {
+		dir       *tiffDir
+		tag, zone uint16
+	}{
+		{exif, tagDateTimeOriginal, tagOffsetTimeOriginal},
+		{exif, tagDateTimeDigitized, tagOffsetTimeDigitized},
+		{e.dir, tagDateTime, tagOffsetTime},
+	}
+	for _, c := range candidates {
+		s := c.dir.ascii(c.tag)
+		if s == "" {
+			continue
+		}
+		if offset := exif.ascii(c.zone); offset != "" {
+			if t, err := time.Parse("2006:01:02 15:04:05-07:00", s+offset); err == nil {
+				return t, true
+			}
+		}
+		if t, err := time.Parse("2006:01:02 15:04:05", s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Orientation returns the value of the orientation tag or OrientationUnspecified.
+func (e *EXIF) Orientation() Orientation {
+	o := Orientation(e.dir.uint(tagOrientation))
+	if o < OrientationNormal || o > OrientationRotate90 {
+		return OrientationUnspecified
+	}
+	return o
+}
+
+// readJPEGEXIF returns the EXIF data (a TIFF structure) of the JPEG image
+// or nil if there is none.
+func readJPEGEXIF(data []byte) []byte {
+	var exif []byte
+	walkJPEGSegments(data, func(marker byte, payload []byte) bool {
+		if marker == markerAPP1 && len(payload) > len(exifHeader) && string(payload[:len(exifHeader)]) == exifHeader {
+			exif = payload[len(exifHeader):]
+			return false
+		}
+		return true
+	})
+	return exif
+}
+
+// JPEG markers used by the package.
+const (
+	markerAPP1  = 0xe1
+	markerAPP2  = 0xe2
+	markerAPP11 = 0xeb
+	markerAPP13 = 0xed
+	markerSOS   = 0xda
+)
+
+// walkJPEGSegments calls fn for each marker segment of the JPEG image before the
+// image data, until fn returns false. Broken segments end the walk.
+func walkJPEGSegments(data []byte, fn func(marker byte, payload []byte) bool) {
+	if !isJPEG(data) {
+		return
+	}
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xff {
+			return
+		}
+		marker := data[pos+1]
+		if marker == 0xff {
+			// Fill byte.
+			pos++
+			continue
+		}
+		if marker == 0x01 || (marker >= 0xd0 && marker <= 0xd7) {
+			// Markers without a length.
+			pos += 2
+			continue
+		}
+		if marker == markerSOS {
+			return
+		}
+		size := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if size < 2 || pos+2+size > len(data) {
+			return
+		}
+		if !fn(marker, data[pos+4:pos+2+size]) {
+			return
+		}
+		pos += 2 + size
+	}
+}
+
+// isJPEG reports whether data starts with a JPEG SOI marker.
+func isJPEG(data []byte) bool {
+	return len(data) >= 2 && data[0] == 0xff && data[1] == 0xd8
+}
@@ -0,0 +1,274 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+// makeEXIFJPEG returns a JPEG image with the EXIF directory stored in the APP1 segment.
+func makeEXIFJPEG(t *testing.T, dir *tiffDir) []byte {
+	t.Helper()
+	exif := &bytes.Buffer{}
+	if err := writeTIFF(exif, dir.order, []*tiffDir{dir}); err != nil {
+		t.Fatalf("failed to write EXIF data: %v", err)
+	}
+	img := &bytes.Buffer{}
+	if err := Encode(img, makeNoiseNRGBA(8, 8, 1), JPEG); err != nil {
+		t.Fatalf("failed to encode JPEG: %v", err)
+	}
+	segment := []byte{0xff, 0xe1, 0, 0}
+	segment = append(segment, exifHeader...)
+	segment = append(segment, exif.Bytes()...)
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(segment)-2))
+
+	data := img.Bytes()
+	out := append([]byte{}, data[:2]...)
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+func TestEXIFDateTime(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		ifd0   map[uint16]string
+		exif   map[uint16]string
+		want   time.Time
+		wantOK bool
+	}{
+		{
+			name:   "original date",
+			ifd0:   map[uint16]string{tagDateTime: "2023:05:06 07:08:09"},
+			exif:   map[uint16]string{tagDateTimeOriginal: "2021:01:02 03:04:05", tagDateTimeDigitized: "2022:01:01 00:00:00"},
+			want:   time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC),
+			wantOK: true,
+		},
+		{
+			name:   "original date with offset",
+			exif:   map[uint16]string{tagDateTimeOriginal: "2021:01:02 03:04:05", tagOffsetTimeOriginal: "+09:00"},
+			want:   time.Date(2021, 1, 1, 18, 4, 5, 0, time.UTC),
+			wantOK: true,
+		},
+		{
+			name:   "digitized date",
+			exif:   map[uint16]string{tagDateTimeDigitized: "2022:12:31 23:59:59"},
+			want:   time.Date(2022, 12, 31, 23, 59, 59, 0, time.UTC),
+			wantOK: true,
+		},
+		{
+			name:   "modification date",
+			ifd0:   map[uint16]string{tagDateTime: "2023:05:06 07:08:09"},
+			want:   time.Date(2023, 5, 6, 7, 8, 9, 0, time.UTC),
+			wantOK: true,
+		},
+		{
+			name:   "invalid date",
+			ifd0:   map[uint16]string{tagDateTime: "0000:00:00 00:00:00"},
+			wantOK: false,
+		},
+		{
+			name:   "no date",
+			wantOK: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			dir := newTIFFDir(binary.LittleEndian)
+			dir.setUints(tagOrientation, tiffShort, uint32(OrientationRotate90))
+			for tag, s := range tc.ifd0 {
+				dir.setASCII(tag, s)
+			}
+			if tc.exif != nil {
+				sub := newTIFFDir(binary.LittleEndian)
+				for tag, s := range tc.exif {
+					sub.setASCII(tag, s)
+				}
+				dir.subs[tagExifIFD] = sub
+			}
+
+			meta, err := DecodeMetadata(bytes.NewReader(makeEXIFJPEG(t, dir)))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if meta.EXIF == nil {
+				t.Fatalf("EXIF data not found")
+			}
+			got, ok := meta.EXIF.DateTime()
+			if ok != tc.wantOK || !got.Equal(tc.want) {
+				t.Fatalf("got %v, %v want %v, %v", got, ok, tc.want, tc.wantOK)
+			}
+			if o := meta.EXIF.Orientation(); o != OrientationRotate90 {
+				t.Fatalf("got orientation %v want %v", o, OrientationRotate90)
+			}
+		})
+	}
+}
+
+func TestReadJPEGEXIF(t *testing.T) {
+	for _, name := range []string{"testdata/branches.jpg", "testdata/orientation_0.jpg"} {
+		meta, err := OpenMetadata(name)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if meta.EXIF != nil {
+			t.Fatalf("%s: got EXIF data for an image without it", name)
+		}
+	}
+	meta, err := OpenMetadata("testdata/orientation_6.jpg")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if meta.EXIF == nil || meta.EXIF.Orientation() != OrientationRotate270 {
+		t.Fatalf("got EXIF %+v want orientation %v", meta.EXIF, OrientationRotate270)
+	}
+
+	for _, data := range [][]byte{
+		nil,
+		{0xff, 0xd8},
+		{0xff, 0xd8, 0x00, 0x00, 0x00, 0x00},
+		{0xff, 0xd8, 0xff, 0xe1, 0xff, 0xff, 'E', 'x'},
+		{0xff, 0xd8, 0xff, 0xda, 0x00, 0x02},
+	} {
+		if exif := readJPEGEXIF(data); exif != nil {
+			t.Fatalf("%v: got %v want nil", data, exif)
+		}
+	}
+}
+
+func TestEXIFEdit(t *testing.T) {
+	t.Parallel()
+
+	meta, err := DecodeMetadata(bytes.NewReader(makeEXIFJPEG(t, makeTestEXIF(OrientationRotate90))))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	date := time.Date(2023, 5, 6, 7, 8, 9, 0, time.FixedZone("", 2*60*60))
+	exif := meta.EXIF.
+		SetArtist("Jane Doe").
+		SetCopyright("").
+		SetDateTime(date).
+		SetOrientation(OrientationNormal).
+		StripGPS().
+		RemoveTag(271)
+
+	if meta.EXIF.Artist() != "" || meta.EXIF.Copyright() != "Copyright Holder" || meta.EXIF.dir.subs[tagGPSIFD] == nil {
+		t.Fatalf("the original EXIF data was modified")
+	}
+
+	for _, f := range []Format{JPEG, TIFF} {
+		buf := &bytes.Buffer{}
+		if err := Encode(buf, makeNoiseNRGBA(8, 8, 1), f, WithMetadata(meta), WithEXIF(exif)); err != nil {
+			t.Fatalf("%s: failed to encode: %v", f, err)
+		}
+		got, err := DecodeMetadata(buf)
+		if err != nil {
+			t.Fatalf("%s: failed to decode metadata: %v", f, err)
+		}
+		if got.EXIF == nil {
+			t.Fatalf("%s: EXIF data not written", f)
+		}
+		if s := got.EXIF.Artist(); s != "Jane Doe" {
+			t.Fatalf("%s: got artist %q", f, s)
+		}
+		if s := got.EXIF.Copyright(); s != "" {
+			t.Fatalf("%s: got copyright %q", f, s)
+		}
+		if s := got.EXIF.dir.ascii(271); s != "" {
+			t.Fatalf("%s: got make %q", f, s)
+		}
+		if d, ok := got.EXIF.DateTime(); !ok || !d.Equal(date) {
+			t.Fatalf("%s: got date %v, %v want %v", f, d, ok, date)
+		}
+		if o := got.EXIF.Orientation(); o != OrientationNormal {
+			t.Fatalf("%s: got orientation %v", f, o)
+		}
+		if got.EXIF.dir.subs[tagGPSIFD] != nil || got.EXIF.dir.field(tagGPSIFD) != nil {
+			t.Fatalf("%s: GPS data not removed", f)
+		}
+	}
+}
+
+func TestWithEXIF(t *testing.T) {
+	t.Parallel()
+
+	t.Run("new EXIF", func(t *testing.T) {
+		var e *EXIF
+		buf := &bytes.Buffer{}
+		if err := Encode(buf, makeNoiseNRGBA(8, 8, 1), JPEG, WithEXIF(e.SetCopyright("(c) Example"))); err != nil {
+			t.Fatalf("failed to encode: %v", err)
+		}
+		got, err := DecodeMetadata(buf)
+		if err != nil {
+			t.Fatalf("failed to decode metadata: %v", err)
+		}
+		if got.EXIF == nil || got.EXIF.Copyright() != "(c) Example" {
+			t.Fatalf("got EXIF %+v", got.EXIF)
+		}
+	})
+
+	t.Run("empty EXIF", func(t *testing.T) {
+		src := makeEXIFJPEG(t, makeTestEXIF(OrientationNormal))
+		img, meta, err := DecodeWithMetadata(bytes.NewReader(src))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, f := range []Format{JPEG, TIFF} {
+			buf := &bytes.Buffer{}
+			if err := Encode(buf, img, f, WithEXIF(NewEXIF()), WithMetadata(meta)); err != nil {
+				t.Fatalf("%s: failed to encode: %v", f, err)
+			}
+			got, err := DecodeMetadata(buf)
+			if err != nil {
+				t.Fatalf("%s: failed to decode metadata: %v", f, err)
+			}
+			// The TIFF encoder writes the resolution tags on its own.
+			if got.EXIF != nil && (got.EXIF.Copyright() != "" || got.EXIF.dir.subs[tagExifIFD] != nil || got.EXIF.dir.subs[tagGPSIFD] != nil) {
+				t.Fatalf("%s: got EXIF %+v want none", f, got.EXIF)
+			}
+		}
+	})
+}
+
+func TestWithAuthorship(t *testing.T) {
+	t.Parallel()
+
+	src := makeEXIFJPEG(t, makeTestEXIF(OrientationNormal))
+	img, meta, err := DecodeWithMetadata(bytes.NewReader(src))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	testCases := []struct {
+		name          string
+		opts          []EncodeOption
+		artist, right string
+	}{
+		{"no metadata", []EncodeOption{WithAuthorship("Jane Doe", "(c) Studio")}, "Jane Doe", "(c) Studio"},
+		{"metadata", []EncodeOption{WithAuthorship("Jane Doe", ""), WithMetadata(meta)}, "Jane Doe", meta.EXIF.Copyright()},
+		{"EXIF", []EncodeOption{WithEXIF(NewEXIF().SetCopyright("(c) Old")), WithAuthorship("", "(c) New")}, "", "(c) New"},
+	}
+	for _, tc := range testCases {
+		for _, f := range []Format{JPEG, TIFF} {
+			buf := &bytes.Buffer{}
+			if err := Encode(buf, img, f, tc.opts...); err != nil {
+				t.Fatalf("%s %s: failed to encode: %v", tc.name, f, err)
+			}
+			got, err := DecodeMetadata(buf)
+			if err != nil {
+				t.Fatalf("%s %s: failed to decode metadata: %v", tc.name, f, err)
+			}
+			if got.EXIF == nil || got.EXIF.Artist() != tc.artist || got.EXIF.Copyright() != tc.right {
+				t.Fatalf("%s %s: got EXIF %+v", tc.name, f, got.EXIF)
+			}
+		}
+	}
+	if meta.EXIF.Artist() == "Jane Doe" {
+		t.Fatalf("the EXIF data of the metadata changed")
+	}
+}
@@ -0,0 +1,276 @@
+package imaging
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// GeometryFlag modifies how a Geometry size is applied to an image.
+type GeometryFlag int
+
+// Geometry flags (the suffixes of the ImageMagick geometry syntax).
+const (
+	// GeometryFit fits the image into the given size preserving the aspect ratio (no suffix).
+	GeometryFit GeometryFlag = iota
+	// GeometryExact ignores the aspect ratio and uses the exact given size ("!" suffix).
+	GeometryExact
+	// GeometryFill fills the given size preserving the aspect ratio, so the result
+	// may be larger than the given size ("^" suffix).
+	GeometryFill
+	// GeometryShrink only shrinks images larger than the given size (">" suffix).
+	GeometryShrink
+	// GeometryEnlarge only enlarges images smaller than the given size ("<" suffix).
+	GeometryEnlarge
+	// GeometryArea resizes the image to have at most the given number of pixels ("@" suffix).
+	GeometryArea
+)
+
+// Geometry is an image geometry specification in the ImageMagick syntax,
+// e.g. "800x600", "800x600^", "50%x50%", "100x100+10+20" or "+10+20".
+type Geometry struct {
+	// Width and Height are the requested dimensions. Zero means the dimension is not specified.
+	// If Flag is GeometryArea, Width holds the maximum number of pixels.
+	Width, Height float64
+	// Percent reports whether Width and Height are percentages of the image size.
+	Percent bool
+	// X and Y are the offsets, used by the cropping and padding functions.
+	X, Y int
+	// Flag modifies how the size is applied to an image.
+	Flag GeometryFlag
+}
+
+// ErrInvalidGeometry means the geometry string can not be parsed.
+var ErrInvalidGeometry = errors.New("imaging: invalid geometry")
+
+// geometryRegexp matches the geometry syntax: size, flags and offsets.
+var geometryRegexp = regexp.MustCompile(`^(?:(\d+(?:\.\d+)?)?(%)?(?:x(\d+(?:\.\d+)?)?(%)?)?)?([!^<>@%]*)?([+-]\d+)?([+-]\d+)?$`) //nolint
+
+// ParseGeometry parses a geometry string in the ImageMagick syntax.
+//
+// Examples:
+//
+//	g, err := imaging.ParseGeometry("800x600^")  // Fill 800x600 preserving the aspect ratio.
+//	g, err := imaging.ParseGeometry("50%")       // Scale both dimensions by 50%.
+//	g, err := imaging.ParseGeometry("200x100+10+20") // A 200x100 region at offset (10, 20).
+func ParseGeometry(s string) (Geometry, error) {
+	var g Geometry
+	m := geometryRegexp.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil || strings.TrimSpace(s) == "" {
+		return g, fmt.Errorf("%w: %q", ErrInvalidGeometry, s)
+	}
+
+	var err error
+	if m[1] != "" {
+		if g.Width, err = strconv.ParseFloat(m[1], 64); err != nil {
+			return g, fmt.Errorf("%w: %q", ErrInvalidGeometry, s)
+		}
+	}
+	if m[3] != "" {
+		if g.Height, err = strconv.ParseFloat(m[3], 64); err != nil {
+			return g, fmt.Errorf("%w: %q", ErrInvalidGeometry, s)
+		}
+	}
+	g.Percent = m[2] != "" || m[4] != ""
+
+	flags := 0
+	for _, c := range m[5] {
+		switch c {
+		case '%':
+			g.Percent = true
+			continue
+		case '!':
+			g.Flag = GeometryExact
+		case '^':
+			g.Flag = GeometryFill
+		case '>':
+			g.Flag = GeometryShrink
+		case '<':
+			g.Flag = GeometryEnlarge
+		case '@':
+			g.Flag = GeometryArea
+		}
+		flags++
+	}
+	if flags > 1 {
+		return g, fmt.Errorf("%w: %q: conflicting flags", ErrInvalidGeometry, s)
+	}
+	if g.Percent && m[1] != "" && m[3] == "" && !strings.Contains(s, "x") {
+		// "50%" scales both dimensions.
+		g.Height = g.Width
+	}
+
+	if m[6] != "" {
+		x, _ := strconv.Atoi(m[6]) //nolint
+		g.X = x
+	}
+	if m[7] != "" {
+		y, _ := strconv.Atoi(m[7]) //nolint
+		g.Y = y
+	}
+	return g, nil
+}
+
+// String returns the geometry in the ImageMagick syntax.
+func (g Geometry) String() string {
+	var sb strings.Builder
+	num := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	if g.Width != 0 {
+		sb.WriteString(num(g.Width))
+	}
+	if g.Height != 0 && (g.Height != g.Width || !g.Percent) {
+		sb.WriteString("x" + num(g.Height))
+	}
+	if g.Percent {
+		sb.WriteString("%")
+	}
+	switch g.Flag {
+	case GeometryFit:
+	case GeometryExact:
+		sb.WriteString("!")
+	case GeometryFill:
+		sb.WriteString("^")
+	case GeometryShrink:
+		sb.WriteString(">")
+	case GeometryEnlarge:
+		sb.WriteString("<")
+	case GeometryArea:
+		sb.WriteString("@")
+	}
+	if g.X != 0 || g.Y != 0 {
+		sb.WriteString(fmt.Sprintf("%+d%+d", g.X, g.Y))
+	}
+	return sb.String()
+}
+
+// dimensions returns the requested width and height in pixels for the image
+// of the given size. Unspecified dimensions are returned as 0.
+func (g Geometry) dimensions(srcW, srcH int) (float64, float64) {
+	w, h := g.Width, g.Height
+	if g.Percent {
+		w = w * float64(srcW) / 100
+		h = h * float64(srcH) / 100
+	}
+	return w, h
+}
+
+// Size returns the dimensions of the image of the given size resized according to the geometry.
+func (g Geometry) Size(srcW, srcH int) (int, int) {
+	if srcW <= 0 || srcH <= 0 {
+		return 0, 0
+	}
+	w, h := g.dimensions(srcW, srcH)
+	sw, sh := float64(srcW), float64(srcH)
+
+	if g.Flag == GeometryArea {
+		if g.Width <= 0 || g.Width >= sw*sh {
+			return srcW, srcH
+		}
+		scale := math.Sqrt(g.Width / (sw * sh))
+		return geometryRound(sw * scale), geometryRound(sh * scale)
+	}
+	if w <= 0 && h <= 0 {
+		return srcW, srcH
+	}
+	if g.Percent || g.Flag == GeometryExact {
+		if w <= 0 {
+			w = sw * h / sh
+		}
+		if h <= 0 {
+			h = sh * w / sw
+		}
+		return geometryRound(w), geometryRound(h)
+	}
+
+	scaleW, scaleH := w/sw, h/sh
+	var scale float64
+	switch {
+	case w <= 0:
+		scale = scaleH
+	case h <= 0:
+		scale = scaleW
+	case g.Flag == GeometryFill:
+		scale = math.Max(scaleW, scaleH)
+	default:
+		scale = math.Min(scaleW, scaleH)
+	}
+	if (g.Flag == GeometryShrink && scale >= 1) || (g.Flag == GeometryEnlarge && scale <= 1) {
+		return srcW, srcH
+	}
+	return geometryRound(sw * scale), geometryRound(sh * scale)
+}
+
+// geometryRound rounds the dimension to the nearest integer, minimum 1px.
+func geometryRound(v float64) int {
+	return int(math.Max(1, math.Floor(v+0.5)))
+}
+
+// Rect returns the region described by the geometry within the image bounds b,
+// positioned relative to the anchor point. The offsets move the region inwards
+// from the anchor edges. Unspecified dimensions default to the image size.
+func (g Geometry) Rect(b image.Rectangle, anchor Anchor) image.Rectangle {
+	fw, fh := g.dimensions(b.Dx(), b.Dy())
+	w, h := b.Dx(), b.Dy()
+	if fw > 0 {
+		w = geometryRound(fw)
+	}
+	if fh > 0 {
+		h = geometryRound(fh)
+	}
+
+	pt := anchorPt(b, w, h, anchor)
+	dx, dy := g.X, g.Y
+	switch anchor {
+	case TopRight, Right, BottomRight:
+		dx = -dx
+	case Center, TopLeft, Top, Left, BottomLeft, Bottom:
+	}
+	switch anchor {
+	case BottomLeft, Bottom, BottomRight:
+		dy = -dy
+	case Center, TopLeft, Top, TopRight, Left, Right:
+	}
+	return image.Rect(0, 0, w, h).Add(pt).Add(image.Pt(dx, dy))
+}
+
+// ResizeGeometry resizes the image according to the geometry using the specified
+// resampling filter and returns the transformed image. The offsets are ignored.
+//
+// Example:
+//
+//	g, _ := imaging.ParseGeometry("800x600>")
+//	dstImage := imaging.ResizeGeometry(srcImage, g, imaging.Lanczos)
+func ResizeGeometry(img image.Image, g Geometry, filter ResampleFilter) *image.NRGBA {
+	w, h := g.Size(img.Bounds().Dx(), img.Bounds().Dy())
+	return Resize(img, w, h, filter)
+}
+
+// CropGeometry cuts out the region described by the geometry, positioned
+// relative to the anchor point (gravity), and returns the cropped image.
+//
+// Example:
+//
+//	g, _ := imaging.ParseGeometry("200x100+10+10")
+//	dstImage := imaging.CropGeometry(srcImage, g, imaging.BottomRight)
+func CropGeometry(img image.Image, g Geometry, anchor Anchor) *image.NRGBA {
+	return Crop(img, g.Rect(img.Bounds(), anchor))
+}
+
+// ExtentGeometry places the image on a canvas with the size described by the
+// geometry, positioned relative to the anchor point (gravity), and returns the
+// combined image. The canvas is filled with bgColor, which may be AutoColor.
+// It is the equivalent of the ImageMagick -extent operation, so it pads or crops
+// the image depending on the canvas size.
+func ExtentGeometry(img image.Image, g Geometry, anchor Anchor, bgColor color.Color) *image.NRGBA {
+	r := g.Rect(img.Bounds(), anchor)
+	if r.Empty() {
+		return &image.NRGBA{}
+	}
+	dst := New(r.Dx(), r.Dy(), resolveColor(img, bgColor))
+	return Overlay(dst, img, img.Bounds().Min.Sub(r.Min), 1)
+}
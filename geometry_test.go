@@ -0,0 +1,150 @@
+package imaging
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestParseGeometry(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		in   string
+		want Geometry
+		str  string
+	}{
+		{"800x600", Geometry{Width: 800, Height: 600}, "800x600"},
+		{"800", Geometry{Width: 800}, "800"},
+		{"x600", Geometry{Height: 600}, "x600"},
+		{"800x600^", Geometry{Width: 800, Height: 600, Flag: GeometryFill}, "800x600^"},
+		{"800x600!", Geometry{Width: 800, Height: 600, Flag: GeometryExact}, "800x600!"},
+		{"800x600>", Geometry{Width: 800, Height: 600, Flag: GeometryShrink}, "800x600>"},
+		{"800x600<", Geometry{Width: 800, Height: 600, Flag: GeometryEnlarge}, "800x600<"},
+		{"10000@", Geometry{Width: 10000, Flag: GeometryArea}, "10000@"},
+		{"50%", Geometry{Width: 50, Height: 50, Percent: true}, "50%"},
+		{"50%x25%", Geometry{Width: 50, Height: 25, Percent: true}, "50x25%"},
+		{"50x25%", Geometry{Width: 50, Height: 25, Percent: true}, "50x25%"},
+		{"+10+20", Geometry{X: 10, Y: 20}, "+10+20"},
+		{"100x50-10+5", Geometry{Width: 100, Height: 50, X: -10, Y: 5}, "100x50-10+5"},
+		{"12.5%", Geometry{Width: 12.5, Height: 12.5, Percent: true}, "12.5%"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.in, func(t *testing.T) {
+			t.Parallel()
+			got, err := ParseGeometry(tc.in)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("got %+v want %+v", got, tc.want)
+			}
+			if got.String() != tc.str {
+				t.Fatalf("got string %q want %q", got.String(), tc.str)
+			}
+		})
+	}
+
+	for _, in := range []string{"", "abc", "800y600", "800x600^!", "10+x"} {
+		if _, err := ParseGeometry(in); !errors.Is(err, ErrInvalidGeometry) {
+			t.Fatalf("%q: got error %v want ErrInvalidGeometry", in, err)
+		}
+	}
+}
+
+func TestGeometrySize(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		geometry   string
+		srcW, srcH int
+		w, h       int
+	}{
+		{"800x600", 1600, 800, 800, 400},
+		{"800x600", 400, 200, 800, 400},
+		{"800x600^", 1600, 800, 1200, 600},
+		{"800x600!", 1600, 800, 800, 600},
+		{"800x600>", 400, 200, 400, 200},
+		{"800x600>", 1600, 800, 800, 400},
+		{"800x600<", 1600, 800, 1600, 800},
+		{"800x600<", 400, 200, 800, 400},
+		{"800", 1600, 800, 800, 400},
+		{"x100", 1600, 800, 200, 100},
+		{"50%", 1600, 800, 800, 400},
+		{"50%x25%", 1600, 800, 800, 200},
+		{"20000@", 400, 200, 200, 100},
+		{"+10+10", 400, 200, 400, 200},
+		{"800x600", 0, 0, 0, 0},
+	}
+
+	for _, tc := range testCases {
+		g, err := ParseGeometry(tc.geometry)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", tc.geometry, err)
+		}
+		w, h := g.Size(tc.srcW, tc.srcH)
+		if w != tc.w || h != tc.h {
+			t.Fatalf("%q on %dx%d: got %dx%d want %dx%d", tc.geometry, tc.srcW, tc.srcH, w, h, tc.w, tc.h)
+		}
+	}
+}
+
+func TestGeometryRect(t *testing.T) {
+	t.Parallel()
+
+	b := image.Rect(0, 0, 100, 50)
+	testCases := []struct {
+		geometry string
+		anchor   Anchor
+		want     image.Rectangle
+	}{
+		{"10x20+5+6", TopLeft, image.Rect(5, 6, 15, 26)},
+		{"10x20+5+6", BottomRight, image.Rect(85, 24, 95, 44)},
+		{"10x20", Center, image.Rect(45, 15, 55, 35)},
+		{"50%x50%", Center, image.Rect(25, 12, 75, 37)},
+		{"+10+0", TopLeft, image.Rect(10, 0, 110, 50)},
+	}
+
+	for _, tc := range testCases {
+		g, err := ParseGeometry(tc.geometry)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", tc.geometry, err)
+		}
+		if got := g.Rect(b, tc.anchor); got != tc.want {
+			t.Fatalf("%q: got %v want %v", tc.geometry, got, tc.want)
+		}
+	}
+}
+
+func TestGeometryOperations(t *testing.T) {
+	t.Parallel()
+
+	src := New(40, 20, color.NRGBA{0xff, 0, 0, 0xff})
+
+	g, _ := ParseGeometry("10x10^") //nolint
+	if got := ResizeGeometry(src, g, Linear); got.Bounds() != image.Rect(0, 0, 20, 10) {
+		t.Fatalf("ResizeGeometry: got bounds %v", got.Bounds())
+	}
+
+	g, _ = ParseGeometry("10x5+1+1") //nolint
+	if got := CropGeometry(src, g, TopLeft); got.Bounds() != image.Rect(0, 0, 10, 5) {
+		t.Fatalf("CropGeometry: got bounds %v", got.Bounds())
+	}
+
+	g, _ = ParseGeometry("60x30") //nolint
+	bg := color.NRGBA{0, 0, 0xff, 0xff}
+	got := ExtentGeometry(src, g, Center, bg)
+	if got.Bounds() != image.Rect(0, 0, 60, 30) {
+		t.Fatalf("ExtentGeometry: got bounds %v", got.Bounds())
+	}
+	if c := got.NRGBAAt(0, 0); c != bg {
+		t.Fatalf("ExtentGeometry: got corner color %v want %v", c, bg)
+	}
+	if c := got.NRGBAAt(30, 15); c != src.NRGBAAt(0, 0) {
+		t.Fatalf("ExtentGeometry: got center color %v want %v", c, src.NRGBAAt(0, 0))
+	}
+}
@@ -0,0 +1,157 @@
+package imaging
+
+import "image"
+
+// GeoTIFF holds the GeoTIFF tags that georeference a TIFF image.
+// Nil slices and empty strings mean the tag is absent.
+type GeoTIFF struct {
+	// ModelPixelScale is the size of a pixel in model space (ScaleX, ScaleY, ScaleZ).
+	ModelPixelScale []float64
+	// ModelTiepoint holds the raster to model tie points, six values (I, J, K, X, Y, Z) each.
+	ModelTiepoint []float64
+	// ModelTransformation is the row-major 4x4 raster to model transformation matrix.
+	ModelTransformation []float64
+	// GeoKeyDirectory is the GeoKey directory describing the coordinate reference system.
+	GeoKeyDirectory []uint16
+	// GeoDoubleParams holds the double values referenced by the GeoKey directory.
+	GeoDoubleParams []float64
+	// GeoASCIIParams holds the ASCII values referenced by the GeoKey directory.
+	GeoASCIIParams string
+	// GDALMetadata is the GDAL metadata XML (GDAL_METADATA tag).
+	GDALMetadata string
+	// GDALNoData is the no-data value of the pixels (GDAL_NODATA tag).
+	GDALNoData string
+}
+
+// readGeoTIFF returns the GeoTIFF tags of the directory or nil if there are none.
+func readGeoTIFF(d *tiffDir) *GeoTIFF {
+	g := &GeoTIFF{
+		ModelPixelScale:     d.floats(tagModelPixelScale),
+		ModelTiepoint:       d.floats(tagModelTiepoint),
+		ModelTransformation: d.floats(tagModelTransformation),
+		GeoDoubleParams:     d.floats(tagGeoDoubleParams),
+		GeoASCIIParams:      d.ascii(tagGeoASCIIParams),
+		GDALMetadata:        d.ascii(tagGDALMetadata),
+		GDALNoData:          d.ascii(tagGDALNoData),
+	}
+	for _, v := range d.uints(tagGeoKeyDirectory) {
+		g.GeoKeyDirectory = append(g.GeoKeyDirectory, uint16(v))
+	}
+	if g.ModelPixelScale == nil && g.ModelTiepoint == nil && g.ModelTransformation == nil &&
+		g.GeoKeyDirectory == nil && g.GeoDoubleParams == nil && g.GeoASCIIParams == "" &&
+		g.GDALMetadata == "" && g.GDALNoData == "" {
+		return nil
+	}
+	return g
+}
+
+// writeTo sets the GeoTIFF tags of the directory.
+func (g *GeoTIFF) writeTo(d *tiffDir) {
+	if g.ModelPixelScale != nil {
+		d.setFloats(tagModelPixelScale, g.ModelPixelScale...)
+	}
+	if g.ModelTiepoint != nil {
+		d.setFloats(tagModelTiepoint, g.ModelTiepoint...)
+	}
+	if g.ModelTransformation != nil {
+		d.setFloats(tagModelTransformation, g.ModelTransformation...)
+	}
+	if g.GeoKeyDirectory != nil {
+		keys := make([]uint32, len(g.GeoKeyDirectory))
+		for i, v := range g.GeoKeyDirectory {
+			keys[i] = uint32(v)
+		}
+		d.setUints(tagGeoKeyDirectory, tiffShort, keys...)
+	}
+	if g.GeoDoubleParams != nil {
+		d.setFloats(tagGeoDoubleParams, g.GeoDoubleParams...)
+	}
+	for tag, s := range map[uint16]string{
+		tagGeoASCIIParams: g.GeoASCIIParams,
+		tagGDALMetadata:   g.GDALMetadata,
+		tagGDALNoData:     g.GDALNoData,
+	} {
+		if s != "" {
+			d.setASCII(tag, s)
+		}
+	}
+}
+
+// clone returns a deep copy of the GeoTIFF tags, nil for nil tags.
+func (g *GeoTIFF) clone() *GeoTIFF {
+	if g == nil {
+		return nil
+	}
+	c := *g
+	c.ModelPixelScale = copyFloats(g.ModelPixelScale)
+	c.ModelTiepoint = copyFloats(g.ModelTiepoint)
+	c.ModelTransformation = copyFloats(g.ModelTransformation)
+	c.GeoDoubleParams = copyFloats(g.GeoDoubleParams)
+	if g.GeoKeyDirectory != nil {
+		c.GeoKeyDirectory = append([]uint16{}, g.GeoKeyDirectory...)
+	}
+	return &c
+}
+
+// copyFloats returns a copy of the slice, keeping nil slices nil.
+func copyFloats(s []float64) []float64 {
+	if s == nil {
+		return nil
+	}
+	return append([]float64{}, s...)
+}
+
+// Crop returns a copy of the GeoTIFF tags adjusted for the image cropped to
+// the rectangle r, given in pixel coordinates of the source image (e.g. the
+// rectangle passed to Crop). It can be called on a nil *GeoTIFF, which is
+// returned unchanged, so the images without GeoTIFF tags are handled as well.
+//
+// Example:
+//
+//	rect := image.Rect(100, 100, 600, 400)
+//	dstImage := imaging.Crop(srcImage, rect)
+//	meta.GeoTIFF = meta.GeoTIFF.Crop(rect)
+//	err := imaging.Save(dstImage, "out.tif", imaging.WithMetadata(meta))
+func (g *GeoTIFF) Crop(r image.Rectangle) *GeoTIFF {
+	c := g.clone()
+	if c == nil {
+		return nil
+	}
+	x0, y0 := float64(r.Min.X), float64(r.Min.Y)
+	for i := 0; i+5 < len(c.ModelTiepoint); i += 6 {
+		c.ModelTiepoint[i] -= x0
+		c.ModelTiepoint[i+1] -= y0
+	}
+	if m := c.ModelTransformation; len(m) == 16 {
+		for row := 0; row < 3; row++ {
+			m[4*row+3] += m[4*row]*x0 + m[4*row+1]*y0
+		}
+	}
+	return c
+}
+
+// Resize returns a copy of the GeoTIFF tags adjusted for the image resized
+// from srcW x srcH to dstW x dstH pixels. Like Crop, it returns nil for a nil
+// *GeoTIFF.
+func (g *GeoTIFF) Resize(srcW, srcH, dstW, dstH int) *GeoTIFF {
+	c := g.clone()
+	if c == nil || srcW <= 0 || srcH <= 0 || dstW <= 0 || dstH <= 0 {
+		return c
+	}
+	sx, sy := float64(srcW)/float64(dstW), float64(srcH)/float64(dstH)
+	if len(c.ModelPixelScale) >= 2 {
+		c.ModelPixelScale[0] *= sx
+		c.ModelPixelScale[1] *= sy
+	}
+	for i := 0; i+5 < len(c.ModelTiepoint); i += 6 {
+		c.ModelTiepoint[i] /= sx
+		c.ModelTiepoint[i+1] /= sy
+	}
+	if m := c.ModelTransformation; len(m) == 16 {
+		for row := 0; row < 3; row++ {
+			m[4*row] *= sx
+			m[4*row+1] *= sy
+		}
+	}
+	return c
+}
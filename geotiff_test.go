@@ -0,0 +1,137 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"reflect"
+	"testing"
+)
+
+func makeGeoTIFF() *GeoTIFF {
+	return &GeoTIFF{
+		ModelPixelScale: []float64{0.5, 0.25, 0},
+		ModelTiepoint:   []float64{0, 0, 0, 440720, 3751320, 0},
+		GeoKeyDirectory: []uint16{1, 1, 0, 2, 1024, 0, 1, 1, 3072, 0, 1, 26711},
+		GeoASCIIParams:  "NAD27 / UTM zone 11N|",
+		GDALNoData:      "-9999",
+	}
+}
+
+func TestGeoTIFFRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	geo := makeGeoTIFF()
+	geo.ModelTransformation = []float64{2, 0, 0, 10, 0, -2, 0, 20, 0, 0, 0, 0, 0, 0, 0, 1}
+	img := makeNoiseNRGBA(16, 8, 1)
+
+	for _, opts := range [][]EncodeOption{
+		{WithMetadata(&Metadata{GeoTIFF: geo})},
+		{WithMetadata(&Metadata{GeoTIFF: geo}), TIFFPredictor(true)},
+		{WithMetadata(&Metadata{GeoTIFF: geo}), TIFFBigTIFF(true)},
+	} {
+		buf := &bytes.Buffer{}
+		if err := Encode(buf, img, TIFF, opts...); err != nil {
+			t.Fatalf("failed to encode: %v", err)
+		}
+		meta, err := DecodeMetadata(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("failed to decode metadata: %v", err)
+		}
+		if !reflect.DeepEqual(meta.GeoTIFF, geo) {
+			t.Fatalf("got %+v want %+v", meta.GeoTIFF, geo)
+		}
+		decoded, err := Decode(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("failed to decode: %v", err)
+		}
+		if !compareNRGBA(Clone(decoded), img, 0) {
+			t.Fatalf("decoded image differs")
+		}
+	}
+
+	// The tags survive a byte order change.
+	buf := &bytes.Buffer{}
+	if err := Encode(buf, img, TIFF, WithMetadata(&Metadata{GeoTIFF: geo})); err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	dirs, err := parseTIFF(buf.Bytes())
+	if err != nil {
+		t.Fatalf("failed to parse TIFF: %v", err)
+	}
+	out := &bytes.Buffer{}
+	if err := writeTIFF(out, binary.BigEndian, dirs); err != nil {
+		t.Fatalf("failed to write TIFF: %v", err)
+	}
+	meta, err := DecodeMetadata(out)
+	if err != nil {
+		t.Fatalf("failed to decode metadata: %v", err)
+	}
+	if !reflect.DeepEqual(meta.GeoTIFF, geo) {
+		t.Fatalf("big-endian: got %+v want %+v", meta.GeoTIFF, geo)
+	}
+}
+
+func TestGeoTIFFCrop(t *testing.T) {
+	t.Parallel()
+
+	geo := makeGeoTIFF()
+	geo.ModelTransformation = []float64{2, 0.5, 0, 10, 0.25, -2, 0, 20, 0, 0, 1, 5, 0, 0, 0, 1}
+	got := geo.Crop(image.Rect(10, 4, 20, 8))
+
+	if want := []float64{-10, -4, 0, 440720, 3751320, 0}; !reflect.DeepEqual(got.ModelTiepoint, want) {
+		t.Fatalf("got tie point %v want %v", got.ModelTiepoint, want)
+	}
+	m := got.ModelTransformation
+	if m[3] != 10+2*10+0.5*4 || m[7] != 20+0.25*10-2*4 || m[11] != 5 {
+		t.Fatalf("got transformation %v", m)
+	}
+	if geo.ModelTiepoint[0] != 0 || geo.ModelTransformation[3] != 10 {
+		t.Fatalf("source tags were modified")
+	}
+	if !reflect.DeepEqual(got.ModelPixelScale, geo.ModelPixelScale) {
+		t.Fatalf("pixel scale must not change")
+	}
+}
+
+func TestGeoTIFFResize(t *testing.T) {
+	t.Parallel()
+
+	geo := makeGeoTIFF()
+	geo.ModelTiepoint = []float64{8, 4, 0, 100, 200, 0}
+	geo.ModelTransformation = []float64{2, 0, 0, 10, 0, -2, 0, 20, 0, 0, 0, 0, 0, 0, 0, 1}
+	got := geo.Resize(100, 50, 50, 100)
+
+	if want := []float64{1, 0.125, 0}; !reflect.DeepEqual(got.ModelPixelScale, want) {
+		t.Fatalf("got pixel scale %v want %v", got.ModelPixelScale, want)
+	}
+	if want := []float64{4, 8, 0, 100, 200, 0}; !reflect.DeepEqual(got.ModelTiepoint, want) {
+		t.Fatalf("got tie point %v want %v", got.ModelTiepoint, want)
+	}
+	if m := got.ModelTransformation; m[0] != 4 || m[5] != -1 || m[3] != 10 || m[7] != 20 {
+		t.Fatalf("got transformation %v", m)
+	}
+	if same := geo.Resize(0, 0, 10, 10); !reflect.DeepEqual(same, geo) {
+		t.Fatalf("invalid sizes must return unchanged tags")
+	}
+}
+
+func TestGeoTIFFNil(t *testing.T) {
+	t.Parallel()
+
+	var geo *GeoTIFF
+	if got := geo.Crop(image.Rect(10, 10, 20, 20)); got != nil {
+		t.Fatalf("got cropped tags %+v want nil", got)
+	}
+	if got := geo.Resize(100, 50, 50, 25); got != nil {
+		t.Fatalf("got resized tags %+v want nil", got)
+	}
+	if _, ok := geo.GeoTransform(); ok {
+		t.Fatalf("got a transformation of nil tags")
+	}
+	tr := GeoTransform{A: 2, C: 100, E: -2, F: 200}
+	got, ok := geo.SetGeoTransform(tr).GeoTransform()
+	if !ok || got != tr {
+		t.Fatalf("got transformation %+v, %v want %+v", got, ok, tr)
+	}
+}
@@ -0,0 +1,225 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"io"
+	"time"
+)
+
+// ErrNoFrames means the animation has no frames.
+var ErrNoFrames = errors.New("imaging: animation has no frames")
+
+// errInvalidGIF means the GIF structure is malformed.
+var errInvalidGIF = errors.New("imaging: invalid GIF structure")
+
+// encodeGIF writes the animation to w in GIF format using the GIF options from the config.
+func encodeGIF(w io.Writer, anim *Animation, cfg *encodeConfig) error {
+	if len(anim.Frames) == 0 {
+		return ErrNoFrames
+	}
+
+	width, height := anim.Width, anim.Height
+	var offset image.Point
+	if width == 0 && height == 0 {
+		b := anim.Frames[0].Image.Bounds()
+		width, height, offset = b.Dx(), b.Dy(), b.Min
+	}
+
+	g := &gif.GIF{
+		Image:     make([]*image.Paletted, len(anim.Frames)),
+		Delay:     make([]int, len(anim.Frames)),
+		Disposal:  make([]byte, len(anim.Frames)),
+		LoopCount: anim.LoopCount,
+		Config:    image.Config{Width: width, Height: height},
+	}
+	for i, f := range anim.Frames {
+		transparent := f.Transparent
+		if transparent == nil {
+			transparent = cfg.gifTransparentColor
+		}
+		pm := gifPaletted(f.Image, cfg, transparent)
+		if offset != (image.Point{}) {
+			dup := *pm
+			dup.Rect = dup.Rect.Sub(offset)
+			pm = &dup
+		}
+		if cfg.gifInterlaced {
+			pm = gifInterlace(pm)
+		}
+		g.Image[i] = pm
+		g.Delay[i] = int((f.Delay + 5*time.Millisecond) / (10 * time.Millisecond))
+		g.Disposal[i] = byte(f.Disposal)
+	}
+
+	if !cfg.gifInterlaced {
+		return gif.EncodeAll(w, g)
+	}
+	buf := &bytes.Buffer{}
+	if err := gif.EncodeAll(buf, g); err != nil {
+		return err
+	}
+	data := buf.Bytes()
+	if err := gifSetInterlaced(data); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// gifPaletted converts the image to a paletted image with at most cfg.gifNumColors colors.
+// Pixels of the transparent color (if not nil) and pixels with alpha below 50% are
+// mapped to a transparent palette entry. Paletted images without a transparent color
+// that fit the number of colors are returned as is.
+func gifPaletted(img image.Image, cfg *encodeConfig, transparent color.Color) *image.Paletted {
+	if pm, ok := img.(*image.Paletted); ok && transparent == nil && len(pm.Palette) <= cfg.gifNumColors {
+		return pm
+	}
+
+	b := img.Bounds()
+	src := Clone(img)
+	var tc color.NRGBA
+	if transparent != nil {
+		tc = color.NRGBAModel.Convert(transparent).(color.NRGBA)
+	}
+	mask := make([]bool, len(src.Pix)/4)
+	hasTransparent := false
+	for i := 0; i < len(src.Pix); i += 4 {
+		px := src.Pix[i : i+4 : i+4]
+		if px[3] < 0x80 || (transparent != nil && px[0] == tc.R && px[1] == tc.G && px[2] == tc.B && px[3] == tc.A) {
+			mask[i/4] = true
+			hasTransparent = true
+			px[0], px[1], px[2], px[3] = 0, 0, 0, 0
+			continue
+		}
+		px[3] = 0xff
+	}
+
+	numColors := cfg.gifNumColors
+	if numColors < 1 || numColors > 256 {
+		numColors = 256
+	}
+	if hasTransparent && numColors > 1 {
+		numColors--
+	}
+	pm := image.NewPaletted(src.Rect, palette.Plan9[:numColors])
+	if cfg.gifQuantizer != nil {
+		pm.Palette = cfg.gifQuantizer.Quantize(make(color.Palette, 0, numColors), src)
+	}
+	drawer := cfg.gifDrawer
+	if drawer == nil {
+		drawer = draw.FloydSteinberg
+	}
+	drawer.Draw(pm, src.Rect, src, image.Point{})
+
+	if hasTransparent {
+		idx := uint8(len(pm.Palette))
+		if len(pm.Palette) >= 256 {
+			idx = 255
+			pm.Palette = pm.Palette[:255]
+		}
+		pm.Palette = append(pm.Palette, color.NRGBA{})
+		for i, t := range mask {
+			if t {
+				pm.Pix[i] = idx
+			}
+		}
+	}
+	pm.Rect = pm.Rect.Add(b.Min)
+	return pm
+}
+
+// gifInterlaceRows returns the order in which the rows of an interlaced GIF image are stored.
+func gifInterlaceRows(h int) []int {
+	rows := make([]int, 0, h)
+	for _, pass := range [][2]int{{0, 8}, {4, 8}, {2, 4}, {1, 2}} {
+		for y := pass[0]; y < h; y += pass[1] {
+			rows = append(rows, y)
+		}
+	}
+	return rows
+}
+
+// gifInterlace returns a copy of the paletted image with the rows reordered in the
+// interlaced GIF order, so that the encoded pixel data is interlaced.
+func gifInterlace(pm *image.Paletted) *image.Paletted {
+	w, h := pm.Rect.Dx(), pm.Rect.Dy()
+	dst := &image.Paletted{
+		Pix:     make([]uint8, w*h),
+		Stride:  w,
+		Rect:    pm.Rect,
+		Palette: pm.Palette,
+	}
+	for i, y := range gifInterlaceRows(h) {
+		copy(dst.Pix[i*w:(i+1)*w], pm.Pix[y*pm.Stride:y*pm.Stride+w])
+	}
+	return dst
+}
+
+// gifSetInterlaced sets the interlace flag of every image descriptor of the GIF data.
+func gifSetInterlaced(data []byte) error {
+	const (
+		headerLen      = 6
+		screenDescLen  = 7
+		imageDescLen   = 10
+		colorTableFlag = 0x80
+		interlacedFlag = 0x40
+	)
+	if len(data) < headerLen+screenDescLen {
+		return errInvalidGIF
+	}
+	pos := headerLen + screenDescLen
+	if flags := data[headerLen+4]; flags&colorTableFlag != 0 {
+		pos += 3 << (flags&7 + 1)
+	}
+
+	// skipSubBlocks returns the position after the data sub-blocks starting at p.
+	skipSubBlocks := func(p int) (int, error) {
+		for p < len(data) {
+			n := int(data[p])
+			p++
+			if n == 0 {
+				return p, nil
+			}
+			p += n
+		}
+		return 0, errInvalidGIF
+	}
+
+	var err error
+	for pos < len(data) {
+		switch data[pos] {
+		case 0x21: // Extension.
+			if pos+2 > len(data) {
+				return errInvalidGIF
+			}
+			if pos, err = skipSubBlocks(pos + 2); err != nil {
+				return err
+			}
+		case 0x2c: // Image descriptor.
+			if pos+imageDescLen+1 > len(data) {
+				return errInvalidGIF
+			}
+			flags := data[pos+9]
+			data[pos+9] = flags | interlacedFlag
+			pos += imageDescLen
+			if flags&colorTableFlag != 0 {
+				pos += 3 << (flags&7 + 1)
+			}
+			// Skip the LZW minimum code size and the image data.
+			if pos, err = skipSubBlocks(pos + 1); err != nil {
+				return err
+			}
+		case 0x3b: // Trailer.
+			return nil
+		default:
+			return errInvalidGIF
+		}
+	}
+	return errInvalidGIF
+}
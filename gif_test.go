@@ -0,0 +1,119 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"reflect"
+	"testing"
+)
+
+func TestGIFInterlaceRows(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[int][]int{
+		0:  {},
+		1:  {0},
+		5:  {0, 4, 2, 1, 3},
+		10: {0, 8, 4, 2, 6, 1, 3, 5, 7, 9},
+	}
+	for h, want := range testCases {
+		if got := gifInterlaceRows(h); !reflect.DeepEqual(got, want) {
+			t.Fatalf("h=%d: got %v want %v", h, got, want)
+		}
+	}
+}
+
+func TestEncodeGIFInterlaced(t *testing.T) {
+	t.Parallel()
+
+	src := image.NewNRGBA(image.Rect(0, 0, 7, 19))
+	for y := 0; y < 19; y++ {
+		for x := 0; x < 7; x++ {
+			src.SetNRGBA(x, y, color.NRGBA{uint8(y * 13), uint8(x * 36), 0, 0xff})
+		}
+	}
+	want := Clone(src)
+	pm := gifPaletted(src, &defaultEncodeConfig, nil)
+
+	buf := &bytes.Buffer{}
+	if err := Encode(buf, pm, GIF, GIFInterlaced(true)); err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	data := buf.Bytes()
+	// The image descriptor follows the header, the screen descriptor and the global color table.
+	pos := 6 + 7
+	if data[10]&0x80 != 0 {
+		pos += 3 << (data[10]&7 + 1)
+	}
+	for data[pos] == 0x21 {
+		pos += 2
+		for data[pos] != 0 {
+			pos += int(data[pos]) + 1
+		}
+		pos++
+	}
+	if data[pos] != 0x2c || data[pos+9]&0x40 == 0 {
+		t.Fatalf("interlace flag is not set")
+	}
+
+	got, err := gif.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	if !compareNRGBA(Clone(got), Clone(pm), 0) {
+		t.Fatalf("decoded image differs")
+	}
+	if !compareNRGBA(Clone(got), want, 64) {
+		t.Fatalf("decoded image differs too much from the source")
+	}
+}
+
+func TestEncodeGIFTransparency(t *testing.T) {
+	t.Parallel()
+
+	magenta := color.NRGBA{0xff, 0x00, 0xff, 0xff}
+	src := New(4, 4, color.NRGBA{0xff, 0xff, 0xff, 0xff})
+	src.SetNRGBA(0, 0, magenta)
+	src.SetNRGBA(1, 0, color.NRGBA{0x00, 0x00, 0x00, 0x10})
+
+	testCases := []struct {
+		name string
+		opts []EncodeOption
+		want []bool
+	}{
+		{
+			name: "transparent color option",
+			opts: []EncodeOption{GIFTransparentColor(magenta)},
+			want: []bool{true, true, false},
+		},
+		{
+			name: "interlaced without transparent color",
+			opts: []EncodeOption{GIFInterlaced(true)},
+			want: []bool{false, true, false},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			buf := &bytes.Buffer{}
+			if err := Encode(buf, src, GIF, tc.opts...); err != nil {
+				t.Fatalf("failed to encode: %v", err)
+			}
+			img, err := Decode(buf)
+			if err != nil {
+				t.Fatalf("failed to decode: %v", err)
+			}
+			for i, want := range tc.want {
+				_, _, _, a := img.At(i, 0).RGBA()
+				if got := a == 0; got != want {
+					t.Fatalf("pixel %d: got transparent %v want %v", i, got, want)
+				}
+			}
+		})
+	}
+}
@@ -50,3 +50,48 @@ func Histogram(img image.Image) [256]float64 {
 	}
 	return histogram
 }
+
+// ProjectRows returns the projection profile of the rows of the image: the sums of the
+// luminances (0-255) of the pixels of every row, from the top to the bottom. The profile
+// is the base of the analysis of the scanned documents: the lines of dark text on light
+// paper are the valleys of the profile, and the margins its flat tops.
+//
+// Example:
+//
+//	profile := imaging.ProjectRows(page)
+func ProjectRows(img image.Image) []float64 {
+	src := newScanner(img)
+	sums := make([]float64, src.h)
+	parallel(0, src.h, func(ys <-chan int) {
+		scanLine := make([]uint8, src.w*4)
+		for y := range ys {
+			src.scan(0, y, src.w, y+1, scanLine)
+			var sum float64
+			for i := 0; i < len(scanLine); i += 4 {
+				sum += luminance(scanLine[i : i+3 : i+3])
+			}
+			sums[y] = sum
+		}
+	})
+	return sums
+}
+
+// ProjectColumns returns the projection profile of the columns of the image: the sums
+// of the luminances (0-255) of the pixels of every column, from the left to the right.
+// See ProjectRows.
+func ProjectColumns(img image.Image) []float64 {
+	src := newScanner(img)
+	sums := make([]float64, src.w)
+	parallel(0, src.w, func(xs <-chan int) {
+		scanLine := make([]uint8, src.h*4)
+		for x := range xs {
+			src.scan(x, 0, x+1, src.h, scanLine)
+			var sum float64
+			for i := 0; i < len(scanLine); i += 4 {
+				sum += luminance(scanLine[i : i+3 : i+3])
+			}
+			sums[x] = sum
+		}
+	})
+	return sums
+}
@@ -2,6 +2,7 @@ package imaging
 
 import (
 	"image"
+	"math"
 	"testing"
 )
 
@@ -61,3 +62,36 @@ func BenchmarkHistogram(b *testing.B) {
 		Histogram(testdataBranchesJPG)
 	}
 }
+
+func TestProjectRows(t *testing.T) {
+	t.Parallel()
+
+	src := &image.NRGBA{
+		Rect:   image.Rect(-1, -1, 2, 1),
+		Stride: 3 * 4,
+		Pix: []uint8{
+			0xff, 0xff, 0xff, 0xff, 0x00, 0x00, 0x00, 0xff, 0xff, 0x00, 0x00, 0xff,
+			0x00, 0x00, 0xff, 0xff, 0xff, 0xff, 0xff, 0x80, 0x00, 0x00, 0x00, 0x00,
+		},
+	}
+	testCases := []struct {
+		name string
+		got  []float64
+		want []float64
+	}{
+		{"rows", ProjectRows(src), []float64{255 + 0.299*255, 0.114*255 + 255}},
+		{"columns", ProjectColumns(src), []float64{255 + 0.114*255, 255, 0.299 * 255}},
+		{"empty rows", ProjectRows(&image.NRGBA{}), []float64{}},
+		{"empty columns", ProjectColumns(&image.NRGBA{}), []float64{}},
+	}
+	for _, tc := range testCases {
+		if len(tc.got) != len(tc.want) {
+			t.Fatalf("%s: got %v want %v", tc.name, tc.got, tc.want)
+		}
+		for i := range tc.got {
+			if math.Abs(tc.got[i]-tc.want[i]) > 1e-9 {
+				t.Fatalf("%s: got %v want %v", tc.name, tc.got, tc.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,490 @@
+package imaging
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"io"
+	"math"
+)
+
+// tagICCProfile is the TIFF tag holding the embedded ICC profile.
+const tagICCProfile = 34675
+
+// iccHeader is the header of the JPEG APP2 segments holding the ICC profile.
+const iccHeader = "ICC_PROFILE\x00"
+
+// iccChunkSize is the maximum size of the ICC profile chunk in a JPEG APP2 segment.
+const iccChunkSize = 0xffff - 2 - len(iccHeader) - 2
+
+// pngHeader is the signature at the start of PNG files.
+const pngHeader = "\x89PNG\r\n\x1a\n"
+
+// errInvalidPNG means the encoded PNG data is too short to hold the IHDR chunk.
+var errInvalidPNG = errors.New("imaging: invalid PNG data")
+
+// ConvertToSRGB returns a DecodeOption that converts the pixels of images with an
+// embedded ICC profile other than sRGB (e.g. Display P3 or Adobe RGB photos) to sRGB.
+// Without the conversion such images look desaturated, because the processing
+// functions and most viewers treat the pixel values as sRGB. Matrix/TRC RGB profiles
+// (used by cameras and phones) are supported, images with other profiles are left
+// unchanged. When the image is converted, DecodeWithMetadata drops the ICC profile
+// from the returned metadata. By default it's disabled.
+//
+// Example:
+//
+//	img, err := imaging.Open("iphone.jpg", imaging.AutoOrientation(true), imaging.ConvertToSRGB(true))
+func ConvertToSRGB(enabled bool) DecodeOption {
+	return func(c *decodeConfig) {
+		c.convertToSRGB = enabled
+	}
+}
+
+// decodeToSRGB decodes the image and converts it to sRGB using the embedded ICC profile.
+func decodeToSRGB(r io.Reader, opts []DecodeOption) (image.Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	img, err := Decode(bytes.NewReader(data), append(opts, ConvertToSRGB(false))...)
+	if err != nil {
+		return nil, err
+	}
+	if p, ok := parseICCProfile(readICCProfile(data)); ok && !p.isSRGB() {
+		return p.toSRGB(img), nil
+	}
+	return img, nil
+}
+
+// readICCProfile returns the ICC profile embedded in the JPEG, PNG or TIFF image or nil.
+func readICCProfile(data []byte) []byte {
+	switch {
+	case isJPEG(data):
+		return readJPEGICC(data)
+	case isPNG(data):
+		return readPNGICC(data)
+	case isTIFF(data):
+		dirs, err := parseTIFF(data)
+		if err != nil {
+			return nil
+		}
+		if f := dirs[0].field(tagICCProfile); f != nil {
+			return f.data
+		}
+	}
+	return nil
+}
+
+// readJPEGICC returns the ICC profile stored in the APP2 segments of the JPEG image.
+// The profile may be split into several segments numbered from 1.
+func readJPEGICC(data []byte) []byte {
+	chunks := map[int][]byte{}
+	count := 0
+	walkJPEGSegments(data, func(marker byte, payload []byte) bool {
+		if marker == markerAPP2 && len(payload) > len(iccHeader)+2 && string(payload[:len(iccHeader)]) == iccHeader {
+			chunks[int(payload[len(iccHeader)])] = payload[len(iccHeader)+2:]
+			count = int(payload[len(iccHeader)+1])
+		}
+		return true
+	})
+	if count == 0 || len(chunks) != count {
+		return nil
+	}
+	var profile []byte
+	for i := 1; i <= count; i++ {
+		chunk, ok := chunks[i]
+		if !ok {
+			return nil
+		}
+		profile = append(profile, chunk...)
+	}
+	return profile
+}
+
+// isPNG reports whether data starts with the PNG signature.
+func isPNG(data []byte) bool {
+	return len(data) >= len(pngHeader) && string(data[:len(pngHeader)]) == pngHeader
+}
+
+// readPNGICC returns the ICC profile stored in the iCCP chunk of the PNG image.
+func readPNGICC(data []byte) []byte {
+	pos := len(pngHeader)
+	for pos+8 <= len(data) {
+		size := int(binary.BigEndian.Uint32(data[pos:]))
+		typ := string(data[pos+4 : pos+8])
+		if size < 0 || pos+12+size > len(data) || typ == "IDAT" {
+			return nil
+		}
+		if typ == "iCCP" {
+			chunk := data[pos+8 : pos+8+size]
+			// Profile name, NUL separator, compression method (0 = zlib) and the profile.
+			i := bytes.IndexByte(chunk, 0)
+			if i < 0 || i+2 > len(chunk) || chunk[i+1] != 0 {
+				return nil
+			}
+			zr, err := zlib.NewReader(bytes.NewReader(chunk[i+2:]))
+			if err != nil {
+				return nil
+			}
+			profile, err := io.ReadAll(zr)
+			if err != nil {
+				return nil
+			}
+			return profile
+		}
+		pos += 12 + size
+	}
+	return nil
+}
+
+// jpegICCSegments returns the JPEG APP2 segments holding the ICC profile.
+func jpegICCSegments(profile []byte) [][]byte {
+	count := (len(profile) + iccChunkSize - 1) / iccChunkSize
+	if count > 255 {
+		return nil
+	}
+	segments := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		chunk := profile[i*iccChunkSize:]
+		if len(chunk) > iccChunkSize {
+			chunk = chunk[:iccChunkSize]
+		}
+		segment := []byte{0xff, markerAPP2, 0, 0}
+		segment = append(segment, iccHeader...)
+		segment = append(segment, byte(i+1), byte(count))
+		segment = append(segment, chunk...)
+		binary.BigEndian.PutUint16(segment[2:], uint16(len(segment)-2))
+		segments = append(segments, segment)
+	}
+	return segments
+}
+
+// insertPNGICC writes the PNG image in data to w with the ICC profile stored
+// in an iCCP chunk right after the IHDR chunk.
+func insertPNGICC(w io.Writer, data, profile []byte) error {
+	// Signature and the IHDR chunk (13 bytes of data).
+	const ihdrEnd = len(pngHeader) + 12 + 13
+	if len(data) < ihdrEnd {
+		return errInvalidPNG
+	}
+
+	compressed := &bytes.Buffer{}
+	zw := zlib.NewWriter(compressed)
+	if _, err := zw.Write(profile); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	chunk := make([]byte, 8, 8+compressed.Len()+16)
+	copy(chunk[4:], "iCCP")
+	chunk = append(chunk, "ICC Profile\x00\x00"...)
+	chunk = append(chunk, compressed.Bytes()...)
+	binary.BigEndian.PutUint32(chunk, uint32(len(chunk)-8))
+	crc := make([]byte, 4)
+	binary.BigEndian.PutUint32(crc, crc32.ChecksumIEEE(chunk[4:]))
+	chunk = append(chunk, crc...)
+
+	for _, part := range [][]byte{data[:ihdrEnd], chunk, data[ihdrEnd:]} {
+		if _, err := w.Write(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// iccProfile is the part of a matrix/TRC RGB ICC profile needed to convert colors to sRGB.
+type iccProfile struct {
+	// matrix converts linear RGB to the XYZ (D50) profile connection space.
+	matrix [3][3]float64
+	// trc holds the tone reproduction curves of the red, green and blue channels.
+	trc [3]iccCurve
+}
+
+// iccCurve is a tone reproduction curve, converting the encoded values (0-1) to linear light.
+type iccCurve struct {
+	// table is the sampled curve, used if not empty.
+	table []float64
+	// params are the parameters (g, a, b, c, d, e, f) of the parametric curve
+	// Y = (aX+b)^g + e for X >= d, Y = cX + f otherwise.
+	params [7]float64
+}
+
+// srgbToXYZ converts linear sRGB to the XYZ (D50) profile connection space, see the sRGB ICC profile.
+var srgbToXYZ = [3][3]float64{ //nolint
+	{0.4360747, 0.3850649, 0.1430804},
+	{0.2225045, 0.7168786, 0.0606169},
+	{0.0139322, 0.0971045, 0.7141733},
+}
+
+// parseICCProfile parses the colorants and the tone reproduction curves of the
+// ICC profile. It reports false if the profile is not a matrix/TRC RGB profile.
+func parseICCProfile(data []byte) (*iccProfile, bool) {
+	if len(data) < 132 || string(data[16:20]) != "RGB " || string(data[20:24]) != "XYZ " {
+		return nil, false
+	}
+	tags := map[string][]byte{}
+	n := int(binary.BigEndian.Uint32(data[128:]))
+	for i := 0; i < n; i++ {
+		entry := 132 + 12*i
+		if entry+12 > len(data) {
+			return nil, false
+		}
+		off := int(binary.BigEndian.Uint32(data[entry+4:]))
+		size := int(binary.BigEndian.Uint32(data[entry+8:]))
+		if off < 0 || size < 0 || off+size > len(data) || off+size < off {
+			return nil, false
+		}
+		tags[string(data[entry:entry+4])] = data[off : off+size]
+	}
+
+	p := &iccProfile{}
+	for c, sig := range []string{"rXYZ", "gXYZ", "bXYZ"} {
+		xyz := tags[sig]
+		if len(xyz) < 20 || string(xyz[:4]) != "XYZ " {
+			return nil, false
+		}
+		for j := 0; j < 3; j++ {
+			p.matrix[j][c] = s15Fixed16(xyz[8+4*j:])
+		}
+	}
+	for c, sig := range []string{"rTRC", "gTRC", "bTRC"} {
+		curve, ok := parseICCCurve(tags[sig])
+		if !ok {
+			return nil, false
+		}
+		p.trc[c] = curve
+	}
+	return p, true
+}
+
+// parseICCCurve parses a curv or para tag.
+func parseICCCurve(data []byte) (iccCurve, bool) {
+	if len(data) < 12 {
+		return iccCurve{}, false
+	}
+	var c iccCurve
+	switch string(data[:4]) {
+	case "curv":
+		n := int(binary.BigEndian.Uint32(data[8:]))
+		if len(data) < 12+2*n {
+			return iccCurve{}, false
+		}
+		switch n {
+		case 0:
+			c.params = [7]float64{1, 1}
+		case 1:
+			c.params = [7]float64{float64(binary.BigEndian.Uint16(data[12:])) / 256, 1}
+		default:
+			c.table = make([]float64, n)
+			for i := range c.table {
+				c.table[i] = float64(binary.BigEndian.Uint16(data[12+2*i:])) / 0xffff
+			}
+		}
+	case "para":
+		counts := []int{1, 3, 4, 5, 7}
+		typ := int(binary.BigEndian.Uint16(data[8:]))
+		if typ >= len(counts) || len(data) < 12+4*counts[typ] {
+			return iccCurve{}, false
+		}
+		var v [7]float64
+		for i := 0; i < counts[typ]; i++ {
+			v[i] = s15Fixed16(data[12+4*i:])
+		}
+		g, a, b := v[0], 1.0, 0.0
+		if typ > 0 {
+			a, b = v[1], v[2]
+		}
+		switch typ {
+		case 0:
+			c.params = [7]float64{g, 1}
+		case 1:
+			// Y = (aX+b)^g for X >= -b/a, 0 otherwise.
+			c.params = [7]float64{g, a, b, 0, -b / a}
+		case 2:
+			// Y = (aX+b)^g + c for X >= -b/a, c otherwise.
+			c.params = [7]float64{g, a, b, 0, -b / a, v[3], v[3]}
+		case 3:
+			c.params = [7]float64{g, a, b, v[3], v[4]}
+		case 4:
+			c.params = [7]float64{g, a, b, v[3], v[4], v[5], v[6]}
+		}
+	default:
+		return iccCurve{}, false
+	}
+	return c, true
+}
+
+// s15Fixed16 decodes an ICC signed fixed point number.
+func s15Fixed16(b []byte) float64 {
+	return float64(int32(binary.BigEndian.Uint32(b))) / 65536
+}
+
+// linear returns the linear light value of the encoded value x (0-1).
+func (c *iccCurve) linear(x float64) float64 {
+	if len(c.table) > 0 {
+		pos := x * float64(len(c.table)-1)
+		i := int(pos)
+		if i >= len(c.table)-1 {
+			return c.table[len(c.table)-1]
+		}
+		f := pos - float64(i)
+		return c.table[i]*(1-f) + c.table[i+1]*f
+	}
+	g, a, b, cc, d, e, f := c.params[0], c.params[1], c.params[2], c.params[3], c.params[4], c.params[5], c.params[6]
+	if x >= d {
+		return math.Pow(math.Max(a*x+b, 0), g) + e
+	}
+	return cc*x + f
+}
+
+// isSRGB reports whether the profile describes the sRGB color space, so no conversion is needed.
+func (p *iccProfile) isSRGB() bool {
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			if math.Abs(p.matrix[i][j]-srgbToXYZ[i][j]) > 0.003 {
+				return false
+			}
+		}
+	}
+	for c := range p.trc {
+		for _, x := range []float64{0.02, 0.2, 0.5, 0.8} {
+			if math.Abs(p.trc[c].linear(x)-srgbToLinear(x)) > 0.003 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// srgbToLinear converts an sRGB encoded value (0-1) to linear light.
+func srgbToLinear(x float64) float64 {
+	if x <= 0.04045 {
+		return x / 12.92
+	}
+	return math.Pow((x+0.055)/1.055, 2.4)
+}
+
+// linearToSRGB converts linear light to an sRGB encoded value (0-1).
+func linearToSRGB(x float64) float64 {
+	if x <= 0.0031308 {
+		return 12.92 * x
+	}
+	return 1.055*math.Pow(x, 1/2.4) - 0.055
+}
+
+// toSRGB returns the image converted from the profile color space to sRGB.
+// 16-bit images are converted to *image.NRGBA64, others to *image.NRGBA.
+func (p *iccProfile) toSRGB(img image.Image) image.Image {
+	m := mulMatrix(invertMatrix(srgbToXYZ), p.matrix)
+
+	if is16Bit(img) {
+		src := toNRGBA64(img)
+		dst := image.NewNRGBA64(src.Rect)
+		dec, enc := p.luts(0xffff)
+		parallel(0, src.Rect.Dy(), func(ys <-chan int) {
+			for y := range ys {
+				i := y * src.Stride
+				j := y * dst.Stride
+				for x := 0; x < src.Rect.Dx(); x++ {
+					var rgb [3]int
+					for c := 0; c < 3; c++ {
+						rgb[c] = int(src.Pix[i+2*c])<<8 | int(src.Pix[i+2*c+1])
+					}
+					out := convertPixel(m, rgb, dec, enc)
+					for c := 0; c < 3; c++ {
+						dst.Pix[j+2*c] = uint8(out[c] >> 8)
+						dst.Pix[j+2*c+1] = uint8(out[c])
+					}
+					dst.Pix[j+6], dst.Pix[j+7] = src.Pix[i+6], src.Pix[i+7]
+					i += 8
+					j += 8
+				}
+			}
+		})
+		return dst
+	}
+
+	src := newScanner(img)
+	dst := image.NewNRGBA(image.Rect(0, 0, src.w, src.h))
+	dec, enc := p.luts(0xff)
+	parallel(0, src.h, func(ys <-chan int) {
+		for y := range ys {
+			i := y * dst.Stride
+			src.scan(0, y, src.w, y+1, dst.Pix[i:i+src.w*4])
+			for x := 0; x < src.w; x++ {
+				d := dst.Pix[i : i+3 : i+3]
+				out := convertPixel(m, [3]int{int(d[0]), int(d[1]), int(d[2])}, dec, enc)
+				d[0], d[1], d[2] = uint8(out[0]), uint8(out[1]), uint8(out[2])
+				i += 4
+			}
+		}
+	})
+	return dst
+}
+
+// encodeLUTSize is the number of entries of the lookup table encoding linear light as sRGB.
+const encodeLUTSize = 1 << 14
+
+// luts returns the lookup tables that decode the channel values (0-maxValue) to linear
+// light and encode linear light (quantized to encodeLUTSize steps) as sRGB values.
+func (p *iccProfile) luts(maxValue int) ([3][]float64, []int) {
+	var dec [3][]float64
+	for c := range dec {
+		dec[c] = make([]float64, maxValue+1)
+		for i := range dec[c] {
+			dec[c][i] = p.trc[c].linear(float64(i) / float64(maxValue))
+		}
+	}
+	enc := make([]int, encodeLUTSize+1)
+	for i := range enc {
+		enc[i] = int(linearToSRGB(float64(i)/encodeLUTSize)*float64(maxValue) + 0.5)
+	}
+	return dec, enc
+}
+
+// convertPixel converts the color using the lookup tables and the matrix from the
+// linear profile RGB to linear sRGB.
+func convertPixel(m [3][3]float64, rgb [3]int, dec [3][]float64, enc []int) [3]int {
+	lin := [3]float64{dec[0][rgb[0]], dec[1][rgb[1]], dec[2][rgb[2]]}
+	var out [3]int
+	for c := 0; c < 3; c++ {
+		v := m[c][0]*lin[0] + m[c][1]*lin[1] + m[c][2]*lin[2]
+		v = math.Min(math.Max(v, 0), 1)
+		out[c] = enc[int(v*encodeLUTSize+0.5)]
+	}
+	return out
+}
+
+// mulMatrix returns the product of the 3x3 matrices.
+func mulMatrix(a, b [3][3]float64) [3][3]float64 {
+	var m [3][3]float64
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				m[i][j] += a[i][k] * b[k][j]
+			}
+		}
+	}
+	return m
+}
+
+// invertMatrix returns the inverse of the 3x3 matrix.
+func invertMatrix(a [3][3]float64) [3][3]float64 {
+	det := a[0][0]*(a[1][1]*a[2][2]-a[1][2]*a[2][1]) -
+		a[0][1]*(a[1][0]*a[2][2]-a[1][2]*a[2][0]) +
+		a[0][2]*(a[1][0]*a[2][1]-a[1][1]*a[2][0])
+	var m [3][3]float64
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			// Cofactor of the transposed matrix.
+			r0, r1 := (j+1)%3, (j+2)%3
+			c0, c1 := (i+1)%3, (i+2)%3
+			m[i][j] = (a[r0][c0]*a[r1][c1] - a[r0][c1]*a[r1][c0]) / det
+		}
+	}
+	return m
+}
@@ -0,0 +1,251 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// adobeRGBToXYZ holds the D50 adapted colorants of the Adobe RGB (1998) color space.
+var adobeRGBToXYZ = [3][3]float64{ //nolint
+	{0.6097559, 0.2052401, 0.1492240},
+	{0.3111242, 0.6256560, 0.0632197},
+	{0.0194811, 0.0608902, 0.7448387},
+}
+
+// makeICCProfile returns a matrix/TRC RGB profile. The curve is either a gamma
+// value (curv type) or the parameters of the para type 3 curve.
+func makeICCProfile(matrix [3][3]float64, curve ...float64) []byte {
+	fixed := func(v float64) []byte {
+		b := make([]byte, 4)
+		binary.BigEndian.PutUint32(b, uint32(int32(math.Round(v*65536))))
+		return b
+	}
+	var trc []byte
+	if len(curve) == 1 {
+		trc = append([]byte("curv\x00\x00\x00\x00\x00\x00\x00\x01"), byte(int(curve[0]*256)>>8), byte(int(curve[0]*256)), 0, 0)
+	} else {
+		trc = []byte("para\x00\x00\x00\x00\x00\x03\x00\x00")
+		for _, v := range curve {
+			trc = append(trc, fixed(v)...)
+		}
+	}
+
+	var tags [][]byte
+	var sigs []string
+	for c, sig := range []string{"rXYZ", "gXYZ", "bXYZ"} {
+		xyz := []byte("XYZ \x00\x00\x00\x00")
+		for j := 0; j < 3; j++ {
+			xyz = append(xyz, fixed(matrix[j][c])...)
+		}
+		tags = append(tags, xyz)
+		sigs = append(sigs, sig)
+	}
+	for _, sig := range []string{"rTRC", "gTRC", "bTRC"} {
+		tags = append(tags, trc)
+		sigs = append(sigs, sig)
+	}
+
+	header := make([]byte, 128)
+	copy(header[12:], "mntr")
+	copy(header[16:], "RGB ")
+	copy(header[20:], "XYZ ")
+	copy(header[36:], "acsp")
+	table := make([]byte, 4+12*len(tags))
+	binary.BigEndian.PutUint32(table, uint32(len(tags)))
+	data := []byte{}
+	for i, tag := range tags {
+		e := table[4+12*i:]
+		copy(e, sigs[i])
+		binary.BigEndian.PutUint32(e[4:], uint32(len(header)+len(table)+len(data)))
+		binary.BigEndian.PutUint32(e[8:], uint32(len(tag)))
+		data = append(data, tag...)
+	}
+	profile := append(append(header, table...), data...)
+	binary.BigEndian.PutUint32(profile, uint32(len(profile)))
+	return profile
+}
+
+func TestICCProfilePreservation(t *testing.T) {
+	t.Parallel()
+
+	small := makeICCProfile(adobeRGBToXYZ, 2.19921875)
+	large := make([]byte, 150000)
+	for i := range large {
+		large[i] = byte(i * 7)
+	}
+	for _, profile := range [][]byte{small, large} {
+		for _, f := range []Format{JPEG, PNG, TIFF} {
+			src := makeNoiseNRGBA(8, 8, 1)
+			buf := &bytes.Buffer{}
+			if err := Encode(buf, src, f, WithMetadata(&Metadata{ICCProfile: profile})); err != nil {
+				t.Fatalf("%s: failed to encode: %v", f, err)
+			}
+			if _, err := Decode(bytes.NewReader(buf.Bytes())); err != nil {
+				t.Fatalf("%s: failed to decode: %v", f, err)
+			}
+			got, err := DecodeMetadata(buf)
+			if err != nil {
+				t.Fatalf("%s: failed to decode metadata: %v", f, err)
+			}
+			if !bytes.Equal(got.ICCProfile, profile) {
+				t.Fatalf("%s: got profile of %d bytes want %d bytes", f, len(got.ICCProfile), len(profile))
+			}
+			if f == TIFF && got.EXIF != nil && got.EXIF.dir.field(tagICCProfile) != nil {
+				t.Fatalf("%s: ICC profile stored in the EXIF data", f)
+			}
+		}
+	}
+
+	// Images without a profile.
+	for _, f := range []Format{JPEG, PNG, TIFF} {
+		buf := &bytes.Buffer{}
+		if err := Encode(buf, makeNoiseNRGBA(8, 8, 1), f); err != nil {
+			t.Fatalf("%s: failed to encode: %v", f, err)
+		}
+		got, err := DecodeMetadata(buf)
+		if err != nil {
+			t.Fatalf("%s: failed to decode metadata: %v", f, err)
+		}
+		if got.ICCProfile != nil {
+			t.Fatalf("%s: got unexpected profile", f)
+		}
+	}
+}
+
+func TestConvertToSRGB(t *testing.T) {
+	t.Parallel()
+
+	src := image.NewNRGBA(image.Rect(0, 0, 3, 1))
+	src.SetNRGBA(0, 0, color.NRGBA{0x80, 0x80, 0x80, 0xff})
+	src.SetNRGBA(1, 0, color.NRGBA{0xc8, 0x32, 0x32, 0xff})
+	src.SetNRGBA(2, 0, color.NRGBA{0x00, 0xff, 0x00, 0x80})
+
+	encode := func(t *testing.T, img image.Image, profile []byte) []byte {
+		t.Helper()
+		buf := &bytes.Buffer{}
+		if err := Encode(buf, img, PNG, WithMetadata(&Metadata{ICCProfile: profile})); err != nil {
+			t.Fatalf("failed to encode: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	t.Run("Adobe RGB", func(t *testing.T) {
+		data := encode(t, src, makeICCProfile(adobeRGBToXYZ, 2.19921875))
+		img, meta, err := DecodeWithMetadata(bytes.NewReader(data), ConvertToSRGB(true))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got := toNRGBA(img)
+		gray, red, green := got.NRGBAAt(0, 0), got.NRGBAAt(1, 0), got.NRGBAAt(2, 0)
+		if gray.R != gray.G || gray.G != gray.B || absInt(int(gray.R)-0x80) > 3 {
+			t.Fatalf("got gray %v", gray)
+		}
+		if red.R <= 0xc8 || red.G >= 0x32 {
+			t.Fatalf("got red %v want more saturated than %v", red, src.NRGBAAt(1, 0))
+		}
+		if green.R != 0 || green.G != 0xff || green.A != 0x80 {
+			t.Fatalf("got green %v", green)
+		}
+		if meta.ICCProfile != nil {
+			t.Fatalf("profile kept after the conversion")
+		}
+
+		img, meta, err = DecodeWithMetadata(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !compareNRGBA(toNRGBA(img), src, 0) || meta.ICCProfile == nil {
+			t.Fatalf("image converted without the option")
+		}
+	})
+
+	t.Run("sRGB", func(t *testing.T) {
+		data := encode(t, src, makeICCProfile(srgbToXYZ, 2.4, 1/1.055, 0.055/1.055, 1/12.92, 0.04045))
+		img, err := Decode(bytes.NewReader(data), ConvertToSRGB(true))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !compareNRGBA(toNRGBA(img), src, 0) {
+			t.Fatalf("sRGB image changed: %v", img)
+		}
+	})
+
+	t.Run("16-bit", func(t *testing.T) {
+		data := encode(t, toNRGBA64(src), makeICCProfile(adobeRGBToXYZ, 2.19921875))
+		img, err := Decode(bytes.NewReader(data), ConvertToSRGB(true), Preserve16Bit(true))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got, ok := img.(*image.NRGBA64)
+		if !ok {
+			t.Fatalf("got %T want *image.NRGBA64", img)
+		}
+		if c := got.NRGBA64At(1, 0); c.R <= 0xc8c8 || c.G >= 0x3232 {
+			t.Fatalf("got red %v", c)
+		}
+	})
+
+	t.Run("no profile", func(t *testing.T) {
+		img, err := Decode(bytes.NewReader(encode(t, src, nil)), ConvertToSRGB(true))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !compareNRGBA(toNRGBA(img), src, 0) {
+			t.Fatalf("image without profile changed")
+		}
+	})
+}
+
+func TestParseICCCurve(t *testing.T) {
+	t.Parallel()
+
+	para := func(typ uint16, params ...float64) []byte {
+		b := []byte("para\x00\x00\x00\x00\x00\x00\x00\x00")
+		binary.BigEndian.PutUint16(b[8:], typ)
+		for _, v := range params {
+			f := make([]byte, 4)
+			binary.BigEndian.PutUint32(f, uint32(int32(math.Round(v*65536))))
+			b = append(b, f...)
+		}
+		return b
+	}
+	testCases := []struct {
+		name string
+		data []byte
+		x    float64
+		want float64
+		ok   bool
+	}{
+		{"identity", []byte("curv\x00\x00\x00\x00\x00\x00\x00\x00"), 0.3, 0.3, true},
+		{"gamma", []byte("curv\x00\x00\x00\x00\x00\x00\x00\x01\x02\x00"), 0.5, 0.25, true},
+		{"table", []byte("curv\x00\x00\x00\x00\x00\x00\x00\x03\x00\x00\x40\x00\xff\xff"), 0.25, 0.125, true},
+		{"para 0", para(0, 2), 0.5, 0.25, true},
+		{"para 1", para(1, 1, 2, -0.5), 0.2, 0, true},
+		{"para 2", para(2, 1, 2, -0.5, 0.25), 0.5, 0.75, true},
+		{"para 3", para(3, 2.4, 1/1.055, 0.055/1.055, 1/12.92, 0.04045), 0.5, srgbToLinear(0.5), true},
+		{"para 4", para(4, 1, 1, 0, 0.5, 0.5, 0.1, 0.2), 0.25, 0.325, true},
+		{"unknown type", para(5, 1), 0, 0, false},
+		{"short", []byte("curv\x00\x00\x00\x00\x00\x00\x00\x02\x00\x00"), 0, 0, false},
+		{"unknown", []byte("mft2\x00\x00\x00\x00\x00\x00\x00\x00"), 0, 0, false},
+	}
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			c, ok := parseICCCurve(tc.data)
+			if ok != tc.ok {
+				t.Fatalf("got ok %v want %v", ok, tc.ok)
+			}
+			if !ok {
+				return
+			}
+			if got := c.linear(tc.x); math.Abs(got-tc.want) > 1e-3 {
+				t.Fatalf("got %v want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+//go:build js && wasm
+
+package imaging
+
+import (
+	"errors"
+	"image"
+	"syscall/js"
+)
+
+// ErrNotImageData means the JS value is not an ImageData object.
+var ErrNotImageData = errors.New("imaging: not an ImageData")
+
+// FromImageData returns a copy of the JS ImageData object, e.g. the result of
+// getImageData of a canvas context, as an image. Both store non-premultiplied
+// RGBA pixels, so they are copied as is.
+//
+// Example:
+//
+//	ctx := canvas.Call("getContext", "2d")
+//	img, err := imaging.FromImageData(ctx.Call("getImageData", 0, 0, w, h))
+func FromImageData(v js.Value) (*image.NRGBA, error) {
+	if v.Type() != js.TypeObject || !v.InstanceOf(js.Global().Get("ImageData")) {
+		return nil, ErrNotImageData
+	}
+	w, h := v.Get("width").Int(), v.Get("height").Int()
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	js.CopyBytesToGo(dst.Pix, v.Get("data"))
+	return dst, nil
+}
+
+// ToImageData returns the image as a new JS ImageData object, e.g. to draw it to a
+// canvas with putImageData.
+//
+// Example:
+//
+//	ctx.Call("putImageData", imaging.ToImageData(dstImage), 0, 0)
+func ToImageData(img image.Image) js.Value {
+	src := toNRGBA(img)
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	data := js.Global().Get("Uint8ClampedArray").New(w * h * 4)
+	if src.Stride == w*4 {
+		js.CopyBytesToJS(data, src.Pix[:w*h*4])
+	} else {
+		for y := 0; y < h; y++ {
+			row := data.Call("subarray", y*w*4, (y+1)*w*4)
+			js.CopyBytesToJS(row, src.Pix[y*src.Stride:y*src.Stride+w*4])
+		}
+	}
+	return js.Global().Get("ImageData").New(data, w, h)
+}
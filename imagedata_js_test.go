@@ -0,0 +1,53 @@
+//go:build js && wasm
+
+package imaging
+
+import (
+	"errors"
+	"image"
+	"syscall/js"
+	"testing"
+)
+
+func TestImageData(t *testing.T) {
+	// Node.js doesn't implement ImageData of the browsers.
+	if js.Global().Get("ImageData").IsUndefined() {
+		js.Global().Call("eval", `globalThis.ImageData = class ImageData {
+			constructor(data, width, height) { this.data = data; this.width = width; this.height = height; }
+		}`)
+	}
+
+	src := &image.NRGBA{
+		Rect:   image.Rect(-1, -1, 1, 1),
+		Stride: 3 * 4,
+		Pix: []uint8{
+			0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0xff, 0xff, 0xff, 0xff,
+			0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10, 0xff, 0xff, 0xff, 0xff,
+		},
+	}
+	want := &image.NRGBA{
+		Rect:   image.Rect(0, 0, 2, 2),
+		Stride: 2 * 4,
+		Pix: []uint8{
+			0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08,
+			0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10,
+		},
+	}
+	for _, img := range []image.Image{src, want} {
+		v := ToImageData(img)
+		if w, h := v.Get("width").Int(), v.Get("height").Int(); w != 2 || h != 2 {
+			t.Fatalf("got ImageData %dx%d want 2x2", w, h)
+		}
+		got, err := FromImageData(v)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !compareNRGBA(got, want, 0) {
+			t.Fatalf("got result %#v want %#v", got, want)
+		}
+	}
+
+	if _, err := FromImageData(js.ValueOf(map[string]interface{}{"width": 1})); !errors.Is(err, ErrNotImageData) {
+		t.Fatalf("got error %v want %v", err, ErrNotImageData)
+	}
+}
@@ -0,0 +1,75 @@
+package imaging
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+)
+
+// ImageInfo describes an image without its pixel data.
+type ImageInfo struct {
+	// Width and Height are the image dimensions in pixels.
+	Width, Height int
+	// Format is the image file format.
+	Format Format
+	// ColorModel is the color model of the decoded image.
+	ColorModel color.Model
+}
+
+// Inspect reads the header of the image file and returns the image dimensions,
+// format and color model without decoding the pixel data.
+//
+// Example:
+//
+//	info, err := imaging.Inspect("upload.jpg")
+//	if err == nil && info.Width*info.Height > 50e6 {
+//		// Reject or process differently.
+//	}
+func Inspect(filename string) (info ImageInfo, err error) {
+	file, err := openFile(filename)
+	if err != nil {
+		return ImageInfo{}, err
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			if err == nil {
+				err = closeErr
+			} else {
+				err = fmt.Errorf("original error: %s, defer close error: %w", err.Error(), closeErr)
+			}
+		}
+	}()
+	return DecodeConfig(file)
+}
+
+// DecodeConfig reads the image header from io.Reader and returns the image
+// dimensions, format and color model without decoding the pixel data.
+// Images in formats registered by other packages result in ErrUnsupportedFormat.
+func DecodeConfig(r io.Reader) (ImageInfo, error) {
+	c, name, err := decodeImageConfig(r)
+	if err != nil {
+		return ImageInfo{}, err
+	}
+	f, ok := formatExts[name]
+	if !ok {
+		return ImageInfo{}, fmt.Errorf("%w: %s", ErrUnsupportedFormat, name)
+	}
+	return ImageInfo{Width: c.Width, Height: c.Height, Format: f, ColorModel: c.ColorModel}, nil
+}
+
+// decodeImageConfig decodes the color model and the dimensions of an image
+// in any of the registered formats and returns them along with the format name.
+func decodeImageConfig(r io.Reader) (image.Config, string, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(4); err == nil && isBigTIFF(magic) {
+		c, err := decodeBigTIFFConfig(br)
+		return c, "tiff", err
+	}
+	if magic, err := br.Peek(2); err == nil && string(magic) == "BM" {
+		c, err := decodeBMPConfig(br)
+		return c, "bmp", err
+	}
+	return image.DecodeConfig(br)
+}
@@ -0,0 +1,90 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDecodeConfig(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		img    image.Image
+		format Format
+		model  color.Model
+	}{
+		{name: "JPEG", img: New(30, 20, color.White), format: JPEG, model: color.YCbCrModel},
+		{name: "PNG", img: New(31, 21, color.Transparent), format: PNG, model: color.NRGBAModel},
+		{name: "GIF", img: image.NewPaletted(image.Rect(0, 0, 32, 22), palette.Plan9), format: GIF},
+		{name: "TIFF", img: makeGradient64(33, 23), format: TIFF, model: color.NRGBA64Model},
+		{name: "BMP", img: New(34, 24, color.Transparent), format: BMP, model: color.NRGBAModel},
+		{name: "BMP opaque", img: New(35, 25, color.Black), format: BMP, model: color.RGBAModel},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			buf := &bytes.Buffer{}
+			if err := Encode(buf, tc.img, tc.format); err != nil {
+				t.Fatalf("failed to encode: %v", err)
+			}
+			info, err := DecodeConfig(buf)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			b := tc.img.Bounds()
+			if info.Width != b.Dx() || info.Height != b.Dy() || info.Format != tc.format {
+				t.Fatalf("got %dx%d %s want %dx%d %s", info.Width, info.Height, info.Format, b.Dx(), b.Dy(), tc.format)
+			}
+			if tc.model != nil && info.ColorModel != tc.model {
+				t.Fatalf("got color model %T want %T", info.ColorModel, tc.model)
+			}
+		})
+	}
+
+	if _, err := DecodeConfig(bytes.NewReader([]byte("not an image"))); !errors.Is(err, image.ErrFormat) {
+		t.Fatalf("got error %v want %v", err, image.ErrFormat)
+	}
+}
+
+func TestDecodeConfigUnregisteredFormat(t *testing.T) {
+	image.RegisterFormat("imagingtest", "IMAGINGTEST", nil, func(r io.Reader) (image.Config, error) {
+		return image.Config{Width: 1, Height: 1}, nil
+	})
+	if _, err := DecodeConfig(bytes.NewReader([]byte("IMAGINGTEST"))); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("got error %v want %v", err, ErrUnsupportedFormat)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	dir, err := os.MkdirTemp("", "imaging")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir) //nolint
+
+	filename := filepath.Join(dir, "in.png")
+	if err := Save(New(12, 7, color.White), filename); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+	info, err := Inspect(filename)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.Width != 12 || info.Height != 7 || info.Format != PNG {
+		t.Fatalf("got %+v", info)
+	}
+	if _, err := Inspect(filepath.Join(dir, "missing.png")); err == nil {
+		t.Fatalf("expected error opening a missing file")
+	}
+}
@@ -15,6 +15,12 @@ type fileSystem interface {
 	Create(string) (io.WriteCloser, error)
 	// Open opens the named file for reading.
 	Open(string) (io.ReadCloser, error)
+	// CreateTemp creates a new temporary file in the directory and returns it along with its name.
+	CreateTemp(dir, pattern string) (io.WriteCloser, string, error)
+	// Rename renames (moves) the file, replacing the target if it exists.
+	Rename(oldpath, newpath string) error
+	// Remove removes the named file.
+	Remove(string) error
 }
 
 // localFS implements fileSystem interface using local file system.
@@ -26,3 +32,26 @@ func (localFS) Create(name string) (io.WriteCloser, error) { return os.Create(fi
 
 // Open implements fileSystem interface. Same as os.Open.
 func (localFS) Open(name string) (io.ReadCloser, error) { return os.Open(filepath.Clean(name)) }
+
+// CreateTemp implements fileSystem interface. Same as os.CreateTemp, except that
+// the file is readable by everyone like the files created by os.Create.
+func (localFS) CreateTemp(dir, pattern string) (io.WriteCloser, string, error) {
+	f, err := os.CreateTemp(filepath.Clean(dir), pattern)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := f.Chmod(0o644); err != nil {
+		f.Close()           //nolint
+		os.Remove(f.Name()) //nolint
+		return nil, "", err
+	}
+	return f, f.Name(), nil
+}
+
+// Rename implements fileSystem interface. Same as os.Rename.
+func (localFS) Rename(oldpath, newpath string) error {
+	return os.Rename(filepath.Clean(oldpath), filepath.Clean(newpath))
+}
+
+// Remove implements fileSystem interface. Same as os.Remove.
+func (localFS) Remove(name string) error { return os.Remove(filepath.Clean(name)) }
@@ -1,18 +1,23 @@
 package imaging
 
 import (
+	"bufio"
+	"bytes"
 	"errors"
 	"fmt"
 	"image"
+	"image/color"
 	"image/draw"
 	"image/gif"
 	"image/jpeg"
 	"image/png"
 	"io"
+	iofs "io/fs"
+	"math"
 	"path/filepath"
+	"sort"
 	"strings"
 
-	"golang.org/x/image/bmp"
 	"golang.org/x/image/tiff"
 	"golang.org/x/sync/errgroup"
 )
@@ -28,7 +33,7 @@ import (
 //	// Load an image and transform it depending on the EXIF orientation tag (if present).
 //	img, err := imaging.Open("test.jpg", imaging.AutoOrientation(true))
 func Open(filename string, opts ...DecodeOption) (img image.Image, err error) {
-	file, err := fs.Open(filename)
+	file, err := openFile(filename)
 	if err != nil {
 		return nil, err
 	}
@@ -41,18 +46,98 @@ func Open(filename string, opts ...DecodeOption) (img image.Image, err error) {
 			}
 		}
 	}()
+	if decode := customDecoder(filename); decode != nil {
+		return decodeCustom(file, decode, opts)
+	}
+	return Decode(file, opts...)
+}
+
+// OpenFS loads an image from the file system fsys, e.g. an embed.FS or
+// a zip.Reader. It accepts the same options as Open.
+//
+// Example:
+//
+//	//go:embed assets
+//	var assets embed.FS
+//
+//	img, err := imaging.OpenFS(assets, "assets/logo.png")
+func OpenFS(fsys iofs.FS, name string, opts ...DecodeOption) (img image.Image, err error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			if err == nil {
+				err = closeErr
+			} else {
+				err = fmt.Errorf("original error: %s, defer close error: %w", err.Error(), closeErr)
+			}
+		}
+	}()
+	if decode := customDecoder(name); decode != nil {
+		return decodeCustom(file, decode, opts)
+	}
 	return Decode(file, opts...)
 }
 
+// OpenAll loads all images from file. For multi-page TIFF files every page is
+// decoded, other files result in a single image.
+//
+// Example:
+//
+//	pages, err := imaging.OpenAll("scan.tif")
+func OpenAll(filename string, opts ...DecodeOption) (imgs []image.Image, err error) {
+	file, err := openFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			if err == nil {
+				err = closeErr
+			} else {
+				err = fmt.Errorf("original error: %s, defer close error: %w", err.Error(), closeErr)
+			}
+		}
+	}()
+	if decode := customDecoder(filename); decode != nil {
+		img, err := decodeCustom(file, decode, opts)
+		if err != nil {
+			return nil, err
+		}
+		return []image.Image{img}, nil
+	}
+	return DecodeAll(file, opts...)
+}
+
 // decodeConfig holds the optional parameters for the Decode().
 type decodeConfig struct {
 	// autoOrientation enables or disables the auto-orientation mode.
 	autoOrientation bool
+	// preserve16Bit enables or disables keeping 16 bits per color channel.
+	preserve16Bit bool
+	// maxWidth is the maximum image width in pixels. Zero means no limit.
+	maxWidth int
+	// maxHeight is the maximum image height in pixels. Zero means no limit.
+	maxHeight int
+	// maxBytes is the maximum estimated size of the decoded image in bytes. Zero means no limit.
+	maxBytes int64
+	// maxDownloadSize is the maximum size of the data fetched by OpenURL in bytes.
+	maxDownloadSize int64
+	// convertToSRGB converts the pixels to sRGB using the embedded ICC profile.
+	convertToSRGB bool
 }
 
 // defaultDecodeConfig is the default decode config.
 var defaultDecodeConfig = decodeConfig{
 	autoOrientation: false,
+	preserve16Bit:   false,
+	maxWidth:        0,
+	maxHeight:       0,
+	maxBytes:        0,
+	maxDownloadSize: 32 << 20,
+	convertToSRGB:   false,
 }
 
 // DecodeOption sets an optional parameter for the Decode and Open functions.
@@ -67,22 +152,135 @@ func AutoOrientation(enabled bool) DecodeOption {
 	}
 }
 
-// Decode reads an image from io.Reader.
+// Preserve16Bit returns a DecodeOption that enables keeping 16 bits per color channel.
+// If enabled, images decoded from 16-bit sources (e.g. 16-bit PNG or TIFF) are returned
+// as *image.NRGBA64, and the auto-orientation transform keeps their full precision.
+// Such images can be processed with the 16-bit functions (Clone64, Crop64, Resize64)
+// and saved as PNG or TIFF without losing precision. By default it's disabled.
+func Preserve16Bit(enabled bool) DecodeOption {
+	return func(c *decodeConfig) {
+		c.preserve16Bit = enabled
+	}
+}
+
+// Limits returns a DecodeOption that sets the maximum dimensions and the maximum
+// estimated memory size (in bytes) of the decoded image. The image header is checked
+// before the pixel data is decoded, and images that exceed the limits are rejected
+// with ErrLimitExceeded. Zero values mean no limit. By default there are no limits.
+//
+// Example:
+//
+//	// Reject uploads larger than 8000x8000 pixels or 256 MiB when decoded.
+//	img, err := imaging.Decode(r, imaging.Limits(8000, 8000, 256<<20))
+func Limits(maxWidth, maxHeight int, maxBytes int64) DecodeOption {
+	return func(c *decodeConfig) {
+		c.maxWidth = maxWidth
+		c.maxHeight = maxHeight
+		c.maxBytes = maxBytes
+	}
+}
+
+// MaxDownloadSize returns a DecodeOption that sets the maximum size (in bytes) of
+// the encoded image fetched by OpenURL. Larger responses are rejected with
+// ErrLimitExceeded. Zero means no limit. Default is 32 MiB.
+func MaxDownloadSize(n int64) DecodeOption {
+	return func(c *decodeConfig) {
+		c.maxDownloadSize = n
+	}
+}
+
+// Decode reads an image from io.Reader. TIFF and BigTIFF images are read through
+// io.ReaderAt if r implements it, as *os.File and *bytes.Reader do, so only the first
+// page is read from large and multi-page files. From other readers, and with the
+// ConvertToSRGB option, the TIFF data is read whole into memory.
 func Decode(r io.Reader, opts ...DecodeOption) (image.Image, error) {
 	cfg := defaultDecodeConfig
 	for _, option := range opts {
 		option(&cfg)
 	}
 
+	if cfg.convertToSRGB {
+		return decodeToSRGB(r, opts)
+	}
+
+	if ra, ok := tiffReaderAt(r); ok {
+		imgs, err := decodeTIFFPages(ra, cfg, false)
+		if err != nil {
+			return nil, err
+		}
+		return imgs[0], nil
+	}
+
+	if cfg.hasLimits() {
+		var err error
+		if r, err = checkLimits(r, cfg); err != nil {
+			return nil, err
+		}
+	}
+
 	if !cfg.autoOrientation {
-		img, _, err := image.Decode(r)
-		return img, err
+		img, err := decodeImage(r)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.preserve16Bit && is16Bit(img) {
+			return toNRGBA64(img), nil
+		}
+		return img, nil
+	}
+	return decodeWithAutoOrientation(r, cfg)
+}
+
+// decodeImage decodes an image in any of the registered formats. 32-bit BMP
+// images are decoded by decodeBMP to keep the alpha channel, TIFF images by
+// decodeTIFFPages to read BigTIFF and the edge tiles the tiff package gets wrong.
+func decodeImage(r io.Reader) (image.Image, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(8); err == nil && isTIFF(magic) {
+		data, err := io.ReadAll(br)
+		if err != nil {
+			return nil, err
+		}
+		imgs, err := decodeTIFFPages(tiffBytes(data), decodeConfig{}, false)
+		if err != nil {
+			return nil, err
+		}
+		return imgs[0], nil
+	}
+	if magic, err := br.Peek(2); err == nil && string(magic) == "BM" {
+		return decodeBMP(br)
+	}
+	img, _, err := image.Decode(br)
+	return img, err
+}
+
+// DecodeAll reads all images from io.Reader. For multi-page TIFF data every page
+// is decoded, other formats result in a single image. Unless r implements io.ReaderAt,
+// the data is read whole into memory.
+func DecodeAll(r io.Reader, opts ...DecodeOption) ([]image.Image, error) {
+	cfg := defaultDecodeConfig
+	for _, option := range opts {
+		option(&cfg)
+	}
+	if ra, ok := tiffReaderAt(r); ok {
+		return decodeTIFFPages(ra, cfg, true)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if isTIFF(data) {
+		return decodeTIFFPages(tiffBytes(data), cfg, true)
+	}
+	img, err := Decode(bytes.NewReader(data), opts...)
+	if err != nil {
+		return nil, err
 	}
-	return decodeWithAutoOrientation(r)
+	return []image.Image{img}, nil
 }
 
 // decodeWithAutoOrientation reads an image from io.Reader and automatically orientates it.
-func decodeWithAutoOrientation(r io.Reader) (image.Image, error) {
+func decodeWithAutoOrientation(r io.Reader, cfg decodeConfig) (image.Image, error) {
 	var orient Orientation
 
 	pr, pw := io.Pipe()
@@ -97,7 +295,7 @@ func decodeWithAutoOrientation(r io.Reader) (image.Image, error) {
 		return nil
 	})
 
-	img, _, err := image.Decode(r)
+	img, err := decodeImage(r)
 	if err != nil {
 		return nil, err
 	}
@@ -109,6 +307,9 @@ func decodeWithAutoOrientation(r io.Reader) (image.Image, error) {
 	if err = eg.Wait(); err != nil {
 		return nil, err
 	}
+	if cfg.preserve16Bit && is16Bit(img) {
+		return fixOrientation64(img, orient), nil
+	}
 	return FixOrientation(img, orient), nil
 }
 
@@ -136,6 +337,9 @@ const (
 	// BMP (Bitmap): A basic image format that stores pixel data without compression.
 	// It is widely supported but results in larger file sizes compared to compressed formats.
 	BMP
+	// PDF (Portable Document Format): A document format, the images are written as its
+	// pages, e.g. to turn scanned pages into a document. PDF files can only be encoded.
+	PDF
 )
 
 // formatExts maps image format extensions to Format.
@@ -147,6 +351,7 @@ var formatExts = map[string]Format{
 	"tif":  TIFF,
 	"tiff": TIFF,
 	"bmp":  BMP,
+	"pdf":  PDF,
 }
 
 // formatNames maps image formats to their names.
@@ -156,27 +361,49 @@ var formatNames = map[Format]string{
 	GIF:  "GIF",
 	TIFF: "TIFF",
 	BMP:  "BMP",
+	PDF:  "PDF",
 }
 
 // String returns the name of the image format.
 func (f Format) String() string {
-	return formatNames[f]
+	if name, ok := formatNames[f]; ok {
+		return name
+	}
+	cf, _ := lookupCustomFormat(f)
+	return cf.name
 }
 
 // ErrUnsupportedFormat means the given image format is not supported.
 var ErrUnsupportedFormat = errors.New("imaging: unsupported image format")
 
 // FormatFromExtension parses image format from filename extension:
-// "jpg" (or "jpeg"), "png", "gif", "tif" (or "tiff") and "bmp" are supported.
+// "jpg" (or "jpeg"), "png", "gif", "tif" (or "tiff"), "bmp", "pdf" and the extensions
+// registered with RegisterFormat are supported.
 func FormatFromExtension(ext string) (Format, error) {
-	if f, ok := formatExts[strings.ToLower(strings.TrimPrefix(ext, "."))]; ok {
+	ext = strings.ToLower(strings.TrimPrefix(ext, "."))
+	if f, ok := formatExts[ext]; ok {
+		return f, nil
+	}
+	if f, ok := customFormatFromExtension(ext); ok {
 		return f, nil
 	}
 	return -1, ErrUnsupportedFormat
 }
 
+// FormatExtensions returns the sorted lowercase extensions (without the dot) of all the
+// supported image formats, including the formats registered with RegisterFormat.
+func FormatExtensions() []string {
+	exts := customFormatExtensions()
+	for ext := range formatExts {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	return exts
+}
+
 // FormatFromFilename parses image format from filename:
-// "jpg" (or "jpeg"), "png", "gif", "tif" (or "tiff") and "bmp" are supported.
+// "jpg" (or "jpeg"), "png", "gif", "tif" (or "tiff"), "bmp", "pdf" and the extensions
+// registered with RegisterFormat are supported.
 func FormatFromFilename(filename string) (Format, error) {
 	ext := filepath.Ext(filename)
 	return FormatFromExtension(ext)
@@ -186,23 +413,137 @@ func FormatFromFilename(filename string) (Format, error) {
 type encodeConfig struct {
 	// jpegQuality JPEG quality (1-100). Default is 95.
 	jpegQuality int
+	// jpegMinQuality the lowest JPEG quality used by EncodeTargetSize (1-100). Default is 1.
+	jpegMinQuality int
 	// gifNumColors GIF encoder number of colors (1-256). Default is 256.
 	gifNumColors int
 	// gifQuantizer GIF encoder quantizer. Default is nil (use the default quantizer).
 	gifQuantizer draw.Quantizer
 	// gifDrawer GIF encoder drawer. Default is nil (use the default drawer).
 	gifDrawer draw.Drawer
-	// pngCompressionLevel PNG compression level (1-9). Default is DefaultCompression.
+	// gifInterlaced enables the interlaced GIF output. Default is false.
+	gifInterlaced bool
+	// gifTransparentColor GIF encoder transparent color. Default is nil (no transparent color).
+	gifTransparentColor color.Color
+	// pngCompressionLevel PNG compression level. Default is DefaultCompression.
 	pngCompressionLevel png.CompressionLevel
+	// tiffCompression TIFF compression type. Default is tiff.Deflate.
+	tiffCompression tiff.CompressionType
+	// tiffPredictor enables the TIFF horizontal differencing predictor. Default is false.
+	tiffPredictor bool
+	// tiffTileWidth and tiffTileHeight TIFF tile size. Default is 0 (use strips).
+	tiffTileWidth, tiffTileHeight int
+	// tiffBigTIFF enables the BigTIFF output. Default is false (BigTIFF only if needed).
+	tiffBigTIFF bool
+	// metadata is the metadata written to the output. Default is nil (no metadata).
+	metadata *Metadata
+	// exif replaces the EXIF data of the metadata. Default is nil (use the metadata).
+	exif *EXIF
+	// artist and copyright are set in the EXIF data written. Default is "" (unchanged).
+	artist, copyright string
+	// pdfPageSize PDF page size. Default is the zero PageSize (the size of each image).
+	pdfPageSize PageSize
+	// c2pa is the C2PA manifest attached to the output. Default is nil (no manifest).
+	c2pa *c2paOutput
+	// deterministic removes the timestamps and the random identifiers of the output.
+	// Default is false.
+	deterministic bool
 }
 
 // defaultEncodeConfig is the default encoding configuration.
 var defaultEncodeConfig = encodeConfig{
 	jpegQuality:         95,
+	jpegMinQuality:      1,
 	gifNumColors:        256,
 	gifQuantizer:        nil,
 	gifDrawer:           nil,
+	gifInterlaced:       false,
+	gifTransparentColor: nil,
 	pngCompressionLevel: png.DefaultCompression,
+	tiffCompression:     tiff.Deflate,
+	tiffPredictor:       false,
+	tiffTileWidth:       0,
+	tiffTileHeight:      0,
+	tiffBigTIFF:         false,
+	metadata:            nil,
+	exif:                nil,
+	pdfPageSize:         PageSize{},
+}
+
+// ErrInvalidEncodeOption means an EncodeOption is out of its range or conflicts
+// with another option for the output format.
+var ErrInvalidEncodeOption = errors.New("imaging: invalid encode option")
+
+// newEncodeConfig applies the options to the default encoding configuration and
+// validates the options used by the format.
+func newEncodeConfig(format Format, opts []EncodeOption) (encodeConfig, error) {
+	cfg := defaultEncodeConfig
+	for _, option := range opts {
+		option(&cfg)
+	}
+	return cfg, cfg.validate(format)
+}
+
+// validate checks the options used by the format, so the invalid ones are reported
+// before anything is written instead of being clamped or failing inside the encoders.
+func (c *encodeConfig) validate(format Format) error {
+	switch format {
+	case JPEG:
+		if c.jpegQuality < 1 || c.jpegQuality > 100 {
+			return fmt.Errorf("%w: JPEG quality %d is out of the range 1-100", ErrInvalidEncodeOption, c.jpegQuality)
+		}
+		if c.jpegMinQuality < 1 || c.jpegMinQuality > 100 {
+			return fmt.Errorf("%w: JPEG minimum quality %d is out of the range 1-100", ErrInvalidEncodeOption, c.jpegMinQuality)
+		}
+
+	case PNG:
+		switch c.pngCompressionLevel {
+		case png.DefaultCompression, png.NoCompression, png.BestSpeed, png.BestCompression:
+		default:
+			return fmt.Errorf("%w: unknown PNG compression level %d", ErrInvalidEncodeOption, c.pngCompressionLevel)
+		}
+
+	case GIF:
+		if c.gifNumColors < 1 || c.gifNumColors > 256 {
+			return fmt.Errorf("%w: GIF number of colors %d is out of the range 1-256", ErrInvalidEncodeOption, c.gifNumColors)
+		}
+
+	case TIFF:
+		switch c.tiffCompression {
+		case tiff.Uncompressed, tiff.Deflate, tiff.LZW:
+		default:
+			return fmt.Errorf("%w: %w %d", ErrInvalidEncodeOption, ErrUnsupportedTIFFCompression, c.tiffCompression)
+		}
+		tiled := c.tiffTileWidth != 0 || c.tiffTileHeight != 0
+		if tiled && (c.tiffTileWidth <= 0 || c.tiffTileHeight <= 0 || c.tiffTileWidth%16 != 0 || c.tiffTileHeight%16 != 0) {
+			return fmt.Errorf("%w: %w %dx%d, the width and the height must be positive multiples of 16",
+				ErrInvalidEncodeOption, ErrInvalidTIFFTileSize, c.tiffTileWidth, c.tiffTileHeight)
+		}
+
+	case PDF:
+		w, h := c.pdfPageSize.Width, c.pdfPageSize.Height
+		if (w != 0 || h != 0) && !(w > 0 && h > 0 && !math.IsInf(w, 0) && !math.IsInf(h, 0)) {
+			return fmt.Errorf("%w: PDF page size %gx%g, the width and the height must be positive", ErrInvalidEncodeOption, w, h)
+		}
+	}
+	return nil
+}
+
+// ValidateEncodeOptions checks the options for encoding images in the format and returns
+// an error wrapping ErrInvalidEncodeOption that describes the first invalid option,
+// e.g. a JPEG quality out of the range 1-100 or a GIF number of colors out of the range
+// 1-256. Only the options used by the format are checked. Encode and the other encoding
+// functions validate their options the same way before writing anything, so it is
+// only needed to check the options early, e.g. the values of command line flags.
+//
+// Example:
+//
+//	if err := imaging.ValidateEncodeOptions(imaging.JPEG, imaging.JPEGQuality(quality)); err != nil {
+//		return err
+//	}
+func ValidateEncodeOptions(format Format, opts ...EncodeOption) error {
+	_, err := newEncodeConfig(format, opts)
+	return err
 }
 
 // EncodeOption sets an optional parameter for the Encode and Save functions.
@@ -216,6 +557,14 @@ func JPEGQuality(quality int) EncodeOption {
 	}
 }
 
+// JPEGMinQuality returns an EncodeOption that sets the lowest JPEG quality
+// EncodeTargetSize may use to fit the output into the size budget. Default is 1.
+func JPEGMinQuality(quality int) EncodeOption {
+	return func(c *encodeConfig) {
+		c.jpegMinQuality = quality
+	}
+}
+
 // GIFNumColors returns an EncodeOption that sets the maximum number of colors
 // used in the GIF-encoded image. It ranges from 1 to 256.  Default is 256.
 func GIFNumColors(numColors int) EncodeOption {
@@ -240,6 +589,23 @@ func GIFDrawer(drawer draw.Drawer) EncodeOption {
 	}
 }
 
+// GIFInterlaced returns an EncodeOption that enables the interlaced GIF output.
+// Interlaced images are displayed progressively while they are being loaded. Default is false.
+func GIFInterlaced(enabled bool) EncodeOption {
+	return func(c *encodeConfig) {
+		c.gifInterlaced = enabled
+	}
+}
+
+// GIFTransparentColor returns an EncodeOption that sets the color that is encoded
+// as transparent in the GIF-encoded image. It is used for the animation frames
+// that do not specify their own transparent color. Default is nil (no transparent color).
+func GIFTransparentColor(c color.Color) EncodeOption {
+	return func(cfg *encodeConfig) {
+		cfg.gifTransparentColor = c
+	}
+}
+
 // PNGCompressionLevel returns an EncodeOption that sets the compression level
 // of the PNG-encoded image. Default is png.DefaultCompression.
 func PNGCompressionLevel(level png.CompressionLevel) EncodeOption {
@@ -248,30 +614,173 @@ func PNGCompressionLevel(level png.CompressionLevel) EncodeOption {
 	}
 }
 
-// Encode writes the image img to w in the specified format (JPEG, PNG, GIF, TIFF or BMP).
+// TIFFCompression returns an EncodeOption that sets the compression type
+// of the TIFF-encoded image. Supported types are tiff.Uncompressed, tiff.Deflate
+// and tiff.LZW. Default is tiff.Deflate.
+func TIFFCompression(compression tiff.CompressionType) EncodeOption {
+	return func(c *encodeConfig) {
+		c.tiffCompression = compression
+	}
+}
+
+// TIFFPredictor returns an EncodeOption that enables or disables the horizontal
+// differencing predictor of the TIFF-encoded image. The predictor stores the
+// difference to the preceding pixel instead of each pixel's color, which usually
+// reduces the size of photos compressed with tiff.LZW or tiff.Deflate. Default is false.
+func TIFFPredictor(enabled bool) EncodeOption {
+	return func(c *encodeConfig) {
+		c.tiffPredictor = enabled
+	}
+}
+
+// TIFFTileSize returns an EncodeOption that makes the TIFF encoder store the image
+// in tiles of the given size instead of strips. Tiled images allow readers, such as
+// DecodeTIFFRegion, to access regions of large images efficiently. The tile width and height must be multiples of 16.
+// Default is 0x0 (no tiling).
+func TIFFTileSize(width, height int) EncodeOption {
+	return func(c *encodeConfig) {
+		c.tiffTileWidth = width
+		c.tiffTileHeight = height
+	}
+}
+
+// TIFFBigTIFF returns an EncodeOption that makes the TIFF encoder write BigTIFF,
+// the TIFF variant with 64-bit offsets. Images too large for the 4 GB offsets of
+// TIFF are written as BigTIFF regardless of the option. Default is false.
+func TIFFBigTIFF(enabled bool) EncodeOption {
+	return func(c *encodeConfig) {
+		c.tiffBigTIFF = enabled
+	}
+}
+
+// WithMetadata returns an EncodeOption that writes the metadata to the output.
+// The EXIF data is written to JPEG and TIFF images, the ICC profile to JPEG, PNG
+// and TIFF images and the GeoTIFF tags to TIFF images, other formats ignore the metadata.
+func WithMetadata(m *Metadata) EncodeOption {
+	return func(c *encodeConfig) {
+		c.metadata = m
+	}
+}
+
+// WithEXIF returns an EncodeOption that writes the EXIF data to JPEG and TIFF images.
+// It takes precedence over the EXIF data of WithMetadata, so the tags of a decoded
+// image can be edited or removed before saving:
+//
+//	img, meta, err := imaging.OpenWithMetadata("upload.jpg", imaging.AutoOrientation(true))
+//	...
+//	exif := meta.EXIF.StripGPS().SetCopyright("(c) Example Inc.")
+//	err = imaging.Save(img, "out.jpg", imaging.WithMetadata(meta), imaging.WithEXIF(exif))
+//
+// EXIF data without any tags (e.g. NewEXIF()) writes no EXIF data at all.
+func WithEXIF(e *EXIF) EncodeOption {
+	return func(c *encodeConfig) {
+		c.exif = e
+	}
+}
+
+// WithAuthorship returns an EncodeOption that sets the artist and the copyright notice
+// in the EXIF data written to JPEG and TIFF images, on top of the EXIF data of
+// WithMetadata or WithEXIF, if any. An empty string leaves the field as it is, so that
+// ownership can be stamped during a batch export:
+//
+//	err := imaging.Save(img, "out.jpg", imaging.WithMetadata(meta),
+//		imaging.WithAuthorship("Jane Doe", "(c) 2024 Example Studio"))
+func WithAuthorship(artist, copyright string) EncodeOption {
+	return func(c *encodeConfig) {
+		c.artist = artist
+		c.copyright = copyright
+	}
+}
+
+// DeterministicEncode returns an EncodeOption that makes the output depend on the
+// image and the options only, so that encoding the same image with the same options
+// always gives the same bytes, e.g. for content-addressed storage. The built-in
+// encoders don't write the time of encoding anyway, and with the option:
+//
+//   - the date of the last change (DateTime) is removed from the EXIF data, while
+//     the dates the picture was taken are kept;
+//   - the identifiers of the C2PA manifests are derived from the hash of the image
+//     data instead of being random, and the times of the actions are removed.
+//
+// The ECDSA and RSA signatures of the C2PA manifests are randomized, so only Ed25519
+// keys give the same signed bytes. The formats registered with RegisterFormat are
+// encoded as their encoders do.
+func DeterministicEncode(enabled bool) EncodeOption {
+	return func(c *encodeConfig) {
+		c.deterministic = enabled
+	}
+}
+
+// outputMetadata returns the metadata to write, with the EXIF data replaced by WithEXIF,
+// the authorship of WithAuthorship set and the date of the last change removed by
+// DeterministicEncode. It returns nil if there is no metadata.
+func (c *encodeConfig) outputMetadata() *Metadata {
+	exif := c.exif
+	if c.artist != "" || c.copyright != "" || c.deterministic {
+		if exif == nil && c.metadata != nil {
+			exif = c.metadata.EXIF
+		}
+		if c.artist != "" {
+			exif = exif.SetArtist(c.artist)
+		}
+		if c.copyright != "" {
+			exif = exif.SetCopyright(c.copyright)
+		}
+		if c.deterministic {
+			exif = exif.withoutModifyDate()
+		}
+	}
+	if exif == nil {
+		return c.metadata
+	}
+	m := &Metadata{EXIF: exif}
+	if c.metadata != nil {
+		m.GeoTIFF = c.metadata.GeoTIFF
+		m.ICCProfile = c.metadata.ICCProfile
+	}
+	return m
+}
+
+// Encode writes the image img to w in the specified format (JPEG, PNG, GIF, TIFF, BMP,
+// PDF or a format registered with RegisterFormat). The options are validated first,
+// invalid ones return an error wrapping ErrInvalidEncodeOption.
 func Encode(w io.Writer, img image.Image, format Format, opts ...EncodeOption) error {
-	cfg := defaultEncodeConfig
-	for _, option := range opts {
-		option(&cfg)
+	cfg, err := newEncodeConfig(format, opts)
+	if err != nil {
+		return err
+	}
+
+	if cfg.c2pa != nil && (format == JPEG || format == PNG) {
+		buf := &bytes.Buffer{}
+		withoutC2PA := func(c *encodeConfig) { c.c2pa = nil }
+		if err := Encode(buf, img, format, append(opts, withoutC2PA)...); err != nil {
+			return err
+		}
+		return embedC2PA(w, buf.Bytes(), format, cfg.c2pa, cfg.deterministic)
 	}
 
 	switch format {
 	case JPEG:
-		if nrgba, ok := img.(*image.NRGBA); ok && nrgba.Opaque() {
-			rgba := &image.RGBA{
-				Pix:    nrgba.Pix,
-				Stride: nrgba.Stride,
-				Rect:   nrgba.Rect,
-			}
-			return jpeg.Encode(w, rgba, &jpeg.Options{Quality: cfg.jpegQuality})
+		if m := cfg.outputMetadata(); m != nil && (!m.EXIF.empty() || len(m.ICCProfile) > 0) {
+			return encodeJPEGWithMetadata(w, img, m, cfg.jpegQuality)
 		}
-		return jpeg.Encode(w, img, &jpeg.Options{Quality: cfg.jpegQuality})
+		return encodeJPEG(w, img, cfg.jpegQuality)
 
 	case PNG:
 		encoder := png.Encoder{CompressionLevel: cfg.pngCompressionLevel}
+		if m := cfg.outputMetadata(); m != nil && len(m.ICCProfile) > 0 {
+			buf := &bytes.Buffer{}
+			if err := encoder.Encode(buf, img); err != nil {
+				return err
+			}
+			return insertPNGICC(w, buf.Bytes(), m.ICCProfile)
+		}
 		return encoder.Encode(w, img)
 
 	case GIF:
+		if cfg.gifInterlaced || cfg.gifTransparentColor != nil {
+			return encodeGIF(w, &Animation{Frames: []Frame{{Image: img}}}, &cfg)
+		}
 		return gif.Encode(w, img, &gif.Options{
 			NumColors: cfg.gifNumColors,
 			Quantizer: cfg.gifQuantizer,
@@ -279,18 +788,37 @@ func Encode(w io.Writer, img image.Image, format Format, opts ...EncodeOption) e
 		})
 
 	case TIFF:
-		return tiff.Encode(w, img, &tiff.Options{Compression: tiff.Deflate, Predictor: true})
+		return encodeTIFF(w, img, &cfg)
 
 	case BMP:
-		return bmp.Encode(w, img)
+		return encodeBMP(w, img)
+
+	case PDF:
+		return encodePDF(w, []image.Image{img}, &cfg)
 	}
 
+	if cf, ok := lookupCustomFormat(format); ok && cf.encode != nil {
+		return cf.encode(w, img)
+	}
 	return ErrUnsupportedFormat
 }
 
+// encodeJPEG writes the image to w in JPEG format with the given quality.
+func encodeJPEG(w io.Writer, img image.Image, quality int) error {
+	if nrgba, ok := img.(*image.NRGBA); ok && nrgba.Opaque() {
+		rgba := &image.RGBA{
+			Pix:    nrgba.Pix,
+			Stride: nrgba.Stride,
+			Rect:   nrgba.Rect,
+		}
+		return jpeg.Encode(w, rgba, &jpeg.Options{Quality: quality})
+	}
+	return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+}
+
 // Save saves the image to file with the specified filename.
 // The format is determined from the filename extension:
-// "jpg" (or "jpeg"), "png", "gif", "tif" (or "tiff") and "bmp" are supported.
+// "jpg" (or "jpeg"), "png", "gif", "tif" (or "tiff"), "bmp" and "pdf" are supported.
 //
 // Examples:
 //
@@ -304,7 +832,10 @@ func Save(img image.Image, filename string, opts ...EncodeOption) (err error) {
 	if err != nil {
 		return err
 	}
-	file, err := fs.Create(filename)
+	if err = ValidateEncodeOptions(f, opts...); err != nil {
+		return err
+	}
+	file, err := createFile(filename)
 	if err != nil {
 		return err
 	}
@@ -317,6 +848,118 @@ func Save(img image.Image, filename string, opts ...EncodeOption) (err error) {
 	return err
 }
 
+// SaveAtomic saves the image to file like Save, but the image is first written
+// to a temporary file in the same directory, which is renamed to filename once
+// the image is fully written. If encoding or writing fails, the temporary file is
+// removed and an existing file with the same name is left untouched, so readers
+// never see a partially written image. Files in a storage registered with
+// RegisterStorage are written directly, as the storage only exposes them once
+// they are complete.
+//
+// Example:
+//
+//	err := imaging.SaveAtomic(img, "/var/www/thumbs/photo.jpg", imaging.JPEGQuality(85))
+func SaveAtomic(img image.Image, filename string, opts ...EncodeOption) error {
+	if _, ok := lookupStorage(filename); ok {
+		return Save(img, filename, opts...)
+	}
+	f, err := FormatFromFilename(filename)
+	if err != nil {
+		return err
+	}
+	return writeAtomic(filename, func(w io.Writer) error {
+		return Encode(w, img, f, opts...)
+	})
+}
+
+// writeAtomic writes the local file with write into a temporary file of the same
+// directory, which replaces the file once it is written completely.
+func writeAtomic(filename string, write func(io.Writer) error) (err error) {
+	file, tmpName, err := fs.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == nil {
+			return
+		}
+		if removeErr := fs.Remove(tmpName); removeErr != nil {
+			err = fmt.Errorf("original error: %s, remove temporary file error: %w", err.Error(), removeErr)
+		}
+	}()
+
+	err = write(file)
+	if s, ok := file.(interface{ Sync() error }); ok && err == nil {
+		// Flush the data to the disk before the file replaces the target.
+		err = s.Sync()
+	}
+	errClose := file.Close()
+	if err == nil {
+		err = errClose
+	}
+	if err != nil {
+		return err
+	}
+	return fs.Rename(tmpName, filename)
+}
+
+// ErrNoImages means an empty list of images was passed to EncodeAll or SaveAll.
+var ErrNoImages = errors.New("imaging: no images")
+
+// EncodeAll writes the images to w in the specified format. TIFF and PDF support
+// multiple images which are written as pages of a multi-page TIFF or of a PDF
+// document, for other formats exactly one image must be given.
+func EncodeAll(w io.Writer, imgs []image.Image, format Format, opts ...EncodeOption) error {
+	if len(imgs) == 0 {
+		return ErrNoImages
+	}
+	if format == TIFF || format == PDF {
+		cfg, err := newEncodeConfig(format, opts)
+		if err != nil {
+			return err
+		}
+		if format == TIFF {
+			return encodeTIFFPages(w, imgs, &cfg)
+		}
+		return encodePDF(w, imgs, &cfg)
+	}
+	if len(imgs) > 1 {
+		return fmt.Errorf("%w: multiple images can not be encoded as %s", ErrUnsupportedFormat, format)
+	}
+	return Encode(w, imgs[0], format, opts...)
+}
+
+// SaveAll saves the images to file with the specified filename. The format is
+// determined from the filename extension as in Save. Multiple images can only
+// be saved as a multi-page TIFF or a PDF document.
+//
+// Examples:
+//
+//	err := imaging.SaveAll([]image.Image{page1, page2}, "out.tif")
+//
+//	// Save the scanned pages as an A4 PDF document.
+//	err := imaging.SaveAll(pages, "out.pdf", imaging.PDFPageSize(imaging.A4))
+func SaveAll(imgs []image.Image, filename string, opts ...EncodeOption) (err error) {
+	f, err := FormatFromFilename(filename)
+	if err != nil {
+		return err
+	}
+	if err = ValidateEncodeOptions(f, opts...); err != nil {
+		return err
+	}
+	file, err := createFile(filename)
+	if err != nil {
+		return err
+	}
+
+	err = EncodeAll(file, imgs, f, opts...)
+	errClose := file.Close()
+	if err == nil {
+		err = errClose
+	}
+	return err
+}
+
 // Orientation is an EXIF flag that specifies the transformation
 // that should be applied to image to display it correctly.
 type Orientation int
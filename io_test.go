@@ -2,6 +2,8 @@ package imaging
 
 import (
 	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
 	"errors"
 	"image"
 	"image/color"
@@ -9,16 +11,22 @@ import (
 	"image/draw"
 	"image/png"
 	"io"
+	iofs "io/fs"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/go-spectest/imaging/storage/memfs"
 )
 
 var (
 	errCreate = errors.New("failed to create file")
 	errClose  = errors.New("failed to close file")
 	errOpen   = errors.New("failed to open file")
+	errRemove = errors.New("failed to remove file")
 )
 
 type badFS struct{}
@@ -34,33 +42,26 @@ func (badFS) Open(_ string) (io.ReadCloser, error) {
 	return nil, errOpen
 }
 
-type badFile struct {
-	io.Writer
-}
-
-func (badFile) Close() error {
-	return errClose
-}
-
-type closeErrorFS struct{}
-
-func (closeErrorFS) Open(_ string) (io.ReadCloser, error) {
-	return closeErrorFile{}, nil
+func (badFS) CreateTemp(_, pattern string) (io.WriteCloser, string, error) {
+	if strings.HasPrefix(pattern, ".badFile.jpg") {
+		return badFile{io.Discard}, "badFile.tmp", nil
+	}
+	return nil, "", errCreate
 }
 
-func (closeErrorFS) Create(_ string) (io.WriteCloser, error) {
-	return nil, errors.New("this method should not be called")
+func (badFS) Rename(_, _ string) error {
+	return errors.New("this method should not be called")
 }
 
-type closeErrorFile struct {
-	io.ReadCloser
+func (badFS) Remove(_ string) error {
+	return errRemove
 }
 
-func (closeErrorFile) Read(_ []byte) (int, error) {
-	return 0, errors.New("read error")
+type badFile struct {
+	io.Writer
 }
 
-func (closeErrorFile) Close() error {
+func (badFile) Close() error {
 	return errClose
 }
 
@@ -177,38 +178,234 @@ func TestOpenSave(t *testing.T) {
 			t.Fatalf("got %v want ErrUnsupportedFormat", err)
 		}
 
-		prevFS := fs
-		fs = badFS{}
-		defer func() { fs = prevFS }()
+		mem := memfs.New()
+		RegisterStorage("mem", mem)
+		defer RegisterStorage("mem", nil)
 
-		err = Save(imgWithAlpha, "test.jpg")
+		mem.Fail("mem://test.jpg", memfs.OpCreate, errCreate)
+		err = Save(imgWithAlpha, "mem://test.jpg")
 		if !errors.Is(err, errCreate) {
 			t.Fatalf("got error %v want errCreate", err)
 		}
 
-		err = Save(imgWithAlpha, "badFile.jpg")
+		mem.Fail("mem://badFile.jpg", memfs.OpClose, errClose)
+		err = Save(imgWithAlpha, "mem://badFile.jpg")
 		if !errors.Is(err, errClose) {
 			t.Fatalf("got error %v want errClose", err)
 		}
 
-		_, err = Open("test.jpg")
+		mem.Fail("mem://test.jpg", memfs.OpOpen, errOpen)
+		_, err = Open("mem://test.jpg")
 		if !errors.Is(err, errOpen) {
 			t.Fatalf("got error %v want errOpen", err)
 		}
 	})
 
 	t.Run("defered close error", func(t *testing.T) {
-		fs = closeErrorFS{}
-		defer func() { fs = localFS{} }()
-
-		_, got := Open("dummy")
-		want := "original error: image: unknown format, defer close error: failed to close file"
-		if got.Error() != want {
+		mem := memfs.New()
+		RegisterStorage("mem", mem)
+		defer RegisterStorage("mem", nil)
+		mem.WriteFile("mem://dummy", []byte("bad data"))
+		mem.Fail("mem://dummy", memfs.OpClose, errClose)
+
+		_, got := Open("mem://dummy")
+		want := "original error: image: unknown format, defer close error: close mem://dummy: failed to close file"
+		if got.Error() != want || !errors.Is(got, errClose) {
 			t.Errorf("got=%v want=%v", got, want)
 		}
 	})
 }
 
+func TestOpenFS(t *testing.T) {
+	t.Parallel()
+
+	img := New(5, 3, color.NRGBA{0x10, 0x20, 0x30, 0xff})
+	buf := &bytes.Buffer{}
+	if err := Encode(buf, img, PNG); err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	fsys := fstest.MapFS{
+		"images/img.png": {Data: buf.Bytes()},
+		"images/bad.png": {Data: []byte("bad data")},
+	}
+
+	got, err := OpenFS(fsys, "images/img.png")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !compareNRGBA(Clone(got), img, 0) {
+		t.Fatalf("got unexpected image")
+	}
+	if _, err := OpenFS(fsys, "images/bad.png"); !errors.Is(err, image.ErrFormat) {
+		t.Fatalf("got error %v want %v", err, image.ErrFormat)
+	}
+	if _, err := OpenFS(fsys, "missing.png"); !errors.Is(err, iofs.ErrNotExist) {
+		t.Fatalf("got error %v want %v", err, iofs.ErrNotExist)
+	}
+
+	got, err = OpenFS(os.DirFS("testdata"), "branches.png", AutoOrientation(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !compareNRGBA(Clone(got), Clone(testdataBranchesPNG), 0) {
+		t.Fatalf("got unexpected image from os.DirFS")
+	}
+}
+
+func TestOpenSaveAll(t *testing.T) {
+	dir, err := os.MkdirTemp("", "imaging")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir) //nolint
+
+	pages := []image.Image{
+		New(8, 6, color.NRGBA{0xff, 0x00, 0x00, 0xff}),
+		New(3, 5, color.NRGBA{0x00, 0x00, 0xff, 0x80}),
+	}
+	filename := filepath.Join(dir, "pages.tif")
+	if err := SaveAll(pages, filename); err != nil {
+		t.Fatalf("failed to save pages: %v", err)
+	}
+	got, err := OpenAll(filename)
+	if err != nil {
+		t.Fatalf("failed to open pages: %v", err)
+	}
+	if len(got) != len(pages) {
+		t.Fatalf("got %d pages want %d", len(got), len(pages))
+	}
+	for i := range pages {
+		if !compareNRGBA(Clone(got[i]), pages[i].(*image.NRGBA), 0) { //nolint
+			t.Fatalf("page %d differs", i)
+		}
+	}
+
+	if err := SaveAll(pages, filepath.Join(dir, "pages.png")); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("got error %v want ErrUnsupportedFormat", err)
+	}
+	if err := SaveAll(pages, filepath.Join(dir, "pages.unknown")); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("got error %v want ErrUnsupportedFormat", err)
+	}
+	if _, err := OpenAll(filepath.Join(dir, "missing.tif")); err == nil {
+		t.Fatalf("expected error opening a missing file")
+	}
+}
+
+func TestSaveAtomic(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "out.png")
+	img := New(4, 3, color.NRGBA{0x10, 0x20, 0x30, 0xff})
+	if err := SaveAtomic(img, filename); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+	got, err := Open(filename)
+	if err != nil {
+		t.Fatalf("failed to open: %v", err)
+	}
+	if !compareNRGBA(Clone(got), img, 0) {
+		t.Fatalf("saved image differs")
+	}
+
+	// A failed save keeps the existing file and leaves no temporary files behind.
+	if err := SaveAtomic(img, filepath.Join(dir, "out.tif"), TIFFCompression(100)); !errors.Is(err, ErrUnsupportedTIFFCompression) {
+		t.Fatalf("got error %v want %v", err, ErrUnsupportedTIFFCompression)
+	}
+	if err := SaveAtomic(&image.NRGBA{}, filename); err == nil {
+		t.Fatalf("expected error saving an empty image")
+	}
+	if got, err := Open(filename); err != nil || got.Bounds() != img.Bounds() {
+		t.Fatalf("existing file changed: %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read directory: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("got %d files in the directory want 1", len(entries))
+	}
+
+	if err := SaveAtomic(img, filepath.Join(dir, "out.unknown")); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("got error %v want ErrUnsupportedFormat", err)
+	}
+	if err := SaveAtomic(img, filepath.Join(dir, "missing", "out.png")); err == nil {
+		t.Fatalf("expected error saving to a missing directory")
+	}
+
+	prevFS := fs
+	fs = badFS{}
+	defer func() { fs = prevFS }()
+
+	if err := SaveAtomic(img, "test.jpg"); !errors.Is(err, errCreate) {
+		t.Fatalf("got error %v want errCreate", err)
+	}
+	err = SaveAtomic(img, "badFile.jpg")
+	if !errors.Is(err, errRemove) || !strings.Contains(err.Error(), errClose.Error()) {
+		t.Fatalf("got error %v want errClose and errRemove", err)
+	}
+}
+
+func TestValidateEncodeOptions(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		format Format
+		opts   []EncodeOption
+		want   error
+	}{
+		{"defaults", JPEG, nil, nil},
+		{"JPEG quality", JPEG, []EncodeOption{JPEGQuality(100), JPEGMinQuality(1)}, nil},
+		{"JPEG quality 0", JPEG, []EncodeOption{JPEGQuality(0)}, ErrInvalidEncodeOption},
+		{"JPEG quality 101", JPEG, []EncodeOption{JPEGQuality(101)}, ErrInvalidEncodeOption},
+		{"JPEG min quality", JPEG, []EncodeOption{JPEGMinQuality(-1)}, ErrInvalidEncodeOption},
+		{"JPEG quality for PNG", PNG, []EncodeOption{JPEGQuality(101)}, nil},
+		{"PNG compression", PNG, []EncodeOption{PNGCompressionLevel(png.BestCompression)}, nil},
+		{"PNG compression 9", PNG, []EncodeOption{PNGCompressionLevel(9)}, ErrInvalidEncodeOption},
+		{"GIF colors", GIF, []EncodeOption{GIFNumColors(1)}, nil},
+		{"GIF colors 0", GIF, []EncodeOption{GIFNumColors(0)}, ErrInvalidEncodeOption},
+		{"GIF colors 257", GIF, []EncodeOption{GIFNumColors(257)}, ErrInvalidEncodeOption},
+		{"TIFF compression", TIFF, []EncodeOption{TIFFCompression(100)}, ErrUnsupportedTIFFCompression},
+		{"TIFF tile size", TIFF, []EncodeOption{TIFFTileSize(16, 0)}, ErrInvalidTIFFTileSize},
+		{"PDF page size", PDF, []EncodeOption{PDFPageSize(A4)}, nil},
+		{"PDF page size negative", PDF, []EncodeOption{PDFPageSize(PageSize{-1, 100})}, ErrInvalidEncodeOption},
+		{"PDF page size zero width", PDF, []EncodeOption{PDFPageSize(PageSize{0, 100})}, ErrInvalidEncodeOption},
+	}
+	img := New(4, 4, color.White)
+	for _, tc := range testCases {
+		err := ValidateEncodeOptions(tc.format, tc.opts...)
+		if !errors.Is(err, tc.want) || (err == nil) != (tc.want == nil) {
+			t.Fatalf("%s: got error %v want %v", tc.name, err, tc.want)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidEncodeOption) {
+			t.Fatalf("%s: got error %v want %v", tc.name, err, ErrInvalidEncodeOption)
+		}
+		buf := &bytes.Buffer{}
+		if err := Encode(buf, img, tc.format, tc.opts...); !errors.Is(err, tc.want) {
+			t.Fatalf("%s: got error %v from Encode want %v", tc.name, err, tc.want)
+		}
+		if tc.want != nil && buf.Len() != 0 {
+			t.Fatalf("%s: got %d bytes written want 0", tc.name, buf.Len())
+		}
+	}
+
+	// Invalid options are reported before the file is created.
+	filename := filepath.Join(t.TempDir(), "out.gif")
+	if err := Save(img, filename, GIFNumColors(1000)); !errors.Is(err, ErrInvalidEncodeOption) {
+		t.Fatalf("got error %v want %v", err, ErrInvalidEncodeOption)
+	}
+	if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		t.Fatalf("got file %s, error %v want none", filename, err)
+	}
+	anim := &Animation{Frames: []Frame{{Image: img}}}
+	if err := EncodeAnimation(io.Discard, anim, GIFNumColors(0)); !errors.Is(err, ErrInvalidEncodeOption) {
+		t.Fatalf("got error %v want %v", err, ErrInvalidEncodeOption)
+	}
+	err := EncodeAll(io.Discard, []image.Image{img, img}, TIFF, TIFFTileSize(15, 15))
+	if !errors.Is(err, ErrInvalidTIFFTileSize) {
+		t.Fatalf("got error %v want %v", err, ErrInvalidTIFFTileSize)
+	}
+}
+
 func TestFormats(t *testing.T) {
 	t.Parallel()
 
@@ -218,6 +415,7 @@ func TestFormats(t *testing.T) {
 		GIF:        "GIF",
 		BMP:        "BMP",
 		TIFF:       "TIFF",
+		PDF:        "PDF",
 		Format(-1): "",
 	}
 	for format, name := range formatNames {
@@ -337,3 +535,55 @@ func TestAutoOrientation(t *testing.T) {
 		t.Fatal("expected error got nil")
 	}
 }
+
+func TestDeterministicEncode(t *testing.T) {
+	t.Parallel()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer := newC2PASigner(t, key)
+	img := New(16, 16, color.White)
+	date := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	exif := NewEXIF().SetArtist("Jane Doe").SetDateTime(date)
+	encode := func(f Format, opts ...EncodeOption) []byte {
+		t.Helper()
+		manifest := &C2PAManifest{Actions: []C2PAAction{{Action: C2PACreated, When: time.Now()}}}
+		opts = append([]EncodeOption{WithEXIF(exif), WithC2PA(manifest, signer)}, opts...)
+		buf := &bytes.Buffer{}
+		if err := Encode(buf, img, f, opts...); err != nil {
+			t.Fatalf("failed to encode %s: %v", f, err)
+		}
+		return buf.Bytes()
+	}
+
+	for _, f := range []Format{JPEG, PNG, TIFF} {
+		data := encode(f, DeterministicEncode(true))
+		if !bytes.Equal(data, encode(f, DeterministicEncode(true))) {
+			t.Fatalf("%s: got different outputs", f)
+		}
+		if f != TIFF && bytes.Equal(encode(f), encode(f)) {
+			t.Fatalf("%s: got the same outputs without DeterministicEncode", f)
+		}
+		meta, err := DecodeMetadata(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("%s: failed to decode metadata: %v", f, err)
+		}
+		if f != PNG {
+			if meta.EXIF == nil || meta.EXIF.dir.field(tagDateTime) != nil {
+				t.Fatalf("%s: got EXIF %+v want no DateTime", f, meta.EXIF)
+			}
+			if got, ok := meta.EXIF.DateTime(); !ok || !got.Equal(date) {
+				t.Fatalf("%s: got date %v want %v", f, got, date)
+			}
+		}
+		if f == TIFF {
+			continue
+		}
+		if m := meta.C2PA.Active(); m == nil || !m.Actions[0].When.IsZero() || !strings.HasPrefix(m.Label, "urn:uuid:") {
+			t.Fatalf("%s: got manifest %+v", f, m)
+		}
+		verifyC2PA(t, data, meta.C2PA.Store)
+	}
+}
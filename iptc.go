@@ -0,0 +1,174 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"unicode/utf8"
+)
+
+// tagIPTC is the TIFF tag holding the IPTC-IIM records.
+const tagIPTC = 33723
+
+// photoshopHeader is the header of the JPEG APP13 segment holding the Photoshop
+// image resources, one of which holds the IPTC-IIM records.
+const photoshopHeader = "Photoshop 3.0\x00"
+
+// photoshopIPTCResource is the ID of the Photoshop image resource holding the IPTC-IIM records.
+const photoshopIPTCResource = 0x0404
+
+// IPTC-IIM datasets of the application record (2) read by the package.
+const (
+	iptcObjectName = 5
+	iptcKeywords   = 25
+	iptcByline     = 80
+	iptcHeadline   = 105
+	iptcCopyright  = 116
+	iptcCaption    = 120
+)
+
+// IPTC holds the fields of the IPTC-IIM application record embedded in a JPEG
+// or TIFF image, as written by older photo management and news agency software.
+type IPTC struct {
+	// Title is the short reference of the image (Object Name).
+	Title string
+	// Headline is the synopsis of the image content.
+	Headline string
+	// Caption is the description of the image (Caption/Abstract).
+	Caption string
+	// Creators are the authors of the image (By-line).
+	Creators []string
+	// Keywords are the keywords of the image.
+	Keywords []string
+	// Copyright is the copyright notice.
+	Copyright string
+}
+
+// readIPTC returns the IPTC-IIM records embedded in the JPEG or TIFF image or nil.
+func readIPTC(data []byte) []byte {
+	switch {
+	case isJPEG(data):
+		var records []byte
+		walkJPEGSegments(data, func(marker byte, payload []byte) bool {
+			if marker == markerAPP13 && len(payload) > len(photoshopHeader) && string(payload[:len(photoshopHeader)]) == photoshopHeader {
+				records = photoshopResource(payload[len(photoshopHeader):], photoshopIPTCResource)
+				return records == nil
+			}
+			return true
+		})
+		return records
+	case isTIFF(data):
+		dirs, err := parseTIFF(data)
+		if err != nil {
+			return nil
+		}
+		if f := dirs[0].field(tagIPTC); f != nil {
+			return f.data
+		}
+	}
+	return nil
+}
+
+// photoshopResource returns the data of the Photoshop image resource with the ID or nil.
+// Each resource is stored as the "8BIM" signature, the ID, the name (a Pascal string
+// padded to an even size), the data size and the data padded to an even size.
+func photoshopResource(data []byte, id uint16) []byte {
+	pos := 0
+	for pos+7 <= len(data) && string(data[pos:pos+4]) == "8BIM" {
+		resourceID := binary.BigEndian.Uint16(data[pos+4:])
+		nameSize := int(data[pos+6]) + 1
+		nameSize += nameSize % 2
+		pos += 6 + nameSize
+		if pos+4 > len(data) {
+			return nil
+		}
+		size := int(binary.BigEndian.Uint32(data[pos:]))
+		pos += 4
+		if size < 0 || pos+size > len(data) {
+			return nil
+		}
+		if resourceID == id {
+			return data[pos : pos+size]
+		}
+		pos += size + size%2
+	}
+	return nil
+}
+
+// parseIPTC returns the fields of the application record of the IPTC-IIM records.
+// It returns nil if there are none.
+func parseIPTC(data []byte) *IPTC {
+	p := &IPTC{}
+	found := false
+	utf8Charset := false
+	pos := 0
+	// Each dataset is stored as the 0x1c tag marker, the record and dataset numbers,
+	// the data size and the data.
+	for pos+5 <= len(data) && data[pos] == 0x1c {
+		record, dataset := data[pos+1], data[pos+2]
+		size := int(binary.BigEndian.Uint16(data[pos+3:]))
+		pos += 5
+		if size&0x8000 != 0 {
+			// Extended dataset: the size is stored in the following bytes.
+			n := size & 0x7fff
+			if n > 4 || pos+n > len(data) {
+				return nil
+			}
+			size = 0
+			for _, b := range data[pos : pos+n] {
+				size = size<<8 | int(b)
+			}
+			pos += n
+		}
+		if pos+size > len(data) {
+			break
+		}
+		value := data[pos : pos+size]
+		pos += size
+
+		if record == 1 && dataset == 90 {
+			// Coded character set, ESC % G is UTF-8.
+			utf8Charset = bytes.Equal(value, []byte("\x1b%G"))
+			continue
+		}
+		if record != 2 {
+			continue
+		}
+		s := iptcString(value, utf8Charset)
+		switch dataset {
+		case iptcObjectName:
+			p.Title = s
+		case iptcKeywords:
+			p.Keywords = append(p.Keywords, s)
+		case iptcByline:
+			p.Creators = append(p.Creators, s)
+		case iptcHeadline:
+			p.Headline = s
+		case iptcCopyright:
+			p.Copyright = s
+		case iptcCaption:
+			p.Caption = s
+		default:
+			continue
+		}
+		found = true
+	}
+	if !found {
+		return nil
+	}
+	return p
+}
+
+// iptcString decodes the IPTC-IIM text. Text without the UTF-8 character set
+// declaration is often UTF-8 anyway, so it's decoded as Latin-1 only if it isn't
+// valid UTF-8.
+func iptcString(b []byte, utf8Charset bool) string {
+	b = bytes.TrimRight(b, "\x00")
+	if utf8Charset || utf8.Valid(b) {
+		return string(b)
+	}
+	r := make([]rune, len(b))
+	for i, c := range b {
+		r[i] = rune(c)
+	}
+	return string(r)
+}
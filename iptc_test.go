@@ -0,0 +1,131 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// makeIPTC returns IPTC-IIM records with the datasets of the application record.
+func makeIPTC(utf8Charset bool, datasets ...interface{}) []byte {
+	var data []byte
+	appendDataset := func(record, dataset byte, value []byte) {
+		data = append(data, 0x1c, record, dataset, byte(len(value)>>8), byte(len(value)))
+		data = append(data, value...)
+	}
+	if utf8Charset {
+		appendDataset(1, 90, []byte("\x1b%G"))
+	}
+	for i := 0; i+1 < len(datasets); i += 2 {
+		appendDataset(2, byte(datasets[i].(int)), []byte(datasets[i+1].(string)))
+	}
+	return data
+}
+
+// makePhotoshopIPTC returns the payload of the JPEG APP13 segment holding the IPTC-IIM records.
+func makePhotoshopIPTC(records []byte) []byte {
+	payload := []byte(photoshopHeader)
+	// A resolution info resource before the IPTC records.
+	payload = append(payload, "8BIM\x03\xed\x00\x00\x00\x00\x00\x03abc\x00"...)
+	payload = append(payload, "8BIM\x04\x04\x04name\x00"...)
+	size := make([]byte, 4)
+	binary.BigEndian.PutUint32(size, uint32(len(records)))
+	payload = append(payload, size...)
+	return append(payload, records...)
+}
+
+func TestParseIPTC(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		data []byte
+		want *IPTC
+	}{
+		{
+			name: "all fields",
+			data: makeIPTC(true,
+				iptcObjectName, "Sunset",
+				iptcHeadline, "Sunset over the sea",
+				iptcCaption, "The sun sets over the sea.",
+				iptcByline, "Jane Doe",
+				iptcKeywords, "sunset",
+				iptcKeywords, "sea",
+				iptcCopyright, "(c) 2023 Jane Doe",
+				200, "ignored",
+			),
+			want: &IPTC{
+				Title:     "Sunset",
+				Headline:  "Sunset over the sea",
+				Caption:   "The sun sets over the sea.",
+				Creators:  []string{"Jane Doe"},
+				Keywords:  []string{"sunset", "sea"},
+				Copyright: "(c) 2023 Jane Doe",
+			},
+		},
+		{
+			name: "Latin-1",
+			data: makeIPTC(false, iptcByline, "Andr\xe9"),
+			want: &IPTC{Creators: []string{"André"}},
+		},
+		{
+			name: "UTF-8 without declaration",
+			data: makeIPTC(false, iptcByline, "André"),
+			want: &IPTC{Creators: []string{"André"}},
+		},
+		{
+			name: "no application record",
+			data: makeIPTC(true),
+			want: nil,
+		},
+		{
+			name: "truncated",
+			data: makeIPTC(false, iptcObjectName, "Sunset")[:8],
+			want: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got := parseIPTC(tc.data)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("got %+v want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestReadIPTC(t *testing.T) {
+	t.Parallel()
+
+	records := makeIPTC(true, iptcObjectName, "Sunset", iptcKeywords, "sunset", iptcByline, "Jane Doe")
+	data := makeJPEGWithSegment(t, markerAPP13, makePhotoshopIPTC(records))
+	meta, err := DecodeMetadata(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if meta.IPTC == nil || meta.IPTC.Title != "Sunset" {
+		t.Fatalf("got IPTC %+v", meta.IPTC)
+	}
+	if meta.Title() != "Sunset" || !reflect.DeepEqual(meta.Keywords(), []string{"sunset"}) ||
+		!reflect.DeepEqual(meta.Creators(), []string{"Jane Doe"}) {
+		t.Fatalf("got title %q, keywords %q, creators %q", meta.Title(), meta.Keywords(), meta.Creators())
+	}
+
+	tiffDir := newTIFFDir(binary.LittleEndian)
+	tiffDir.set(tagIPTC, tiffUndefined, uint32(len(records)), records)
+	buf := &bytes.Buffer{}
+	if err := Encode(buf, makeNoiseNRGBA(4, 4, 1), TIFF, WithEXIF(newEXIF(tiffDir))); err != nil {
+		t.Fatalf("failed to encode TIFF: %v", err)
+	}
+	meta, err = DecodeMetadata(buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if meta.IPTC == nil || meta.IPTC.Title != "Sunset" {
+		t.Fatalf("got IPTC %+v from TIFF", meta.IPTC)
+	}
+}
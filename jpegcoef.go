@@ -0,0 +1,780 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// ErrUnsupportedJPEG means the JPEG image uses an encoding that can't be transformed
+// losslessly (progressive, arithmetic coded, lossless or 12-bit JPEG).
+var ErrUnsupportedJPEG = errors.New("imaging: unsupported JPEG encoding for lossless transformation")
+
+// errInvalidJPEG means the JPEG data is malformed.
+var errInvalidJPEG = errors.New("imaging: invalid JPEG data")
+
+// JPEG markers of the coefficient codec.
+const (
+	markerSOI  = 0xd8
+	markerEOI  = 0xd9
+	markerSOF0 = 0xc0
+	markerSOF1 = 0xc1
+	markerDHT  = 0xc4
+	markerDQT  = 0xdb
+	markerDRI  = 0xdd
+	markerRST0 = 0xd0
+	markerCOM  = 0xfe
+)
+
+// jpegUnzig maps the zig-zag order of the coefficients to the natural order.
+var jpegUnzig = [64]int{ //nolint
+	0, 1, 8, 16, 9, 2, 3, 10,
+	17, 24, 32, 25, 18, 11, 4, 5,
+	12, 19, 26, 33, 40, 48, 41, 34,
+	27, 20, 13, 6, 7, 14, 21, 28,
+	35, 42, 49, 56, 57, 50, 43, 36,
+	29, 22, 15, 23, 30, 37, 44, 51,
+	58, 59, 52, 45, 38, 31, 39, 46,
+	53, 60, 61, 54, 47, 55, 62, 63,
+}
+
+// jpegHuffmanSpec is a Huffman table given by the number of codes of each length
+// (1 to 16 bits) and the values in the order of the codes.
+type jpegHuffmanSpec struct {
+	counts [16]byte
+	values []byte
+}
+
+// jpegStandardHuffman are the Huffman tables of section K.3 of the JPEG specification:
+// luminance DC and AC, chrominance DC and AC. They cover all the values of 8-bit images.
+var jpegStandardHuffman = [4]jpegHuffmanSpec{ //nolint
+	{
+		[16]byte{0, 1, 5, 1, 1, 1, 1, 1, 1, 0, 0, 0, 0, 0, 0, 0},
+		[]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11},
+	},
+	{
+		[16]byte{0, 2, 1, 3, 3, 2, 4, 3, 5, 5, 4, 4, 0, 0, 1, 125},
+		[]byte{
+			0x01, 0x02, 0x03, 0x00, 0x04, 0x11, 0x05, 0x12,
+			0x21, 0x31, 0x41, 0x06, 0x13, 0x51, 0x61, 0x07,
+			0x22, 0x71, 0x14, 0x32, 0x81, 0x91, 0xa1, 0x08,
+			0x23, 0x42, 0xb1, 0xc1, 0x15, 0x52, 0xd1, 0xf0,
+			0x24, 0x33, 0x62, 0x72, 0x82, 0x09, 0x0a, 0x16,
+			0x17, 0x18, 0x19, 0x1a, 0x25, 0x26, 0x27, 0x28,
+			0x29, 0x2a, 0x34, 0x35, 0x36, 0x37, 0x38, 0x39,
+			0x3a, 0x43, 0x44, 0x45, 0x46, 0x47, 0x48, 0x49,
+			0x4a, 0x53, 0x54, 0x55, 0x56, 0x57, 0x58, 0x59,
+			0x5a, 0x63, 0x64, 0x65, 0x66, 0x67, 0x68, 0x69,
+			0x6a, 0x73, 0x74, 0x75, 0x76, 0x77, 0x78, 0x79,
+			0x7a, 0x83, 0x84, 0x85, 0x86, 0x87, 0x88, 0x89,
+			0x8a, 0x92, 0x93, 0x94, 0x95, 0x96, 0x97, 0x98,
+			0x99, 0x9a, 0xa2, 0xa3, 0xa4, 0xa5, 0xa6, 0xa7,
+			0xa8, 0xa9, 0xaa, 0xb2, 0xb3, 0xb4, 0xb5, 0xb6,
+			0xb7, 0xb8, 0xb9, 0xba, 0xc2, 0xc3, 0xc4, 0xc5,
+			0xc6, 0xc7, 0xc8, 0xc9, 0xca, 0xd2, 0xd3, 0xd4,
+			0xd5, 0xd6, 0xd7, 0xd8, 0xd9, 0xda, 0xe1, 0xe2,
+			0xe3, 0xe4, 0xe5, 0xe6, 0xe7, 0xe8, 0xe9, 0xea,
+			0xf1, 0xf2, 0xf3, 0xf4, 0xf5, 0xf6, 0xf7, 0xf8,
+			0xf9, 0xfa,
+		},
+	},
+	{
+		[16]byte{0, 3, 1, 1, 1, 1, 1, 1, 1, 1, 1, 0, 0, 0, 0, 0},
+		[]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11},
+	},
+	{
+		[16]byte{0, 2, 1, 2, 4, 4, 3, 4, 7, 5, 4, 4, 0, 1, 2, 119},
+		[]byte{
+			0x00, 0x01, 0x02, 0x03, 0x11, 0x04, 0x05, 0x21,
+			0x31, 0x06, 0x12, 0x41, 0x51, 0x07, 0x61, 0x71,
+			0x13, 0x22, 0x32, 0x81, 0x08, 0x14, 0x42, 0x91,
+			0xa1, 0xb1, 0xc1, 0x09, 0x23, 0x33, 0x52, 0xf0,
+			0x15, 0x62, 0x72, 0xd1, 0x0a, 0x16, 0x24, 0x34,
+			0xe1, 0x25, 0xf1, 0x17, 0x18, 0x19, 0x1a, 0x26,
+			0x27, 0x28, 0x29, 0x2a, 0x35, 0x36, 0x37, 0x38,
+			0x39, 0x3a, 0x43, 0x44, 0x45, 0x46, 0x47, 0x48,
+			0x49, 0x4a, 0x53, 0x54, 0x55, 0x56, 0x57, 0x58,
+			0x59, 0x5a, 0x63, 0x64, 0x65, 0x66, 0x67, 0x68,
+			0x69, 0x6a, 0x73, 0x74, 0x75, 0x76, 0x77, 0x78,
+			0x79, 0x7a, 0x82, 0x83, 0x84, 0x85, 0x86, 0x87,
+			0x88, 0x89, 0x8a, 0x92, 0x93, 0x94, 0x95, 0x96,
+			0x97, 0x98, 0x99, 0x9a, 0xa2, 0xa3, 0xa4, 0xa5,
+			0xa6, 0xa7, 0xa8, 0xa9, 0xaa, 0xb2, 0xb3, 0xb4,
+			0xb5, 0xb6, 0xb7, 0xb8, 0xb9, 0xba, 0xc2, 0xc3,
+			0xc4, 0xc5, 0xc6, 0xc7, 0xc8, 0xc9, 0xca, 0xd2,
+			0xd3, 0xd4, 0xd5, 0xd6, 0xd7, 0xd8, 0xd9, 0xda,
+			0xe2, 0xe3, 0xe4, 0xe5, 0xe6, 0xe7, 0xe8, 0xe9,
+			0xea, 0xf2, 0xf3, 0xf4, 0xf5, 0xf6, 0xf7, 0xf8,
+			0xf9, 0xfa,
+		},
+	},
+}
+
+// jpegBlock holds the quantized DCT coefficients of an 8x8 block in the natural order.
+type jpegBlock [64]int16
+
+// jpegComponent is a color component of a JPEG image with its coefficients.
+type jpegComponent struct {
+	// id is the component identifier.
+	id byte
+	// h and v are the horizontal and vertical sampling factors.
+	h, v int
+	// tq is the index of the quantization table.
+	tq byte
+	// bw and bh are the size of the block grid, covering whole MCUs.
+	bw, bh int
+	// blocks are the blocks of the grid in the row-major order.
+	blocks []jpegBlock
+}
+
+// jpegCoefs is a sequential JPEG image decoded to the quantized DCT coefficients.
+type jpegCoefs struct {
+	width, height int
+	comps         []jpegComponent
+	// quant are the quantization tables in the natural order, nil if not defined.
+	quant [4]*[64]uint16
+	// segments are the APPn and COM segments (with the markers) to be copied.
+	segments [][]byte
+}
+
+// maxSampling returns the maximum horizontal and vertical sampling factors.
+func (c *jpegCoefs) maxSampling() (hmax, vmax int) {
+	for _, comp := range c.comps {
+		if comp.h > hmax {
+			hmax = comp.h
+		}
+		if comp.v > vmax {
+			vmax = comp.v
+		}
+	}
+	return hmax, vmax
+}
+
+// jpegHuffman is a Huffman table prepared for decoding (section F.2.2.3 of the specification).
+type jpegHuffman struct {
+	maxCode [17]int32
+	valPtr  [17]int32
+	minCode [17]int32
+	values  []byte
+}
+
+// newJPEGHuffman returns the decoding table for the Huffman table specification.
+func newJPEGHuffman(s jpegHuffmanSpec) *jpegHuffman {
+	h := &jpegHuffman{values: s.values}
+	code, k := int32(0), int32(0)
+	for l := 1; l <= 16; l++ {
+		n := int32(s.counts[l-1])
+		h.valPtr[l] = k
+		h.minCode[l] = code
+		h.maxCode[l] = -1
+		if n > 0 {
+			h.maxCode[l] = code + n - 1
+		}
+		code = (code + n) << 1
+		k += n
+	}
+	return h
+}
+
+// jpegBitReader reads the entropy-coded data, removing the stuffed zero bytes.
+type jpegBitReader struct {
+	data []byte
+	pos  int
+	acc  uint32
+	n    uint
+}
+
+// bit returns the next bit. Past the end of the data or at a marker it returns zeros,
+// like the common decoders do for truncated images.
+func (r *jpegBitReader) bit() int {
+	if r.n == 0 {
+		b := byte(0)
+		if r.pos < len(r.data) {
+			b = r.data[r.pos]
+			switch {
+			case b != 0xff:
+				r.pos++
+			case r.pos+1 < len(r.data) && r.data[r.pos+1] == 0:
+				r.pos += 2
+			default:
+				// A marker: the data of the scan ends here.
+				b = 0
+			}
+		}
+		r.acc, r.n = uint32(b), 8
+	}
+	r.n--
+	return int(r.acc>>r.n) & 1
+}
+
+// receive reads s bits and returns them extended to a signed value (section F.2.2.1).
+func (r *jpegBitReader) receive(s int) int32 {
+	v := int32(0)
+	for i := 0; i < s; i++ {
+		v = v<<1 | int32(r.bit())
+	}
+	if s > 0 && v < 1<<(s-1) {
+		v += -1<<s + 1
+	}
+	return v
+}
+
+// decode reads the next Huffman coded value.
+func (r *jpegBitReader) decode(h *jpegHuffman) (byte, error) {
+	code := int32(0)
+	for l := 1; l <= 16; l++ {
+		code = code<<1 | int32(r.bit())
+		if code <= h.maxCode[l] {
+			i := h.valPtr[l] + code - h.minCode[l]
+			if int(i) >= len(h.values) {
+				return 0, errInvalidJPEG
+			}
+			return h.values[i], nil
+		}
+	}
+	return 0, errInvalidJPEG
+}
+
+// restart skips the restart marker at the end of a restart interval.
+func (r *jpegBitReader) restart() error {
+	r.n = 0
+	for r.pos < len(r.data) && r.data[r.pos] == 0xff && r.pos+1 < len(r.data) && r.data[r.pos+1] == 0xff {
+		r.pos++
+	}
+	if r.pos+1 >= len(r.data) || r.data[r.pos] != 0xff || r.data[r.pos+1]&0xf8 != markerRST0 {
+		return errInvalidJPEG
+	}
+	r.pos += 2
+	return nil
+}
+
+// readJPEGCoefs decodes the sequential Huffman coded JPEG image to the quantized
+// DCT coefficients.
+func readJPEGCoefs(data []byte) (*jpegCoefs, error) {
+	if !isJPEG(data) {
+		return nil, errInvalidJPEG
+	}
+	c := &jpegCoefs{}
+	var (
+		dc, ac          [4]*jpegHuffman
+		restartInterval int
+		frame, scanned  bool
+	)
+	pos := 2
+	for {
+		for pos < len(data) && data[pos] == 0xff && pos+1 < len(data) && data[pos+1] == 0xff {
+			pos++
+		}
+		if pos+2 > len(data) || data[pos] != 0xff {
+			return nil, errInvalidJPEG
+		}
+		marker := data[pos+1]
+		if marker == markerEOI {
+			break
+		}
+		if pos+4 > len(data) {
+			return nil, errInvalidJPEG
+		}
+		size := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if size < 2 || pos+2+size > len(data) {
+			return nil, errInvalidJPEG
+		}
+		payload := data[pos+4 : pos+2+size]
+		next := pos + 2 + size
+
+		switch {
+		case marker >= 0xe0 && marker <= 0xef, marker == markerCOM:
+			c.segments = append(c.segments, data[pos:next])
+		case marker == markerDQT:
+			if err := c.readDQT(payload); err != nil {
+				return nil, err
+			}
+		case marker == markerSOF0, marker == markerSOF1:
+			if frame {
+				return nil, errInvalidJPEG
+			}
+			if err := c.readSOF(payload); err != nil {
+				return nil, err
+			}
+			frame = true
+		case marker >= 0xc2 && marker <= 0xcf && marker != markerDHT && marker != 0xc8 && marker != 0xcc:
+			// Progressive, lossless, hierarchical and arithmetic coded frames.
+			return nil, ErrUnsupportedJPEG
+		case marker == markerDHT:
+			for len(payload) > 0 {
+				if len(payload) < 17 {
+					return nil, errInvalidJPEG
+				}
+				var s jpegHuffmanSpec
+				copy(s.counts[:], payload[1:17])
+				n := 0
+				for _, count := range s.counts {
+					n += int(count)
+				}
+				if n > 256 || len(payload) < 17+n {
+					return nil, errInvalidJPEG
+				}
+				s.values = payload[17 : 17+n]
+				class, id := payload[0]>>4, payload[0]&0x0f
+				if id > 3 || class > 1 {
+					return nil, errInvalidJPEG
+				}
+				if class == 0 {
+					dc[id] = newJPEGHuffman(s)
+				} else {
+					ac[id] = newJPEGHuffman(s)
+				}
+				payload = payload[17+n:]
+			}
+		case marker == markerDRI:
+			if len(payload) < 2 {
+				return nil, errInvalidJPEG
+			}
+			restartInterval = int(binary.BigEndian.Uint16(payload))
+		case marker == markerSOS:
+			if !frame {
+				return nil, errInvalidJPEG
+			}
+			end, err := c.readScan(data, payload, next, dc, ac, restartInterval)
+			if err != nil {
+				return nil, err
+			}
+			scanned = true
+			next = end
+		}
+		pos = next
+	}
+	if !scanned {
+		return nil, errInvalidJPEG
+	}
+	for _, comp := range c.comps {
+		if c.quant[comp.tq] == nil {
+			return nil, errInvalidJPEG
+		}
+	}
+	return c, nil
+}
+
+// readDQT reads the quantization tables of the DQT segment.
+func (c *jpegCoefs) readDQT(payload []byte) error {
+	for len(payload) > 0 {
+		precision, id := payload[0]>>4, payload[0]&0x0f
+		size := 64 * (int(precision) + 1)
+		if id > 3 || precision > 1 || len(payload) < 1+size {
+			return errInvalidJPEG
+		}
+		q := &[64]uint16{}
+		for k := 0; k < 64; k++ {
+			if precision == 0 {
+				q[jpegUnzig[k]] = uint16(payload[1+k])
+			} else {
+				q[jpegUnzig[k]] = binary.BigEndian.Uint16(payload[1+2*k:])
+			}
+		}
+		c.quant[id] = q
+		payload = payload[1+size:]
+	}
+	return nil
+}
+
+// readSOF reads the frame header and allocates the coefficient blocks.
+func (c *jpegCoefs) readSOF(payload []byte) error {
+	if len(payload) < 6 {
+		return errInvalidJPEG
+	}
+	if payload[0] != 8 {
+		return ErrUnsupportedJPEG
+	}
+	c.height = int(binary.BigEndian.Uint16(payload[1:]))
+	c.width = int(binary.BigEndian.Uint16(payload[3:]))
+	n := int(payload[5])
+	if c.width == 0 || c.height == 0 {
+		// The height defined by a DNL marker is not supported.
+		return ErrUnsupportedJPEG
+	}
+	if n == 0 || n > 4 || len(payload) < 6+3*n {
+		return errInvalidJPEG
+	}
+	for i := 0; i < n; i++ {
+		p := payload[6+3*i:]
+		comp := jpegComponent{id: p[0], h: int(p[1] >> 4), v: int(p[1] & 0x0f), tq: p[2]}
+		if comp.h < 1 || comp.h > 4 || comp.v < 1 || comp.v > 4 || comp.tq > 3 {
+			return errInvalidJPEG
+		}
+		c.comps = append(c.comps, comp)
+	}
+	c.allocBlocks()
+	return nil
+}
+
+// allocBlocks allocates the block grids of the components, covering whole MCUs.
+func (c *jpegCoefs) allocBlocks() {
+	hmax, vmax := c.maxSampling()
+	mcusX := (c.width + 8*hmax - 1) / (8 * hmax)
+	mcusY := (c.height + 8*vmax - 1) / (8 * vmax)
+	for i := range c.comps {
+		comp := &c.comps[i]
+		comp.bw, comp.bh = mcusX*comp.h, mcusY*comp.v
+		comp.blocks = make([]jpegBlock, comp.bw*comp.bh)
+	}
+}
+
+// readScan decodes the entropy-coded data of the scan starting at pos and
+// returns the position of the marker following it.
+func (c *jpegCoefs) readScan(data, header []byte, pos int, dc, ac [4]*jpegHuffman, restartInterval int) (int, error) {
+	if len(header) < 1 {
+		return 0, errInvalidJPEG
+	}
+	n := int(header[0])
+	if n == 0 || n > len(c.comps) || len(header) < 1+2*n+3 {
+		return 0, errInvalidJPEG
+	}
+	type scanComp struct {
+		comp   *jpegComponent
+		dc, ac *jpegHuffman
+		pred   int32
+	}
+	comps := make([]scanComp, n)
+	for i := range comps {
+		id, tables := header[1+2*i], header[2+2*i]
+		for j := range c.comps {
+			if c.comps[j].id == id {
+				comps[i].comp = &c.comps[j]
+			}
+		}
+		td, ta := tables>>4, tables&0x0f
+		if comps[i].comp == nil || td > 3 || ta > 3 || dc[td] == nil || ac[ta] == nil {
+			return 0, errInvalidJPEG
+		}
+		comps[i].dc, comps[i].ac = dc[td], ac[ta]
+	}
+
+	r := &jpegBitReader{data: data, pos: pos}
+	readBlock := func(sc *scanComp, b *jpegBlock) error {
+		s, err := r.decode(sc.dc)
+		if err != nil {
+			return err
+		}
+		if s > 11 {
+			return errInvalidJPEG
+		}
+		sc.pred += r.receive(int(s))
+		b[0] = int16(sc.pred)
+		for k := 1; k < 64; k++ {
+			rs, err := r.decode(sc.ac)
+			if err != nil {
+				return err
+			}
+			run, size := int(rs>>4), int(rs&0x0f)
+			if size == 0 {
+				if run != 15 {
+					break
+				}
+				k += 15
+				continue
+			}
+			k += run
+			if k > 63 {
+				return errInvalidJPEG
+			}
+			b[jpegUnzig[k]] = int16(r.receive(size))
+		}
+		return nil
+	}
+
+	// The MCU of an interleaved scan holds h*v blocks of each component, the MCU
+	// of a single component scan is a single block of the component's own grid.
+	hmax, vmax := c.maxSampling()
+	mcusX, mcusY := comps[0].comp.bw/comps[0].comp.h, comps[0].comp.bh/comps[0].comp.v
+	if n == 1 {
+		comp := comps[0].comp
+		mcusX = ((c.width*comp.h+hmax-1)/hmax + 7) / 8
+		mcusY = ((c.height*comp.v+vmax-1)/vmax + 7) / 8
+	}
+	mcu := 0
+	for my := 0; my < mcusY; my++ {
+		for mx := 0; mx < mcusX; mx++ {
+			if restartInterval > 0 && mcu > 0 && mcu%restartInterval == 0 {
+				if err := r.restart(); err != nil {
+					return 0, err
+				}
+				for i := range comps {
+					comps[i].pred = 0
+				}
+			}
+			mcu++
+			for i := range comps {
+				sc := &comps[i]
+				if n == 1 {
+					if err := readBlock(sc, &sc.comp.blocks[my*sc.comp.bw+mx]); err != nil {
+						return 0, err
+					}
+					continue
+				}
+				for by := 0; by < sc.comp.v; by++ {
+					for bx := 0; bx < sc.comp.h; bx++ {
+						x, y := mx*sc.comp.h+bx, my*sc.comp.v+by
+						if err := readBlock(sc, &sc.comp.blocks[y*sc.comp.bw+x]); err != nil {
+							return 0, err
+						}
+					}
+				}
+			}
+		}
+	}
+
+	// Find the marker following the scan, skipping the stuffed bytes and restart markers.
+	pos = r.pos
+	for pos+1 < len(data) {
+		if data[pos] == 0xff && data[pos+1] != 0 && data[pos+1] != 0xff && data[pos+1]&0xf8 != markerRST0 {
+			return pos, nil
+		}
+		pos++
+	}
+	return 0, errInvalidJPEG
+}
+
+// jpegHuffmanCode is a Huffman code for encoding.
+type jpegHuffmanCode struct {
+	code uint32
+	size uint
+}
+
+// jpegEncodeTable returns the codes of the Huffman table specification indexed by the values.
+func jpegEncodeTable(s jpegHuffmanSpec) [256]jpegHuffmanCode {
+	var t [256]jpegHuffmanCode
+	code, k := uint32(0), 0
+	for l := 0; l < 16; l++ {
+		for i := 0; i < int(s.counts[l]); i++ {
+			t[s.values[k]] = jpegHuffmanCode{code: code, size: uint(l + 1)}
+			code++
+			k++
+		}
+		code <<= 1
+	}
+	return t
+}
+
+// jpegBitWriter writes the entropy-coded data, stuffing a zero byte after 0xff bytes.
+type jpegBitWriter struct {
+	buf *bytes.Buffer
+	acc uint32
+	n   uint
+}
+
+// write writes the low size bits of v.
+func (w *jpegBitWriter) write(v uint32, size uint) {
+	for size > 0 {
+		size--
+		w.acc = w.acc<<1 | (v>>size)&1
+		w.n++
+		if w.n == 8 {
+			b := byte(w.acc)
+			w.buf.WriteByte(b)
+			if b == 0xff {
+				w.buf.WriteByte(0)
+			}
+			w.acc, w.n = 0, 0
+		}
+	}
+}
+
+// flush pads the last byte with one bits.
+func (w *jpegBitWriter) flush() {
+	if w.n > 0 {
+		w.write(0xff, 8-w.n)
+	}
+}
+
+// jpegCategory returns the number of bits of the magnitude of v and its encoded bits.
+func jpegCategory(v int32) (uint, uint32) {
+	a := v
+	if a < 0 {
+		a = -a
+		v--
+	}
+	s := uint(0)
+	for a > 0 {
+		s++
+		a >>= 1
+	}
+	return s, uint32(v) & (1<<s - 1)
+}
+
+// write encodes the coefficients as a baseline JPEG image with the standard Huffman tables.
+func (c *jpegCoefs) write(w io.Writer) error {
+	buf := &bytes.Buffer{}
+	buf.Write([]byte{0xff, markerSOI})
+	for _, s := range c.segments {
+		buf.Write(s)
+	}
+
+	// 16-bit quantization tables are not allowed in baseline JPEG.
+	sof := byte(markerSOF0)
+	for id, q := range c.quant {
+		if q == nil {
+			continue
+		}
+		precision := byte(0)
+		for _, v := range q {
+			if v > 0xff {
+				precision = 1
+				sof = markerSOF1
+			}
+		}
+		seg := []byte{0xff, markerDQT, 0, 0, precision<<4 | byte(id)}
+		for k := 0; k < 64; k++ {
+			v := q[jpegUnzig[k]]
+			if precision == 0 {
+				seg = append(seg, byte(v))
+			} else {
+				seg = append(seg, byte(v>>8), byte(v))
+			}
+		}
+		binary.BigEndian.PutUint16(seg[2:], uint16(len(seg)-2))
+		buf.Write(seg)
+	}
+
+	seg := []byte{0xff, sof, 0, 0, 8, byte(c.height >> 8), byte(c.height), byte(c.width >> 8), byte(c.width), byte(len(c.comps))}
+	for _, comp := range c.comps {
+		seg = append(seg, comp.id, byte(comp.h<<4|comp.v), comp.tq)
+	}
+	binary.BigEndian.PutUint16(seg[2:], uint16(len(seg)-2))
+	buf.Write(seg)
+
+	// The first component (luminance) uses the tables 0, the others the tables 1.
+	tables := 1
+	if len(c.comps) > 1 {
+		tables = 2
+	}
+	seg = []byte{0xff, markerDHT, 0, 0}
+	for i := 0; i < 2*tables; i++ {
+		s := jpegStandardHuffman[i]
+		seg = append(seg, byte(i%2)<<4|byte(i/2))
+		seg = append(seg, s.counts[:]...)
+		seg = append(seg, s.values...)
+	}
+	binary.BigEndian.PutUint16(seg[2:], uint16(len(seg)-2))
+	buf.Write(seg)
+
+	// An interleaved MCU may hold at most 10 blocks, otherwise each component
+	// is written in its own scan.
+	blocks := 0
+	for _, comp := range c.comps {
+		blocks += comp.h * comp.v
+	}
+	if len(c.comps) == 1 || blocks <= 10 {
+		if err := c.writeScan(buf, c.comps); err != nil {
+			return err
+		}
+	} else {
+		for i := range c.comps {
+			if err := c.writeScan(buf, c.comps[i:i+1]); err != nil {
+				return err
+			}
+		}
+	}
+	buf.Write([]byte{0xff, markerEOI})
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// writeScan writes a scan of the components.
+func (c *jpegCoefs) writeScan(buf *bytes.Buffer, comps []jpegComponent) error {
+	seg := []byte{0xff, markerSOS, 0, 0, byte(len(comps))}
+	for _, comp := range comps {
+		table := byte(0)
+		if comp.id != c.comps[0].id {
+			table = 1
+		}
+		seg = append(seg, comp.id, table<<4|table)
+	}
+	seg = append(seg, 0, 63, 0)
+	binary.BigEndian.PutUint16(seg[2:], uint16(len(seg)-2))
+	buf.Write(seg)
+
+	dcTables := [2][256]jpegHuffmanCode{jpegEncodeTable(jpegStandardHuffman[0]), jpegEncodeTable(jpegStandardHuffman[2])}
+	acTables := [2][256]jpegHuffmanCode{jpegEncodeTable(jpegStandardHuffman[1]), jpegEncodeTable(jpegStandardHuffman[3])}
+	w := &jpegBitWriter{buf: buf}
+	emit := func(t *[256]jpegHuffmanCode, v byte) error {
+		code := t[v]
+		if code.size == 0 {
+			return errInvalidJPEG
+		}
+		w.write(code.code, code.size)
+		return nil
+	}
+	preds := make([]int32, len(comps))
+	writeBlock := func(i int, b *jpegBlock) error {
+		table := 0
+		if comps[i].id != c.comps[0].id {
+			table = 1
+		}
+		s, bits := jpegCategory(int32(b[0]) - preds[i])
+		preds[i] = int32(b[0])
+		if err := emit(&dcTables[table], byte(s)); err != nil {
+			return err
+		}
+		w.write(bits, s)
+		run := 0
+		for k := 1; k < 64; k++ {
+			v := b[jpegUnzig[k]]
+			if v == 0 {
+				run++
+				continue
+			}
+			for ; run >= 16; run -= 16 {
+				if err := emit(&acTables[table], 0xf0); err != nil {
+					return err
+				}
+			}
+			s, bits := jpegCategory(int32(v))
+			if s > 10 {
+				return errInvalidJPEG
+			}
+			if err := emit(&acTables[table], byte(run<<4)|byte(s)); err != nil {
+				return err
+			}
+			w.write(bits, s)
+			run = 0
+		}
+		if run > 0 {
+			return emit(&acTables[table], 0x00)
+		}
+		return nil
+	}
+
+	hmax, vmax := c.maxSampling()
+	if len(comps) == 1 {
+		comp := &comps[0]
+		blocksX := ((c.width*comp.h+hmax-1)/hmax + 7) / 8
+		blocksY := ((c.height*comp.v+vmax-1)/vmax + 7) / 8
+		for y := 0; y < blocksY; y++ {
+			for x := 0; x < blocksX; x++ {
+				if err := writeBlock(0, &comp.blocks[y*comp.bw+x]); err != nil {
+					return err
+				}
+			}
+		}
+	} else {
+		mcusX, mcusY := comps[0].bw/comps[0].h, comps[0].bh/comps[0].v
+		for my := 0; my < mcusY; my++ {
+			for mx := 0; mx < mcusX; mx++ {
+				for i := range comps {
+					comp := &comps[i]
+					for by := 0; by < comp.v; by++ {
+						for bx := 0; bx < comp.h; bx++ {
+							x, y := mx*comp.h+bx, my*comp.v+by
+							if err := writeBlock(i, &comp.blocks[y*comp.bw+x]); err != nil {
+								return err
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	w.flush()
+	return nil
+}
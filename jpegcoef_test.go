@@ -0,0 +1,109 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"testing"
+)
+
+// encodeTestJPEG returns the image encoded in JPEG format.
+func encodeTestJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	if err := jpeg.Encode(buf, img, &jpeg.Options{Quality: 90}); err != nil {
+		t.Fatalf("failed to encode JPEG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// maxPixelDiff returns the maximum difference of the color channels of the images.
+func maxPixelDiff(a, b image.Image) int {
+	na, nb := Clone(a), Clone(b)
+	if na.Rect.Size() != nb.Rect.Size() {
+		return 256
+	}
+	m := 0
+	for i := range na.Pix {
+		d := int(na.Pix[i]) - int(nb.Pix[i])
+		if d < 0 {
+			d = -d
+		}
+		if d > m {
+			m = d
+		}
+	}
+	return m
+}
+
+func TestJPEGCoefsRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		img  image.Image
+	}{
+		{"color", makeNoiseNRGBA(37, 21, 1)},
+		{"gray", Grayscale(makeNoiseNRGBA(19, 30, 2))},
+		{"gray image", image.NewGray(image.Rect(0, 0, 9, 17))},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			data := encodeTestJPEG(t, tc.img)
+			c, err := readJPEGCoefs(data)
+			if err != nil {
+				t.Fatalf("failed to read the coefficients: %v", err)
+			}
+			buf := &bytes.Buffer{}
+			if err := c.write(buf); err != nil {
+				t.Fatalf("failed to write the coefficients: %v", err)
+			}
+
+			want, err := jpeg.Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("failed to decode the original: %v", err)
+			}
+			got, err := jpeg.Decode(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("failed to decode the result: %v", err)
+			}
+			if d := maxPixelDiff(got, want); d != 0 {
+				t.Fatalf("the decoded images differ by %d", d)
+			}
+		})
+	}
+}
+
+func TestReadJPEGCoefsErrors(t *testing.T) {
+	t.Parallel()
+
+	data := encodeTestJPEG(t, makeNoiseNRGBA(16, 16, 1))
+	progressive := append([]byte(nil), data...)
+	if i := bytes.Index(progressive, []byte{0xff, markerSOF0}); i >= 0 {
+		progressive[i+1] = 0xc2
+	}
+
+	testCases := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"progressive", progressive, ErrUnsupportedJPEG},
+		{"not JPEG", []byte("not a JPEG image"), errInvalidJPEG},
+		{"truncated", data[:100], errInvalidJPEG},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if _, err := readJPEGCoefs(tc.data); !errors.Is(err, tc.want) {
+				t.Fatalf("got error %v want %v", err, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+// JPEGTransform is a lossless transformation of a JPEG image.
+type JPEGTransform int
+
+// Lossless JPEG transformations. They transform the image in the same way as
+// the functions of the same name (e.g. JPEGRotate90 like Rotate90).
+const (
+	// JPEGFlipH flips the image horizontally (from left to right).
+	JPEGFlipH JPEGTransform = iota + 1
+	// JPEGFlipV flips the image vertically (from top to bottom).
+	JPEGFlipV
+	// JPEGRotate90 rotates the image 90 degrees counter-clockwise.
+	JPEGRotate90
+	// JPEGRotate180 rotates the image 180 degrees.
+	JPEGRotate180
+	// JPEGRotate270 rotates the image 270 degrees counter-clockwise.
+	JPEGRotate270
+	// JPEGTranspose flips the image horizontally and rotates 90 degrees counter-clockwise.
+	JPEGTranspose
+	// JPEGTransverse flips the image vertically and rotates 90 degrees counter-clockwise.
+	JPEGTransverse
+)
+
+// errInvalidJPEGTransform means the JPEGTransform value is unknown.
+var errInvalidJPEGTransform = errors.New("imaging: invalid JPEG transformation")
+
+// errJPEGTooSmall means the image is smaller than an MCU along the trimmed edge.
+var errJPEGTooSmall = errors.New("imaging: JPEG image too small for lossless transformation")
+
+// TransformJPEG reads a JPEG image from r, transforms it without decoding the pixels
+// and writes the result to w. The transformation moves and flips the blocks of DCT
+// coefficients, so unlike the Decode, transform and Encode cycle it doesn't lose
+// any quality. The metadata (EXIF, ICC profile, XMP) is copied unchanged.
+//
+// JPEG images are stored in blocks of 8 or 16 pixels (MCUs). The edge blocks that
+// are partially outside of the image can't be moved to the top or left edges, so
+// like jpegtran -trim, these edges are trimmed and the width or height of the result
+// may be up to 15 pixels smaller. Only sequential JPEG images (used by most cameras)
+// are supported; for progressive and other JPEG images ErrUnsupportedJPEG is returned.
+//
+// Example:
+//
+//	err := imaging.TransformJPEG(src, dst, imaging.JPEGRotate270)
+func TransformJPEG(r io.Reader, w io.Writer, op JPEGTransform) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	c, err := readJPEGCoefs(data)
+	if err != nil {
+		return err
+	}
+	t, err := c.transform(op)
+	if err != nil {
+		return err
+	}
+	return t.write(w)
+}
+
+// AutoOrientJPEG reads a JPEG image from r, applies the transformation given by its
+// EXIF orientation tag losslessly in the same way as TransformJPEG and writes the
+// result with the orientation tag set to normal to w. Images without orientation
+// are copied unchanged.
+//
+// Example:
+//
+//	err := imaging.AutoOrientJPEG(src, dst)
+func AutoOrientJPEG(r io.Reader, w io.Writer) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	var op JPEGTransform
+	switch ReadOrientation(bytes.NewReader(data)) {
+	case OrientationFlipH:
+		op = JPEGFlipH
+	case OrientationFlipV:
+		op = JPEGFlipV
+	case OrientationRotate90:
+		op = JPEGRotate90
+	case OrientationRotate180:
+		op = JPEGRotate180
+	case OrientationRotate270:
+		op = JPEGRotate270
+	case OrientationTranspose:
+		op = JPEGTranspose
+	case OrientationTransverse:
+		op = JPEGTransverse
+	default:
+		_, err := w.Write(data)
+		return err
+	}
+
+	c, err := readJPEGCoefs(data)
+	if err != nil {
+		return err
+	}
+	t, err := c.transform(op)
+	if err != nil {
+		return err
+	}
+	for i, s := range t.segments {
+		if s[1] == markerAPP1 && len(s) > 4+len(exifHeader) && string(s[4:4+len(exifHeader)]) == exifHeader {
+			s = append([]byte(nil), s...)
+			resetEXIFOrientation(s[4+len(exifHeader):])
+			t.segments[i] = s
+		}
+	}
+	return t.write(w)
+}
+
+// resetEXIFOrientation sets the orientation tag of the EXIF data (a TIFF structure)
+// to normal in place.
+func resetEXIFOrientation(exif []byte) {
+	order, offset, err := readTIFFHeader(exif)
+	if err != nil || uint64(offset)+2 > uint64(len(exif)) {
+		return
+	}
+	n := int(order.Uint16(exif[offset:]))
+	for i := 0; i < n; i++ {
+		pos := int(offset) + 2 + 12*i
+		if pos+12 > len(exif) {
+			return
+		}
+		entry := exif[pos : pos+12]
+		if order.Uint16(entry) == tagOrientation && order.Uint16(entry[2:]) == tiffShort {
+			order.PutUint16(entry[8:], uint16(OrientationNormal))
+			return
+		}
+	}
+}
+
+// transform returns the coefficients transformed by the operation.
+func (c *jpegCoefs) transform(op JPEGTransform) (*jpegCoefs, error) {
+	var transposed, flipX, flipY bool
+	switch op {
+	case JPEGFlipH:
+		flipX = true
+	case JPEGFlipV:
+		flipY = true
+	case JPEGRotate90:
+		transposed, flipX = true, true
+	case JPEGRotate180:
+		flipX, flipY = true, true
+	case JPEGRotate270:
+		transposed, flipY = true, true
+	case JPEGTranspose:
+		transposed = true
+	case JPEGTransverse:
+		transposed, flipX, flipY = true, true, true
+	default:
+		return nil, errInvalidJPEGTransform
+	}
+
+	// The flipped source edges are trimmed to whole MCUs.
+	hmax, vmax := c.maxSampling()
+	width, height := c.width, c.height
+	if flipX {
+		width -= width % (8 * hmax)
+	}
+	if flipY {
+		height -= height % (8 * vmax)
+	}
+	if width == 0 || height == 0 {
+		return nil, errJPEGTooSmall
+	}
+
+	t := &jpegCoefs{width: width, height: height, quant: c.quant, segments: c.segments}
+	if transposed {
+		t.width, t.height = height, width
+		for i, q := range c.quant {
+			if q == nil {
+				continue
+			}
+			tq := &[64]uint16{}
+			for k := range q {
+				tq[k%8*8+k/8] = q[k]
+			}
+			t.quant[i] = tq
+		}
+	}
+
+	// sign negates the odd horizontal frequencies of the flipped columns and
+	// the odd vertical frequencies of the flipped rows, in the source block order.
+	var sign [64]int16
+	for k := range sign {
+		sign[k] = 1
+		if (flipX && k%2 == 1) != (flipY && k/8%2 == 1) {
+			sign[k] = -1
+		}
+	}
+
+	for _, comp := range c.comps {
+		// The size of the source grid used, without the trimmed edges.
+		bw, bh := comp.bw, comp.bh
+		if flipX {
+			bw = width / (8 * hmax) * comp.h
+		}
+		if flipY {
+			bh = height / (8 * vmax) * comp.v
+		}
+
+		out := jpegComponent{id: comp.id, h: comp.h, v: comp.v, tq: comp.tq, bw: bw, bh: bh}
+		if transposed {
+			out.h, out.v, out.bw, out.bh = comp.v, comp.h, bh, bw
+		}
+		out.blocks = make([]jpegBlock, out.bw*out.bh)
+		for oy := 0; oy < out.bh; oy++ {
+			for ox := 0; ox < out.bw; ox++ {
+				x, y := ox, oy
+				if transposed {
+					x, y = oy, ox
+				}
+				if flipX {
+					x = bw - 1 - x
+				}
+				if flipY {
+					y = bh - 1 - y
+				}
+				src := &comp.blocks[y*comp.bw+x]
+				dst := &out.blocks[oy*out.bw+ox]
+				for k, v := range src {
+					if transposed {
+						dst[k%8*8+k/8] = v * sign[k]
+					} else {
+						dst[k] = v * sign[k]
+					}
+				}
+			}
+		}
+		t.comps = append(t.comps, out)
+	}
+	return t, nil
+}
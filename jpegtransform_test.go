@@ -0,0 +1,142 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"testing"
+)
+
+func TestTransformJPEG(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		op   JPEGTransform
+		fn   func(image.Image) *image.NRGBA
+	}{
+		{"FlipH", JPEGFlipH, FlipH},
+		{"FlipV", JPEGFlipV, FlipV},
+		{"Rotate90", JPEGRotate90, Rotate90},
+		{"Rotate180", JPEGRotate180, Rotate180},
+		{"Rotate270", JPEGRotate270, Rotate270},
+		{"Transpose", JPEGTranspose, Transpose},
+		{"Transverse", JPEGTransverse, Transverse},
+	}
+
+	gray := image.NewGray(image.Rect(0, 0, 44, 27))
+	draw.Draw(gray, gray.Rect, makeNoiseNRGBA(44, 27, 2), image.Point{}, draw.Src)
+	images := map[string]image.Image{
+		"color":   makeNoiseNRGBA(64, 48, 1),
+		"gray":    gray,
+		"partial": makeNoiseNRGBA(70, 50, 3),
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		for name, img := range images {
+			name, img := name, img
+			t.Run(tc.name+" "+name, func(t *testing.T) {
+				t.Parallel()
+				data := encodeTestJPEG(t, img)
+				buf := &bytes.Buffer{}
+				if err := TransformJPEG(bytes.NewReader(data), buf, tc.op); err != nil {
+					t.Fatalf("failed to transform: %v", err)
+				}
+				got, err := jpeg.Decode(buf)
+				if err != nil {
+					t.Fatalf("failed to decode the result: %v", err)
+				}
+				src, err := jpeg.Decode(bytes.NewReader(data))
+				if err != nil {
+					t.Fatalf("failed to decode the original: %v", err)
+				}
+
+				// The partial MCUs of the flipped edges are trimmed.
+				mcu := 16
+				if name == "gray" {
+					mcu = 8
+				}
+				w, h := src.Bounds().Dx(), src.Bounds().Dy()
+				switch tc.op {
+				case JPEGFlipH, JPEGRotate90:
+					w -= w % mcu
+				case JPEGFlipV, JPEGRotate270:
+					h -= h % mcu
+				case JPEGRotate180, JPEGTransverse:
+					w, h = w-w%mcu, h-h%mcu
+				}
+				want := tc.fn(Crop(src, image.Rect(0, 0, w, h)))
+				if d := maxPixelDiff(got, want); d > 4 {
+					t.Fatalf("got size %v, the images differ by %d", got.Bounds().Size(), d)
+				}
+			})
+		}
+	}
+}
+
+func TestTransformJPEGErrors(t *testing.T) {
+	t.Parallel()
+
+	data := encodeTestJPEG(t, makeNoiseNRGBA(12, 32, 1))
+	if err := TransformJPEG(bytes.NewReader(data), &bytes.Buffer{}, JPEGFlipH); !errors.Is(err, errJPEGTooSmall) {
+		t.Fatalf("got error %v for a narrow image", err)
+	}
+	if err := TransformJPEG(bytes.NewReader(data), &bytes.Buffer{}, JPEGTransform(0)); !errors.Is(err, errInvalidJPEGTransform) {
+		t.Fatalf("got error %v for an invalid transformation", err)
+	}
+	if err := TransformJPEG(bytes.NewReader([]byte("not a JPEG")), &bytes.Buffer{}, JPEGFlipV); err == nil {
+		t.Fatalf("expected error for invalid data")
+	}
+}
+
+func TestAutoOrientJPEG(t *testing.T) {
+	t.Parallel()
+
+	exif := &bytes.Buffer{}
+	if err := writeTIFF(exif, binary.BigEndian, []*tiffDir{makeTestEXIF(OrientationRotate270)}); err != nil {
+		t.Fatalf("failed to write EXIF data: %v", err)
+	}
+	segment := []byte{0xff, markerAPP1, 0, 0}
+	segment = append(segment, exifHeader...)
+	segment = append(segment, exif.Bytes()...)
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(segment)-2))
+	img := encodeTestJPEG(t, makeNoiseNRGBA(48, 32, 1))
+	data := append(append(append([]byte{}, img[:2]...), segment...), img[2:]...)
+
+	buf := &bytes.Buffer{}
+	if err := AutoOrientJPEG(bytes.NewReader(data), buf); err != nil {
+		t.Fatalf("failed to transform: %v", err)
+	}
+	out := buf.Bytes()
+	if o := ReadOrientation(bytes.NewReader(out)); o != OrientationNormal {
+		t.Fatalf("got orientation %d want %d", o, OrientationNormal)
+	}
+	want, err := Decode(bytes.NewReader(data), AutoOrientation(true))
+	if err != nil {
+		t.Fatalf("failed to decode the original: %v", err)
+	}
+	got, err := Decode(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("failed to decode the result: %v", err)
+	}
+	if d := maxPixelDiff(got, want); d > 4 {
+		t.Fatalf("got size %v, the images differ by %d", got.Bounds().Size(), d)
+	}
+	meta, err := DecodeMetadata(bytes.NewReader(out))
+	if err != nil || meta.EXIF == nil || meta.EXIF.Copyright() != "Copyright Holder" {
+		t.Fatalf("the EXIF data was not kept: %v", err)
+	}
+
+	plain := encodeTestJPEG(t, makeNoiseNRGBA(8, 8, 2))
+	buf.Reset()
+	if err := AutoOrientJPEG(bytes.NewReader(plain), buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), plain) {
+		t.Fatalf("an image without orientation was changed")
+	}
+}
@@ -0,0 +1,68 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+)
+
+// ErrLimitExceeded means the image exceeds the limits set with the Limits decode option.
+var ErrLimitExceeded = errors.New("imaging: image exceeds the decode limits")
+
+// hasLimits reports whether any of the decode limits is set.
+func (c decodeConfig) hasLimits() bool {
+	return c.maxWidth > 0 || c.maxHeight > 0 || c.maxBytes > 0
+}
+
+// checkLimits reads the image header from r and checks it against the decode limits.
+// It returns a reader that yields the whole image data, including the header.
+func checkLimits(r io.Reader, cfg decodeConfig) (io.Reader, error) {
+	buf := &bytes.Buffer{}
+	c, _, err := decodeImageConfig(io.TeeReader(r, buf))
+	if err != nil {
+		return nil, err
+	}
+	if err := checkConfigLimits(c, cfg); err != nil {
+		return nil, err
+	}
+	return io.MultiReader(buf, r), nil
+}
+
+// checkConfigLimits checks the image dimensions and the estimated memory size against the decode limits.
+func checkConfigLimits(c image.Config, cfg decodeConfig) error {
+	if cfg.maxWidth > 0 && c.Width > cfg.maxWidth {
+		return fmt.Errorf("%w: width %d exceeds %d", ErrLimitExceeded, c.Width, cfg.maxWidth)
+	}
+	if cfg.maxHeight > 0 && c.Height > cfg.maxHeight {
+		return fmt.Errorf("%w: height %d exceeds %d", ErrLimitExceeded, c.Height, cfg.maxHeight)
+	}
+	if cfg.maxBytes > 0 {
+		size := int64(c.Width) * int64(c.Height) * int64(bytesPerPixel(c.ColorModel))
+		if size > cfg.maxBytes {
+			return fmt.Errorf("%w: %dx%d image needs about %d bytes, limit is %d",
+				ErrLimitExceeded, c.Width, c.Height, size, cfg.maxBytes)
+		}
+	}
+	return nil
+}
+
+// bytesPerPixel returns the number of bytes per pixel the decoders use for the color model.
+func bytesPerPixel(m color.Model) int {
+	if _, ok := m.(color.Palette); ok {
+		return 1
+	}
+	switch m {
+	case color.GrayModel, color.AlphaModel:
+		return 1
+	case color.Gray16Model, color.Alpha16Model:
+		return 2
+	case color.YCbCrModel:
+		return 3
+	case color.RGBA64Model, color.NRGBA64Model:
+		return 8
+	}
+	return 4
+}
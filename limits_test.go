@@ -0,0 +1,136 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// makePNGHeader returns the PNG signature and the IHDR chunk of an 8-bit RGBA
+// image with the given dimensions, without any pixel data.
+func makePNGHeader(w, h uint32) []byte {
+	buf := &bytes.Buffer{}
+	buf.WriteString("\x89PNG\r\n\x1a\n")
+	chunk := make([]byte, 4+13)
+	copy(chunk, "IHDR")
+	binary.BigEndian.PutUint32(chunk[4:], w)
+	binary.BigEndian.PutUint32(chunk[8:], h)
+	chunk[12], chunk[13] = 8, 6
+	var n [4]byte
+	binary.BigEndian.PutUint32(n[:], 13)
+	buf.Write(n[:])
+	buf.Write(chunk)
+	binary.BigEndian.PutUint32(n[:], crc32.ChecksumIEEE(chunk))
+	buf.Write(n[:])
+	return buf.Bytes()
+}
+
+func TestDecodeLimits(t *testing.T) {
+	t.Parallel()
+
+	src := New(40, 30, color.NRGBA{0x10, 0x20, 0x30, 0xff})
+	encoded := map[Format][]byte{}
+	for _, f := range []Format{JPEG, PNG, GIF, TIFF, BMP} {
+		buf := &bytes.Buffer{}
+		if err := Encode(buf, src, f); err != nil {
+			t.Fatalf("failed to encode %s: %v", f, err)
+		}
+		encoded[f] = buf.Bytes()
+	}
+
+	testCases := []struct {
+		name string
+		opts []DecodeOption
+		err  error
+	}{
+		{name: "within limits", opts: []DecodeOption{Limits(40, 30, 40*30*4)}},
+		{name: "no limits", opts: []DecodeOption{Limits(0, 0, 0)}},
+		{name: "width", opts: []DecodeOption{Limits(39, 0, 0)}, err: ErrLimitExceeded},
+		{name: "height", opts: []DecodeOption{Limits(0, 29, 0)}, err: ErrLimitExceeded},
+		{name: "bytes", opts: []DecodeOption{Limits(0, 0, 100)}, err: ErrLimitExceeded},
+		{name: "with auto-orientation", opts: []DecodeOption{Limits(100, 100, 0), AutoOrientation(true)}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			for f, data := range encoded {
+				img, err := Decode(bytes.NewReader(data), tc.opts...)
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("%s: got error %v want %v", f, err, tc.err)
+				}
+				if err != nil {
+					continue
+				}
+				if img.Bounds() != src.Bounds() {
+					t.Fatalf("%s: got bounds %v want %v", f, img.Bounds(), src.Bounds())
+				}
+				if f != GIF && !compareNRGBA(Clone(img), src, 8) {
+					t.Fatalf("%s: decoded image differs", f)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeLimitsBomb(t *testing.T) {
+	t.Parallel()
+
+	header := makePNGHeader(100000, 100000)
+	if c, _, err := image.DecodeConfig(bytes.NewReader(header)); err != nil || c.Width != 100000 {
+		t.Fatalf("invalid test header: %v %v", c, err)
+	}
+	_, err := Decode(bytes.NewReader(header), Limits(0, 0, 512<<20))
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("got error %v want %v", err, ErrLimitExceeded)
+	}
+	_, err = Decode(bytes.NewReader(header[:10]), Limits(0, 0, 512<<20))
+	if err == nil || errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("got error %v want a header decoding error", err)
+	}
+}
+
+func TestDecodeAllLimits(t *testing.T) {
+	t.Parallel()
+
+	pages := []image.Image{New(4, 4, color.White), New(64, 4, color.Black)}
+	buf := &bytes.Buffer{}
+	if err := EncodeAll(buf, pages, TIFF); err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	if _, err := DecodeAll(bytes.NewReader(buf.Bytes()), Limits(32, 0, 0)); !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("got error %v want %v", err, ErrLimitExceeded)
+	}
+	imgs, err := DecodeAll(bytes.NewReader(buf.Bytes()), Limits(64, 4, 0))
+	if err != nil || len(imgs) != 2 {
+		t.Fatalf("got %d pages, error %v", len(imgs), err)
+	}
+}
+
+func TestBytesPerPixel(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		model color.Model
+		want  int
+	}{
+		{color.Palette{color.Black}, 1},
+		{color.GrayModel, 1},
+		{color.Gray16Model, 2},
+		{color.YCbCrModel, 3},
+		{color.NRGBAModel, 4},
+		{color.CMYKModel, 4},
+		{color.RGBA64Model, 8},
+	}
+	for _, tc := range testCases {
+		if got := bytesPerPixel(tc.model); got != tc.want {
+			t.Fatalf("%T: got %d want %d", tc.model, got, tc.want)
+		}
+	}
+}
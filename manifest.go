@@ -0,0 +1,225 @@
+package imaging
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	iofs "io/fs"
+	"sync"
+)
+
+// ManifestEntry describes how an output file was produced.
+type ManifestEntry struct {
+	// Source is the name of the source file.
+	Source string `json:"source"`
+	// SourceHash is the SHA-256 hash of the source file content, as returned by HashFile.
+	SourceHash string `json:"source_hash"`
+	// Spec describes the processing of the source, e.g. the String of a Pipeline.
+	Spec string `json:"spec"`
+}
+
+// Manifest is a ledger of the output files along with the source content and the
+// processing they were produced from. It is persisted between the runs of a batch,
+// so that the outputs of the unchanged sources are skipped instead of rebuilt.
+// A Manifest is safe for concurrent use.
+//
+// Example:
+//
+//	m, err := imaging.LoadManifest("thumbs/.manifest.json")
+//	...
+//	for _, name := range names {
+//		skipped, err := p.ProcessIncremental(m, name, "thumbs/"+name)
+//		...
+//	}
+//	err = m.Save()
+type Manifest struct {
+	mu      sync.Mutex
+	name    string
+	outputs map[string]ManifestEntry
+}
+
+// LoadManifest loads the manifest file. A missing file results in an empty manifest,
+// which is created by Save.
+func LoadManifest(name string) (*Manifest, error) {
+	m := &Manifest{name: name, outputs: map[string]ManifestEntry{}}
+	file, err := openFile(name)
+	if errors.Is(err, iofs.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close() //nolint
+	if err := json.NewDecoder(file).Decode(&m.outputs); err != nil {
+		return nil, fmt.Errorf("imaging: manifest %s: %w", name, err)
+	}
+	if m.outputs == nil {
+		m.outputs = map[string]ManifestEntry{}
+	}
+	return m, nil
+}
+
+// Save writes the manifest back to its file. Local files are replaced atomically,
+// as in SaveAtomic.
+func (m *Manifest) Save() error {
+	m.mu.Lock()
+	data, err := json.MarshalIndent(m.outputs, "", "  ")
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	write := func(w io.Writer) error {
+		_, err := w.Write(append(data, '\n'))
+		return err
+	}
+	if _, ok := lookupStorage(m.name); !ok {
+		return writeAtomic(m.name, write)
+	}
+	file, err := createFile(m.name)
+	if err != nil {
+		return err
+	}
+	err = write(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Lookup returns the entry of the output file.
+func (m *Manifest) Lookup(output string) (ManifestEntry, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.outputs[output]
+	return e, ok
+}
+
+// UpToDate reports whether the output file exists and was produced as the entry
+// describes, i.e. from the same source content with the same spec.
+func (m *Manifest) UpToDate(output string, entry ManifestEntry) bool {
+	if e, ok := m.Lookup(output); !ok || e != entry {
+		return false
+	}
+	file, err := openFile(output)
+	if err != nil {
+		return false
+	}
+	file.Close() //nolint
+	return true
+}
+
+// Record records the entry of the output file, replacing the previous one.
+func (m *Manifest) Record(output string, entry ManifestEntry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.outputs[output] = entry
+}
+
+// HashFile returns the hex encoded SHA-256 hash of the file content.
+func HashFile(name string) (string, error) {
+	file, err := openFile(name)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close() //nolint
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashBytes returns the hash of the data as HashFile does.
+func hashBytes(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+// readFile reads the whole file from its storage or the file system.
+func readFile(name string) ([]byte, error) {
+	file, err := openFile(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close() //nolint
+	return io.ReadAll(file)
+}
+
+// ProcessIncremental is like Process, but skips the source if the manifest records
+// that dst was produced from the same source content by a pipeline with the same
+// String, decode and encode options and Strip setting, and dst still exists. Otherwise
+// the source is processed and recorded in the manifest, which must be saved by the
+// caller. It reports whether the source was skipped.
+//
+// The String of the pipeline is compared, so the names of the operations should
+// include their parameters, e.g. "fit 800x600" rather than "fit". The options are
+// compared by their settings, e.g. the JPEG quality, except the metadata, the GIF
+// quantizer and drawer and the C2PA manifest, which are only compared by whether
+// they are set.
+func (p *Pipeline) ProcessIncremental(m *Manifest, src, dst string) (skipped bool, err error) {
+	data, err := readFile(src)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", src, err)
+	}
+	entry := ManifestEntry{Source: src, SourceHash: hashBytes(data), Spec: p.spec()}
+	if m.UpToDate(dst, entry) {
+		return true, nil
+	}
+
+	var img image.Image
+	var meta *Metadata
+	switch decode := customDecoder(src); {
+	case decode != nil:
+		img, err = decodeCustom(bytes.NewReader(data), decode, p.decodeOpts)
+	case p.strip:
+		img, err = Decode(bytes.NewReader(data), p.decodeOpts...)
+	default:
+		img, meta, err = DecodeWithMetadata(bytes.NewReader(data), p.decodeOpts...)
+	}
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", src, err)
+	}
+	if err := Save(p.Apply(img), dst, p.outputOptions(meta)...); err != nil {
+		return false, fmt.Errorf("%s: %w", dst, err)
+	}
+	m.Record(dst, entry)
+	return false, nil
+}
+
+// spec returns the description of the pipeline recorded in manifests: the String
+// of the pipeline followed by the settings of its decode and encode options and
+// whether it strips the metadata.
+func (p *Pipeline) spec() string {
+	dc := defaultDecodeConfig
+	for _, option := range p.decodeOpts {
+		option(&dc)
+	}
+	ec := defaultEncodeConfig
+	for _, option := range p.encodeOpts {
+		option(&ec)
+	}
+	return fmt.Sprintf("%s; decode %s; encode %s; strip=%t", p.String(), dc.describe(), ec.describe(), p.strip)
+}
+
+// describe returns the settings of the decode options.
+func (c *decodeConfig) describe() string {
+	return fmt.Sprintf("auto-orient=%t 16-bit=%t limits=%dx%d/%d max-download=%d srgb=%t",
+		c.autoOrientation, c.preserve16Bit, c.maxWidth, c.maxHeight, c.maxBytes, c.maxDownloadSize, c.convertToSRGB)
+}
+
+// describe returns the settings of the encode options. The metadata, the GIF
+// quantizer and drawer and the C2PA manifest are only described as set or not.
+func (c *encodeConfig) describe() string {
+	return fmt.Sprintf("jpeg-quality=%d jpeg-min-quality=%d gif-colors=%d gif-quantizer=%t gif-drawer=%t "+
+		"gif-interlaced=%t gif-transparent=%v png-compression=%d tiff-compression=%d tiff-predictor=%t "+
+		"tiff-tile=%dx%d bigtiff=%t metadata=%t exif=%t artist=%q copyright=%q pdf-page=%gx%g c2pa=%t deterministic=%t",
+		c.jpegQuality, c.jpegMinQuality, c.gifNumColors, c.gifQuantizer != nil, c.gifDrawer != nil,
+		c.gifInterlaced, c.gifTransparentColor, c.pngCompressionLevel, c.tiffCompression, c.tiffPredictor,
+		c.tiffTileWidth, c.tiffTileHeight, c.tiffBigTIFF, c.metadata != nil, c.exif != nil, c.artist, c.copyright,
+		c.pdfPageSize.Width, c.pdfPageSize.Height, c.c2pa != nil, c.deterministic)
+}
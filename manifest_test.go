@@ -0,0 +1,151 @@
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-spectest/imaging/storage/memfs"
+)
+
+func TestManifest(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	name := filepath.Join(dir, "manifest.json")
+	m, err := LoadManifest(name)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := filepath.Join(dir, "out.png")
+	entry := ManifestEntry{Source: "in.png", SourceHash: "abc", Spec: "fit 8x8"}
+	if m.UpToDate(out, entry) {
+		t.Fatalf("an empty manifest is up to date")
+	}
+	m.Record(out, entry)
+	if m.UpToDate(out, entry) {
+		t.Fatalf("a missing output is up to date")
+	}
+	if err := os.WriteFile(out, []byte("data"), 0o644); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	if !m.UpToDate(out, entry) {
+		t.Fatalf("the recorded output is not up to date")
+	}
+	if err := m.Save(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	m, err = LoadManifest(name)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, ok := m.Lookup(out); !ok || got != entry {
+		t.Fatalf("got entry %v, %t want %v", got, ok, entry)
+	}
+	changed := entry
+	changed.Spec = "fit 16x16"
+	if m.UpToDate(out, changed) {
+		t.Fatalf("the output of a changed spec is up to date")
+	}
+
+	if err := os.WriteFile(name, []byte("bad data"), 0o644); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	if _, err := LoadManifest(name); err == nil {
+		t.Fatalf("expected error loading bad data")
+	}
+}
+
+func TestHashFile(t *testing.T) {
+	t.Parallel()
+
+	name := filepath.Join(t.TempDir(), "a.txt")
+	if err := os.WriteFile(name, []byte("abc"), 0o644); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	got, err := HashFile(name)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+	if got != want || hashBytes([]byte("abc")) != want {
+		t.Fatalf("got hash %s want %s", got, want)
+	}
+	if _, err := HashFile(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Fatalf("expected error hashing a missing file")
+	}
+}
+
+func TestProcessIncremental(t *testing.T) {
+	mem := memfs.New()
+	RegisterStorage("mem", mem)
+	defer RegisterStorage("mem", nil)
+	save := func(name string, c color.Color) {
+		buf := &bytes.Buffer{}
+		if err := Encode(buf, New(8, 6, c), PNG); err != nil {
+			t.Fatalf("failed to encode: %v", err)
+		}
+		mem.WriteFile(name, buf.Bytes())
+	}
+	save("mem://src/a.png", color.White)
+	save("mem://src/b.png", color.Black)
+
+	fit := func(size int) *Pipeline {
+		return NewPipeline().Then(fmt.Sprintf("fit %dx%d", size, size), func(img image.Image) image.Image {
+			return Fit(img, size, size, Box)
+		})
+	}
+	m, err := LoadManifest("mem://manifest.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	run := func(p *Pipeline, want ...bool) {
+		t.Helper()
+		for i, name := range []string{"a.png", "b.png"} {
+			skipped, err := p.ProcessIncremental(m, "mem://src/"+name, "mem://dst/"+name)
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", name, err)
+			}
+			if skipped != want[i] {
+				t.Fatalf("%s: got skipped %t want %t", name, skipped, want[i])
+			}
+		}
+	}
+
+	run(fit(4), false, false)
+	run(fit(4), true, true)
+	if err := m.Save(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m, err = LoadManifest("mem://manifest.json"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	run(fit(4), true, true)
+
+	// A changed source and a removed output are processed again.
+	save("mem://src/a.png", color.Gray{0x80})
+	mem.Remove("mem://dst/b.png") //nolint
+	run(fit(4), false, false)
+	run(fit(4), true, true)
+
+	// A changed pipeline processes all the sources.
+	run(fit(4).Then("grayscale", func(img image.Image) image.Image { return Grayscale(img) }), false, false)
+
+	// Changed encode and decode options process all the sources too.
+	jpeg := func(quality int) *Pipeline {
+		return fit(4).WithEncodeOptions(JPEGQuality(quality))
+	}
+	run(jpeg(80), false, false)
+	run(jpeg(80), true, true)
+	run(jpeg(60), false, false)
+	run(jpeg(60).WithDecodeOptions(AutoOrientation(true)), false, false)
+
+	if _, err := fit(4).ProcessIncremental(m, "mem://src/missing.png", "mem://dst/missing.png"); err == nil {
+		t.Fatalf("expected error processing a missing file")
+	}
+}
@@ -0,0 +1,229 @@
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"io"
+)
+
+// Metadata holds the image metadata that is not a part of the pixel data.
+// The processing functions only operate on pixels, so the metadata is read
+// separately with OpenMetadata or DecodeMetadata and written with the
+// WithMetadata encode option.
+type Metadata struct {
+	// GeoTIFF holds the GeoTIFF tags of a TIFF image, nil if there are none.
+	GeoTIFF *GeoTIFF
+	// EXIF holds the EXIF metadata of a JPEG or TIFF image, nil if there is none.
+	EXIF *EXIF
+	// ICCProfile holds the embedded ICC color profile of a JPEG, PNG or TIFF image,
+	// nil if there is none.
+	ICCProfile []byte
+	// XMP holds the descriptive fields of the XMP packet of a JPEG, PNG or TIFF image,
+	// nil if there is none.
+	XMP *XMP
+	// IPTC holds the IPTC-IIM fields of a JPEG or TIFF image, nil if there are none.
+	IPTC *IPTC
+	// C2PA holds the C2PA manifest store of a JPEG or PNG image, nil if there is none.
+	C2PA *C2PA
+}
+
+// Title returns the title of the image from the XMP or IPTC metadata,
+// or an empty string if there is none.
+func (m *Metadata) Title() string {
+	if m.XMP != nil && m.XMP.Title != "" {
+		return m.XMP.Title
+	}
+	if m.IPTC != nil {
+		return m.IPTC.Title
+	}
+	return ""
+}
+
+// Keywords returns the keywords of the image from the XMP or IPTC metadata.
+func (m *Metadata) Keywords() []string {
+	if m.XMP != nil && len(m.XMP.Keywords) > 0 {
+		return m.XMP.Keywords
+	}
+	if m.IPTC != nil {
+		return m.IPTC.Keywords
+	}
+	return nil
+}
+
+// Creators returns the authors of the image from the XMP, IPTC or EXIF metadata.
+func (m *Metadata) Creators() []string {
+	if m.XMP != nil && len(m.XMP.Creators) > 0 {
+		return m.XMP.Creators
+	}
+	if m.IPTC != nil && len(m.IPTC.Creators) > 0 {
+		return m.IPTC.Creators
+	}
+	if m.EXIF != nil && m.EXIF.Artist() != "" {
+		return []string{m.EXIF.Artist()}
+	}
+	return nil
+}
+
+// OpenMetadata reads the metadata of the image file.
+//
+// Example:
+//
+//	meta, err := imaging.OpenMetadata("map.tif")
+func OpenMetadata(filename string) (m *Metadata, err error) {
+	file, err := openFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			if err == nil {
+				err = closeErr
+			} else {
+				err = fmt.Errorf("original error: %s, defer close error: %w", err.Error(), closeErr)
+			}
+		}
+	}()
+	return DecodeMetadata(file)
+}
+
+// DecodeMetadata reads the metadata of the image from io.Reader. The EXIF and IPTC
+// data is read from JPEG and TIFF images, the ICC profile and the XMP packet from
+// JPEG, PNG and TIFF images, the C2PA manifest store from JPEG and PNG images and
+// the GeoTIFF tags from TIFF images. Images without supported metadata result in
+// empty Metadata.
+//
+// The XMP, IPTC and C2PA fields are only read: the WithMetadata option doesn't write
+// them. A new C2PA manifest is attached with the WithC2PA option.
+func DecodeMetadata(r io.Reader) (*Metadata, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	m := &Metadata{ICCProfile: readICCProfile(data)}
+	if packet := readXMP(data); packet != nil {
+		m.XMP = parseXMP(packet)
+	}
+	if records := readIPTC(data); records != nil {
+		m.IPTC = parseIPTC(records)
+	}
+	m.C2PA = readC2PA(data)
+	switch {
+	case isTIFF(data):
+		dirs, err := parseTIFF(data)
+		if err != nil {
+			return nil, err
+		}
+		m.GeoTIFF = readGeoTIFF(dirs[0])
+		m.EXIF = newEXIF(dirs[0])
+	case isJPEG(data):
+		if exif := readJPEGEXIF(data); exif != nil {
+			// Broken EXIF data is ignored like the decoders do.
+			if dirs, err := parseTIFF(exif); err == nil {
+				m.EXIF = newEXIF(dirs[0])
+			}
+		}
+	}
+	return m, nil
+}
+
+// OpenWithMetadata loads an image from file along with its metadata, so that
+// the metadata can be written back when the processed image is saved. It accepts
+// the same options as Open. If the image is rotated by the AutoOrientation
+// option, the orientation tag is removed from the EXIF data.
+//
+// Example:
+//
+//	img, meta, err := imaging.OpenWithMetadata("photo.jpg", imaging.AutoOrientation(true))
+//	...
+//	dstImage := imaging.Fit(img, 1600, 1600, imaging.Lanczos)
+//	err = imaging.Save(dstImage, "photo_small.jpg", imaging.WithMetadata(meta))
+func OpenWithMetadata(filename string, opts ...DecodeOption) (img image.Image, m *Metadata, err error) {
+	file, err := openFile(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			if err == nil {
+				err = closeErr
+			} else {
+				err = fmt.Errorf("original error: %s, defer close error: %w", err.Error(), closeErr)
+			}
+		}
+	}()
+	return DecodeWithMetadata(file, opts...)
+}
+
+// DecodeWithMetadata reads an image along with its metadata from io.Reader
+// in the same way as OpenWithMetadata.
+func DecodeWithMetadata(r io.Reader, opts ...DecodeOption) (image.Image, *Metadata, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	m, err := DecodeMetadata(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	img, err := Decode(bytes.NewReader(data), opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cfg := defaultDecodeConfig
+	for _, option := range opts {
+		option(&cfg)
+	}
+	// The orientation is only applied to JPEG images, see ReadOrientation.
+	if m.EXIF != nil && cfg.autoOrientation && ReadOrientation(bytes.NewReader(data)) != OrientationUnspecified {
+		m.EXIF.dir.remove(tagOrientation)
+	}
+	// The converted pixels are sRGB, so the original profile no longer applies.
+	if p, ok := parseICCProfile(m.ICCProfile); cfg.convertToSRGB && ok && !p.isSRGB() {
+		m.ICCProfile = nil
+	}
+	return img, m, nil
+}
+
+// writeTIFF sets the metadata tags of the TIFF directory.
+func (m *Metadata) writeTIFF(d *tiffDir) {
+	if m.GeoTIFF != nil {
+		m.GeoTIFF.writeTo(d)
+	}
+	if !m.EXIF.empty() {
+		m.EXIF.writeTIFF(d)
+	}
+	if len(m.ICCProfile) > 0 {
+		d.set(tagICCProfile, tiffUndefined, uint32(len(m.ICCProfile)), m.ICCProfile)
+	}
+}
+
+// encodeJPEGWithMetadata writes the image to w in JPEG format with the EXIF data
+// and the ICC profile stored in the APP1 and APP2 segments right after the start
+// of image marker.
+func encodeJPEGWithMetadata(w io.Writer, img image.Image, m *Metadata, quality int) error {
+	var segments [][]byte
+	if !m.EXIF.empty() {
+		b := img.Bounds()
+		segment, err := m.EXIF.jpegSegment(b.Dx(), b.Dy())
+		if err != nil {
+			return err
+		}
+		segments = append(segments, segment)
+	}
+	segments = append(segments, jpegICCSegments(m.ICCProfile)...)
+
+	buf := &bytes.Buffer{}
+	if err := encodeJPEG(buf, img, quality); err != nil {
+		return err
+	}
+	data := buf.Bytes()
+	parts := append([][]byte{data[:2]}, segments...)
+	for _, part := range append(parts, data[2:]) {
+		if _, err := w.Write(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDecodeMetadata(t *testing.T) {
+	t.Parallel()
+
+	img := makeNoiseNRGBA(4, 4, 1)
+	for _, f := range []Format{PNG, TIFF} {
+		buf := &bytes.Buffer{}
+		if err := Encode(buf, img, f); err != nil {
+			t.Fatalf("%s: failed to encode: %v", f, err)
+		}
+		meta, err := DecodeMetadata(buf)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", f, err)
+		}
+		if meta.GeoTIFF != nil {
+			t.Fatalf("%s: got GeoTIFF tags for an image without them", f)
+		}
+	}
+
+	if _, err := DecodeMetadata(bytes.NewReader([]byte("II*\x00\xff\x00\x00\x00"))); err == nil {
+		t.Fatalf("expected error for an invalid TIFF")
+	}
+}
+
+func TestOpenMetadata(t *testing.T) {
+	dir, err := os.MkdirTemp("", "imaging")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir) //nolint
+
+	filename := filepath.Join(dir, "geo.tif")
+	meta := &Metadata{GeoTIFF: makeGeoTIFF()}
+	if err := Save(makeNoiseNRGBA(8, 8, 2), filename, WithMetadata(meta)); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+	got, err := OpenMetadata(filename)
+	if err != nil {
+		t.Fatalf("failed to open metadata: %v", err)
+	}
+	if got.GeoTIFF == nil || got.GeoTIFF.GDALNoData != "-9999" {
+		t.Fatalf("got GeoTIFF tags %+v", got.GeoTIFF)
+	}
+	if _, err := OpenMetadata(filepath.Join(dir, "missing.tif")); err == nil {
+		t.Fatalf("expected error opening a missing file")
+	}
+}
+
+// makeTestEXIF returns an EXIF directory with camera, copyright, date and GPS data.
+func makeTestEXIF(orientation Orientation) *tiffDir {
+	dir := newTIFFDir(binary.BigEndian)
+	dir.setASCII(271, "Camera Maker")
+	dir.setASCII(33432, "Copyright Holder")
+	dir.setUints(tagOrientation, tiffShort, uint32(orientation))
+	exif := newTIFFDir(binary.BigEndian)
+	exif.setASCII(tagDateTimeOriginal, "2020:02:03 04:05:06")
+	exif.setUints(tagPixelXDimension, tiffShort, 16)
+	exif.setUints(tagPixelYDimension, tiffShort, 8)
+	dir.subs[tagExifIFD] = exif
+	gps := newTIFFDir(binary.BigEndian)
+	gps.setASCII(1, "N")
+	dir.subs[tagGPSIFD] = gps
+	return dir
+}
+
+func TestEXIFPreservation(t *testing.T) {
+	t.Parallel()
+
+	src := makeEXIFJPEG(t, makeTestEXIF(OrientationRotate270))
+
+	for _, f := range []Format{JPEG, TIFF} {
+		img, meta, err := DecodeWithMetadata(bytes.NewReader(src), AutoOrientation(true))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if meta.EXIF == nil || meta.EXIF.Orientation() != OrientationUnspecified {
+			t.Fatalf("got EXIF %+v want EXIF without orientation", meta.EXIF)
+		}
+		dst := Resize(img, 4, 0, Box)
+
+		buf := &bytes.Buffer{}
+		if err := Encode(buf, dst, f, WithMetadata(meta)); err != nil {
+			t.Fatalf("%s: failed to encode: %v", f, err)
+		}
+		decoded, err := Decode(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("%s: failed to decode: %v", f, err)
+		}
+		if decoded.Bounds() != dst.Bounds() {
+			t.Fatalf("%s: got bounds %v want %v", f, decoded.Bounds(), dst.Bounds())
+		}
+
+		got, err := DecodeMetadata(buf)
+		if err != nil {
+			t.Fatalf("%s: failed to decode metadata: %v", f, err)
+		}
+		if got.EXIF == nil {
+			t.Fatalf("%s: EXIF data not written", f)
+		}
+		if s := got.EXIF.dir.ascii(33432); s != "Copyright Holder" {
+			t.Fatalf("%s: got copyright %q", f, s)
+		}
+		if date, ok := got.EXIF.DateTime(); !ok || !date.Equal(time.Date(2020, 2, 3, 4, 5, 6, 0, time.UTC)) {
+			t.Fatalf("%s: got date %v, %v", f, date, ok)
+		}
+		if got.EXIF.Orientation() != OrientationUnspecified {
+			t.Fatalf("%s: got orientation %v", f, got.EXIF.Orientation())
+		}
+		exif := got.EXIF.dir.subs[tagExifIFD]
+		if exif == nil || exif.uint(tagPixelXDimension) != 4 || exif.uint(tagPixelYDimension) != uint32(dst.Bounds().Dy()) {
+			t.Fatalf("%s: pixel dimensions not updated", f)
+		}
+		if gps := got.EXIF.dir.subs[tagGPSIFD]; gps == nil || gps.ascii(1) != "N" {
+			t.Fatalf("%s: GPS data not written", f)
+		}
+	}
+
+	// Without auto-orientation the image is not rotated and the orientation is kept.
+	_, meta, err := DecodeWithMetadata(bytes.NewReader(src))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if meta.EXIF.Orientation() != OrientationRotate270 {
+		t.Fatalf("got orientation %v want %v", meta.EXIF.Orientation(), OrientationRotate270)
+	}
+}
+
+func TestOpenWithMetadata(t *testing.T) {
+	img, meta, err := OpenWithMetadata("testdata/orientation_6.jpg", AutoOrientation(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want, err := Open("testdata/orientation_6.jpg", AutoOrientation(true))
+	if err != nil {
+		t.Fatalf("failed to open: %v", err)
+	}
+	if img.Bounds() != want.Bounds() {
+		t.Fatalf("got bounds %v want %v", img.Bounds(), want.Bounds())
+	}
+	if meta.EXIF != nil && meta.EXIF.Orientation() != OrientationUnspecified {
+		t.Fatalf("got orientation %v", meta.EXIF.Orientation())
+	}
+	if _, _, err := OpenWithMetadata("testdata/missing.jpg"); err == nil {
+		t.Fatalf("expected error opening a missing file")
+	}
+}
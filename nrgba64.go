@@ -0,0 +1,279 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+)
+
+// is16Bit reports whether the image stores more than 8 bits per color channel.
+func is16Bit(img image.Image) bool {
+	switch img.(type) {
+	case *image.NRGBA64, *image.RGBA64, *image.Gray16, *image.Alpha16:
+		return true
+	}
+	return false
+}
+
+// Clone64 returns a copy of the given image as *image.NRGBA64 (64bit RGBA colors,
+// non-premultiplied alpha). Unlike Clone, it keeps the full precision of 16-bit sources.
+func Clone64(img image.Image) *image.NRGBA64 {
+	b := img.Bounds()
+	dst := image.NewNRGBA64(image.Rect(0, 0, b.Dx(), b.Dy()))
+	if src, ok := img.(*image.NRGBA64); ok {
+		size := b.Dx() * 8
+		parallel(0, b.Dy(), func(ys <-chan int) {
+			for y := range ys {
+				i := src.PixOffset(b.Min.X, b.Min.Y+y)
+				j := y * dst.Stride
+				copy(dst.Pix[j:j+size], src.Pix[i:i+size])
+			}
+		})
+		return dst
+	}
+
+	parallel(0, b.Dy(), func(ys <-chan int) {
+		for y := range ys {
+			for x := 0; x < b.Dx(); x++ {
+				c := color.NRGBA64Model.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.NRGBA64)
+				dst.SetNRGBA64(x, y, c)
+			}
+		}
+	})
+	return dst
+}
+
+// toNRGBA64 converts image.Image to *image.NRGBA64.
+func toNRGBA64(img image.Image) *image.NRGBA64 {
+	if img, ok := img.(*image.NRGBA64); ok {
+		return &image.NRGBA64{
+			Pix:    img.Pix,
+			Stride: img.Stride,
+			Rect:   img.Rect.Sub(img.Rect.Min),
+		}
+	}
+	return Clone64(img)
+}
+
+// Crop64 cuts out a rectangular region with the specified bounds from the image
+// and returns the cropped image, keeping 16 bits per color channel.
+func Crop64(img image.Image, rect image.Rectangle) *image.NRGBA64 {
+	r := rect.Intersect(img.Bounds())
+	if r.Empty() {
+		return &image.NRGBA64{}
+	}
+	src := toNRGBA64(img)
+	r = r.Sub(img.Bounds().Min)
+	dst := image.NewNRGBA64(image.Rect(0, 0, r.Dx(), r.Dy()))
+	size := r.Dx() * 8
+	parallel(r.Min.Y, r.Max.Y, func(ys <-chan int) {
+		for y := range ys {
+			i := src.PixOffset(r.Min.X, y)
+			j := (y - r.Min.Y) * dst.Stride
+			copy(dst.Pix[j:j+size], src.Pix[i:i+size])
+		}
+	})
+	return dst
+}
+
+// remap64 builds a dstW x dstH image where each pixel is copied from the source
+// pixel returned by the srcPt function.
+func remap64(img *image.NRGBA64, dstW, dstH int, srcPt func(x, y int) (int, int)) *image.NRGBA64 {
+	dst := image.NewNRGBA64(image.Rect(0, 0, dstW, dstH))
+	parallel(0, dstH, func(ys <-chan int) {
+		for y := range ys {
+			j := y * dst.Stride
+			for x := 0; x < dstW; x++ {
+				sx, sy := srcPt(x, y)
+				i := img.PixOffset(sx, sy)
+				copy(dst.Pix[j:j+8], img.Pix[i:i+8])
+				j += 8
+			}
+		}
+	})
+	return dst
+}
+
+// fixOrientation64 is the 16-bit counterpart of FixOrientation.
+func fixOrientation64(img image.Image, o Orientation) *image.NRGBA64 {
+	src := toNRGBA64(img)
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	switch o {
+	case OrientationFlipH:
+		return remap64(src, w, h, func(x, y int) (int, int) { return w - x - 1, y })
+	case OrientationFlipV:
+		return remap64(src, w, h, func(x, y int) (int, int) { return x, h - y - 1 })
+	case OrientationRotate90:
+		return remap64(src, h, w, func(x, y int) (int, int) { return w - y - 1, x })
+	case OrientationRotate180:
+		return remap64(src, w, h, func(x, y int) (int, int) { return w - x - 1, h - y - 1 })
+	case OrientationRotate270:
+		return remap64(src, h, w, func(x, y int) (int, int) { return y, h - x - 1 })
+	case OrientationTranspose:
+		return remap64(src, h, w, func(x, y int) (int, int) { return y, x })
+	case OrientationTransverse:
+		return remap64(src, h, w, func(x, y int) (int, int) { return w - y - 1, h - x - 1 })
+	}
+	return src
+}
+
+// Resize64 resizes the image to the specified width and height using the specified
+// resampling filter and returns the transformed image, keeping 16 bits per color channel.
+// If one of width or height is 0, the image aspect ratio is preserved.
+//
+// Example:
+//
+//	dstImage := imaging.Resize64(srcImage, 800, 600, imaging.Lanczos)
+func Resize64(img image.Image, width, height int, filter ResampleFilter) *image.NRGBA64 {
+	srcW, srcH := img.Bounds().Dx(), img.Bounds().Dy()
+	dstW, dstH := resizeDimensions(srcW, srcH, width, height)
+	if dstW == 0 {
+		return &image.NRGBA64{}
+	}
+
+	src := toNRGBA64(img)
+	if srcW == dstW && srcH == dstH {
+		return Clone64(src)
+	}
+
+	if filter.Support <= 0 {
+		// Nearest-neighbor special case.
+		dx := float64(srcW) / float64(dstW)
+		dy := float64(srcH) / float64(dstH)
+		return remap64(src, dstW, dstH, func(x, y int) (int, int) {
+			return int((float64(x) + 0.5) * dx), int((float64(y) + 0.5) * dy)
+		})
+	}
+
+	if srcW != dstW {
+		src = resample64(src, dstW, srcH, precomputeWeights(dstW, srcW, filter), true)
+	}
+	if srcH != dstH {
+		src = resample64(src, dstW, dstH, precomputeWeights(dstH, srcH, filter), false)
+	}
+	return src
+}
+
+// resample64 applies the precomputed weights along one axis of the image.
+// If horizontal is false, the weights are applied along the vertical axis.
+func resample64(src *image.NRGBA64, dstW, dstH int, weights [][]indexWeight, horizontal bool) *image.NRGBA64 {
+	dst := image.NewNRGBA64(image.Rect(0, 0, dstW, dstH))
+	parallel(0, dstH, func(ys <-chan int) {
+		for y := range ys {
+			for x := 0; x < dstW; x++ {
+				var ws []indexWeight
+				if horizontal {
+					ws = weights[x]
+				} else {
+					ws = weights[y]
+				}
+				var r, g, b, a float64
+				for _, w := range ws {
+					var i int
+					if horizontal {
+						i = src.PixOffset(w.index, y)
+					} else {
+						i = src.PixOffset(x, w.index)
+					}
+					s := src.Pix[i : i+8 : i+8]
+					aw := float64(uint16(s[6])<<8|uint16(s[7])) * w.weight
+					r += float64(uint16(s[0])<<8|uint16(s[1])) * aw
+					g += float64(uint16(s[2])<<8|uint16(s[3])) * aw
+					b += float64(uint16(s[4])<<8|uint16(s[5])) * aw
+					a += aw
+				}
+				if a != 0 {
+					aInv := 1 / a
+					dst.SetNRGBA64(x, y, color.NRGBA64{
+						R: clamp16(r * aInv),
+						G: clamp16(g * aInv),
+						B: clamp16(b * aInv),
+						A: clamp16(a),
+					})
+				}
+			}
+		}
+	})
+	return dst
+}
+
+// clamp16 rounds and clamps float64 value to fit into uint16.
+func clamp16(x float64) uint16 {
+	v := int64(x + 0.5)
+	if v > 0xffff {
+		v = 0xffff
+	}
+	if v < 0 {
+		v = 0
+	}
+	return uint16(v)
+}
+
+// Open16 loads an image from file as *image.NRGBA64. 16-bit sources keep their
+// full precision, 8-bit sources are widened without changing their colors.
+// It accepts the same options as Open.
+//
+// Example:
+//
+//	img, err := imaging.Open16("scan.tif", imaging.AutoOrientation(true))
+//	...
+//	err = imaging.Save16(imaging.Resize64(img, 1200, 0, imaging.Lanczos), "scan_small.png")
+func Open16(filename string, opts ...DecodeOption) (*image.NRGBA64, error) {
+	img, err := Open(filename, append(opts, Preserve16Bit(true))...)
+	if err != nil {
+		return nil, err
+	}
+	return toNRGBA64(img), nil
+}
+
+// Decode16 reads an image from io.Reader as *image.NRGBA64 in the same way as Open16.
+func Decode16(r io.Reader, opts ...DecodeOption) (*image.NRGBA64, error) {
+	img, err := Decode(r, append(opts, Preserve16Bit(true))...)
+	if err != nil {
+		return nil, err
+	}
+	return toNRGBA64(img), nil
+}
+
+// Encode16 writes the image to w with 16 bits per color channel. Only PNG and
+// TIFF are supported, other formats return ErrUnsupportedFormat. No precision is
+// lost: decoding the output with Decode16 returns the same colors as Clone64(img).
+func Encode16(w io.Writer, img image.Image, format Format, opts ...EncodeOption) error {
+	if format != PNG && format != TIFF {
+		return fmt.Errorf("%w: 16-bit images can not be encoded as %s", ErrUnsupportedFormat, format)
+	}
+	switch img.(type) {
+	case *image.NRGBA64, *image.Gray16:
+	default:
+		img = toNRGBA64(img)
+	}
+	return Encode(w, img, format, opts...)
+}
+
+// Save16 saves the image to file with 16 bits per color channel.
+// The format is determined from the filename extension, only "png"
+// and "tif" (or "tiff") are supported. See Encode16 for details.
+func Save16(img image.Image, filename string, opts ...EncodeOption) (err error) {
+	f, err := FormatFromFilename(filename)
+	if err != nil {
+		return err
+	}
+	if f != PNG && f != TIFF {
+		return fmt.Errorf("%w: 16-bit images can not be saved as %s", ErrUnsupportedFormat, f)
+	}
+	if err = ValidateEncodeOptions(f, opts...); err != nil {
+		return err
+	}
+	file, err := createFile(filename)
+	if err != nil {
+		return err
+	}
+
+	err = Encode16(file, img, f, opts...)
+	errClose := file.Close()
+	if err == nil {
+		err = errClose
+	}
+	return err
+}
@@ -0,0 +1,256 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/image/tiff"
+)
+
+func makeGradient64(w, h int) *image.NRGBA64 {
+	img := image.NewNRGBA64(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetNRGBA64(x, y, color.NRGBA64{
+				R: uint16(x*4099 + y*7),
+				G: uint16(y*3001 + x*13),
+				B: uint16((x+y)*1021 + 1),
+				A: 0xffff - uint16(x*11+y*3),
+			})
+		}
+	}
+	return img
+}
+
+func compareNRGBA64(img1, img2 *image.NRGBA64) bool {
+	if !img1.Rect.Eq(img2.Rect) {
+		return false
+	}
+	return bytes.Equal(img1.Pix, img2.Pix)
+}
+
+func TestClone64(t *testing.T) {
+	t.Parallel()
+
+	src := makeGradient64(5, 4)
+
+	t.Run("NRGBA64 with offset", func(t *testing.T) {
+		t.Parallel()
+		sub := src.SubImage(image.Rect(1, 1, 4, 3)).(*image.NRGBA64) //nolint
+		got := Clone64(sub)
+		if got.Rect != image.Rect(0, 0, 3, 2) {
+			t.Fatalf("got bounds %v want %v", got.Rect, image.Rect(0, 0, 3, 2))
+		}
+		for y := 0; y < 2; y++ {
+			for x := 0; x < 3; x++ {
+				if got.NRGBA64At(x, y) != src.NRGBA64At(x+1, y+1) {
+					t.Fatalf("pixel (%d, %d): got %v want %v", x, y, got.NRGBA64At(x, y), src.NRGBA64At(x+1, y+1))
+				}
+			}
+		}
+	})
+
+	t.Run("Gray16", func(t *testing.T) {
+		t.Parallel()
+		gray := image.NewGray16(image.Rect(0, 0, 2, 1))
+		gray.SetGray16(0, 0, color.Gray16{Y: 0x1234})
+		gray.SetGray16(1, 0, color.Gray16{Y: 0xfedc})
+		got := Clone64(gray)
+		want := []color.NRGBA64{{0x1234, 0x1234, 0x1234, 0xffff}, {0xfedc, 0xfedc, 0xfedc, 0xffff}}
+		for x, c := range want {
+			if got.NRGBA64At(x, 0) != c {
+				t.Fatalf("pixel %d: got %v want %v", x, got.NRGBA64At(x, 0), c)
+			}
+		}
+	})
+}
+
+func TestCrop64(t *testing.T) {
+	t.Parallel()
+
+	src := makeGradient64(6, 6)
+	got := Crop64(src, image.Rect(2, 1, 8, 3))
+	if got.Rect != image.Rect(0, 0, 4, 2) {
+		t.Fatalf("got bounds %v want %v", got.Rect, image.Rect(0, 0, 4, 2))
+	}
+	if got.NRGBA64At(3, 1) != src.NRGBA64At(5, 2) {
+		t.Fatalf("got %v want %v", got.NRGBA64At(3, 1), src.NRGBA64At(5, 2))
+	}
+	if empty := Crop64(src, image.Rect(10, 10, 20, 20)); !empty.Rect.Empty() {
+		t.Fatalf("got bounds %v want empty", empty.Rect)
+	}
+}
+
+func TestResize64(t *testing.T) {
+	t.Parallel()
+
+	t.Run("keeps 16-bit precision", func(t *testing.T) {
+		t.Parallel()
+		c := color.NRGBA64{R: 0x1201, G: 0x3403, B: 0x5605, A: 0xffff}
+		src := image.NewNRGBA64(image.Rect(0, 0, 16, 8))
+		for y := 0; y < 8; y++ {
+			for x := 0; x < 16; x++ {
+				src.SetNRGBA64(x, y, c)
+			}
+		}
+		for _, filter := range []ResampleFilter{NearestNeighbor, Box, Linear, Lanczos} {
+			got := Resize64(src, 5, 0, filter)
+			if got.Rect != image.Rect(0, 0, 5, 3) {
+				t.Fatalf("got bounds %v want %v", got.Rect, image.Rect(0, 0, 5, 3))
+			}
+			for y := 0; y < 3; y++ {
+				for x := 0; x < 5; x++ {
+					if got.NRGBA64At(x, y) != c {
+						t.Fatalf("pixel (%d, %d): got %v want %v", x, y, got.NRGBA64At(x, y), c)
+					}
+				}
+			}
+		}
+	})
+
+	t.Run("same size", func(t *testing.T) {
+		t.Parallel()
+		src := makeGradient64(4, 4)
+		if got := Resize64(src, 4, 4, Lanczos); !compareNRGBA64(got, src) {
+			t.Fatal("resized image differs from source")
+		}
+	})
+
+	t.Run("invalid size", func(t *testing.T) {
+		t.Parallel()
+		src := makeGradient64(4, 4)
+		for _, size := range [][2]int{{0, 0}, {-1, 4}, {4, -1}} {
+			if got := Resize64(src, size[0], size[1], Lanczos); !got.Rect.Empty() {
+				t.Fatalf("size %v: got bounds %v want empty", size, got.Rect)
+			}
+		}
+	})
+}
+
+func TestFixOrientation64(t *testing.T) {
+	t.Parallel()
+
+	src := makeGradient64(5, 3)
+	src8 := Clone(src)
+	for o := OrientationUnspecified; o <= OrientationRotate90; o++ {
+		got := fixOrientation64(src, o)
+		want := toNRGBA(FixOrientation(src8, o))
+		if !compareNRGBA(Clone(got), want, 0) {
+			t.Fatalf("orientation %d: 16-bit transform differs from 8-bit transform", o)
+		}
+	}
+}
+
+func TestPreserve16Bit(t *testing.T) {
+	t.Parallel()
+
+	src := makeGradient64(7, 5)
+	for _, format := range []Format{PNG, TIFF} {
+		buf := &bytes.Buffer{}
+		if err := Encode(buf, src, format); err != nil {
+			t.Fatalf("failed to encode %s: %v", format, err)
+		}
+		data := buf.Bytes()
+
+		img, err := Decode(bytes.NewReader(data), Preserve16Bit(true))
+		if err != nil {
+			t.Fatalf("failed to decode %s: %v", format, err)
+		}
+		got, ok := img.(*image.NRGBA64)
+		if !ok {
+			t.Fatalf("%s: got %T want *image.NRGBA64", format, img)
+		}
+		if !compareNRGBA64(got, src) {
+			t.Fatalf("%s: decoded image differs from source", format)
+		}
+
+		img, err = Decode(bytes.NewReader(data), Preserve16Bit(true), AutoOrientation(true))
+		if err != nil {
+			t.Fatalf("failed to decode %s: %v", format, err)
+		}
+		if _, ok := img.(*image.NRGBA64); !ok {
+			t.Fatalf("%s with auto-orientation: got %T want *image.NRGBA64", format, img)
+		}
+	}
+}
+
+func TestEncodeDecode16(t *testing.T) {
+	t.Parallel()
+
+	gray := image.NewGray16(image.Rect(0, 0, 3, 2))
+	for i := range gray.Pix {
+		gray.Pix[i] = uint8(i*37 + 1)
+	}
+	testCases := []struct {
+		name string
+		img  image.Image
+	}{
+		{name: "NRGBA64 with alpha", img: makeGradient64(9, 7)},
+		{name: "Gray16", img: gray},
+		{name: "8-bit NRGBA", img: makeNoiseNRGBA(5, 4, 1)},
+	}
+	options := [][]EncodeOption{
+		nil,
+		{TIFFCompression(tiff.LZW), TIFFPredictor(true)},
+		{PNGCompressionLevel(png.BestSpeed)},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			want := Clone64(tc.img)
+			for _, format := range []Format{PNG, TIFF} {
+				for _, opts := range options {
+					buf := &bytes.Buffer{}
+					if err := Encode16(buf, tc.img, format, opts...); err != nil {
+						t.Fatalf("failed to encode %s: %v", format, err)
+					}
+					got, err := Decode16(buf)
+					if err != nil {
+						t.Fatalf("failed to decode %s: %v", format, err)
+					}
+					if !compareNRGBA64(got, want) {
+						t.Fatalf("%s: decoded image differs from source", format)
+					}
+				}
+			}
+		})
+	}
+
+	if err := Encode16(io.Discard, gray, JPEG); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("got error %v want %v", err, ErrUnsupportedFormat)
+	}
+}
+
+func TestOpenSave16(t *testing.T) {
+	dir := t.TempDir()
+	src := makeGradient64(6, 5)
+	for _, name := range []string{"out.png", "out.tif"} {
+		filename := filepath.Join(dir, name)
+		if err := Save16(src, filename); err != nil {
+			t.Fatalf("failed to save %s: %v", name, err)
+		}
+		got, err := Open16(filename)
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", name, err)
+		}
+		if !compareNRGBA64(got, src) {
+			t.Fatalf("%s: image differs from source", name)
+		}
+	}
+
+	if err := Save16(src, filepath.Join(dir, "out.jpg")); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("got error %v want %v", err, ErrUnsupportedFormat)
+	}
+	if _, err := Open16(filepath.Join(dir, "missing.png")); err == nil {
+		t.Fatalf("expected error opening a missing file")
+	}
+}
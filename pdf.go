@@ -0,0 +1,205 @@
+package imaging
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// PageSize is the size of a PDF page in points (1/72 inch).
+type PageSize struct {
+	Width, Height float64
+}
+
+// Standard page sizes in portrait orientation.
+var (
+	// A3 is the ISO A3 page size (297x420 mm).
+	A3 = PageSize{841.89, 1190.55}
+	// A4 is the ISO A4 page size (210x297 mm).
+	A4 = PageSize{595.28, 841.89}
+	// A5 is the ISO A5 page size (148x210 mm).
+	A5 = PageSize{419.53, 595.28}
+	// Letter is the US Letter page size (8.5x11 inches).
+	Letter = PageSize{612, 792}
+	// Legal is the US Legal page size (8.5x14 inches).
+	Legal = PageSize{612, 1008}
+)
+
+// errEmptyPDFPage means an empty image was passed to the PDF encoder.
+var errEmptyPDFPage = errors.New("imaging: can't encode an empty image as PDF page")
+
+// PDFPageSize returns an EncodeOption that sets the page size of the PDF output.
+// Each image is scaled to fit the page, keeping its aspect ratio, and centered on
+// it. The page is turned to landscape for landscape images. Default is the zero
+// PageSize, which makes every page the size of its image at 72 DPI.
+func PDFPageSize(size PageSize) EncodeOption {
+	return func(c *encodeConfig) {
+		c.pdfPageSize = size
+	}
+}
+
+// pdfWriter writes the objects of a PDF file and records their offsets for
+// the cross-reference table.
+type pdfWriter struct {
+	w *bufio.Writer
+	// n is the number of bytes written.
+	n int64
+	// offsets are the offsets of the objects by their number, 0 is unused.
+	offsets []int64
+	err     error
+}
+
+// printf writes the formatted text unless an error occurred before.
+func (p *pdfWriter) printf(format string, args ...interface{}) {
+	if p.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(p.w, format, args...)
+	p.n += int64(n)
+	p.err = err
+}
+
+// newObject reserves the number of a new object.
+func (p *pdfWriter) newObject() int {
+	p.offsets = append(p.offsets, 0)
+	return len(p.offsets) - 1
+}
+
+// object writes the object with the given number and dictionary.
+func (p *pdfWriter) object(id int, dict string) {
+	p.offsets[id] = p.n
+	p.printf("%d 0 obj\n%s\nendobj\n", id, dict)
+}
+
+// stream writes the stream object with the given number, dictionary entries and data
+// compressed with the Flate filter.
+func (p *pdfWriter) stream(id int, dict string, data []byte) {
+	buf := &bytes.Buffer{}
+	zw := zlib.NewWriter(buf)
+	if _, err := zw.Write(data); err != nil && p.err == nil {
+		p.err = err
+	}
+	if err := zw.Close(); err != nil && p.err == nil {
+		p.err = err
+	}
+	p.offsets[id] = p.n
+	p.printf("%d 0 obj\n<< %s /Filter /FlateDecode /Length %d >>\nstream\n", id, dict, buf.Len())
+	if p.err == nil {
+		n, err := p.w.Write(buf.Bytes())
+		p.n += int64(n)
+		p.err = err
+	}
+	p.printf("\nendstream\nendobj\n")
+}
+
+// encodePDF writes the images to w as the pages of a PDF document. The pixels are
+// stored losslessly, grayscale images with one color component and transparent
+// images with a soft mask.
+func encodePDF(w io.Writer, imgs []image.Image, cfg *encodeConfig) error {
+	p := &pdfWriter{w: bufio.NewWriter(w), offsets: make([]int64, 3)}
+	const catalogID, pagesID = 1, 2
+	// The binary comment marks the file as binary for transfer programs.
+	p.printf("%%PDF-1.4\n%%\xe2\xe3\xcf\xd3\n")
+
+	kids := make([]string, 0, len(imgs))
+	for _, img := range imgs {
+		id, err := p.page(img, pagesID, cfg.pdfPageSize)
+		if err != nil {
+			return err
+		}
+		kids = append(kids, strconv.Itoa(id)+" 0 R")
+	}
+	p.object(catalogID, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesID))
+	p.object(pagesID, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids)))
+
+	xref := p.n
+	p.printf("xref\n0 %d\n0000000000 65535 f \n", len(p.offsets))
+	for _, offset := range p.offsets[1:] {
+		p.printf("%010d 00000 n \n", offset)
+	}
+	p.printf("trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(p.offsets), catalogID, xref)
+	if p.err != nil {
+		return p.err
+	}
+	return p.w.Flush()
+}
+
+// page writes a page showing the image with its content stream and image objects,
+// and returns the number of the page object.
+func (p *pdfWriter) page(img image.Image, parent int, size PageSize) (int, error) {
+	src := Clone(img)
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	if w == 0 || h == 0 {
+		return 0, errEmptyPDFPage
+	}
+
+	gray, opaque := true, true
+	for i := 0; i < len(src.Pix); i += 4 {
+		s := src.Pix[i : i+4 : i+4]
+		if s[0] != s[1] || s[1] != s[2] {
+			gray = false
+		}
+		if s[3] != 0xff {
+			opaque = false
+		}
+	}
+	colorSpace, components := "/DeviceRGB", 3
+	if gray {
+		colorSpace, components = "/DeviceGray", 1
+	}
+	pix := make([]byte, 0, w*h*components)
+	var alpha []byte
+	if !opaque {
+		alpha = make([]byte, 0, w*h)
+	}
+	for i := 0; i < len(src.Pix); i += 4 {
+		pix = append(pix, src.Pix[i:i+components]...)
+		if alpha != nil {
+			alpha = append(alpha, src.Pix[i+3])
+		}
+	}
+
+	// The image is placed at 72 DPI, or scaled to fit the page and centered.
+	pw, ph := float64(w), float64(h)
+	x, y, sw, sh := 0.0, 0.0, pw, ph
+	if size.Width > 0 && size.Height > 0 {
+		pw, ph = size.Width, size.Height
+		if w != h && (w > h) != (pw > ph) {
+			pw, ph = ph, pw
+		}
+		scale := math.Min(pw/float64(w), ph/float64(h))
+		sw, sh = float64(w)*scale, float64(h)*scale
+		x, y = (pw-sw)/2, (ph-sh)/2
+	}
+
+	pageID, contentsID, imageID := p.newObject(), p.newObject(), p.newObject()
+	dict := fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace %s /BitsPerComponent 8", w, h, colorSpace)
+	if alpha != nil {
+		maskID := p.newObject()
+		p.stream(maskID, fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceGray /BitsPerComponent 8", w, h), alpha)
+		dict += fmt.Sprintf(" /SMask %d 0 R", maskID)
+	}
+	p.stream(imageID, dict, pix)
+	content := fmt.Sprintf("q %s 0 0 %s %s %s cm /Im0 Do Q", pdfNumber(sw), pdfNumber(sh), pdfNumber(x), pdfNumber(y))
+	p.stream(contentsID, "", []byte(content))
+	p.object(pageID, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources << /XObject << /Im0 %d 0 R >> >> /Contents %d 0 R >>",
+		parent, pdfNumber(pw), pdfNumber(ph), imageID, contentsID))
+	return pageID, p.err
+}
+
+// pdfNumber formats the number with at most two decimal places.
+func pdfNumber(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 2, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
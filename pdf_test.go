@@ -0,0 +1,179 @@
+package imaging
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// pdfStreams returns the decompressed streams of the PDF document by their object numbers,
+// with their dictionaries.
+func pdfStreams(t *testing.T, data []byte) (map[int]string, map[int][]byte) {
+	t.Helper()
+	dicts, streams := map[int]string{}, map[int][]byte{}
+	re := regexp.MustCompile(`(\d+) 0 obj\n<< (.*) /Filter /FlateDecode /Length (\d+) >>\nstream\n`)
+	for _, m := range re.FindAllSubmatchIndex(data, -1) {
+		id, _ := strconv.Atoi(string(data[m[2]:m[3]]))
+		n, _ := strconv.Atoi(string(data[m[6]:m[7]]))
+		zr, err := zlib.NewReader(bytes.NewReader(data[m[1] : m[1]+n]))
+		if err != nil {
+			t.Fatalf("object %d: %v", id, err)
+		}
+		s, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatalf("object %d: %v", id, err)
+		}
+		dicts[id], streams[id] = string(data[m[4]:m[5]]), s
+	}
+	return dicts, streams
+}
+
+// checkPDFXref checks that the cross-reference table points to the objects.
+func checkPDFXref(t *testing.T, data []byte) {
+	t.Helper()
+	m := regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`).FindSubmatch(data)
+	if m == nil {
+		t.Fatalf("missing startxref")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(data[xref:], []byte("xref\n0 ")) {
+		t.Fatalf("startxref %d doesn't point to the xref table", xref)
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n \n`).FindAllSubmatch(data[xref:], -1)
+	for i, e := range entries {
+		offset, _ := strconv.Atoi(string(e[1]))
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !bytes.HasPrefix(data[offset:], []byte(want)) {
+			t.Fatalf("xref entry %d doesn't point to the object", i+1)
+		}
+	}
+}
+
+func TestEncodePDF(t *testing.T) {
+	t.Parallel()
+
+	color := makeNoiseNRGBA(6, 4, 1)
+	for i := 3; i < len(color.Pix); i += 4 {
+		color.Pix[i] = 0xff
+	}
+	gray := image.NewGray(image.Rect(0, 0, 3, 5))
+	for i := range gray.Pix {
+		gray.Pix[i] = uint8(i * 10)
+	}
+	transparent := makeNoiseNRGBA(4, 4, 2)
+
+	testCases := []struct {
+		name      string
+		img       image.Image
+		opts      []EncodeOption
+		mediaBox  string
+		placement string
+		dict      string
+		pix       []byte
+	}{
+		{
+			name:      "color",
+			img:       color,
+			mediaBox:  "[0 0 6 4]",
+			placement: "q 6 0 0 4 0 0 cm /Im0 Do Q",
+			dict:      "/Width 6 /Height 4 /ColorSpace /DeviceRGB",
+		},
+		{
+			name:      "gray on A4",
+			img:       gray,
+			opts:      []EncodeOption{PDFPageSize(A4)},
+			mediaBox:  "[0 0 595.28 841.89]",
+			placement: "q 505.13 0 0 841.89 45.07 0 cm /Im0 Do Q",
+			dict:      "/Width 3 /Height 5 /ColorSpace /DeviceGray",
+			pix:       gray.Pix,
+		},
+		{
+			name:      "landscape on letter",
+			img:       color,
+			opts:      []EncodeOption{PDFPageSize(Letter)},
+			mediaBox:  "[0 0 792 612]",
+			placement: "q 792 0 0 528 0 42 cm /Im0 Do Q",
+			dict:      "/Width 6 /Height 4 /ColorSpace /DeviceRGB",
+		},
+		{
+			name:      "transparent",
+			img:       transparent,
+			mediaBox:  "[0 0 4 4]",
+			placement: "q 4 0 0 4 0 0 cm /Im0 Do Q",
+			dict:      "/Width 4 /Height 4 /ColorSpace /DeviceRGB /BitsPerComponent 8 /SMask",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			buf := &bytes.Buffer{}
+			if err := Encode(buf, tc.img, PDF, tc.opts...); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			data := buf.Bytes()
+			if !bytes.HasPrefix(data, []byte("%PDF-1.4\n")) {
+				t.Fatalf("missing PDF header")
+			}
+			checkPDFXref(t, data)
+			if !bytes.Contains(data, []byte("/MediaBox "+tc.mediaBox)) {
+				t.Fatalf("missing media box %s", tc.mediaBox)
+			}
+
+			dicts, streams := pdfStreams(t, data)
+			var content, pix []byte
+			for id, d := range dicts {
+				switch {
+				case d == "":
+					content = streams[id]
+				case strings.Contains(d, tc.dict):
+					pix = streams[id]
+				}
+			}
+			if string(content) != tc.placement {
+				t.Fatalf("got content %q want %q", content, tc.placement)
+			}
+			src := Clone(tc.img)
+			if tc.pix == nil {
+				for i := 0; i < len(src.Pix); i += 4 {
+					tc.pix = append(tc.pix, src.Pix[i:i+3]...)
+				}
+			}
+			if !bytes.Equal(pix, tc.pix) {
+				t.Fatalf("the image data differs")
+			}
+		})
+	}
+}
+
+func TestSaveAllPDF(t *testing.T) {
+	pages := []image.Image{makeNoiseNRGBA(10, 14, 1), makeNoiseNRGBA(14, 10, 2), image.NewGray(image.Rect(0, 0, 5, 5))}
+	filename := filepath.Join(t.TempDir(), "out.pdf")
+	if err := SaveAll(pages, filename, PDFPageSize(A4)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("failed to read the PDF: %v", err)
+	}
+	checkPDFXref(t, data)
+	if !bytes.Contains(data, []byte("/Type /Pages /Kids [3 0 R 7 0 R 11 0 R] /Count 3")) {
+		t.Fatalf("the pages are missing")
+	}
+	if n := bytes.Count(data, []byte("/MediaBox [0 0 595.28 841.89]")); n != 2 {
+		t.Fatalf("got %d portrait pages want 2", n)
+	}
+
+	if err := EncodeAll(&bytes.Buffer{}, []image.Image{&image.NRGBA{}}, PDF); !errors.Is(err, errEmptyPDFPage) {
+		t.Fatalf("got error %v want %v", err, errEmptyPDFPage)
+	}
+}
@@ -0,0 +1,182 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// Perspective maps the srcQuad region of the image to the dstQuad quadrilateral with
+// a projective transformation and returns the result. The quadrilaterals can be any
+// convex shapes, e.g. the corners of a photographed document to a rectangle to remove
+// the perspective distortion, or the corners of an image to the corners of a device
+// screen to place a screenshot into a mockup.
+//
+// The result spans from the origin to the bottom right corner of the dstQuad bounding
+// box, so it can be overlaid on an image in the dstQuad coordinates at the origin.
+// The pixels outside of dstQuad, and those mapped outside of the image, are filled
+// with bgColor. The filter is used to interpolate and, where the region is shrunk,
+// to average the source pixels.
+//
+// Example:
+//
+//	screen := imaging.Quad{{412, 188}, {1085, 231}, {1061, 1402}, {391, 1381}}
+//	b := screenshot.Bounds()
+//	corners := imaging.Quad{{b.Min.X, b.Min.Y}, {b.Max.X, b.Min.Y}, {b.Max.X, b.Max.Y}, {b.Min.X, b.Max.Y}}
+//	warped := imaging.Perspective(screenshot, corners, screen, imaging.Lanczos, color.Transparent)
+//	dstImage := imaging.Overlay(mockup, warped, image.Pt(0, 0), 1.0)
+func Perspective(img image.Image, srcQuad, dstQuad Quad, filter ResampleFilter, bgColor color.Color) *image.NRGBA {
+	width, height := 0, 0
+	for _, c := range dstQuad {
+		if c.X > width {
+			width = c.X
+		}
+		if c.Y > height {
+			height = c.Y
+		}
+	}
+	bg := color.NRGBAModel.Convert(bgColor).(color.NRGBA)
+	dst := New(width, height, bg)
+	if width == 0 || height == 0 {
+		return dst
+	}
+
+	b := img.Bounds()
+	var sp, dp [4][2]float64
+	for i := range srcQuad {
+		sp[i] = [2]float64{float64(srcQuad[i].X - b.Min.X), float64(srcQuad[i].Y - b.Min.Y)}
+		dp[i] = [2]float64{float64(dstQuad[i].X), float64(dstQuad[i].Y)}
+	}
+	toSquare, ok := squareToQuad(dp).invert()
+	if !ok {
+		return dst
+	}
+	fromSquare := squareToQuad(sp)
+	src := toNRGBA(img)
+
+	parallel(0, height, func(ys <-chan int) {
+		for y := range ys {
+			for x := 0; x < width; x++ {
+				xc, yc := float64(x)+0.5, float64(y)+0.5
+				u, v := toSquare.apply(xc, yc)
+				if !(u >= 0 && u <= 1 && v >= 0 && v <= 1) {
+					continue
+				}
+				sx, sy := fromSquare.apply(u, v)
+				if filter.Support <= 0 || filter.Kernel == nil {
+					i := dst.PixOffset(x, y)
+					nearestPoint(dst.Pix[i:i+4:i+4], src, sx, sy, bg)
+					continue
+				}
+
+				// The scale is the distance in the source between the neighboring pixels.
+				u1, v1 := toSquare.apply(xc+1, yc)
+				x1, y1 := fromSquare.apply(u1, v1)
+				u2, v2 := toSquare.apply(xc, yc+1)
+				x2, y2 := fromSquare.apply(u2, v2)
+				scale := math.Max(math.Hypot(x1-sx, y1-sy), math.Hypot(x2-sx, y2-sy))
+				if !(scale >= 1) || math.IsInf(scale, 0) {
+					scale = 1
+				}
+				i := dst.PixOffset(x, y)
+				filterPoint(dst.Pix[i:i+4:i+4], src, sx, sy, scale, filter, bg)
+			}
+		}
+	})
+	return dst
+}
+
+// nearestPoint sets the pixel to the source pixel at the point, or to bg if the point
+// is outside of the source image.
+func nearestPoint(d []uint8, src *image.NRGBA, sx, sy float64, bg color.NRGBA) {
+	ix, iy := int(math.Floor(sx)), int(math.Floor(sy))
+	if ix < 0 || iy < 0 || ix >= src.Rect.Dx() || iy >= src.Rect.Dy() || math.IsNaN(sx) || math.IsNaN(sy) {
+		d[0], d[1], d[2], d[3] = bg.R, bg.G, bg.B, bg.A
+		return
+	}
+	j := iy*src.Stride + ix*4
+	copy(d, src.Pix[j:j+4])
+}
+
+// filterPoint sets the pixel to the weighted average of the source pixels around the
+// point, with the filter kernel stretched by the scale. The pixels outside of the source
+// image are taken as bg.
+func filterPoint(d []uint8, src *image.NRGBA, sx, sy, scale float64, filter ResampleFilter, bg color.NRGBA) {
+	if math.IsNaN(sx) || math.IsNaN(sy) || math.IsInf(sx, 0) || math.IsInf(sy, 0) {
+		d[0], d[1], d[2], d[3] = bg.R, bg.G, bg.B, bg.A
+		return
+	}
+	// The kernel works with pixel centers.
+	cx, cy := sx-0.5, sy-0.5
+	r := filter.Support * scale
+	x0, x1 := int(math.Ceil(cx-r)), int(math.Floor(cx+r))
+	y0, y1 := int(math.Ceil(cy-r)), int(math.Floor(cy+r))
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+
+	var sr, sg, sb, sa, sw float64
+	for iy := y0; iy <= y1; iy++ {
+		wy := filter.Kernel((float64(iy) - cy) / scale)
+		if wy == 0 {
+			continue
+		}
+		for ix := x0; ix <= x1; ix++ {
+			k := wy * filter.Kernel((float64(ix)-cx)/scale)
+			if k == 0 {
+				continue
+			}
+			c := bg
+			if ix >= 0 && iy >= 0 && ix < w && iy < h {
+				j := iy*src.Stride + ix*4
+				c = color.NRGBA{src.Pix[j], src.Pix[j+1], src.Pix[j+2], src.Pix[j+3]}
+			}
+			a := float64(c.A) * k
+			sr += float64(c.R) * a
+			sg += float64(c.G) * a
+			sb += float64(c.B) * a
+			sa += a
+			sw += k
+		}
+	}
+	if sw == 0 {
+		nearestPoint(d, src, sx, sy, bg)
+		return
+	}
+	if sa <= 0 {
+		d[0], d[1], d[2], d[3] = 0, 0, 0, 0
+		return
+	}
+	d[0] = clamp(sr / sa)
+	d[1] = clamp(sg / sa)
+	d[2] = clamp(sb / sa)
+	d[3] = clamp(sa / sw)
+}
+
+// invert returns the inverse transformation. It reports false if the transformation
+// is degenerate, e.g. for a quadrilateral with collinear corners.
+func (t projective) invert() (projective, bool) {
+	// The transformation as the 3x3 matrix [a b c; d e f; g h 1].
+	a, b, c := t.a, t.b, t.c
+	d, e, f := t.d, t.e, t.f
+	g, h, i := t.g, t.h, 1.0
+
+	A := e*i - f*h
+	B := -(d*i - f*g)
+	C := d*h - e*g
+	det := a*A + b*B + c*C
+	if det == 0 || math.IsNaN(det) {
+		return projective{}, false
+	}
+	// The adjugate matrix, normalized so that its last element is 1.
+	m := [9]float64{
+		A, -(b*i - c*h), b*f - c*e,
+		B, a*i - c*g, -(a*f - c*d),
+		C, -(a*h - b*g), a*e - b*d,
+	}
+	if m[8] == 0 {
+		return projective{}, false
+	}
+	for k := range m {
+		m[k] /= m[8]
+	}
+	return projective{a: m[0], b: m[1], c: m[2], d: m[3], e: m[4], f: m[5], g: m[6], h: m[7]}, true
+}
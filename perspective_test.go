@@ -0,0 +1,128 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestPerspective(t *testing.T) {
+	t.Parallel()
+
+	opaque := makeNoiseNRGBA(16, 12, 1)
+	for i := 3; i < len(opaque.Pix); i += 4 {
+		opaque.Pix[i] = 0xff
+	}
+	corners := Quad{{0, 0}, {16, 0}, {16, 12}, {0, 12}}
+
+	t.Run("identity", func(t *testing.T) {
+		t.Parallel()
+		for _, filter := range []ResampleFilter{NearestNeighbor, Linear, Lanczos} {
+			got := Perspective(opaque, corners, corners, filter, color.Transparent)
+			if !compareNRGBA(got, opaque, 0) {
+				t.Fatalf("got result %#v want %#v", got, opaque)
+			}
+		}
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		t.Parallel()
+		src := image.NewNRGBA(image.Rect(0, 0, 40, 40))
+		for y := 0; y < 40; y++ {
+			for x := 0; x < 40; x++ {
+				src.SetNRGBA(x, y, color.NRGBA{uint8(x * 6), uint8(y * 6), 0x80, 0xff})
+			}
+		}
+		square := Quad{{0, 0}, {40, 0}, {40, 40}, {0, 40}}
+		q := Quad{{20, 10}, {90, 20}, {80, 70}, {10, 60}}
+		warped := Perspective(src, square, q, Linear, color.Transparent)
+		if warped.Bounds() != image.Rect(0, 0, 90, 70) {
+			t.Fatalf("got bounds %v want 90x70", warped.Bounds())
+		}
+		got := Perspective(warped, q, square, Linear, color.Transparent)
+		// The borders blend with the background.
+		if !compareNRGBA(Crop(got, image.Rect(2, 2, 38, 38)), Crop(src, image.Rect(2, 2, 38, 38)), 8) {
+			t.Fatalf("the round trip differs from the source")
+		}
+	})
+
+	t.Run("background", func(t *testing.T) {
+		t.Parallel()
+		bg := color.NRGBA{0, 0xff, 0, 0xff}
+		q := Quad{{10, 0}, {20, 10}, {10, 20}, {0, 10}}
+		got := Perspective(opaque, corners, q, Linear, bg)
+		if got.Bounds() != image.Rect(0, 0, 20, 20) {
+			t.Fatalf("got bounds %v want 20x20", got.Bounds())
+		}
+		for _, p := range []image.Point{{0, 0}, {19, 0}, {19, 19}, {0, 19}, {3, 3}} {
+			if c := got.NRGBAAt(p.X, p.Y); c != bg {
+				t.Fatalf("pixel %v: got %v want background", p, c)
+			}
+		}
+		if c := got.NRGBAAt(10, 10); c == bg {
+			t.Fatalf("pixel (10, 10): got background want image")
+		}
+	})
+
+	t.Run("shrink", func(t *testing.T) {
+		t.Parallel()
+		// Averaging a checkerboard gives gray; point sampling would give black or white.
+		src := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+		for y := 0; y < 64; y++ {
+			for x := 0; x < 64; x++ {
+				v := uint8(0)
+				if (x+y)%2 == 0 {
+					v = 0xff
+				}
+				src.SetNRGBA(x, y, color.NRGBA{v, v, v, 0xff})
+			}
+		}
+		got := Perspective(src, Quad{{0, 0}, {64, 0}, {64, 64}, {0, 64}}, Quad{{0, 0}, {8, 0}, {8, 8}, {0, 8}}, Box, color.Black)
+		for y := 1; y < 7; y++ {
+			for x := 1; x < 7; x++ {
+				if c := got.NRGBAAt(x, y); c.R < 0x70 || c.R > 0x90 {
+					t.Fatalf("pixel (%d, %d): got %v want gray", x, y, c)
+				}
+			}
+		}
+	})
+
+	t.Run("degenerate", func(t *testing.T) {
+		t.Parallel()
+		got := Perspective(opaque, corners, Quad{{0, 0}, {10, 0}, {20, 0}, {5, 0}}, Linear, color.Black)
+		if !got.Bounds().Empty() {
+			t.Fatalf("got bounds %v want empty", got.Bounds())
+		}
+		got = Perspective(opaque, corners, Quad{{0, 0}, {10, 10}, {20, 20}, {0, 0}}, Linear, color.White)
+		for i := range got.Pix {
+			if got.Pix[i] != 0xff {
+				t.Fatalf("got %v want white", got.Pix[i:i+4])
+			}
+		}
+	})
+}
+
+func TestProjectiveInvert(t *testing.T) {
+	t.Parallel()
+
+	for _, p := range [][4][2]float64{
+		{{0, 0}, {1, 0}, {1, 1}, {0, 1}},
+		{{30, 10}, {70, 10}, {90, 90}, {10, 90}},
+		{{12, 3}, {95, 20}, {80, 77}, {5, 60}},
+	} {
+		inv, ok := squareToQuad(p).invert()
+		if !ok {
+			t.Fatalf("%v: not invertible", p)
+		}
+		for i, uv := range [4][2]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}} {
+			u, v := inv.apply(p[i][0], p[i][1])
+			if math.Abs(u-uv[0]) > 1e-9 || math.Abs(v-uv[1]) > 1e-9 {
+				t.Fatalf("%v: corner %d mapped to (%v, %v)", p, i, u, v)
+			}
+		}
+	}
+	if _, ok := (projective{}).invert(); ok {
+		t.Fatalf("degenerate transformation inverted")
+	}
+}
@@ -0,0 +1,147 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"strings"
+)
+
+// pipelineStep is a single named operation of a Pipeline.
+type pipelineStep struct {
+	// name describes the operation, e.g. "resize 800x600>".
+	name string
+	// fn applies the operation.
+	fn func(image.Image) image.Image
+}
+
+// Pipeline is a reusable sequence of image processing operations along with
+// the options used to decode the source image and to encode the result.
+//
+// Example:
+//
+//	p := imaging.NewPipeline().
+//		Then("fit 800x600", func(img image.Image) image.Image {
+//			return imaging.Fit(img, 800, 600, imaging.Lanczos)
+//		}).
+//		WithEncodeOptions(imaging.JPEGQuality(85))
+//	err := p.Process("in.jpg", "out.jpg")
+type Pipeline struct {
+	steps      []pipelineStep
+	decodeOpts []DecodeOption
+	encodeOpts []EncodeOption
+	strip      bool
+}
+
+// NewPipeline creates an empty pipeline.
+func NewPipeline() *Pipeline {
+	return &Pipeline{}
+}
+
+// Then appends the operation to the pipeline. The name describes the operation
+// and is reported by the Steps method.
+func (p *Pipeline) Then(name string, fn func(image.Image) image.Image) *Pipeline {
+	p.steps = append(p.steps, pipelineStep{name: name, fn: fn})
+	return p
+}
+
+// WithDecodeOptions appends the options used to decode the source image.
+func (p *Pipeline) WithDecodeOptions(opts ...DecodeOption) *Pipeline {
+	p.decodeOpts = append(p.decodeOpts, opts...)
+	return p
+}
+
+// WithEncodeOptions appends the options used to encode the result.
+func (p *Pipeline) WithEncodeOptions(opts ...EncodeOption) *Pipeline {
+	p.encodeOpts = append(p.encodeOpts, opts...)
+	return p
+}
+
+// Strip sets whether metadata must be removed from the result. By default Run and
+// Process write the metadata of the source to the result like OpenWithMetadata and
+// WithMetadata, an encode option of the pipeline setting the metadata takes precedence.
+func (p *Pipeline) Strip(enabled bool) *Pipeline {
+	p.strip = enabled
+	return p
+}
+
+// Stripped reports whether metadata is removed from the result.
+func (p *Pipeline) Stripped() bool {
+	return p.strip
+}
+
+// Steps returns the names of the pipeline operations in order.
+func (p *Pipeline) Steps() []string {
+	names := make([]string, 0, len(p.steps))
+	for _, s := range p.steps {
+		names = append(names, s.name)
+	}
+	return names
+}
+
+// String returns the description of the pipeline operations.
+func (p *Pipeline) String() string {
+	return strings.Join(p.Steps(), " | ")
+}
+
+// Apply runs the pipeline operations on the image and returns the result.
+func (p *Pipeline) Apply(img image.Image) image.Image {
+	for _, s := range p.steps {
+		img = s.fn(img)
+	}
+	return img
+}
+
+// Run decodes the image from r, applies the pipeline operations and writes
+// the result to w in the specified format.
+func (p *Pipeline) Run(r io.Reader, w io.Writer, format Format) error {
+	var img image.Image
+	var m *Metadata
+	var err error
+	if p.strip {
+		img, err = Decode(r, p.decodeOpts...)
+	} else {
+		img, m, err = DecodeWithMetadata(r, p.decodeOpts...)
+	}
+	if err != nil {
+		return err
+	}
+	return Encode(w, p.Apply(img), format, p.outputOptions(m)...)
+}
+
+// Process opens the src image file, applies the pipeline operations and saves
+// the result to the dst file. The output format is determined from the dst
+// filename extension as in Save.
+func (p *Pipeline) Process(src, dst string) error {
+	img, m, err := p.open(src)
+	if err != nil {
+		return err
+	}
+	if err := Save(p.Apply(img), dst, p.outputOptions(m)...); err != nil {
+		return fmt.Errorf("%s: %w", dst, err)
+	}
+	return nil
+}
+
+// open loads the src file with the decode options of the pipeline, along with its
+// metadata unless the pipeline strips it or the file has a custom decoder.
+func (p *Pipeline) open(src string) (img image.Image, m *Metadata, err error) {
+	if p.strip || customDecoder(src) != nil {
+		img, err = Open(src, p.decodeOpts...)
+	} else {
+		img, m, err = OpenWithMetadata(src, p.decodeOpts...)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", src, err)
+	}
+	return img, m, nil
+}
+
+// outputOptions returns the encode options of the pipeline preceded by the option
+// writing the metadata of the source, if it's kept.
+func (p *Pipeline) outputOptions(m *Metadata) []EncodeOption {
+	if m == nil {
+		return p.encodeOpts
+	}
+	return append([]EncodeOption{WithMetadata(m)}, p.encodeOpts...)
+}
@@ -0,0 +1,140 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPipeline(t *testing.T) {
+	t.Parallel()
+
+	p := NewPipeline().
+		Then("fit 4x4", func(img image.Image) image.Image {
+			return Fit(img, 4, 4, NearestNeighbor)
+		}).
+		Then("flip", func(img image.Image) image.Image {
+			return FlipH(img)
+		}).
+		WithEncodeOptions(PNGCompressionLevel(0))
+
+	if got, want := p.String(), "fit 4x4 | flip"; got != want {
+		t.Fatalf("got %q want %q", got, want)
+	}
+	if p.Stripped() {
+		t.Fatalf("pipeline must not strip metadata by default")
+	}
+
+	src := New(8, 4, color.NRGBA{0x10, 0x20, 0x30, 0xff})
+	src.SetNRGBA(0, 0, color.NRGBA{0xff, 0x00, 0x00, 0xff})
+	want := FlipH(Fit(src, 4, 4, NearestNeighbor))
+
+	if got := p.Apply(src); !compareNRGBA(Clone(got), want, 0) {
+		t.Fatalf("Apply: got unexpected image")
+	}
+
+	in := &bytes.Buffer{}
+	if err := Encode(in, src, PNG); err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	out := &bytes.Buffer{}
+	if err := p.Run(in, out, PNG); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	got, err := Decode(out)
+	if err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	if !compareNRGBA(Clone(got), want, 0) {
+		t.Fatalf("Run: got unexpected image")
+	}
+}
+
+func TestPipelineProcess(t *testing.T) {
+	dir, err := os.MkdirTemp("", "imaging")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir) //nolint
+
+	src := filepath.Join(dir, "in.png")
+	if err := Save(New(6, 3, color.NRGBA{0, 0, 0xff, 0xff}), src); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+	p := NewPipeline().Then("rotate 90", func(img image.Image) image.Image {
+		return Rotate90(img)
+	})
+	dst := filepath.Join(dir, "out.png")
+	if err := p.Process(src, dst); err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	img, err := Open(dst)
+	if err != nil {
+		t.Fatalf("failed to open result: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 3 || b.Dy() != 6 {
+		t.Fatalf("got bounds %v want 3x6", b)
+	}
+
+	if err := p.Process(filepath.Join(dir, "missing.png"), dst); err == nil {
+		t.Fatalf("expected error processing a missing file")
+	}
+	if err := p.Process(src, filepath.Join(dir, "out.unknown")); err == nil {
+		t.Fatalf("expected error saving to an unknown format")
+	}
+}
+
+func TestPipelineMetadata(t *testing.T) {
+	t.Parallel()
+
+	in := &bytes.Buffer{}
+	exif := (*EXIF)(nil).SetArtist("Jane Doe")
+	if err := Encode(in, New(8, 4, color.White), JPEG, WithMetadata(&Metadata{EXIF: exif})); err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	dir := t.TempDir()
+	src := filepath.Join(dir, "in.jpg")
+	if err := os.WriteFile(src, in.Bytes(), 0o600); err != nil {
+		t.Fatalf("failed to write source: %v", err)
+	}
+
+	testCases := []struct {
+		name   string
+		p      *Pipeline
+		artist string
+	}{
+		{"kept", NewPipeline(), "Jane Doe"},
+		{"stripped", NewPipeline().Strip(true), ""},
+		{"replaced", NewPipeline().WithEncodeOptions(WithMetadata(&Metadata{EXIF: (*EXIF)(nil).SetArtist("John Doe")})), "John Doe"},
+	}
+	for _, tc := range testCases {
+		out := &bytes.Buffer{}
+		if err := tc.p.Run(bytes.NewReader(in.Bytes()), out, JPEG); err != nil {
+			t.Fatalf("%s: Run: %v", tc.name, err)
+		}
+		dst := filepath.Join(dir, tc.name+".jpg")
+		if err := tc.p.Process(src, dst); err != nil {
+			t.Fatalf("%s: Process: %v", tc.name, err)
+		}
+		processed, err := os.ReadFile(dst)
+		if err != nil {
+			t.Fatalf("%s: failed to read result: %v", tc.name, err)
+		}
+		for _, data := range [][]byte{out.Bytes(), processed} {
+			m, err := DecodeMetadata(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("%s: failed to decode metadata: %v", tc.name, err)
+			}
+			got := ""
+			if m.EXIF != nil {
+				got = m.EXIF.Artist()
+			}
+			if got != tc.artist {
+				t.Fatalf("%s: got artist %q want %q", tc.name, got, tc.artist)
+			}
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// makePixelArt returns the image of the rows of the colors named by the letters
+// X (black) and O (white), with the bounds starting at -1, -1.
+func makePixelArt(rows ...string) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(-1, -1, len(rows[0])-1, len(rows)-1))
+	for y, row := range rows {
+		for x, c := range row {
+			col := color.NRGBA{0xff, 0xff, 0xff, 0xff}
+			if c == 'X' {
+				col = color.NRGBA{0x00, 0x00, 0x00, 0xff}
+			}
+			img.SetNRGBA(x-1, y-1, col)
+		}
+	}
+	return img
+}
+
+func TestScalePixelArt(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		src       *image.NRGBA
+		factor    int
+		algorithm PixelArtAlgorithm
+		want      *image.NRGBA
+	}{
+		{
+			"nearest",
+			makePixelArt("XO", "OX"),
+			2, PixelArtNearest,
+			makePixelArt("XXOO", "XXOO", "OOXX", "OOXX"),
+		},
+		{
+			"scale2x",
+			makePixelArt("XO", "OX"),
+			2, PixelArtScale2x,
+			makePixelArt("XXOO", "XOXO", "OXOX", "OOXX"),
+		},
+		{
+			"scale3x",
+			makePixelArt("XO", "OX"),
+			3, PixelArtScale2x,
+			makePixelArt("XXXOOO", "XXOXOO", "XOOXXO", "OXXOOX", "OOXOXX", "OOOXXX"),
+		},
+		{
+			"scale2x and nearest",
+			makePixelArt("OOO", "OXO", "OOO"),
+			5, PixelArtScale2x,
+			Resize(makePixelArt("OOO", "OXO", "OOO"), 15, 15, NearestNeighbor),
+		},
+		{
+			"factor 1",
+			makePixelArt("XO"),
+			1, PixelArtScale2x,
+			makePixelArt("XO"),
+		},
+		{
+			"factor 0",
+			makePixelArt("XO"),
+			0, PixelArtScale2x,
+			&image.NRGBA{},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got := ScalePixelArt(tc.src, tc.factor, tc.algorithm)
+			want := tc.want
+			if want.Rect.Min != (image.Point{}) {
+				want = Clone(want)
+			}
+			if !compareNRGBA(got, want, 0) {
+				t.Fatalf("got result %#v want %#v", got, want)
+			}
+		})
+	}
+
+	src := makeNoiseNRGBA(7, 5, 1)
+	got := ScalePixelArt(src, 12, PixelArtScale2x)
+	if got.Rect != image.Rect(0, 0, 84, 60) {
+		t.Fatalf("got bounds %v want %v", got.Rect, image.Rect(0, 0, 84, 60))
+	}
+	colors := map[color.NRGBA]bool{}
+	for y := 0; y < 5; y++ {
+		for x := 0; x < 7; x++ {
+			colors[src.NRGBAAt(x, y)] = true
+		}
+	}
+	for y := 0; y < 60; y++ {
+		for x := 0; x < 84; x++ {
+			if c := got.NRGBAAt(x, y); !colors[c] {
+				t.Fatalf("got new color %v at %d, %d", c, x, y)
+			}
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package imaging
+
+import (
+	"image"
+	"math"
+)
+
+// ToPolar wraps the image around its bottom center into a disk, as ImageMagick's
+// -distort Polar does. The columns of the image become the angles, clockwise from
+// the top, and the rows become the radii, the bottom row at the center and the top
+// row on the circle. The result is a square twice as large as the image height,
+// transparent outside of the disk. It makes "tiny planets" of 360° panoramas,
+// because the ground lands in the center and the sky around it.
+//
+// Example:
+//
+//	planet := imaging.ToPolar(imaging.Resize(panorama, 2000, 500, imaging.Lanczos))
+func ToPolar(img image.Image) *image.NRGBA {
+	src := toNRGBA(img)
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	if w == 0 || h == 0 {
+		return &image.NRGBA{}
+	}
+	size := 2 * h
+	radius := float64(h)
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+	parallel(0, size, func(ys <-chan int) {
+		for y := range ys {
+			for x := 0; x < size; x++ {
+				dx, dy := float64(x)+0.5-radius, float64(y)+0.5-radius
+				r := math.Hypot(dx, dy)
+				if r > radius {
+					continue
+				}
+				a := math.Atan2(dx, -dy)
+				if a < 0 {
+					a += 2 * math.Pi
+				}
+				sx := a / (2 * math.Pi) * float64(w)
+				sy := float64(h) - r/radius*float64(h)
+				i := dst.PixOffset(x, y)
+				bilinearPoint(dst.Pix[i:i+4:i+4], src, sx, sy, true)
+			}
+		}
+	})
+	return dst
+}
+
+// FromPolar is the inverse of ToPolar, as ImageMagick's -distort DePolar: it unwraps
+// the largest disk centered in the image into a rectangle, e.g. to read the scale of
+// a round gauge or the label of a bottle photographed from the top. The angles,
+// clockwise from the top, become the columns and the radii become the rows, the
+// center at the bottom. The result is as wide as the circumference of the disk and
+// as high as its radius.
+//
+// Example:
+//
+//	strip := imaging.FromPolar(imaging.CropCenter(gaugePhoto, 800, 800))
+func FromPolar(img image.Image) *image.NRGBA {
+	src := toNRGBA(img)
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	radius := float64(w) / 2
+	if h < w {
+		radius = float64(h) / 2
+	}
+	width, height := int(math.Round(2*math.Pi*radius)), int(math.Round(radius))
+	if width == 0 || height == 0 {
+		return &image.NRGBA{}
+	}
+	cx, cy := float64(w)/2, float64(h)/2
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	parallel(0, height, func(ys <-chan int) {
+		for y := range ys {
+			r := (float64(height) - float64(y) - 0.5) / float64(height) * radius
+			for x := 0; x < width; x++ {
+				a := (float64(x) + 0.5) / float64(width) * 2 * math.Pi
+				sx, sy := cx+r*math.Sin(a), cy-r*math.Cos(a)
+				i := dst.PixOffset(x, y)
+				bilinearPoint(dst.Pix[i:i+4:i+4], src, sx, sy, false)
+			}
+		}
+	})
+	return dst
+}
+
+// bilinearPoint sets the pixel to the bilinear interpolation of the source pixels
+// around the point. The pixels outside of the source image are clamped, except the
+// columns are wrapped around if wrapX is true.
+func bilinearPoint(d []uint8, src *image.NRGBA, sx, sy float64, wrapX bool) {
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	// The interpolation works with pixel centers.
+	fx, fy := sx-0.5, sy-0.5
+	x0, y0 := int(math.Floor(fx)), int(math.Floor(fy))
+	tx, ty := fx-float64(x0), fy-float64(y0)
+
+	xMode := EdgeClamp
+	if wrapX {
+		xMode = EdgeWrap
+	}
+	var r, g, b, a, sw float64
+	for j := 0; j < 2; j++ {
+		iy, _ := EdgeClamp.index(y0+j, h)
+		wy := 1 - ty
+		if j == 1 {
+			wy = ty
+		}
+		for i := 0; i < 2; i++ {
+			ix, _ := xMode.index(x0+i, w)
+			k := 1 - tx
+			if i == 1 {
+				k = tx
+			}
+			k *= wy
+			s := src.Pix[iy*src.Stride+ix*4 : iy*src.Stride+ix*4+4]
+			wa := float64(s[3]) * k
+			r += float64(s[0]) * wa
+			g += float64(s[1]) * wa
+			b += float64(s[2]) * wa
+			a += wa
+			sw += k
+		}
+	}
+	if a <= 0 {
+		d[0], d[1], d[2], d[3] = 0, 0, 0, 0
+		return
+	}
+	d[0] = clamp(r / a)
+	d[1] = clamp(g / a)
+	d[2] = clamp(b / a)
+	d[3] = clamp(a / sw)
+}
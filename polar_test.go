@@ -0,0 +1,87 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestToPolar(t *testing.T) {
+	t.Parallel()
+
+	// The left half of the image is red, the right half blue.
+	src := New(8, 4, color.NRGBA{0xff, 0x00, 0x00, 0xff})
+	for y := 0; y < 4; y++ {
+		for x := 4; x < 8; x++ {
+			src.SetNRGBA(x, y, color.NRGBA{0x00, 0x00, 0xff, 0xff})
+		}
+	}
+	got := ToPolar(src)
+	if got.Bounds() != image.Rect(0, 0, 8, 8) {
+		t.Fatalf("got bounds %v want 8x8", got.Bounds())
+	}
+	testCases := []struct {
+		name string
+		x, y int
+		want color.NRGBA
+	}{
+		{"right of the center", 5, 4, color.NRGBA{0xff, 0x00, 0x00, 0xff}},
+		{"left of the center", 2, 3, color.NRGBA{0x00, 0x00, 0xff, 0xff}},
+		{"outside of the disk", 0, 0, color.NRGBA{}},
+	}
+	for _, tc := range testCases {
+		if c := got.NRGBAAt(tc.x, tc.y); c != tc.want {
+			t.Fatalf("%s: got color %v want %v", tc.name, c, tc.want)
+		}
+	}
+	if got := ToPolar(&image.NRGBA{}); !got.Rect.Empty() {
+		t.Fatalf("got bounds %v want empty", got.Rect)
+	}
+}
+
+func TestFromPolar(t *testing.T) {
+	t.Parallel()
+
+	// The right half of the disk is red, the left half blue.
+	src := New(40, 40, color.NRGBA{0xff, 0x00, 0x00, 0xff})
+	for y := 0; y < 40; y++ {
+		for x := 0; x < 20; x++ {
+			src.SetNRGBA(x, y, color.NRGBA{0x00, 0x00, 0xff, 0xff})
+		}
+	}
+	got := FromPolar(src)
+	if got.Bounds() != image.Rect(0, 0, 126, 20) {
+		t.Fatalf("got bounds %v want 126x20", got.Bounds())
+	}
+	for y := 0; y < 20; y++ {
+		if c := got.NRGBAAt(30, y); c != (color.NRGBA{0xff, 0x00, 0x00, 0xff}) {
+			t.Fatalf("got color %v at (30, %d) want red", c, y)
+		}
+		if c := got.NRGBAAt(95, y); c != (color.NRGBA{0x00, 0x00, 0xff, 0xff}) {
+			t.Fatalf("got color %v at (95, %d) want blue", c, y)
+		}
+	}
+
+	// FromPolar unwraps the disk of ToPolar back.
+	strip := Resize(testdataFlowersSmallPNG, 64, 16, Box)
+	back := FromPolar(ToPolar(strip))
+	if back.Bounds() != image.Rect(0, 0, 101, 16) {
+		t.Fatalf("got bounds %v want 101x16", back.Bounds())
+	}
+	back = Resize(back, 64, 16, Box)
+	var diff, n float64
+	for i := range back.Pix {
+		d := float64(back.Pix[i]) - float64(strip.Pix[i])
+		if d < 0 {
+			d = -d
+		}
+		diff += d
+		n++
+	}
+	if diff/n > 12 {
+		t.Fatalf("got mean difference %.1f after the round trip, want at most 12", diff/n)
+	}
+	if got := FromPolar(&image.NRGBA{}); !got.Rect.Empty() {
+		t.Fatalf("got bounds %v want empty", got.Rect)
+	}
+}
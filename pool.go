@@ -0,0 +1,469 @@
+package imaging
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrPoolClosed means a job is submitted to a closed Pool, or was still queued
+	// when the pool was closed.
+	ErrPoolClosed = errors.New("imaging: pool is closed")
+	// ErrPoolOverloaded means a job is rejected by the PoolShed hook, or its source
+	// image can't be downscaled to fit within the memory limit.
+	ErrPoolOverloaded = errors.New("imaging: pool is overloaded")
+)
+
+// Priority is the priority of a Pool job. The jobs of a higher priority are started
+// before the queued jobs of a lower priority.
+type Priority int
+
+// Job priorities.
+const (
+	// PriorityBackground is for batch jobs nobody waits for.
+	PriorityBackground Priority = -1
+	// PriorityNormal is the default priority.
+	PriorityNormal Priority = 0
+	// PriorityInteractive is for the jobs of requests users wait for.
+	PriorityInteractive Priority = 1
+)
+
+// JobOption sets an optional parameter of a Pool job.
+type JobOption func(*jobConfig)
+
+type jobConfig struct {
+	priority Priority
+	deadline time.Time
+	memory   int64
+}
+
+// WithPriority sets the priority of the job, PriorityNormal by default.
+func WithPriority(p Priority) JobOption {
+	return func(c *jobConfig) {
+		c.priority = p
+	}
+}
+
+// WithDeadline sets the deadline of the job. A job still queued at the deadline is
+// dropped with context.DeadlineExceeded, a running job sees the deadline in its context.
+func WithDeadline(t time.Time) JobOption {
+	return func(c *jobConfig) {
+		c.deadline = t
+	}
+}
+
+// WithMemory sets the estimated memory used by the job in bytes, which is counted
+// against the memory limit of the pool. Pool.Process estimates it from the image
+// header of the source file.
+func WithMemory(bytes int64) JobOption {
+	return func(c *jobConfig) {
+		c.memory = bytes
+	}
+}
+
+// PoolOption sets an optional parameter of a Pool.
+type PoolOption func(*poolConfig)
+
+type poolConfig struct {
+	memoryLimit int64
+	downscale   bool
+	shed        func(stats PoolStats, memory int64) bool
+	observer    func(stats PoolStats)
+}
+
+// PoolMemoryLimit sets a soft limit of the estimated memory used by the running jobs
+// in bytes, 0 (the default) means no limit. A job that doesn't fit within the limit
+// stays queued until the running jobs release enough memory; a job larger than the
+// limit is run once no other job is running.
+func PoolMemoryLimit(bytes int64) PoolOption {
+	return func(c *poolConfig) {
+		c.memoryLimit = bytes
+	}
+}
+
+// PoolDownscale sets whether Pool.Process downscales the source images of the jobs
+// larger than the memory limit right after decoding, so that the pipeline operations
+// fit within the limit. The result is smaller than without the limit, but the
+// service keeps running instead of running out of memory. The source is decoded at
+// full size before it is downscaled, so the jobs whose decoded image leaves no room
+// within the limit for the downscaled copies of at least 16 pixels on the shorter
+// side are rejected with ErrPoolOverloaded.
+func PoolDownscale(enabled bool) PoolOption {
+	return func(c *poolConfig) {
+		c.downscale = enabled
+	}
+}
+
+// PoolShed sets a hook called for every submitted job with the current stats of the
+// pool and the estimated memory of the job. If it returns true, the job is rejected
+// with ErrPoolOverloaded, so that services can shed the load gracefully, e.g. with
+// 503 responses, instead of queueing it. The hook may call the methods of the pool.
+func PoolShed(fn func(stats PoolStats, memory int64) bool) PoolOption {
+	return func(c *poolConfig) {
+		c.shed = fn
+	}
+}
+
+// PoolObserver sets a hook called with the current stats of the pool whenever a job
+// is started or finished, e.g. to export the memory usage as metrics. The calls are
+// made one at a time in the order of the changes, and the hook may call the methods
+// of the pool.
+func PoolObserver(fn func(stats PoolStats)) PoolOption {
+	return func(c *poolConfig) {
+		c.observer = fn
+	}
+}
+
+// PoolStats describes the current load of a Pool.
+type PoolStats struct {
+	// Running and Queued are the numbers of the running and the queued jobs.
+	Running, Queued int
+	// MemoryInUse and MemoryQueued are the estimated memory of the running and the
+	// queued jobs in bytes.
+	MemoryInUse, MemoryQueued int64
+	// MemoryLimit is the memory limit set with PoolMemoryLimit.
+	MemoryLimit int64
+}
+
+// Pool is a pool of workers shared by the image processing jobs of a process, e.g.
+// the pipelines of interactive requests and background batches. The queued jobs are
+// started by priority, and in the order they were submitted within a priority, so
+// interactive jobs overtake the queued batch jobs. The running jobs are not
+// interrupted. With PoolMemoryLimit, the jobs are also held back when the memory
+// they need would exceed the limit. A Pool is safe for concurrent use.
+//
+// Example:
+//
+//	pool := imaging.NewPool(0)
+//	defer pool.Close()
+//	// In the HTTP handler:
+//	err := pool.Process(r.Context(), thumbnail, src, dst,
+//		imaging.WithPriority(imaging.PriorityInteractive),
+//		imaging.WithDeadline(time.Now().Add(2*time.Second)))
+//	// In the nightly batch:
+//	err := pool.Process(ctx, archive, src, dst, imaging.WithPriority(imaging.PriorityBackground))
+type Pool struct {
+	config  poolConfig
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queue   jobQueue
+	seq     uint64
+	closed  bool
+	running int
+	inUse   int64
+	wg      sync.WaitGroup
+	// observed holds the stats not yet passed to the observer, guarded by mu.
+	// observeMu serializes the observer calls, so the stats are passed in order.
+	observed  []PoolStats
+	observeMu sync.Mutex
+}
+
+// NewPool starts a pool of the number of workers, GOMAXPROCS if workers is less than 1.
+func NewPool(workers int, opts ...PoolOption) *Pool {
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	p := &Pool{}
+	for _, option := range opts {
+		option(&p.config)
+	}
+	p.cond = sync.NewCond(&p.mu)
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+// poolJob is a job queued in a Pool.
+type poolJob struct {
+	ctx      context.Context
+	fn       func(ctx context.Context) error
+	priority Priority
+	memory   int64
+	seq      uint64
+	// started and canceled are guarded by the pool mutex.
+	started  bool
+	canceled bool
+	done     chan error
+}
+
+// Do runs fn in the pool and returns its error. It returns the error of the context
+// without running fn if the context is done, or the deadline of the job passes,
+// before fn is started.
+func (p *Pool) Do(ctx context.Context, fn func(ctx context.Context) error, opts ...JobOption) error {
+	var cfg jobConfig
+	for _, option := range opts {
+		option(&cfg)
+	}
+	if !cfg.deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, cfg.deadline)
+		defer cancel()
+	}
+
+	job := &poolJob{ctx: ctx, fn: fn, priority: cfg.priority, memory: cfg.memory, done: make(chan error, 1)}
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrPoolClosed
+	}
+	if p.config.shed != nil {
+		// The hook is called without the mutex, so it may call the pool methods.
+		stats := p.stats()
+		p.mu.Unlock()
+		if p.config.shed(stats, job.memory) {
+			return ErrPoolOverloaded
+		}
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return ErrPoolClosed
+		}
+	}
+	p.seq++
+	job.seq = p.seq
+	heap.Push(&p.queue, job)
+	p.cond.Signal()
+	p.mu.Unlock()
+
+	select {
+	case err := <-job.done:
+		return err
+	case <-ctx.Done():
+	}
+	p.mu.Lock()
+	if !job.started {
+		// The job is dropped by the worker popping it.
+		job.canceled = true
+		p.mu.Unlock()
+		return ctx.Err()
+	}
+	p.mu.Unlock()
+	return <-job.done
+}
+
+// Process runs the pipeline on the src file in the pool as Pipeline.Process does. The
+// memory of the job is estimated from the image header of the source file, unless it
+// is set with WithMemory. If the header can't be read, the job is not counted and the
+// error is left to Pipeline.Process.
+func (p *Pool) Process(ctx context.Context, pipeline *Pipeline, src, dst string, opts ...JobOption) error {
+	info, err := Inspect(src)
+	if err != nil {
+		return p.Do(ctx, func(context.Context) error {
+			return pipeline.Process(src, dst)
+		}, opts...)
+	}
+	decoded := int64(info.Width) * int64(info.Height) * int64(bytesPerPixel(info.ColorModel))
+	working := int64(info.Width) * int64(info.Height) * 4 * 2
+	maxPixels := 0
+	if limit := p.config.memoryLimit; p.config.downscale && limit > 0 && decoded+working > limit {
+		// The pipeline operations work on NRGBA copies of the downscaled image, next to
+		// the decoded source.
+		maxPixels = int((limit - decoded) / 8)
+		if minPixels := poolMinDownscalePixels(info.Width, info.Height); maxPixels < minPixels {
+			return fmt.Errorf("%w: %s: %dx%d image can't be downscaled within the memory limit of %d bytes",
+				ErrPoolOverloaded, src, info.Width, info.Height, limit)
+		}
+		working = int64(maxPixels) * 8
+	}
+	opts = append([]JobOption{WithMemory(decoded + working)}, opts...)
+	return p.Do(ctx, func(context.Context) error {
+		if maxPixels == 0 {
+			return pipeline.Process(src, dst)
+		}
+		return processDownscaled(pipeline, src, dst, maxPixels)
+	}, opts...)
+}
+
+// poolMinDownscale is the smallest shorter side of the images downscaled by a Pool.
+const poolMinDownscale = 16
+
+// poolMinDownscalePixels returns the number of pixels of the image of the size
+// downscaled to poolMinDownscale pixels on the shorter side, or of the whole image
+// if it's smaller.
+func poolMinDownscalePixels(width, height int) int {
+	short, long := width, height
+	if short > long {
+		short, long = long, short
+	}
+	if short <= poolMinDownscale {
+		return width * height
+	}
+	return poolMinDownscale * int(math.Ceil(float64(long)*poolMinDownscale/float64(short)))
+}
+
+// processDownscaled is like Pipeline.Process, but downscales the source image to at
+// most maxPixels before the pipeline operations.
+func processDownscaled(pipeline *Pipeline, src, dst string, maxPixels int) error {
+	img, m, err := pipeline.open(src)
+	if err != nil {
+		return err
+	}
+	b := img.Bounds()
+	if pixels := b.Dx() * b.Dy(); pixels > maxPixels {
+		s := math.Sqrt(float64(maxPixels) / float64(pixels))
+		w, h := int(float64(b.Dx())*s), int(float64(b.Dy())*s)
+		if w < 1 {
+			w = 1
+		}
+		if h < 1 {
+			h = 1
+		}
+		img = Resize(img, w, h, Linear)
+	}
+	if err := Save(pipeline.Apply(img), dst, pipeline.outputOptions(m)...); err != nil {
+		return fmt.Errorf("%s: %w", dst, err)
+	}
+	return nil
+}
+
+// Queued returns the number of the jobs waiting for a worker.
+func (p *Pool) Queued() int {
+	return p.Stats().Queued
+}
+
+// Stats returns the current stats of the pool.
+func (p *Pool) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stats()
+}
+
+// stats returns the current stats of the pool. It must be called with the mutex held.
+func (p *Pool) stats() PoolStats {
+	s := PoolStats{Running: p.running, MemoryInUse: p.inUse, MemoryLimit: p.config.memoryLimit}
+	for _, job := range p.queue {
+		if !job.canceled {
+			s.Queued++
+			s.MemoryQueued += job.memory
+		}
+	}
+	return s
+}
+
+// startable reports whether the first queued job can be started within the memory
+// limit, dropping the canceled jobs. It must be called with the mutex held.
+func (p *Pool) startable() bool {
+	for len(p.queue) > 0 && p.queue[0].canceled {
+		heap.Pop(&p.queue)
+	}
+	if len(p.queue) == 0 {
+		return false
+	}
+	limit := p.config.memoryLimit
+	return limit <= 0 || p.running == 0 || p.inUse+p.queue[0].memory <= limit
+}
+
+// Close stops the pool: the queued jobs fail with ErrPoolClosed, and Close waits for
+// the running jobs to finish.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	for _, job := range p.queue {
+		job.done <- ErrPoolClosed
+	}
+	p.queue = nil
+	p.cond.Broadcast()
+	p.mu.Unlock()
+	p.wg.Wait()
+}
+
+// work runs the queued jobs until the pool is closed.
+func (p *Pool) work() {
+	defer p.wg.Done()
+	for {
+		p.mu.Lock()
+		for !p.closed && !p.startable() {
+			p.cond.Wait()
+		}
+		if p.closed {
+			p.mu.Unlock()
+			return
+		}
+		job := heap.Pop(&p.queue).(*poolJob)
+		job.started = true
+		if err := job.ctx.Err(); err != nil {
+			p.mu.Unlock()
+			job.done <- err
+			continue
+		}
+		p.running++
+		p.inUse += job.memory
+		p.observe()
+		p.mu.Unlock()
+		p.flushObserved()
+
+		err := job.fn(job.ctx)
+
+		p.mu.Lock()
+		p.running--
+		p.inUse -= job.memory
+		p.observe()
+		// The released memory may let several jobs start.
+		p.cond.Broadcast()
+		p.mu.Unlock()
+		p.flushObserved()
+		job.done <- err
+	}
+}
+
+// observe queues the current stats for the observer. It must be called with the
+// mutex held, and followed by flushObserved once the mutex is released.
+func (p *Pool) observe() {
+	if p.config.observer != nil {
+		p.observed = append(p.observed, p.stats())
+	}
+}
+
+// flushObserved calls the observer with the queued stats. It must be called without
+// the mutex held, so the observer may call the pool methods.
+func (p *Pool) flushObserved() {
+	if p.config.observer == nil {
+		return
+	}
+	p.observeMu.Lock()
+	defer p.observeMu.Unlock()
+	p.mu.Lock()
+	observed := p.observed
+	p.observed = nil
+	p.mu.Unlock()
+	for _, stats := range observed {
+		p.config.observer(stats)
+	}
+}
+
+// jobQueue is a heap of the queued jobs, the first job has the highest priority and
+// was submitted first.
+type jobQueue []*poolJob
+
+func (q jobQueue) Len() int { return len(q) }
+
+func (q jobQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q jobQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *jobQueue) Push(x any) { *q = append(*q, x.(*poolJob)) }
+
+func (q *jobQueue) Pop() any {
+	old := *q
+	job := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	return job
+}
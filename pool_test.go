@@ -0,0 +1,379 @@
+package imaging
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockPool returns a pool of a single worker blocked until the returned function is
+// called.
+func blockPool(t *testing.T) (*Pool, func()) {
+	t.Helper()
+	p := NewPool(1)
+	started, release := make(chan struct{}), make(chan struct{})
+	go p.Do(context.Background(), func(context.Context) error { //nolint
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+	var once sync.Once
+	return p, func() { once.Do(func() { close(release) }) }
+}
+
+// waitQueued waits until the pool has n queued jobs.
+func waitQueued(t *testing.T, p *Pool, n int) {
+	t.Helper()
+	for start := time.Now(); p.Queued() != n; time.Sleep(time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("got %d queued jobs want %d", p.Queued(), n)
+		}
+	}
+}
+
+func TestPoolPriority(t *testing.T) {
+	t.Parallel()
+
+	p, release := blockPool(t)
+	defer p.Close()
+	defer release()
+
+	var mu sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	jobs := []struct {
+		name     string
+		priority Priority
+	}{
+		{"background 1", PriorityBackground},
+		{"background 2", PriorityBackground},
+		{"interactive", PriorityInteractive},
+		{"normal", PriorityNormal},
+	}
+	for i, job := range jobs {
+		job := job
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := p.Do(context.Background(), func(context.Context) error {
+				mu.Lock()
+				defer mu.Unlock()
+				order = append(order, job.name)
+				return nil
+			}, WithPriority(job.priority))
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", job.name, err)
+			}
+		}()
+		waitQueued(t, p, i+1)
+	}
+	release()
+	wg.Wait()
+
+	want := []string{"interactive", "normal", "background 1", "background 2"}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("got order %v want %v", order, want)
+		}
+	}
+}
+
+func TestPoolDeadline(t *testing.T) {
+	t.Parallel()
+
+	p, release := blockPool(t)
+	defer p.Close()
+
+	run := false
+	fn := func(context.Context) error {
+		run = true
+		return nil
+	}
+	err := p.Do(context.Background(), fn, WithDeadline(time.Now().Add(10*time.Millisecond)))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got error %v want %v", err, context.DeadlineExceeded)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		waitQueued(t, p, 1)
+		cancel()
+	}()
+	if err := p.Do(ctx, fn); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v want %v", err, context.Canceled)
+	}
+	if n := p.Queued(); n != 0 {
+		t.Fatalf("got %d queued jobs want 0", n)
+	}
+
+	// The running job sees the deadline in its context.
+	release()
+	err = p.Do(context.Background(), func(ctx context.Context) error {
+		if _, ok := ctx.Deadline(); !ok {
+			return errors.New("no deadline")
+		}
+		return fn(ctx)
+	}, WithDeadline(time.Now().Add(time.Minute)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !run {
+		t.Fatalf("the job is not run")
+	}
+}
+
+func TestPoolClose(t *testing.T) {
+	t.Parallel()
+
+	p, release := blockPool(t)
+	errJob := errors.New("job failed")
+	errs := make(chan error)
+	go func() {
+		errs <- p.Do(context.Background(), func(context.Context) error { return errJob })
+	}()
+	waitQueued(t, p, 1)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		release()
+	}()
+	p.Close()
+	if err := <-errs; !errors.Is(err, ErrPoolClosed) {
+		t.Fatalf("got error %v want %v", err, ErrPoolClosed)
+	}
+	if err := p.Do(context.Background(), func(context.Context) error { return errJob }); !errors.Is(err, ErrPoolClosed) {
+		t.Fatalf("got error %v want %v", err, ErrPoolClosed)
+	}
+	p.Close()
+}
+
+func TestPoolProcess(t *testing.T) {
+	t.Parallel()
+
+	p := NewPool(0)
+	defer p.Close()
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "in.png"), filepath.Join(dir, "out.png")
+	if err := Save(New(8, 8, color.White), src); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+	pipeline := NewPipeline().Then("fit 4x4", func(img image.Image) image.Image {
+		return Fit(img, 4, 4, Box)
+	})
+	if err := p.Process(context.Background(), pipeline, src, dst, WithPriority(PriorityInteractive)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	img, err := Open(dst)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if img.Bounds() != image.Rect(0, 0, 4, 4) {
+		t.Fatalf("got bounds %v want 4x4", img.Bounds())
+	}
+	if err := p.Process(context.Background(), pipeline, filepath.Join(dir, "missing.png"), dst); err == nil {
+		t.Fatalf("expected error processing a missing file")
+	}
+}
+
+func TestPoolMemoryLimit(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var peak int64
+	p := NewPool(2, PoolMemoryLimit(100), PoolObserver(func(s PoolStats) {
+		mu.Lock()
+		defer mu.Unlock()
+		if s.MemoryInUse > peak {
+			peak = s.MemoryInUse
+		}
+	}))
+	defer p.Close()
+
+	started, release := make(chan struct{}), make(chan struct{})
+	go p.Do(context.Background(), func(context.Context) error { //nolint
+		close(started)
+		<-release
+		return nil
+	}, WithMemory(80))
+	<-started
+
+	done := make(chan error, 1)
+	go func() {
+		done <- p.Do(context.Background(), func(context.Context) error { return nil }, WithMemory(50))
+	}()
+	waitQueued(t, p, 1)
+	time.Sleep(10 * time.Millisecond)
+	want := PoolStats{Running: 1, Queued: 1, MemoryInUse: 80, MemoryQueued: 50, MemoryLimit: 100}
+	if s := p.Stats(); s != want {
+		t.Fatalf("got stats %+v want %+v", s, want)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A job larger than the limit runs alone.
+	if err := p.Do(context.Background(), func(context.Context) error { return nil }, WithMemory(200)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if peak != 200 {
+		t.Fatalf("got peak memory %d want 200", peak)
+	}
+	if s := p.Stats(); s.Running != 0 || s.MemoryInUse != 0 {
+		t.Fatalf("got stats %+v want no running jobs", s)
+	}
+}
+
+func TestPoolShed(t *testing.T) {
+	t.Parallel()
+
+	p := NewPool(1, PoolShed(func(s PoolStats, memory int64) bool {
+		return memory > 100
+	}))
+	defer p.Close()
+	if err := p.Do(context.Background(), func(context.Context) error { return nil }, WithMemory(100)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err := p.Do(context.Background(), func(context.Context) error { return nil }, WithMemory(101))
+	if !errors.Is(err, ErrPoolOverloaded) {
+		t.Fatalf("got error %v want %v", err, ErrPoolOverloaded)
+	}
+}
+
+func TestPoolHooksCallPool(t *testing.T) {
+	t.Parallel()
+
+	var p *Pool
+	var mu sync.Mutex
+	var observed []PoolStats
+	p = NewPool(1,
+		PoolShed(func(s PoolStats, memory int64) bool {
+			return p.Queued() > 10
+		}),
+		PoolObserver(func(s PoolStats) {
+			mu.Lock()
+			defer mu.Unlock()
+			observed = append(observed, p.Stats())
+		}))
+	defer p.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- p.Do(context.Background(), func(context.Context) error { return nil })
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("the job calling the pool from the hooks didn't finish")
+	}
+	p.Close()
+	mu.Lock()
+	defer mu.Unlock()
+	if len(observed) != 2 {
+		t.Fatalf("got %d observer calls want 2", len(observed))
+	}
+}
+
+func TestPoolDownscale(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "in.png")
+	if err := Save(New(100, 50, color.White), src); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+	// The decoded NRGBA image takes 20000 bytes, the limit of 28000 bytes leaves 8000
+	// bytes for 1000 working pixels. The downscaled copies of 16x32 pixels need 4096 bytes.
+	testCases := []struct {
+		name      string
+		limit     int64
+		downscale bool
+		want      image.Rectangle
+		wantErr   error
+	}{
+		{"queued", 28000, false, image.Rect(0, 0, 100, 50), nil},
+		{"downscaled", 28000, true, image.Rect(0, 0, 44, 22), nil},
+		{"smallest", 24096, true, image.Rect(0, 0, 32, 16), nil},
+		{"too small", 24000, true, image.Rectangle{}, ErrPoolOverloaded},
+		{"decoded above limit", 15000, true, image.Rectangle{}, ErrPoolOverloaded},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			p := NewPool(1, PoolMemoryLimit(tc.limit), PoolDownscale(tc.downscale))
+			defer p.Close()
+			dst := filepath.Join(dir, tc.name+".png")
+			err := p.Process(context.Background(), NewPipeline(), src, dst)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("got error %v want %v", err, tc.wantErr)
+				}
+				if _, err := os.Stat(dst); !os.IsNotExist(err) {
+					t.Fatalf("got output file, error %v want none", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			img, err := Open(dst)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if img.Bounds() != tc.want {
+				t.Fatalf("got bounds %v want %v", img.Bounds(), tc.want)
+			}
+		})
+	}
+}
+
+func TestPoolDownscaleMetadata(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "in.jpg")
+	exif := (*EXIF)(nil).SetArtist("Jane Doe")
+	if err := Save(New(100, 50, color.White), src, WithMetadata(&Metadata{EXIF: exif})); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+	p := NewPool(1, PoolMemoryLimit(28000), PoolDownscale(true))
+	defer p.Close()
+	for _, tc := range []struct {
+		name   string
+		p      *Pipeline
+		artist string
+	}{
+		{"kept", NewPipeline(), "Jane Doe"},
+		{"stripped", NewPipeline().Strip(true), ""},
+	} {
+		dst := filepath.Join(dir, tc.name+".jpg")
+		if err := p.Process(context.Background(), tc.p, src, dst); err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+		img, m, err := OpenWithMetadata(dst)
+		if err != nil {
+			t.Fatalf("%s: failed to open: %v", tc.name, err)
+		}
+		if img.Bounds().Dx() >= 100 {
+			t.Fatalf("%s: got bounds %v want the downscaled image", tc.name, img.Bounds())
+		}
+		artist := ""
+		if m.EXIF != nil {
+			artist = m.EXIF.Artist()
+		}
+		if artist != tc.artist {
+			t.Fatalf("%s: got artist %q want %q", tc.name, artist, tc.artist)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package imaging
+
+import (
+	"context"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"sync/atomic"
+	"unicode"
+	"unicode/utf8"
+)
+
+var profileLabels int32
+
+// SetProfileLabels enables or disables the pprof labels of the processing goroutines.
+// When enabled, the goroutines are labeled with the operation using them, as
+// "imaging_op", e.g. "Resize" or "Blur", and the bucket of the number of the rows or
+// columns they process, as "imaging_size": "small" up to 512, "medium" up to 2048,
+// "large" up to 8192, and "huge" above, so that CPU profiles attribute the time to the
+// operations and the image sizes.
+//
+// The labels can't be merged with those of the calling goroutine, so the processing
+// goroutines don't inherit the labels of the caller, e.g. the endpoint set with
+// pprof.Do, while the labels are enabled. Disabled by default.
+func SetProfileLabels(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&profileLabels, v)
+}
+
+// profileContext returns the context with the pprof labels of the processing
+// goroutines of count rows or columns, or nil if the labels are disabled.
+func profileContext(count int) context.Context {
+	if atomic.LoadInt32(&profileLabels) == 0 {
+		return nil
+	}
+	return pprof.WithLabels(context.Background(), pprof.Labels(
+		"imaging_op", callerOperation(),
+		"imaging_size", sizeBucket(count),
+	))
+}
+
+// callerOperation returns the name of the innermost exported function of the package
+// in the call stack, "unknown" if there is none.
+func callerOperation() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		frame, more := frames.Next()
+		if op, ok := operationName(frame.Function); ok {
+			return op
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
+
+// operationName returns the name of the function of the package without the package
+// path, e.g. "Resize" or "Pipeline.Apply". It reports false for the functions of other
+// packages, and for the unexported functions and the closures.
+func operationName(function string) (string, bool) {
+	const prefix = "github.com/go-spectest/imaging."
+	if !strings.HasPrefix(function, prefix) {
+		return "", false
+	}
+	name := function[len(prefix):]
+	if strings.HasPrefix(name, "(") {
+		// A method, e.g. "(*Pipeline).Apply".
+		i := strings.Index(name, ").")
+		if i < 0 {
+			return "", false
+		}
+		name = strings.TrimLeft(name[:i], "(*") + "." + name[i+2:]
+	}
+	for _, part := range strings.Split(name, ".") {
+		if !exported(part) {
+			return "", false
+		}
+	}
+	return name, true
+}
+
+// exported reports whether the name starts with an upper case letter.
+func exported(name string) bool {
+	r, _ := utf8.DecodeRuneInString(name)
+	return unicode.IsUpper(r)
+}
+
+// sizeBucket returns the "imaging_size" label of the number of rows or columns.
+func sizeBucket(count int) string {
+	switch {
+	case count <= 512:
+		return "small"
+	case count <= 2048:
+		return "medium"
+	case count <= 8192:
+		return "large"
+	default:
+		return "huge"
+	}
+}
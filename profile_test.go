@@ -0,0 +1,60 @@
+package imaging
+
+import (
+	"bytes"
+	"runtime/pprof"
+	"testing"
+)
+
+func TestOperationName(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		function string
+		want     string
+		ok       bool
+	}{
+		{"github.com/go-spectest/imaging.Resize", "Resize", true},
+		{"github.com/go-spectest/imaging.(*Pipeline).Apply", "Pipeline.Apply", true},
+		{"github.com/go-spectest/imaging.Resize.func1", "", false},
+		{"github.com/go-spectest/imaging.resizeHorizontal", "", false},
+		{"github.com/go-spectest/imaging.(*carver).resize", "", false},
+		{"github.com/go-spectest/imaging/storage/memfs.New", "", false},
+		{"main.main", "", false},
+	}
+	for _, tc := range testCases {
+		got, ok := operationName(tc.function)
+		if got != tc.want || ok != tc.ok {
+			t.Fatalf("operationName(%q): got %q, %t want %q, %t", tc.function, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestSizeBucket(t *testing.T) {
+	t.Parallel()
+
+	for count, want := range map[int]string{1: "small", 512: "small", 513: "medium", 8192: "large", 8193: "huge"} {
+		if got := sizeBucket(count); got != want {
+			t.Fatalf("sizeBucket(%d): got %q want %q", count, got, want)
+		}
+	}
+}
+
+func TestSetProfileLabels(t *testing.T) {
+	// Not parallel: the labels are enabled for the whole package.
+	SetProfileLabels(true)
+	defer SetProfileLabels(false)
+
+	var buf bytes.Buffer
+	parallel(0, 1, func(<-chan int) {
+		if err := pprof.Lookup("goroutine").WriteTo(&buf, 1); err != nil {
+			t.Errorf("failed to write the profile: %v", err)
+		}
+	})
+	if want := `"imaging_size":"small"`; !bytes.Contains(buf.Bytes(), []byte(want)) {
+		t.Fatalf("the goroutine profile has no %s label:\n%s", want, buf.String())
+	}
+	if want := `"imaging_op":"TestSetProfileLabels"`; !bytes.Contains(buf.Bytes(), []byte(want)) {
+		t.Fatalf("the goroutine profile has no %s label:\n%s", want, buf.String())
+	}
+}
@@ -0,0 +1,149 @@
+package imaging
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"strings"
+	"sync"
+)
+
+// ErrFormatRegistered means the format extension passed to RegisterFormat is already in use.
+var ErrFormatRegistered = errors.New("imaging: format extension already registered")
+
+// customFormat is an image format registered with RegisterFormat.
+type customFormat struct {
+	// name is the name of the format returned by Format.String.
+	name string
+	// decode decodes the image, nil if the format can't be decoded.
+	decode func(io.Reader) (image.Image, error)
+	// encode encodes the image, nil if the format can't be encoded.
+	encode func(io.Writer, image.Image) error
+}
+
+// customFormats holds the formats registered with RegisterFormat.
+var customFormats = struct { //nolint
+	sync.RWMutex
+	formats map[Format]customFormat
+	exts    map[string]Format
+	next    Format
+}{
+	formats: map[Format]customFormat{},
+	exts:    map[string]Format{},
+	next:    PDF + 1,
+}
+
+// RegisterFormat registers an image format with its own codec and returns its Format value.
+// Files with any of the extensions are decoded with decode by Open and OpenFS, and
+// Encode, Save and the other saving functions use encode. Either function can be nil
+// if the format is read-only or write-only. FormatFromExtension and FormatFromFilename
+// recognize the extensions, and the name is returned by Format.String.
+//
+// Decode and DecodeAll can't tell the format from the data alone, so to decode the
+// format from io.Reader also register it with image.RegisterFormat.
+//
+// RegisterFormat is usually called from an init function. The extensions must not be
+// in use by another format, otherwise ErrFormatRegistered is returned.
+//
+// Example:
+//
+//	var QOI, _ = imaging.RegisterFormat("QOI", []string{"qoi"}, qoi.Decode, qoi.Encode)
+func RegisterFormat(
+	name string,
+	extensions []string,
+	decode func(io.Reader) (image.Image, error),
+	encode func(io.Writer, image.Image) error,
+) (Format, error) {
+	if len(extensions) == 0 {
+		return -1, fmt.Errorf("%w: no extensions given for %s", ErrUnsupportedFormat, name)
+	}
+	if decode == nil && encode == nil {
+		return -1, fmt.Errorf("%w: no codec given for %s", ErrUnsupportedFormat, name)
+	}
+
+	customFormats.Lock()
+	defer customFormats.Unlock()
+	exts := make([]string, len(extensions))
+	for i, ext := range extensions {
+		ext = strings.ToLower(strings.TrimPrefix(ext, "."))
+		_, builtin := formatExts[ext]
+		_, custom := customFormats.exts[ext]
+		if ext == "" || builtin || custom {
+			return -1, fmt.Errorf("%w: %q", ErrFormatRegistered, ext)
+		}
+		exts[i] = ext
+	}
+
+	f := customFormats.next
+	customFormats.next++
+	customFormats.formats[f] = customFormat{name: name, decode: decode, encode: encode}
+	for _, ext := range exts {
+		customFormats.exts[ext] = f
+	}
+	return f, nil
+}
+
+// lookupCustomFormat returns the registered format with the given value.
+func lookupCustomFormat(f Format) (customFormat, bool) {
+	customFormats.RLock()
+	defer customFormats.RUnlock()
+	cf, ok := customFormats.formats[f]
+	return cf, ok
+}
+
+// customFormatFromExtension returns the registered format of the extension
+// (lowercase and without the leading dot).
+func customFormatFromExtension(ext string) (Format, bool) {
+	customFormats.RLock()
+	defer customFormats.RUnlock()
+	f, ok := customFormats.exts[ext]
+	return f, ok
+}
+
+// customFormatExtensions returns the extensions of the registered formats.
+func customFormatExtensions() []string {
+	customFormats.RLock()
+	defer customFormats.RUnlock()
+	exts := make([]string, 0, len(customFormats.exts))
+	for ext := range customFormats.exts {
+		exts = append(exts, ext)
+	}
+	return exts
+}
+
+// customDecoder returns the decoder of the registered format of the file, or nil.
+func customDecoder(filename string) func(io.Reader) (image.Image, error) {
+	f, err := FormatFromFilename(filename)
+	if err != nil {
+		return nil
+	}
+	cf, ok := lookupCustomFormat(f)
+	if !ok {
+		return nil
+	}
+	return cf.decode
+}
+
+// decodeCustom decodes an image of a registered format. The decode limits are
+// checked after decoding since the image header can't be read separately.
+func decodeCustom(r io.Reader, decode func(io.Reader) (image.Image, error), opts []DecodeOption) (image.Image, error) {
+	cfg := defaultDecodeConfig
+	for _, option := range opts {
+		option(&cfg)
+	}
+	img, err := decode(r)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.hasLimits() {
+		b := img.Bounds()
+		if err := checkConfigLimits(image.Config{ColorModel: img.ColorModel(), Width: b.Dx(), Height: b.Dy()}, cfg); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.preserve16Bit && is16Bit(img) {
+		return toNRGBA64(img), nil
+	}
+	return img, nil
+}
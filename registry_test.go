@@ -0,0 +1,129 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// encodeTestRaw writes the image as the width and height followed by the NRGBA pixels.
+func encodeTestRaw(w io.Writer, img image.Image) error {
+	src := Clone(img)
+	hdr := make([]byte, 8)
+	binary.BigEndian.PutUint32(hdr, uint32(src.Rect.Dx()))
+	binary.BigEndian.PutUint32(hdr[4:], uint32(src.Rect.Dy()))
+	if _, err := w.Write(hdr); err != nil {
+		return err
+	}
+	_, err := w.Write(src.Pix)
+	return err
+}
+
+// decodeTestRaw reads an image written by encodeTestRaw.
+func decodeTestRaw(r io.Reader) (image.Image, error) {
+	hdr := make([]byte, 8)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return nil, err
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, int(binary.BigEndian.Uint32(hdr)), int(binary.BigEndian.Uint32(hdr[4:]))))
+	if _, err := io.ReadFull(r, dst.Pix); err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+// The formats are registered once, so that the tests can run multiple times.
+var (
+	testRawFormat, errTestRawFormat     = RegisterFormat("RAW", []string{".testraw", "TRW"}, decodeTestRaw, encodeTestRaw)
+	testWriteFormat, errTestWriteFormat = RegisterFormat("WRITE", []string{"testwo"}, nil, encodeTestRaw)
+)
+
+func TestRegisterFormat(t *testing.T) {
+	raw, err := testRawFormat, errTestRawFormat
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if raw.String() != "RAW" {
+		t.Fatalf("got name %q want %q", raw.String(), "RAW")
+	}
+	for _, name := range []string{"a.testraw", "b.TRW"} {
+		if f, err := FormatFromFilename(name); err != nil || f != raw {
+			t.Fatalf("%s: got format %v, error %v", name, f, err)
+		}
+	}
+
+	exts := strings.Join(FormatExtensions(), " ")
+	if !strings.Contains(exts, "jpeg jpg pdf png testraw testwo tif tiff trw") {
+		t.Fatalf("got extensions %q", exts)
+	}
+
+	writeOnly, err := testWriteFormat, errTestWriteFormat
+	if err != nil || writeOnly == raw {
+		t.Fatalf("got format %v, error %v", writeOnly, err)
+	}
+
+	for _, tc := range []struct {
+		name       string
+		extensions []string
+		decode     func(io.Reader) (image.Image, error)
+		encode     func(io.Writer, image.Image) error
+		want       error
+	}{
+		{name: "builtin extension", extensions: []string{"png"}, decode: decodeTestRaw, want: ErrFormatRegistered},
+		{name: "registered extension", extensions: []string{"x-testraw", "TESTRAW"}, decode: decodeTestRaw, want: ErrFormatRegistered},
+		{name: "no extensions", decode: decodeTestRaw, want: ErrUnsupportedFormat},
+		{name: "no codec", extensions: []string{"testnone"}, want: ErrUnsupportedFormat},
+	} {
+		if _, err := RegisterFormat(tc.name, tc.extensions, tc.decode, tc.encode); !errors.Is(err, tc.want) {
+			t.Fatalf("%s: got error %v want %v", tc.name, err, tc.want)
+		}
+	}
+	// A failed registration doesn't register any of the extensions.
+	if _, err := FormatFromExtension("x-testraw"); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("got error %v want %v", err, ErrUnsupportedFormat)
+	}
+
+	img := makeNoiseNRGBA(5, 3, 6)
+	buf := &bytes.Buffer{}
+	if err := Encode(buf, img, raw); err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	if buf.Len() != 8+len(img.Pix) {
+		t.Fatalf("got %d bytes want %d", buf.Len(), 8+len(img.Pix))
+	}
+
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "out.testraw")
+	if err := Save(img, filename); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+	got, err := Open(filename)
+	if err != nil {
+		t.Fatalf("failed to open: %v", err)
+	}
+	if !compareNRGBA(Clone(got), img, 0) {
+		t.Fatalf("opened image differs")
+	}
+	all, err := OpenAll(filename)
+	if err != nil || len(all) != 1 {
+		t.Fatalf("got %d images, error %v", len(all), err)
+	}
+	if _, err := Open(filename, Limits(4, 0, 0)); !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("got error %v want %v", err, ErrLimitExceeded)
+	}
+
+	// Formats without a decoder fall back to the registered image formats.
+	woName := filepath.Join(dir, "out.testwo")
+	if err := Save(New(2, 2, color.White), woName); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+	if _, err := Open(woName); err == nil {
+		t.Fatalf("expected error decoding a write-only format")
+	}
+}
@@ -1,8 +1,13 @@
 package imaging
 
 import (
+	"errors"
+	"fmt"
 	"image"
+	"image/color"
 	"math"
+	"strings"
+	"sync"
 )
 
 type indexWeight struct {
@@ -62,53 +67,234 @@ func precomputeWeights(dstSize, srcSize int, filter ResampleFilter) [][]indexWei
 //
 //	dstImage := imaging.Resize(srcImage, 800, 600, imaging.Lanczos)
 func Resize(img image.Image, width, height int, filter ResampleFilter) *image.NRGBA {
-	dstW, dstH := width, height
-	if dstW < 0 || dstH < 0 {
+	srcW, srcH := img.Bounds().Dx(), img.Bounds().Dy()
+	dstW, dstH := resizeDimensions(srcW, srcH, width, height)
+	if dstW == 0 {
 		return &image.NRGBA{}
 	}
-	if dstW == 0 && dstH == 0 {
-		return &image.NRGBA{}
+
+	if srcW == dstW && srcH == dstH {
+		return Clone(img)
+	}
+
+	if filter.Support <= 0 {
+		// Nearest-neighbor special case.
+		return resizeNearest(img, dstW, dstH)
+	}
+
+	if srcW != dstW && srcH != dstH {
+		return resizeVertical(resizeHorizontal(img, dstW, filter), dstH, filter)
+	}
+	if srcW != dstW {
+		return resizeHorizontal(img, dstW, filter)
 	}
+	return resizeVertical(img, dstH, filter)
+
+}
 
-	srcW := img.Bounds().Dx()
-	srcH := img.Bounds().Dy()
+// ResizeInto is like Resize, but writes the resized image into dst instead of allocating
+// it, e.g. to reuse the buffers of the video frame thumbnails. The image is resized to
+// the size of dst, ignoring the aspect ratio. The intermediate buffer of the two passes
+// is reused between the calls as well. dst must not overlap the image. An empty image
+// makes dst transparent.
+//
+// Example:
+//
+//	thumb := image.NewNRGBA(image.Rect(0, 0, 320, 180))
+//	for frame := range frames {
+//		imaging.ResizeInto(thumb, frame, imaging.Linear)
+//		...
+//	}
+func ResizeInto(dst *image.NRGBA, img image.Image, filter ResampleFilter) {
+	srcW, srcH := img.Bounds().Dx(), img.Bounds().Dy()
+	dstW, dstH := dst.Rect.Dx(), dst.Rect.Dy()
+	if dstW <= 0 || dstH <= 0 {
+		return
+	}
 	if srcW <= 0 || srcH <= 0 {
-		return &image.NRGBA{}
+		for y := 0; y < dstH; y++ {
+			row := dst.Pix[y*dst.Stride : y*dst.Stride+dstW*4]
+			for i := range row {
+				row[i] = 0
+			}
+		}
+		return
 	}
 
-	// If new width or height is 0 then preserve aspect ratio, minimum 1px.
-	if dstW == 0 {
-		tmpW := float64(dstH) * float64(srcW) / float64(srcH)
-		dstW = int(math.Max(1.0, math.Floor(tmpW+0.5)))
+	switch {
+	case srcW == dstW && srcH == dstH:
+		_ = CloneInto(dst, img)
+	case filter.Support <= 0:
+		resizeNearestInto(dst, img)
+	case srcW != dstW && srcH != dstH:
+		tmp := getScratch(dstW, srcH)
+		defer putScratch(tmp)
+		resampleHorizontalInto(tmp, img, precomputeWeights(dstW, srcW, filter))
+		resampleVerticalInto(dst, tmp, precomputeWeights(dstH, srcH, filter))
+	case srcW != dstW:
+		resampleHorizontalInto(dst, img, precomputeWeights(dstW, srcW, filter))
+	default:
+		resampleVerticalInto(dst, img, precomputeWeights(dstH, srcH, filter))
 	}
-	if dstH == 0 {
-		tmpH := float64(dstW) * float64(srcH) / float64(srcW)
-		dstH = int(math.Max(1.0, math.Floor(tmpH+0.5)))
+}
+
+// ResizeXY resizes the image like Resize, but with different resampling filters for the
+// horizontal and the vertical passes, e.g. for anisotropic content such as the scanlines
+// of the video frames or the rows of the text, sharp along the lines and smooth across
+// them. NearestNeighbor can be used for one of the axes.
+//
+// Example:
+//
+//	dstImage := imaging.ResizeXY(srcImage, 640, 480, imaging.Lanczos, imaging.Linear)
+func ResizeXY(img image.Image, width, height int, filterX, filterY ResampleFilter) *image.NRGBA {
+	srcW, srcH := img.Bounds().Dx(), img.Bounds().Dy()
+	dstW, dstH := resizeDimensions(srcW, srcH, width, height)
+	if dstW == 0 {
+		return &image.NRGBA{}
 	}
 
 	if srcW == dstW && srcH == dstH {
 		return Clone(img)
 	}
 
-	if filter.Support <= 0 {
-		// Nearest-neighbor special case.
+	if filterX.Support <= 0 && filterY.Support <= 0 {
 		return resizeNearest(img, dstW, dstH)
 	}
 
 	if srcW != dstW && srcH != dstH {
-		return resizeVertical(resizeHorizontal(img, dstW, filter), dstH, filter)
+		return resampleVertical(resampleHorizontal(img, axisWeights(dstW, srcW, filterX)), axisWeights(dstH, srcH, filterY))
 	}
 	if srcW != dstW {
-		return resizeHorizontal(img, dstW, filter)
+		return resampleHorizontal(img, axisWeights(dstW, srcW, filterX))
 	}
-	return resizeVertical(img, dstH, filter)
+	return resampleVertical(img, axisWeights(dstH, srcH, filterY))
+}
 
+// axisWeights returns the weights of the source pixels of every destination pixel for
+// the filter, taking the nearest source pixels for NearestNeighbor.
+func axisWeights(dstSize, srcSize int, filter ResampleFilter) [][]indexWeight {
+	if filter.Support > 0 {
+		return precomputeWeights(dstSize, srcSize, filter)
+	}
+	du := float64(srcSize) / float64(dstSize)
+	out := make([][]indexWeight, dstSize)
+	for v := range out {
+		out[v] = []indexWeight{{index: int((float64(v) + 0.5) * du), weight: 1}}
+	}
+	return out
+}
+
+// Scale resizes the image by the horizontal and vertical scale factors using the specified
+// resampling filter and returns the transformed image, e.g. 0.5, 0.5 halves both
+// dimensions. The dimensions are rounded to the nearest integer, as the percentages of
+// the geometries of ParseGeometry are, and are never less than 1px. If a factor is not
+// positive and finite, an empty image is returned.
+//
+// Example:
+//
+//	dstImage := imaging.Scale(srcImage, 1.5, 1.5, imaging.Lanczos)
+func Scale(img image.Image, factorX, factorY float64, filter ResampleFilter) *image.NRGBA {
+	if !(factorX > 0) || !(factorY > 0) || math.IsInf(factorX, 1) || math.IsInf(factorY, 1) {
+		return &image.NRGBA{}
+	}
+	b := img.Bounds()
+	if b.Empty() {
+		return &image.NRGBA{}
+	}
+	return Resize(img, geometryRound(float64(b.Dx())*factorX), geometryRound(float64(b.Dy())*factorY), filter)
+}
+
+// resizeDimensions returns the size of the source image resized to the width and
+// height. If one of width or height is 0, the aspect ratio is preserved, minimum 1px.
+// It returns 0, 0 if the result is empty.
+func resizeDimensions(srcW, srcH, width, height int) (int, int) {
+	if width < 0 || height < 0 || (width == 0 && height == 0) || srcW <= 0 || srcH <= 0 {
+		return 0, 0
+	}
+	if width == 0 {
+		tmpW := float64(height) * float64(srcW) / float64(srcH)
+		width = int(math.Max(1.0, math.Floor(tmpW+0.5)))
+	}
+	if height == 0 {
+		tmpH := float64(width) * float64(srcH) / float64(srcW)
+		height = int(math.Max(1.0, math.Floor(tmpH+0.5)))
+	}
+	return width, height
+}
+
+// ResizeArea resizes the image to the specified width and height by averaging the
+// source pixels over the area covered by every destination pixel, partially covered
+// pixels weighted by their coverage, and returns the transformed image. If one of width
+// or height is 0, the image aspect ratio is preserved.
+//
+// For large downscale ratios, e.g. of scans to thumbnails, it is faster than the
+// wider filters like Lanczos, while averaging every source pixel removes the fine
+// patterns causing moiré. For integer ratios it gives the result of Resize with Box.
+// It is not suited to upscaling, where it only repeats the source pixels.
+//
+// Example:
+//
+//	thumb := imaging.ResizeArea(scan, 200, 0)
+func ResizeArea(img image.Image, width, height int) *image.NRGBA {
+	srcW, srcH := img.Bounds().Dx(), img.Bounds().Dy()
+	dstW, dstH := resizeDimensions(srcW, srcH, width, height)
+	if dstW == 0 {
+		return &image.NRGBA{}
+	}
+
+	if srcW == dstW && srcH == dstH {
+		return Clone(img)
+	}
+
+	if srcW != dstW && srcH != dstH {
+		return resampleVertical(resampleHorizontal(img, areaWeights(dstW, srcW)), areaWeights(dstH, srcH))
+	}
+	if srcW != dstW {
+		return resampleHorizontal(img, areaWeights(dstW, srcW))
+	}
+	return resampleVertical(img, areaWeights(dstH, srcH))
+}
+
+// areaWeights returns the weights of the source pixels of every destination pixel,
+// the parts of the destination pixel covered by them.
+func areaWeights(dstSize, srcSize int) [][]indexWeight {
+	du := float64(srcSize) / float64(dstSize)
+	out := make([][]indexWeight, dstSize)
+	tmp := make([]indexWeight, 0, dstSize*(int(du)+2))
+	for v := 0; v < dstSize; v++ {
+		lo, hi := float64(v)*du, float64(v+1)*du
+		end := int(math.Ceil(hi))
+		if end > srcSize {
+			end = srcSize
+		}
+		for u := int(lo); u < end; u++ {
+			w := math.Min(hi, float64(u+1)) - math.Max(lo, float64(u))
+			if w > 0 {
+				tmp = append(tmp, indexWeight{index: u, weight: w / du})
+			}
+		}
+		out[v] = tmp
+		tmp = tmp[len(tmp):]
+	}
+	return out
 }
 
 func resizeHorizontal(img image.Image, width int, filter ResampleFilter) *image.NRGBA {
+	return resampleHorizontal(img, precomputeWeights(width, img.Bounds().Dx(), filter))
+}
+
+// resampleHorizontal resamples the rows of the image with the weights of every
+// destination column.
+func resampleHorizontal(img image.Image, weights [][]indexWeight) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, len(weights), img.Bounds().Dy()))
+	resampleHorizontalInto(dst, img, weights)
+	return dst
+}
+
+// resampleHorizontalInto is resampleHorizontal writing into dst, which has the width
+// of the weights and the height of the image.
+func resampleHorizontalInto(dst *image.NRGBA, img image.Image, weights [][]indexWeight) {
 	src := newScanner(img)
-	dst := image.NewNRGBA(image.Rect(0, 0, width, src.h))
-	weights := precomputeWeights(width, src.w, filter)
 	parallel(0, src.h, func(ys <-chan int) {
 		scanLine := make([]uint8, src.w*4)
 		for y := range ys {
@@ -125,25 +311,38 @@ func resizeHorizontal(img image.Image, width int, filter ResampleFilter) *image.
 					b += float64(s[2]) * aw
 					a += aw
 				}
-				if a != 0 {
-					aInv := 1 / a
-					j := j0 + x*4
-					d := dst.Pix[j : j+4 : j+4]
-					d[0] = clamp(r * aInv)
-					d[1] = clamp(g * aInv)
-					d[2] = clamp(b * aInv)
-					d[3] = clamp(a)
+				j := j0 + x*4
+				d := dst.Pix[j : j+4 : j+4]
+				if a == 0 {
+					d[0], d[1], d[2], d[3] = 0, 0, 0, 0
+					continue
 				}
+				aInv := 1 / a
+				d[0] = clamp(r * aInv)
+				d[1] = clamp(g * aInv)
+				d[2] = clamp(b * aInv)
+				d[3] = clamp(a)
 			}
 		}
 	})
-	return dst
 }
 
 func resizeVertical(img image.Image, height int, filter ResampleFilter) *image.NRGBA {
+	return resampleVertical(img, precomputeWeights(height, img.Bounds().Dy(), filter))
+}
+
+// resampleVertical resamples the columns of the image with the weights of every
+// destination row.
+func resampleVertical(img image.Image, weights [][]indexWeight) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, img.Bounds().Dx(), len(weights)))
+	resampleVerticalInto(dst, img, weights)
+	return dst
+}
+
+// resampleVerticalInto is resampleVertical writing into dst, which has the width
+// of the image and the height of the weights.
+func resampleVerticalInto(dst *image.NRGBA, img image.Image, weights [][]indexWeight) {
 	src := newScanner(img)
-	dst := image.NewNRGBA(image.Rect(0, 0, src.w, height))
-	weights := precomputeWeights(height, src.h, filter)
 	parallel(0, src.w, func(xs <-chan int) {
 		scanLine := make([]uint8, src.h*4)
 		for x := range xs {
@@ -159,24 +358,178 @@ func resizeVertical(img image.Image, height int, filter ResampleFilter) *image.N
 					b += float64(s[2]) * aw
 					a += aw
 				}
-				if a != 0 {
+				j := y*dst.Stride + x*4
+				d := dst.Pix[j : j+4 : j+4]
+				if a == 0 {
+					d[0], d[1], d[2], d[3] = 0, 0, 0, 0
+					continue
+				}
+				aInv := 1 / a
+				d[0] = clamp(r * aInv)
+				d[1] = clamp(g * aInv)
+				d[2] = clamp(b * aInv)
+				d[3] = clamp(a)
+			}
+		}
+	})
+}
+
+// ResizeEWA resizes the image like Resize, but resamples it with the elliptical
+// weighted average: the weights of the source pixels are those of the filter kernel at
+// their distances from the sampled point, instead of the products of the kernel values
+// along the rows and the columns. The kernel is stretched to an ellipse by the scales
+// of the width and the height when downscaling. Such cylindrical filtering has no
+// preferred directions, so diagonal edges and synthetic graphics are resampled without
+// the staircase and the ringing crosses of the separable filters, at a higher cost.
+// CatmullRom is a sharp and MitchellNetravali a smooth choice of the filter.
+//
+// Example:
+//
+//	dstImage := imaging.ResizeEWA(srcImage, 800, 0, imaging.CatmullRom)
+func ResizeEWA(img image.Image, width, height int, filter ResampleFilter) *image.NRGBA {
+	srcW, srcH := img.Bounds().Dx(), img.Bounds().Dy()
+	dstW, dstH := resizeDimensions(srcW, srcH, width, height)
+	if dstW == 0 {
+		return &image.NRGBA{}
+	}
+	if filter.Support <= 0 || filter.Kernel == nil {
+		return Resize(img, dstW, dstH, NearestNeighbor)
+	}
+
+	src := toNRGBA(img)
+	dst := image.NewNRGBA(image.Rect(0, 0, dstW, dstH))
+	du, dv := float64(srcW)/float64(dstW), float64(srcH)/float64(dstH)
+	su, sv := math.Max(du, 1), math.Max(dv, 1)
+	ru, rv := filter.Support*su, filter.Support*sv
+	parallel(0, dstH, func(ys <-chan int) {
+		for y := range ys {
+			fv := (float64(y)+0.5)*dv - 0.5
+			v0, v1 := int(math.Ceil(fv-rv)), int(math.Floor(fv+rv))
+			if v0 < 0 {
+				v0 = 0
+			}
+			if v1 > srcH-1 {
+				v1 = srcH - 1
+			}
+			for x := 0; x < dstW; x++ {
+				fu := (float64(x)+0.5)*du - 0.5
+				u0, u1 := int(math.Ceil(fu-ru)), int(math.Floor(fu+ru))
+				if u0 < 0 {
+					u0 = 0
+				}
+				if u1 > srcW-1 {
+					u1 = srcW - 1
+				}
+				var r, g, b, a, sum float64
+				for v := v0; v <= v1; v++ {
+					ty := (float64(v) - fv) / sv
+					row := src.Pix[v*src.Stride:]
+					for u := u0; u <= u1; u++ {
+						d := math.Hypot((float64(u)-fu)/su, ty)
+						if d >= filter.Support {
+							continue
+						}
+						w := filter.Kernel(d)
+						if w == 0 {
+							continue
+						}
+						s := row[u*4 : u*4+4 : u*4+4]
+						aw := float64(s[3]) * w
+						r += float64(s[0]) * aw
+						g += float64(s[1]) * aw
+						b += float64(s[2]) * aw
+						a += aw
+						sum += w
+					}
+				}
+				if a != 0 && sum != 0 {
 					aInv := 1 / a
-					j := y*dst.Stride + x*4
-					d := dst.Pix[j : j+4 : j+4]
+					d := dst.Pix[y*dst.Stride+x*4 : y*dst.Stride+x*4+4 : y*dst.Stride+x*4+4]
 					d[0] = clamp(r * aInv)
 					d[1] = clamp(g * aInv)
 					d[2] = clamp(b * aInv)
-					d[3] = clamp(a)
+					d[3] = clamp(a / sum)
+				}
+			}
+		}
+	})
+	return dst
+}
+
+// ResizeLinear resizes the image like Resize, but resamples it in linear light: the
+// sRGB colors are decoded to light intensities, resampled with 16 bits per channel,
+// and encoded back. Resampling the encoded values, as Resize does, darkens the
+// averaged details, so thin bright lines and text on a dark background fade and
+// alias when the image is scaled down; ResizeLinear keeps their brightness.
+//
+// Example:
+//
+//	thumb := imaging.ResizeLinear(srcImage, 200, 0, imaging.Lanczos)
+func ResizeLinear(img image.Image, width, height int, filter ResampleFilter) *image.NRGBA {
+	src := Clone64(img)
+	dec, enc := linearLUTs()
+	parallel(0, src.Rect.Dy(), func(ys <-chan int) {
+		for y := range ys {
+			row := src.Pix[y*src.Stride : y*src.Stride+src.Rect.Dx()*8]
+			for i := 0; i < len(row); i += 8 {
+				for c := 0; c < 6; c += 2 {
+					v := dec[uint16(row[i+c])<<8|uint16(row[i+c+1])]
+					row[i+c], row[i+c+1] = uint8(v>>8), uint8(v)
+				}
+			}
+		}
+	})
+
+	res := Resize64(src, width, height, filter)
+	dst := image.NewNRGBA(res.Rect)
+	parallel(0, res.Rect.Dy(), func(ys <-chan int) {
+		for y := range ys {
+			i, j := y*res.Stride, y*dst.Stride
+			for x := 0; x < res.Rect.Dx(); x++ {
+				s, d := res.Pix[i:i+8:i+8], dst.Pix[j:j+4:j+4]
+				for c := 0; c < 3; c++ {
+					d[c] = enc[uint16(s[2*c])<<8|uint16(s[2*c+1])]
 				}
+				d[3] = uint8((uint32(s[6])<<8 | uint32(s[7])) * 0xff / 0xffff)
+				i += 8
+				j += 4
 			}
 		}
 	})
 	return dst
 }
 
+var (
+	linearLUTsOnce sync.Once //nolint
+	linearDecode   []uint16  //nolint
+	linearEncode   []uint8   //nolint
+)
+
+// linearLUTs returns the lookup tables decoding 16-bit sRGB values to 16-bit linear
+// light and encoding 16-bit linear light to 8-bit sRGB values.
+func linearLUTs() ([]uint16, []uint8) {
+	linearLUTsOnce.Do(func() {
+		linearDecode = make([]uint16, 0x10000)
+		linearEncode = make([]uint8, 0x10000)
+		for i := range linearDecode {
+			x := float64(i) / 0xffff
+			linearDecode[i] = clamp16(srgbToLinear(x) * 0xffff)
+			linearEncode[i] = clamp(linearToSRGB(x) * 0xff)
+		}
+	})
+	return linearDecode, linearEncode
+}
+
 // resizeNearest is a fast nearest-neighbor resize, no filtering.
 func resizeNearest(img image.Image, width, height int) *image.NRGBA {
 	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	resizeNearestInto(dst, img)
+	return dst
+}
+
+// resizeNearestInto is resizeNearest writing into dst.
+func resizeNearestInto(dst *image.NRGBA, img image.Image) {
+	width, height := dst.Rect.Dx(), dst.Rect.Dy()
 	dx := float64(img.Bounds().Dx()) / float64(width)
 	dy := float64(img.Bounds().Dy()) / float64(height)
 
@@ -209,8 +562,6 @@ func resizeNearest(img image.Image, width, height int) *image.NRGBA {
 			}
 		})
 	}
-
-	return dst
 }
 
 // Fit scales down the image using the specified resample filter to fit the specified
@@ -253,6 +604,36 @@ func Fit(img image.Image, width, height int, filter ResampleFilter) *image.NRGBA
 	return Resize(img, newW, newH, filter)
 }
 
+// FitPad scales down the image using the specified resample filter to fit the specified
+// width and height, like Fit, and centers it on a canvas of exactly that size, e.g. to
+// letterbox the video thumbnails. The padding is filled with the background color. If
+// the color is AutoColor, it is inferred from the image borders, if it is BlurColor, the
+// padding shows a blurred copy of the image, and nil means transparent.
+//
+// Example:
+//
+//	dstImage := imaging.FitPad(srcImage, 320, 180, imaging.Lanczos, color.Black)
+func FitPad(img image.Image, width, height int, filter ResampleFilter, bg color.Color) *image.NRGBA {
+	fitted := Fit(img, width, height, filter)
+	if fitted.Rect.Empty() {
+		return &image.NRGBA{}
+	}
+
+	var canvas *image.NRGBA
+	switch bg.(type) {
+	case nil:
+		canvas = image.NewNRGBA(image.Rect(0, 0, width, height))
+	case blurBackground:
+		// Blurring a small copy is much faster and as smooth.
+		small := Fill(img, (width+15)/16, (height+15)/16, Center, Box)
+		canvas = Resize(Blur(small, 2), width, height, Linear)
+	default:
+		canvas = New(width, height, resolveColor(img, bg))
+	}
+	pos := image.Pt((width-fitted.Rect.Dx())/2, (height-fitted.Rect.Dy())/2)
+	return Overlay(canvas, fitted, pos, 1)
+}
+
 // Fill creates an image with the specified dimensions and fills it with the scaled source image.
 // To achieve the correct aspect ratio without stretching, the source image will be cropped.
 //
@@ -260,6 +641,35 @@ func Fit(img image.Image, width, height int, filter ResampleFilter) *image.NRGBA
 //
 //	dstImage := imaging.Fill(srcImage, 800, 600, imaging.Center, imaging.Lanczos)
 func Fill(img image.Image, width, height int, anchor Anchor, filter ResampleFilter) *image.NRGBA {
+	return fill(img, width, height, anchorCrop(anchor), filter)
+}
+
+// FillPoint is like Fill, but the source image is cropped around the focal point given
+// in coordinates normalized to the image size, as in CropPoint. The crop window is
+// centered on the focal point as close as the image edges allow.
+//
+// Example:
+//
+//	// Keep the face stored by the CMS at 30% of the width and 20% of the height.
+//	dstImage := imaging.FillPoint(srcImage, 800, 600, 0.3, 0.2, imaging.Lanczos)
+func FillPoint(img image.Image, width, height int, fx, fy float64, filter ResampleFilter) *image.NRGBA {
+	return fill(img, width, height, func(img image.Image, width, height int) *image.NRGBA {
+		return CropPoint(img, width, height, fx, fy)
+	}, filter)
+}
+
+// cropFunc crops the image to the specified size, choosing the region to keep.
+type cropFunc func(img image.Image, width, height int) *image.NRGBA
+
+// anchorCrop returns the cropFunc cutting out the region at the anchor point.
+func anchorCrop(anchor Anchor) cropFunc {
+	return func(img image.Image, width, height int) *image.NRGBA {
+		return CropAnchor(img, width, height, anchor)
+	}
+}
+
+// fill implements Fill and FillPoint, cropping the image with crop.
+func fill(img image.Image, width, height int, crop cropFunc, filter ResampleFilter) *image.NRGBA {
 	dstW, dstH := width, height
 
 	if dstW <= 0 || dstH <= 0 {
@@ -279,16 +689,22 @@ func Fill(img image.Image, width, height int, anchor Anchor, filter ResampleFilt
 	}
 
 	if srcW >= 100 && srcH >= 100 {
-		return cropAndResize(img, dstW, dstH, anchor, filter)
+		return cropAndResize(img, dstW, dstH, crop, filter)
 	}
-	return resizeAndCrop(img, dstW, dstH, anchor, filter)
+	return resizeAndCrop(img, dstW, dstH, crop, filter)
 }
 
 // cropAndResize crops the image to the smallest possible size that has the required aspect ratio using
-// the given anchor point, then scales it to the specified dimensions and returns the transformed image.
+// the given crop function, then scales it to the specified dimensions and returns the transformed image.
 //
 // This is generally faster than resizing first, but may result in inaccuracies when used on small source images.
-func cropAndResize(img image.Image, width, height int, anchor Anchor, filter ResampleFilter) *image.NRGBA {
+func cropAndResize(img image.Image, width, height int, crop cropFunc, filter ResampleFilter) *image.NRGBA {
+	return Resize(cropAspect(img, width, height, crop), width, height, filter)
+}
+
+// cropAspect crops the image to the largest region that has the aspect ratio of the
+// specified dimensions using the given crop function.
+func cropAspect(img image.Image, width, height int, crop cropFunc) *image.NRGBA {
 	dstW, dstH := width, height
 
 	srcBounds := img.Bounds()
@@ -300,19 +716,18 @@ func cropAndResize(img image.Image, width, height int, anchor Anchor, filter Res
 	var tmp *image.NRGBA
 	if srcAspectRatio < dstAspectRatio {
 		cropH := float64(srcW) * float64(dstH) / float64(dstW)
-		tmp = CropAnchor(img, srcW, int(math.Max(1, cropH)+0.5), anchor)
+		tmp = crop(img, srcW, int(math.Max(1, cropH)+0.5))
 	} else {
 		cropW := float64(srcH) * float64(dstW) / float64(dstH)
-		tmp = CropAnchor(img, int(math.Max(1, cropW)+0.5), srcH, anchor)
+		tmp = crop(img, int(math.Max(1, cropW)+0.5), srcH)
 	}
-
-	return Resize(tmp, dstW, dstH, filter)
+	return tmp
 }
 
 // resizeAndCrop resizes the image to the smallest possible size that will cover the specified dimensions,
-// crops the resized image to the specified dimensions using the given anchor point and returns
+// crops the resized image to the specified dimensions using the given crop function and returns
 // the transformed image.
-func resizeAndCrop(img image.Image, width, height int, anchor Anchor, filter ResampleFilter) *image.NRGBA {
+func resizeAndCrop(img image.Image, width, height int, crop cropFunc, filter ResampleFilter) *image.NRGBA {
 	dstW, dstH := width, height
 
 	srcBounds := img.Bounds()
@@ -328,7 +743,7 @@ func resizeAndCrop(img image.Image, width, height int, anchor Anchor, filter Res
 		tmp = Resize(img, 0, dstH, filter)
 	}
 
-	return CropAnchor(tmp, dstW, dstH, anchor)
+	return crop(tmp, dstW, dstH)
 }
 
 // Thumbnail scales the image up or down using the specified resample filter, crops it
@@ -341,6 +756,54 @@ func Thumbnail(img image.Image, width, height int, filter ResampleFilter) *image
 	return Fill(img, width, height, Center, filter)
 }
 
+// ThumbnailHQ is like Thumbnail, but large reductions are done in steps: the cropped
+// image is halved with area averaging while it is at least four times the specified
+// size, and the final pass from less than four times the size uses the specified
+// filter. For reductions of 20x and more this keeps the fine patterns from aliasing
+// and is faster than a single pass, as the filter of the final pass covers just a few
+// source pixels. Smaller reductions give the same result as Thumbnail.
+//
+// Example:
+//
+//	dstImage := imaging.ThumbnailHQ(srcImage, 160, 90, imaging.Lanczos)
+func ThumbnailHQ(img image.Image, width, height int, filter ResampleFilter) *image.NRGBA {
+	b := img.Bounds()
+	if width <= 0 || height <= 0 || b.Dx() < 4*width || b.Dy() < 4*height {
+		return Thumbnail(img, width, height, filter)
+	}
+	tmp := cropAspect(img, width, height, anchorCrop(Center))
+	for tmp.Rect.Dx() >= 4*width && tmp.Rect.Dy() >= 4*height {
+		tmp = ResizeArea(tmp, tmp.Rect.Dx()/2, tmp.Rect.Dy()/2)
+	}
+	return Resize(tmp, width, height, filter)
+}
+
+// ResizePhysical resizes the image to the physical size given in millimeters at the
+// specified resolution in dots per inch. The pixel dimensions are rounded to the nearest
+// integer, but a positive size is at least 1 pixel. If one of widthMM or heightMM is 0,
+// the image aspect ratio is preserved.
+//
+// Example:
+//
+//	// A 54x86 mm badge printed at 300 DPI (638x1016 pixels).
+//	dstImage := imaging.ResizePhysical(srcImage, 54, 86, 300, imaging.Lanczos)
+func ResizePhysical(img image.Image, widthMM, heightMM, dpi float64, filter ResampleFilter) *image.NRGBA {
+	if dpi <= 0 || widthMM < 0 || heightMM < 0 {
+		return &image.NRGBA{}
+	}
+	return Resize(img, mmToPixels(widthMM, dpi), mmToPixels(heightMM, dpi), filter)
+}
+
+// mmToPixels converts the length in millimeters to pixels at the resolution in dots per inch.
+// A positive length is at least 1 pixel, as 0 pixels means preserving the aspect ratio.
+func mmToPixels(mm, dpi float64) int {
+	px := int(math.Round(mm / 25.4 * dpi))
+	if px == 0 && mm > 0 {
+		return 1
+	}
+	return px
+}
+
 // ResampleFilter specifies a resampling filter to be used for image resizing.
 //
 //	General filter recommendations:
@@ -361,6 +824,9 @@ func Thumbnail(img image.Image, width, height int, filter ResampleFilter) *image
 //		Simple and fast averaging filter appropriate for downscaling.
 //		When upscaling it's similar to NearestNeighbor.
 //
+//	- MagicKernelSharp
+//		A sharp filter with much less ringing than Lanczos, also for synthetic graphics.
+//
 //	- NearestNeighbor
 //		Fastest resampling filter, no antialiasing.
 type ResampleFilter struct {
@@ -413,6 +879,198 @@ var Welch ResampleFilter
 // Cosine is a Cosine-windowed sinc filter (3 lobes).
 var Cosine ResampleFilter
 
+// MagicKernelSharp is the Magic Kernel Sharp 2013 filter, as sharp as Lanczos with
+// much weaker ringing, suited to both photos and synthetic graphics.
+var MagicKernelSharp ResampleFilter
+
+// WindowedSinc returns a Lanczos-windowed sinc filter of the given number of lobes,
+// the support radius in pixels. Lanczos is WindowedSinc(3); fewer lobes ring less
+// around the sharp edges, e.g. of synthetic graphics, more lobes keep more fine detail
+// of photos. The number of lobes is at least 1.
+func WindowedSinc(lobes int) ResampleFilter {
+	if lobes < 1 {
+		lobes = 1
+	}
+	n := float64(lobes)
+	return ResampleFilter{
+		Support: n,
+		Kernel: func(x float64) float64 {
+			x = math.Abs(x)
+			if x < n {
+				return sinc(x) * sinc(x/n)
+			}
+			return 0
+		},
+	}
+}
+
+var (
+	// ErrInvalidFilter means the support or the kernel passed to NewResampleFilter is invalid.
+	ErrInvalidFilter = errors.New("imaging: invalid resample filter")
+	// ErrUnknownFilter means there is no resample filter with the given name.
+	ErrUnknownFilter = errors.New("imaging: unknown resample filter")
+	// ErrFilterRegistered means the name passed to RegisterResampleFilter is already in use.
+	ErrFilterRegistered = errors.New("imaging: resample filter name already registered")
+)
+
+// NewResampleFilter returns a resample filter of the kernel, a function of the distance
+// from the center of the filter in source pixels (scaled up when downscaling), with the
+// given support radius. The kernel is only evaluated within the support and zero outside
+// of it, and the weights are normalized, so the kernel needn't sum to 1. The filter is
+// usable by Resize, Fit, Fill, Thumbnail and the other resizing functions, and can be
+// given a name with RegisterResampleFilter. ErrInvalidFilter is returned if the support
+// isn't positive and finite or the kernel is nil.
+//
+// Example:
+//
+//	// A triangle filter twice as wide as Linear.
+//	wide, err := imaging.NewResampleFilter(2, func(x float64) float64 {
+//		return 1 - math.Abs(x)/2
+//	})
+func NewResampleFilter(support float64, kernel func(x float64) float64) (ResampleFilter, error) {
+	if !(support > 0) || math.IsInf(support, 1) || kernel == nil {
+		return ResampleFilter{}, fmt.Errorf("%w: support %v", ErrInvalidFilter, support)
+	}
+	return ResampleFilter{
+		Support: support,
+		Kernel: func(x float64) float64 {
+			if math.Abs(x) > support {
+				return 0
+			}
+			return kernel(x)
+		},
+	}, nil
+}
+
+// resampleFilterNames lists the resample filters by their names, the predefined ones
+// first and then the ones registered with RegisterResampleFilter.
+var resampleFilterNames = []struct { //nolint
+	name   string
+	filter *ResampleFilter
+}{
+	{"nearestneighbor", &NearestNeighbor},
+	{"box", &Box},
+	{"linear", &Linear},
+	{"hermite", &Hermite},
+	{"mitchellnetravali", &MitchellNetravali},
+	{"catmullrom", &CatmullRom},
+	{"bspline", &BSpline},
+	{"gaussian", &Gaussian},
+	{"bartlett", &Bartlett},
+	{"lanczos", &Lanczos},
+	{"hann", &Hann},
+	{"hamming", &Hamming},
+	{"blackman", &Blackman},
+	{"welch", &Welch},
+	{"cosine", &Cosine},
+	{"magickernelsharp", &MagicKernelSharp},
+}
+
+// resampleFilterMu guards resampleFilterNames.
+var resampleFilterMu sync.RWMutex //nolint
+
+// RegisterResampleFilter registers the resample filter, e.g. made with NewResampleFilter,
+// with the name, so ResampleFilterByName and ResampleFilterNames (and so the filter
+// flag of gina) know it. The name is stored in lowercase and must not be in use by
+// another filter, otherwise ErrFilterRegistered is returned.
+//
+// RegisterResampleFilter is usually called from an init function.
+func RegisterResampleFilter(name string, filter ResampleFilter) error {
+	resampleFilterMu.Lock()
+	defer resampleFilterMu.Unlock()
+	name = strings.ToLower(name)
+	for _, f := range resampleFilterNames {
+		if f.name == name || name == "" {
+			return fmt.Errorf("%w: %q", ErrFilterRegistered, name)
+		}
+	}
+	resampleFilterNames = append(resampleFilterNames, struct {
+		name   string
+		filter *ResampleFilter
+	}{name, &filter})
+	return nil
+}
+
+// ResampleFilterByName returns the resample filter with the given name, predefined or
+// registered with RegisterResampleFilter, compared case-insensitively (e.g. "lanczos"
+// or "CatmullRom" for Lanczos and CatmullRom). For unknown names ErrUnknownFilter is
+// returned.
+func ResampleFilterByName(name string) (ResampleFilter, error) {
+	resampleFilterMu.RLock()
+	defer resampleFilterMu.RUnlock()
+	for _, f := range resampleFilterNames {
+		if strings.EqualFold(f.name, name) {
+			return *f.filter, nil
+		}
+	}
+	return ResampleFilter{}, fmt.Errorf("%w: %q", ErrUnknownFilter, name)
+}
+
+// ContentHint describes the content of an image for ChooseFilter.
+type ContentHint int
+
+// Content hints.
+const (
+	// ContentPhoto is for the photographs and other natural images.
+	ContentPhoto ContentHint = iota
+	// ContentGraphics is for the synthetic graphics with sharp edges, such as text,
+	// line art, diagrams and screenshots.
+	ContentGraphics
+	// ContentPixelArt is for the pixel art, the icons and the sprites, whose pixels
+	// must stay sharp squares.
+	ContentPixelArt
+)
+
+// ChooseFilter returns the recommended resample filter for resizing an image of the
+// content from the source size to the destination size. If one of the dimensions of
+// the destination size is 0, the aspect ratio is preserved, as in Resize. The filters
+// recommended are:
+//
+//   - Box for the reductions of more than 4x, where the filter covers many pixels
+//     anyway, so the simple average looks the same and is the fastest. The halving
+//     steps of ThumbnailHQ average the pixels the same way before the final pass.
+//   - Lanczos for the photos, and MitchellNetravali for enlarging them more than 2x,
+//     where the ringing of Lanczos becomes visible.
+//   - MagicKernelSharp for the graphics, sharp without ringing around the edges.
+//   - NearestNeighbor for enlarging the pixel art, and Box for reducing it.
+//
+// Example:
+//
+//	filter := imaging.ChooseFilter(img.Bounds().Size(), image.Pt(320, 0), imaging.ContentPhoto)
+//	dstImage := imaging.Resize(img, 320, 0, filter)
+func ChooseFilter(srcSize, dstSize image.Point, content ContentHint) ResampleFilter {
+	dstW, dstH := resizeDimensions(srcSize.X, srcSize.Y, dstSize.X, dstSize.Y)
+	if dstW == 0 {
+		return Lanczos
+	}
+	// reduction is the largest reduction of the two axes, below 1 for enlarging.
+	reduction := math.Max(float64(srcSize.X)/float64(dstW), float64(srcSize.Y)/float64(dstH))
+
+	switch {
+	case content == ContentPixelArt && reduction <= 1:
+		return NearestNeighbor
+	case content == ContentPixelArt || reduction > 4:
+		return Box
+	case content == ContentGraphics:
+		return MagicKernelSharp
+	case reduction < 0.5:
+		return MitchellNetravali
+	}
+	return Lanczos
+}
+
+// ResampleFilterNames returns the lowercase names of the resample filters accepted by
+// ResampleFilterByName, e.g. to list them in a user interface.
+func ResampleFilterNames() []string {
+	resampleFilterMu.RLock()
+	defer resampleFilterMu.RUnlock()
+	names := make([]string, len(resampleFilterNames))
+	for i, f := range resampleFilterNames {
+		names[i] = f.name
+	}
+	return names
+}
+
 func bcspline(x, b, c float64) float64 {
 	var y float64
 	x = math.Abs(x)
@@ -589,4 +1247,20 @@ func init() {
 			return 0
 		},
 	}
+
+	MagicKernelSharp = ResampleFilter{
+		Support: 2.5,
+		Kernel: func(x float64) float64 {
+			x = math.Abs(x)
+			switch {
+			case x <= 0.5:
+				return 17.0/16.0 - 7.0/4.0*x*x
+			case x <= 1.5:
+				return (1.0 - x) * (7.0/4.0 - x)
+			case x < 2.5:
+				return -(2.5 - x) * (2.5 - x) / 8.0
+			}
+			return 0
+		},
+	}
 }
@@ -1,8 +1,11 @@
 package imaging
 
 import (
+	"errors"
 	"fmt"
 	"image"
+	"image/color"
+	"math"
 	"path/filepath"
 	"testing"
 )
@@ -212,6 +215,87 @@ func TestResize(t *testing.T) {
 	}
 }
 
+func TestResizeLinear(t *testing.T) {
+	t.Parallel()
+
+	// A white line every 4 columns of black, averaged to 25% of light.
+	lines := New(8, 4, color.Black)
+	for y := 0; y < 4; y++ {
+		lines.SetNRGBA(0, y, color.NRGBA{0xff, 0xff, 0xff, 0xff})
+		lines.SetNRGBA(4, y, color.NRGBA{0xff, 0xff, 0xff, 0xff})
+	}
+	testCases := []struct {
+		name string
+		src  image.Image
+		w, h int
+		f    ResampleFilter
+		want *image.NRGBA
+	}{
+		{
+			"lines 8x4 2x1 box",
+			lines,
+			2, 1,
+			Box,
+			&image.NRGBA{
+				Rect:   image.Rect(0, 0, 2, 1),
+				Stride: 2 * 4,
+				Pix:    []uint8{0x89, 0x89, 0x89, 0xff, 0x89, 0x89, 0x89, 0xff},
+			},
+		},
+		{
+			"lines 8x4 4x2 nearest",
+			lines,
+			4, 0,
+			NearestNeighbor,
+			Resize(lines, 4, 0, NearestNeighbor),
+		},
+		{
+			"same size",
+			testdataFlowersSmallPNG,
+			testdataFlowersSmallPNG.Bounds().Dx(), 0,
+			Lanczos,
+			Clone(testdataFlowersSmallPNG),
+		},
+		{
+			"translucent 2x1 1x1 box",
+			&image.NRGBA{
+				Rect:   image.Rect(0, 0, 2, 1),
+				Stride: 2 * 4,
+				Pix:    []uint8{0xff, 0x00, 0x00, 0xff, 0xff, 0x00, 0x00, 0x80},
+			},
+			1, 1,
+			Box,
+			&image.NRGBA{
+				Rect:   image.Rect(0, 0, 1, 1),
+				Stride: 1 * 4,
+				Pix:    []uint8{0xff, 0x00, 0x00, 0xbf},
+			},
+		},
+		{
+			"negative size",
+			lines,
+			-1, 1,
+			Box,
+			&image.NRGBA{},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got := ResizeLinear(tc.src, tc.w, tc.h, tc.f)
+			if !compareNRGBA(got, tc.want, 0) {
+				t.Fatalf("got result %#v want %#v", got, tc.want)
+			}
+		})
+	}
+
+	// Resize averages the encoded values, making the lines darker.
+	if c := Resize(lines, 2, 1, Box).NRGBAAt(0, 0); c.R != 0x40 {
+		t.Fatalf("got color %v want 25%% gray", c)
+	}
+}
+
 func TestResampleFilters(t *testing.T) {
 	t.Parallel()
 
@@ -231,6 +315,9 @@ func TestResampleFilters(t *testing.T) {
 		Bartlett,
 		Welch,
 		Cosine,
+		MagicKernelSharp,
+		WindowedSinc(2),
+		WindowedSinc(0),
 	} {
 		t.Run("", func(t *testing.T) {
 			src := image.NewNRGBA(image.Rect(-1, -1, 2, 3))
@@ -248,6 +335,270 @@ func TestResampleFilters(t *testing.T) {
 	}
 }
 
+func TestWindowedSinc(t *testing.T) {
+	t.Parallel()
+
+	for _, x := range []float64{0, 0.3, 1, 1.5, 2.9, 3} {
+		if got, want := WindowedSinc(3).Kernel(x), Lanczos.Kernel(x); got != want {
+			t.Fatalf("got kernel value %f at %f want %f", got, x, want)
+		}
+	}
+	if f := WindowedSinc(-2); f.Support != 1 {
+		t.Fatalf("got support %f want 1", f.Support)
+	}
+}
+
+func TestResizeEWA(t *testing.T) {
+	t.Parallel()
+
+	row := image.NewNRGBA(image.Rect(0, 0, 9, 1))
+	for i := range row.Pix {
+		row.Pix[i] = uint8(i * 7)
+	}
+	testCases := []struct {
+		name string
+		src  image.Image
+		w, h int
+		f    ResampleFilter
+		want *image.NRGBA
+	}{
+		{"uniform down", New(40, 30, color.NRGBA{10, 100, 200, 255}), 7, 0, CatmullRom, New(7, 5, color.NRGBA{10, 100, 200, 255})},
+		{"uniform up", New(4, 3, color.NRGBA{10, 100, 200, 128}), 0, 11, MagicKernelSharp, New(15, 11, color.NRGBA{10, 100, 200, 128})},
+		{"single row", row, 4, 1, Linear, Resize(row, 4, 1, Linear)},
+		{"nearest", testdataFlowersSmallPNG, 30, 20, NearestNeighbor, Resize(testdataFlowersSmallPNG, 30, 20, NearestNeighbor)},
+		{"negative size", row, -1, 2, Linear, &image.NRGBA{}},
+		{"empty", &image.NRGBA{}, 2, 2, Linear, &image.NRGBA{}},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got := ResizeEWA(tc.src, tc.w, tc.h, tc.f)
+			if !compareNRGBA(got, tc.want, 1) {
+				t.Fatalf("got result %#v want %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestResizeXY(t *testing.T) {
+	t.Parallel()
+
+	src := AdjustFunc(makeNoiseNRGBA(30, 20, 1), func(c color.NRGBA) color.NRGBA {
+		c.A = 0xff
+		return c
+	})
+	testCases := []struct {
+		name   string
+		w, h   int
+		fx, fy ResampleFilter
+		want   *image.NRGBA
+	}{
+		{"same filters", 12, 9, CatmullRom, CatmullRom, Resize(src, 12, 9, CatmullRom)},
+		{"different filters", 12, 9, Lanczos, Linear, Resize(Resize(src, 12, 20, Lanczos), 12, 9, Linear)},
+		{"nearest horizontally", 50, 9, NearestNeighbor, Linear, Resize(Resize(src, 50, 20, NearestNeighbor), 50, 9, Linear)},
+		{"nearest vertically", 12, 41, Box, NearestNeighbor, Resize(Resize(src, 12, 20, Box), 12, 41, NearestNeighbor)},
+		{"nearest", 12, 9, NearestNeighbor, NearestNeighbor, Resize(src, 12, 9, NearestNeighbor)},
+		{"height only", 30, 7, Lanczos, Linear, Resize(src, 30, 7, Linear)},
+		{"aspect ratio", 15, 0, Lanczos, Linear, Resize(Resize(src, 15, 20, Lanczos), 15, 10, Linear)},
+		{"same size", 30, 20, Lanczos, Linear, src},
+		{"invalid size", -1, 9, Lanczos, Linear, &image.NRGBA{}},
+	}
+	for _, tc := range testCases {
+		if got := ResizeXY(src, tc.w, tc.h, tc.fx, tc.fy); !compareNRGBA(got, tc.want, 0) {
+			t.Fatalf("%s: got different result", tc.name)
+		}
+	}
+}
+
+func TestScale(t *testing.T) {
+	t.Parallel()
+
+	src := makeNoiseNRGBA(10, 7, 1)
+	testCases := []struct {
+		name   string
+		fx, fy float64
+		want   image.Rectangle
+	}{
+		{"half", 0.5, 0.5, image.Rect(0, 0, 5, 4)},
+		{"double", 2, 2, image.Rect(0, 0, 20, 14)},
+		{"per axis", 1.5, 1, image.Rect(0, 0, 15, 7)},
+		{"never zero", 0.01, 0.01, image.Rect(0, 0, 1, 1)},
+		{"zero", 0, 1, image.Rectangle{}},
+		{"negative", 1, -1, image.Rectangle{}},
+		{"NaN", math.NaN(), 1, image.Rectangle{}},
+		{"infinite", 1, math.Inf(1), image.Rectangle{}},
+	}
+	for _, tc := range testCases {
+		if got := Scale(src, tc.fx, tc.fy, Linear); got.Rect != tc.want {
+			t.Fatalf("%s: got bounds %v want %v", tc.name, got.Rect, tc.want)
+		}
+	}
+	if got, want := Scale(src, 2, 3, CatmullRom), Resize(src, 20, 21, CatmullRom); !compareNRGBA(got, want, 0) {
+		t.Fatalf("got different result than Resize")
+	}
+	if got := Scale(&image.NRGBA{}, 2, 2, Linear); !got.Rect.Empty() {
+		t.Fatalf("got bounds %v want empty", got.Rect)
+	}
+}
+
+func TestResizeInto(t *testing.T) {
+	t.Parallel()
+
+	src := makeNoiseNRGBA(40, 30, 1)
+	testCases := []struct {
+		name   string
+		w, h   int
+		filter ResampleFilter
+	}{
+		{"down", 17, 11, Lanczos},
+		{"up", 60, 45, CatmullRom},
+		{"width", 13, 30, Linear},
+		{"height", 40, 7, Box},
+		{"nearest", 23, 19, NearestNeighbor},
+		{"same size", 40, 30, Lanczos},
+	}
+	for _, tc := range testCases {
+		// The destination is a subimage of a dirty buffer, the pixels around it must stay.
+		buf := New(tc.w+2, tc.h+2, color.NRGBA{0x01, 0x02, 0x03, 0x04})
+		dst := buf.SubImage(image.Rect(1, 1, tc.w+1, tc.h+1)).(*image.NRGBA)
+		ResizeInto(dst, src, tc.filter)
+		if !compareNRGBA(Clone(dst), Resize(src, tc.w, tc.h, tc.filter), 0) {
+			t.Fatalf("%s: result differs from Resize", tc.name)
+		}
+		if c := buf.NRGBAAt(0, 0); c != (color.NRGBA{0x01, 0x02, 0x03, 0x04}) {
+			t.Fatalf("%s: got color %v outside of the destination", tc.name, c)
+		}
+	}
+
+	dst := New(3, 2, color.White)
+	ResizeInto(dst, &image.NRGBA{}, Lanczos)
+	if !compareNRGBA(dst, image.NewNRGBA(image.Rect(0, 0, 3, 2)), 0) {
+		t.Fatalf("got result %#v want transparent", dst)
+	}
+}
+
+func TestResizeArea(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		src  image.Image
+		w, h int
+		want *image.NRGBA
+	}{
+		{
+			"partial coverage",
+			&image.NRGBA{
+				Rect:   image.Rect(0, 0, 3, 1),
+				Stride: 3 * 4,
+				Pix:    []uint8{0, 0, 0, 0xff, 90, 90, 90, 0xff, 180, 180, 180, 0xff},
+			},
+			2, 1,
+			&image.NRGBA{
+				Rect:   image.Rect(0, 0, 2, 1),
+				Stride: 2 * 4,
+				Pix:    []uint8{30, 30, 30, 0xff, 150, 150, 150, 0xff},
+			},
+		},
+		{
+			"transparent",
+			&image.NRGBA{
+				Rect:   image.Rect(-1, -1, 1, 1),
+				Stride: 2 * 4,
+				Pix: []uint8{
+					0xff, 0x00, 0x00, 0xff, 0x00, 0xff, 0x00, 0x00,
+					0xff, 0x00, 0x00, 0xff, 0x00, 0xff, 0x00, 0x00,
+				},
+			},
+			1, 0,
+			&image.NRGBA{
+				Rect:   image.Rect(0, 0, 1, 1),
+				Stride: 1 * 4,
+				Pix:    []uint8{0xff, 0x00, 0x00, 0x80},
+			},
+		},
+		{
+			"negative size",
+			image.NewNRGBA(image.Rect(0, 0, 4, 4)),
+			-1, 2,
+			&image.NRGBA{},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got := ResizeArea(tc.src, tc.w, tc.h)
+			if !compareNRGBA(got, tc.want, 0) {
+				t.Fatalf("got result %#v want %#v", got, tc.want)
+			}
+		})
+	}
+
+	src := makeNoiseNRGBA(40, 30, 1)
+	for _, size := range []image.Point{{10, 15}, {40, 10}, {20, 30}} {
+		if got, want := ResizeArea(src, size.X, size.Y), Resize(src, size.X, size.Y, Box); !compareNRGBA(got, want, 0) {
+			t.Fatalf("%v: got different result than Box", size)
+		}
+	}
+	if got := ResizeArea(src, 7, 0); got.Rect != image.Rect(0, 0, 7, 5) {
+		t.Fatalf("got bounds %v want %v", got.Rect, image.Rect(0, 0, 7, 5))
+	}
+}
+
+func TestChooseFilter(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		src     image.Point
+		dst     image.Point
+		content ContentHint
+		want    ResampleFilter
+	}{
+		{"photo down", image.Pt(1000, 800), image.Pt(500, 400), ContentPhoto, Lanczos},
+		{"photo same size", image.Pt(100, 100), image.Pt(100, 100), ContentPhoto, Lanczos},
+		{"photo up", image.Pt(100, 100), image.Pt(150, 0), ContentPhoto, Lanczos},
+		{"photo up 4x", image.Pt(100, 100), image.Pt(400, 0), ContentPhoto, MitchellNetravali},
+		{"photo down 5x", image.Pt(1000, 800), image.Pt(200, 0), ContentPhoto, Box},
+		{"photo down 5x one axis", image.Pt(1000, 800), image.Pt(900, 160), ContentPhoto, Box},
+		{"graphics down", image.Pt(1000, 800), image.Pt(500, 0), ContentGraphics, MagicKernelSharp},
+		{"graphics down 8x", image.Pt(1000, 800), image.Pt(125, 0), ContentGraphics, Box},
+		{"graphics up", image.Pt(100, 100), image.Pt(300, 300), ContentGraphics, MagicKernelSharp},
+		{"pixel art up", image.Pt(16, 16), image.Pt(48, 0), ContentPixelArt, NearestNeighbor},
+		{"pixel art down", image.Pt(64, 64), image.Pt(48, 0), ContentPixelArt, Box},
+		{"empty", image.Pt(0, 0), image.Pt(10, 10), ContentPixelArt, Lanczos},
+	}
+	for _, tc := range testCases {
+		got := ChooseFilter(tc.src, tc.dst, tc.content)
+		if got.Support != tc.want.Support || (tc.want.Kernel != nil && got.Kernel(0.7) != tc.want.Kernel(0.7)) {
+			t.Fatalf("%s: got filter %+v want %+v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestResampleFilterByName(t *testing.T) {
+	t.Parallel()
+
+	names := ResampleFilterNames()
+	if len(names) != 16 || names[0] != "nearestneighbor" {
+		t.Fatalf("got filter names %v", names)
+	}
+	for _, name := range names {
+		if _, err := ResampleFilterByName(name); err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+	}
+	f, err := ResampleFilterByName("CatmullRom")
+	if err != nil || f.Support != CatmullRom.Support {
+		t.Fatalf("got filter %v, error %v", f, err)
+	}
+	if _, err := ResampleFilterByName("bicubic"); !errors.Is(err, ErrUnknownFilter) {
+		t.Fatalf("got error %v want %v", err, ErrUnknownFilter)
+	}
+}
+
 func TestResizeGolden(t *testing.T) {
 	t.Parallel()
 
@@ -387,6 +738,84 @@ func TestFit(t *testing.T) {
 	}
 }
 
+func TestFitPad(t *testing.T) {
+	t.Parallel()
+
+	red := color.NRGBA{0xff, 0x00, 0x00, 0xff}
+	black := color.NRGBA{0x00, 0x00, 0x00, 0xff}
+	testCases := []struct {
+		name string
+		src  image.Image
+		w, h int
+		bg   color.Color
+		want func(x, y int) color.NRGBA
+	}{
+		{
+			"letterbox",
+			New(8, 4, red),
+			4, 4, color.Black,
+			func(x, y int) color.NRGBA {
+				if y == 1 || y == 2 {
+					return red
+				}
+				return black
+			},
+		},
+		{
+			"pillarbox transparent",
+			New(2, 4, red),
+			4, 4, nil,
+			func(x, y int) color.NRGBA {
+				if x == 1 || x == 2 {
+					return red
+				}
+				return color.NRGBA{}
+			},
+		},
+		{
+			"not enlarged",
+			New(2, 2, red),
+			4, 4, AutoColor,
+			func(x, y int) color.NRGBA { return red },
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got := FitPad(tc.src, tc.w, tc.h, Linear, tc.bg)
+			if got.Rect != image.Rect(0, 0, tc.w, tc.h) {
+				t.Fatalf("got bounds %v want %dx%d", got.Rect, tc.w, tc.h)
+			}
+			for y := 0; y < tc.h; y++ {
+				for x := 0; x < tc.w; x++ {
+					if c := got.NRGBAAt(x, y); c != tc.want(x, y) {
+						t.Fatalf("got color %v at %d, %d want %v", c, x, y, tc.want(x, y))
+					}
+				}
+			}
+		})
+	}
+
+	src := AdjustFunc(makeNoiseNRGBA(100, 50, 1), func(c color.NRGBA) color.NRGBA {
+		c.A = 0xff
+		return c
+	})
+	got := FitPad(src, 60, 60, Linear, BlurColor)
+	if got.Rect != image.Rect(0, 0, 60, 60) {
+		t.Fatalf("got bounds %v want 60x60", got.Rect)
+	}
+	if !compareNRGBA(Crop(got, image.Rect(0, 15, 60, 45)), Fit(src, 60, 60, Linear), 0) {
+		t.Fatalf("the fitted image is not centered")
+	}
+	if c := got.NRGBAAt(30, 5); c.A != 0xff {
+		t.Fatalf("got blurred background %v want opaque", c)
+	}
+	if got := FitPad(src, 0, 60, Linear, BlurColor); !got.Rect.Empty() {
+		t.Fatalf("got bounds %v want empty", got.Rect)
+	}
+}
+
 func TestFitGolden(t *testing.T) {
 	t.Parallel()
 
@@ -492,6 +921,65 @@ func TestFill(t *testing.T) {
 	}
 }
 
+func TestFillPoint(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		src    *image.NRGBA
+		w, h   int
+		fx, fy float64
+		want   func(src *image.NRGBA) *image.NRGBA
+	}{
+		{
+			// Large images are cropped before resizing.
+			"crop and resize",
+			makeNoiseNRGBA(200, 100, 1),
+			50, 50, 0.1, 0.5,
+			func(src *image.NRGBA) *image.NRGBA {
+				return Resize(Crop(src, image.Rect(0, 0, 100, 100)), 50, 50, Box)
+			},
+		},
+		{
+			"crop and resize focal point",
+			makeNoiseNRGBA(200, 100, 1),
+			50, 50, 0.6, 0.5,
+			func(src *image.NRGBA) *image.NRGBA {
+				return Resize(Crop(src, image.Rect(70, 0, 170, 100)), 50, 50, Box)
+			},
+		},
+		{
+			// Small images are resized before cropping.
+			"resize and crop",
+			makeNoiseNRGBA(20, 40, 1),
+			10, 10, 0.5, 1,
+			func(src *image.NRGBA) *image.NRGBA {
+				return Crop(Resize(src, 10, 20, Box), image.Rect(0, 10, 10, 20))
+			},
+		},
+		{
+			"same size",
+			makeNoiseNRGBA(20, 40, 1),
+			20, 40, 0, 0,
+			func(src *image.NRGBA) *image.NRGBA { return src },
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got := FillPoint(tc.src, tc.w, tc.h, tc.fx, tc.fy, Box)
+			if !compareNRGBA(got, tc.want(tc.src), 0) {
+				t.Fatalf("got unexpected image")
+			}
+		})
+	}
+
+	if got := FillPoint(makeNoiseNRGBA(20, 40, 1), 0, 10, 0.5, 0.5, Box); !got.Bounds().Empty() {
+		t.Fatalf("got bounds %v want empty", got.Bounds())
+	}
+}
+
 func TestFillGolden(t *testing.T) {
 	t.Parallel()
 
@@ -636,7 +1124,7 @@ func TestResizeAndCrop(t *testing.T) {
 		tc := tc
 
 		t.Run(tc.name, func(t *testing.T) {
-			got := resizeAndCrop(tc.src, tc.w, tc.h, tc.a, tc.f)
+			got := resizeAndCrop(tc.src, tc.w, tc.h, anchorCrop(tc.a), tc.f)
 			if !compareNRGBA(got, tc.want, 0) {
 				t.Fatalf("got result %#v want %#v", got, tc.want)
 			}
@@ -767,7 +1255,7 @@ func TestCropAndResize(t *testing.T) {
 		tc := tc
 
 		t.Run(tc.name, func(t *testing.T) {
-			got := cropAndResize(tc.src, tc.w, tc.h, tc.a, tc.f)
+			got := cropAndResize(tc.src, tc.w, tc.h, anchorCrop(tc.a), tc.f)
 			if !compareNRGBA(got, tc.want, 0) {
 				t.Fatalf("got result %#v want %#v", got, tc.want)
 			}
@@ -860,6 +1348,40 @@ func TestThumbnail(t *testing.T) {
 	}
 }
 
+func TestThumbnailHQ(t *testing.T) {
+	t.Parallel()
+
+	checker := image.NewNRGBA(image.Rect(0, 0, 400, 300))
+	for y := 0; y < 300; y++ {
+		for x := 0; x < 400; x++ {
+			if (x+y)%2 == 0 {
+				checker.SetNRGBA(x, y, color.NRGBA{0xff, 0xff, 0xff, 0xff})
+			} else {
+				checker.SetNRGBA(x, y, color.NRGBA{0x00, 0x00, 0x00, 0xff})
+			}
+		}
+	}
+	got := ThumbnailHQ(checker, 20, 15, Lanczos)
+	if !compareNRGBA(got, New(20, 15, color.NRGBA{0x80, 0x80, 0x80, 0xff}), 1) {
+		t.Fatalf("got aliased result %#v want gray", got)
+	}
+	got = ThumbnailHQ(checker, 15, 15, CatmullRom)
+	if got.Rect != image.Rect(0, 0, 15, 15) {
+		t.Fatalf("got bounds %v want %v", got.Rect, image.Rect(0, 0, 15, 15))
+	}
+
+	src := makeNoiseNRGBA(30, 20, 1)
+	if !compareNRGBA(ThumbnailHQ(src, 10, 10, Lanczos), Thumbnail(src, 10, 10, Lanczos), 0) {
+		t.Fatalf("small reduction differs from Thumbnail")
+	}
+	if got := ThumbnailHQ(src, 0, 10, Lanczos); !compareNRGBA(got, &image.NRGBA{}, 0) {
+		t.Fatalf("got result %#v want empty", got)
+	}
+	if got := ThumbnailHQ(&image.NRGBA{}, 10, 10, Lanczos); !compareNRGBA(got, &image.NRGBA{}, 0) {
+		t.Fatalf("got result %#v want empty", got)
+	}
+}
+
 func BenchmarkResize(b *testing.B) {
 	for _, dir := range []string{"Down", "Up"} {
 		for _, filter := range []string{"NearestNeighbor", "Linear", "CatmullRom", "Lanczos"} {
@@ -903,6 +1425,41 @@ func BenchmarkResize(b *testing.B) {
 	}
 }
 
+func BenchmarkResizeInto(b *testing.B) {
+	dst := image.NewNRGBA(image.Rect(0, 0, 100, 100))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ResizeInto(dst, testdataBranchesJPG, Linear)
+	}
+}
+
+func BenchmarkResizeArea(b *testing.B) {
+	for _, format := range []string{"JPEG", "PNG"} {
+		var img image.Image
+		switch format {
+		case "JPEG":
+			img = testdataBranchesJPG
+		case "PNG":
+			img = testdataBranchesPNG
+		}
+
+		b.Run(format, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				ResizeArea(img, 100, 100)
+			}
+		})
+	}
+}
+
+func BenchmarkThumbnailHQ(b *testing.B) {
+	img := Resize(testdataBranchesJPG, 2000, 0, Linear)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ThumbnailHQ(img, 100, 100, Lanczos)
+	}
+}
+
 func BenchmarkFill(b *testing.B) {
 	for _, dir := range []string{"Vertical", "Horizontal"} {
 		for _, filter := range []string{"NearestNeighbor", "Linear", "CatmullRom", "Lanczos"} {
@@ -947,3 +1504,77 @@ func BenchmarkFill(b *testing.B) {
 		}
 	}
 }
+
+func TestResizePhysical(t *testing.T) {
+	t.Parallel()
+
+	src := image.NewNRGBA(image.Rect(0, 0, 200, 100))
+	testCases := []struct {
+		name                   string
+		widthMM, heightMM, dpi float64
+		want                   image.Rectangle
+	}{
+		{name: "badge at 300 dpi", widthMM: 54, heightMM: 86, dpi: 300, want: image.Rect(0, 0, 638, 1016)},
+		{name: "one inch at 72 dpi", widthMM: 25.4, heightMM: 12.7, dpi: 72, want: image.Rect(0, 0, 72, 36)},
+		{name: "preserve aspect ratio", widthMM: 25.4, heightMM: 0, dpi: 100, want: image.Rect(0, 0, 100, 50)},
+		{name: "small positive size", widthMM: 0.1, heightMM: 25.4, dpi: 72, want: image.Rect(0, 0, 1, 72)},
+		{name: "zero dpi", widthMM: 10, heightMM: 10, dpi: 0, want: image.Rectangle{}},
+		{name: "negative size", widthMM: -10, heightMM: 10, dpi: 300, want: image.Rectangle{}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got := ResizePhysical(src, tc.widthMM, tc.heightMM, tc.dpi, Box)
+			if got.Bounds() != tc.want {
+				t.Fatalf("got bounds %v want %v", got.Bounds(), tc.want)
+			}
+		})
+	}
+}
+
+func TestNewResampleFilter(t *testing.T) {
+	// Not parallel: RegisterResampleFilter changes the filters known by name.
+	saved := append(resampleFilterNames[:0:0], resampleFilterNames...)
+	t.Cleanup(func() { resampleFilterNames = saved })
+
+	triangle := func(x float64) float64 { return 1 - math.Abs(x) }
+	f, err := NewResampleFilter(1, triangle)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f.Kernel(0.5) != 0.5 || f.Kernel(-2) != 0 {
+		t.Fatalf("got kernel values %v, %v want 0.5, 0", f.Kernel(0.5), f.Kernel(-2))
+	}
+	src := makeNoiseNRGBA(40, 30, 1)
+	for _, size := range []image.Point{{13, 9}, {80, 61}} {
+		if got, want := Resize(src, size.X, size.Y, f), Resize(src, size.X, size.Y, Linear); !compareNRGBA(got, want, 0) {
+			t.Fatalf("%v: got different result than Linear", size)
+		}
+	}
+	for _, support := range []float64{0, -1, math.NaN(), math.Inf(1)} {
+		if _, err := NewResampleFilter(support, triangle); !errors.Is(err, ErrInvalidFilter) {
+			t.Fatalf("support %v: got error %v want %v", support, err, ErrInvalidFilter)
+		}
+	}
+	if _, err := NewResampleFilter(1, nil); !errors.Is(err, ErrInvalidFilter) {
+		t.Fatalf("got error %v want %v", err, ErrInvalidFilter)
+	}
+
+	if err := RegisterResampleFilter("Triangle", f); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if names := ResampleFilterNames(); names[len(names)-1] != "triangle" {
+		t.Fatalf("got filter names %v", names)
+	}
+	if got, err := ResampleFilterByName("TRIANGLE"); err != nil || got.Support != 1 {
+		t.Fatalf("got filter %v, error %v", got, err)
+	}
+	for _, name := range []string{"triangle", "Lanczos", ""} {
+		if err := RegisterResampleFilter(name, f); !errors.Is(err, ErrFilterRegistered) {
+			t.Fatalf("%q: got error %v want %v", name, err, ErrFilterRegistered)
+		}
+	}
+}
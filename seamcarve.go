@@ -0,0 +1,220 @@
+package imaging
+
+import (
+	"image"
+	"math"
+)
+
+// ResizeContentAware resizes the image to the specified width and height by seam
+// carving (liquid rescale): instead of scaling the whole image, it removes or duplicates
+// the connected paths of pixels ("seams") crossing the image where the content is the
+// least detailed, so that the objects and the edges are preserved when the aspect ratio
+// changes. A zero width or height keeps that dimension of the source image.
+//
+// The seams are recomputed after every removed seam, so ResizeContentAware is much
+// slower than Resize: the time grows with the number of changed rows and columns
+// times the image area. Scale large images down with Resize first, and change the
+// width or height by seam carving only by the difference of the aspect ratios.
+//
+// Example:
+//
+//	// Make a 4:3 photo square without squeezing the people in it.
+//	dstImage := imaging.ResizeContentAware(srcImage, 600, 600)
+func ResizeContentAware(img image.Image, width, height int) *image.NRGBA {
+	b := img.Bounds()
+	if width < 0 || height < 0 || b.Empty() {
+		return &image.NRGBA{}
+	}
+	if width == 0 {
+		width = b.Dx()
+	}
+	if height == 0 {
+		height = b.Dy()
+	}
+
+	src := Clone(img)
+	c := &carver{pix: src.Pix, w: src.Rect.Dx(), h: src.Rect.Dy()}
+	c.resize(width)
+	c.transpose()
+	c.resize(height)
+	c.transpose()
+	return &image.NRGBA{Pix: c.pix, Stride: c.w * 4, Rect: image.Rect(0, 0, c.w, c.h)}
+}
+
+// carver holds the pixels of an image carved by vertical seams: h rows of w NRGBA
+// pixels without padding. The horizontal seams are vertical seams of the transposed
+// image.
+type carver struct {
+	pix  []uint8
+	w, h int
+	// cols holds the source column of every pixel while the seams for insertion are
+	// searched, nil otherwise.
+	cols []int
+}
+
+// resize changes the width by removing or inserting vertical seams.
+func (c *carver) resize(width int) {
+	for c.w > width {
+		c.removeSeam(c.findSeam())
+	}
+	for c.w < width {
+		// Duplicating the same seam over and over would stretch it, so at most
+		// half of the columns are duplicated at once.
+		n := width - c.w
+		if n > c.w/2 {
+			n = c.w / 2
+		}
+		if n < 1 {
+			n = 1
+		}
+		c.insertSeams(n)
+	}
+}
+
+// energy returns the energy of every pixel, the sum of the absolute differences of its
+// luminance, weighted by the alpha, from the luminances of its four neighbors.
+func (c *carver) energy() []float64 {
+	w, h := c.w, c.h
+	lum := make([]float64, w*h)
+	for i := range lum {
+		p := c.pix[i*4 : i*4+4 : i*4+4]
+		lum[i] = (0.299*float64(p[0]) + 0.587*float64(p[1]) + 0.114*float64(p[2])) * float64(p[3]) / 255
+	}
+	e := make([]float64, w*h)
+	parallel(0, h, func(ys <-chan int) {
+		for y := range ys {
+			y1, y2 := y-1, y+1
+			if y1 < 0 {
+				y1 = 0
+			}
+			if y2 >= h {
+				y2 = h - 1
+			}
+			for x := 0; x < w; x++ {
+				x1, x2 := x-1, x+1
+				if x1 < 0 {
+					x1 = 0
+				}
+				if x2 >= w {
+					x2 = w - 1
+				}
+				l := lum[y*w+x]
+				e[y*w+x] = math.Abs(lum[y*w+x1]-l) + math.Abs(lum[y*w+x2]-l) +
+					math.Abs(lum[y1*w+x]-l) + math.Abs(lum[y2*w+x]-l)
+			}
+		}
+	})
+	return e
+}
+
+// findSeam returns the column of every row of the vertical seam with the lowest
+// total energy, found by dynamic programming.
+func (c *carver) findSeam() []int {
+	w, h := c.w, c.h
+	m := c.energy()
+	for y := 1; y < h; y++ {
+		prev, row := m[(y-1)*w:y*w], m[y*w:(y+1)*w]
+		for x := range row {
+			best := prev[x]
+			if x > 0 && prev[x-1] < best {
+				best = prev[x-1]
+			}
+			if x < w-1 && prev[x+1] < best {
+				best = prev[x+1]
+			}
+			row[x] += best
+		}
+	}
+
+	seam := make([]int, h)
+	last := m[(h-1)*w:]
+	for x := range last {
+		if last[x] < last[seam[h-1]] {
+			seam[h-1] = x
+		}
+	}
+	for y := h - 2; y >= 0; y-- {
+		prev, x := m[y*w:(y+1)*w], seam[y+1]
+		best := x
+		if x > 0 && prev[x-1] < prev[best] {
+			best = x - 1
+		}
+		if x < w-1 && prev[x+1] < prev[best] {
+			best = x + 1
+		}
+		seam[y] = best
+	}
+	return seam
+}
+
+// removeSeam removes the pixels of the seam, one from every row.
+func (c *carver) removeSeam(seam []int) {
+	w := c.w
+	pix := c.pix[:0]
+	cols := c.cols[:0]
+	for y, x := range seam {
+		row := c.pix[y*w*4 : (y+1)*w*4]
+		pix = append(pix, row[:x*4]...)
+		pix = append(pix, row[(x+1)*4:]...)
+		if c.cols != nil {
+			r := c.cols[y*w : (y+1)*w]
+			cols = append(cols, r[:x]...)
+			cols = append(cols, r[x+1:]...)
+		}
+	}
+	c.pix, c.w = pix, w-1
+	if c.cols != nil {
+		c.cols = cols
+	}
+}
+
+// insertSeams widens the image by n columns. The n lowest energy seams are found by
+// removing them from a copy of the image, then every pixel of them is duplicated as
+// the average of the pixel and its right neighbor.
+func (c *carver) insertSeams(n int) {
+	w, h := c.w, c.h
+	tmp := &carver{pix: append([]uint8(nil), c.pix...), w: w, h: h, cols: make([]int, w*h)}
+	for i := range tmp.cols {
+		tmp.cols[i] = i % w
+	}
+	marked := make([]bool, w*h)
+	for i := 0; i < n; i++ {
+		seam := tmp.findSeam()
+		for y, x := range seam {
+			marked[y*w+tmp.cols[y*tmp.w+x]] = true
+		}
+		tmp.removeSeam(seam)
+	}
+
+	pix := make([]uint8, 0, (w+n)*h*4)
+	for y := 0; y < h; y++ {
+		row := c.pix[y*w*4 : (y+1)*w*4]
+		for x := 0; x < w; x++ {
+			p := row[x*4 : x*4+4]
+			pix = append(pix, p...)
+			if !marked[y*w+x] {
+				continue
+			}
+			q := p
+			if x < w-1 {
+				q = row[(x+1)*4 : (x+2)*4]
+			}
+			for j := 0; j < 4; j++ {
+				pix = append(pix, uint8((int(p[j])+int(q[j])+1)/2))
+			}
+		}
+	}
+	c.pix, c.w = pix, w+n
+}
+
+// transpose swaps the rows and the columns of the image.
+func (c *carver) transpose() {
+	w, h := c.w, c.h
+	pix := make([]uint8, len(c.pix))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			copy(pix[(x*h+y)*4:(x*h+y)*4+4], c.pix[(y*w+x)*4:(y*w+x)*4+4])
+		}
+	}
+	c.pix, c.w, c.h = pix, h, w
+}
@@ -0,0 +1,78 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestResizeContentAware(t *testing.T) {
+	t.Parallel()
+
+	// object returns a white image with a 6x6 checkerboard at (10, 6).
+	object := func() *image.NRGBA {
+		img := New(40, 20, color.White)
+		for y := 6; y < 12; y++ {
+			for x := 10; x < 16; x++ {
+				if (x+y)%2 == 0 {
+					img.SetNRGBA(x, y, color.NRGBA{0, 0, 0, 255})
+				}
+			}
+		}
+		return img
+	}
+	want := Crop(object(), image.Rect(10, 6, 16, 12))
+
+	testCases := []struct {
+		name string
+		w, h int
+		want image.Rectangle
+	}{
+		{"shrink width", 30, 20, image.Rect(0, 0, 30, 20)},
+		{"shrink both", 25, 14, image.Rect(0, 0, 25, 14)},
+		{"enlarge width", 70, 0, image.Rect(0, 0, 70, 20)},
+		{"enlarge height", 0, 30, image.Rect(0, 0, 40, 30)},
+		{"shrink and enlarge", 30, 26, image.Rect(0, 0, 30, 26)},
+		{"same size", 0, 0, image.Rect(0, 0, 40, 20)},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got := ResizeContentAware(object(), tc.w, tc.h)
+			if got.Bounds() != tc.want {
+				t.Fatalf("got bounds %v want %v", got.Bounds(), tc.want)
+			}
+			// The seams avoid the checkerboard, which is kept intact.
+			var topLeft image.Point
+			black := 0
+			for y := got.Rect.Dy() - 1; y >= 0; y-- {
+				for x := got.Rect.Dx() - 1; x >= 0; x-- {
+					if got.NRGBAAt(x, y).R == 0 {
+						topLeft = image.Pt(x, y)
+						black++
+					}
+				}
+			}
+			if black != 18 || !compareNRGBA(Crop(got, image.Rectangle{topLeft, topLeft.Add(image.Pt(6, 6))}), want, 0) {
+				t.Fatalf("the checkerboard is changed")
+			}
+		})
+	}
+
+	src := object()
+	if got := ResizeContentAware(src, 0, 0); !compareNRGBA(got, src, 0) {
+		t.Fatalf("the image of the same size is changed")
+	}
+	for _, size := range [][2]int{{-1, 10}, {10, -1}} {
+		if got := ResizeContentAware(src, size[0], size[1]); !got.Bounds().Empty() {
+			t.Fatalf("got bounds %v want empty", got.Bounds())
+		}
+	}
+	if got := ResizeContentAware(&image.NRGBA{}, 10, 10); !got.Bounds().Empty() {
+		t.Fatalf("got bounds %v want empty", got.Bounds())
+	}
+	if got := ResizeContentAware(New(1, 1, color.White), 3, 2); got.Bounds() != image.Rect(0, 0, 3, 2) {
+		t.Fatalf("got bounds %v want 3x2", got.Bounds())
+	}
+}
@@ -0,0 +1,126 @@
+package imaging
+
+import (
+	"image"
+	"math"
+)
+
+// Region is a region of interest of an image, such as a face or an object found by
+// a detector.
+type Region struct {
+	// Bounds are the bounds of the region in the coordinates of the image.
+	Bounds image.Rectangle
+	// Score is the importance of the region, e.g. the confidence of the detector.
+	// The regions without a positive score are ignored.
+	Score float64
+}
+
+// RegionScorer finds the regions of interest of an image. It is the hook for plugging
+// an external face or object detector into SmartCrop.
+type RegionScorer interface {
+	ScoreRegions(img image.Image) ([]Region, error)
+}
+
+// RegionScorerFunc is an adapter to use an ordinary function as a RegionScorer.
+type RegionScorerFunc func(img image.Image) ([]Region, error)
+
+// ScoreRegions calls f(img).
+func (f RegionScorerFunc) ScoreRegions(img image.Image) ([]Region, error) {
+	return f(img)
+}
+
+// SmartCrop is like Fill, but the crop window is placed to keep the regions of
+// interest found by the scorer. The errors of the scorer are returned as is.
+//
+// Example:
+//
+//	faces := imaging.RegionScorerFunc(func(img image.Image) ([]imaging.Region, error) {
+//		return detector.DetectFaces(img) // e.g. an ONNX model
+//	})
+//	dstImage, err := imaging.SmartCrop(srcImage, 400, 400, faces, imaging.Lanczos)
+func SmartCrop(img image.Image, width, height int, scorer RegionScorer, filter ResampleFilter) (*image.NRGBA, error) {
+	regions, err := scorer.ScoreRegions(img)
+	if err != nil {
+		return nil, err
+	}
+	return FillRegions(img, width, height, regions, filter), nil
+}
+
+// FillRegions is like Fill, but the crop window is placed to keep the most of the
+// regions of interest, weighted by their scores. When several positions keep as much,
+// the window is centered on the regions as close as the image edges allow. Without
+// regions the image is cropped at the center.
+func FillRegions(img image.Image, width, height int, regions []Region, filter ResampleFilter) *image.NRGBA {
+	src := img.Bounds()
+	return fill(img, width, height, func(img image.Image, width, height int) *image.NRGBA {
+		// The image may be resized already, so the regions are scaled to it.
+		b := img.Bounds()
+		sx := float64(b.Dx()) / float64(src.Dx())
+		sy := float64(b.Dy()) / float64(src.Dy())
+		xs, ys := make([]regionSpan, 0, len(regions)), make([]regionSpan, 0, len(regions))
+		for _, r := range regions {
+			r.Bounds = r.Bounds.Canon().Intersect(src)
+			if r.Score <= 0 || r.Bounds.Empty() {
+				continue
+			}
+			xs = append(xs, regionSpan{
+				float64(r.Bounds.Min.X-src.Min.X) * sx, float64(r.Bounds.Max.X-src.Min.X) * sx, r.Score,
+			})
+			ys = append(ys, regionSpan{
+				float64(r.Bounds.Min.Y-src.Min.Y) * sy, float64(r.Bounds.Max.Y-src.Min.Y) * sy, r.Score,
+			})
+		}
+		pt := image.Pt(b.Min.X+regionOffset(b.Dx(), width, xs), b.Min.Y+regionOffset(b.Dy(), height, ys))
+		return Crop(img, image.Rect(0, 0, width, height).Add(pt).Intersect(b))
+	}, filter)
+}
+
+// regionSpan is the extent of a region along one side of the image.
+type regionSpan struct {
+	start, end, score float64
+}
+
+// regionOffset returns the offset of the window of the size along the image side
+// keeping the most of the spans, weighted by their scores. Ties are broken by the
+// distance of the window center from the weighted center of the spans. A window
+// larger than the side and a window without spans are centered.
+func regionOffset(side, size int, spans []regionSpan) int {
+	if size >= side || len(spans) == 0 {
+		return (side - size) / 2
+	}
+	var sum, weights float64
+	for _, s := range spans {
+		sum += s.score * (s.start + s.end) / 2
+		weights += s.score
+	}
+	center := sum / weights
+
+	// The kept part changes its slope only where the window edges meet the span
+	// edges, so the best offset is one of those, or the centered one.
+	candidates := []float64{center - float64(size)/2}
+	for _, s := range spans {
+		candidates = append(candidates, s.start, s.end-float64(size))
+	}
+	best, bestKept, bestDist := 0, -1.0, 0.0
+	for _, c := range candidates {
+		off := int(math.Round(c))
+		if off < 0 {
+			off = 0
+		}
+		if off > side-size {
+			off = side - size
+		}
+		var kept float64
+		lo, hi := float64(off), float64(off+size)
+		for _, s := range spans {
+			if overlap := math.Min(hi, s.end) - math.Max(lo, s.start); overlap > 0 {
+				kept += s.score * overlap / (s.end - s.start)
+			}
+		}
+		dist := math.Abs(float64(off) + float64(size)/2 - center)
+		if kept > bestKept+1e-9 || (math.Abs(kept-bestKept) <= 1e-9 && dist < bestDist) {
+			best, bestKept, bestDist = off, kept, dist
+		}
+	}
+	return best
+}
@@ -0,0 +1,96 @@
+package imaging
+
+import (
+	"errors"
+	"image"
+	"testing"
+)
+
+func TestFillRegions(t *testing.T) {
+	t.Parallel()
+
+	wide := makeNoiseNRGBA(300, 100, 1)
+	testCases := []struct {
+		name    string
+		src     *image.NRGBA
+		w, h    int
+		regions []Region
+		want    image.Rectangle
+	}{
+		{"no regions", wide, 100, 100, nil, image.Rect(100, 0, 200, 100)},
+		{"near the edge", wide, 100, 100, []Region{{image.Rect(20, 10, 60, 50), 1}}, image.Rect(0, 0, 100, 100)},
+		{"centered", wide, 100, 100, []Region{{image.Rect(200, 10, 240, 50), 0.9}}, image.Rect(170, 0, 270, 100)},
+		{
+			"both regions fit",
+			wide, 100, 100,
+			[]Region{{image.Rect(100, 0, 130, 30), 1}, {image.Rect(160, 0, 190, 30), 1}},
+			image.Rect(95, 0, 195, 100),
+		},
+		{
+			"higher score",
+			wide, 100, 100,
+			[]Region{{image.Rect(10, 0, 50, 30), 1}, {image.Rect(250, 0, 290, 30), 3}},
+			image.Rect(190, 0, 290, 100),
+		},
+		{
+			"ignored regions",
+			wide, 100, 100,
+			[]Region{{image.Rect(10, 0, 50, 30), 0}, {image.Rect(250, 0, 290, 30), -1}, {image.Rect(400, 0, 450, 30), 1}},
+			image.Rect(100, 0, 200, 100),
+		},
+		{"vertical", makeNoiseNRGBA(100, 300, 1), 100, 100, []Region{{image.Rect(0, 250, 10, 300), 1}}, image.Rect(0, 200, 100, 300)},
+		// Small images are resized before cropping.
+		{"resized", makeNoiseNRGBA(60, 20, 1), 20, 20, []Region{{image.Rect(45, 5, 55, 15), 1}}, image.Rect(40, 0, 60, 20)},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got := FillRegions(tc.src, tc.w, tc.h, tc.regions, Box)
+			want := Resize(Crop(tc.src, tc.want), tc.w, tc.h, Box)
+			if !compareNRGBA(got, want, 0) {
+				t.Fatalf("got image of bounds %v not cropped to %v", got.Bounds(), tc.want)
+			}
+		})
+	}
+
+	t.Run("bounds", func(t *testing.T) {
+		t.Parallel()
+		src := makeNoiseNRGBA(300, 100, 1)
+		src.Rect = src.Rect.Add(image.Pt(-50, 20))
+		got := FillRegions(src, 100, 100, []Region{{image.Rect(150, 30, 190, 70), 1}}, Box)
+		want := Crop(src, image.Rect(120, 20, 220, 120))
+		if !compareNRGBA(got, want, 0) {
+			t.Fatalf("got unexpected image")
+		}
+	})
+}
+
+func TestSmartCrop(t *testing.T) {
+	t.Parallel()
+
+	src := makeNoiseNRGBA(300, 100, 1)
+	var scored image.Image
+	scorer := RegionScorerFunc(func(img image.Image) ([]Region, error) {
+		scored = img
+		return []Region{{image.Rect(260, 0, 300, 40), 1}}, nil
+	})
+	got, err := SmartCrop(src, 50, 50, scorer, Box)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if scored != src {
+		t.Fatalf("the scorer got another image")
+	}
+	if want := Resize(Crop(src, image.Rect(200, 0, 300, 100)), 50, 50, Box); !compareNRGBA(got, want, 0) {
+		t.Fatalf("got unexpected image")
+	}
+
+	errDetect := errors.New("detector failed")
+	_, err = SmartCrop(src, 50, 50, RegionScorerFunc(func(image.Image) ([]Region, error) {
+		return nil, errDetect
+	}), Box)
+	if !errors.Is(err, errDetect) {
+		t.Fatalf("got error %v want %v", err, errDetect)
+	}
+}
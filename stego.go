@@ -0,0 +1,210 @@
+package imaging
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"hash/fnv"
+	"image"
+	"math"
+	"math/rand"
+)
+
+var (
+	// ErrWatermarkTooLarge means the payload does not fit in the image.
+	ErrWatermarkTooLarge = errors.New("imaging: watermark payload too large for the image")
+	// ErrNoWatermark means no watermark of the key is found in the image.
+	ErrNoWatermark = errors.New("imaging: no invisible watermark found")
+)
+
+const (
+	// stegoStep is the quantization step of the watermarked DCT coefficients. Larger
+	// steps survive stronger JPEG compression but are more visible.
+	stegoStep = 24
+	// stegoRepeat is the minimum number of blocks carrying every bit.
+	stegoRepeat = 3
+	// stegoHeaderBits is the size of the payload length header.
+	stegoHeaderBits = 16
+)
+
+// stegoCoefs are the DCT coefficients of the 8x8 luminance blocks carrying the bits.
+// They are of middle frequencies, quantized finely enough by JPEG encoders at the
+// usual qualities and not as visible as the low frequencies.
+var stegoCoefs = [2][2]int{{1, 2}, {2, 1}} //nolint
+
+// stegoBasis holds the orthonormal 8x8 DCT basis of the coefficients, indexed by y*8+x.
+var stegoBasis = func() (basis [len(stegoCoefs)][64]float64) { //nolint
+	for k, uv := range stegoCoefs {
+		for y := 0; y < 8; y++ {
+			for x := 0; x < 8; x++ {
+				basis[k][y*8+x] = 0.25 * math.Cos(float64(2*x+1)*float64(uv[0])*math.Pi/16) *
+					math.Cos(float64(2*y+1)*float64(uv[1])*math.Pi/16)
+			}
+		}
+	}
+	return basis
+}()
+
+// EmbedInvisibleWatermark hides the payload, e.g. an ID of the owner or the content
+// record, in the image. The key, shared by the embedding and the extraction, selects
+// the pixels carrying it, so the payload can't be read or found without the key.
+//
+// The payload is written in the luminance of the 8x8 pixel blocks of the JPEG grid,
+// by quantizing two middle frequency coefficients of their discrete cosine transforms,
+// changing the pixels by a few levels. Every bit is repeated in several blocks, so the
+// watermark survives mild JPEG recompression (quality 75 and better) and small edits,
+// but not resizing, rotation or cropping other than by multiples of 8 pixels from the
+// bottom and the right. A bit needs at least three of the 8x8 blocks, a quarter of the
+// blocks carry the payload length and the checksum takes 4 bytes, so a 512x512 image
+// carries up to 124 bytes; ErrWatermarkTooLarge is returned for larger payloads.
+// Shorter payloads are repeated more and are more robust.
+//
+// Example:
+//
+//	marked, err := imaging.EmbedInvisibleWatermark(img, []byte("asset:8f3e2a"), key)
+//	...
+//	payload, err := imaging.ExtractInvisibleWatermark(marked, key)
+func EmbedInvisibleWatermark(img image.Image, payload []byte, key []byte) (*image.NRGBA, error) {
+	dst := Clone(img)
+	data := make([]byte, len(payload)+crc32.Size)
+	copy(data, payload)
+	binary.BigEndian.PutUint32(data[len(payload):], crc32.ChecksumIEEE(payload))
+	header, body := newStegoLayout(dst.Rect.Dx(), dst.Rect.Dy(), key)
+	if len(payload) > 0xffff || len(header.blocks) < stegoHeaderBits*stegoRepeat ||
+		len(body.blocks) < len(data)*8*stegoRepeat {
+		return nil, ErrWatermarkTooLarge
+	}
+
+	var length [2]byte
+	binary.BigEndian.PutUint16(length[:], uint16(len(payload)))
+	header.embed(dst, length[:])
+	body.embed(dst, data)
+	return dst, nil
+}
+
+// ExtractInvisibleWatermark returns the payload hidden in the image by
+// EmbedInvisibleWatermark with the key. It returns ErrNoWatermark if the image has
+// no watermark of the key, or it is damaged too much to be read.
+func ExtractInvisibleWatermark(img image.Image, key []byte) ([]byte, error) {
+	src := toNRGBA(img)
+	header, body := newStegoLayout(src.Rect.Dx(), src.Rect.Dy(), key)
+	if len(header.blocks) < stegoHeaderBits*stegoRepeat {
+		return nil, ErrNoWatermark
+	}
+	n := int(binary.BigEndian.Uint16(header.extract(src, stegoHeaderBits/8)))
+	if len(body.blocks) < (n+crc32.Size)*8*stegoRepeat {
+		return nil, ErrNoWatermark
+	}
+	data := body.extract(src, n+crc32.Size)
+	payload := data[:n]
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(data[n:]) {
+		return nil, ErrNoWatermark
+	}
+	return payload, nil
+}
+
+// stegoLayout is the assignment of the bits of a message to the 8x8 blocks.
+// The block blocks[i] carries the bit i modulo the number of the bits.
+type stegoLayout struct {
+	blocks []image.Point
+	// dither holds the offsets of the quantization lattices of every block and
+	// coefficient, making them unpredictable without the key.
+	dither [][len(stegoCoefs)]float64
+}
+
+// newStegoLayout returns the layouts of the header and the body of the watermark of
+// the image size, shuffled by the key. A quarter of the blocks carry the header.
+func newStegoLayout(w, h int, key []byte) (header, body *stegoLayout) {
+	hash := fnv.New64a()
+	_, _ = hash.Write(key)
+	rnd := rand.New(rand.NewSource(int64(hash.Sum64()))) //nolint
+
+	bw, bh := w/8, h/8
+	header, body = &stegoLayout{}, &stegoLayout{}
+	for i, j := range rnd.Perm(bw * bh) {
+		l := body
+		if i%4 == 0 {
+			l = header
+		}
+		var d [len(stegoCoefs)]float64
+		for k := range d {
+			d[k] = rnd.Float64() * stegoStep
+		}
+		l.blocks = append(l.blocks, image.Pt(j%bw*8, j/bw*8))
+		l.dither = append(l.dither, d)
+	}
+	return header, body
+}
+
+// embed writes the message to the blocks of the image.
+func (l *stegoLayout) embed(img *image.NRGBA, msg []byte) {
+	bits := len(msg) * 8
+	parallel(0, len(l.blocks), func(is <-chan int) {
+		for i := range is {
+			bit := float64(msg[i%bits/8] >> (7 - i%bits%8) & 1)
+			var lum [64]float64
+			stegoLuminance(img, l.blocks[i], &lum)
+			var delta [64]float64
+			for k := range stegoCoefs {
+				c := stegoCoef(&lum, k)
+				offset := l.dither[i][k] + bit*stegoStep/2
+				q := math.Round((c-offset)/stegoStep)*stegoStep + offset
+				for j := range delta {
+					delta[j] += (q - c) * stegoBasis[k][j]
+				}
+			}
+			p := l.blocks[i]
+			for j, d := range delta {
+				o := img.PixOffset(p.X+j%8, p.Y+j/8)
+				for c := 0; c < 3; c++ {
+					img.Pix[o+c] = clamp(float64(img.Pix[o+c]) + d)
+				}
+			}
+		}
+	})
+}
+
+// extract reads a message of n bytes from the blocks of the image. Every bit is
+// decided by the sum of its votes, the closeness of the coefficients to the
+// quantization lattices of 0 (positive) or 1 (negative) in all of its blocks.
+func (l *stegoLayout) extract(img *image.NRGBA, n int) []byte {
+	bits := n * 8
+	votes := make([]float64, len(l.blocks))
+	parallel(0, len(l.blocks), func(is <-chan int) {
+		for i := range is {
+			var lum [64]float64
+			stegoLuminance(img, l.blocks[i], &lum)
+			for k := range stegoCoefs {
+				votes[i] += math.Cos(2 * math.Pi * (stegoCoef(&lum, k) - l.dither[i][k]) / stegoStep)
+			}
+		}
+	})
+	sums := make([]float64, bits)
+	for i, v := range votes {
+		sums[i%bits] += v
+	}
+	msg := make([]byte, n)
+	for i, s := range sums {
+		if s < 0 {
+			msg[i/8] |= 1 << (7 - i%8)
+		}
+	}
+	return msg
+}
+
+// stegoLuminance reads the luminance of the 8x8 block at the point.
+func stegoLuminance(img *image.NRGBA, p image.Point, lum *[64]float64) {
+	for j := range lum {
+		o := img.PixOffset(p.X+j%8, p.Y+j/8)
+		lum[j] = 0.299*float64(img.Pix[o]) + 0.587*float64(img.Pix[o+1]) + 0.114*float64(img.Pix[o+2])
+	}
+}
+
+// stegoCoef returns the DCT coefficient k of stegoCoefs of the block luminance.
+func stegoCoef(lum *[64]float64, k int) float64 {
+	var c float64
+	for j, v := range lum {
+		c += v * stegoBasis[k][j]
+	}
+	return c
+}
@@ -0,0 +1,67 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"testing"
+)
+
+func TestInvisibleWatermark(t *testing.T) {
+	t.Parallel()
+
+	key := []byte("secret")
+	payload := []byte("asset:42")
+	marked, err := EmbedInvisibleWatermark(testdataFlowersSmallPNG, payload, key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff, err := Compare(marked, testdataFlowersSmallPNG, 0); err != nil || diff.MaxDelta > 8 {
+		t.Fatalf("got max difference %d want at most 8", diff.MaxDelta)
+	}
+
+	testCases := []struct {
+		name    string
+		quality int
+	}{
+		{"lossless", 0},
+		{"JPEG 90", 90},
+		{"JPEG 75", 75},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var img image.Image = marked
+			if tc.quality > 0 {
+				buf := &bytes.Buffer{}
+				if err := Encode(buf, marked, JPEG, JPEGQuality(tc.quality)); err != nil {
+					t.Fatalf("failed to encode: %v", err)
+				}
+				var err error
+				if img, err = Decode(buf); err != nil {
+					t.Fatalf("failed to decode: %v", err)
+				}
+			}
+			got, err := ExtractInvisibleWatermark(img, key)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !bytes.Equal(got, payload) {
+				t.Fatalf("got payload %q want %q", got, payload)
+			}
+		})
+	}
+
+	for _, img := range []image.Image{marked, testdataFlowersSmallPNG} {
+		if _, err := ExtractInvisibleWatermark(img, []byte("wrong")); !errors.Is(err, ErrNoWatermark) {
+			t.Fatalf("got error %v want %v", err, ErrNoWatermark)
+		}
+	}
+	if _, err := ExtractInvisibleWatermark(testdataFlowersSmallPNG, key); !errors.Is(err, ErrNoWatermark) {
+		t.Fatalf("got error %v want %v", err, ErrNoWatermark)
+	}
+	if _, err := EmbedInvisibleWatermark(testdataFlowersSmallPNG, make([]byte, 1000), key); !errors.Is(err, ErrWatermarkTooLarge) {
+		t.Fatalf("got error %v want %v", err, ErrWatermarkTooLarge)
+	}
+}
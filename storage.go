@@ -0,0 +1,90 @@
+package imaging
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+)
+
+// ErrTemporary is wrapped by the storage errors that are likely to go away when the
+// operation is retried, such as throttling and server errors of object stores.
+var ErrTemporary = errors.New("imaging: temporary storage error")
+
+// IsTemporary reports whether the error is worth retrying: it wraps ErrTemporary or
+// is a network timeout.
+func IsTemporary(err error) bool {
+	if errors.Is(err, ErrTemporary) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// Storage reads and writes image files at locations other than the local file system,
+// such as object stores. Storage implementations are registered for a URL scheme with
+// RegisterStorage and receive the complete URL as the name, e.g. "s3://bucket/key.jpg".
+type Storage interface {
+	// Open opens the named file for reading.
+	Open(name string) (io.ReadCloser, error)
+	// Create creates or replaces the named file. The file must not be visible to
+	// readers before the writer is closed successfully.
+	Create(name string) (io.WriteCloser, error)
+}
+
+// storages holds the storages registered with RegisterStorage by their URL schemes.
+var storages = struct { //nolint
+	sync.RWMutex
+	schemes map[string]Storage
+}{
+	schemes: map[string]Storage{},
+}
+
+// RegisterStorage registers the storage for the URL scheme, so that Open, Save and
+// the other functions taking a filename read and write the names starting with
+// scheme + "://" through the storage. Registering a storage for a scheme that is
+// already registered replaces it, and a nil storage unregisters the scheme.
+//
+// Example:
+//
+//	imaging.RegisterStorage("s3", s3.New(s3.ConfigFromEnv()))
+//	img, err := imaging.Open("s3://photos/2023/beach.jpg")
+func RegisterStorage(scheme string, s Storage) {
+	scheme = strings.ToLower(scheme)
+	storages.Lock()
+	defer storages.Unlock()
+	if s == nil {
+		delete(storages.schemes, scheme)
+		return
+	}
+	storages.schemes[scheme] = s
+}
+
+// lookupStorage returns the storage registered for the URL scheme of the name.
+func lookupStorage(name string) (Storage, bool) {
+	scheme, _, ok := strings.Cut(name, "://")
+	if !ok {
+		return nil, false
+	}
+	storages.RLock()
+	defer storages.RUnlock()
+	s, ok := storages.schemes[strings.ToLower(scheme)]
+	return s, ok
+}
+
+// openFile opens the named file for reading from its storage or the file system.
+func openFile(name string) (io.ReadCloser, error) {
+	if s, ok := lookupStorage(name); ok {
+		return s.Open(name)
+	}
+	return fs.Open(name)
+}
+
+// createFile creates the named file in its storage or the file system.
+func createFile(name string) (io.WriteCloser, error) {
+	if s, ok := lookupStorage(name); ok {
+		return s.Create(name)
+	}
+	return fs.Create(name)
+}
@@ -0,0 +1,171 @@
+// Package archive implements imaging.Storage adapters reading the source images from
+// and writing the results into zip and tar archives, so that whole photo sets can be
+// processed from archive to archive without unpacking them to disk.
+//
+// The storages are registered for a scheme of their own, and the part of the name
+// after "scheme://" is the name of the entry in the archive.
+//
+// Example:
+//
+//	src, err := archive.OpenZip("photos.zip")
+//	...
+//	defer src.Close()
+//	dst, err := archive.CreateTar("thumbs.tar.gz")
+//	...
+//	imaging.RegisterStorage("src", src)
+//	imaging.RegisterStorage("dst", dst)
+//	for _, name := range src.Names() {
+//		err := pipeline.Process("src://"+name, "dst://"+name)
+//		...
+//	}
+//	err = dst.Close()
+package archive
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	iofs "io/fs"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrReadOnly means a file is created in an archive opened for reading.
+	ErrReadOnly = errors.New("archive: the archive is opened for reading")
+	// ErrWriteOnly means a file is opened in an archive created for writing.
+	ErrWriteOnly = errors.New("archive: the archive is created for writing")
+)
+
+// entryName returns the name of the archive entry for the storage name.
+func entryName(name string) string {
+	if _, rest, ok := strings.Cut(name, "://"); ok {
+		name = rest
+	}
+	return strings.TrimLeft(name, "/")
+}
+
+// Reader is an imaging.Storage opening the entries of an archive. It is safe for
+// concurrent use.
+type Reader struct {
+	// entries open the entries by their names.
+	entries map[string]func() (io.ReadCloser, error)
+	names   []string
+	closer  io.Closer
+}
+
+// newReader returns a reader of the entries, closing the closer (which may be nil)
+// when it is closed.
+func newReader(entries map[string]func() (io.ReadCloser, error), closer io.Closer) *Reader {
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return &Reader{entries: entries, names: names, closer: closer}
+}
+
+// Names returns the names of the files in the archive in sorted order.
+func (r *Reader) Names() []string {
+	return append([]string(nil), r.names...)
+}
+
+// Open opens the archive entry. Missing entries are reported with an error matching
+// fs.ErrNotExist.
+func (r *Reader) Open(name string) (io.ReadCloser, error) {
+	open, ok := r.entries[entryName(name)]
+	if !ok {
+		return nil, &iofs.PathError{Op: "open", Path: name, Err: iofs.ErrNotExist}
+	}
+	return open()
+}
+
+// Create returns ErrReadOnly.
+func (r *Reader) Create(name string) (io.WriteCloser, error) {
+	return nil, &iofs.PathError{Op: "create", Path: name, Err: ErrReadOnly}
+}
+
+// Close closes the archive file. The entries must not be read afterwards.
+func (r *Reader) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}
+
+// Writer is an imaging.Storage adding the created files to an archive. Each file is
+// buffered in memory and written to the archive when it is closed, so the files can
+// be created concurrently. The archive is complete once the Writer is closed.
+type Writer struct {
+	mu     sync.Mutex
+	add    func(name string, data []byte) error
+	finish func() error
+	closer io.Closer
+	closed bool
+}
+
+// Open returns ErrWriteOnly.
+func (w *Writer) Open(name string) (io.ReadCloser, error) {
+	return nil, &iofs.PathError{Op: "open", Path: name, Err: ErrWriteOnly}
+}
+
+// Create returns a writer for the archive entry.
+func (w *Writer) Create(name string) (io.WriteCloser, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil, &iofs.PathError{Op: "create", Path: name, Err: iofs.ErrClosed}
+	}
+	return &entryWriter{archive: w, name: name}, nil
+}
+
+// Close writes the end of the archive and closes the archive file, if any.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return iofs.ErrClosed
+	}
+	w.closed = true
+	err := w.finish()
+	if w.closer != nil {
+		if closeErr := w.closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// entryWriter is a file created in a Writer.
+type entryWriter struct {
+	data    bytes.Buffer
+	archive *Writer
+	name    string
+	closed  bool
+}
+
+func (e *entryWriter) Write(p []byte) (int, error) {
+	if e.closed {
+		return 0, &iofs.PathError{Op: "write", Path: e.name, Err: iofs.ErrClosed}
+	}
+	return e.data.Write(p)
+}
+
+// Close adds the entry to the archive.
+func (e *entryWriter) Close() error {
+	if e.closed {
+		return &iofs.PathError{Op: "close", Path: e.name, Err: iofs.ErrClosed}
+	}
+	e.closed = true
+	w := e.archive
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return &iofs.PathError{Op: "close", Path: e.name, Err: iofs.ErrClosed}
+	}
+	if err := w.add(entryName(e.name), e.data.Bytes()); err != nil {
+		return &iofs.PathError{Op: "close", Path: e.name, Err: err}
+	}
+	return nil
+}
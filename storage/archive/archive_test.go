@@ -0,0 +1,104 @@
+package archive
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	iofs "io/fs"
+	"testing"
+)
+
+func TestEntryName(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name, want string
+	}{
+		{"src://2023/beach.jpg", "2023/beach.jpg"},
+		{"src:///beach.jpg", "beach.jpg"},
+		{"beach.jpg", "beach.jpg"},
+	}
+	for _, tc := range testCases {
+		if got := entryName(tc.name); got != tc.want {
+			t.Fatalf("%s: got %q want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestWriter(t *testing.T) {
+	t.Parallel()
+
+	buf := &bytes.Buffer{}
+	w := NewZipWriter(buf)
+	if _, err := w.Open("dst://a.txt"); !errors.Is(err, ErrWriteOnly) {
+		t.Fatalf("got error %v want %v", err, ErrWriteOnly)
+	}
+	f, err := w.Create("dst://a.txt")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	io.WriteString(f, "hello") //nolint
+	if err := f.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := f.Close(); !errors.Is(err, iofs.ErrClosed) {
+		t.Fatalf("got error %v want %v", err, iofs.ErrClosed)
+	}
+	if _, err := io.WriteString(f, "more"); !errors.Is(err, iofs.ErrClosed) {
+		t.Fatalf("got error %v want %v", err, iofs.ErrClosed)
+	}
+
+	// A file still open when the archive is closed is not added.
+	pending, err := w.Create("dst://b.txt")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := w.Close(); !errors.Is(err, iofs.ErrClosed) {
+		t.Fatalf("got error %v want %v", err, iofs.ErrClosed)
+	}
+	if err := pending.Close(); !errors.Is(err, iofs.ErrClosed) {
+		t.Fatalf("got error %v want %v", err, iofs.ErrClosed)
+	}
+	if _, err := w.Create("dst://c.txt"); !errors.Is(err, iofs.ErrClosed) {
+		t.Fatalf("got error %v want %v", err, iofs.ErrClosed)
+	}
+
+	r, err := NewZipReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if names := r.Names(); len(names) != 1 || names[0] != "a.txt" {
+		t.Fatalf("got names %v want [a.txt]", names)
+	}
+}
+
+func TestReader(t *testing.T) {
+	t.Parallel()
+
+	r := newReader(map[string]func() (io.ReadCloser, error){
+		"b.txt": func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader([]byte("b"))), nil },
+		"a.txt": func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader([]byte("a"))), nil },
+	}, nil)
+	if names := r.Names(); len(names) != 2 || names[0] != "a.txt" || names[1] != "b.txt" {
+		t.Fatalf("got names %v want [a.txt b.txt]", names)
+	}
+	f, err := r.Open("src://b.txt")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if data, err := io.ReadAll(f); err != nil || string(data) != "b" {
+		t.Fatalf("got %q, %v want b", data, err)
+	}
+	if _, err := r.Open("src://c.txt"); !errors.Is(err, iofs.ErrNotExist) {
+		t.Fatalf("got error %v want %v", err, iofs.ErrNotExist)
+	}
+	if _, err := r.Create("src://c.txt"); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("got error %v want %v", err, ErrReadOnly)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
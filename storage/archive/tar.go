@@ -0,0 +1,163 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// OpenTar opens the tar file for reading. The entries of a plain tar file are read
+// from the file when opened. A gzip compressed file, named with the ".gz" or ".tgz"
+// extension, can't be read at random, so it is decompressed into memory at once.
+// The returned Reader must be closed.
+func OpenTar(name string) (*Reader, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if isGzip(name) {
+		defer f.Close()
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		return ReadTar(zr)
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r, err := NewTarReader(f, st.Size())
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r.closer = f
+	return r, nil
+}
+
+// NewTarReader returns a Reader of the tar archive read from r, which has the size.
+// Only the headers are read in advance, the entries are read from r when opened.
+func NewTarReader(r io.ReaderAt, size int64) (*Reader, error) {
+	counter := &countingReader{r: io.NewSectionReader(r, 0, size)}
+	tr := tar.NewReader(counter)
+	entries := map[string]func() (io.ReadCloser, error){}
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		// The data of the entry follows the header.
+		data := io.NewSectionReader(r, counter.n, h.Size)
+		entries[strings.TrimLeft(h.Name, "/")] = func() (io.ReadCloser, error) {
+			return io.NopCloser(io.NewSectionReader(data, 0, data.Size())), nil
+		}
+	}
+	return newReader(entries, nil), nil
+}
+
+// ReadTar reads the tar archive from r into memory and returns its Reader. Unlike
+// NewTarReader, it works with any reader, e.g. a decompressor or a network stream.
+func ReadTar(r io.Reader) (*Reader, error) {
+	tr := tar.NewReader(r)
+	entries := map[string]func() (io.ReadCloser, error){}
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		entries[strings.TrimLeft(h.Name, "/")] = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(data)), nil
+		}
+	}
+	return newReader(entries, nil), nil
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// CreateTar creates the tar file, compressed with gzip if it is named with the ".gz"
+// or ".tgz" extension. The returned Writer must be closed to complete it.
+func CreateTar(name string) (*Writer, error) {
+	f, err := os.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	if !isGzip(name) {
+		w := NewTarWriter(f)
+		w.closer = f
+		return w, nil
+	}
+	zw := gzip.NewWriter(f)
+	w := NewTarWriter(zw)
+	finish := w.finish
+	w.finish = func() error {
+		err := finish()
+		if closeErr := zw.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	}
+	w.closer = f
+	return w, nil
+}
+
+// NewTarWriter returns a Writer of a tar archive written to w. The entries are
+// streamed to w as they are closed.
+func NewTarWriter(w io.Writer) *Writer {
+	tw := tar.NewWriter(w)
+	return &Writer{
+		add: func(name string, data []byte) error {
+			err := tw.WriteHeader(&tar.Header{
+				Typeflag: tar.TypeReg,
+				Name:     name,
+				Mode:     0o644,
+				Size:     int64(len(data)),
+				ModTime:  time.Now(),
+			})
+			if err != nil {
+				return err
+			}
+			_, err = tw.Write(data)
+			return err
+		},
+		finish: tw.Close,
+	}
+}
+
+// isGzip reports whether the file name has a gzip extension.
+func isGzip(name string) bool {
+	name = strings.ToLower(name)
+	return strings.HasSuffix(name, ".gz") || strings.HasSuffix(name, ".tgz")
+}
@@ -0,0 +1,121 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"image/color"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-spectest/imaging"
+)
+
+func TestTar(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for _, name := range []string{"out.tar", "out.tar.gz", "out.TGZ"} {
+		name := filepath.Join(dir, name)
+		w, err := CreateTar(name)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, entry := range []string{"a.txt", "b/c.txt"} {
+			f, err := w.Create("dst://" + entry)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			io.WriteString(f, "data of "+entry) //nolint
+			if err := f.Close(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		r, err := OpenTar(name)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if names := r.Names(); len(names) != 2 || names[0] != "a.txt" || names[1] != "b/c.txt" {
+			t.Fatalf("%s: got names %v want [a.txt b/c.txt]", name, names)
+		}
+		for _, entry := range r.Names() {
+			f, err := r.Open("src://" + entry)
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", name, err)
+			}
+			data, err := io.ReadAll(f)
+			if err != nil || string(data) != "data of "+entry {
+				t.Fatalf("%s: got %q, %v want %q", name, data, err, "data of "+entry)
+			}
+		}
+		if err := r.Close(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
+
+func TestNewTarReader(t *testing.T) {
+	t.Parallel()
+
+	// Make an archive with a long name, which is stored in an extra header,
+	// and a directory, which is skipped.
+	long := "photos/" + string(bytes.Repeat([]byte("a"), 120)) + ".png"
+	img := imaging.New(3, 2, color.Black)
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: "photos/", Mode: 0o755}) //nolint
+	for _, name := range []string{long, "photos/b.png"} {
+		data := &bytes.Buffer{}
+		if err := imaging.Encode(data, img, imaging.PNG); err != nil {
+			t.Fatalf("failed to encode: %v", err)
+		}
+		tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: 0o644, Size: int64(data.Len())}) //nolint
+		tw.Write(data.Bytes())                                                                               //nolint
+	}
+	tw.Close() //nolint
+
+	compressed := &bytes.Buffer{}
+	zw := gzip.NewWriter(compressed)
+	zw.Write(buf.Bytes()) //nolint
+	zw.Close()            //nolint
+	zr, err := gzip.NewReader(compressed)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	indexed, err := NewTarReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	read, err := ReadTar(zr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, r := range []*Reader{indexed, read} {
+		if names := r.Names(); len(names) != 2 {
+			t.Fatalf("got names %v want the 2 files", names)
+		}
+		for _, name := range r.Names() {
+			f, err := r.Open(name)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := imaging.Decode(f)
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", name, err)
+			}
+			if got.Bounds() != img.Bounds() {
+				t.Fatalf("got bounds %v want %v", got.Bounds(), img.Bounds())
+			}
+		}
+	}
+
+	if _, err := NewTarReader(bytes.NewReader([]byte("bad data")), 8); err == nil {
+		t.Fatalf("expected error reading bad data")
+	}
+}
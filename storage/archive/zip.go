@@ -0,0 +1,69 @@
+package archive
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// OpenZip opens the zip file for reading. The returned Reader must be closed.
+func OpenZip(name string) (*Reader, error) {
+	f, err := zip.OpenReader(name)
+	if err != nil {
+		return nil, err
+	}
+	return newZipReader(&f.Reader, f), nil
+}
+
+// NewZipReader returns a Reader of the zip archive read from r, which has the size.
+func NewZipReader(r io.ReaderAt, size int64) (*Reader, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return newZipReader(zr, nil), nil
+}
+
+func newZipReader(zr *zip.Reader, closer io.Closer) *Reader {
+	entries := map[string]func() (io.ReadCloser, error){}
+	for _, f := range zr.File {
+		if !strings.HasSuffix(f.Name, "/") {
+			entries[strings.TrimLeft(f.Name, "/")] = f.Open
+		}
+	}
+	return newReader(entries, closer)
+}
+
+// CreateZip creates the zip file. The returned Writer must be closed to complete it.
+func CreateZip(name string) (*Writer, error) {
+	f, err := os.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	w := NewZipWriter(f)
+	w.closer = f
+	return w, nil
+}
+
+// NewZipWriter returns a Writer of a zip archive written to w. The entries are
+// stored uncompressed, as the image formats are compressed already.
+func NewZipWriter(w io.Writer) *Writer {
+	zw := zip.NewWriter(w)
+	return &Writer{
+		add: func(name string, data []byte) error {
+			f, err := zw.CreateHeader(&zip.FileHeader{
+				Name:     name,
+				Method:   zip.Store,
+				Modified: time.Now(),
+			})
+			if err != nil {
+				return err
+			}
+			_, err = f.Write(data)
+			return err
+		},
+		finish: zw.Close,
+	}
+}
@@ -0,0 +1,85 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-spectest/imaging"
+)
+
+func TestZip(t *testing.T) {
+	dir := t.TempDir()
+	srcName, dstName := filepath.Join(dir, "src.zip"), filepath.Join(dir, "dst.zip")
+
+	// Make the source archive with a directory entry, which is skipped.
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	zw.Create("photos/") //nolint
+	for _, name := range []string{"photos/a.png", "photos/b.png"} {
+		f, _ := zw.Create(name) //nolint
+		if err := imaging.Encode(f, imaging.New(8, 6, color.White), imaging.PNG); err != nil {
+			t.Fatalf("failed to encode: %v", err)
+		}
+	}
+	zw.Close() //nolint
+	if err := os.WriteFile(srcName, buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	src, err := OpenZip(srcName)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer src.Close()
+	dst, err := CreateZip(dstName)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	imaging.RegisterStorage("zipsrc", src)
+	imaging.RegisterStorage("zipdst", dst)
+	defer imaging.RegisterStorage("zipsrc", nil)
+	defer imaging.RegisterStorage("zipdst", nil)
+
+	if names := src.Names(); len(names) != 2 {
+		t.Fatalf("got names %v want the 2 files", names)
+	}
+	p := imaging.NewPipeline().Then("fit", func(img image.Image) image.Image {
+		return imaging.Fit(img, 4, 4, imaging.Box)
+	})
+	for _, name := range src.Names() {
+		if err := p.Process("zipsrc://"+name, "zipdst://thumbs/"+filepath.Base(name)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := dst.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	out, err := OpenZip(dstName)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer out.Close()
+	imaging.RegisterStorage("zipout", out)
+	defer imaging.RegisterStorage("zipout", nil)
+	img, err := imaging.Open("zipout://thumbs/b.png")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if img.Bounds() != image.Rect(0, 0, 4, 3) {
+		t.Fatalf("got bounds %v want 4x3", img.Bounds())
+	}
+}
+
+func TestNewZipReader(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewZipReader(bytes.NewReader([]byte("bad data")), 8); err == nil {
+		t.Fatalf("expected error reading bad data")
+	}
+}
@@ -0,0 +1,192 @@
+// Package azblob implements an imaging.Storage for Azure Blob Storage. It only depends on
+// the standard library: the requests are authorized with the account key (Shared Key) or
+// a shared access signature (SAS).
+//
+// Example:
+//
+//	imaging.RegisterStorage("azblob", azblob.New(azblob.ConfigFromEnv()))
+//	img, err := imaging.Open("azblob://photos/2023/beach.jpg")
+package azblob
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/go-spectest/imaging/storage/internal/httpstore"
+)
+
+// ErrInvalidName means the name is not an azblob://container/blob URL.
+var ErrInvalidName = errors.New("azblob: invalid name")
+
+// apiVersion is the version of the Blob service REST API used for the requests.
+const apiVersion = "2021-08-06"
+
+// Config is the configuration of the Azure Blob storage.
+type Config struct {
+	// Account is the name of the storage account.
+	Account string
+	// AccountKey is the base64 encoded key of the storage account.
+	AccountKey string
+	// SASToken is a shared access signature used instead of the account key, the query
+	// string with or without the leading "?". Without the account key and the signature
+	// the requests are sent anonymously, which works for public containers.
+	SASToken string
+	// Endpoint is the URL of the Blob service, e.g. "http://127.0.0.1:10000/devstoreaccount1"
+	// for Azurite. The default is "https://<account>.blob.core.windows.net".
+	Endpoint string
+	// Client is the HTTP client used for the requests. The default is http.DefaultClient.
+	Client *http.Client
+}
+
+// ConfigFromEnv returns the configuration from the environment variables used by the
+// Azure CLI: AZURE_STORAGE_CONNECTION_STRING, or AZURE_STORAGE_ACCOUNT with
+// AZURE_STORAGE_KEY or AZURE_STORAGE_SAS_TOKEN.
+func ConfigFromEnv() Config {
+	if s := os.Getenv("AZURE_STORAGE_CONNECTION_STRING"); s != "" {
+		return ParseConnectionString(s)
+	}
+	return Config{
+		Account:    os.Getenv("AZURE_STORAGE_ACCOUNT"),
+		AccountKey: os.Getenv("AZURE_STORAGE_KEY"),
+		SASToken:   os.Getenv("AZURE_STORAGE_SAS_TOKEN"),
+	}
+}
+
+// ParseConnectionString returns the configuration of a storage account connection
+// string, e.g. "DefaultEndpointsProtocol=https;AccountName=...;AccountKey=...".
+// "UseDevelopmentStorage=true" is the configuration of the Azurite emulator.
+func ParseConnectionString(s string) Config {
+	var c Config
+	protocol, suffix := "https", "core.windows.net"
+	for _, part := range strings.Split(s, ";") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch strings.ToLower(k) {
+		case "usedevelopmentstorage":
+			if strings.EqualFold(v, "true") {
+				return Config{
+					Account: "devstoreaccount1",
+					// The well-known key of the emulator.
+					AccountKey: "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw==",
+					Endpoint:   "http://127.0.0.1:10000/devstoreaccount1",
+				}
+			}
+		case "accountname":
+			c.Account = v
+		case "accountkey":
+			c.AccountKey = v
+		case "sharedaccesssignature":
+			c.SASToken = v
+		case "blobendpoint":
+			c.Endpoint = v
+		case "defaultendpointsprotocol":
+			protocol = v
+		case "endpointsuffix":
+			suffix = v
+		}
+	}
+	if c.Endpoint == "" && c.Account != "" {
+		c.Endpoint = protocol + "://" + c.Account + ".blob." + suffix
+	}
+	return c
+}
+
+// Storage is an imaging.Storage reading and writing azblob://container/blob names.
+type Storage struct {
+	config Config
+	// now returns the time the requests are signed at.
+	now func() time.Time
+}
+
+// New returns a new Azure Blob storage with the configuration.
+func New(config Config) *Storage {
+	if config.Endpoint == "" {
+		config.Endpoint = "https://" + config.Account + ".blob.core.windows.net"
+	}
+	config.Endpoint = strings.TrimSuffix(config.Endpoint, "/")
+	config.SASToken = strings.TrimPrefix(config.SASToken, "?")
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+	return &Storage{config: config, now: time.Now}
+}
+
+// Open opens the blob for reading. Missing blobs are reported with an error
+// matching os.ErrNotExist.
+func (s *Storage) Open(name string) (io.ReadCloser, error) {
+	req, err := s.newRequest(http.MethodGet, name, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.sign(req, 0); err != nil {
+		return nil, err
+	}
+	resp, err := s.config.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := httpstore.CheckResponse(resp, "azblob", name); err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Create returns a writer for the block blob. The data is buffered in memory and
+// uploaded when the writer is closed, so the blob is replaced at once.
+func (s *Storage) Create(name string) (io.WriteCloser, error) {
+	if _, _, err := splitName(name); err != nil {
+		return nil, err
+	}
+	return httpstore.NewWriter("azblob", name, func(data []byte) error {
+		req, err := s.newRequest(http.MethodPut, name, data)
+		if err != nil {
+			return err
+		}
+		t := mime.TypeByExtension(path.Ext(name))
+		if t == "" {
+			t = "application/octet-stream"
+		}
+		req.Header.Set("Content-Type", t)
+		req.Header.Set("X-Ms-Blob-Type", "BlockBlob")
+		if err := s.sign(req, len(data)); err != nil {
+			return err
+		}
+		return httpstore.Do(s.config.Client, req, "azblob", name)
+	}), nil
+}
+
+// newRequest returns a request for the blob. It must be signed once the headers are set.
+func (s *Storage) newRequest(method, name string, body []byte) (*http.Request, error) {
+	container, blob, err := splitName(name)
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(s.config.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("azblob: endpoint: %w", err)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + container + "/" + blob
+	u.RawPath = httpstore.Escape(u.Path, true)
+	if s.config.AccountKey == "" && s.config.SASToken != "" {
+		u.RawQuery = s.config.SASToken
+	}
+
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	return http.NewRequest(method, u.String(), r)
+}
+
+// splitName returns the container and the blob of an azblob://container/blob name.
+func splitName(name string) (container, blob string, err error) {
+	return httpstore.SplitName(name, "azblob", ErrInvalidName)
+}
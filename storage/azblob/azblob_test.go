@@ -0,0 +1,123 @@
+package azblob
+
+import (
+	"errors"
+	"image"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-spectest/imaging"
+)
+
+// fakeBlob is a Blob service keeping the blobs in memory.
+type fakeBlob struct {
+	mu    sync.Mutex
+	blobs map[string][]byte
+}
+
+func (f *fakeBlob) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "SharedKey devstoreaccount1:") && r.URL.Query().Get("sig") == "" {
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, "<?xml version=\"1.0\" encoding=\"utf-8\"?><Error><Code>AuthorizationFailure</Code><Message>\nThis request is not authorized.</Message></Error>") //nolint
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		if r.Header.Get("X-Ms-Blob-Type") != "BlockBlob" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		f.blobs[r.URL.Path] = data
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodGet:
+		data, ok := f.blobs[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(data) //nolint
+	}
+}
+
+func TestStorage(t *testing.T) {
+	server := &fakeBlob{blobs: map[string][]byte{}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	config := ParseConnectionString("UseDevelopmentStorage=true")
+	config.Endpoint = ts.URL + "/devstoreaccount1"
+	imaging.RegisterStorage("azblob", New(config))
+	defer imaging.RegisterStorage("azblob", nil)
+
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 3))
+	if err := imaging.Save(img, "azblob://photos/2023/a.png"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := server.blobs["/devstoreaccount1/photos/2023/a.png"]; !ok {
+		t.Fatalf("the blob is not uploaded")
+	}
+	got, err := imaging.Open("azblob://photos/2023/a.png")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Bounds() != img.Bounds() {
+		t.Fatalf("got bounds %v want %v", got.Bounds(), img.Bounds())
+	}
+	if _, err := imaging.Open("azblob://photos/missing.png"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("got error %v want %v", err, os.ErrNotExist)
+	}
+
+	imaging.RegisterStorage("azblob", New(Config{Account: "devstoreaccount1", SASToken: "?sv=2021-08-06&sig=abc", Endpoint: config.Endpoint}))
+	if _, err := imaging.Open("azblob://photos/2023/a.png"); err != nil {
+		t.Fatalf("unexpected error with the SAS token: %v", err)
+	}
+
+	imaging.RegisterStorage("azblob", New(Config{Account: "devstoreaccount1", Endpoint: config.Endpoint}))
+	_, err = imaging.Open("azblob://photos/2023/a.png")
+	if err == nil || !strings.Contains(err.Error(), "AuthorizationFailure: This request is not authorized.") {
+		t.Fatalf("got error %v want the authorization error", err)
+	}
+}
+
+func TestParseConnectionString(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		s    string
+		want Config
+	}{
+		{
+			"DefaultEndpointsProtocol=https;AccountName=acct;AccountKey=a2V5;EndpointSuffix=core.chinacloudapi.cn",
+			Config{Account: "acct", AccountKey: "a2V5", Endpoint: "https://acct.blob.core.chinacloudapi.cn"},
+		},
+		{
+			"BlobEndpoint=https://acct.blob.core.windows.net/;SharedAccessSignature=sv=2021-08-06&sig=abc",
+			Config{SASToken: "sv=2021-08-06&sig=abc", Endpoint: "https://acct.blob.core.windows.net/"},
+		},
+		{
+			"UseDevelopmentStorage=true",
+			Config{
+				Account:    "devstoreaccount1",
+				AccountKey: "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw==",
+				Endpoint:   "http://127.0.0.1:10000/devstoreaccount1",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		if got := ParseConnectionString(tc.s); got != tc.want {
+			t.Fatalf("ParseConnectionString(%q): got %+v want %+v", tc.s, got, tc.want)
+		}
+	}
+}
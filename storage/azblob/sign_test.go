@@ -0,0 +1,73 @@
+package azblob
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	t.Parallel()
+
+	s := New(Config{Account: "myaccount", AccountKey: "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="})
+	s.now = func() time.Time { return time.Date(2015, 6, 26, 23, 39, 12, 0, time.UTC) }
+
+	testCases := []struct {
+		name          string
+		method        string
+		url           string
+		headers       map[string]string
+		contentLength int
+		stringToSign  string
+		authorization string
+	}{
+		{
+			name:          "put",
+			method:        http.MethodPut,
+			url:           "https://myaccount.blob.core.windows.net/photos/2023/a%20b.jpg",
+			headers:       map[string]string{"Content-Type": "image/jpeg", "X-Ms-Blob-Type": "BlockBlob"},
+			contentLength: 5,
+			stringToSign: "PUT\n\n\n5\n\nimage/jpeg\n\n\n\n\n\n\n" +
+				"x-ms-blob-type:BlockBlob\nx-ms-date:Fri, 26 Jun 2015 23:39:12 GMT\nx-ms-version:2021-08-06\n" +
+				"/myaccount/photos/2023/a%20b.jpg",
+			authorization: "SharedKey myaccount:escIbm1ulN3H+CvevfIGRpVTt68xCTP2rTjewwrnxVo=",
+		},
+		{
+			name:   "get with query",
+			method: http.MethodGet,
+			url:    "https://myaccount.blob.core.windows.net/photos/a.jpg?restype=y&comp=metadata&RESTYPE=x",
+			stringToSign: "GET\n\n\n\n\n\n\n\n\n\n\n\n" +
+				"x-ms-date:Fri, 26 Jun 2015 23:39:12 GMT\nx-ms-version:2021-08-06\n" +
+				"/myaccount/photos/a.jpg\ncomp:metadata\nrestype:x,y",
+			authorization: "SharedKey myaccount:Ju3On00D6y4myOdCdqY8mUIRRkpCNkHc/TwkC0MuA4w=",
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			req, err := http.NewRequest(tc.method, tc.url, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			if err := s.sign(req, tc.contentLength); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := s.stringToSign(req, tc.contentLength); got != tc.stringToSign {
+				t.Fatalf("got string to sign %q want %q", got, tc.stringToSign)
+			}
+			if got := req.Header.Get("Authorization"); got != tc.authorization {
+				t.Fatalf("got authorization %q want %q", got, tc.authorization)
+			}
+		})
+	}
+
+	bad := New(Config{Account: "myaccount", AccountKey: "not base64"})
+	req, _ := http.NewRequest(http.MethodGet, "https://myaccount.blob.core.windows.net/photos/a.jpg", nil) //nolint
+	if err := bad.sign(req, 0); err == nil {
+		t.Fatalf("signed with an invalid key")
+	}
+}
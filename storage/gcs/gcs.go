@@ -0,0 +1,151 @@
+// Package gcs implements an imaging.Storage for Google Cloud Storage. It uses the XML API
+// of Cloud Storage with OAuth 2.0 access tokens and only depends on the standard library.
+// Buckets can also be accessed with HMAC keys through the s3 package, with the endpoint
+// https://storage.googleapis.com and the region "auto".
+//
+// Example:
+//
+//	imaging.RegisterStorage("gs", gcs.New(gcs.ConfigFromEnv()))
+//	img, err := imaging.Open("gs://photos/2023/beach.jpg")
+package gcs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/go-spectest/imaging/storage/internal/httpstore"
+)
+
+// ErrInvalidName means the name is not a gs://bucket/object URL.
+var ErrInvalidName = errors.New("gcs: invalid name")
+
+// Config is the configuration of the Cloud Storage storage.
+type Config struct {
+	// Endpoint is the URL of the Cloud Storage XML API, e.g. the URL of an emulator.
+	// The default is "https://storage.googleapis.com".
+	Endpoint string
+	// AccessToken is the OAuth 2.0 access token, e.g. printed by
+	// "gcloud auth print-access-token". Without a token the requests are sent
+	// unauthenticated, which works for public buckets.
+	AccessToken string
+	// TokenSource returns the access token for each request, so that expiring tokens
+	// can be refreshed, e.g. from a golang.org/x/oauth2 token source. It takes
+	// precedence over AccessToken.
+	TokenSource func() (string, error)
+	// Client is the HTTP client used for the requests. The default is http.DefaultClient.
+	Client *http.Client
+}
+
+// ConfigFromEnv returns the configuration from the environment variables: the access
+// token from GOOGLE_OAUTH_ACCESS_TOKEN and the endpoint of an emulator from
+// STORAGE_EMULATOR_HOST, as used by the Cloud Storage client libraries.
+func ConfigFromEnv() Config {
+	c := Config{
+		Endpoint:    os.Getenv("STORAGE_EMULATOR_HOST"),
+		AccessToken: os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"),
+	}
+	if c.Endpoint != "" && !strings.Contains(c.Endpoint, "://") {
+		c.Endpoint = "http://" + c.Endpoint
+	}
+	return c
+}
+
+// Storage is an imaging.Storage reading and writing gs://bucket/object names.
+type Storage struct {
+	config Config
+}
+
+// New returns a new Cloud Storage storage with the configuration.
+func New(config Config) *Storage {
+	if config.Endpoint == "" {
+		config.Endpoint = "https://storage.googleapis.com"
+	}
+	config.Endpoint = strings.TrimSuffix(config.Endpoint, "/")
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+	return &Storage{config: config}
+}
+
+// Open opens the object for reading. Missing objects are reported with an error
+// matching os.ErrNotExist.
+func (s *Storage) Open(name string) (io.ReadCloser, error) {
+	req, err := s.newRequest(http.MethodGet, name, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.config.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := httpstore.CheckResponse(resp, "gcs", name); err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Create returns a writer for the object. The data is buffered in memory and
+// uploaded when the writer is closed, so the object is replaced at once.
+func (s *Storage) Create(name string) (io.WriteCloser, error) {
+	if _, _, err := splitName(name); err != nil {
+		return nil, err
+	}
+	return httpstore.NewWriter("gcs", name, func(data []byte) error {
+		req, err := s.newRequest(http.MethodPut, name, data)
+		if err != nil {
+			return err
+		}
+		t := mime.TypeByExtension(path.Ext(name))
+		if t == "" {
+			t = "application/octet-stream"
+		}
+		req.Header.Set("Content-Type", t)
+		return httpstore.Do(s.config.Client, req, "gcs", name)
+	}), nil
+}
+
+// newRequest returns an authorized request for the object.
+func (s *Storage) newRequest(method, name string, body []byte) (*http.Request, error) {
+	bucket, object, err := splitName(name)
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(s.config.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("gcs: endpoint: %w", err)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + bucket + "/" + object
+	u.RawPath = httpstore.Escape(u.Path, true)
+
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, u.String(), r)
+	if err != nil {
+		return nil, err
+	}
+	token := s.config.AccessToken
+	if s.config.TokenSource != nil {
+		if token, err = s.config.TokenSource(); err != nil {
+			return nil, fmt.Errorf("gcs: access token: %w", err)
+		}
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req, nil
+}
+
+// splitName returns the bucket and the object of a gs://bucket/object name.
+func splitName(name string) (bucket, object string, err error) {
+	return httpstore.SplitName(name, "gs", ErrInvalidName)
+}
@@ -0,0 +1,104 @@
+package gcs
+
+import (
+	"errors"
+	"image"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-spectest/imaging"
+)
+
+// fakeGCS is a Cloud Storage XML API server keeping the objects in memory.
+type fakeGCS struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	types   map[string]string
+}
+
+func (f *fakeGCS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		io.WriteString(w, "<Error><Code>AuthenticationRequired</Code><Message>Authentication required.</Message></Error>") //nolint
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		f.objects[r.URL.EscapedPath()] = data
+		f.types[r.URL.EscapedPath()] = r.Header.Get("Content-Type")
+	case http.MethodGet:
+		data, ok := f.objects[r.URL.EscapedPath()]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(data) //nolint
+	}
+}
+
+func TestStorage(t *testing.T) {
+	server := &fakeGCS{objects: map[string][]byte{}, types: map[string]string{}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	imaging.RegisterStorage("gs", New(Config{Endpoint: ts.URL, AccessToken: "token"}))
+	defer imaging.RegisterStorage("gs", nil)
+
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 3))
+	if err := imaging.Save(img, "gs://bucket/2023/a+b.jpg"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := server.types["/bucket/2023/a%2Bb.jpg"]; got != "image/jpeg" {
+		t.Fatalf("got content type %q want image/jpeg", got)
+	}
+	got, err := imaging.Open("gs://bucket/2023/a+b.jpg")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Bounds() != img.Bounds() {
+		t.Fatalf("got bounds %v want %v", got.Bounds(), img.Bounds())
+	}
+	if _, err := imaging.Open("gs://bucket/missing.jpg"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("got error %v want %v", err, os.ErrNotExist)
+	}
+
+	calls := 0
+	imaging.RegisterStorage("gs", New(Config{Endpoint: ts.URL, AccessToken: "stale", TokenSource: func() (string, error) {
+		calls++
+		return "token", nil
+	}}))
+	if _, err := imaging.Open("gs://bucket/2023/a+b.jpg"); err != nil || calls != 1 {
+		t.Fatalf("got error %v and %d token source calls want 1", err, calls)
+	}
+
+	imaging.RegisterStorage("gs", New(Config{Endpoint: ts.URL}))
+	_, err = imaging.Open("gs://bucket/2023/a+b.jpg")
+	if err == nil || !strings.Contains(err.Error(), "AuthenticationRequired") {
+		t.Fatalf("got error %v want the authentication error", err)
+	}
+	if _, err := imaging.Open("gs://bucket"); !errors.Is(err, ErrInvalidName) {
+		t.Fatalf("got error %v want %v", err, ErrInvalidName)
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("STORAGE_EMULATOR_HOST", "localhost:4443")
+	t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "ya29.token")
+
+	got := ConfigFromEnv()
+	if got.Endpoint != "http://localhost:4443" || got.AccessToken != "ya29.token" {
+		t.Fatalf("got config %+v", got)
+	}
+}
@@ -0,0 +1,130 @@
+// Package httpstore has the parts shared by the storages of the object stores with
+// HTTP APIs: parsing the names, escaping the paths, reporting the errors and buffering
+// the uploads.
+package httpstore
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/go-spectest/imaging"
+)
+
+// SplitName returns the bucket (or container) and the key of a scheme://bucket/key
+// name. Names of other schemes and without a key are reported with errInvalid.
+func SplitName(name, scheme string, errInvalid error) (bucket, key string, err error) {
+	prefix := scheme + "://"
+	if len(name) < len(prefix) || !strings.EqualFold(name[:len(prefix)], prefix) {
+		return "", "", fmt.Errorf("%w: %q", errInvalid, name)
+	}
+	bucket, key, _ = strings.Cut(name[len(prefix):], "/")
+	if bucket == "" || key == "" {
+		return "", "", fmt.Errorf("%w: %q", errInvalid, name)
+	}
+	return bucket, key, nil
+}
+
+// Escape percent-encodes all the bytes of s except the unreserved characters,
+// and the slashes if keepSlash is true.
+func Escape(s string, keepSlash bool) string {
+	const hexDigits = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && keepSlash) {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hexDigits[c>>4])
+		b.WriteByte(hexDigits[c&15])
+	}
+	return b.String()
+}
+
+// CheckResponse returns an error for the non-2xx responses, closing their bodies.
+// The errors start with the prefix, missing objects match os.ErrNotExist and the
+// throttling and server errors match imaging.ErrTemporary.
+func CheckResponse(resp *http.Response, prefix, name string) error {
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return nil
+	}
+	defer resp.Body.Close() //nolint
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%s: %s: %w", prefix, name, os.ErrNotExist)
+	}
+	// S3, the XML API of GCS and Azure Blob report the errors as XML documents
+	// with the error code and message.
+	var e struct {
+		Code    string
+		Message string
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10)) //nolint
+	status := resp.Status
+	if xml.Unmarshal(data, &e) == nil && e.Code != "" {
+		status += ": " + e.Code + ": " + strings.TrimSpace(e.Message)
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		// Throttling and server errors are worth retrying.
+		return temporaryError(fmt.Sprintf("%s: %s: %s", prefix, name, status))
+	}
+	return fmt.Errorf("%s: %s: %s", prefix, name, status)
+}
+
+// temporaryError is an error worth retrying. It matches imaging.ErrTemporary, without
+// adding its text to the message.
+type temporaryError string
+
+func (e temporaryError) Error() string {
+	return string(e)
+}
+
+func (e temporaryError) Unwrap() error {
+	return imaging.ErrTemporary
+}
+
+// Do sends the request and checks the response, discarding its body.
+func Do(client *http.Client, req *http.Request, prefix, name string) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	if err := CheckResponse(resp, prefix, name); err != nil {
+		return err
+	}
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		resp.Body.Close() //nolint
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// Writer buffers an object in memory and uploads it when closed, so the object is
+// replaced at once.
+type Writer struct {
+	bytes.Buffer
+	prefix string
+	name   string
+	upload func(data []byte) error
+	closed bool
+}
+
+// NewWriter returns a writer of the named object uploaded by the function.
+func NewWriter(prefix, name string, upload func(data []byte) error) *Writer {
+	return &Writer{prefix: prefix, name: name, upload: upload}
+}
+
+// Close uploads the object.
+func (w *Writer) Close() error {
+	if w.closed {
+		return fmt.Errorf("%s: %s: already closed", w.prefix, w.name)
+	}
+	w.closed = true
+	return w.upload(w.Bytes())
+}
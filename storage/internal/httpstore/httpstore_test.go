@@ -0,0 +1,102 @@
+package httpstore
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/go-spectest/imaging"
+)
+
+func TestSplitName(t *testing.T) {
+	t.Parallel()
+
+	errInvalid := errors.New("invalid")
+	testCases := []struct {
+		name   string
+		bucket string
+		key    string
+		err    error
+	}{
+		{"s3://photos/2023/a.jpg", "photos", "2023/a.jpg", nil},
+		{"S3://photos/a.jpg", "photos", "a.jpg", nil},
+		{"s3://photos", "", "", errInvalid},
+		{"s3://photos/", "", "", errInvalid},
+		{"gs://photos/a.jpg", "", "", errInvalid},
+	}
+	for _, tc := range testCases {
+		bucket, key, err := SplitName(tc.name, "s3", errInvalid)
+		if bucket != tc.bucket || key != tc.key || !errors.Is(err, tc.err) {
+			t.Fatalf("SplitName(%q): got %q, %q, %v want %q, %q, %v", tc.name, bucket, key, err, tc.bucket, tc.key, tc.err)
+		}
+	}
+}
+
+func TestEscape(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		s         string
+		keepSlash bool
+		want      string
+	}{
+		{"photos/2023/beach.jpg", true, "photos/2023/beach.jpg"},
+		{"a b+c/ä~_-.", true, "a%20b%2Bc/%C3%A4~_-."},
+		{"a/b=c", false, "a%2Fb%3Dc"},
+	}
+	for _, tc := range testCases {
+		if got := Escape(tc.s, tc.keepSlash); got != tc.want {
+			t.Fatalf("Escape(%q, %v): got %q want %q", tc.s, tc.keepSlash, got, tc.want)
+		}
+	}
+}
+
+func TestCheckResponse(t *testing.T) {
+	t.Parallel()
+
+	response := func(status int, body string) *http.Response {
+		return &http.Response{StatusCode: status, Status: http.StatusText(status), Body: io.NopCloser(strings.NewReader(body))}
+	}
+	if err := CheckResponse(response(http.StatusOK, ""), "s3", "a.jpg"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := CheckResponse(response(http.StatusNotFound, ""), "s3", "a.jpg"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("got error %v want %v", err, os.ErrNotExist)
+	}
+	err := CheckResponse(response(http.StatusForbidden, "<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>"), "s3", "a.jpg")
+	if err == nil || err.Error() != "s3: a.jpg: Forbidden: AccessDenied: Access Denied" {
+		t.Fatalf("got error %v", err)
+	}
+	if imaging.IsTemporary(err) {
+		t.Fatalf("the error %v is temporary", err)
+	}
+	if err := CheckResponse(response(http.StatusBadGateway, "<html>"), "s3", "a.jpg"); err == nil || err.Error() != "s3: a.jpg: Bad Gateway" || !imaging.IsTemporary(err) {
+		t.Fatalf("got error %v", err)
+	}
+	if err := CheckResponse(response(http.StatusTooManyRequests, ""), "s3", "a.jpg"); !errors.Is(err, imaging.ErrTemporary) {
+		t.Fatalf("got error %v want %v", err, imaging.ErrTemporary)
+	}
+}
+
+func TestWriter(t *testing.T) {
+	t.Parallel()
+
+	var uploaded string
+	w := NewWriter("s3", "a.jpg", func(data []byte) error {
+		uploaded = string(data)
+		return nil
+	})
+	io.WriteString(w, "image") //nolint
+	if uploaded != "" {
+		t.Fatalf("uploaded before closing")
+	}
+	if err := w.Close(); err != nil || uploaded != "image" {
+		t.Fatalf("got %q, %v want the uploaded data", uploaded, err)
+	}
+	if err := w.Close(); err == nil {
+		t.Fatalf("closing twice succeeded")
+	}
+}
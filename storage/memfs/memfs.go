@@ -0,0 +1,194 @@
+// Package memfs implements an imaging.Storage keeping the files in memory, for tests and
+// for ephemeral pipelines whose intermediate images don't need to be persisted. The
+// failures of the file operations can be simulated with FS.Fail.
+//
+// Example:
+//
+//	mem := memfs.New()
+//	imaging.RegisterStorage("mem", mem)
+//	err := imaging.NewPipeline().Then("thumbnail", thumbnail).Process("photo.jpg", "mem://thumbs/photo.jpg")
+//	data, err := mem.ReadFile("mem://thumbs/photo.jpg")
+package memfs
+
+import (
+	"bytes"
+	"io"
+	iofs "io/fs"
+	"sort"
+	"sync"
+)
+
+// Op is an operation on a file.
+type Op int
+
+// File operations.
+const (
+	// OpOpen is opening a file with FS.Open.
+	OpOpen Op = iota
+	// OpRead is reading an opened file.
+	OpRead
+	// OpCreate is creating a file with FS.Create.
+	OpCreate
+	// OpWrite is writing a created file.
+	OpWrite
+	// OpClose is closing an opened or created file.
+	OpClose
+)
+
+// opNames are the names of the operations used in the errors.
+var opNames = [...]string{"open", "read", "create", "write", "close"}
+
+// fault is a simulated failure of an operation on a file.
+type fault struct {
+	name string
+	op   Op
+}
+
+// FS is an imaging.Storage keeping the files in memory by their names. It is safe for
+// concurrent use. The files are written when they are closed, so readers never see
+// partially written files.
+type FS struct {
+	mu     sync.RWMutex
+	files  map[string][]byte
+	faults map[fault]error
+}
+
+// New returns a new empty FS.
+func New() *FS {
+	return &FS{files: map[string][]byte{}, faults: map[fault]error{}}
+}
+
+// Fail makes the operation on the named file fail with the error, until Fail is called
+// again with a nil error. It simulates the failures of the real storages in tests.
+func (f *FS) Fail(name string, op Op, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err == nil {
+		delete(f.faults, fault{name, op})
+		return
+	}
+	f.faults[fault{name, op}] = err
+}
+
+// failure returns the error of the operation on the named file, wrapped in *fs.PathError.
+func (f *FS) failure(name string, op Op) error {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if err, ok := f.faults[fault{name, op}]; ok {
+		return &iofs.PathError{Op: opNames[op], Path: name, Err: err}
+	}
+	return nil
+}
+
+// Open opens the named file for reading. Missing files are reported with an error
+// matching fs.ErrNotExist.
+func (f *FS) Open(name string) (io.ReadCloser, error) {
+	if err := f.failure(name, OpOpen); err != nil {
+		return nil, err
+	}
+	data, err := f.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return &reader{data: bytes.NewReader(data), fs: f, name: name}, nil
+}
+
+// Create creates or replaces the named file. The file is stored when the writer is
+// closed successfully.
+func (f *FS) Create(name string) (io.WriteCloser, error) {
+	if err := f.failure(name, OpCreate); err != nil {
+		return nil, err
+	}
+	return &writer{fs: f, name: name}, nil
+}
+
+// ReadFile returns the content of the named file.
+func (f *FS) ReadFile(name string) ([]byte, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	data, ok := f.files[name]
+	if !ok {
+		return nil, &iofs.PathError{Op: "open", Path: name, Err: iofs.ErrNotExist}
+	}
+	return append([]byte(nil), data...), nil
+}
+
+// WriteFile creates or replaces the named file with the data.
+func (f *FS) WriteFile(name string, data []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.files[name] = append([]byte(nil), data...)
+}
+
+// Remove removes the named file.
+func (f *FS) Remove(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.files[name]; !ok {
+		return &iofs.PathError{Op: "remove", Path: name, Err: iofs.ErrNotExist}
+	}
+	delete(f.files, name)
+	return nil
+}
+
+// Names returns the sorted names of the files.
+func (f *FS) Names() []string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	names := make([]string, 0, len(f.files))
+	for name := range f.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// reader is a file opened for reading. The data is not embedded, so that io.Copy
+// doesn't bypass the simulated failures of Read.
+type reader struct {
+	data *bytes.Reader
+	fs   *FS
+	name string
+}
+
+// Read reads the file, unless the reading fails.
+func (r *reader) Read(p []byte) (int, error) {
+	if err := r.fs.failure(r.name, OpRead); err != nil {
+		return 0, err
+	}
+	return r.data.Read(p)
+}
+
+// Close closes the file.
+func (r *reader) Close() error {
+	return r.fs.failure(r.name, OpClose)
+}
+
+// writer is a created file.
+type writer struct {
+	data   bytes.Buffer
+	fs     *FS
+	name   string
+	closed bool
+}
+
+// Write writes to the file, unless the writing fails.
+func (w *writer) Write(p []byte) (int, error) {
+	if err := w.fs.failure(w.name, OpWrite); err != nil {
+		return 0, err
+	}
+	return w.data.Write(p)
+}
+
+// Close stores the file, unless the closing fails.
+func (w *writer) Close() error {
+	if w.closed {
+		return &iofs.PathError{Op: "close", Path: w.name, Err: iofs.ErrClosed}
+	}
+	w.closed = true
+	if err := w.fs.failure(w.name, OpClose); err != nil {
+		return err
+	}
+	w.fs.WriteFile(w.name, w.data.Bytes())
+	return nil
+}
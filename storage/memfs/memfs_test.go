@@ -0,0 +1,149 @@
+package memfs
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	iofs "io/fs"
+	"sync"
+	"testing"
+
+	"github.com/go-spectest/imaging"
+)
+
+func TestFS(t *testing.T) {
+	t.Parallel()
+
+	f := New()
+	w, err := f.Create("a.txt")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	io.WriteString(w, "hello") //nolint
+	if _, err := f.ReadFile("a.txt"); !errors.Is(err, iofs.ErrNotExist) {
+		t.Fatalf("the file is visible before it is closed")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := w.Close(); !errors.Is(err, iofs.ErrClosed) {
+		t.Fatalf("got error %v want %v", err, iofs.ErrClosed)
+	}
+
+	r, err := f.Open("a.txt")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil || string(data) != "hello" {
+		t.Fatalf("got %q, %v want hello", data, err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	f.WriteFile("b.txt", []byte("world"))
+	if got := f.Names(); fmt.Sprint(got) != "[a.txt b.txt]" {
+		t.Fatalf("got names %v", got)
+	}
+	if err := f.Remove("a.txt"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := f.Remove("a.txt"); !errors.Is(err, iofs.ErrNotExist) {
+		t.Fatalf("got error %v want %v", err, iofs.ErrNotExist)
+	}
+	if _, err := f.Open("a.txt"); !errors.Is(err, iofs.ErrNotExist) {
+		t.Fatalf("got error %v want %v", err, iofs.ErrNotExist)
+	}
+}
+
+func TestFail(t *testing.T) {
+	t.Parallel()
+
+	errInjected := errors.New("injected")
+	testCases := []struct {
+		op  Op
+		run func(f *FS) error
+	}{
+		{OpOpen, func(f *FS) error {
+			_, err := f.Open("a.txt")
+			return err
+		}},
+		{OpRead, func(f *FS) error {
+			r, _ := f.Open("a.txt") //nolint
+			_, err := io.Copy(io.Discard, r)
+			return err
+		}},
+		{OpCreate, func(f *FS) error {
+			_, err := f.Create("a.txt")
+			return err
+		}},
+		{OpWrite, func(f *FS) error {
+			w, _ := f.Create("a.txt") //nolint
+			_, err := io.WriteString(w, "data")
+			return err
+		}},
+		{OpClose, func(f *FS) error {
+			w, _ := f.Create("a.txt") //nolint
+			return w.Close()
+		}},
+	}
+	for _, tc := range testCases {
+		f := New()
+		f.WriteFile("a.txt", []byte("old"))
+		f.Fail("a.txt", tc.op, errInjected)
+		err := tc.run(f)
+		var pathErr *iofs.PathError
+		if !errors.Is(err, errInjected) || !errors.As(err, &pathErr) || pathErr.Op != opNames[tc.op] {
+			t.Fatalf("%s: got error %v want the injected error", opNames[tc.op], err)
+		}
+		if data, _ := f.ReadFile("a.txt"); string(data) != "old" { //nolint
+			t.Fatalf("%s: the file is changed by the failed operation", opNames[tc.op])
+		}
+		f.Fail("a.txt", tc.op, nil)
+		if err := tc.run(f); err != nil {
+			t.Fatalf("%s: got error %v after clearing the failure", opNames[tc.op], err)
+		}
+	}
+}
+
+func TestStorage(t *testing.T) {
+	mem := New()
+	imaging.RegisterStorage("memfs", mem)
+	defer imaging.RegisterStorage("memfs", nil)
+
+	// The files are saved and processed in parallel.
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			src, dst := fmt.Sprintf("memfs://src/%d.png", i), fmt.Sprintf("memfs://dst/%d.jpg", i)
+			if errs[i] = imaging.Save(imaging.New(8, 8, color.White), src); errs[i] != nil {
+				return
+			}
+			errs[i] = imaging.NewPipeline().Then("grayscale", func(img image.Image) image.Image {
+				return imaging.Grayscale(img)
+			}).Process(src, dst)
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("file %d: unexpected error: %v", i, err)
+		}
+	}
+	if n := len(mem.Names()); n != 16 {
+		t.Fatalf("got %d files want 16", n)
+	}
+	img, err := imaging.Open("memfs://dst/3.jpg")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if img.Bounds() != image.Rect(0, 0, 8, 8) {
+		t.Fatalf("got bounds %v want 8x8", img.Bounds())
+	}
+}
@@ -0,0 +1,173 @@
+// Package s3 implements an imaging.Storage for Amazon S3 and S3-compatible object stores
+// such as MinIO, Ceph or Cloudflare R2. It only depends on the standard library: the
+// requests are signed with AWS Signature Version 4.
+//
+// Example:
+//
+//	imaging.RegisterStorage("s3", s3.New(s3.ConfigFromEnv()))
+//	img, err := imaging.Open("s3://photos/2023/beach.jpg")
+//	...
+//	err = imaging.Save(thumb, "s3://thumbs/2023/beach.jpg")
+package s3
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/go-spectest/imaging/storage/internal/httpstore"
+)
+
+// ErrInvalidName means the name is not an s3://bucket/key URL.
+var ErrInvalidName = errors.New("s3: invalid name")
+
+// Config is the configuration of the S3 storage.
+type Config struct {
+	// Endpoint is the URL of the S3 service, e.g. "http://localhost:9000" for MinIO.
+	// The default is the AWS endpoint of the region.
+	Endpoint string
+	// Region is the region of the buckets. The default is "us-east-1".
+	Region string
+	// AccessKeyID, SecretAccessKey and SessionToken are the credentials. Without
+	// the access key the requests are sent unsigned, which works for public buckets.
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// PathStyle addresses the buckets as a part of the path ("endpoint/bucket/key")
+	// instead of the host name ("bucket.endpoint/key").
+	PathStyle bool
+	// Client is the HTTP client used for the requests. The default is http.DefaultClient.
+	Client *http.Client
+}
+
+// ConfigFromEnv returns the configuration from the environment variables used by the
+// AWS tools: AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN, AWS_REGION
+// (or AWS_DEFAULT_REGION) and AWS_ENDPOINT_URL_S3 (or AWS_ENDPOINT_URL). With a custom
+// endpoint the path style addressing is used, as S3-compatible stores expect.
+func ConfigFromEnv() Config {
+	c := Config{
+		Endpoint:        firstEnv("AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL"),
+		Region:          firstEnv("AWS_REGION", "AWS_DEFAULT_REGION"),
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	c.PathStyle = c.Endpoint != ""
+	return c
+}
+
+// firstEnv returns the value of the first environment variable that is set.
+func firstEnv(keys ...string) string {
+	for _, k := range keys {
+		if v := os.Getenv(k); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// Storage is an imaging.Storage reading and writing s3://bucket/key names.
+type Storage struct {
+	config Config
+	// now returns the time the requests are signed at.
+	now func() time.Time
+}
+
+// New returns a new S3 storage with the configuration.
+func New(config Config) *Storage {
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	if config.Endpoint == "" {
+		config.Endpoint = "https://s3." + config.Region + ".amazonaws.com"
+	}
+	config.Endpoint = strings.TrimSuffix(config.Endpoint, "/")
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+	return &Storage{config: config, now: time.Now}
+}
+
+// Open opens the object for reading. Missing objects are reported with an error
+// matching os.ErrNotExist.
+func (s *Storage) Open(name string) (io.ReadCloser, error) {
+	req, err := s.newRequest(http.MethodGet, name, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.config.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := httpstore.CheckResponse(resp, "s3", name); err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Create returns a writer for the object. The data is buffered in memory and
+// uploaded when the writer is closed, so the object is replaced at once.
+func (s *Storage) Create(name string) (io.WriteCloser, error) {
+	if _, _, err := splitName(name); err != nil {
+		return nil, err
+	}
+	return httpstore.NewWriter("s3", name, func(data []byte) error {
+		req, err := s.newRequest(http.MethodPut, name, data)
+		if err != nil {
+			return err
+		}
+		t := mime.TypeByExtension(path.Ext(name))
+		if t == "" {
+			t = "application/octet-stream"
+		}
+		req.Header.Set("Content-Type", t)
+		s.sign(req, data)
+		return httpstore.Do(s.config.Client, req, "s3", name)
+	}), nil
+}
+
+// newRequest returns a request for the object. GET requests are signed, other
+// requests must be signed by the caller once the headers are set.
+func (s *Storage) newRequest(method, name string, body []byte) (*http.Request, error) {
+	bucket, key, err := splitName(name)
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(s.config.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("s3: endpoint: %w", err)
+	}
+	if s.config.PathStyle {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + bucket + "/" + key
+	} else {
+		u.Host = bucket + "." + u.Host
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + key
+	}
+	u.RawPath = httpstore.Escape(u.Path, true)
+
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, u.String(), r)
+	if err != nil {
+		return nil, err
+	}
+	if method == http.MethodGet {
+		s.sign(req, nil)
+	}
+	return req, nil
+}
+
+// splitName returns the bucket and the key of an s3://bucket/key name.
+func splitName(name string) (bucket, key string, err error) {
+	return httpstore.SplitName(name, "s3", ErrInvalidName)
+}
@@ -0,0 +1,141 @@
+package s3
+
+import (
+	"errors"
+	"image"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-spectest/imaging"
+)
+
+// fakeS3 is a path style S3 server keeping the objects in memory.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	types   map[string]string
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key/") {
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, "<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>") //nolint
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		f.objects[r.URL.Path] = data
+		f.types[r.URL.Path] = r.Header.Get("Content-Type")
+	case http.MethodGet:
+		data, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(data) //nolint
+	}
+}
+
+func TestStorage(t *testing.T) {
+	server := &fakeS3{objects: map[string][]byte{}, types: map[string]string{}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	imaging.RegisterStorage("s3", New(Config{Endpoint: ts.URL, AccessKeyID: "key", SecretAccessKey: "secret", PathStyle: true}))
+	defer imaging.RegisterStorage("s3", nil)
+
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 3))
+	if err := imaging.Save(img, "s3://bucket/dir/my photo.png"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := server.types["/bucket/dir/my photo.png"]; got != "image/png" {
+		t.Fatalf("got content type %q want image/png", got)
+	}
+	got, err := imaging.Open("s3://bucket/dir/my photo.png")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Bounds() != img.Bounds() {
+		t.Fatalf("got bounds %v want %v", got.Bounds(), img.Bounds())
+	}
+
+	if _, err := imaging.Open("s3://bucket/missing.png"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("got error %v want %v", err, os.ErrNotExist)
+	}
+	if _, err := imaging.Open("s3://bucket"); !errors.Is(err, ErrInvalidName) {
+		t.Fatalf("got error %v want %v", err, ErrInvalidName)
+	}
+
+	imaging.RegisterStorage("s3", New(Config{Endpoint: ts.URL, PathStyle: true}))
+	_, err = imaging.Open("s3://bucket/dir/my photo.png")
+	if err == nil || !strings.Contains(err.Error(), "AccessDenied: Access Denied") {
+		t.Fatalf("got error %v want the access denied error", err)
+	}
+}
+
+func TestNewRequest(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		config Config
+		want   string
+	}{
+		{
+			name:   "virtual hosted",
+			config: Config{Region: "eu-west-1"},
+			want:   "https://photos.s3.eu-west-1.amazonaws.com/2023/a%2Bb.jpg",
+		},
+		{
+			name:   "path style",
+			config: Config{Endpoint: "http://localhost:9000/", PathStyle: true},
+			want:   "http://localhost:9000/photos/2023/a%2Bb.jpg",
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			req, err := New(tc.config).newRequest(http.MethodGet, "s3://photos/2023/a+b.jpg", nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := req.URL.String(); got != tc.want {
+				t.Fatalf("got URL %q want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "id")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "ap-northeast-1")
+	t.Setenv("AWS_ENDPOINT_URL_S3", "")
+	t.Setenv("AWS_ENDPOINT_URL", "http://minio:9000")
+
+	want := Config{
+		Endpoint:        "http://minio:9000",
+		Region:          "ap-northeast-1",
+		AccessKeyID:     "id",
+		SecretAccessKey: "secret",
+		PathStyle:       true,
+	}
+	if got := ConfigFromEnv(); got != want {
+		t.Fatalf("got config %+v want %+v", got, want)
+	}
+}
@@ -0,0 +1,78 @@
+package imaging
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/go-spectest/imaging/storage/memfs"
+)
+
+func TestRegisterStorage(t *testing.T) {
+	storage := memfs.New()
+	RegisterStorage("MEM", storage)
+	defer RegisterStorage("mem", nil)
+	// The local file system must not be used for the storage.
+	prevFS := fs
+	fs = badFS{}
+	defer func() { fs = prevFS }()
+
+	img := makeNoiseNRGBA(8, 6, 1)
+	for _, name := range []string{"mem://bucket/a.png", "Mem://bucket/b.png"} {
+		if err := Save(img, name); err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+	}
+	if err := SaveAtomic(img, "mem://bucket/c.png"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(storage.Names()) != 3 {
+		t.Fatalf("got %d stored files want 3", len(storage.Names()))
+	}
+
+	got, err := Open("mem://bucket/c.png")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !compareNRGBA(Clone(got), img, 0) {
+		t.Fatalf("the opened image differs from the saved one")
+	}
+	if _, err := Open("mem://bucket/missing.png"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("got error %v want %v", err, os.ErrNotExist)
+	}
+
+	RegisterStorage("mem", nil)
+	if _, err := Open("mem://bucket/c.png"); !errors.Is(err, errOpen) {
+		t.Fatalf("got error %v want the file system error %v", err, errOpen)
+	}
+}
+
+// timeoutError is a network timeout.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestIsTemporary(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{errOpen, false},
+		{ErrTemporary, true},
+		{fmt.Errorf("s3: a.jpg: %w", ErrTemporary), true},
+		{&net.OpError{Op: "dial", Err: timeoutError{}}, true},
+		{&net.OpError{Op: "dial", Err: errOpen}, false},
+	}
+	for _, tc := range testCases {
+		if got := IsTemporary(tc.err); got != tc.want {
+			t.Fatalf("%v: got %t want %t", tc.err, got, tc.want)
+		}
+	}
+}
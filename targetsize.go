@@ -0,0 +1,61 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+)
+
+// ErrTargetSize means the image can not be encoded within the requested size.
+var ErrTargetSize = errors.New("imaging: image does not fit the target size")
+
+// EncodeTargetSize writes the image to w in JPEG format using the highest quality
+// that keeps the output within maxBytes. The quality is searched between the
+// JPEGMinQuality and the JPEGQuality options (1 and 95 by default), a minimum above
+// the quality returns ErrInvalidEncodeOption. If the image does not fit even at the
+// minimum quality, nothing is written and ErrTargetSize is returned. Formats other
+// than JPEG return ErrUnsupportedFormat.
+//
+// Example:
+//
+//	// Keep thumbnails under 50 KB, but never go below quality 60.
+//	err := imaging.EncodeTargetSize(w, img, imaging.JPEG, 50<<10, imaging.JPEGMinQuality(60))
+func EncodeTargetSize(w io.Writer, img image.Image, format Format, maxBytes int64, opts ...EncodeOption) error {
+	if format != JPEG {
+		return fmt.Errorf("%w: %s has no quality setting", ErrUnsupportedFormat, format)
+	}
+	cfg, err := newEncodeConfig(format, opts)
+	if err != nil {
+		return err
+	}
+	lo, hi := cfg.jpegMinQuality, cfg.jpegQuality
+	if lo > hi {
+		return fmt.Errorf("%w: JPEG minimum quality %d is above the quality %d", ErrInvalidEncodeOption, lo, hi)
+	}
+	minQuality := lo
+
+	// The output size grows with the quality, so the highest quality
+	// that fits is found with a binary search.
+	var best []byte
+	buf := &bytes.Buffer{}
+	for lo <= hi {
+		q := (lo + hi) / 2
+		buf.Reset()
+		if err := Encode(buf, img, JPEG, append(opts, JPEGQuality(q))...); err != nil {
+			return err
+		}
+		if int64(buf.Len()) <= maxBytes {
+			best = append(best[:0], buf.Bytes()...)
+			lo = q + 1
+		} else {
+			hi = q - 1
+		}
+	}
+	if best == nil {
+		return fmt.Errorf("%w: %d bytes at quality %d", ErrTargetSize, buf.Len(), minQuality)
+	}
+	_, err = w.Write(best)
+	return err
+}
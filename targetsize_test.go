@@ -0,0 +1,68 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image/jpeg"
+	"io"
+	"testing"
+)
+
+func TestEncodeTargetSize(t *testing.T) {
+	t.Parallel()
+
+	img := makeNoiseNRGBA(64, 48, 5)
+	sizeAt := func(q int) int64 {
+		buf := &bytes.Buffer{}
+		if err := Encode(buf, img, JPEG, JPEGQuality(q)); err != nil {
+			t.Fatalf("failed to encode: %v", err)
+		}
+		return int64(buf.Len())
+	}
+
+	testCases := []struct {
+		name     string
+		maxBytes int64
+		opts     []EncodeOption
+		want     int64
+	}{
+		{name: "fits at default quality", maxBytes: sizeAt(95), want: sizeAt(95)},
+		{name: "reduced quality", maxBytes: sizeAt(50), want: sizeAt(50)},
+		{name: "maximum quality option", maxBytes: 1 << 30, opts: []EncodeOption{JPEGQuality(70)}, want: sizeAt(70)},
+		{name: "minimum quality", maxBytes: sizeAt(20), opts: []EncodeOption{JPEGMinQuality(20)}, want: sizeAt(20)},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			buf := &bytes.Buffer{}
+			if err := EncodeTargetSize(buf, img, JPEG, tc.maxBytes, tc.opts...); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if int64(buf.Len()) > tc.maxBytes || int64(buf.Len()) != tc.want {
+				t.Fatalf("got %d bytes want %d (max %d)", buf.Len(), tc.want, tc.maxBytes)
+			}
+			if _, err := jpeg.Decode(buf); err != nil {
+				t.Fatalf("failed to decode output: %v", err)
+			}
+		})
+	}
+
+	buf := &bytes.Buffer{}
+	err := EncodeTargetSize(buf, img, JPEG, sizeAt(40)-1, JPEGMinQuality(40))
+	if !errors.Is(err, ErrTargetSize) {
+		t.Fatalf("got error %v want %v", err, ErrTargetSize)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("got %d bytes written want 0", buf.Len())
+	}
+	err = EncodeTargetSize(io.Discard, img, JPEG, 1<<20, JPEGQuality(50), JPEGMinQuality(60))
+	if !errors.Is(err, ErrInvalidEncodeOption) {
+		t.Fatalf("got error %v want %v", err, ErrInvalidEncodeOption)
+	}
+	if err := EncodeTargetSize(io.Discard, img, PNG, 1<<20); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("got error %v want %v", err, ErrUnsupportedFormat)
+	}
+}
@@ -0,0 +1,84 @@
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"io"
+)
+
+// thumbnailSize is the maximum width and height of the thumbnails generated for
+// images without an embedded thumbnail, the size of the typical EXIF thumbnail.
+const thumbnailSize = 160
+
+// OpenThumbnail loads a small preview of the image from file. If the JPEG image
+// has a thumbnail embedded in the EXIF data (typically 160x120 pixels, written by
+// cameras and phones), it's decoded instead of the full image, which is many
+// times faster. Otherwise the image is decoded and downscaled to fit 160x160
+// pixels. The AutoOrientation option is applied to the embedded thumbnail too.
+//
+// Example:
+//
+//	thumb, err := imaging.OpenThumbnail("photo.jpg", imaging.AutoOrientation(true))
+func OpenThumbnail(filename string, opts ...DecodeOption) (img image.Image, err error) {
+	file, err := openFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			if err == nil {
+				err = closeErr
+			} else {
+				err = fmt.Errorf("original error: %s, defer close error: %w", err.Error(), closeErr)
+			}
+		}
+	}()
+	return DecodeThumbnail(file, opts...)
+}
+
+// DecodeThumbnail reads a small preview of the image from io.Reader
+// in the same way as OpenThumbnail.
+func DecodeThumbnail(r io.Reader, opts ...DecodeOption) (image.Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if thumb := readEXIFThumbnail(data); thumb != nil {
+		// A broken thumbnail falls back to the full image.
+		if img, err := decodeImage(bytes.NewReader(thumb)); err == nil {
+			cfg := defaultDecodeConfig
+			for _, option := range opts {
+				option(&cfg)
+			}
+			if cfg.autoOrientation {
+				return FixOrientation(img, ReadOrientation(bytes.NewReader(data))), nil
+			}
+			return img, nil
+		}
+	}
+
+	img, err := Decode(bytes.NewReader(data), opts...)
+	if err != nil {
+		return nil, err
+	}
+	return Fit(img, thumbnailSize, thumbnailSize, Box), nil
+}
+
+// readEXIFThumbnail returns the JPEG thumbnail stored in the second directory
+// (IFD1) of the EXIF data of the JPEG image or nil if there is none.
+func readEXIFThumbnail(data []byte) []byte {
+	exif := readJPEGEXIF(data)
+	if exif == nil {
+		return nil
+	}
+	dirs, err := parseTIFF(exif)
+	if err != nil || len(dirs) < 2 {
+		return nil
+	}
+	blobs := dirs[1].blobs[tagJPEGInterchangeFormat]
+	if len(blobs) != 1 || !isJPEG(blobs[0]) {
+		return nil
+	}
+	return blobs[0]
+}
@@ -0,0 +1,121 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// makeThumbnailJPEG returns a 40x20 JPEG image with the thumbnail in the EXIF data.
+func makeThumbnailJPEG(t *testing.T, orientation Orientation, thumb []byte) []byte {
+	t.Helper()
+	ifd0 := newTIFFDir(binary.BigEndian)
+	ifd0.setUints(tagOrientation, tiffShort, uint32(orientation))
+	ifd1 := newTIFFDir(binary.BigEndian)
+	ifd1.setUints(tagCompression, tiffShort, 6)
+	ifd1.blobs[tagJPEGInterchangeFormat] = [][]byte{thumb}
+	exif := &bytes.Buffer{}
+	if err := writeTIFF(exif, binary.BigEndian, []*tiffDir{ifd0, ifd1}); err != nil {
+		t.Fatalf("failed to write EXIF data: %v", err)
+	}
+
+	img := &bytes.Buffer{}
+	if err := Encode(img, makeNoiseNRGBA(40, 20, 1), JPEG); err != nil {
+		t.Fatalf("failed to encode JPEG: %v", err)
+	}
+	segment := []byte{0xff, markerAPP1, 0, 0}
+	segment = append(segment, exifHeader...)
+	segment = append(segment, exif.Bytes()...)
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(segment)-2))
+
+	data := img.Bytes()
+	out := append([]byte{}, data[:2]...)
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+func TestDecodeThumbnail(t *testing.T) {
+	t.Parallel()
+
+	thumb := &bytes.Buffer{}
+	if err := Encode(thumb, makeNoiseNRGBA(8, 4, 2), JPEG); err != nil {
+		t.Fatalf("failed to encode JPEG: %v", err)
+	}
+	large := &bytes.Buffer{}
+	if err := Encode(large, makeNoiseNRGBA(400, 200, 3), PNG); err != nil {
+		t.Fatalf("failed to encode PNG: %v", err)
+	}
+
+	testCases := []struct {
+		name          string
+		data          []byte
+		opts          []DecodeOption
+		width, height int
+	}{
+		{
+			name:  "embedded thumbnail",
+			data:  makeThumbnailJPEG(t, OrientationRotate270, thumb.Bytes()),
+			width: 8, height: 4,
+		},
+		{
+			name:  "embedded thumbnail with auto orientation",
+			data:  makeThumbnailJPEG(t, OrientationRotate270, thumb.Bytes()),
+			opts:  []DecodeOption{AutoOrientation(true)},
+			width: 4, height: 8,
+		},
+		{
+			name:  "broken thumbnail",
+			data:  makeThumbnailJPEG(t, OrientationNormal, thumb.Bytes()[:20]),
+			width: 40, height: 20,
+		},
+		{
+			name:  "no thumbnail",
+			data:  large.Bytes(),
+			width: 160, height: 80,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			img, err := DecodeThumbnail(bytes.NewReader(tc.data), tc.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			b := img.Bounds()
+			if b.Dx() != tc.width || b.Dy() != tc.height {
+				t.Fatalf("got size %dx%d want %dx%d", b.Dx(), b.Dy(), tc.width, tc.height)
+			}
+		})
+	}
+
+	if _, err := DecodeThumbnail(bytes.NewReader([]byte("not an image"))); err == nil {
+		t.Fatalf("expected error for invalid data")
+	}
+}
+
+func TestOpenThumbnail(t *testing.T) {
+	dir, err := os.MkdirTemp("", "imaging")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir) //nolint
+
+	filename := filepath.Join(dir, "large.png")
+	if err := Save(makeNoiseNRGBA(320, 320, 1), filename); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+	img, err := OpenThumbnail(filename)
+	if err != nil {
+		t.Fatalf("failed to open thumbnail: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != thumbnailSize || b.Dy() != thumbnailSize {
+		t.Fatalf("got size %dx%d", b.Dx(), b.Dy())
+	}
+	if _, err := OpenThumbnail(filepath.Join(dir, "missing.png")); err == nil {
+		t.Fatalf("expected error opening a missing file")
+	}
+}
@@ -0,0 +1,448 @@
+package imaging
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"image"
+	"io"
+	"math"
+
+	"golang.org/x/image/tiff"
+)
+
+// TIFF compression schemes as defined by the TIFF specification.
+const (
+	tiffCompressionNone    = 1
+	tiffCompressionLZW     = 5
+	tiffCompressionDeflate = 8
+)
+
+// tiffPredictorHorizontal is the value of the predictor tag for horizontal differencing.
+const tiffPredictorHorizontal = 2
+
+// ErrUnsupportedTIFFCompression means the TIFF compression type can not be used for encoding.
+var ErrUnsupportedTIFFCompression = errors.New("imaging: unsupported TIFF compression")
+
+// ErrInvalidTIFFTileSize means the TIFF tile size is not a positive multiple of 16.
+var ErrInvalidTIFFTileSize = errors.New("imaging: invalid TIFF tile size")
+
+// tiffMaxBandSize is the maximum size of the uncompressed pixel data encoded at
+// once, larger images are encoded in bands. It's a variable so tests can lower it.
+var tiffMaxBandSize uint64 = 1 << 28
+
+// encodeTIFF writes the image to w as TIFF using the compression and predictor from the config.
+// Images that don't fit in the 32-bit offsets of TIFF are written as BigTIFF.
+func encodeTIFF(w io.Writer, img image.Image, cfg *encodeConfig) error {
+	if cfg.outputMetadata() == nil {
+		dir, err := tiffPageDir(img, cfg)
+		if err != nil {
+			return err
+		}
+		if dir == nil {
+			return tiff.Encode(w, img, &tiff.Options{Compression: cfg.tiffCompression})
+		}
+		return cfg.writeTIFF(w, []*tiffDir{dir})
+	}
+	return encodeTIFFPages(w, []image.Image{img}, cfg)
+}
+
+// encodeTIFFPages writes the images to w as a multi-page TIFF, one directory per image.
+// The metadata from the config is written to the first page.
+func encodeTIFFPages(w io.Writer, imgs []image.Image, cfg *encodeConfig) error {
+	dirs := make([]*tiffDir, 0, len(imgs))
+	for i, img := range imgs {
+		dir, err := tiffPageDir(img, cfg)
+		if err != nil {
+			return err
+		}
+		if dir == nil {
+			buf := &bytes.Buffer{}
+			if err := tiff.Encode(buf, img, &tiff.Options{Compression: cfg.tiffCompression}); err != nil {
+				return err
+			}
+			parsed, err := parseTIFF(buf.Bytes())
+			if err != nil {
+				return err
+			}
+			dir = parsed[0]
+		}
+		if len(imgs) > 1 {
+			dir.setUints(tagPageNumber, tiffShort, uint32(i), uint32(len(imgs)))
+		}
+		if m := cfg.outputMetadata(); i == 0 && m != nil {
+			m.writeTIFF(dir)
+		}
+		dirs = append(dirs, dir)
+	}
+	return cfg.writeTIFF(w, dirs)
+}
+
+// writeTIFF writes the directories as TIFF, or as BigTIFF if requested by the config.
+func (cfg *encodeConfig) writeTIFF(w io.Writer, dirs []*tiffDir) error {
+	if cfg.tiffBigTIFF {
+		return writeBigTIFF(w, dirs[0].order, dirs)
+	}
+	return writeTIFF(w, dirs[0].order, dirs)
+}
+
+// decodeTIFFPages decodes the pages of the multi-page TIFF or BigTIFF read from r,
+// every page if all is set or only the first one otherwise.
+func decodeTIFFPages(r io.ReaderAt, cfg decodeConfig, all bool) ([]image.Image, error) {
+	t, err := newTIFFReader(r)
+	if err != nil {
+		return nil, err
+	}
+	hdr, err := t.readAt(0, 8)
+	if err != nil {
+		return nil, err
+	}
+	offsets := t.offsets
+	if !all {
+		offsets = offsets[:1]
+	}
+	imgs := make([]image.Image, 0, len(offsets))
+	for i, offset := range offsets {
+		if cfg.hasLimits() {
+			c, err := t.config(i)
+			if err != nil {
+				return nil, err
+			}
+			if err := checkConfigLimits(c, cfg); err != nil {
+				return nil, err
+			}
+		}
+		p, err := t.page(i)
+		if err != nil && t.big {
+			return nil, err
+		}
+		var img image.Image
+		if err == nil && (t.big || p.tag == tagTileOffsets) {
+			img, err = t.decodePage(p)
+		} else {
+			// The tiff package only decodes the first directory, so the header
+			// is patched to point at the directory of the page.
+			pr := &tiffPageReader{r: r}
+			copy(pr.header[:], hdr)
+			t.order.PutUint32(pr.header[4:], uint32(offset))
+			img, err = tiff.Decode(io.NewSectionReader(pr, 0, math.MaxInt64))
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		orient := OrientationUnspecified
+		if cfg.autoOrientation {
+			dir, _, err := t.readDir(offset, 0)
+			if err != nil {
+				return nil, err
+			}
+			orient = Orientation(dir.uint(tagOrientation))
+		}
+		switch {
+		case cfg.preserve16Bit && is16Bit(img):
+			img = fixOrientation64(img, orient)
+		case orient != OrientationUnspecified:
+			img = FixOrientation(img, orient)
+		}
+		imgs = append(imgs, img)
+	}
+	return imgs, nil
+}
+
+// isTIFF reports whether data starts with a TIFF or BigTIFF header.
+func isTIFF(data []byte) bool {
+	_, _, err := readTIFFHeader(data)
+	return err == nil || isBigTIFF(data)
+}
+
+// tiffReaderAt returns r as an io.ReaderAt starting at the current position
+// of r if it implements io.ReaderAt and holds a TIFF or BigTIFF image.
+func tiffReaderAt(r io.Reader) (io.ReaderAt, bool) {
+	ra, ok := r.(io.ReaderAt)
+	if !ok {
+		return nil, false
+	}
+	var pos int64
+	if s, ok := r.(io.Seeker); ok {
+		var err error
+		if pos, err = s.Seek(0, io.SeekCurrent); err != nil {
+			return nil, false
+		}
+	}
+	sr := io.NewSectionReader(ra, pos, math.MaxInt64-pos)
+	magic := make([]byte, 8)
+	if _, err := sr.ReadAt(magic, 0); err != nil || !isTIFF(magic) {
+		return nil, false
+	}
+	return sr, true
+}
+
+// tiffPageReader is an io.ReaderAt over the TIFF data with the replaced header.
+type tiffPageReader struct {
+	// header is the replacement of the first 8 bytes of the data.
+	header [8]byte
+	// r reads the TIFF data.
+	r io.ReaderAt
+}
+
+// ReadAt implements io.ReaderAt interface.
+func (r *tiffPageReader) ReadAt(p []byte, off int64) (int, error) {
+	n, err := r.r.ReadAt(p, off)
+	if off >= 0 && off < int64(len(r.header)) {
+		copy(p[:n], r.header[off:])
+	}
+	return n, err
+}
+
+// tiffPageDir encodes the image as a single TIFF directory. It returns nil directory
+// if the requested options are natively supported by the tiff package encoder.
+func tiffPageDir(img image.Image, cfg *encodeConfig) (*tiffDir, error) {
+	var compression uint32
+	switch cfg.tiffCompression {
+	case tiff.Uncompressed:
+		compression = tiffCompressionNone
+	case tiff.Deflate:
+		compression = tiffCompressionDeflate
+	case tiff.LZW:
+		compression = tiffCompressionLZW
+	default:
+		return nil, ErrUnsupportedTIFFCompression
+	}
+	tiled := cfg.tiffTileWidth != 0 || cfg.tiffTileHeight != 0
+	if tiled && (cfg.tiffTileWidth <= 0 || cfg.tiffTileHeight <= 0 || cfg.tiffTileWidth%16 != 0 || cfg.tiffTileHeight%16 != 0) {
+		return nil, ErrInvalidTIFFTileSize
+	}
+
+	// Images too large for a single strip are encoded in bands of rows, each one
+	// at most tiffMaxBandSize bytes assuming the largest pixel size of 8 bytes.
+	b := img.Bounds()
+	sub, canBand := img.(interface {
+		SubImage(r image.Rectangle) image.Image
+	})
+	bandRows := b.Dy()
+	if rowSize := 8 * uint64(b.Dx()); canBand && rowSize*uint64(b.Dy()) > tiffMaxBandSize {
+		bandRows = int(tiffMaxBandSize / rowSize)
+		if tiled {
+			bandRows -= bandRows % cfg.tiffTileHeight
+			if bandRows < cfg.tiffTileHeight {
+				bandRows = cfg.tiffTileHeight
+			}
+		} else if bandRows < 1 {
+			bandRows = 1
+		}
+	}
+	banded := bandRows < b.Dy()
+	if compression != tiffCompressionLZW && !cfg.tiffPredictor && !tiled && !cfg.tiffBigTIFF && !banded {
+		return nil, nil
+	}
+
+	// Encode the image uncompressed and compress the pixel data afterwards.
+	var dir *tiffDir
+	var bands [][]byte
+	for y := b.Min.Y; dir == nil || y < b.Max.Y; y += bandRows {
+		band := img
+		if banded {
+			r := image.Rect(b.Min.X, y, b.Max.X, y+bandRows).Intersect(b)
+			band = sub.SubImage(r)
+		}
+		buf := &bytes.Buffer{}
+		if err := tiff.Encode(buf, band, nil); err != nil {
+			return nil, err
+		}
+		dirs, err := parseTIFF(buf.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		strips := dirs[0].blobs[tagStripOffsets]
+		if len(strips) != 1 {
+			return nil, errInvalidTIFF
+		}
+		if dir == nil {
+			dir = dirs[0]
+		}
+		bands = append(bands, strips[0])
+	}
+
+	bits := dir.uint(tagBitsPerSample)
+	samples := int(dir.uint(tagSamplesPerPixel))
+	if samples == 0 {
+		samples = 1
+	}
+	width, height := b.Dx(), b.Dy()
+	var blocks [][]byte
+	blockWidth := width
+	for i, data := range bands {
+		if !tiled {
+			blocks = append(blocks, data)
+			continue
+		}
+		rows := bandRows
+		if (i+1)*bandRows > height {
+			rows = height - i*bandRows
+		}
+		blocks = append(blocks, splitTIFFTiles(data, width, rows, samples*int(bits)/8, cfg.tiffTileWidth, cfg.tiffTileHeight)...)
+		blockWidth = cfg.tiffTileWidth
+	}
+
+	for i, data := range blocks {
+		if cfg.tiffPredictor {
+			data = append([]byte(nil), data...)
+			applyHorizontalPredictor(data, blockWidth, samples, bits == 16)
+		}
+		switch compression {
+		case tiffCompressionDeflate:
+			zbuf := &bytes.Buffer{}
+			zw := zlib.NewWriter(zbuf)
+			if _, err := zw.Write(data); err != nil {
+				return nil, err
+			}
+			if err := zw.Close(); err != nil {
+				return nil, err
+			}
+			data = zbuf.Bytes()
+		case tiffCompressionLZW:
+			data = compressTIFFLZW(data)
+		}
+		blocks[i] = data
+	}
+
+	if cfg.tiffPredictor {
+		dir.setUints(tagPredictor, tiffShort, tiffPredictorHorizontal)
+	}
+	if tiled {
+		dir.remove(tagStripOffsets)
+		dir.remove(tagStripByteCounts)
+		dir.remove(tagRowsPerStrip)
+		dir.setUints(tagTileWidth, tiffLong, uint32(cfg.tiffTileWidth))
+		dir.setUints(tagTileLength, tiffLong, uint32(cfg.tiffTileHeight))
+		dir.blobs[tagTileOffsets] = blocks
+	} else {
+		dir.blobs[tagStripOffsets] = blocks
+		if banded {
+			dir.setUints(tagRowsPerStrip, tiffLong, uint32(bandRows))
+		}
+	}
+	if banded {
+		dir.setUints(tagImageLength, tiffLong, uint32(height))
+	}
+	dir.setUints(tagCompression, tiffShort, compression)
+	return dir, nil
+}
+
+// splitTIFFTiles splits the uncompressed pixel data of the image into tiles in
+// row-major order. The tiles on the right and bottom edges are padded with zeros.
+func splitTIFFTiles(data []byte, width, height, pixelSize, tileWidth, tileHeight int) [][]byte {
+	rowSize := width * pixelSize
+	tileRowSize := tileWidth * pixelSize
+	var tiles [][]byte
+	for ty := 0; ty < height; ty += tileHeight {
+		for tx := 0; tx < width; tx += tileWidth {
+			tile := make([]byte, tileRowSize*tileHeight)
+			n := tileRowSize
+			if tx+tileWidth > width {
+				n = (width - tx) * pixelSize
+			}
+			for y := 0; y < tileHeight && ty+y < height; y++ {
+				off := (ty+y)*rowSize + tx*pixelSize
+				copy(tile[y*tileRowSize:y*tileRowSize+n], data[off:off+n])
+			}
+			tiles = append(tiles, tile)
+		}
+	}
+	return tiles
+}
+
+// applyHorizontalPredictor replaces the samples of each row with the differences
+// to the preceding pixel's samples. The data is expected to be little-endian
+// if the image has 16 bits per sample.
+func applyHorizontalPredictor(data []byte, width, samples int, is16 bool) {
+	if width <= 0 || samples <= 0 {
+		return
+	}
+	rowSize := width * samples
+	if is16 {
+		rowSize *= 2
+	}
+	for off := 0; off+rowSize <= len(data); off += rowSize {
+		row := data[off : off+rowSize]
+		if is16 {
+			for i := len(row) - 2; i >= 2*samples; i -= 2 {
+				v := uint16(row[i]) | uint16(row[i+1])<<8
+				p := uint16(row[i-2*samples]) | uint16(row[i-2*samples+1])<<8
+				v -= p
+				row[i], row[i+1] = uint8(v), uint8(v>>8)
+			}
+			continue
+		}
+		for i := len(row) - 1; i >= samples; i-- {
+			row[i] -= row[i-samples]
+		}
+	}
+}
+
+// compressTIFFLZW compresses data using the LZW variant of the TIFF specification
+// (MSB-first codes with the code width switched one code earlier than in standard LZW).
+func compressTIFFLZW(data []byte) []byte {
+	const (
+		clearCode = 256
+		eoiCode   = 257
+		firstCode = 258
+		maxCode   = 4094
+	)
+
+	out := make([]byte, 0, len(data)/2+16)
+	var acc uint32
+	var nbits uint
+	width := uint(9)
+	emit := func(code uint32) {
+		acc = acc<<width | code
+		nbits += width
+		for nbits >= 8 {
+			out = append(out, uint8(acc>>(nbits-8)))
+			nbits -= 8
+		}
+		acc &= 1<<nbits - 1
+	}
+
+	// table maps (prefix code, next byte) to a code. Entries are tagged with
+	// a generation number so the table can be reset in constant time.
+	table := make([]uint32, 4096*256)
+	gen := uint32(1)
+	next := uint32(firstCode)
+	advance := func() {
+		next++
+		if next >= 1<<width && width < 12 {
+			width++
+		}
+	}
+
+	emit(clearCode)
+	if len(data) > 0 {
+		code := uint32(data[0])
+		for _, b := range data[1:] {
+			key := code<<8 | uint32(b)
+			if e := table[key]; e>>16 == gen {
+				code = e & 0xffff
+				continue
+			}
+			emit(code)
+			table[key] = gen<<16 | next
+			advance()
+			if next >= maxCode {
+				emit(clearCode)
+				gen++
+				next = firstCode
+				width = 9
+			}
+			code = uint32(b)
+		}
+		emit(code)
+		advance()
+	}
+	emit(eoiCode)
+	if nbits > 0 {
+		out = append(out, uint8(acc<<(8-nbits)))
+	}
+	return out
+}
@@ -0,0 +1,447 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"io"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/image/tiff"
+)
+
+func makeNoiseNRGBA(w, h int, seed int64) *image.NRGBA {
+	rnd := rand.New(rand.NewSource(seed)) //nolint
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		// Mix noise with a gradient so that both literal and repeated codes are produced.
+		img.Pix[i] = uint8(rnd.Intn(4)) + uint8(i/97)
+	}
+	return img
+}
+
+func TestEncodeTIFFOptions(t *testing.T) {
+	t.Parallel()
+
+	noise := makeNoiseNRGBA(300, 200, 1)
+	gray := image.NewGray(image.Rect(0, 0, 31, 17))
+	paletted := image.NewPaletted(image.Rect(0, 0, 19, 23), palette.Plan9)
+	for i := range gray.Pix {
+		gray.Pix[i] = uint8(i * 7)
+	}
+	for i := range paletted.Pix {
+		paletted.Pix[i] = uint8(i % 200)
+	}
+
+	images := map[string]image.Image{
+		"NRGBA":    noise,
+		"NRGBA64":  makeGradient64(33, 21),
+		"Gray":     gray,
+		"Paletted": paletted,
+		"Empty":    image.NewNRGBA(image.Rect(0, 0, 1, 1)),
+	}
+	compressions := map[tiff.CompressionType]uint32{
+		tiff.Uncompressed: tiffCompressionNone,
+		tiff.Deflate:      tiffCompressionDeflate,
+		tiff.LZW:          tiffCompressionLZW,
+	}
+
+	for name, img := range images {
+		for compression, tag := range compressions {
+			for _, predictor := range []bool{false, true} {
+				buf := &bytes.Buffer{}
+				err := Encode(buf, img, TIFF, TIFFCompression(compression), TIFFPredictor(predictor))
+				if err != nil {
+					t.Fatalf("%s (compression=%d, predictor=%v): failed to encode: %v", name, compression, predictor, err)
+				}
+
+				dirs, err := parseTIFF(buf.Bytes())
+				if err != nil {
+					t.Fatalf("%s: failed to parse TIFF: %v", name, err)
+				}
+				if got := dirs[0].uint(tagCompression); got != tag {
+					t.Fatalf("%s: got compression tag %d want %d", name, got, tag)
+				}
+				if got := dirs[0].uint(tagPredictor) == tiffPredictorHorizontal; got != predictor {
+					t.Fatalf("%s: got predictor %v want %v", name, got, predictor)
+				}
+
+				decoded, err := Decode(bytes.NewReader(buf.Bytes()))
+				if err != nil {
+					t.Fatalf("%s (compression=%d, predictor=%v): failed to decode: %v", name, compression, predictor, err)
+				}
+				if !sameTIFFImage(decoded, img) {
+					t.Fatalf("%s (compression=%d, predictor=%v): decoded image differs", name, compression, predictor)
+				}
+				pages, err := DecodeAll(bytes.NewReader(buf.Bytes()))
+				if err != nil {
+					t.Fatalf("%s (compression=%d, predictor=%v): failed to decode all: %v", name, compression, predictor, err)
+				}
+				if len(pages) != 1 || !sameTIFFImage(pages[0], img) {
+					t.Fatalf("%s (compression=%d, predictor=%v): decoded page differs", name, compression, predictor)
+				}
+			}
+		}
+	}
+}
+
+func TestEncodeTIFFUnsupportedCompression(t *testing.T) {
+	t.Parallel()
+
+	img := New(2, 2, color.White)
+	err := Encode(&bytes.Buffer{}, img, TIFF, TIFFCompression(tiff.CCITTGroup4))
+	if !errors.Is(err, ErrUnsupportedTIFFCompression) {
+		t.Fatalf("got error %v want ErrUnsupportedTIFFCompression", err)
+	}
+}
+
+func TestCompressTIFFLZWSize(t *testing.T) {
+	t.Parallel()
+
+	data := bytes.Repeat([]byte{1, 2, 3, 4}, 10000)
+	if got := compressTIFFLZW(data); len(got) >= len(data)/10 {
+		t.Fatalf("got compressed size %d for repetitive data of size %d", len(got), len(data))
+	}
+}
+
+func TestEncodeDecodeAllTIFF(t *testing.T) {
+	t.Parallel()
+
+	pages := []image.Image{
+		makeNoiseNRGBA(40, 30, 1),
+		makeNoiseNRGBA(17, 23, 2),
+		makeGradient64(9, 5),
+	}
+
+	for _, compression := range []tiff.CompressionType{tiff.Uncompressed, tiff.Deflate, tiff.LZW} {
+		buf := &bytes.Buffer{}
+		if err := EncodeAll(buf, pages, TIFF, TIFFCompression(compression)); err != nil {
+			t.Fatalf("compression=%d: failed to encode: %v", compression, err)
+		}
+
+		dirs, err := parseTIFF(buf.Bytes())
+		if err != nil {
+			t.Fatalf("compression=%d: failed to parse TIFF: %v", compression, err)
+		}
+		if len(dirs) != len(pages) {
+			t.Fatalf("compression=%d: got %d directories want %d", compression, len(dirs), len(pages))
+		}
+		for i, dir := range dirs {
+			if got := dir.uints(tagPageNumber); len(got) != 2 || got[0] != uint32(i) || got[1] != uint32(len(pages)) {
+				t.Fatalf("compression=%d: got page number %v for page %d", compression, got, i)
+			}
+		}
+
+		decoded, err := DecodeAll(bytes.NewReader(buf.Bytes()), Preserve16Bit(true))
+		if err != nil {
+			t.Fatalf("compression=%d: failed to decode: %v", compression, err)
+		}
+		if len(decoded) != len(pages) {
+			t.Fatalf("compression=%d: got %d pages want %d", compression, len(decoded), len(pages))
+		}
+		for i := 0; i < 2; i++ {
+			if !compareNRGBA(Clone(decoded[i]), pages[i].(*image.NRGBA), 0) { //nolint
+				t.Fatalf("compression=%d: page %d differs", compression, i)
+			}
+		}
+		if !compareNRGBA64(Clone64(decoded[2]), pages[2].(*image.NRGBA64)) { //nolint
+			t.Fatalf("compression=%d: 16-bit page differs", compression)
+		}
+	}
+}
+
+func TestDecodeAllTIFFOrientation(t *testing.T) {
+	t.Parallel()
+
+	src := makeNoiseNRGBA(6, 4, 3)
+	buf := &bytes.Buffer{}
+	if err := Encode(buf, src, TIFF); err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	dirs, err := parseTIFF(buf.Bytes())
+	if err != nil {
+		t.Fatalf("failed to parse TIFF: %v", err)
+	}
+	dirs[0].setUints(tagOrientation, tiffShort, uint32(OrientationRotate90))
+	out := &bytes.Buffer{}
+	if err := writeTIFF(out, dirs[0].order, []*tiffDir{dirs[0], dirs[0]}); err != nil {
+		t.Fatalf("failed to write TIFF: %v", err)
+	}
+
+	imgs, err := DecodeAll(bytes.NewReader(out.Bytes()), AutoOrientation(true))
+	if err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	want := Rotate90(src)
+	for i, img := range imgs {
+		if !compareNRGBA(Clone(img), want, 0) {
+			t.Fatalf("page %d: orientation was not applied", i)
+		}
+	}
+}
+
+func TestEncodeDecodeAllErrors(t *testing.T) {
+	t.Parallel()
+
+	img := makeNoiseNRGBA(4, 4, 1)
+	if err := EncodeAll(&bytes.Buffer{}, nil, TIFF); !errors.Is(err, ErrNoImages) {
+		t.Fatalf("got error %v want %v", err, ErrNoImages)
+	}
+	if err := EncodeAll(&bytes.Buffer{}, []image.Image{img, img}, PNG); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("got error %v want %v", err, ErrUnsupportedFormat)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := EncodeAll(buf, []image.Image{img}, PNG); err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	imgs, err := DecodeAll(buf)
+	if err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	if len(imgs) != 1 || !compareNRGBA(Clone(imgs[0]), img, 0) {
+		t.Fatalf("single PNG image was not decoded as one page")
+	}
+
+	if _, err := DecodeAll(bytes.NewReader([]byte("II*\x00\x08\x00\x00\x00"))); err == nil {
+		t.Fatalf("expected error decoding a truncated TIFF")
+	}
+}
+
+// makeTiledTIFFImages returns images of the types decoded by the tiff package,
+// their sizes aren't multiples of the 32x16 tiles.
+func makeTiledTIFFImages() map[string]image.Image {
+	gray := image.NewGray(image.Rect(0, 0, 70, 17))
+	for i := range gray.Pix {
+		gray.Pix[i] = uint8(i * 3)
+	}
+	paletted := image.NewPaletted(image.Rect(0, 0, 45, 20), palette.Plan9)
+	for i := range paletted.Pix {
+		paletted.Pix[i] = uint8(i * 7)
+	}
+	return map[string]image.Image{
+		"NRGBA":    makeNoiseNRGBA(70, 33, 5),
+		"NRGBA64":  makeGradient64(50, 40),
+		"Gray":     gray,
+		"Paletted": paletted,
+	}
+}
+
+// sameTIFFImage reports whether the decoded TIFF image has the type and the pixels of img.
+func sameTIFFImage(decoded, img image.Image) bool {
+	if fmt.Sprintf("%T", decoded) != fmt.Sprintf("%T", img) {
+		return false
+	}
+	if _, ok := img.(*image.NRGBA64); ok {
+		return compareNRGBA64(Clone64(decoded), Clone64(img))
+	}
+	return compareNRGBA(Clone(decoded), Clone(img), 0)
+}
+
+func TestEncodeTIFFTiled(t *testing.T) {
+	t.Parallel()
+
+	images := makeTiledTIFFImages()
+
+	for name, img := range images {
+		for _, compression := range []tiff.CompressionType{tiff.Uncompressed, tiff.Deflate, tiff.LZW} {
+			for _, predictor := range []bool{false, true} {
+				buf := &bytes.Buffer{}
+				err := Encode(buf, img, TIFF, TIFFTileSize(32, 16), TIFFCompression(compression), TIFFPredictor(predictor))
+				if err != nil {
+					t.Fatalf("%s: failed to encode: %v", name, err)
+				}
+				dirs, err := parseTIFF(buf.Bytes())
+				if err != nil {
+					t.Fatalf("%s: failed to parse TIFF: %v", name, err)
+				}
+				b := img.Bounds()
+				wantTiles := ((b.Dx() + 31) / 32) * ((b.Dy() + 15) / 16)
+				if got := len(dirs[0].blobs[tagTileOffsets]); got != wantTiles {
+					t.Fatalf("%s: got %d tiles want %d", name, got, wantTiles)
+				}
+				if dirs[0].field(tagStripOffsets) != nil {
+					t.Fatalf("%s: tiled image must not have strips", name)
+				}
+
+				decoded, err := Decode(bytes.NewReader(buf.Bytes()))
+				if err != nil {
+					t.Fatalf("%s (compression=%d, predictor=%v): failed to decode: %v", name, compression, predictor, err)
+				}
+				if !sameTIFFImage(decoded, img) {
+					t.Fatalf("%s (compression=%d, predictor=%v): decoded image differs", name, compression, predictor)
+				}
+				pages, err := DecodeAll(bytes.NewReader(buf.Bytes()))
+				if err != nil {
+					t.Fatalf("%s (compression=%d, predictor=%v): failed to decode all: %v", name, compression, predictor, err)
+				}
+				if len(pages) != 1 || !sameTIFFImage(pages[0], img) {
+					t.Fatalf("%s (compression=%d, predictor=%v): decoded page differs", name, compression, predictor)
+				}
+			}
+		}
+	}
+}
+
+func TestEncodeTIFFInvalidTileSize(t *testing.T) {
+	t.Parallel()
+
+	img := makeNoiseNRGBA(10, 10, 1)
+	for _, size := range [][2]int{{0, 16}, {16, 0}, {24, 16}, {-16, 16}} {
+		err := Encode(&bytes.Buffer{}, img, TIFF, TIFFTileSize(size[0], size[1]))
+		if !errors.Is(err, ErrInvalidTIFFTileSize) {
+			t.Fatalf("%v: got error %v want %v", size, err, ErrInvalidTIFFTileSize)
+		}
+	}
+}
+
+func TestEncodeBigTIFF(t *testing.T) {
+	t.Parallel()
+
+	img := makeNoiseNRGBA(70, 33, 6)
+	for _, compression := range []tiff.CompressionType{tiff.Uncompressed, tiff.Deflate, tiff.LZW} {
+		for _, tiled := range []bool{false, true} {
+			opts := []EncodeOption{TIFFBigTIFF(true), TIFFCompression(compression)}
+			if tiled {
+				opts = append(opts, TIFFTileSize(32, 16))
+			}
+			buf := &bytes.Buffer{}
+			if err := Encode(buf, img, TIFF, opts...); err != nil {
+				t.Fatalf("compression=%d, tiled=%v: failed to encode: %v", compression, tiled, err)
+			}
+			if !bytes.HasPrefix(buf.Bytes(), []byte("II+\x00\x08\x00\x00\x00")) {
+				t.Fatalf("compression=%d, tiled=%v: got header %q want BigTIFF", compression, tiled, buf.Bytes()[:8])
+			}
+			decoded, err := Decode(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("compression=%d, tiled=%v: failed to decode: %v", compression, tiled, err)
+			}
+			if !compareNRGBA(Clone(decoded), img, 0) {
+				t.Fatalf("compression=%d, tiled=%v: decoded image differs", compression, tiled)
+			}
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	if err := Encode(buf, img, TIFF, TIFFBigTIFF(true)); err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	c, format, err := decodeImageConfig(bytes.NewReader(buf.Bytes()))
+	if err != nil || format != "tiff" || c.Width != 70 || c.Height != 33 {
+		t.Fatalf("got config %dx%d, format %q, error %v", c.Width, c.Height, format, err)
+	}
+	if _, err := Decode(bytes.NewReader(buf.Bytes()), Limits(50, 0, 0)); !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("got error %v want %v", err, ErrLimitExceeded)
+	}
+
+	pages := []image.Image{makeNoiseNRGBA(40, 30, 1), makeGradient64(9, 5)}
+	buf.Reset()
+	if err := EncodeAll(buf, pages, TIFF, TIFFBigTIFF(true), TIFFPredictor(true)); err != nil {
+		t.Fatalf("failed to encode pages: %v", err)
+	}
+	decodedPages, err := DecodeAll(bytes.NewReader(buf.Bytes()), Preserve16Bit(true))
+	if err != nil {
+		t.Fatalf("failed to decode pages: %v", err)
+	}
+	if len(decodedPages) != 2 || !compareNRGBA(Clone(decodedPages[0]), pages[0].(*image.NRGBA), 0) ||
+		!compareNRGBA64(Clone64(decodedPages[1]), pages[1].(*image.NRGBA64)) {
+		t.Fatalf("decoded pages differ")
+	}
+}
+
+func TestEncodeTIFFBands(t *testing.T) {
+	// The test lowers the size limits of the package, so it doesn't run in parallel.
+	defer func(band, classic uint64) {
+		tiffMaxBandSize, tiffMaxClassicSize = band, classic
+	}(tiffMaxBandSize, tiffMaxClassicSize)
+	tiffMaxBandSize = 8 * 150 * 10
+	tiffMaxClassicSize = 20000
+
+	img := makeNoiseNRGBA(150, 100, 7)
+	testCases := []struct {
+		name   string
+		opts   []EncodeOption
+		tag    uint16
+		blocks int
+	}{
+		{"strips", nil, tagStripOffsets, 10},
+		{"tiles", []EncodeOption{TIFFTileSize(32, 16)}, tagTileOffsets, 5 * 7},
+	}
+	for _, tc := range testCases {
+		buf := &bytes.Buffer{}
+		if err := Encode(buf, img, TIFF, tc.opts...); err != nil {
+			t.Fatalf("%s: failed to encode: %v", tc.name, err)
+		}
+		// The output exceeds the lowered limit of TIFF, so it's written as BigTIFF.
+		if !isBigTIFF(buf.Bytes()) {
+			t.Fatalf("%s: got header %q want BigTIFF", tc.name, buf.Bytes()[:8])
+		}
+		dirs, err := parseTIFF(buf.Bytes())
+		if err != nil {
+			t.Fatalf("%s: failed to parse TIFF: %v", tc.name, err)
+		}
+		if got := len(dirs[0].blobs[tc.tag]); got != tc.blocks {
+			t.Fatalf("%s: got %d blocks want %d", tc.name, got, tc.blocks)
+		}
+
+		// The blocks don't fit in a single TIFF structure either, so they're decoded in bands.
+		decoded, err := Decode(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("%s: failed to decode: %v", tc.name, err)
+		}
+		if !compareNRGBA(Clone(decoded), img, 0) {
+			t.Fatalf("%s: decoded image differs", tc.name)
+		}
+		rect := image.Rect(20, 15, 130, 85)
+		region, err := DecodeTIFFRegion(bytes.NewReader(buf.Bytes()), 0, rect)
+		if err != nil {
+			t.Fatalf("%s: failed to decode region: %v", tc.name, err)
+		}
+		if !compareNRGBA(Clone(region), Crop(img, rect), 0) {
+			t.Fatalf("%s: decoded region differs", tc.name)
+		}
+	}
+}
+
+// readerAtOnly is an io.ReadSeeker and io.ReaderAt that fails the sequential reads.
+type readerAtOnly struct {
+	*bytes.Reader
+}
+
+func (readerAtOnly) Read([]byte) (int, error) {
+	return 0, errors.New("sequential read")
+}
+
+func TestDecodeTIFFReaderAt(t *testing.T) {
+	t.Parallel()
+
+	img1, img2 := makeNoiseNRGBA(40, 30, 1), makeNoiseNRGBA(20, 10, 2)
+	buf := &bytes.Buffer{}
+	buf.WriteString("prefix")
+	if err := EncodeAll(buf, []image.Image{img1, img2}, TIFF, TIFFBigTIFF(true), TIFFTileSize(16, 16)); err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+
+	r := readerAtOnly{bytes.NewReader(buf.Bytes())}
+	if _, err := r.Seek(int64(len("prefix")), io.SeekStart); err != nil {
+		t.Fatalf("failed to seek: %v", err)
+	}
+	got, err := Decode(r)
+	if err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	if !compareNRGBA(Clone(got), img1, 0) {
+		t.Fatalf("decoded image differs")
+	}
+	imgs, err := DecodeAll(r)
+	if err != nil {
+		t.Fatalf("failed to decode all: %v", err)
+	}
+	if len(imgs) != 2 || !compareNRGBA(Clone(imgs[1]), img2, 0) {
+		t.Fatalf("decoded pages differ")
+	}
+}
@@ -0,0 +1,834 @@
+package imaging
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+// TIFF field types, including the 64-bit types of BigTIFF.
+const (
+	tiffByte      = 1
+	tiffASCII     = 2
+	tiffShort     = 3
+	tiffLong      = 4
+	tiffRational  = 5
+	tiffSByte     = 6
+	tiffUndefined = 7
+	tiffSShort    = 8
+	tiffSLong     = 9
+	tiffSRational = 10
+	tiffFloat     = 11
+	tiffDouble    = 12
+	tiffIFD       = 13
+	tiffLong8     = 16
+	tiffSLong8    = 17
+	tiffIFD8      = 18
+)
+
+// tiffTypeSizes maps TIFF field types to the size of a single value in bytes.
+var tiffTypeSizes = map[uint16]uint32{ //nolint
+	tiffByte:      1,
+	tiffASCII:     1,
+	tiffShort:     2,
+	tiffLong:      4,
+	tiffRational:  8,
+	tiffSByte:     1,
+	tiffUndefined: 1,
+	tiffSShort:    2,
+	tiffSLong:     4,
+	tiffSRational: 8,
+	tiffFloat:     4,
+	tiffDouble:    8,
+	tiffIFD:       4,
+	tiffLong8:     8,
+	tiffSLong8:    8,
+	tiffIFD8:      8,
+}
+
+// tiffClassicTypes maps the 64-bit BigTIFF field types to the classic TIFF types.
+var tiffClassicTypes = map[uint16]uint16{ //nolint
+	tiffLong8:  tiffLong,
+	tiffSLong8: tiffSLong,
+	tiffIFD8:   tiffIFD,
+}
+
+// TIFF tags used by the package.
+const (
+	tagImageWidth                  = 256
+	tagImageLength                 = 257
+	tagBitsPerSample               = 258
+	tagCompression                 = 259
+	tagStripOffsets                = 273
+	tagOrientation                 = 274
+	tagSamplesPerPixel             = 277
+	tagRowsPerStrip                = 278
+	tagStripByteCounts             = 279
+	tagPageNumber                  = 297
+	tagPredictor                   = 317
+	tagTileWidth                   = 322
+	tagTileLength                  = 323
+	tagTileOffsets                 = 324
+	tagTileByteCounts              = 325
+	tagJPEGInterchangeFormat       = 513
+	tagJPEGInterchangeFormatLength = 514
+	tagModelPixelScale             = 33550
+	tagModelTiepoint               = 33922
+	tagModelTransformation         = 34264
+	tagExifIFD                     = 34665
+	tagGeoKeyDirectory             = 34735
+	tagGeoDoubleParams             = 34736
+	tagGeoASCIIParams              = 34737
+	tagGPSIFD                      = 34853
+	tagInteropIFD                  = 40965
+	tagGDALMetadata                = 42112
+	tagGDALNoData                  = 42113
+)
+
+// tiffBlobTags maps the tags that point to data blobs (e.g. strips) to the tags holding blob sizes.
+var tiffBlobTags = map[uint16]uint16{ //nolint
+	tagStripOffsets:          tagStripByteCounts,
+	tagTileOffsets:           tagTileByteCounts,
+	tagJPEGInterchangeFormat: tagJPEGInterchangeFormatLength,
+}
+
+// tiffSubDirTags is the list of tags that point to sub-directories.
+var tiffSubDirTags = []uint16{tagExifIFD, tagGPSIFD, tagInteropIFD} //nolint
+
+// tiffMaxClassicSize is the size of the largest structure written as TIFF, larger
+// structures need the 64-bit offsets of BigTIFF.
+var tiffMaxClassicSize uint64 = math.MaxUint32
+
+// errInvalidTIFF means the TIFF structure is malformed.
+var errInvalidTIFF = errors.New("imaging: invalid TIFF structure")
+
+// tiffField is a single entry of a TIFF image file directory.
+type tiffField struct {
+	// tag is the field tag.
+	tag uint16
+	// typ is the field type.
+	typ uint16
+	// count is the number of values.
+	count uint32
+	// data holds the values in the byte order of the directory.
+	data []byte
+}
+
+// tiffDir is a TIFF image file directory (IFD) with the data it refers to.
+type tiffDir struct {
+	// order is the byte order of the field values.
+	order binary.ByteOrder
+	// fields is the list of directory entries.
+	fields []tiffField
+	// blobs holds the data referenced by the blob tags (strips, tiles, thumbnails).
+	blobs map[uint16][][]byte
+	// subs holds the sub-directories (e.g. EXIF and GPS directories).
+	subs map[uint16]*tiffDir
+}
+
+// newTIFFDir creates an empty directory with the given byte order.
+func newTIFFDir(order binary.ByteOrder) *tiffDir {
+	return &tiffDir{
+		order: order,
+		blobs: map[uint16][][]byte{},
+		subs:  map[uint16]*tiffDir{},
+	}
+}
+
+// field returns the field with the given tag or nil.
+func (d *tiffDir) field(tag uint16) *tiffField {
+	for i := range d.fields {
+		if d.fields[i].tag == tag {
+			return &d.fields[i]
+		}
+	}
+	return nil
+}
+
+// set adds or replaces the field with the given tag.
+func (d *tiffDir) set(tag, typ uint16, count uint32, data []byte) {
+	if f := d.field(tag); f != nil {
+		f.typ, f.count, f.data = typ, count, data
+		return
+	}
+	d.fields = append(d.fields, tiffField{tag: tag, typ: typ, count: count, data: data})
+}
+
+// setUints adds or replaces an unsigned integer field with the given tag.
+func (d *tiffDir) setUints(tag, typ uint16, values ...uint32) {
+	size := tiffTypeSizes[typ]
+	data := make([]byte, int(size)*len(values))
+	for i, v := range values {
+		switch size {
+		case 1:
+			data[i] = uint8(v)
+		case 2:
+			d.order.PutUint16(data[2*i:], uint16(v))
+		case 4:
+			d.order.PutUint32(data[4*i:], v)
+		default:
+			d.order.PutUint64(data[8*i:], uint64(v))
+		}
+	}
+	d.set(tag, typ, uint32(len(values)), data)
+}
+
+// remove deletes the field with the given tag along with the data it refers to.
+func (d *tiffDir) remove(tag uint16) {
+	for i := range d.fields {
+		if d.fields[i].tag == tag {
+			d.fields = append(d.fields[:i], d.fields[i+1:]...)
+			break
+		}
+	}
+	delete(d.blobs, tag)
+	delete(d.subs, tag)
+}
+
+// uints returns the values of an unsigned integer field or nil.
+func (d *tiffDir) uints(tag uint16) []uint32 {
+	f := d.field(tag)
+	if f == nil {
+		return nil
+	}
+	return f.uints(d.order)
+}
+
+// uint returns the first value of an unsigned integer field or 0.
+func (d *tiffDir) uint(tag uint16) uint32 {
+	if v := d.uints(tag); len(v) > 0 {
+		return v[0]
+	}
+	return 0
+}
+
+// floats returns the values of a floating point field or nil.
+func (d *tiffDir) floats(tag uint16) []float64 {
+	f := d.field(tag)
+	if f == nil {
+		return nil
+	}
+	var values []float64
+	switch f.typ {
+	case tiffDouble:
+		for i := 0; i+8 <= len(f.data); i += 8 {
+			values = append(values, math.Float64frombits(d.order.Uint64(f.data[i:])))
+		}
+	case tiffFloat:
+		for i := 0; i+4 <= len(f.data); i += 4 {
+			values = append(values, float64(math.Float32frombits(d.order.Uint32(f.data[i:]))))
+		}
+	}
+	return values
+}
+
+// setFloats adds or replaces a double precision floating point field with the given tag.
+func (d *tiffDir) setFloats(tag uint16, values ...float64) {
+	data := make([]byte, 8*len(values))
+	for i, v := range values {
+		d.order.PutUint64(data[8*i:], math.Float64bits(v))
+	}
+	d.set(tag, tiffDouble, uint32(len(values)), data)
+}
+
+// ascii returns the value of an ASCII field without the terminating NUL.
+func (d *tiffDir) ascii(tag uint16) string {
+	f := d.field(tag)
+	if f == nil || f.typ != tiffASCII {
+		return ""
+	}
+	return strings.TrimRight(string(f.data), "\x00")
+}
+
+// setASCII adds or replaces an ASCII field with the given tag.
+func (d *tiffDir) setASCII(tag uint16, s string) {
+	data := append([]byte(s), 0)
+	d.set(tag, tiffASCII, uint32(len(data)), data)
+}
+
+// uints decodes the field values as unsigned integers.
+func (f *tiffField) uints(order binary.ByteOrder) []uint32 {
+	var size uint32
+	switch f.typ {
+	case tiffByte, tiffUndefined:
+		size = 1
+	case tiffShort:
+		size = 2
+	case tiffLong, tiffIFD:
+		size = 4
+	default:
+		return nil
+	}
+	values := make([]uint32, 0, f.count)
+	for i := uint32(0); i < f.count && (i+1)*size <= uint32(len(f.data)); i++ {
+		switch size {
+		case 1:
+			values = append(values, uint32(f.data[i]))
+		case 2:
+			values = append(values, uint32(order.Uint16(f.data[2*i:])))
+		default:
+			values = append(values, order.Uint32(f.data[4*i:]))
+		}
+	}
+	return values
+}
+
+// convert returns a copy of the field values converted from one byte order to another.
+func (f *tiffField) convert(from, to binary.ByteOrder) []byte {
+	data := append([]byte(nil), f.data...)
+	if from == to {
+		return data
+	}
+	size := tiffTypeSizes[f.typ]
+	if f.typ == tiffRational || f.typ == tiffSRational {
+		size = 4
+	}
+	for i := 0; i+int(size) <= len(data); i += int(size) {
+		switch size {
+		case 2:
+			to.PutUint16(data[i:], from.Uint16(data[i:]))
+		case 4:
+			to.PutUint32(data[i:], from.Uint32(data[i:]))
+		case 8:
+			to.PutUint64(data[i:], from.Uint64(data[i:]))
+		}
+	}
+	return data
+}
+
+// readTIFFHeader reads the TIFF header and returns the byte order and the offset of the first directory.
+func readTIFFHeader(data []byte) (binary.ByteOrder, uint32, error) {
+	if len(data) < 8 {
+		return nil, 0, errInvalidTIFF
+	}
+	var order binary.ByteOrder
+	switch string(data[0:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, 0, errInvalidTIFF
+	}
+	if order.Uint16(data[2:4]) != 42 {
+		return nil, 0, errInvalidTIFF
+	}
+	return order, order.Uint32(data[4:8]), nil
+}
+
+// tiffReader reads a TIFF or a BigTIFF structure through an io.ReaderAt, so only
+// the directories and the data in use are read from large files.
+type tiffReader struct {
+	r io.ReaderAt
+	// order is the byte order of the structure.
+	order binary.ByteOrder
+	// big is true for BigTIFF, which has 8-byte offsets, counts and inline values.
+	big bool
+	// offsets holds the offsets of the chain of image file directories.
+	offsets []uint64
+}
+
+// tiffBlocks holds the offsets and the sizes of the blobs referred to by a blob tag.
+type tiffBlocks struct {
+	offsets, counts []uint64
+}
+
+// newTIFFReader reads the header and the chain of image file directories.
+func newTIFFReader(r io.ReaderAt) (*tiffReader, error) {
+	t := &tiffReader{r: r}
+	hdr, err := t.readAt(0, 8)
+	if err != nil {
+		return nil, err
+	}
+	switch string(hdr[0:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return nil, errInvalidTIFF
+	}
+	var offset uint64
+	switch t.order.Uint16(hdr[2:4]) {
+	case 42:
+		offset = uint64(t.order.Uint32(hdr[4:8]))
+	case 43:
+		// The BigTIFF header holds the size of the offsets, which is always 8,
+		// followed by the 8-byte offset of the first directory.
+		if t.order.Uint16(hdr[4:6]) != 8 || t.order.Uint16(hdr[6:8]) != 0 {
+			return nil, errInvalidTIFF
+		}
+		b, err := t.readAt(8, 8)
+		if err != nil {
+			return nil, err
+		}
+		t.big = true
+		offset = t.order.Uint64(b)
+	default:
+		return nil, errInvalidTIFF
+	}
+
+	countSize, entrySize, valueSize := t.sizes()
+	visited := map[uint64]bool{}
+	for offset != 0 {
+		if visited[offset] {
+			return nil, errInvalidTIFF
+		}
+		visited[offset] = true
+		t.offsets = append(t.offsets, offset)
+		n, err := t.entries(offset)
+		if err != nil {
+			return nil, err
+		}
+		b, err := t.readAt(offset+countSize+entrySize*n, valueSize)
+		if err != nil {
+			return nil, err
+		}
+		offset = t.uint(b)
+	}
+	if len(t.offsets) == 0 {
+		return nil, errInvalidTIFF
+	}
+	return t, nil
+}
+
+// sizes returns the sizes of the entry count, of an entry and of the offsets
+// (and the inline values) of the directories.
+func (t *tiffReader) sizes() (countSize, entrySize, valueSize uint64) {
+	if t.big {
+		return 8, 20, 8
+	}
+	return 2, 12, 4
+}
+
+// uint decodes an entry count, a value count or an offset.
+func (t *tiffReader) uint(b []byte) uint64 {
+	switch len(b) {
+	case 2:
+		return uint64(t.order.Uint16(b))
+	case 4:
+		return uint64(t.order.Uint32(b))
+	default:
+		return t.order.Uint64(b)
+	}
+}
+
+// entries returns the number of entries of the directory at offset.
+func (t *tiffReader) entries(offset uint64) (uint64, error) {
+	countSize, _, _ := t.sizes()
+	b, err := t.readAt(offset, countSize)
+	if err != nil {
+		return 0, err
+	}
+	n := t.uint(b)
+	if n > math.MaxUint16 {
+		return 0, errInvalidTIFF
+	}
+	return n, nil
+}
+
+// readAt reads n bytes at offset off. Large reads grow the buffer as the data
+// arrives, so broken sizes don't allocate much more memory than the file has.
+func (t *tiffReader) readAt(off, n uint64) ([]byte, error) {
+	if off > math.MaxInt64 || n > math.MaxInt64-off {
+		return nil, errInvalidTIFF
+	}
+	if n > 1<<20 {
+		buf, err := io.ReadAll(io.NewSectionReader(t.r, int64(off), int64(n)))
+		if err != nil {
+			return nil, err
+		}
+		if uint64(len(buf)) != n {
+			return nil, errInvalidTIFF
+		}
+		return buf, nil
+	}
+	buf := make([]byte, n)
+	m, err := t.r.ReadAt(buf, int64(off))
+	if m == len(buf) {
+		return buf, nil
+	}
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return nil, errInvalidTIFF
+}
+
+// readDir reads the image file directory at offset along with its sub-directories.
+// The fields of the 64-bit BigTIFF types are converted to the classic types. The
+// blobs are not read, their locations are returned instead.
+func (t *tiffReader) readDir(offset uint64, depth int) (*tiffDir, map[uint16]tiffBlocks, error) {
+	if depth > 2 {
+		return nil, nil, errInvalidTIFF
+	}
+	n, err := t.entries(offset)
+	if err != nil {
+		return nil, nil, err
+	}
+	countSize, entrySize, valueSize := t.sizes()
+	entries, err := t.readAt(offset+countSize, entrySize*n)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	d := newTIFFDir(t.order)
+	for i := uint64(0); i < n; i++ {
+		e := entries[entrySize*i : entrySize*(i+1)]
+		f := tiffField{
+			tag: t.order.Uint16(e[0:2]),
+			typ: t.order.Uint16(e[2:4]),
+		}
+		count, value := t.uint(e[4:4+valueSize]), e[4+valueSize:]
+		size, ok := tiffTypeSizes[f.typ]
+		if !ok {
+			// Skip unknown field types, their size can not be determined.
+			continue
+		}
+		if count > math.MaxUint32 {
+			return nil, nil, errInvalidTIFF
+		}
+		f.count = uint32(count)
+		total := uint64(size) * count
+		if total <= valueSize {
+			f.data = append([]byte(nil), value[:total]...)
+		} else if f.data, err = t.readAt(t.uint(value), total); err != nil {
+			return nil, nil, err
+		}
+		d.fields = append(d.fields, f)
+	}
+
+	blocks := map[uint16]tiffBlocks{}
+	for offTag, lenTag := range tiffBlobTags {
+		b := tiffBlocks{offsets: d.uint64s(offTag), counts: d.uint64s(lenTag)}
+		if len(b.offsets) > 0 && len(b.offsets) == len(b.counts) {
+			blocks[offTag] = b
+		}
+	}
+	subOffsets := map[uint16]uint64{}
+	for _, tag := range tiffSubDirTags {
+		if v := d.uint64s(tag); len(v) > 0 && v[0] != 0 {
+			subOffsets[tag] = v[0]
+		}
+	}
+	d.toClassic()
+
+	for _, tag := range tiffSubDirTags {
+		off, ok := subOffsets[tag]
+		if !ok {
+			continue
+		}
+		sub, err := t.readDirBlobs(off, depth+1)
+		if err != nil {
+			// A broken sub-directory is dropped rather than failing the whole structure.
+			d.remove(tag)
+			continue
+		}
+		d.subs[tag] = sub
+	}
+	return d, blocks, nil
+}
+
+// readDirBlobs reads the image file directory at offset along with its blobs.
+func (t *tiffReader) readDirBlobs(offset uint64, depth int) (*tiffDir, error) {
+	d, blocks, err := t.readDir(offset, depth)
+	if err != nil {
+		return nil, err
+	}
+	for tag, b := range blocks {
+		blobs := make([][]byte, len(b.offsets))
+		for i := range blobs {
+			if blobs[i], err = t.readBlob(b.offsets[i], b.counts[i]); err != nil {
+				return nil, err
+			}
+		}
+		d.blobs[tag] = blobs
+	}
+	return d, nil
+}
+
+// readBlob reads the blob of n bytes at offset off. The blobs of in-memory
+// structures refer to the data rather than copy it.
+func (t *tiffReader) readBlob(off, n uint64) ([]byte, error) {
+	if data, ok := t.r.(tiffBytes); ok {
+		if off > uint64(len(data)) || n > uint64(len(data))-off {
+			return nil, errInvalidTIFF
+		}
+		return data[off : off+n], nil
+	}
+	return t.readAt(off, n)
+}
+
+// tiffBytes is an in-memory TIFF or BigTIFF structure.
+type tiffBytes []byte
+
+// ReadAt implements io.ReaderAt interface.
+func (b tiffBytes) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errInvalidTIFF
+	}
+	if off >= int64(len(b)) {
+		return 0, io.EOF
+	}
+	n := copy(p, b[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// uint64s returns the values of an unsigned integer field, including the 64-bit
+// BigTIFF types, or nil.
+func (d *tiffDir) uint64s(tag uint16) []uint64 {
+	f := d.field(tag)
+	if f == nil {
+		return nil
+	}
+	if f.typ != tiffLong8 && f.typ != tiffIFD8 {
+		values := make([]uint64, 0, f.count)
+		for _, v := range f.uints(d.order) {
+			values = append(values, uint64(v))
+		}
+		return values
+	}
+	values := make([]uint64, 0, f.count)
+	for i := 0; i+8 <= len(f.data); i += 8 {
+		values = append(values, d.order.Uint64(f.data[i:]))
+	}
+	return values
+}
+
+// toClassic converts the fields of the 64-bit BigTIFF types to the classic types.
+// The fields with values that don't fit in 32 bits are dropped.
+func (d *tiffDir) toClassic() {
+	fields := d.fields[:0]
+	for _, f := range d.fields {
+		switch f.typ {
+		case tiffLong8, tiffIFD8, tiffSLong8:
+			data := make([]byte, len(f.data)/2)
+			fits := true
+			for i := 0; i+8 <= len(f.data); i += 8 {
+				v := d.order.Uint64(f.data[i:])
+				if f.typ == tiffSLong8 {
+					fits = fits && int64(v) >= math.MinInt32 && int64(v) <= math.MaxInt32
+				} else {
+					fits = fits && v <= math.MaxUint32
+				}
+				d.order.PutUint32(data[i/2:], uint32(v))
+			}
+			if !fits {
+				continue
+			}
+			f.typ = tiffClassicTypes[f.typ]
+			f.data = data
+		}
+		fields = append(fields, f)
+	}
+	d.fields = fields
+}
+
+// parseTIFF parses the chain of image file directories of the TIFF or BigTIFF structure in data.
+func parseTIFF(data []byte) ([]*tiffDir, error) {
+	t, err := newTIFFReader(tiffBytes(data))
+	if err != nil {
+		return nil, err
+	}
+	dirs := make([]*tiffDir, 0, len(t.offsets))
+	for _, offset := range t.offsets {
+		d, err := t.readDirBlobs(offset, 0)
+		if err != nil {
+			return nil, err
+		}
+		dirs = append(dirs, d)
+	}
+	return dirs, nil
+}
+
+// tiffLayout is a TIFF structure laid out for writing. The blobs are referred
+// to rather than copied.
+type tiffLayout struct {
+	// order is the byte order of the structure.
+	order binary.ByteOrder
+	// big is true for BigTIFF.
+	big bool
+	// chunks are the parts of the structure in the order they are written.
+	chunks [][]byte
+	// size is the total size of the chunks.
+	size uint64
+}
+
+// layoutTIFF lays out the directories as a TIFF structure, or as BigTIFF if big is true.
+func layoutTIFF(order binary.ByteOrder, dirs []*tiffDir, big bool) *tiffLayout {
+	l := &tiffLayout{order: order, big: big}
+	hdr := make([]byte, 8, 16)
+	if order == binary.BigEndian {
+		copy(hdr, "MM")
+	} else {
+		copy(hdr, "II")
+	}
+	if big {
+		hdr = hdr[:16]
+		order.PutUint16(hdr[2:], 43)
+		order.PutUint16(hdr[4:], 8)
+		order.PutUint64(hdr[8:], 16)
+	} else {
+		order.PutUint16(hdr[2:], 42)
+		order.PutUint32(hdr[4:], 8)
+	}
+	l.add(hdr)
+
+	var next []byte
+	for _, d := range dirs {
+		if next != nil {
+			l.putUint(next, l.size)
+		}
+		next = d.layout(l)
+	}
+	return l
+}
+
+// add appends the chunk to the layout, padding it to a word boundary.
+func (l *tiffLayout) add(b []byte) {
+	l.chunks = append(l.chunks, b)
+	l.size += uint64(len(b))
+	if len(b)%2 == 1 {
+		l.chunks = append(l.chunks, []byte{0})
+		l.size++
+	}
+}
+
+// putUint stores a value count or an offset.
+func (l *tiffLayout) putUint(b []byte, v uint64) {
+	if l.big {
+		l.order.PutUint64(b, v)
+	} else {
+		l.order.PutUint32(b, uint32(v))
+	}
+}
+
+// write writes the structure to w.
+func (l *tiffLayout) write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, c := range l.chunks {
+		if _, err := bw.Write(c); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// writeTIFF writes the directories as a TIFF structure using the given byte order.
+// Structures too large for the 32-bit offsets of TIFF are written as BigTIFF.
+func writeTIFF(w io.Writer, order binary.ByteOrder, dirs []*tiffDir) error {
+	l := layoutTIFF(order, dirs, false)
+	if l.size > tiffMaxClassicSize {
+		l = layoutTIFF(order, dirs, true)
+	}
+	return l.write(w)
+}
+
+// writeBigTIFF writes the directories as a BigTIFF structure using the given byte order.
+func writeBigTIFF(w io.Writer, order binary.ByteOrder, dirs []*tiffDir) error {
+	return layoutTIFF(order, dirs, true).write(w)
+}
+
+// layout appends the directory and the data it refers to to the layout.
+// It returns the bytes of the layout holding the offset of the next directory.
+func (d *tiffDir) layout(l *tiffLayout) []byte {
+	order := l.order
+	offsetType, countSize, entrySize, valueSize := uint16(tiffLong), 2, 12, 4
+	if l.big {
+		offsetType, countSize, entrySize, valueSize = tiffLong8, 8, 20, 8
+	}
+
+	// Copy the fields converting them to the output byte order and
+	// regenerate the fields that refer to blobs and sub-directories.
+	out := newTIFFDir(order)
+	for i := range d.fields {
+		f := &d.fields[i]
+		out.set(f.tag, f.typ, f.count, f.convert(d.order, order))
+	}
+	for offTag, blobs := range d.blobs {
+		out.setUints(offTag, offsetType, make([]uint32, len(blobs))...)
+		out.setUints(tiffBlobTags[offTag], offsetType, make([]uint32, len(blobs))...)
+	}
+	for tag := range d.subs {
+		out.setUints(tag, offsetType, 0)
+	}
+	sort.Slice(out.fields, func(i, j int) bool { return out.fields[i].tag < out.fields[j].tag })
+
+	n := len(out.fields)
+	dir := make([]byte, countSize+entrySize*n+valueSize)
+	l.add(dir)
+
+	// Out-of-line field values. The data of the fields is moved into the
+	// layout, so the regenerated fields can be filled in once the blobs and
+	// sub-directories are placed.
+	valueOffsets := make([]uint64, n)
+	var values []byte
+	for i := range out.fields {
+		if f := &out.fields[i]; len(f.data) > valueSize {
+			valueOffsets[i] = l.size + uint64(len(values))
+			values = append(values, f.data...)
+			if len(values)%2 == 1 {
+				values = append(values, 0)
+			}
+		}
+	}
+	for i := range out.fields {
+		if f := &out.fields[i]; len(f.data) > valueSize {
+			start := valueOffsets[i] - l.size
+			f.data = values[start : start+uint64(len(f.data))]
+		}
+	}
+	l.add(values)
+	fill := func(tag uint16, i int, v uint64) {
+		f := out.field(tag)
+		l.putUint(f.data[valueSize*i:], v)
+	}
+
+	subTags := make([]uint16, 0, len(d.subs))
+	for tag := range d.subs {
+		subTags = append(subTags, tag)
+	}
+	sort.Slice(subTags, func(i, j int) bool { return subTags[i] < subTags[j] })
+	for _, tag := range subTags {
+		fill(tag, 0, l.size)
+		d.subs[tag].layout(l)
+	}
+
+	blobTags := make([]uint16, 0, len(d.blobs))
+	for tag := range d.blobs {
+		blobTags = append(blobTags, tag)
+	}
+	sort.Slice(blobTags, func(i, j int) bool { return blobTags[i] < blobTags[j] })
+	for _, tag := range blobTags {
+		for i, b := range d.blobs[tag] {
+			fill(tag, i, l.size)
+			fill(tiffBlobTags[tag], i, uint64(len(b)))
+			l.add(b)
+		}
+	}
+
+	if l.big {
+		order.PutUint64(dir, uint64(n))
+	} else {
+		order.PutUint16(dir, uint16(n))
+	}
+	for i := range out.fields {
+		f := &out.fields[i]
+		e := dir[countSize+entrySize*i : countSize+entrySize*(i+1)]
+		order.PutUint16(e[0:], f.tag)
+		order.PutUint16(e[2:], f.typ)
+		l.putUint(e[4:], uint64(f.count))
+		if len(f.data) > valueSize {
+			l.putUint(e[4+valueSize:], valueOffsets[i])
+		} else {
+			copy(e[4+valueSize:], f.data)
+		}
+	}
+	return dir[len(dir)-valueSize:]
+}
@@ -0,0 +1,82 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func TestTIFFDirRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		dir := newTIFFDir(order)
+		dir.setUints(tagImageWidth, tiffShort, 3)
+		dir.setUints(tagBitsPerSample, tiffShort, 8, 8, 8)
+		dir.set(270, tiffASCII, 12, []byte("hello world\x00"))
+		dir.blobs[tagStripOffsets] = [][]byte{{1, 2, 3}, {4, 5, 6, 7}}
+		exif := newTIFFDir(order)
+		exif.set(0x9003, tiffASCII, 20, []byte("2023:10:20 10:20:30\x00"))
+		dir.subs[tagExifIFD] = exif
+		second := newTIFFDir(order)
+		second.setUints(tagImageWidth, tiffLong, 70000)
+
+		// Write the directories in the opposite byte order to exercise the conversion.
+		outOrder := binary.ByteOrder(binary.BigEndian)
+		if order == binary.BigEndian {
+			outOrder = binary.LittleEndian
+		}
+		buf := &bytes.Buffer{}
+		if err := writeTIFF(buf, outOrder, []*tiffDir{dir, second}); err != nil {
+			t.Fatalf("failed to write TIFF: %v", err)
+		}
+
+		dirs, err := parseTIFF(buf.Bytes())
+		if err != nil {
+			t.Fatalf("failed to parse TIFF: %v", err)
+		}
+		if len(dirs) != 2 {
+			t.Fatalf("got %d directories want 2", len(dirs))
+		}
+		got := dirs[0]
+		if w := got.uint(tagImageWidth); w != 3 {
+			t.Fatalf("got width %d want 3", w)
+		}
+		if bps := got.uints(tagBitsPerSample); len(bps) != 3 || bps[2] != 8 {
+			t.Fatalf("got bits per sample %v want [8 8 8]", bps)
+		}
+		if f := got.field(270); f == nil || string(f.data) != "hello world\x00" {
+			t.Fatalf("got description field %v", f)
+		}
+		strips := got.blobs[tagStripOffsets]
+		if len(strips) != 2 || !bytes.Equal(strips[0], []byte{1, 2, 3}) || !bytes.Equal(strips[1], []byte{4, 5, 6, 7}) {
+			t.Fatalf("got strips %v", strips)
+		}
+		sub := got.subs[tagExifIFD]
+		if sub == nil || sub.field(0x9003) == nil || string(sub.field(0x9003).data) != "2023:10:20 10:20:30\x00" {
+			t.Fatalf("got EXIF sub-directory %v", sub)
+		}
+		if w := dirs[1].uint(tagImageWidth); w != 70000 {
+			t.Fatalf("got second directory width %d want 70000", w)
+		}
+	}
+}
+
+func TestParseTIFFInvalid(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string][]byte{
+		"empty":          {},
+		"bad byte order": []byte("XX\x2a\x00\x08\x00\x00\x00"),
+		"bad magic":      []byte("II\x2b\x00\x08\x00\x00\x00"),
+		"truncated dir":  []byte("II\x2a\x00\x08\x00\x00\x00\x05\x00"),
+		"no dirs":        []byte("II\x2a\x00\x00\x00\x00\x00"),
+		"loop":           []byte("II\x2a\x00\x08\x00\x00\x00\x00\x00\x08\x00\x00\x00"),
+	}
+	for name, data := range testCases {
+		if _, err := parseTIFF(data); !errors.Is(err, errInvalidTIFF) {
+			t.Fatalf("%s: got error %v want errInvalidTIFF", name, err)
+		}
+	}
+}
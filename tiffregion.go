@@ -0,0 +1,330 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+
+	"golang.org/x/image/tiff"
+)
+
+// ErrTIFFPage means the requested page is not in the TIFF file.
+var ErrTIFFPage = errors.New("imaging: TIFF page not found")
+
+// DecodeTIFFRegion decodes a region of a page of the TIFF or BigTIFF image read from r.
+// Only the strips or tiles that intersect the region are read and decoded, so large
+// tiled images, such as the GeoTIFF rasters of GIS and the whole-slide images of digital
+// pathology, can be read piece by piece. The region is in the pixel coordinates of the
+// page and is clipped to the page bounds, the bounds of the returned image start at (0, 0).
+// Pages are numbered from 0, a page not in the file returns ErrTIFFPage. The orientation
+// tag is not applied.
+//
+// Example:
+//
+//	// Read a 1024x1024 region of the full resolution page of a slide.
+//	img, err := imaging.DecodeTIFFRegion(f, 0, image.Rect(40960, 20480, 41984, 21504))
+func DecodeTIFFRegion(r io.ReaderAt, page int, rect image.Rectangle) (image.Image, error) {
+	t, err := newTIFFReader(r)
+	if err != nil {
+		return nil, err
+	}
+	if page < 0 || page >= len(t.offsets) {
+		return nil, fmt.Errorf("%w: page %d of %d", ErrTIFFPage, page, len(t.offsets))
+	}
+	p, err := t.page(page)
+	if err != nil {
+		return nil, err
+	}
+	return t.decodeRegion(p, rect)
+}
+
+// OpenTIFFRegion opens the TIFF or BigTIFF file and decodes a region of the page like
+// DecodeTIFFRegion. The files of the storages without random access are read whole.
+func OpenTIFFRegion(filename string, page int, rect image.Rectangle) (img image.Image, err error) {
+	file, err := openFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			if err == nil {
+				err = closeErr
+			} else {
+				err = fmt.Errorf("original error: %s, defer close error: %w", err.Error(), closeErr)
+			}
+		}
+	}()
+	r, ok := file.(io.ReaderAt)
+	if !ok {
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return nil, err
+		}
+		r = tiffBytes(data)
+	}
+	return DecodeTIFFRegion(r, page, rect)
+}
+
+// isBigTIFF reports whether data starts with a BigTIFF header.
+func isBigTIFF(data []byte) bool {
+	return len(data) >= 4 && (string(data[:4]) == "II+\x00" || string(data[:4]) == "MM\x00+")
+}
+
+// decodeBigTIFFConfig decodes the color model and the dimensions of the first page
+// of a BigTIFF image. The data is only read up to the end of the directory.
+func decodeBigTIFFConfig(r io.Reader) (image.Config, error) {
+	t, err := newTIFFReader(&tiffStream{r: r})
+	if err != nil {
+		return image.Config{}, err
+	}
+	return t.config(0)
+}
+
+// tiffStream is an io.ReaderAt over a stream, which is read as far as needed.
+type tiffStream struct {
+	r io.Reader
+	// data holds the data read from the stream.
+	data bytes.Buffer
+	// err is the error that ended the stream.
+	err error
+}
+
+// ReadAt implements io.ReaderAt interface.
+func (s *tiffStream) ReadAt(p []byte, off int64) (int, error) {
+	if need := off + int64(len(p)) - int64(s.data.Len()); need > 0 && s.err == nil {
+		if _, err := io.CopyN(&s.data, s.r, need); err != nil {
+			s.err = err
+		}
+	}
+	if s.err != nil && s.err != io.EOF {
+		return 0, s.err
+	}
+	return tiffBytes(s.data.Bytes()).ReadAt(p, off)
+}
+
+// tiffPage is a page of a TIFF structure prepared for decoding by blocks.
+type tiffPage struct {
+	// dir holds the fields of the page without the blocks and the sub-directories.
+	dir *tiffDir
+	// tag is the blob tag of the blocks, tagStripOffsets or tagTileOffsets.
+	tag uint16
+	// blocks holds the locations of the strips or tiles in row-major order.
+	blocks tiffBlocks
+	// width and height are the size of the page.
+	width, height int
+	// blockWidth and blockHeight are the size of the blocks, across is the number of blocks in a row.
+	blockWidth, blockHeight, across int
+}
+
+// page reads the directory of the page and the locations of its blocks.
+func (t *tiffReader) page(i int) (*tiffPage, error) {
+	d, blocks, err := t.readDir(t.offsets[i], 0)
+	if err != nil {
+		return nil, err
+	}
+	p := &tiffPage{
+		dir:         d,
+		tag:         tagStripOffsets,
+		width:       int(d.uint(tagImageWidth)),
+		height:      int(d.uint(tagImageLength)),
+		blockHeight: int(d.uint(tagRowsPerStrip)),
+	}
+	p.blockWidth = p.width
+	if p.blockHeight <= 0 || p.blockHeight > p.height {
+		p.blockHeight = p.height
+	}
+	if tw := int(d.uint(tagTileWidth)); tw != 0 {
+		p.tag = tagTileOffsets
+		p.blockWidth, p.blockHeight = tw, int(d.uint(tagTileLength))
+	}
+	if p.width > 0 && p.height > 0 {
+		if p.blockWidth <= 0 || p.blockHeight <= 0 {
+			return nil, errInvalidTIFF
+		}
+		p.across = (p.width + p.blockWidth - 1) / p.blockWidth
+		down := (p.height + p.blockHeight - 1) / p.blockHeight
+		p.blocks = blocks[p.tag]
+		if uint64(len(p.blocks.offsets)) < uint64(p.across)*uint64(down) {
+			return nil, errInvalidTIFF
+		}
+	}
+
+	for offTag, lenTag := range tiffBlobTags {
+		d.remove(offTag)
+		d.remove(lenTag)
+	}
+	for _, tag := range tiffSubDirTags {
+		d.remove(tag)
+	}
+	return p, nil
+}
+
+// classic returns the page as a classic TIFF structure of the given size holding the blocks.
+func (p *tiffPage) classic(width, height int, blobs [][]byte) []byte {
+	d := newTIFFDir(p.dir.order)
+	d.fields = append(d.fields, p.dir.fields...)
+	d.setUints(tagImageWidth, tiffLong, uint32(width))
+	d.setUints(tagImageLength, tiffLong, uint32(height))
+	d.blobs[p.tag] = blobs
+	buf := &bytes.Buffer{}
+	// Writing to a bytes.Buffer doesn't fail.
+	_ = layoutTIFF(d.order, []*tiffDir{d}, false).write(buf)
+	return buf.Bytes()
+}
+
+// config returns the color model and the dimensions of the page.
+func (t *tiffReader) config(page int) (image.Config, error) {
+	p, err := t.page(page)
+	if err != nil {
+		return image.Config{}, err
+	}
+	// The tiff package only reads the directory for the config, the blocks are left empty.
+	return tiff.DecodeConfig(bytes.NewReader(p.classic(p.width, p.height, make([][]byte, len(p.blocks.offsets)))))
+}
+
+// decodePage decodes the whole page.
+func (t *tiffReader) decodePage(p *tiffPage) (image.Image, error) {
+	return t.decodeRegion(p, image.Rect(0, 0, p.width, p.height))
+}
+
+// decodeRegion decodes the part of the page within rect. The blocks intersecting
+// the region are decoded by the tiff package as classic TIFF structures. The tiles
+// are decoded one by one at their full size, as the tiff package only skips the
+// padding of the edge tiles for a few image types, and the strips in bands of rows
+// as large as fit in the 32-bit offsets of a single structure.
+func (t *tiffReader) decodeRegion(p *tiffPage, rect image.Rectangle) (image.Image, error) {
+	rect = rect.Intersect(image.Rect(0, 0, p.width, p.height))
+	if rect.Empty() {
+		return &image.NRGBA{}, nil
+	}
+	bx0, bx1 := rect.Min.X/p.blockWidth, (rect.Max.X+p.blockWidth-1)/p.blockWidth
+	by0, by1 := rect.Min.Y/p.blockHeight, (rect.Max.Y+p.blockHeight-1)/p.blockHeight
+
+	var dst draw.Image
+	put := func(origin image.Point, block image.Image) {
+		if dst == nil {
+			dst = newImageLike(block, rect.Size())
+		}
+		r := rect.Intersect(block.Bounds().Add(origin))
+		copyPixels(dst, r.Min.Sub(rect.Min), block, r.Sub(origin))
+	}
+
+	if p.tag == tagTileOffsets {
+		for by := by0; by < by1; by++ {
+			for bx := bx0; bx < bx1; bx++ {
+				tile, err := t.decodeBlocks(p, p.blockWidth, p.blockHeight, by*p.across+bx, 1)
+				if err != nil {
+					return nil, err
+				}
+				put(image.Pt(bx*p.blockWidth, by*p.blockHeight), tile)
+			}
+		}
+		return dst, nil
+	}
+
+	// Each strip adds its size, its offset and byte count and a padding byte
+	// to the structure, the size of the other fields is measured once.
+	base := layoutTIFF(p.dir.order, []*tiffDir{p.dir}, false).size + 64
+	for y0 := by0; y0 < by1; {
+		size, y1 := base, y0
+		for y1 < by1 {
+			stripSize := p.blocks.counts[y1] + 9
+			if y1 > y0 && size+stripSize > tiffMaxClassicSize {
+				break
+			}
+			size += stripSize
+			y1++
+		}
+		origin := image.Pt(0, y0*p.blockHeight)
+		height := y1*p.blockHeight - origin.Y
+		if origin.Y+height > p.height {
+			height = p.height - origin.Y
+		}
+		band, err := t.decodeBlocks(p, p.width, height, y0, y1-y0)
+		if err != nil {
+			return nil, err
+		}
+		put(origin, band)
+		y0 = y1
+	}
+	return dst, nil
+}
+
+// decodeBlocks decodes n consecutive blocks of the page, starting at block i,
+// as a classic TIFF structure holding an image of the given size.
+func (t *tiffReader) decodeBlocks(p *tiffPage, width, height, i, n int) (image.Image, error) {
+	blobs := make([][]byte, 0, n)
+	for j := i; j < i+n; j++ {
+		b, err := t.readBlob(p.blocks.offsets[j], p.blocks.counts[j])
+		if err != nil {
+			return nil, err
+		}
+		blobs = append(blobs, b)
+	}
+	return tiff.Decode(bytes.NewReader(p.classic(width, height, blobs)))
+}
+
+// pixBuffer returns the pixel buffer of the image types decoded by the tiff package.
+func pixBuffer(img image.Image) (pix []uint8, stride, pixelSize int, ok bool) {
+	switch img := img.(type) {
+	case *image.Gray:
+		return img.Pix, img.Stride, 1, true
+	case *image.Gray16:
+		return img.Pix, img.Stride, 2, true
+	case *image.Paletted:
+		return img.Pix, img.Stride, 1, true
+	case *image.RGBA:
+		return img.Pix, img.Stride, 4, true
+	case *image.NRGBA:
+		return img.Pix, img.Stride, 4, true
+	case *image.RGBA64:
+		return img.Pix, img.Stride, 8, true
+	case *image.NRGBA64:
+		return img.Pix, img.Stride, 8, true
+	}
+	return nil, 0, 0, false
+}
+
+// newImageLike creates an image of the given size and the type of img. The types
+// not decoded by the tiff package result in *image.NRGBA.
+func newImageLike(img image.Image, size image.Point) draw.Image {
+	r := image.Rectangle{Max: size}
+	switch img := img.(type) {
+	case *image.Gray:
+		return image.NewGray(r)
+	case *image.Gray16:
+		return image.NewGray16(r)
+	case *image.Paletted:
+		return image.NewPaletted(r, img.Palette)
+	case *image.RGBA:
+		return image.NewRGBA(r)
+	case *image.RGBA64:
+		return image.NewRGBA64(r)
+	case *image.NRGBA64:
+		return image.NewNRGBA64(r)
+	}
+	return image.NewNRGBA(r)
+}
+
+// copyPixels copies the pixels of src within r, relative to its bounds, to dst at dp.
+// The pixels are copied as is if dst was created by newImageLike from an image
+// of the same type as src.
+func copyPixels(dst draw.Image, dp image.Point, src image.Image, r image.Rectangle) {
+	r = r.Add(src.Bounds().Min)
+	dstPix, dstStride, dstSize, dstOK := pixBuffer(dst)
+	srcPix, srcStride, srcSize, srcOK := pixBuffer(src)
+	if !dstOK || !srcOK || dstSize != srcSize {
+		draw.Draw(dst, image.Rectangle{Min: dp, Max: dp.Add(r.Size())}, src, r.Min, draw.Src)
+		return
+	}
+	sb := src.Bounds()
+	n := r.Dx() * srcSize
+	for y := 0; y < r.Dy(); y++ {
+		si := (r.Min.Y-sb.Min.Y+y)*srcStride + (r.Min.X-sb.Min.X)*srcSize
+		di := (dp.Y+y)*dstStride + dp.X*dstSize
+		copy(dstPix[di:di+n], srcPix[si:si+n])
+	}
+}
@@ -0,0 +1,145 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/image/tiff"
+)
+
+// countingReaderAt counts the bytes read through it.
+type countingReaderAt struct {
+	r io.ReaderAt
+	n int
+}
+
+func (c *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := c.r.ReadAt(p, off)
+	c.n += n
+	return n, err
+}
+
+func TestDecodeTIFFRegion(t *testing.T) {
+	t.Parallel()
+
+	src := makeNoiseNRGBA(150, 100, 3)
+	files := map[string][]EncodeOption{
+		"strips":        {TIFFCompression(tiff.LZW)},
+		"tiles":         {TIFFTileSize(32, 16)},
+		"BigTIFF strip": {TIFFBigTIFF(true)},
+		"BigTIFF tiles": {TIFFBigTIFF(true), TIFFTileSize(32, 16), TIFFPredictor(true)},
+	}
+	regions := []image.Rectangle{
+		image.Rect(0, 0, 150, 100),
+		image.Rect(10, 5, 60, 40),
+		image.Rect(31, 15, 33, 17),
+		image.Rect(140, 90, 200, 200),
+		image.Rect(-10, -10, 5, 5),
+	}
+
+	for name, opts := range files {
+		buf := &bytes.Buffer{}
+		if err := Encode(buf, src, TIFF, opts...); err != nil {
+			t.Fatalf("%s: failed to encode: %v", name, err)
+		}
+		for _, r := range regions {
+			got, err := DecodeTIFFRegion(bytes.NewReader(buf.Bytes()), 0, r)
+			if err != nil {
+				t.Fatalf("%s %v: failed to decode: %v", name, r, err)
+			}
+			if want := Crop(src, r); !compareNRGBA(Clone(got), want, 0) {
+				t.Fatalf("%s %v: got bounds %v want region of bounds %v", name, r, got.Bounds(), want.Rect)
+			}
+		}
+		got, err := DecodeTIFFRegion(bytes.NewReader(buf.Bytes()), 0, image.Rect(200, 200, 300, 300))
+		if err != nil || !got.Bounds().Empty() {
+			t.Fatalf("%s: got %v, %v for a region outside of the image", name, got.Bounds(), err)
+		}
+		for _, page := range []int{-1, 1} {
+			if _, err := DecodeTIFFRegion(bytes.NewReader(buf.Bytes()), page, image.Rect(0, 0, 10, 10)); !errors.Is(err, ErrTIFFPage) {
+				t.Fatalf("%s: page %d: got error %v want %v", name, page, err, ErrTIFFPage)
+			}
+		}
+	}
+}
+
+func TestDecodeTIFFRegionEdgeTiles(t *testing.T) {
+	t.Parallel()
+
+	for name, img := range makeTiledTIFFImages() {
+		for _, bigTIFF := range []bool{false, true} {
+			buf := &bytes.Buffer{}
+			if err := Encode(buf, img, TIFF, TIFFTileSize(32, 16), TIFFBigTIFF(bigTIFF)); err != nil {
+				t.Fatalf("%s: failed to encode: %v", name, err)
+			}
+			b := img.Bounds()
+			r := image.Rect(b.Dx()-20, b.Dy()-10, b.Dx(), b.Dy())
+			got, err := DecodeTIFFRegion(bytes.NewReader(buf.Bytes()), 0, r)
+			if err != nil {
+				t.Fatalf("%s (BigTIFF=%v): failed to decode: %v", name, bigTIFF, err)
+			}
+			want := img.(interface {
+				SubImage(r image.Rectangle) image.Image
+			}).SubImage(r)
+			if got.Bounds() != image.Rect(0, 0, 20, 10) || !sameTIFFImage(got, want) {
+				t.Fatalf("%s (BigTIFF=%v): decoded region differs", name, bigTIFF)
+			}
+			decoded, err := Decode(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("%s (BigTIFF=%v): failed to decode: %v", name, bigTIFF, err)
+			}
+			if !sameTIFFImage(decoded, img) {
+				t.Fatalf("%s (BigTIFF=%v): decoded image differs", name, bigTIFF)
+			}
+		}
+	}
+}
+
+func TestDecodeTIFFRegionReadsTiles(t *testing.T) {
+	t.Parallel()
+
+	src := makeNoiseNRGBA(512, 512, 4)
+	buf := &bytes.Buffer{}
+	if err := Encode(buf, src, TIFF, TIFFTileSize(64, 64), TIFFCompression(tiff.Uncompressed)); err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	r := &countingReaderAt{r: bytes.NewReader(buf.Bytes())}
+	rect := image.Rect(70, 70, 120, 120)
+	got, err := DecodeTIFFRegion(r, 0, rect)
+	if err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	if !compareNRGBA(Clone(got), Crop(src, rect), 0) {
+		t.Fatalf("decoded region differs")
+	}
+	// The region is within a single tile of the 64 tiles.
+	if max := buf.Len() / 32; r.n > max {
+		t.Fatalf("read %d bytes of %d want at most %d", r.n, buf.Len(), max)
+	}
+}
+
+func TestOpenTIFFRegion(t *testing.T) {
+	t.Parallel()
+
+	src := makeNoiseNRGBA(64, 48, 5)
+	name := filepath.Join(t.TempDir(), "big.tif")
+	if err := Save(src, name, TIFFBigTIFF(true), TIFFTileSize(16, 16)); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+	rect := image.Rect(8, 8, 40, 24)
+	got, err := OpenTIFFRegion(name, 0, rect)
+	if err != nil {
+		t.Fatalf("failed to open: %v", err)
+	}
+	if !compareNRGBA(Clone(got), Crop(src, rect), 0) {
+		t.Fatalf("decoded region differs")
+	}
+	if _, err := OpenTIFFRegion(filepath.Join(t.TempDir(), "missing.tif"), 0, rect); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("got error %v want %v", err, os.ErrNotExist)
+	}
+}
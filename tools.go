@@ -2,9 +2,12 @@ package imaging
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"image"
 	"image/color"
 	"math"
+	"strings"
 )
 
 // New creates a new image with the specified width and height, and fills it with the specified color.
@@ -39,6 +42,34 @@ func Clone(img image.Image) *image.NRGBA {
 	return dst
 }
 
+// ErrDestinationSize means the destination image of an Into function has the wrong size.
+var ErrDestinationSize = errors.New("imaging: wrong destination image size")
+
+// CloneInto is like Clone, but copies the image into dst, which must have the size of
+// the image, instead of allocating it, e.g. to convert the decoded video frames to
+// NRGBA reusing a buffer. It returns ErrDestinationSize if the sizes differ.
+//
+// Example:
+//
+//	err := imaging.CloneInto(buf, frame)
+func CloneInto(dst *image.NRGBA, img image.Image) error {
+	src := newScanner(img)
+	if dst.Rect.Dx() != src.w || dst.Rect.Dy() != src.h {
+		return fmt.Errorf("%w: %dx%d for the image of %dx%d", ErrDestinationSize, dst.Rect.Dx(), dst.Rect.Dy(), src.w, src.h)
+	}
+	if dst == img {
+		return nil
+	}
+	size := src.w * 4
+	parallel(0, src.h, func(ys <-chan int) {
+		for y := range ys {
+			i := y * dst.Stride
+			src.scan(0, y, src.w, y+1, dst.Pix[i:i+size])
+		}
+	})
+	return nil
+}
+
 // Anchor is the anchor point for image alignment.
 type Anchor int
 
@@ -55,6 +86,46 @@ const (
 	BottomRight
 )
 
+// ErrUnknownAnchor means there is no anchor point with the given name.
+var ErrUnknownAnchor = errors.New("imaging: unknown anchor")
+
+// anchorNames are the names of the anchor points indexed by their values.
+var anchorNames = []string{ //nolint
+	Center:      "center",
+	TopLeft:     "top-left",
+	Top:         "top",
+	TopRight:    "top-right",
+	Left:        "left",
+	Right:       "right",
+	BottomLeft:  "bottom-left",
+	Bottom:      "bottom",
+	BottomRight: "bottom-right",
+}
+
+// String returns the name of the anchor point, e.g. "top-left" for TopLeft.
+func (a Anchor) String() string {
+	if a >= 0 && int(a) < len(anchorNames) {
+		return anchorNames[a]
+	}
+	return fmt.Sprintf("Anchor(%d)", int(a))
+}
+
+// ParseAnchor returns the anchor point with the given name as returned by Anchor.String,
+// compared case-insensitively. For unknown names ErrUnknownAnchor is returned.
+func ParseAnchor(name string) (Anchor, error) {
+	for a, n := range anchorNames {
+		if strings.EqualFold(n, name) {
+			return Anchor(a), nil
+		}
+	}
+	return Center, fmt.Errorf("%w: %q", ErrUnknownAnchor, name)
+}
+
+// AnchorNames returns the names of all the anchor points accepted by ParseAnchor.
+func AnchorNames() []string {
+	return append([]string(nil), anchorNames...)
+}
+
 func anchorPt(b image.Rectangle, w, h int, anchor Anchor) image.Point {
 	var x, y int
 	switch anchor {
@@ -122,12 +193,193 @@ func CropAnchor(img image.Image, width, height int, anchor Anchor) *image.NRGBA
 	return Crop(img, b)
 }
 
+// CropPoint cuts out a rectangular region with the specified size from the image,
+// centered as close as possible to the focal point. The focal point is given in
+// coordinates normalized to the image size, from (0, 0) at the top left corner to
+// (1, 1) at the bottom right corner, so it doesn't depend on the image resolution.
+//
+// Example:
+//
+//	// Cut out a 400x400 region around the point at 70% of the width and 40% of the height.
+//	dstImage := imaging.CropPoint(srcImage, 400, 400, 0.7, 0.4)
+func CropPoint(img image.Image, width, height int, fx, fy float64) *image.NRGBA {
+	srcBounds := img.Bounds()
+	pt := image.Pt(
+		focalOffset(srcBounds.Min.X, srcBounds.Dx(), width, fx),
+		focalOffset(srcBounds.Min.Y, srcBounds.Dy(), height, fy),
+	)
+	r := image.Rect(0, 0, width, height).Add(pt)
+	b := srcBounds.Intersect(r)
+	return Crop(img, b)
+}
+
+// focalOffset returns the start of the window of the size centered as close as possible
+// to the normalized focal coordinate f along the image side starting at start. A window
+// larger than the side is centered on the side.
+func focalOffset(start, side, size int, f float64) int {
+	if size >= side {
+		return start + (side-size)/2
+	}
+	if math.IsNaN(f) {
+		f = 0.5
+	}
+	f = math.Min(math.Max(f, 0), 1)
+	off := int(math.Round(f*float64(side) - float64(size)/2))
+	if off < 0 {
+		off = 0
+	}
+	if off > side-size {
+		off = side - size
+	}
+	return start + off
+}
+
 // CropCenter cuts out a rectangular region with the specified size
 // from the center of the image and returns the cropped image.
 func CropCenter(img image.Image, width, height int) *image.NRGBA {
 	return CropAnchor(img, width, height, Center)
 }
 
+// CropCircle cuts out the circle with the specified center and radius from the image
+// and returns the cropped image of size 2*radius x 2*radius. The pixels outside of
+// the circle are transparent, with anti-aliased edges.
+//
+// Example:
+//
+//	// Make a round 256x256 avatar from the center of a photo.
+//	square := imaging.Fill(srcImage, 256, 256, imaging.Center, imaging.Lanczos)
+//	dstImage := imaging.CropCircle(square, image.Pt(128, 128), 128)
+func CropCircle(img image.Image, center image.Point, radius int) *image.NRGBA {
+	if radius <= 0 {
+		return &image.NRGBA{}
+	}
+	return CropEllipse(img, image.Rect(center.X-radius, center.Y-radius, center.X+radius, center.Y+radius))
+}
+
+// CropEllipse cuts out the ellipse inscribed in the rectangle from the image and returns
+// the cropped image of the size of the rectangle. The pixels outside of the ellipse, and
+// those of the rectangle outside of the image, are transparent, with anti-aliased edges.
+func CropEllipse(img image.Image, rect image.Rectangle) *image.NRGBA {
+	rect = rect.Canon()
+	w, h := rect.Dx(), rect.Dy()
+	if w == 0 || h == 0 {
+		return &image.NRGBA{}
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	b := img.Bounds()
+	if r := rect.Intersect(b); !r.Empty() {
+		src := newScanner(img)
+		rowSize := r.Dx() * 4
+		parallel(r.Min.Y, r.Max.Y, func(ys <-chan int) {
+			for y := range ys {
+				i := (y-rect.Min.Y)*dst.Stride + (r.Min.X-rect.Min.X)*4
+				src.scan(r.Min.X-b.Min.X, y-b.Min.Y, r.Max.X-b.Min.X, y-b.Min.Y+1, dst.Pix[i:i+rowSize])
+			}
+		})
+	}
+
+	a, c := float64(w)/2, float64(h)/2
+	parallel(0, h, func(ys <-chan int) {
+		for y := range ys {
+			dy := float64(y) + 0.5 - c
+			for x := 0; x < w; x++ {
+				cov := ellipseCoverage(float64(x)+0.5-a, dy, a, c)
+				i := y*dst.Stride + x*4
+				d := dst.Pix[i : i+4 : i+4]
+				if cov <= 0 {
+					d[0], d[1], d[2], d[3] = 0, 0, 0, 0
+				} else if cov < 1 {
+					d[3] = clamp(float64(d[3]) * cov)
+				}
+			}
+		}
+	})
+	return dst
+}
+
+// ellipseCoverage returns the part of the pixel at the offset (dx, dy) from the center
+// covered by the ellipse with the semi-axes a and b. The distance to the edge is
+// approximated by the first order (the implicit function over its gradient).
+func ellipseCoverage(dx, dy, a, b float64) float64 {
+	f := dx*dx/(a*a) + dy*dy/(b*b) - 1
+	g := 2 * math.Sqrt(dx*dx/(a*a*a*a)+dy*dy/(b*b*b*b))
+	if g == 0 {
+		return 1
+	}
+	return math.Max(0, math.Min(1, 0.5-f/g))
+}
+
+// RoundCorners returns a copy of the image with the corners rounded to the radius.
+// The pixels outside of the rounded corners are transparent, with anti-aliased edges.
+//
+// Example:
+//
+//	// Make a UI card thumbnail with 12 pixel rounded corners.
+//	dstImage := imaging.RoundCorners(imaging.Thumbnail(srcImage, 320, 200, imaging.Lanczos), 12)
+func RoundCorners(img image.Image, radius int) *image.NRGBA {
+	return RoundCornersEach(img, radius, radius, radius, radius)
+}
+
+// RoundCornersEach is like RoundCorners, but takes the radii of the top left, top right,
+// bottom right and bottom left corners separately. A zero radius keeps the corner square.
+// Radii too large for the image are scaled down, as in CSS, so that the curves of
+// adjacent corners don't overlap.
+func RoundCornersEach(img image.Image, topLeft, topRight, bottomRight, bottomLeft int) *image.NRGBA {
+	dst := Clone(img)
+	w, h := float64(dst.Rect.Dx()), float64(dst.Rect.Dy())
+	r := [4]float64{float64(topLeft), float64(topRight), float64(bottomRight), float64(bottomLeft)}
+	for i := range r {
+		r[i] = math.Max(r[i], 0)
+	}
+	// Scale the radii down by the tightest side.
+	f := 1.0
+	for _, side := range [...]struct{ length, r1, r2 float64 }{
+		{w, r[0], r[1]}, {h, r[1], r[2]}, {w, r[3], r[2]}, {h, r[0], r[3]},
+	} {
+		if sum := side.r1 + side.r2; sum > side.length {
+			f = math.Min(f, side.length/sum)
+		}
+	}
+
+	// The corners are given by the pixel position of the curve center and the
+	// direction towards the corner.
+	corners := [4]struct{ cx, cy, sx, sy float64 }{
+		{0, 0, -1, -1}, {w, 0, 1, -1}, {w, h, 1, 1}, {0, h, -1, 1},
+	}
+	for i, c := range corners {
+		ri := r[i] * f
+		if ri <= 0 {
+			continue
+		}
+		// Move the center of the curve inside the image by the radius.
+		cx, cy := c.cx-c.sx*ri, c.cy-c.sy*ri
+		n := int(math.Ceil(ri))
+		x0, y0 := 0, 0
+		if c.sx > 0 {
+			x0 = dst.Rect.Dx() - n
+		}
+		if c.sy > 0 {
+			y0 = dst.Rect.Dy() - n
+		}
+		for y := y0; y < y0+n; y++ {
+			dy := (float64(y) + 0.5 - cy) * c.sy
+			if dy <= 0 {
+				continue
+			}
+			for x := x0; x < x0+n; x++ {
+				dx := (float64(x) + 0.5 - cx) * c.sx
+				if dx <= 0 {
+					continue
+				}
+				cov := math.Max(0, math.Min(1, ri-math.Hypot(dx, dy)+0.5))
+				a := &dst.Pix[y*dst.Stride+x*4+3]
+				*a = clamp(float64(*a) * cov)
+			}
+		}
+	}
+	return dst
+}
+
 // Paste pastes the img image to the background image at the specified position and returns the combined image.
 func Paste(background, img image.Image, pos image.Point) *image.NRGBA {
 	dst := Clone(background)
@@ -173,6 +425,83 @@ func PasteCenter(background, img image.Image) *image.NRGBA {
 	return Paste(background, img, image.Pt(x0, y0))
 }
 
+// Pad returns the image centered on a canvas of the specified width and height, e.g.
+// to make it square for the input of a model. The canvas around the image is filled
+// as the edge mode defines. The sides of the image larger than the canvas are cropped
+// at the center.
+//
+// Examples:
+//
+//	// Pad to a square with black bars.
+//	dstImage := imaging.Pad(srcImage, 512, 512, imaging.EdgeColor(color.Black))
+//
+//	// Pad a texture keeping it seamless.
+//	dstImage := imaging.Pad(srcImage, 1024, 1024, imaging.EdgeWrap)
+func Pad(img image.Image, width, height int, mode EdgeMode) *image.NRGBA {
+	b := img.Bounds()
+	return extend(img, width, height, image.Pt((width-b.Dx())/2, (height-b.Dy())/2), mode.resolve(img))
+}
+
+// Tile returns a canvas of the specified width and height filled by repeating the
+// image from its top-left corner, e.g. to generate a background pattern.
+//
+// Example:
+//
+//	dstImage := imaging.Tile(patternImage, 1920, 1080)
+func Tile(img image.Image, width, height int) *image.NRGBA {
+	return TileOffset(img, width, height, 0, 0)
+}
+
+// TileOffset is like Tile, but one of the tiles is placed at the offset on the canvas,
+// which may be negative, e.g. to scroll the pattern or to align it with other layers.
+func TileOffset(img image.Image, width, height, offsetX, offsetY int) *image.NRGBA {
+	return extend(img, width, height, image.Pt(offsetX, offsetY), EdgeWrap)
+}
+
+// extend returns a canvas of the specified width and height with the image placed at
+// the offset, the canvas around it filled as the edge mode defines.
+func extend(img image.Image, width, height int, offset image.Point, mode EdgeMode) *image.NRGBA {
+	if width <= 0 || height <= 0 {
+		return &image.NRGBA{}
+	}
+	src := Clone(img)
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	if sw == 0 || sh == 0 {
+		if mode.kind == edgeConstant {
+			return New(width, height, mode.fill())
+		}
+		return New(width, height, color.Transparent)
+	}
+
+	// The source offsets of the canvas columns, -1 for the filled ones.
+	ox, oy := offset.X, offset.Y
+	xs := make([]int, width)
+	for x := range xs {
+		if i, ok := mode.index(x-ox, sw); ok {
+			xs[x] = i * 4
+		} else {
+			xs[x] = -1
+		}
+	}
+	c := mode.fill()
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	parallel(0, height, func(ys <-chan int) {
+		for y := range ys {
+			row := dst.Pix[y*dst.Stride : y*dst.Stride+width*4]
+			sy, ok := mode.index(y-oy, sh)
+			for x, i := range xs {
+				d := row[x*4 : x*4+4 : x*4+4]
+				if !ok || i < 0 {
+					d[0], d[1], d[2], d[3] = c.R, c.G, c.B, c.A
+					continue
+				}
+				copy(d, src.Pix[sy*src.Stride+i:sy*src.Stride+i+4])
+			}
+		}
+	})
+	return dst
+}
+
 // Overlay draws the img image over the background image at given position
 // and returns the combined image. Opacity parameter is the opacity of the img
 // image layer, used to compose the images, it must be from 0.0 to 1.0.
@@ -254,3 +583,106 @@ func OverlayCenter(background, img image.Image, opacity float64) *image.NRGBA {
 
 	return Overlay(background, img, image.Point{x0, y0}, opacity)
 }
+
+// ExtractAlpha returns the alpha channel of the image as a grayscale image.
+// Opaque pixels are white and fully transparent pixels are black.
+//
+// Example:
+//
+//	// Soften the edges of a cutout.
+//	mask := imaging.Blur(imaging.ExtractAlpha(cutout), 2)
+//	dstImage := imaging.SetAlpha(cutout, mask)
+func ExtractAlpha(img image.Image) *image.Gray {
+	src := newScanner(img)
+	dst := image.NewGray(image.Rect(0, 0, src.w, src.h))
+	parallel(0, src.h, func(ys <-chan int) {
+		scanLine := make([]uint8, src.w*4)
+		for y := range ys {
+			src.scan(0, y, src.w, y+1, scanLine)
+			d := dst.Pix[y*dst.Stride : y*dst.Stride+src.w]
+			for x := range d {
+				d[x] = scanLine[x*4+3]
+			}
+		}
+	})
+	return dst
+}
+
+// SetAlpha returns a copy of the image with the alpha channel replaced by the mask.
+// The luminance of the mask pixels is used as the alpha value, so a mask returned
+// by ExtractAlpha (or any grayscale image) can be applied directly. The mask is
+// aligned with the top left corner of the image, pixels outside of the mask become
+// fully transparent.
+func SetAlpha(img, mask image.Image) *image.NRGBA {
+	dst := Clone(img)
+	m := newScanner(mask)
+	w, h := dst.Rect.Dx(), dst.Rect.Dy()
+	parallel(0, h, func(ys <-chan int) {
+		scanLine := make([]uint8, m.w*4)
+		for y := range ys {
+			d := dst.Pix[y*dst.Stride : y*dst.Stride+w*4]
+			mw := 0
+			if y < m.h {
+				mw = m.w
+				if mw > w {
+					mw = w
+				}
+				m.scan(0, y, mw, y+1, scanLine)
+			}
+			for x := 0; x < w; x++ {
+				if x >= mw {
+					d[x*4+3] = 0
+					continue
+				}
+				s := scanLine[x*4 : x*4+4 : x*4+4]
+				f := (0.299*float64(s[0]) + 0.587*float64(s[1]) + 0.114*float64(s[2])) * float64(s[3]) / 255
+				d[x*4+3] = uint8(f + 0.5)
+			}
+		}
+	})
+	return dst
+}
+
+// TrimTransparent crops the image to the bounding box of its non-transparent pixels
+// and surrounds it with a transparent margin of keepMargin pixels on every side.
+// The margin is the same regardless of the padding of the source image, so assets
+// exported with inconsistent padding are normalized. A fully transparent image
+// results in an empty image.
+//
+// Example:
+//
+//	// Normalize a logo to a 4 pixel margin, leaving room for a drop shadow.
+//	dstImage := imaging.TrimTransparent(srcImage, 4)
+func TrimTransparent(img image.Image, keepMargin int) *image.NRGBA {
+	src := toNRGBA(img)
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	box := image.Rectangle{}
+	for y := 0; y < h; y++ {
+		row := src.Pix[y*src.Stride : y*src.Stride+w*4]
+		x1, x2 := -1, -1
+		for x := 0; x < w; x++ {
+			if row[x*4+3] != 0 {
+				if x1 < 0 {
+					x1 = x
+				}
+				x2 = x + 1
+			}
+		}
+		if x1 >= 0 {
+			box = box.Union(image.Rect(x1, y, x2, y+1))
+		}
+	}
+	if box.Empty() {
+		return &image.NRGBA{}
+	}
+	if keepMargin < 0 {
+		keepMargin = 0
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, box.Dx()+2*keepMargin, box.Dy()+2*keepMargin))
+	for y := box.Min.Y; y < box.Max.Y; y++ {
+		i := (y-box.Min.Y+keepMargin)*dst.Stride + keepMargin*4
+		j := y*src.Stride + box.Min.X*4
+		copy(dst.Pix[i:i+box.Dx()*4], src.Pix[j:j+box.Dx()*4])
+	}
+	return dst
+}
@@ -2,8 +2,10 @@ package imaging
 
 import (
 	"bytes"
+	"errors"
 	"image"
 	"image/color"
+	"math"
 	"testing"
 )
 
@@ -329,6 +331,27 @@ func TestClone(t *testing.T) {
 	}
 }
 
+func TestCloneInto(t *testing.T) {
+	t.Parallel()
+
+	src := image.NewPaletted(image.Rect(-1, -1, 2, 1), color.Palette{color.Black, color.NRGBA{0xff, 0, 0, 0x80}})
+	src.Pix[1] = 1
+	buf := New(5, 4, color.White)
+	dst := buf.SubImage(image.Rect(1, 1, 4, 3)).(*image.NRGBA)
+	if err := CloneInto(dst, src); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !compareNRGBA(Clone(dst), Clone(src), 0) {
+		t.Fatalf("got result %#v want %#v", Clone(dst), Clone(src))
+	}
+	if c := buf.NRGBAAt(0, 0); c != (color.NRGBA{0xff, 0xff, 0xff, 0xff}) {
+		t.Fatalf("got color %v outside of the destination", c)
+	}
+	if err := CloneInto(New(3, 3, color.White), src); !errors.Is(err, ErrDestinationSize) {
+		t.Fatalf("got error %v want %v", err, ErrDestinationSize)
+	}
+}
+
 func TestCrop(t *testing.T) {
 	t.Parallel()
 
@@ -802,6 +825,62 @@ func TestCropAnchor(t *testing.T) {
 	}
 }
 
+func TestCropPoint(t *testing.T) {
+	t.Parallel()
+
+	src := makeNoiseNRGBA(10, 10, 1)
+	src.Rect = src.Rect.Add(image.Pt(10, 20))
+	testCases := []struct {
+		name   string
+		w, h   int
+		fx, fy float64
+		want   image.Rectangle
+	}{
+		{"center", 4, 4, 0.5, 0.5, image.Rect(3, 3, 7, 7)},
+		{"top left", 4, 4, 0, 0, image.Rect(0, 0, 4, 4)},
+		{"bottom right", 4, 4, 1, 1, image.Rect(6, 6, 10, 10)},
+		{"clamped", 4, 4, 0.25, 0.9, image.Rect(1, 6, 5, 10)},
+		{"out of range", 4, 2, -1, 2, image.Rect(0, 8, 4, 10)},
+		{"larger than image", 12, 4, 0.9, 0.1, image.Rect(0, 0, 10, 4)},
+		{"NaN", 2, 2, math.NaN(), 0.5, image.Rect(4, 4, 6, 6)},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got := CropPoint(src, tc.w, tc.h, tc.fx, tc.fy)
+			want := Crop(src, tc.want.Add(src.Rect.Min))
+			if !compareNRGBA(got, want, 0) {
+				t.Fatalf("got image of bounds %v want the region %v", got.Bounds(), tc.want)
+			}
+		})
+	}
+}
+
+func TestParseAnchor(t *testing.T) {
+	t.Parallel()
+
+	names := AnchorNames()
+	if len(names) != 9 {
+		t.Fatalf("got anchor names %v", names)
+	}
+	for _, name := range names {
+		a, err := ParseAnchor(name)
+		if err != nil || a.String() != name {
+			t.Fatalf("%s: got anchor %v, error %v", name, a, err)
+		}
+	}
+	if a, err := ParseAnchor("Bottom-Right"); err != nil || a != BottomRight {
+		t.Fatalf("got anchor %v, error %v", a, err)
+	}
+	if _, err := ParseAnchor("middle"); !errors.Is(err, ErrUnknownAnchor) {
+		t.Fatalf("got error %v want %v", err, ErrUnknownAnchor)
+	}
+	if s := Anchor(42).String(); s != "Anchor(42)" {
+		t.Fatalf("got %q for an invalid anchor", s)
+	}
+}
+
 func TestPaste(t *testing.T) {
 	t.Parallel()
 
@@ -1041,6 +1120,93 @@ func TestPasteCenter(t *testing.T) {
 	}
 }
 
+func TestPad(t *testing.T) {
+	t.Parallel()
+
+	src := &image.NRGBA{
+		Rect:   image.Rect(-1, -1, 1, 0),
+		Stride: 2 * 4,
+		Pix: []uint8{
+			0x01, 0x02, 0x03, 0xff, 0x04, 0x05, 0x06, 0xff,
+		},
+	}
+	a, b, c := []uint8{0x01, 0x02, 0x03, 0xff}, []uint8{0x04, 0x05, 0x06, 0xff}, []uint8{0xff, 0x00, 0x00, 0xff}
+	pixels := func(w, h int, pix ...[]uint8) *image.NRGBA {
+		return &image.NRGBA{Rect: image.Rect(0, 0, w, h), Stride: w * 4, Pix: bytes.Join(pix, nil)}
+	}
+	testCases := []struct {
+		name          string
+		width, height int
+		mode          EdgeMode
+		want          *image.NRGBA
+	}{
+		{"clamp", 5, 1, EdgeClamp, pixels(5, 1, a, a, b, b, b)},
+		{"reflect", 5, 1, EdgeReflect, pixels(5, 1, a, a, b, b, a)},
+		{"wrap", 5, 1, EdgeWrap, pixels(5, 1, b, a, b, a, b)},
+		{"color", 5, 1, EdgeColor(color.NRGBA{0xff, 0x00, 0x00, 0xff}), pixels(5, 1, c, a, b, c, c)},
+		{"height", 2, 3, EdgeColor(color.NRGBA{0xff, 0x00, 0x00, 0xff}), pixels(2, 3, c, c, a, b, c, c)},
+		{"crop", 1, 2, EdgeClamp, pixels(1, 2, a, a)},
+		{"empty canvas", 0, 2, EdgeClamp, &image.NRGBA{}},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got := Pad(src, tc.width, tc.height, tc.mode)
+			if !compareNRGBA(got, tc.want, 0) {
+				t.Fatalf("got result %#v want %#v", got, tc.want)
+			}
+		})
+	}
+
+	got := Pad(&image.NRGBA{}, 2, 1, EdgeColor(color.White))
+	if want := New(2, 1, color.White); !compareNRGBA(got, want, 0) {
+		t.Fatalf("got result %#v want %#v", got, want)
+	}
+}
+
+func TestTile(t *testing.T) {
+	t.Parallel()
+
+	src := &image.NRGBA{
+		Rect:   image.Rect(-1, -1, 1, 0),
+		Stride: 2 * 4,
+		Pix: []uint8{
+			0x01, 0x02, 0x03, 0xff, 0x04, 0x05, 0x06, 0xff,
+		},
+	}
+	a, b := []uint8{0x01, 0x02, 0x03, 0xff}, []uint8{0x04, 0x05, 0x06, 0xff}
+	pixels := func(w, h int, pix ...[]uint8) *image.NRGBA {
+		return &image.NRGBA{Rect: image.Rect(0, 0, w, h), Stride: w * 4, Pix: bytes.Join(pix, nil)}
+	}
+	testCases := []struct {
+		name          string
+		width, height int
+		offset        image.Point
+		want          *image.NRGBA
+	}{
+		{"tile", 3, 2, image.Pt(0, 0), pixels(3, 2, a, b, a, a, b, a)},
+		{"offset", 3, 1, image.Pt(1, 5), pixels(3, 1, b, a, b)},
+		{"negative offset", 3, 1, image.Pt(-3, 0), pixels(3, 1, b, a, b)},
+		{"empty canvas", 3, 0, image.Pt(0, 0), &image.NRGBA{}},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got := TileOffset(src, tc.width, tc.height, tc.offset.X, tc.offset.Y)
+			if !compareNRGBA(got, tc.want, 0) {
+				t.Fatalf("got result %#v want %#v", got, tc.want)
+			}
+			if tc.offset == (image.Point{}) {
+				if got := Tile(src, tc.width, tc.height); !compareNRGBA(got, tc.want, 0) {
+					t.Fatalf("Tile: got result %#v want %#v", got, tc.want)
+				}
+			}
+		})
+	}
+}
+
 func TestOverlay(t *testing.T) {
 	t.Parallel()
 
@@ -1211,3 +1377,296 @@ func TestOverlayCenter(t *testing.T) {
 		})
 	}
 }
+
+func TestExtractAlpha(t *testing.T) {
+	t.Parallel()
+
+	src := image.NewNRGBA(image.Rect(-1, -1, 2, 0))
+	src.Pix = []uint8{
+		0x10, 0x20, 0x30, 0x00,
+		0x10, 0x20, 0x30, 0x80,
+		0x10, 0x20, 0x30, 0xff,
+	}
+	got := ExtractAlpha(src)
+	want := &image.Gray{Rect: image.Rect(0, 0, 3, 1), Stride: 3, Pix: []uint8{0x00, 0x80, 0xff}}
+	if got.Rect != want.Rect || !bytes.Equal(got.Pix, want.Pix) {
+		t.Fatalf("got %#v want %#v", got, want)
+	}
+
+	if got := ExtractAlpha(image.NewGray(image.Rect(0, 0, 2, 2))); !bytes.Equal(got.Pix, []uint8{0xff, 0xff, 0xff, 0xff}) {
+		t.Fatalf("opaque image: got %v", got.Pix)
+	}
+}
+
+func TestSetAlpha(t *testing.T) {
+	t.Parallel()
+
+	src := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	src.Pix = []uint8{
+		0x10, 0x20, 0x30, 0xff, 0x40, 0x50, 0x60, 0xff, 0x70, 0x80, 0x90, 0xff,
+		0xa0, 0xb0, 0xc0, 0xff, 0xd0, 0xe0, 0xf0, 0xff, 0x01, 0x02, 0x03, 0xff,
+	}
+
+	testCases := []struct {
+		name string
+		mask image.Image
+		want []uint8
+	}{
+		{
+			name: "gray mask",
+			mask: &image.Gray{Rect: image.Rect(5, 5, 8, 7), Stride: 3, Pix: []uint8{0x00, 0x40, 0x80, 0xc0, 0xfe, 0xff}},
+			want: []uint8{0x00, 0x40, 0x80, 0xc0, 0xfe, 0xff},
+		},
+		{
+			name: "white color mask",
+			mask: New(3, 2, color.White),
+			want: []uint8{0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		},
+		{
+			name: "smaller mask",
+			mask: New(2, 1, color.White),
+			want: []uint8{0xff, 0xff, 0x00, 0x00, 0x00, 0x00},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got := SetAlpha(src, tc.mask)
+			for i, a := range tc.want {
+				if got.Pix[i*4+3] != a {
+					t.Fatalf("pixel %d: got alpha %#x want %#x", i, got.Pix[i*4+3], a)
+				}
+				if !bytes.Equal(got.Pix[i*4:i*4+3], src.Pix[i*4:i*4+3]) {
+					t.Fatalf("pixel %d: color changed", i)
+				}
+			}
+		})
+	}
+
+	// The mask extracted from an image restores its alpha channel.
+	img := makeNoiseNRGBA(7, 5, 4)
+	if got := SetAlpha(img, ExtractAlpha(img)); !compareNRGBA(got, img, 0) {
+		t.Fatalf("alpha round trip changed the image")
+	}
+}
+
+func TestTrimTransparent(t *testing.T) {
+	t.Parallel()
+
+	src := image.NewNRGBA(image.Rect(-2, -2, 4, 3))
+	src.SetNRGBA(0, -1, color.NRGBA{0xff, 0x00, 0x00, 0xff})
+	src.SetNRGBA(1, 1, color.NRGBA{0x00, 0xff, 0x00, 0x01})
+
+	testCases := []struct {
+		name   string
+		img    image.Image
+		margin int
+		want   *image.NRGBA
+	}{
+		{
+			name:   "no margin",
+			img:    src,
+			margin: 0,
+			want: &image.NRGBA{
+				Rect:   image.Rect(0, 0, 2, 3),
+				Stride: 8,
+				Pix: []uint8{
+					0xff, 0x00, 0x00, 0xff, 0x00, 0x00, 0x00, 0x00,
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+					0x00, 0x00, 0x00, 0x00, 0x00, 0xff, 0x00, 0x01,
+				},
+			},
+		},
+		{
+			name:   "margin larger than the padding",
+			img:    src.SubImage(image.Rect(0, -1, 1, 0)),
+			margin: 1,
+			want: &image.NRGBA{
+				Rect:   image.Rect(0, 0, 3, 3),
+				Stride: 12,
+				Pix: []uint8{
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+					0x00, 0x00, 0x00, 0x00, 0xff, 0x00, 0x00, 0xff, 0x00, 0x00, 0x00, 0x00,
+					0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				},
+			},
+		},
+		{
+			name:   "opaque image",
+			img:    New(3, 2, color.White),
+			margin: -1,
+			want:   New(3, 2, color.White),
+		},
+		{
+			name:   "fully transparent",
+			img:    image.NewNRGBA(image.Rect(0, 0, 5, 5)),
+			margin: 2,
+			want:   &image.NRGBA{},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got := TrimTransparent(tc.img, tc.margin)
+			if !compareNRGBA(got, tc.want, 0) {
+				t.Fatalf("got %#v want %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCropEllipse(t *testing.T) {
+	t.Parallel()
+
+	white := New(100, 80, color.White)
+	// area returns the sum of the alpha values as the number of opaque pixels.
+	area := func(img *image.NRGBA) float64 {
+		var sum float64
+		for i := 3; i < len(img.Pix); i += 4 {
+			sum += float64(img.Pix[i]) / 255
+		}
+		return sum
+	}
+
+	testCases := []struct {
+		name   string
+		crop   func() *image.NRGBA
+		bounds image.Rectangle
+		area   float64
+	}{
+		{
+			"circle",
+			func() *image.NRGBA { return CropCircle(white, image.Pt(50, 40), 20) },
+			image.Rect(0, 0, 40, 40),
+			math.Pi * 20 * 20,
+		},
+		{
+			"ellipse",
+			func() *image.NRGBA { return CropEllipse(white, image.Rect(10, 10, 90, 50)) },
+			image.Rect(0, 0, 80, 40),
+			math.Pi * 40 * 20,
+		},
+		{
+			"partly outside",
+			func() *image.NRGBA { return CropCircle(white, image.Pt(0, 40), 30) },
+			image.Rect(0, 0, 60, 60),
+			math.Pi * 30 * 30 / 2,
+		},
+		{
+			"empty",
+			func() *image.NRGBA { return CropCircle(white, image.Pt(50, 40), 0) },
+			image.Rectangle{},
+			0,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got := tc.crop()
+			if got.Bounds() != tc.bounds {
+				t.Fatalf("got bounds %v want %v", got.Bounds(), tc.bounds)
+			}
+			if a := area(got); math.Abs(a-tc.area) > tc.area*0.01 {
+				t.Fatalf("got area %v want %v", a, tc.area)
+			}
+		})
+	}
+
+	t.Run("edges", func(t *testing.T) {
+		t.Parallel()
+		got := CropCircle(white, image.Pt(50, 40), 20)
+		if c := got.NRGBAAt(20, 20); c != (color.NRGBA{0xff, 0xff, 0xff, 0xff}) {
+			t.Fatalf("got center %v want white", c)
+		}
+		if c := got.NRGBAAt(0, 0); c != (color.NRGBA{}) {
+			t.Fatalf("got corner %v want transparent", c)
+		}
+		partial := 0
+		for y := 0; y < 40; y++ {
+			for x := 0; x < 40; x++ {
+				c := got.NRGBAAt(x, y)
+				if c.A > 0 && c.A < 0xff {
+					partial++
+				}
+				if c.A > 0 && c.R != 0xff {
+					t.Fatalf("pixel (%d, %d): got %v want the source color", x, y, c)
+				}
+			}
+		}
+		if partial == 0 {
+			t.Fatalf("the edges are not anti-aliased")
+		}
+	})
+}
+
+func TestRoundCorners(t *testing.T) {
+	t.Parallel()
+
+	white := New(100, 80, color.White)
+	testCases := []struct {
+		name string
+		img  *image.NRGBA
+		area float64
+	}{
+		{"radius", RoundCorners(white, 10), 8000 - (4-math.Pi)*10*10},
+		{"zero radius", RoundCorners(white, 0), 8000},
+		{"one corner", RoundCornersEach(white, 0, 20, 0, 0), 8000 - (1-math.Pi/4)*20*20},
+		{"each corner", RoundCornersEach(white, 10, 20, 30, 0), 8000 - (1-math.Pi/4)*(100+400+900)},
+		// The radii are scaled down to half of the height.
+		{"large radius", RoundCorners(white, 100), 8000 - (4-math.Pi)*40*40},
+		{"negative radius", RoundCorners(white, -5), 8000},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if tc.img.Bounds() != white.Bounds() {
+				t.Fatalf("got bounds %v want %v", tc.img.Bounds(), white.Bounds())
+			}
+			var area float64
+			for i := 3; i < len(tc.img.Pix); i += 4 {
+				area += float64(tc.img.Pix[i]) / 255
+			}
+			if math.Abs(area-tc.area) > 2 {
+				t.Fatalf("got area %v want %v", area, tc.area)
+			}
+			if c := tc.img.NRGBAAt(50, 40); c != (color.NRGBA{255, 255, 255, 255}) {
+				t.Fatalf("got center pixel %v want opaque white", c)
+			}
+		})
+	}
+
+	t.Run("edges", func(t *testing.T) {
+		t.Parallel()
+		got := RoundCorners(white, 10)
+		if a := got.NRGBAAt(0, 0).A; a != 0 {
+			t.Fatalf("got corner alpha %d want 0", a)
+		}
+		if a := got.NRGBAAt(99, 79).A; a != 0 {
+			t.Fatalf("got corner alpha %d want 0", a)
+		}
+		if a := got.NRGBAAt(10, 0).A; a != 255 {
+			t.Fatalf("got edge alpha %d want 255", a)
+		}
+		partial := 0
+		for i := 3; i < len(got.Pix); i += 4 {
+			if got.Pix[i] > 0 && got.Pix[i] < 255 {
+				partial++
+			}
+		}
+		if partial == 0 {
+			t.Fatalf("the corners are not anti-aliased")
+		}
+	})
+
+	if got := RoundCorners(&image.NRGBA{}, 10); !got.Bounds().Empty() {
+		t.Fatalf("got bounds %v want empty", got.Bounds())
+	}
+}
@@ -132,24 +132,111 @@ func Rotate270(img image.Image) *image.NRGBA {
 // Rotate rotates an image by the given angle counter-clockwise .
 // The angle parameter is the rotation angle in degrees.
 // The bgColor parameter specifies the color of the uncovered zone after the rotation.
+// If bgColor is AutoColor, the color is inferred from the image borders.
 func Rotate(img image.Image, angle float64, bgColor color.Color) *image.NRGBA {
+	return rotate(img, angle, bgColor, ExpandToFit, Linear.Support, interpolatePoint)
+}
+
+// RotateCropMode specifies the size of the image rotated by RotateWithOptions.
+type RotateCropMode int
+
+// Rotate crop modes.
+const (
+	// ExpandToFit enlarges the image to fit the whole rotated image, as Rotate does.
+	ExpandToFit RotateCropMode = iota
+	// KeepOriginalSize keeps the size of the source image, cutting off the rotated corners.
+	KeepOriginalSize
+	// LargestInscribed crops the image to the largest axis-aligned rectangle inside
+	// the rotated image, so no background is left in the corners.
+	LargestInscribed
+)
+
+// RotateOptions are rotation parameters.
+type RotateOptions struct {
+	// Filter is the resampling filter. The zero value is NearestNeighbor, the fastest
+	// filter; Linear gives the quality of Rotate, and filters such as Lanczos keep the
+	// rotated image sharper.
+	Filter ResampleFilter
+
+	// Background is the color of the uncovered zone after the rotation. If Background
+	// is AutoColor, the color is inferred from the image borders. Nil means transparent.
+	Background color.Color
+
+	// Crop is the size of the rotated image.
+	Crop RotateCropMode
+}
+
+// RotateWithOptions rotates an image by the given angle counter-clockwise, like Rotate,
+// with the resampling filter, background color and size of the result set by the options.
+//
+// Example:
+//
+//	dstImage := imaging.RotateWithOptions(srcImage, 7.3, imaging.RotateOptions{
+//		Filter:     imaging.Lanczos,
+//		Background: color.White,
+//		Crop:       imaging.KeepOriginalSize,
+//	})
+func RotateWithOptions(img image.Image, angle float64, options RotateOptions) *image.NRGBA {
+	bgColor := options.Background
+	if bgColor == nil {
+		bgColor = color.Transparent
+	}
+	filter := options.Filter
+	sample := func(dst *image.NRGBA, dstX, dstY int, src *image.NRGBA, xf, yf float64, bgColor color.NRGBA) {
+		i := dst.PixOffset(dstX, dstY)
+		// The filters work with pixel coordinates, not with pixel centers.
+		if filter.Support <= 0 || filter.Kernel == nil {
+			nearestPoint(dst.Pix[i:i+4:i+4], src, xf+0.5, yf+0.5, bgColor)
+			return
+		}
+		filterPoint(dst.Pix[i:i+4:i+4], src, xf+0.5, yf+0.5, 1, filter, bgColor)
+	}
+	return rotate(img, angle, bgColor, options.Crop, filter.Support, sample)
+}
+
+// RotateCrop rotates an image by the given angle counter-clockwise and crops it to
+// the largest axis-aligned rectangle that has no uncovered zone, e.g. to straighten
+// the horizon of a photo. The filter is the resampling filter as in RotateWithOptions.
+//
+// Example:
+//
+//	dstImage := imaging.RotateCrop(srcImage, -2.5, imaging.Lanczos)
+func RotateCrop(img image.Image, angle float64, filter ResampleFilter) *image.NRGBA {
+	return RotateWithOptions(img, angle, RotateOptions{Filter: filter, Crop: LargestInscribed})
+}
+
+// rotate rotates the image, sampling the source pixels at the rotated points. The support
+// is the distance from the points at which sample reads the source pixels.
+func rotate(img image.Image, angle float64, bgColor color.Color, crop RotateCropMode, support float64, sample func(dst *image.NRGBA, dstX, dstY int, src *image.NRGBA, xf, yf float64, bgColor color.NRGBA)) *image.NRGBA {
 	angle = angle - math.Floor(angle/360)*360
 
-	switch angle {
-	case 0:
+	b := img.Bounds()
+	switch {
+	case angle == 0:
 		return Clone(img)
-	case 90:
-		return Rotate90(img)
-	case 180:
+	case angle == 180:
 		return Rotate180(img)
-	case 270:
-		return Rotate270(img)
+	case crop != KeepOriginalSize || b.Dx() == b.Dy():
+		switch angle {
+		case 90:
+			return Rotate90(img)
+		case 270:
+			return Rotate270(img)
+		}
 	}
 
 	src := toNRGBA(img)
 	srcW := src.Bounds().Max.X
 	srcH := src.Bounds().Max.Y
 	dstW, dstH := rotatedSize(srcW, srcH, angle)
+	switch crop {
+	case KeepOriginalSize:
+		dstW, dstH = srcW, srcH
+	case LargestInscribed:
+		// The pixels near the edges are blended with the background.
+		m := 2 * math.Ceil(support)
+		dstW, dstH = inscribedSize(math.Max(float64(srcW)-m, 0), math.Max(float64(srcH)-m, 0), angle)
+	}
 	dst := image.NewNRGBA(image.Rect(0, 0, dstW, dstH))
 
 	if dstW <= 0 || dstH <= 0 {
@@ -161,7 +248,7 @@ func Rotate(img image.Image, angle float64, bgColor color.Color) *image.NRGBA {
 	dstXOff := float64(dstW)/2 - 0.5
 	dstYOff := float64(dstH)/2 - 0.5
 
-	bgColorNRGBA := color.NRGBAModel.Convert(bgColor).(color.NRGBA)
+	bgColorNRGBA := resolveColor(src, bgColor)
 	sin, cos := math.Sincos(math.Pi * angle / 180)
 
 	parallel(0, dstH, func(ys <-chan int) {
@@ -169,7 +256,7 @@ func Rotate(img image.Image, angle float64, bgColor color.Color) *image.NRGBA {
 			for dstX := 0; dstX < dstW; dstX++ {
 				xf, yf := rotatePoint(float64(dstX)-dstXOff, float64(dstY)-dstYOff, sin, cos)
 				xf, yf = xf+srcXOff, yf+srcYOff
-				interpolatePoint(dst, dstX, dstY, src, xf, yf, bgColorNRGBA)
+				sample(dst, dstX, dstY, src, xf, yf, bgColorNRGBA)
 			}
 		}
 	})
@@ -177,6 +264,34 @@ func Rotate(img image.Image, angle float64, bgColor color.Color) *image.NRGBA {
 	return dst
 }
 
+// inscribedSize returns the size of the largest axis-aligned rectangle inside the w x h
+// rectangle rotated by the angle.
+func inscribedSize(w, h, angle float64) (int, int) {
+	if w <= 0 || h <= 0 {
+		return 0, 0
+	}
+	sin, cos := math.Sincos(math.Pi * angle / 180)
+	sin, cos = math.Abs(sin), math.Abs(cos)
+	long, short := math.Max(w, h), math.Min(w, h)
+
+	var wr, hr float64
+	if short <= 2*sin*cos*long || math.Abs(sin-cos) < 1e-10 {
+		// The rectangle touches the long sides only: two of its corners are on
+		// the middle line of the rotated rectangle.
+		x := short / 2
+		if w >= h {
+			wr, hr = x/sin, x/cos
+		} else {
+			wr, hr = x/cos, x/sin
+		}
+	} else {
+		cos2 := cos*cos - sin*sin
+		wr, hr = (w*cos-h*sin)/cos2, (h*cos-w*sin)/cos2
+	}
+	// Tolerate the rounding errors of the exact sizes.
+	return int(math.Floor(wr + 1e-9)), int(math.Floor(hr + 1e-9))
+}
+
 func rotatePoint(x, y, sin, cos float64) (float64, float64) {
 	return x*cos - y*sin, x*sin + y*cos
 }
@@ -208,6 +323,122 @@ func rotatedSize(w, h int, angle float64) (int, int) {
 	return int(neww), int(newh)
 }
 
+// Reflect mirrors an image across the line through its center at the given angle in
+// degrees, counter-clockwise from the horizontal as in Rotate: 0 flips the image
+// vertically like FlipV, 90 horizontally like FlipH, and other angles reflect it
+// diagonally, resampling it with the linear filter. The result is as large as the
+// image and the pixels mirrored from outside of the image are transparent.
+//
+// Example:
+//
+//	// Make the image symmetric along its diagonal.
+//	mirrored := imaging.Reflect(srcImage, 45)
+func Reflect(img image.Image, angle float64) *image.NRGBA {
+	src := toNRGBA(img)
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	if w == 0 || h == 0 {
+		return dst
+	}
+	// The y axis points down, so the line goes along (cos, -sin) in image coordinates.
+	sin, cos := math.Sincos(math.Pi * angle / 90)
+	cx, cy := float64(w)/2, float64(h)/2
+	parallel(0, h, func(ys <-chan int) {
+		for y := range ys {
+			for x := 0; x < w; x++ {
+				dx, dy := float64(x)+0.5-cx, float64(y)+0.5-cy
+				sx := cx + dx*cos - dy*sin
+				sy := cy - dx*sin - dy*cos
+				i := dst.PixOffset(x, y)
+				distortPoint(dst.Pix[i:i+4:i+4], src, sx, sy)
+			}
+		}
+	})
+	return dst
+}
+
+// ShearH shears an image horizontally by the given angle, slanting the vertical lines
+// like italic text. The angle parameter is the slant angle in degrees in range (-90, 90):
+// positive angles move the top of the image to the right, negative angles to the left.
+// The image is widened to fit the sheared rows, and the bgColor parameter specifies the
+// color of the uncovered zone. If bgColor is AutoColor, the color is inferred from the
+// image borders.
+//
+// Example:
+//
+//	dstImage := imaging.ShearH(srcImage, 15, color.Transparent)
+func ShearH(img image.Image, angle float64, bgColor color.Color) *image.NRGBA {
+	return shear(img, angle, bgColor, true)
+}
+
+// ShearV shears an image vertically by the given angle, slanting the horizontal lines.
+// The angle parameter is the slant angle in degrees in range (-90, 90): positive angles
+// move the right side of the image up, negative angles down. The image is heightened to
+// fit the sheared columns, and the bgColor parameter specifies the color of the uncovered
+// zone. If bgColor is AutoColor, the color is inferred from the image borders.
+//
+// Example:
+//
+//	dstImage := imaging.ShearV(srcImage, -10, color.White)
+func ShearV(img image.Image, angle float64, bgColor color.Color) *image.NRGBA {
+	return shear(img, angle, bgColor, false)
+}
+
+// shear shears the image horizontally or vertically. Every row (or column) is shifted
+// by a fractional offset and resampled with linear interpolation.
+func shear(img image.Image, angle float64, bgColor color.Color, horizontal bool) *image.NRGBA {
+	if angle == 0 {
+		return Clone(img)
+	}
+	if !(angle > -90 && angle < 90) {
+		return &image.NRGBA{}
+	}
+
+	src := toNRGBA(img)
+	srcW, srcH := src.Bounds().Dx(), src.Bounds().Dy()
+	if srcW <= 0 || srcH <= 0 {
+		return &image.NRGBA{}
+	}
+
+	// The lines of the image are rows for ShearH and columns for ShearV. For ShearV
+	// the right side moves up, so the shift of the columns grows to the left.
+	s := math.Tan(math.Pi * angle / 180)
+	n, length := srcH, srcW
+	if !horizontal {
+		n, length = srcW, srcH
+	}
+	extent := math.Abs(s) * float64(n)
+	dstLength := length + int(math.Ceil(extent-1e-9))
+	// shift returns the offset of the line with the center at c.
+	shift := func(c float64) float64 {
+		return s*(float64(n)-c) - math.Min(0, s*float64(n))
+	}
+
+	dstW, dstH := dstLength, srcH
+	if !horizontal {
+		dstW, dstH = srcW, dstLength
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dstW, dstH))
+	bgColorNRGBA := resolveColor(src, bgColor)
+
+	parallel(0, dstH, func(ys <-chan int) {
+		for dstY := range ys {
+			for dstX := 0; dstX < dstW; dstX++ {
+				// The interpolation works with pixel centers.
+				xf, yf := float64(dstX), float64(dstY)
+				if horizontal {
+					xf -= shift(yf + 0.5)
+				} else {
+					yf -= shift(xf + 0.5)
+				}
+				interpolatePoint(dst, dstX, dstY, src, xf, yf, bgColorNRGBA)
+			}
+		}
+	})
+
+	return dst
+}
+
 func interpolatePoint(dst *image.NRGBA, dstX, dstY int, src *image.NRGBA, xf, yf float64, bgColor color.NRGBA) {
 	j := dstY*dst.Stride + dstX*4
 	d := dst.Pix[j : j+4 : j+4]
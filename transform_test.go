@@ -3,6 +3,7 @@ package imaging
 import (
 	"image"
 	"image/color"
+	"math"
 	"testing"
 )
 
@@ -675,3 +676,269 @@ func BenchmarkRotate(b *testing.B) {
 		Rotate(testdataBranchesJPG, 30, color.Transparent)
 	}
 }
+
+func TestReflect(t *testing.T) {
+	t.Parallel()
+
+	src := image.NewNRGBA(image.Rect(0, 0, 3, 3))
+	for i := range src.Pix {
+		src.Pix[i] = uint8(i * 7)
+	}
+	for i := 3; i < len(src.Pix); i += 4 {
+		src.Pix[i] = 0xff
+	}
+	testCases := []struct {
+		name  string
+		angle float64
+		want  *image.NRGBA
+	}{
+		{"horizontal axis", 0, FlipV(src)},
+		{"vertical axis", 90, FlipH(src)},
+		{"falling diagonal", -45, Transpose(src)},
+		{"rising diagonal", 45, Transverse(src)},
+		{"full turn", 180, FlipV(src)},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got := Reflect(src, tc.angle)
+			if !compareNRGBA(got, tc.want, 0) {
+				t.Fatalf("got result %#v want %#v", got, tc.want)
+			}
+		})
+	}
+
+	// The corners of a wide image mirrored diagonally are mirrored from outside.
+	got := Reflect(New(20, 10, color.White), 45)
+	if c := got.NRGBAAt(0, 0); c != (color.NRGBA{}) {
+		t.Fatalf("got color %v at the corner want transparent", c)
+	}
+	if c := got.NRGBAAt(10, 5); c != (color.NRGBA{0xff, 0xff, 0xff, 0xff}) {
+		t.Fatalf("got color %v at the center want white", c)
+	}
+	if got := Reflect(&image.NRGBA{}, 30); !got.Rect.Empty() {
+		t.Fatalf("got bounds %v want empty", got.Rect)
+	}
+}
+
+func TestShear(t *testing.T) {
+	t.Parallel()
+
+	// The 2x2 source has red, green, blue and white pixels. With the slope of 2 the rows
+	// (or columns) are shifted by whole pixels.
+	src := &image.NRGBA{
+		Rect:   image.Rect(-1, -1, 1, 1),
+		Stride: 2 * 4,
+		Pix: []uint8{
+			0xff, 0x00, 0x00, 0xff, 0x00, 0xff, 0x00, 0xff,
+			0x00, 0x00, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		},
+	}
+	slope := math.Atan(2) * 180 / math.Pi
+	r, g, b, w, k := []uint8{0xff, 0, 0, 0xff}, []uint8{0, 0xff, 0, 0xff}, []uint8{0, 0, 0xff, 0xff}, []uint8{0xff, 0xff, 0xff, 0xff}, []uint8{0, 0, 0, 0xff}
+	pix := func(pixels ...[]uint8) []uint8 {
+		var p []uint8
+		for _, c := range pixels {
+			p = append(p, c...)
+		}
+		return p
+	}
+
+	testCases := []struct {
+		name  string
+		shear func(image.Image, float64, color.Color) *image.NRGBA
+		angle float64
+		want  *image.NRGBA
+	}{
+		{
+			"ShearH 0",
+			ShearH,
+			0,
+			&image.NRGBA{Rect: image.Rect(0, 0, 2, 2), Stride: 2 * 4, Pix: pix(r, g, b, w)},
+		},
+		{
+			"ShearH positive",
+			ShearH,
+			slope,
+			&image.NRGBA{Rect: image.Rect(0, 0, 6, 2), Stride: 6 * 4, Pix: pix(
+				k, k, k, r, g, k,
+				k, b, w, k, k, k,
+			)},
+		},
+		{
+			"ShearH negative",
+			ShearH,
+			-slope,
+			&image.NRGBA{Rect: image.Rect(0, 0, 6, 2), Stride: 6 * 4, Pix: pix(
+				k, r, g, k, k, k,
+				k, k, k, b, w, k,
+			)},
+		},
+		{
+			"ShearV positive",
+			ShearV,
+			slope,
+			&image.NRGBA{Rect: image.Rect(0, 0, 2, 6), Stride: 2 * 4, Pix: pix(
+				k, k,
+				k, g,
+				k, w,
+				r, k,
+				b, k,
+				k, k,
+			)},
+		},
+		{
+			"ShearV 90",
+			ShearV,
+			90,
+			&image.NRGBA{},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got := tc.shear(src, tc.angle, color.Black)
+			if !compareNRGBA(got, tc.want, 1) {
+				t.Fatalf("got result %#v want %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestShearResampling(t *testing.T) {
+	t.Parallel()
+
+	// Half pixel shifts blend the neighbors.
+	src := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	src.SetNRGBA(0, 0, color.NRGBA{0xff, 0xff, 0xff, 0xff})
+	got := ShearH(src, 45, color.Transparent)
+	if got.Bounds() != image.Rect(0, 0, 2, 1) {
+		t.Fatalf("got bounds %v want 2x1", got.Bounds())
+	}
+	for x := 0; x < 2; x++ {
+		if c := got.NRGBAAt(x, 0); c.A < 0x7e || c.A > 0x81 || c.R != 0xff {
+			t.Fatalf("pixel (%d, 0): got %v want half transparent white", x, c)
+		}
+	}
+	if got := ShearV(makeNoiseNRGBA(10, 7, 1), 30, AutoColor); got.Bounds() != image.Rect(0, 0, 10, 13) {
+		t.Fatalf("got bounds %v want 10x13", got.Bounds())
+	}
+}
+
+func TestRotateWithOptions(t *testing.T) {
+	t.Parallel()
+
+	src := makeNoiseNRGBA(12, 8, 1)
+	for i := 3; i < len(src.Pix); i += 4 {
+		src.Pix[i] = 0xff
+	}
+
+	t.Run("linear like Rotate", func(t *testing.T) {
+		t.Parallel()
+		got := RotateWithOptions(src, 30, RotateOptions{Filter: Linear, Background: color.Black})
+		want := Rotate(src, 30, color.Black)
+		if !compareNRGBA(got, want, 1) {
+			t.Fatalf("got result %#v want %#v", got, want)
+		}
+	})
+
+	t.Run("nearest neighbor", func(t *testing.T) {
+		t.Parallel()
+		colors := map[color.NRGBA]bool{{}: true}
+		for y := 0; y < 8; y++ {
+			for x := 0; x < 12; x++ {
+				colors[src.NRGBAAt(x, y)] = true
+			}
+		}
+		got := RotateWithOptions(src, 17, RotateOptions{})
+		for y := 0; y < got.Rect.Dy(); y++ {
+			for x := 0; x < got.Rect.Dx(); x++ {
+				if c := got.NRGBAAt(x, y); !colors[c] {
+					t.Fatalf("pixel (%d, %d): got interpolated color %v", x, y, c)
+				}
+			}
+		}
+		if c := got.NRGBAAt(0, 0); c != (color.NRGBA{}) {
+			t.Fatalf("got corner %v want transparent", c)
+		}
+	})
+
+	t.Run("keep original size", func(t *testing.T) {
+		t.Parallel()
+		for _, angle := range []float64{7.3, 90, 180, 270} {
+			got := RotateWithOptions(src, angle, RotateOptions{Filter: Lanczos, Background: color.White, Crop: KeepOriginalSize})
+			if got.Bounds() != src.Bounds() {
+				t.Fatalf("angle %v: got bounds %v want %v", angle, got.Bounds(), src.Bounds())
+			}
+		}
+		got := RotateWithOptions(src, 90, RotateOptions{Crop: KeepOriginalSize, Background: color.White})
+		if c := got.NRGBAAt(0, 0); c != (color.NRGBA{0xff, 0xff, 0xff, 0xff}) {
+			t.Fatalf("got corner %v want white", c)
+		}
+		// The pixels are rotated around the center (5.5, 3.5).
+		if got, want := got.NRGBAAt(4, 4), src.NRGBAAt(5, 2); got != want {
+			t.Fatalf("got center %v want %v", got, want)
+		}
+	})
+}
+
+func TestRotateCrop(t *testing.T) {
+	t.Parallel()
+
+	src := makeNoiseNRGBA(120, 80, 1)
+	for i := 3; i < len(src.Pix); i += 4 {
+		src.Pix[i] = 0xff
+	}
+
+	testCases := []struct {
+		name   string
+		angle  float64
+		filter ResampleFilter
+		want   image.Rectangle
+	}{
+		{"0", 0, Lanczos, image.Rect(0, 0, 120, 80)},
+		{"90", 90, Lanczos, image.Rect(0, 0, 80, 120)},
+		{"nearest 7.3", 7.3, NearestNeighbor, image.Rect(0, 0, 112, 66)},
+		{"lanczos -7.3", -7.3, Lanczos, image.Rect(0, 0, 107, 60)},
+		{"linear 45", 45, Linear, image.Rect(0, 0, 55, 55)},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got := RotateCrop(src, tc.angle, tc.filter)
+			if got.Bounds() != tc.want {
+				t.Fatalf("got bounds %v want %v", got.Bounds(), tc.want)
+			}
+			for i := 3; i < len(got.Pix); i += 4 {
+				if got.Pix[i] != 0xff {
+					t.Fatalf("got background at pixel %d", i/4)
+				}
+			}
+		})
+	}
+}
+
+func TestInscribedSize(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		w, h, angle float64
+		wantW       int
+		wantH       int
+	}{
+		{100, 100, 45, 70, 70},
+		{100, 50, 0, 100, 50},
+		{200, 20, 30, 20, 11},
+		{20, 200, 30, 11, 20},
+		{0, 10, 30, 0, 0},
+	}
+	for _, tc := range testCases {
+		if w, h := inscribedSize(tc.w, tc.h, tc.angle); w != tc.wantW || h != tc.wantH {
+			t.Fatalf("inscribedSize(%v, %v, %v): got %dx%d want %dx%d", tc.w, tc.h, tc.angle, w, h, tc.wantW, tc.wantH)
+		}
+	}
+}
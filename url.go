@@ -0,0 +1,85 @@
+package imaging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// httpClient is the HTTP client used by OpenURL.
+var httpClient = &http.Client{Timeout: 30 * time.Second} //nolint
+
+// ErrHTTPStatus means the server responded to OpenURL with a non-2xx status code.
+var ErrHTTPStatus = errors.New("imaging: unexpected HTTP status")
+
+// OpenURL fetches an image over HTTP and decodes it. It accepts the same options
+// as Open, the size of the response is limited by MaxDownloadSize. Responses with
+// a Content-Type other than image/* or application/octet-stream are rejected with
+// ErrUnsupportedFormat. The request is canceled when ctx is done and times out
+// after 30 seconds.
+//
+// Example:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+//	defer cancel()
+//	img, err := imaging.OpenURL(ctx, "https://example.com/photo.jpg", imaging.AutoOrientation(true))
+func OpenURL(ctx context.Context, url string, opts ...DecodeOption) (img image.Image, err error) {
+	cfg := defaultDecodeConfig
+	for _, option := range opts {
+		option(&cfg)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "image/*")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			if err == nil {
+				err = closeErr
+			} else {
+				err = fmt.Errorf("original error: %s, defer close error: %w", err.Error(), closeErr)
+			}
+		}
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("%w: %s", ErrHTTPStatus, resp.Status)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "" {
+		mediaType, _, err := mime.ParseMediaType(ct)
+		if err != nil || (!strings.HasPrefix(mediaType, "image/") && mediaType != "application/octet-stream") {
+			return nil, fmt.Errorf("%w: content type %q", ErrUnsupportedFormat, ct)
+		}
+	}
+
+	body := io.Reader(resp.Body)
+	if limit := cfg.maxDownloadSize; limit > 0 {
+		if resp.ContentLength > limit {
+			return nil, fmt.Errorf("%w: content length %d bytes", ErrLimitExceeded, resp.ContentLength)
+		}
+		// Read one byte more than allowed to detect responses without
+		// the content length that are larger than the limit.
+		data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(data)) > limit {
+			return nil, fmt.Errorf("%w: download exceeds %d bytes", ErrLimitExceeded, limit)
+		}
+		body = bytes.NewReader(data)
+	}
+	return Decode(body, opts...)
+}
@@ -0,0 +1,120 @@
+package imaging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestOpenURL(t *testing.T) {
+	t.Parallel()
+
+	pngData := &bytes.Buffer{}
+	src := makeNoiseNRGBA(6, 4, 2)
+	if err := Encode(pngData, src, PNG); err != nil {
+		t.Fatalf("failed to encode png: %v", err)
+	}
+	jpegData, err := os.ReadFile("testdata/orientation_6.jpg")
+	if err != nil {
+		t.Fatalf("failed to read jpeg: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/image.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(pngData.Bytes()) //nolint
+	})
+	mux.HandleFunc("/untyped", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(pngData.Bytes()) //nolint
+	})
+	mux.HandleFunc("/rotated.jpg", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Write(jpegData) //nolint
+	})
+	mux.HandleFunc("/page.html", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte("<html></html>")) //nolint
+	})
+	mux.HandleFunc("/chunked.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.(http.Flusher).Flush()
+		w.Write(pngData.Bytes()) //nolint
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	ctx := context.Background()
+
+	t.Run("png", func(t *testing.T) {
+		t.Parallel()
+		for _, path := range []string{"/image.png", "/untyped"} {
+			img, err := OpenURL(ctx, server.URL+path)
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", path, err)
+			}
+			if !compareNRGBA(Clone(img), src, 0) {
+				t.Fatalf("%s: decoded image differs from source", path)
+			}
+		}
+	})
+
+	t.Run("auto orientation", func(t *testing.T) {
+		t.Parallel()
+		img, err := OpenURL(ctx, server.URL+"/rotated.jpg", AutoOrientation(true))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want, err := Open("testdata/orientation_6.jpg", AutoOrientation(true))
+		if err != nil {
+			t.Fatalf("failed to open jpeg: %v", err)
+		}
+		if img.Bounds() != want.Bounds() {
+			t.Fatalf("got bounds %v want %v", img.Bounds(), want.Bounds())
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		t.Parallel()
+		testCases := []struct {
+			name string
+			path string
+			opts []DecodeOption
+			want error
+		}{
+			{name: "content type", path: "/page.html", want: ErrUnsupportedFormat},
+			{name: "not found", path: "/missing.png", want: ErrHTTPStatus},
+			{name: "content length", path: "/image.png", opts: []DecodeOption{MaxDownloadSize(10)}, want: ErrLimitExceeded},
+			{name: "chunked", path: "/chunked.png", opts: []DecodeOption{MaxDownloadSize(10)}, want: ErrLimitExceeded},
+		}
+		for _, tc := range testCases {
+			if _, err := OpenURL(ctx, server.URL+tc.path, tc.opts...); !errors.Is(err, tc.want) {
+				t.Fatalf("%s: got error %v want %v", tc.name, err, tc.want)
+			}
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		t.Parallel()
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		if _, err := OpenURL(canceled, server.URL+"/image.png"); !errors.Is(err, context.Canceled) {
+			t.Fatalf("got error %v want %v", err, context.Canceled)
+		}
+	})
+
+	t.Run("unlimited", func(t *testing.T) {
+		t.Parallel()
+		img, err := OpenURL(ctx, server.URL+"/chunked.png", MaxDownloadSize(0))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if img.Bounds() != image.Rect(0, 0, 6, 4) {
+			t.Fatalf("got bounds %v", img.Bounds())
+		}
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"image"
 	"math"
 	"runtime"
+	"runtime/pprof"
 	"sync"
 	"sync/atomic"
 )
@@ -39,16 +40,40 @@ func parallel(start, stop int, fn func(<-chan int)) {
 	}
 	close(c)
 
+	ctx := profileContext(count)
 	wg.Add(procs)
 	for i := 0; i < procs; i++ {
 		go func() {
 			defer wg.Done()
+			if ctx != nil {
+				pprof.SetGoroutineLabels(ctx)
+			}
 			fn(c)
 		}()
 	}
 	wg.Wait()
 }
 
+// scratchPool holds the intermediate images of the Into functions for reuse.
+var scratchPool sync.Pool
+
+// getScratch returns an intermediate image of the size with undefined pixels.
+func getScratch(width, height int) *image.NRGBA {
+	n := width * height * 4
+	if img, ok := scratchPool.Get().(*image.NRGBA); ok && cap(img.Pix) >= n {
+		img.Pix = img.Pix[:n]
+		img.Stride = width * 4
+		img.Rect = image.Rect(0, 0, width, height)
+		return img
+	}
+	return image.NewNRGBA(image.Rect(0, 0, width, height))
+}
+
+// putScratch returns the intermediate image to the pool.
+func putScratch(img *image.NRGBA) {
+	scratchPool.Put(img)
+}
+
 // absInt returns the absolute value of i.
 func absInt(i int) int {
 	if i < 0 {
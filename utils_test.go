@@ -2,6 +2,7 @@ package imaging
 
 import (
 	"image"
+	"image/color"
 	"math"
 	"runtime"
 	"sync/atomic"
@@ -88,6 +89,35 @@ func TestSetMaxProcs(t *testing.T) {
 	SetMaxProcs(0)
 }
 
+func TestParallelDeterministic(t *testing.T) {
+	// Not parallel: the number of the processing goroutines is set for the whole package.
+	defer SetMaxProcs(0)
+
+	src := testdataFlowersSmallPNG
+	ops := map[string]func() image.Image{
+		"resize":        func() image.Image { return Resize(src, 57, 0, Lanczos) },
+		"blur":          func() image.Image { return Blur(src, 1.3) },
+		"sharpen":       func() image.Image { return SharpenEdge(src, 0.7, EdgeReflect) },
+		"rotate":        func() image.Image { return Rotate(src, 33, color.Black) },
+		"contrast":      func() image.Image { return AdjustContrast(src, 17) },
+		"white balance": func() image.Image { return AutoWhiteBalance(src) },
+		"denoise":       func() image.Image { return Denoise(src, 1.5) },
+		"convolve": func() image.Image {
+			return Convolve3x3(src, [9]float64{-1, -1, 0, -1, 1, 1, 0, 1, 1}, nil)
+		},
+	}
+	for name, op := range ops {
+		SetMaxProcs(1)
+		want := toNRGBA(op())
+		for _, procs := range []int{2, 3, 0} {
+			SetMaxProcs(procs)
+			if got := toNRGBA(op()); !compareNRGBA(got, want, 0) {
+				t.Fatalf("%s: the result with %d processing goroutines differs from the result with 1", name, procs)
+			}
+		}
+	}
+}
+
 func TestClamp(t *testing.T) {
 	t.Parallel()
 
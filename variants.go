@@ -0,0 +1,172 @@
+package imaging
+
+import (
+	"errors"
+	"image"
+	"io"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// ErrVariantTemplate means the file name template of SaveVariants has no {width} placeholder.
+var ErrVariantTemplate = errors.New("imaging: variant name template has no {width} placeholder")
+
+// GenerateVariants resizes the image to each of the widths using the specified resample
+// filter, preserving the aspect ratio, and returns the resized images by width, e.g. for
+// the srcset of responsive images. The widths that are not positive or exceed the width
+// of the image are skipped, as the upscaled variants add bytes but no details.
+//
+// Example:
+//
+//	variants := imaging.GenerateVariants(img, []int{320, 640, 1280, 1920}, imaging.Lanczos)
+func GenerateVariants(img image.Image, widths []int, filter ResampleFilter) map[int]*image.NRGBA {
+	variants := make(map[int]*image.NRGBA, len(widths))
+	for _, w := range variantWidths(img, widths) {
+		variants[w] = Resize(img, w, 0, filter)
+	}
+	return variants
+}
+
+// SaveVariants resizes the image to each of the widths like GenerateVariants and saves
+// the variants concurrently to the files named by the template, in which {width} is
+// replaced by the width of the variant. The format is determined from the extension of
+// the names as in Save. It returns the names of the saved files by width. A template
+// without {width} returns ErrVariantTemplate. If saving a variant fails, the first error
+// is returned and the other variants may be saved or not.
+//
+// Example:
+//
+//	names, err := imaging.SaveVariants(img, []int{320, 640, 1280}, imaging.Lanczos,
+//		"public/hero-{width}w.jpg", imaging.JPEGQuality(80))
+func SaveVariants(img image.Image, widths []int, filter ResampleFilter, template string, opts ...EncodeOption) (map[int]string, error) {
+	if !strings.Contains(template, "{width}") {
+		return nil, ErrVariantTemplate
+	}
+	f, err := FormatFromFilename(template)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateEncodeOptions(f, opts...); err != nil {
+		return nil, err
+	}
+
+	ws := variantWidths(img, widths)
+	names := make(map[int]string, len(ws))
+	for _, w := range ws {
+		names[w] = strings.ReplaceAll(template, "{width}", strconv.Itoa(w))
+	}
+	eg := errgroup.Group{}
+	eg.SetLimit(runtime.GOMAXPROCS(0))
+	for _, w := range ws {
+		w := w
+		eg.Go(func() error {
+			return Save(Resize(img, w, 0, filter), names[w], opts...)
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// variantWidths returns the distinct widths of the variants of the image in ascending order.
+func variantWidths(img image.Image, widths []int) []int {
+	srcW := img.Bounds().Dx()
+	if img.Bounds().Dy() <= 0 {
+		return nil
+	}
+	seen := make(map[int]bool, len(widths))
+	ws := make([]int, 0, len(widths))
+	for _, w := range widths {
+		if w > 0 && w <= srcW && !seen[w] {
+			seen[w] = true
+			ws = append(ws, w)
+		}
+	}
+	sort.Ints(ws)
+	return ws
+}
+
+// Target is an output of FanOutEncoder.
+type Target struct {
+	// Width and Height are the size of the output. If one of them is 0, the aspect
+	// ratio of the image is preserved, as in Resize.
+	Width, Height int
+	// Filter is the resample filter of the final resizing pass, e.g. Lanczos.
+	Filter ResampleFilter
+	// Format is the format the output is encoded in.
+	Format Format
+	// Options are the encode options of the output, applied after the common ones.
+	Options []EncodeOption
+	// Writer receives the encoded output.
+	Writer io.Writer
+}
+
+// FanOutEncoder resizes the image to the size of every target and encodes the outputs
+// concurrently to the writers of the targets, the core loop of the responsive image
+// generators. The large reductions share the intermediate downscales: the image is
+// halved with area averaging, and every target is resized from the smallest halving
+// still at least four times its size, like ThumbnailHQ, so the work of the halvings is
+// done once for all the targets. The targets with NearestNeighbor are resized from the
+// image directly. The common options are applied to all targets before their own.
+//
+// The options of all targets are validated before anything is written. If encoding
+// a target fails, the first error is returned and the other targets may be written
+// or not.
+//
+// Example:
+//
+//	err := imaging.FanOutEncoder(img, []imaging.Target{
+//		{Width: 1920, Filter: imaging.Lanczos, Format: imaging.JPEG, Writer: large},
+//		{Width: 640, Filter: imaging.Lanczos, Format: imaging.JPEG, Writer: medium},
+//		{Width: 160, Height: 160, Filter: imaging.Box, Format: imaging.PNG, Writer: icon},
+//	}, imaging.JPEGQuality(80))
+func FanOutEncoder(img image.Image, targets []Target, opts ...EncodeOption) error {
+	targetOpts := make([][]EncodeOption, len(targets))
+	for i, t := range targets {
+		targetOpts[i] = append(append([]EncodeOption(nil), opts...), t.Options...)
+		if err := ValidateEncodeOptions(t.Format, targetOpts[i]...); err != nil {
+			return err
+		}
+	}
+
+	// levels[k] is the image halved k times, levelOf the level each target is resized from.
+	// The sizes are computed from the image, as the halvings may round the aspect ratio.
+	srcW, srcH := img.Bounds().Dx(), img.Bounds().Dy()
+	sizes := make([]image.Point, len(targets))
+	levelOf := make([]int, len(targets))
+	maxLevel := 0
+	for i, t := range targets {
+		dstW, dstH := resizeDimensions(srcW, srcH, t.Width, t.Height)
+		sizes[i] = image.Pt(dstW, dstH)
+		if t.Filter.Support <= 0 || dstW == 0 {
+			continue
+		}
+		for w, h := srcW, srcH; w >= 4*dstW && h >= 4*dstH; w, h = w/2, h/2 {
+			levelOf[i]++
+		}
+		if levelOf[i] > maxLevel {
+			maxLevel = levelOf[i]
+		}
+	}
+	levels := []image.Image{img}
+	for k := 1; k <= maxLevel; k++ {
+		prev := levels[k-1].Bounds()
+		levels = append(levels, ResizeArea(levels[k-1], prev.Dx()/2, prev.Dy()/2))
+	}
+
+	eg := errgroup.Group{}
+	eg.SetLimit(runtime.GOMAXPROCS(0))
+	for i, t := range targets {
+		i, t := i, t
+		eg.Go(func() error {
+			dst := Resize(levels[levelOf[i]], sizes[i].X, sizes[i].Y, t.Filter)
+			return Encode(t.Writer, dst, t.Format, targetOpts[i]...)
+		})
+	}
+	return eg.Wait()
+}
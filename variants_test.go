@@ -0,0 +1,132 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestGenerateVariants(t *testing.T) {
+	t.Parallel()
+
+	src := makeNoiseNRGBA(100, 50, 1)
+	got := GenerateVariants(src, []int{80, 20, 0, -5, 40, 20, 200}, Linear)
+	if len(got) != 3 {
+		t.Fatalf("got %d variants want 3", len(got))
+	}
+	for _, w := range []int{20, 40, 80} {
+		want := Resize(src, w, 0, Linear)
+		if !compareNRGBA(got[w], want, 0) {
+			t.Fatalf("variant %d differs from Resize", w)
+		}
+	}
+	if got := GenerateVariants(&image.NRGBA{}, []int{10}, Linear); len(got) != 0 {
+		t.Fatalf("got %d variants of an empty image want 0", len(got))
+	}
+}
+
+func TestSaveVariants(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	src := makeNoiseNRGBA(100, 50, 1)
+	names, err := SaveVariants(src, []int{25, 50, 150}, Box, filepath.Join(dir, "img-{width}w.png"))
+	if err != nil {
+		t.Fatalf("failed to save variants: %v", err)
+	}
+	if len(names) != 2 {
+		t.Fatalf("got %d variants want 2", len(names))
+	}
+	for w, name := range names {
+		if want := filepath.Join(dir, "img-"+strconv.Itoa(w)+"w.png"); name != want {
+			t.Fatalf("got name %q want %q", name, want)
+		}
+		img, err := Open(name)
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", name, err)
+		}
+		if !compareNRGBA(Clone(img), Resize(src, w, 0, Box), 0) {
+			t.Fatalf("saved variant %d differs", w)
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		template string
+		opts     []EncodeOption
+		want     error
+	}{
+		{"no placeholder", filepath.Join(dir, "img.png"), nil, ErrVariantTemplate},
+		{"unknown format", filepath.Join(dir, "img-{width}.xyz"), nil, ErrUnsupportedFormat},
+		{"invalid option", filepath.Join(dir, "img-{width}.jpg"), []EncodeOption{JPEGQuality(0)}, ErrInvalidEncodeOption},
+	}
+	for _, tc := range testCases {
+		if _, err := SaveVariants(src, []int{10}, Box, tc.template, tc.opts...); !errors.Is(err, tc.want) {
+			t.Fatalf("%s: got error %v want %v", tc.name, err, tc.want)
+		}
+	}
+	if _, err := SaveVariants(src, []int{10}, Box, filepath.Join(dir, "missing", "img-{width}.png")); err == nil {
+		t.Fatalf("expected error saving to a missing directory")
+	}
+}
+
+func TestFanOutEncoder(t *testing.T) {
+	t.Parallel()
+
+	src := makeNoiseNRGBA(400, 200, 1)
+	bufs := make([]bytes.Buffer, 4)
+	targets := []Target{
+		{Width: 200, Filter: Lanczos, Format: PNG, Writer: &bufs[0]},
+		{Width: 40, Height: 40, Filter: Linear, Format: PNG, Writer: &bufs[1]},
+		{Width: 25, Filter: Lanczos, Format: JPEG, Options: []EncodeOption{JPEGQuality(70)}, Writer: &bufs[2]},
+		{Height: 10, Filter: NearestNeighbor, Format: PNG, Writer: &bufs[3]},
+	}
+	if err := FanOutEncoder(src, targets, PNGCompressionLevel(png.BestSpeed)); err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+
+	// The large reductions are resized from the shared halvings.
+	half := ResizeArea(src, 200, 100)
+	quarter := ResizeArea(half, 100, 50)
+	wants := []*image.NRGBA{
+		Resize(src, 200, 100, Lanczos),
+		Resize(half, 40, 40, Linear),
+		Resize(quarter, 25, 13, Lanczos),
+		Resize(src, 20, 10, NearestNeighbor),
+	}
+	for i, want := range wants {
+		img, err := Decode(&bufs[i])
+		if err != nil {
+			t.Fatalf("target %d: failed to decode: %v", i, err)
+		}
+		if targets[i].Format == JPEG {
+			if img.Bounds() != want.Rect {
+				t.Fatalf("target %d: got bounds %v want %v", i, img.Bounds(), want.Rect)
+			}
+			continue
+		}
+		if !compareNRGBA(Clone(img), want, 0) {
+			t.Fatalf("target %d: got result %#v want %#v", i, img, want)
+		}
+	}
+
+	targets = []Target{
+		{Width: 20, Filter: Box, Format: PNG, Writer: &bufs[0]},
+		{Width: 20, Filter: Box, Format: GIF, Options: []EncodeOption{GIFNumColors(0)}, Writer: &bufs[1]},
+	}
+	bufs[0].Reset()
+	if err := FanOutEncoder(src, targets); !errors.Is(err, ErrInvalidEncodeOption) {
+		t.Fatalf("got error %v want %v", err, ErrInvalidEncodeOption)
+	}
+	if bufs[0].Len() != 0 {
+		t.Fatalf("got %d bytes written want 0", bufs[0].Len())
+	}
+	targets = []Target{{Width: 20, Filter: Box, Format: Format(-1), Writer: &bufs[0]}}
+	if err := FanOutEncoder(src, targets); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("got error %v want %v", err, ErrUnsupportedFormat)
+	}
+}
@@ -0,0 +1,143 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// WatermarkOptions are the parameters of WatermarkText.
+type WatermarkOptions struct {
+	// Margin is the distance in pixels of the text from the image edges at the anchor.
+	Margin int
+
+	// Opacity is the opacity of the text and its outline, from 0 to 1.
+	// 0 (the default) means opaque.
+	Opacity float64
+
+	// Color is the color of the text. If nil, the text is white on dark regions of
+	// the image and black on light regions.
+	Color color.Color
+
+	// Outline is the width of the outline in pixels. If 0, it's a sixteenth of the
+	// text height, at least 1 pixel.
+	Outline int
+
+	// If Shadow is true, the contrasting color is drawn as a drop shadow, offset by
+	// the outline width to the bottom right, instead of an outline around the text.
+	Shadow bool
+}
+
+// WatermarkText draws the single line of text with the font face at the anchor
+// position of the image, and returns the result. Unless its color is set, the text
+// is white or black, whichever contrasts more with the mean luminance of the region
+// of the image under it, and it gets an outline, or a shadow, of the opposite color,
+// so the watermark stays legible on any photo, even over the local highlights and
+// shadows. Default options are used if a nil *WatermarkOptions is passed.
+//
+// Example:
+//
+//	face := basicfont.Face7x13 // or an opentype face of golang.org/x/image/font/opentype
+//	dstImage := imaging.WatermarkText(srcImage, "© Studio", face, imaging.BottomRight,
+//		&imaging.WatermarkOptions{Margin: 16, Opacity: 0.8})
+func WatermarkText(img image.Image, s string, face font.Face, anchor Anchor, opts *WatermarkOptions) *image.NRGBA {
+	if opts == nil {
+		opts = &WatermarkOptions{}
+	}
+	metrics := face.Metrics()
+	ascent, descent := metrics.Ascent.Ceil(), metrics.Descent.Ceil()
+	width := font.MeasureString(face, s).Ceil()
+	if width <= 0 || ascent+descent <= 0 {
+		return Clone(img)
+	}
+	outline := opts.Outline
+	if outline <= 0 {
+		outline = (ascent + descent) / 16
+		if outline < 1 {
+			outline = 1
+		}
+	}
+
+	// The mask of the text, padded for the outline.
+	mask := image.NewAlpha(image.Rect(0, 0, width+2*outline, ascent+descent+2*outline))
+	d := font.Drawer{Dst: mask, Src: image.Opaque, Face: face, Dot: fixed.P(outline, outline+ascent)}
+	d.DrawString(s)
+	var contour *image.Alpha
+	if opts.Shadow {
+		contour = image.NewAlpha(mask.Rect)
+		draw.Draw(contour, mask.Rect.Add(image.Pt(outline, outline)), mask, image.Point{}, draw.Src)
+	} else {
+		contour = dilateAlpha(mask, outline)
+	}
+
+	pos := anchorPt(img.Bounds().Inset(opts.Margin), mask.Rect.Dx(), mask.Rect.Dy(), anchor)
+	black, white := color.NRGBA{0, 0, 0, 0xff}, color.NRGBA{0xff, 0xff, 0xff, 0xff}
+	textColor := white
+	if meanLuminance(img, mask.Rect.Add(pos)) >= 0.5 {
+		textColor = black
+	}
+	if opts.Color != nil {
+		textColor = color.NRGBAModel.Convert(opts.Color).(color.NRGBA)
+	}
+	outlineColor := white
+	if 0.299*float64(textColor.R)+0.587*float64(textColor.G)+0.114*float64(textColor.B) >= 0.5*255 {
+		outlineColor = black
+	}
+
+	layer := image.NewNRGBA(mask.Rect)
+	draw.DrawMask(layer, layer.Rect, image.NewUniform(outlineColor), image.Point{}, contour, image.Point{}, draw.Over)
+	draw.DrawMask(layer, layer.Rect, image.NewUniform(textColor), image.Point{}, mask, image.Point{}, draw.Over)
+
+	opacity := opts.Opacity
+	if opacity <= 0 {
+		opacity = 1
+	}
+	return Overlay(img, layer, pos, opacity)
+}
+
+// dilateAlpha returns the mask grown by the radius: every pixel takes the maximum
+// alpha of the pixels within the radius.
+func dilateAlpha(mask *image.Alpha, radius int) *image.Alpha {
+	w, h := mask.Rect.Dx(), mask.Rect.Dy()
+	dst := image.NewAlpha(mask.Rect)
+	parallel(0, h, func(ys <-chan int) {
+		for y := range ys {
+			for x := 0; x < w; x++ {
+				var a uint8
+				for dy := -radius; dy <= radius; dy++ {
+					for dx := -radius; dx <= radius; dx++ {
+						sx, sy := x+dx, y+dy
+						if sx < 0 || sy < 0 || sx >= w || sy >= h || dx*dx+dy*dy > radius*radius {
+							continue
+						}
+						if v := mask.Pix[sy*mask.Stride+sx]; v > a {
+							a = v
+						}
+					}
+				}
+				dst.Pix[y*dst.Stride+x] = a
+			}
+		}
+	})
+	return dst
+}
+
+// meanLuminance returns the mean luminance (0-1) of the pixels of the image within the
+// rectangle, 0.5 if the rectangle is outside of the image.
+func meanLuminance(img image.Image, r image.Rectangle) float64 {
+	src := toNRGBA(Crop(img, r))
+	if src.Rect.Empty() {
+		return 0.5
+	}
+	var sum float64
+	for y := 0; y < src.Rect.Dy(); y++ {
+		row := src.Pix[y*src.Stride : y*src.Stride+src.Rect.Dx()*4]
+		for i := 0; i < len(row); i += 4 {
+			sum += 0.299*float64(row[i]) + 0.587*float64(row[i+1]) + 0.114*float64(row[i+2])
+		}
+	}
+	return sum / 255 / float64(src.Rect.Dx()*src.Rect.Dy())
+}
@@ -0,0 +1,79 @@
+package imaging
+
+import (
+	"image/color"
+	"testing"
+
+	"golang.org/x/image/font/basicfont"
+)
+
+func TestWatermarkText(t *testing.T) {
+	t.Parallel()
+
+	black, white := color.NRGBA{0, 0, 0, 0xff}, color.NRGBA{0xff, 0xff, 0xff, 0xff}
+	red := color.NRGBA{0xff, 0, 0, 0xff}
+	testCases := []struct {
+		name          string
+		background    color.NRGBA
+		opts          *WatermarkOptions
+		text, outline color.NRGBA
+		shadow        bool
+	}{
+		{"dark", color.NRGBA{0x20, 0x20, 0x20, 0xff}, nil, white, black, false},
+		{"light", color.NRGBA{0xe0, 0xe0, 0xe0, 0xff}, nil, black, white, false},
+		{"color", color.NRGBA{0xe0, 0xe0, 0xe0, 0xff}, &WatermarkOptions{Color: red}, red, white, false},
+		{"shadow", color.NRGBA{0x20, 0x20, 0x20, 0xff}, &WatermarkOptions{Shadow: true, Outline: 2}, white, black, true},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			src := New(100, 40, tc.background)
+			got := WatermarkText(src, "Hi", basicfont.Face7x13, BottomRight, tc.opts)
+			counts := map[color.NRGBA]int{}
+			for y := 0; y < 40; y++ {
+				for x := 0; x < 100; x++ {
+					counts[got.NRGBAAt(x, y)]++
+				}
+			}
+			if counts[tc.text] == 0 || counts[tc.outline] == 0 {
+				t.Fatalf("got %d text and %d outline pixels, want both", counts[tc.text], counts[tc.outline])
+			}
+			// The shadow is below the text, the outline around it.
+			first := tc.background
+			for i := 0; i < len(got.Pix) && first == tc.background; i += 4 {
+				first = color.NRGBA{got.Pix[i], got.Pix[i+1], got.Pix[i+2], got.Pix[i+3]}
+			}
+			if want := map[bool]color.NRGBA{false: tc.outline, true: tc.text}[tc.shadow]; first != want {
+				t.Fatalf("got the topmost color %v want %v", first, want)
+			}
+			// The watermark is in the bottom right corner.
+			for y := 0; y < 40; y++ {
+				for x := 0; x < 100; x++ {
+					if c := got.NRGBAAt(x, y); c != tc.background && (x < 70 || y < 20) {
+						t.Fatalf("got color %v at (%d, %d) out of the bottom right corner", c, x, y)
+					}
+				}
+			}
+		})
+	}
+
+	// The margin and the opacity.
+	src := New(100, 40, color.NRGBA{0x20, 0x20, 0x20, 0xff})
+	got := WatermarkText(src, "Hi", basicfont.Face7x13, TopLeft, &WatermarkOptions{Margin: 10, Opacity: 0.5})
+	for y := 0; y < 40; y++ {
+		for x := 0; x < 100; x++ {
+			c := got.NRGBAAt(x, y)
+			if (x < 10 || y < 10) && c != src.NRGBAAt(x, y) {
+				t.Fatalf("got color %v at (%d, %d) within the margin", c, x, y)
+			}
+			if c == white {
+				t.Fatalf("got opaque white at (%d, %d) with the opacity 0.5", x, y)
+			}
+		}
+	}
+
+	if got := WatermarkText(src, "", basicfont.Face7x13, Center, nil); !compareNRGBA(got, src, 0) {
+		t.Fatalf("got the image changed by an empty text")
+	}
+}
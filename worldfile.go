@@ -0,0 +1,199 @@
+package imaging
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// GeoTransform is an affine transformation from pixel coordinates to world coordinates:
+//
+//	X = A*col + B*row + C
+//	Y = D*col + E*row + F
+//
+// The pixel coordinates refer to the pixel corners, so (C, F) is the world
+// position of the top left corner of the image.
+type GeoTransform struct {
+	A, B, C, D, E, F float64
+}
+
+// ErrInvalidWorldFile means the world file can not be parsed.
+var ErrInvalidWorldFile = errors.New("imaging: invalid world file")
+
+// gtRasterTypeGeoKey is the GeoKey that tells whether the raster space
+// coordinates refer to pixel corners (1) or to pixel centers (2).
+const (
+	gtRasterTypeGeoKey = 1025
+	rasterPixelIsPoint = 2
+)
+
+// ParseWorldFile reads an ESRI world file (e.g. ".tfw", ".jgw" or ".pgw").
+// A world file consists of six lines: A, D, B, E and the world coordinates of
+// the center of the top left pixel.
+func ParseWorldFile(r io.Reader) (GeoTransform, error) {
+	var v []float64
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		f, err := strconv.ParseFloat(line, 64)
+		if err != nil {
+			return GeoTransform{}, fmt.Errorf("%w: %q", ErrInvalidWorldFile, line)
+		}
+		v = append(v, f)
+	}
+	if err := sc.Err(); err != nil {
+		return GeoTransform{}, err
+	}
+	if len(v) != 6 {
+		return GeoTransform{}, fmt.Errorf("%w: got %d values, want 6", ErrInvalidWorldFile, len(v))
+	}
+	t := GeoTransform{A: v[0], D: v[1], B: v[2], E: v[3]}
+	t.C = v[4] - (t.A+t.B)/2
+	t.F = v[5] - (t.D+t.E)/2
+	return t, nil
+}
+
+// WriteWorldFile writes the transformation to w in the ESRI world file format.
+func WriteWorldFile(w io.Writer, t GeoTransform) error {
+	num := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	_, err := fmt.Fprintf(w, "%s\n%s\n%s\n%s\n%s\n%s\n",
+		num(t.A), num(t.D), num(t.B), num(t.E), num(t.C+(t.A+t.B)/2), num(t.F+(t.D+t.E)/2))
+	return err
+}
+
+// Apply returns the world coordinates of the pixel position (col, row).
+func (t GeoTransform) Apply(col, row float64) (float64, float64) {
+	return t.A*col + t.B*row + t.C, t.D*col + t.E*row + t.F
+}
+
+// Invert returns the pixel position of the world coordinates (x, y).
+// It reports false if the transformation is not invertible.
+func (t GeoTransform) Invert(x, y float64) (float64, float64, bool) {
+	det := t.A*t.E - t.B*t.D
+	if det == 0 {
+		return 0, 0, false
+	}
+	x, y = x-t.C, y-t.F
+	return (t.E*x - t.B*y) / det, (t.A*y - t.D*x) / det, true
+}
+
+// Rect returns the smallest pixel rectangle that covers the world bounding box.
+// The result is not clipped to the image bounds. The rectangle is empty if the
+// transformation is not invertible.
+func (t GeoTransform) Rect(minX, minY, maxX, maxY float64) image.Rectangle {
+	x0, y0 := math.Inf(1), math.Inf(1)
+	x1, y1 := math.Inf(-1), math.Inf(-1)
+	for _, p := range [][2]float64{{minX, minY}, {maxX, minY}, {minX, maxY}, {maxX, maxY}} {
+		col, row, ok := t.Invert(p[0], p[1])
+		if !ok {
+			return image.Rectangle{}
+		}
+		x0, x1 = math.Min(x0, col), math.Max(x1, col)
+		y0, y1 = math.Min(y0, row), math.Max(y1, row)
+	}
+	// Round away from the box allowing for floating point errors.
+	const eps = 1e-9
+	return image.Rect(
+		int(math.Floor(x0+eps)), int(math.Floor(y0+eps)),
+		int(math.Ceil(x1-eps)), int(math.Ceil(y1-eps)),
+	)
+}
+
+// Crop returns the transformation of the image cropped to the rectangle r,
+// given in pixel coordinates relative to the top left corner of the image.
+func (t GeoTransform) Crop(r image.Rectangle) GeoTransform {
+	t.C, t.F = t.Apply(float64(r.Min.X), float64(r.Min.Y))
+	return t
+}
+
+// CropGeo cuts out the region of the image that covers the world bounding box
+// and returns the cropped image along with its transformation. The region is
+// clipped to the image bounds.
+//
+// Example:
+//
+//	meta, _ := imaging.OpenMetadata("map.tif")
+//	t, _ := meta.GeoTIFF.GeoTransform()
+//	dstImage, dstTransform := imaging.CropGeo(srcImage, t, 440800, 3750000, 441200, 3751000)
+func CropGeo(img image.Image, t GeoTransform, minX, minY, maxX, maxY float64) (*image.NRGBA, GeoTransform) {
+	b := img.Bounds()
+	r := t.Rect(minX, minY, maxX, maxY).Intersect(image.Rect(0, 0, b.Dx(), b.Dy()))
+	if r.Empty() {
+		return &image.NRGBA{}, t
+	}
+	return Crop(img, r.Add(b.Min)), t.Crop(r)
+}
+
+// GeoTransform returns the pixel to world transformation described by
+// the ModelTransformation tag or by the ModelTiepoint and ModelPixelScale tags.
+// It reports false if the tags are missing, also for a nil *GeoTIFF.
+func (g *GeoTIFF) GeoTransform() (GeoTransform, bool) {
+	if g == nil {
+		return GeoTransform{}, false
+	}
+	var t GeoTransform
+	switch m := g.ModelTransformation; {
+	case len(m) == 16:
+		t = GeoTransform{A: m[0], B: m[1], C: m[3], D: m[4], E: m[5], F: m[7]}
+	case len(g.ModelTiepoint) >= 6 && len(g.ModelPixelScale) >= 2:
+		tp, s := g.ModelTiepoint, g.ModelPixelScale
+		t = GeoTransform{A: s[0], C: tp[3] - tp[0]*s[0], E: -s[1], F: tp[4] + tp[1]*s[1]}
+	default:
+		return GeoTransform{}, false
+	}
+	if g.rasterType() == rasterPixelIsPoint {
+		t.C, t.F = t.Apply(-0.5, -0.5)
+	}
+	return t, true
+}
+
+// SetGeoTransform returns a copy of the GeoTIFF tags with the pixel to world
+// transformation replaced by t. North-up transformations are stored as a tie
+// point and a pixel scale, others as a ModelTransformation matrix. It can be
+// called on a nil *GeoTIFF, returning new tags with the transformation.
+func (g *GeoTIFF) SetGeoTransform(t GeoTransform) *GeoTIFF {
+	c := g.clone()
+	if c == nil {
+		c = &GeoTIFF{}
+	}
+	if c.rasterType() == rasterPixelIsPoint {
+		t.C, t.F = t.Apply(0.5, 0.5)
+	}
+	if t.B == 0 && t.D == 0 && t.A > 0 && t.E < 0 {
+		c.ModelTransformation = nil
+		c.ModelTiepoint = []float64{0, 0, 0, t.C, t.F, 0}
+		c.ModelPixelScale = []float64{t.A, -t.E, 0}
+		return c
+	}
+	c.ModelTiepoint, c.ModelPixelScale = nil, nil
+	c.ModelTransformation = []float64{
+		t.A, t.B, 0, t.C,
+		t.D, t.E, 0, t.F,
+		0, 0, 0, 0,
+		0, 0, 0, 1,
+	}
+	return c
+}
+
+// rasterType returns the value of the GTRasterTypeGeoKey or 0 if it's not set.
+func (g *GeoTIFF) rasterType() int {
+	keys := g.GeoKeyDirectory
+	if len(keys) < 4 {
+		return 0
+	}
+	for i := 4; i+3 < len(keys); i += 4 {
+		// Key ID, TIFF tag location, count, value. The value is stored inline if the location is 0.
+		if keys[i] == gtRasterTypeGeoKey && keys[i+1] == 0 {
+			return int(keys[i+3])
+		}
+	}
+	return 0
+}
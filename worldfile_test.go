@@ -0,0 +1,132 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParseWriteWorldFile(t *testing.T) {
+	t.Parallel()
+
+	src := "0.5\n0\n0\n-0.5\n100.25\n200.75\n"
+	tr, err := ParseWorldFile(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := GeoTransform{A: 0.5, E: -0.5, C: 100, F: 201}
+	if tr != want {
+		t.Fatalf("got %+v want %+v", tr, want)
+	}
+	buf := &bytes.Buffer{}
+	if err := WriteWorldFile(buf, tr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buf.String() != src {
+		t.Fatalf("got %q want %q", buf.String(), src)
+	}
+
+	for _, bad := range []string{"", "1\n2\n3\n4\n5\n", "1\n2\n3\n4\n5\nx\n", "1\n2\n3\n4\n5\n6\n7\n"} {
+		if _, err := ParseWorldFile(strings.NewReader(bad)); !errors.Is(err, ErrInvalidWorldFile) {
+			t.Fatalf("%q: got error %v want %v", bad, err, ErrInvalidWorldFile)
+		}
+	}
+}
+
+func TestGeoTransformInvert(t *testing.T) {
+	t.Parallel()
+
+	tr := GeoTransform{A: 2, B: 0.5, C: 10, D: 0.25, E: -3, F: 50}
+	x, y := tr.Apply(7, 11)
+	col, row, ok := tr.Invert(x, y)
+	if !ok || math.Abs(col-7) > 1e-9 || math.Abs(row-11) > 1e-9 {
+		t.Fatalf("got %v %v %v", col, row, ok)
+	}
+	if _, _, ok := (GeoTransform{}).Invert(1, 1); ok {
+		t.Fatalf("zero transformation must not be invertible")
+	}
+	if r := (GeoTransform{}).Rect(0, 0, 1, 1); !r.Empty() {
+		t.Fatalf("got %v want empty rectangle", r)
+	}
+}
+
+func TestCropGeo(t *testing.T) {
+	t.Parallel()
+
+	// 1 unit per pixel, top left corner at (1000, 2000), north up.
+	tr := GeoTransform{A: 1, E: -1, C: 1000, F: 2000}
+	img := makeNoiseNRGBA(100, 50, 3)
+
+	testCases := []struct {
+		name                   string
+		minX, minY, maxX, maxY float64
+		rect                   image.Rectangle
+	}{
+		{name: "aligned", minX: 1010, minY: 1970, maxX: 1030, maxY: 1990, rect: image.Rect(10, 10, 30, 30)},
+		{name: "partial pixels", minX: 1010.5, minY: 1969.5, maxX: 1029.5, maxY: 1989.5, rect: image.Rect(10, 10, 30, 31)},
+		{name: "clipped", minX: 990, minY: 1900, maxX: 1005, maxY: 2100, rect: image.Rect(0, 0, 5, 50)},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got, gotT := CropGeo(img, tr, tc.minX, tc.minY, tc.maxX, tc.maxY)
+			if !compareNRGBA(got, Crop(img, tc.rect), 0) {
+				t.Fatalf("got image with bounds %v want crop %v", got.Bounds(), tc.rect)
+			}
+			x, y := gotT.Apply(0, 0)
+			wx, wy := tr.Apply(float64(tc.rect.Min.X), float64(tc.rect.Min.Y))
+			if x != wx || y != wy {
+				t.Fatalf("got origin (%v, %v) want (%v, %v)", x, y, wx, wy)
+			}
+		})
+	}
+
+	if got, _ := CropGeo(img, tr, 0, 0, 10, 10); !got.Bounds().Empty() {
+		t.Fatalf("got bounds %v want empty image", got.Bounds())
+	}
+}
+
+func TestGeoTIFFGeoTransform(t *testing.T) {
+	t.Parallel()
+
+	geo := makeGeoTIFF()
+	tr, ok := geo.GeoTransform()
+	if want := (GeoTransform{A: 0.5, E: -0.25, C: 440720, F: 3751320}); !ok || tr != want {
+		t.Fatalf("got %+v %v want %+v", tr, ok, want)
+	}
+
+	// Round trip through the tags, including a rotated transformation.
+	for _, want := range []GeoTransform{
+		{A: 2, E: -2, C: 10, F: 20},
+		{A: 2, B: 0.5, D: 0.5, E: -2, C: 10, F: 20},
+	} {
+		got, ok := geo.SetGeoTransform(want).GeoTransform()
+		if !ok || got != want {
+			t.Fatalf("got %+v %v want %+v", got, ok, want)
+		}
+	}
+
+	// Pixel is point: the tie point refers to the pixel center.
+	point := &GeoTIFF{
+		ModelPixelScale: []float64{1, 1, 0},
+		ModelTiepoint:   []float64{0, 0, 0, 100, 200, 0},
+		GeoKeyDirectory: []uint16{1, 1, 0, 1, gtRasterTypeGeoKey, 0, 1, rasterPixelIsPoint},
+	}
+	tr, _ = point.GeoTransform()
+	if tr.C != 99.5 || tr.F != 200.5 {
+		t.Fatalf("got origin (%v, %v) want (99.5, 200.5)", tr.C, tr.F)
+	}
+	if got := point.SetGeoTransform(tr); got.ModelTiepoint[3] != 100 || got.ModelTiepoint[4] != 200 {
+		t.Fatalf("got tie point %v", got.ModelTiepoint)
+	}
+
+	if _, ok := (&GeoTIFF{}).GeoTransform(); ok {
+		t.Fatalf("expected no transformation without tags")
+	}
+}
@@ -0,0 +1,220 @@
+package imaging
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// tagXMP is the TIFF tag holding the XMP packet.
+const tagXMP = 700
+
+// xmpHeader is the header of the JPEG APP1 segment holding the XMP packet.
+const xmpHeader = "http://ns.adobe.com/xap/1.0/\x00"
+
+// xmpKeyword is the keyword of the PNG iTXt chunk holding the XMP packet.
+const xmpKeyword = "XML:com.adobe.xmp"
+
+// XML namespaces of the XMP properties read by the package.
+const (
+	nsRDF = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	nsDC  = "http://purl.org/dc/elements/1.1/"
+	nsXML = "http://www.w3.org/XML/1998/namespace"
+)
+
+// XMP holds the descriptive fields (the Dublin Core schema) of the XMP packet
+// embedded in a JPEG, PNG or TIFF image.
+type XMP struct {
+	// Title is the title of the image (dc:title).
+	Title string
+	// Description is the description or caption of the image (dc:description).
+	Description string
+	// Creators are the authors of the image (dc:creator).
+	Creators []string
+	// Keywords are the keywords of the image (dc:subject).
+	Keywords []string
+	// Rights is the copyright notice (dc:rights).
+	Rights string
+	// Packet is the raw XMP packet, for reading the properties of other schemas.
+	Packet []byte
+}
+
+// readXMP returns the XMP packet embedded in the JPEG, PNG or TIFF image or nil.
+func readXMP(data []byte) []byte {
+	switch {
+	case isJPEG(data):
+		var packet []byte
+		walkJPEGSegments(data, func(marker byte, payload []byte) bool {
+			if marker == markerAPP1 && len(payload) > len(xmpHeader) && string(payload[:len(xmpHeader)]) == xmpHeader {
+				packet = payload[len(xmpHeader):]
+				return false
+			}
+			return true
+		})
+		return packet
+	case isPNG(data):
+		return readPNGXMP(data)
+	case isTIFF(data):
+		dirs, err := parseTIFF(data)
+		if err != nil {
+			return nil
+		}
+		if f := dirs[0].field(tagXMP); f != nil {
+			return f.data
+		}
+	}
+	return nil
+}
+
+// readPNGXMP returns the XMP packet stored in the iTXt chunk of the PNG image.
+func readPNGXMP(data []byte) []byte {
+	pos := len(pngHeader)
+	for pos+8 <= len(data) {
+		size := int(binary.BigEndian.Uint32(data[pos:]))
+		typ := string(data[pos+4 : pos+8])
+		if size < 0 || pos+12+size > len(data) {
+			return nil
+		}
+		chunk := data[pos+8 : pos+8+size]
+		// Keyword, NUL separator, compression flag and method, language tag,
+		// translated keyword and the text.
+		if typ == "iTXt" && len(chunk) > len(xmpKeyword)+2 && string(chunk[:len(xmpKeyword)+1]) == xmpKeyword+"\x00" {
+			compressed := chunk[len(xmpKeyword)+1] == 1
+			rest := chunk[len(xmpKeyword)+3:]
+			for i := 0; i < 2; i++ {
+				j := bytes.IndexByte(rest, 0)
+				if j < 0 {
+					return nil
+				}
+				rest = rest[j+1:]
+			}
+			if !compressed {
+				return rest
+			}
+			zr, err := zlib.NewReader(bytes.NewReader(rest))
+			if err != nil {
+				return nil
+			}
+			packet, err := io.ReadAll(zr)
+			if err != nil {
+				return nil
+			}
+			return packet
+		}
+		pos += 12 + size
+	}
+	return nil
+}
+
+// parseXMP returns the descriptive fields of the XMP packet. It returns nil if
+// the packet is not valid XML.
+func parseXMP(packet []byte) *XMP {
+	x := &XMP{Packet: packet}
+	d := xml.NewDecoder(bytes.NewReader(packet))
+	d.Strict = false
+
+	var (
+		// property is the dc property being read, empty outside of the properties.
+		property string
+		// depth is the element depth inside the property.
+		depth int
+		// values are the rdf:li items of the property, or its simple value.
+		values []string
+		// lang is the language of the value being read.
+		lang string
+		// langs are the languages of the values picked for the language alternatives.
+		langs = map[string]string{}
+		text  strings.Builder
+	)
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if property == "" {
+				if t.Name.Space == nsDC {
+					property, depth, values, lang = t.Name.Local, 0, nil, xmlLang(t, "")
+					text.Reset()
+				}
+				continue
+			}
+			depth++
+			if t.Name.Space == nsRDF && t.Name.Local == "li" {
+				lang = xmlLang(t, "")
+				text.Reset()
+			}
+		case xml.CharData:
+			if property != "" {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			if property == "" {
+				continue
+			}
+			if depth > 0 {
+				if t.Name.Space == nsRDF && t.Name.Local == "li" {
+					if s := strings.TrimSpace(text.String()); s != "" {
+						values = append(values, s)
+						x.setAlternative(property, s, lang, langs)
+					}
+				}
+				depth--
+				continue
+			}
+			if values == nil {
+				// A simple value without rdf containers.
+				if s := strings.TrimSpace(text.String()); s != "" {
+					values = []string{s}
+					x.setAlternative(property, s, lang, langs)
+				}
+			}
+			switch property {
+			case "creator":
+				x.Creators = append(x.Creators, values...)
+			case "subject":
+				x.Keywords = append(x.Keywords, values...)
+			}
+			property = ""
+		}
+	}
+	return x
+}
+
+// setAlternative sets the language alternative property (title, description or rights)
+// to the value, preferring the default language over the first value.
+func (x *XMP) setAlternative(property, value, lang string, langs map[string]string) {
+	var field *string
+	switch property {
+	case "title":
+		field = &x.Title
+	case "description":
+		field = &x.Description
+	case "rights":
+		field = &x.Rights
+	default:
+		return
+	}
+	if prev, ok := langs[property]; ok && (prev == "x-default" || lang != "x-default") {
+		return
+	}
+	langs[property] = lang
+	*field = value
+}
+
+// xmlLang returns the xml:lang attribute of the element, or the inherited language.
+func xmlLang(e xml.StartElement, inherited string) string {
+	for _, a := range e.Attr {
+		if a.Name.Space == nsXML && a.Name.Local == "lang" {
+			return a.Value
+		}
+	}
+	return inherited
+}
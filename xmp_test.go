@@ -0,0 +1,143 @@
+package imaging
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"hash/crc32"
+	"reflect"
+	"testing"
+)
+
+// testXMP is an XMP packet as written by photo management software.
+const testXMP = `<?xpacket begin="" id="W5M0MpCehiHzreSzNTczkc9d"?>
+<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/" dc:format="image/jpeg">
+   <dc:title>
+    <rdf:Alt>
+     <rdf:li xml:lang="de">Sonnenuntergang</rdf:li>
+     <rdf:li xml:lang="x-default">Sunset</rdf:li>
+    </rdf:Alt>
+   </dc:title>
+   <dc:description><rdf:Alt><rdf:li xml:lang="en">Sunset over the sea</rdf:li></rdf:Alt></dc:description>
+   <dc:creator><rdf:Seq><rdf:li>Jane Doe</rdf:li><rdf:li>John Doe</rdf:li></rdf:Seq></dc:creator>
+   <dc:subject><rdf:Bag><rdf:li>sunset</rdf:li><rdf:li>sea &amp; sky</rdf:li></rdf:Bag></dc:subject>
+   <dc:rights>(c) 2023 Jane Doe</dc:rights>
+  </rdf:Description>
+ </rdf:RDF>
+</x:xmpmeta>
+<?xpacket end="w"?>`
+
+// makeJPEGWithSegment returns a JPEG image with the marker segment right after the SOI marker.
+func makeJPEGWithSegment(t *testing.T, marker byte, payload []byte) []byte {
+	t.Helper()
+	img := &bytes.Buffer{}
+	if err := Encode(img, makeNoiseNRGBA(8, 8, 1), JPEG); err != nil {
+		t.Fatalf("failed to encode JPEG: %v", err)
+	}
+	segment := []byte{0xff, marker, 0, 0}
+	segment = append(segment, payload...)
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(segment)-2))
+
+	data := img.Bytes()
+	out := append([]byte{}, data[:2]...)
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+// makePNGWithXMP returns a PNG image with the XMP packet in an iTXt chunk.
+func makePNGWithXMP(t *testing.T, packet []byte, compressed bool) []byte {
+	t.Helper()
+	img := &bytes.Buffer{}
+	if err := Encode(img, makeNoiseNRGBA(8, 8, 1), PNG); err != nil {
+		t.Fatalf("failed to encode PNG: %v", err)
+	}
+	text := packet
+	flag := byte(0)
+	if compressed {
+		buf := &bytes.Buffer{}
+		zw := zlib.NewWriter(buf)
+		zw.Write(packet) //nolint
+		zw.Close()       //nolint
+		text, flag = buf.Bytes(), 1
+	}
+	chunk := make([]byte, 8)
+	copy(chunk[4:], "iTXt")
+	chunk = append(chunk, xmpKeyword+"\x00"...)
+	chunk = append(chunk, flag, 0, 0, 0)
+	chunk = append(chunk, text...)
+	binary.BigEndian.PutUint32(chunk, uint32(len(chunk)-8))
+	crc := make([]byte, 4)
+	binary.BigEndian.PutUint32(crc, crc32.ChecksumIEEE(chunk[4:]))
+	chunk = append(chunk, crc...)
+
+	data := img.Bytes()
+	const ihdrEnd = len(pngHeader) + 12 + 13
+	out := append([]byte{}, data[:ihdrEnd]...)
+	out = append(out, chunk...)
+	return append(out, data[ihdrEnd:]...)
+}
+
+func TestParseXMP(t *testing.T) {
+	t.Parallel()
+
+	got := parseXMP([]byte(testXMP))
+	want := &XMP{
+		Title:       "Sunset",
+		Description: "Sunset over the sea",
+		Creators:    []string{"Jane Doe", "John Doe"},
+		Keywords:    []string{"sunset", "sea & sky"},
+		Rights:      "(c) 2023 Jane Doe",
+		Packet:      []byte(testXMP),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v want %+v", got, want)
+	}
+
+	if got := parseXMP([]byte("<x:xmpmeta><rdf:RDF>")); got != nil {
+		t.Fatalf("got %+v for a broken packet", got)
+	}
+}
+
+func TestReadXMP(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		data []byte
+	}{
+		{
+			name: "JPEG",
+			data: makeJPEGWithSegment(t, markerAPP1, append([]byte(xmpHeader), testXMP...)),
+		},
+		{
+			name: "PNG",
+			data: makePNGWithXMP(t, []byte(testXMP), false),
+		},
+		{
+			name: "PNG compressed",
+			data: makePNGWithXMP(t, []byte(testXMP), true),
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			meta, err := DecodeMetadata(bytes.NewReader(tc.data))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if meta.XMP == nil || string(meta.XMP.Packet) != testXMP {
+				t.Fatalf("got XMP %+v", meta.XMP)
+			}
+			if meta.Title() != "Sunset" {
+				t.Fatalf("got title %q want %q", meta.Title(), "Sunset")
+			}
+			if _, err := Decode(bytes.NewReader(tc.data)); err != nil {
+				t.Fatalf("failed to decode the image: %v", err)
+			}
+		})
+	}
+}